@@ -0,0 +1,169 @@
+// Package archive упаковывает результаты конвертации в zip архив.
+package archive
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// StateDirName - служебная директория с БД и кэшем, которая не попадает в архив.
+const StateDirName = ".photoconverter"
+
+// WriteZip упаковывает все файлы из srcDir в zip архив zipPath.
+// Пути в архиве повторяют структуру srcDir (как при --keep-tree).
+// Служебная директория .photoconverter и сам архив (если он внутри srcDir)
+// пропускаются. Архив записывается атомарно через временный файл.
+// Возвращает относительные пути добавленных файлов.
+func WriteZip(srcDir, zipPath string) ([]string, error) {
+	absSrc, err := filepath.Abs(srcDir)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить абсолютный путь: %w", err)
+	}
+	absZip, err := filepath.Abs(zipPath)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить абсолютный путь: %w", err)
+	}
+
+	files, err := collectFiles(absSrc, absZip)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(absZip), 0755); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию для архива: %w", err)
+	}
+
+	tmpPath := absZip + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать архив: %w", err)
+	}
+
+	zw := zip.NewWriter(out)
+	for _, rel := range files {
+		if err := addFile(zw, filepath.Join(absSrc, rel), rel); err != nil {
+			_ = zw.Close()
+			_ = out.Close()
+			_ = os.Remove(tmpPath)
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		_ = out.Close()
+		_ = os.Remove(tmpPath)
+		return nil, fmt.Errorf("не удалось записать архив: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, fmt.Errorf("не удалось записать архив: %w", err)
+	}
+	if err := os.Rename(tmpPath, absZip); err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, fmt.Errorf("не удалось сохранить архив: %w", err)
+	}
+
+	return files, nil
+}
+
+// collectFiles возвращает отсортированные относительные пути файлов для архива.
+func collectFiles(srcDir, zipPath string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(srcDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == StateDirName {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || path == zipPath || path == zipPath+".tmp" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("не удалось обойти %s: %w", srcDir, err)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// addFile добавляет один файл в архив.
+func addFile(zw *zip.Writer, path, rel string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть %s: %w", rel, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("не удалось получить info %s: %w", rel, err)
+	}
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return fmt.Errorf("не удалось создать заголовок для %s: %w", rel, err)
+	}
+	header.Name = filepath.ToSlash(rel)
+	// Изображения уже сжаты - Deflate почти ничего не даёт
+	header.Method = zip.Store
+
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("не удалось добавить %s в архив: %w", rel, err)
+	}
+	if _, err := io.Copy(w, f); err != nil {
+		return fmt.Errorf("не удалось записать %s в архив: %w", rel, err)
+	}
+	return nil
+}
+
+// RemoveFiles удаляет упакованные файлы из srcDir и освободившиеся пустые директории.
+// Сама srcDir и служебная директория не удаляются.
+func RemoveFiles(srcDir string, relPaths []string) error {
+	dirs := make(map[string]bool)
+	for _, rel := range relPaths {
+		if err := os.Remove(filepath.Join(srcDir, rel)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("не удалось удалить %s: %w", rel, err)
+		}
+		for dir := filepath.Dir(rel); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+
+	// Удаляем директории от самых глубоких к верхним; непустые остаются на месте
+	sorted := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return strings.Count(sorted[i], string(filepath.Separator)) > strings.Count(sorted[j], string(filepath.Separator))
+	})
+	for _, dir := range sorted {
+		_ = os.Remove(filepath.Join(srcDir, dir))
+	}
+	return nil
+}
+
+/*
+Возможные расширения:
+- Упаковка только файлов текущего запуска (по манифесту)
+- Поддержка tar.gz
+- Разбиение архива на части по размеру
+*/
@@ -0,0 +1,130 @@
+package archive
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// writeOutputTree создаёт выходную директорию с результатами и служебной БД.
+func writeOutputTree(t *testing.T) (string, map[string]string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	produced := map[string]string{
+		"a.webp":               "aaa",
+		"2024/june/beach.webp": "beach",
+		"2024/july/city.webp":  "city",
+	}
+	all := map[string]string{
+		".photoconverter/state.sqlite": "db",
+		".photoconverter/vips.json":    "{}",
+	}
+	for k, v := range produced {
+		all[k] = v
+	}
+	for rel, content := range all {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, produced
+}
+
+// readZip возвращает содержимое архива (путь -> данные).
+func readZip(t *testing.T, path string) map[string]string {
+	t.Helper()
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	got := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[f.Name] = string(data)
+	}
+	return got
+}
+
+func TestWriteZip_MatchesProducedFiles(t *testing.T) {
+	dir, produced := writeOutputTree(t)
+	// Архив внутри выходной директории не должен попасть сам в себя
+	zipPath := filepath.Join(dir, "album.zip")
+
+	files, err := WriteZip(dir, zipPath)
+	if err != nil {
+		t.Fatalf("WriteZip: %v", err)
+	}
+	if len(files) != len(produced) {
+		t.Errorf("WriteZip returned %d files, want %d: %v", len(files), len(produced), files)
+	}
+
+	got := readZip(t, zipPath)
+	if len(got) != len(produced) {
+		var names []string
+		for name := range got {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		t.Fatalf("archive contains %v, want %d produced files", names, len(produced))
+	}
+	for rel, content := range produced {
+		if got[rel] != content {
+			t.Errorf("archive entry %s = %q, want %q", rel, got[rel], content)
+		}
+	}
+
+	// Исходные файлы остаются на месте
+	if _, err := os.Stat(filepath.Join(dir, "2024", "june", "beach.webp")); err != nil {
+		t.Errorf("loose file removed without RemoveFiles: %v", err)
+	}
+	if _, err := os.Stat(zipPath + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary archive left behind: %v", err)
+	}
+}
+
+func TestRemoveFiles(t *testing.T) {
+	dir, _ := writeOutputTree(t)
+	zipPath := filepath.Join(t.TempDir(), "album.zip")
+
+	files, err := WriteZip(dir, zipPath)
+	if err != nil {
+		t.Fatalf("WriteZip: %v", err)
+	}
+	if err := RemoveFiles(dir, files); err != nil {
+		t.Fatalf("RemoveFiles: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != StateDirName {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("output dir after RemoveFiles = %v, want only %s", names, StateDirName)
+	}
+	if _, err := os.Stat(filepath.Join(dir, StateDirName, "state.sqlite")); err != nil {
+		t.Errorf("state DB removed: %v", err)
+	}
+}
@@ -2,16 +2,27 @@
 package cache
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 
 	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/converter"
+	"github.com/artemshloyda/photoconverter/internal/scanner"
 )
 
+// ErrCorrupt - запись кэша не совпадает с контрольной суммой (--cache-verify).
+// Запись удаляется, вызывающий конвертирует файл заново.
+var ErrCorrupt = errors.New("запись кэша повреждена: контрольная сумма не совпадает")
+
+// checksumExt - расширение файла с SHA256 записи кэша (рядом с записью).
+const checksumExt = ".sha256"
+
 // Cache управляет кэшированием конвертированных изображений.
 type Cache struct {
 	// dir - директория для кэша.
@@ -22,6 +33,20 @@ type Cache struct {
 
 	// enabled - включён ли кэш.
 	enabled bool
+
+	// shared - кэш вне выходной директории (--cache-dir): записи ищутся
+	// по содержимому исходника и общие для разных --out.
+	shared bool
+}
+
+// GlobalDir возвращает директорию общего кэша пользователя
+// (~/.cache/photoconverter/cache на Linux).
+func GlobalDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("не удалось определить директорию общего кэша: %w", err)
+	}
+	return filepath.Join(dir, "photoconverter", "cache"), nil
 }
 
 // New создаёт новый Cache.
@@ -31,75 +56,146 @@ func New(cfg *config.Config) (*Cache, error) {
 	}
 
 	dir := cfg.CacheDir
-	if dir == "" {
+	switch dir {
+	case "":
 		dir = filepath.Join(cfg.OutputDir, ".photoconverter", "cache")
+	case config.CacheDirGlobal:
+		var err error
+		if dir, err = GlobalDir(); err != nil {
+			return nil, err
+		}
 	}
 
-	// Создаём директорию кэша
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return nil, fmt.Errorf("не удалось создать директорию кэша: %w", err)
+	// Создаём директорию кэша; в dry-run кэш только читается
+	if !cfg.DryRun {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("не удалось создать директорию кэша: %w", err)
+		}
 	}
 
 	return &Cache{
 		dir:     dir,
 		cfg:     cfg,
 		enabled: true,
+		shared:  cfg.CacheDir != "",
 	}, nil
 }
 
+// WithConfig возвращает кэш в той же директории с параметрами cfg
+// (вариант выхода со своим форматом).
+func (c *Cache) WithConfig(cfg *config.Config) *Cache {
+	v := *c
+	v.cfg = cfg
+	return &v
+}
+
+// Source возвращает источник ключа записи для исходника srcPath: путь
+// для кэша в выходной директории или SHA256 содержимого для общего кэша,
+// чтобы один и тот же исходник находил запись при любых --in и --out.
+func (c *Cache) Source(srcPath string) (string, error) {
+	if !c.shared {
+		return srcPath, nil
+	}
+	return scanner.ComputeHash(srcPath, "sha256")
+}
+
 // IsEnabled возвращает true если кэш включён.
 func (c *Cache) IsEnabled() bool {
 	return c.enabled
 }
 
-// CacheKey генерирует ключ кэша на основе пути файла и параметров конвертации.
-func (c *Cache) CacheKey(srcPath string, paramsHash string) string {
+// CacheKey генерирует ключ кэша на основе источника (см. Source) и параметров конвертации.
+func (c *Cache) CacheKey(source string, paramsHash string) string {
 	h := sha256.New()
-	h.Write([]byte(srcPath))
+	h.Write([]byte(source))
 	h.Write([]byte(paramsHash))
 	return hex.EncodeToString(h.Sum(nil))[:32]
 }
 
-// Get возвращает путь к кэшированному файлу, если он существует.
-// Возвращает пустую строку если файл не найден в кэше.
-func (c *Cache) Get(srcPath string, paramsHash string) string {
+// Get возвращает путь к кэшированному файлу для источника source (см. Source),
+// если он существует. Возвращает пустую строку если файл не найден в кэше.
+// С --cache-verify запись без контрольной суммы считается отсутствующей.
+func (c *Cache) Get(source string, paramsHash string) string {
 	if !c.enabled {
 		return ""
 	}
 
-	key := c.CacheKey(srcPath, paramsHash)
-	ext := "." + string(c.cfg.OutputFormat)
+	key := c.CacheKey(source, paramsHash)
+	ext := "." + c.cfg.OutputExt()
 	cachePath := filepath.Join(c.dir, key+ext)
 
-	if _, err := os.Stat(cachePath); err == nil {
-		return cachePath
+	if _, err := os.Stat(cachePath); err != nil {
+		return ""
+	}
+	if c.cfg.CacheVerify {
+		if _, err := os.Stat(cachePath + checksumExt); err != nil {
+			return ""
+		}
 	}
 
-	return ""
+	return cachePath
 }
 
-// Put сохраняет файл в кэш.
-func (c *Cache) Put(srcPath string, paramsHash string, convertedPath string) error {
+// Put сохраняет файл в кэш для источника source (см. Source).
+// С --cache-verify рядом с записью сохраняется её SHA256.
+func (c *Cache) Put(source string, paramsHash string, convertedPath string) error {
 	if !c.enabled {
 		return nil
 	}
 
-	key := c.CacheKey(srcPath, paramsHash)
+	key := c.CacheKey(source, paramsHash)
 	ext := filepath.Ext(convertedPath)
 	cachePath := filepath.Join(c.dir, key+ext)
 
-	// Копируем файл в кэш
-	return copyFile(convertedPath, cachePath)
+	// Копируем файл в кэш через временный файл: общий кэш могут
+	// одновременно заполнять несколько воркеров и запусков
+	tmp, err := os.CreateTemp(c.dir, key+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	_ = tmp.Close()
+	sum, err := copyFile(convertedPath, tmp.Name())
+	if err == nil {
+		err = os.Rename(tmp.Name(), cachePath)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if !c.cfg.CacheVerify {
+		// Контрольная сумма прошлой записи больше не подходит
+		_ = os.Remove(cachePath + checksumExt)
+		return nil
+	}
+	return os.WriteFile(cachePath+checksumExt, []byte(hex.EncodeToString(sum)), 0644)
 }
 
 // CopyFromCache копирует файл из кэша в целевой путь.
-func (c *Cache) CopyFromCache(cachePath string, dstPath string) error {
+// С --cache-verify SHA256 скопированного сравнивается с сохранённой при Put:
+// при несовпадении запись и результат удаляются и возвращается ErrCorrupt.
+// С --preserve-mtime результат получает время исходника srcPath.
+func (c *Cache) CopyFromCache(cachePath string, dstPath string, srcPath string) error {
 	// Создаём директорию для целевого файла
 	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
 		return err
 	}
 
-	return copyFile(cachePath, dstPath)
+	sum, err := copyFile(cachePath, dstPath)
+	if err != nil {
+		return err
+	}
+	if c.cfg.CacheVerify {
+		want, err := os.ReadFile(cachePath + checksumExt)
+		if err != nil || !bytes.Equal(bytes.TrimSpace(want), []byte(hex.EncodeToString(sum))) {
+			_ = os.Remove(dstPath)
+			c.remove(cachePath)
+			return fmt.Errorf("%w: %s", ErrCorrupt, cachePath)
+		}
+	}
+	if c.cfg.PreserveMtime {
+		return converter.PreserveTimes(srcPath, dstPath)
+	}
+	return nil
 }
 
 // Clear очищает весь кэш.
@@ -135,22 +231,34 @@ func (c *Cache) Size() (int64, error) {
 	return size, err
 }
 
-// copyFile копирует файл из src в dst.
-func copyFile(src, dst string) error {
+// remove удаляет запись кэша вместе с её контрольной суммой.
+func (c *Cache) remove(cachePath string) {
+	_ = os.Remove(cachePath)
+	_ = os.Remove(cachePath + checksumExt)
+}
+
+// copyFile копирует файл из src в dst и возвращает SHA256 скопированного.
+func copyFile(src, dst string) ([]byte, error) {
 	srcFile, err := os.Open(src)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer srcFile.Close()
 
 	dstFile, err := os.Create(dst)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer dstFile.Close()
 
-	_, err = io.Copy(dstFile, srcFile)
-	return err
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(dstFile, h), srcFile)
+	if closeErr := dstFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
 }
 
 /*
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// newTestCache создаёт включённый кэш во временной директории.
+func newTestCache(t *testing.T, verify bool) (*Cache, string) {
+	t.Helper()
+	root := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.OutputDir = filepath.Join(root, "out")
+	cfg.CacheEnabled = true
+	cfg.CacheVerify = verify
+	c, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c, root
+}
+
+func TestCache_VerifyCorrupt(t *testing.T) {
+	c, root := newTestCache(t, true)
+	converted := filepath.Join(root, "photo."+c.cfg.OutputExt())
+	if err := os.WriteFile(converted, []byte("converted image data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	const src, params = "/in/photo.png", "params"
+	if err := c.Put(src, params, converted); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	cachePath := c.Get(src, params)
+	if cachePath == "" {
+		t.Fatal("Get: запись не найдена после Put")
+	}
+	dst := filepath.Join(root, "dst", "photo."+c.cfg.OutputExt())
+	if err := c.CopyFromCache(cachePath, dst, src); err != nil {
+		t.Fatalf("CopyFromCache: %v", err)
+	}
+
+	// Обрезанная запись: ошибка, результат и запись удалены
+	if err := os.WriteFile(cachePath, []byte("conv"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.Remove(dst)
+	if err := c.CopyFromCache(cachePath, dst, src); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("CopyFromCache повреждённой записи = %v, want ErrCorrupt", err)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("результат из повреждённой записи не удалён: %v", err)
+	}
+	// Следующий Get - промах, файл конвертируется заново
+	if path := c.Get(src, params); path != "" {
+		t.Errorf("Get после повреждения = %q, want промах", path)
+	}
+
+	if err := c.Put(src, params, converted); err != nil {
+		t.Fatalf("Put после повреждения: %v", err)
+	}
+	if err := c.CopyFromCache(c.Get(src, params), dst, src); err != nil {
+		t.Errorf("CopyFromCache новой записи: %v", err)
+	}
+}
+
+func TestCache_NoVerify(t *testing.T) {
+	c, root := newTestCache(t, false)
+	converted := filepath.Join(root, "photo."+c.cfg.OutputExt())
+	if err := os.WriteFile(converted, []byte("converted image data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put("/in/photo.png", "params", converted); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	cachePath := c.Get("/in/photo.png", "params")
+	if _, err := os.Stat(cachePath + checksumExt); !os.IsNotExist(err) {
+		t.Errorf("без --cache-verify контрольная сумма не сохраняется: %v", err)
+	}
+
+	// Без проверки повреждение не обнаруживается
+	if err := os.WriteFile(cachePath, []byte("conv"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CopyFromCache(cachePath, filepath.Join(root, "dst.jpg"), "/in/photo.png"); err != nil {
+		t.Errorf("CopyFromCache без проверки: %v", err)
+	}
+
+	// Запись без контрольной суммы с --cache-verify - промах
+	c.cfg.CacheVerify = true
+	if path := c.Get("/in/photo.png", "params"); path != "" {
+		t.Errorf("Get записи без контрольной суммы = %q, want промах", path)
+	}
+}
@@ -0,0 +1,45 @@
+// Package cli содержит флаг размера в байтах.
+package cli
+
+import (
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// byteSizeValue - значение флага с размером в байтах ("500KB", "5MB").
+// Реализует pflag.Value.
+type byteSizeValue struct {
+	target *int64
+}
+
+// newByteSizeValue создаёт флаг размера, записывающий байты в target.
+func newByteSizeValue(target *int64) *byteSizeValue {
+	return &byteSizeValue{target: target}
+}
+
+// String возвращает текущее значение флага.
+func (v *byteSizeValue) String() string {
+	if v.target == nil || *v.target == 0 {
+		return "0"
+	}
+	return config.FormatByteSize(*v.target)
+}
+
+// Set разбирает размер из строки.
+func (v *byteSizeValue) Set(s string) error {
+	n, err := config.ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*v.target = n
+	return nil
+}
+
+// Type возвращает имя типа для справки.
+func (v *byteSizeValue) Type() string {
+	return "size"
+}
+
+/*
+Возможные расширения:
+- Поддержка размеров в процентах от исходного файла
+*/
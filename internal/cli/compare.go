@@ -0,0 +1,179 @@
+// Package cli содержит команду сравнения выходных форматов.
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/converter"
+	"github.com/artemshloyda/photoconverter/internal/estimate"
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+	"github.com/artemshloyda/photoconverter/internal/storage"
+	"github.com/artemshloyda/photoconverter/internal/vipsfinder"
+	"github.com/artemshloyda/photoconverter/internal/worker"
+)
+
+// newCompareCmd создаёт команду compare.
+func newCompareCmd() *cobra.Command {
+	var (
+		vipsPath   string
+		inputDir   string
+		sample     int
+		formats    []string
+		jsonOutput bool
+	)
+	ccfg := config.DefaultConfig()
+
+	cmd := &cobra.Command{
+		Use:   "compare [file]...",
+		Short: "Сравнить размер и время конвертации в разные форматы",
+		Long: `Конвертирует файлы-образцы в каждый из форматов --formats с качеством
+--quality во временную директорию и выводит таблицу размеров результата
+и времени конвертации. Образцы - файлы из аргументов или --sample файлов,
+равномерно выбранных из директории --in. Результаты удаляются.
+
+Примеры:
+  photoconverter compare photo.heic
+  photoconverter compare --in ./photos --sample 20 --formats webp,avif,jxl --quality 75`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if ccfg.Quality < 1 || ccfg.Quality > 100 {
+				return fmt.Errorf("качество должно быть от 1 до 100, получено: %d", ccfg.Quality)
+			}
+			outFormats, err := parseCompareFormats(formats)
+			if err != nil {
+				return err
+			}
+			files, err := compareSamples(cmd, ccfg, args, inputDir, sample)
+			if err != nil {
+				return err
+			}
+
+			vips, err := vipsfinder.NewFinder(vipsPath).Find()
+			if err != nil {
+				return err
+			}
+			conv := converter.New(vips.Path, ccfg)
+			conv.SetRawDecoderPath(converter.FindRawDecoder(""))
+
+			results, err := estimate.Compare(cmd.Context(), ccfg, conv, files, outFormats)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(results)
+			}
+			printCompare(cmd.OutOrStdout(), len(files), ccfg.Quality, results)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&formats, "formats", []string{string(config.FormatWebP), string(config.FormatAVIF), string(config.FormatJXL)},
+		"Сравниваемые выходные форматы")
+	cmd.Flags().IntVar(&ccfg.Quality, "quality", ccfg.Quality, "Качество для lossy форматов (1-100)")
+	cmd.Flags().StringVar(&inputDir, "in", "", "Директория, из которой выбираются образцы (вместо файлов в аргументах)")
+	cmd.Flags().IntVar(&sample, "sample", 10, "Число образцов из директории --in")
+	cmd.Flags().StringVar(&vipsPath, "vips-path", "", "Путь к бинарнику vips (по умолчанию автопоиск)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Вывести результаты в JSON")
+
+	return cmd
+}
+
+// parseCompareFormats проверяет форматы --formats команды compare.
+func parseCompareFormats(names []string) ([]config.OutputFormat, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("не указаны форматы для сравнения (--formats)")
+	}
+	formats := make([]config.OutputFormat, 0, len(names))
+	for _, name := range names {
+		f := config.OutputFormat(strings.ToLower(strings.TrimSpace(name)))
+		if !f.Valid() {
+			return nil, fmt.Errorf("неверный формат %q (доступны форматы: %v)", name, config.ValidFormats())
+		}
+		formats = append(formats, f)
+	}
+	return formats, nil
+}
+
+// compareSamples возвращает образцы для compare: файлы из args или
+// sample файлов директории inputDir.
+func compareSamples(cmd *cobra.Command, ccfg *config.Config, args []string, inputDir string, sample int) ([]scanner.File, error) {
+	switch {
+	case len(args) > 0 && inputDir != "":
+		return nil, fmt.Errorf("укажите файлы или --in, но не одновременно")
+	case len(args) > 0:
+		files := make([]scanner.File, 0, len(args))
+		for _, path := range args {
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			if info.IsDir() {
+				return nil, fmt.Errorf("%s - директория (для выборки из директории используйте --in)", path)
+			}
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, scanner.File{
+				Path:    abs,
+				Info:    storage.FileInfo{Path: abs, Size: info.Size(), Mtime: info.ModTime().Unix()},
+				RelPath: filepath.Base(path),
+			})
+		}
+		return files, nil
+	case inputDir != "":
+		if sample < 1 {
+			return nil, fmt.Errorf("--sample должен быть >= 1, получено: %d", sample)
+		}
+		scfg := *ccfg
+		scfg.InputDir = inputDir
+		scanned, errs := scanner.New(&scfg).Scan(cmd.Context())
+		var files []scanner.File
+		for f := range scanned {
+			files = append(files, f)
+		}
+		if err := <-errs; err != nil {
+			return nil, fmt.Errorf("ошибка сканирования: %w", err)
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("в %s нет изображений для сравнения", inputDir)
+		}
+		return estimate.SelectN(files, sample), nil
+	default:
+		return nil, fmt.Errorf("укажите файлы-образцы или директорию --in")
+	}
+}
+
+// printCompare печатает таблицу сравнения форматов.
+func printCompare(w io.Writer, files, quality int, results []estimate.FormatResult) {
+	fmt.Fprintf(w, "📊 Сравнение форматов: %d файлов, качество %d\n", files, quality)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  Формат\tФайлов\tИсходники\tРезультат\tОт исходников\tВремя")
+	for _, r := range results {
+		if r.Files == 0 {
+			fmt.Fprintf(tw, "  %s\t0/%d\t-\t-\t-\t-\n", r.Format, r.Failed)
+			continue
+		}
+		fmt.Fprintf(tw, "  %s\t%d/%d\t%s\t%s\t%.0f%%\t%s\n", r.Format, r.Files, r.Files+r.Failed,
+			worker.FormatBytes(r.InputBytes), worker.FormatBytes(r.OutputBytes), r.Ratio*100, r.Duration.Round(time.Millisecond))
+	}
+	_ = tw.Flush()
+
+	for _, r := range results {
+		if r.Error != "" {
+			fmt.Fprintf(w, "⚠️  %s: %s\n", r.Format, r.Error)
+		}
+	}
+}
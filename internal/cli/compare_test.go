@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/estimate"
+)
+
+// compareVipsScript имитирует vips copy: размер результата зависит от формата
+// (по расширению выходного файла). Использует только встроенные команды shell.
+const compareVipsScript = `#!/bin/sh
+case "$1" in
+--version) echo "vips-8.15.1" ;;
+copy)
+  out="${3%%\[*}"
+  case "$out" in
+  *.webp) printf 'webp-webp-webp' > "$out" ;;
+  *.avif) printf 'avif-avif' > "$out" ;;
+  *) printf 'other' > "$out" ;;
+  esac
+  ;;
+esac
+`
+
+func TestCompare_FakeVips(t *testing.T) {
+	isolateEnv(t)
+	vips := filepath.Join(t.TempDir(), "vips")
+	if err := os.WriteFile(vips, []byte(compareVipsScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	inDir := t.TempDir()
+	for _, name := range []string{"a.jpg", "b.jpg", "c.png"} {
+		if err := os.WriteFile(filepath.Join(inDir, name), []byte(strings.Repeat("x", 100)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	cmd := newCompareCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--vips-path", vips, "--json", "--in", inDir, "--sample", "2", "--formats", "webp,avif,jxl"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+	var results []estimate.FormatResult
+	if err := json.Unmarshal(out.Bytes(), &results); err != nil {
+		t.Fatalf("некорректный JSON %q: %v", out.String(), err)
+	}
+
+	want := []struct {
+		format config.OutputFormat
+		size   int64
+	}{{config.FormatWebP, 14}, {config.FormatAVIF, 9}, {config.FormatJXL, 5}}
+	if len(results) != len(want) {
+		t.Fatalf("строк %d, want %d: %+v", len(results), len(want), results)
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.Format != w.format || r.Files != 2 || r.Failed != 0 || r.InputBytes != 200 || r.OutputBytes != 2*w.size {
+			t.Errorf("строка %d = %+v, want %s: 2 файла, 200 -> %d байт", i, r, w.format, 2*w.size)
+		}
+	}
+
+	// Таблица: строка на каждый формат
+	out.Reset()
+	cmd = newCompareCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--vips-path", vips, "--formats", "webp,avif", filepath.Join(inDir, "a.jpg")})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+	for _, want := range []string{"1 файлов, качество 80", "webp  ", "avif  ", "14 B", "9 B"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("вывод не содержит %q:\n%s", want, out.String())
+		}
+	}
+
+	cmd = newCompareCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--vips-path", vips, "--formats", "gif", filepath.Join(inDir, "a.jpg")})
+	if err := cmd.Execute(); err == nil {
+		t.Error("неизвестный формат должен быть ошибкой")
+	}
+}
@@ -0,0 +1,163 @@
+// Package cli содержит команду диагностики окружения.
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/spf13/cobra"
+
+	"github.com/artemshloyda/photoconverter/internal/converter"
+	"github.com/artemshloyda/photoconverter/internal/vipsfinder"
+	"github.com/artemshloyda/photoconverter/internal/worker"
+)
+
+// doctorFormats - выходные форматы, поддержка которых проверяется командой doctor.
+var doctorFormats = []string{"webp", "avif", "heic", "jxl"}
+
+// newDoctorCmd создаёт команду doctor.
+func newDoctorCmd() *cobra.Command {
+	var vipsPath, dbPath, outDir string
+
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Проверить окружение: vips, форматы, БД, ресурсы",
+		Long: `Диагностика окружения перед конвертацией.
+
+Проверяет наличие и версию vips, поддержку форматов webp/avif/heic/jxl,
+доступность БД на запись, количество CPU и доступную память.
+Завершается с ошибкой, если отсутствует что-то критичное (vips или доступ к БД).
+
+Примеры:
+  photoconverter doctor
+  photoconverter doctor --out ./converted
+  photoconverter doctor --vips-path /opt/vips/bin/vips --db ./state.sqlite`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dbPath == "" {
+				dbPath = filepath.Join(outDir, ".photoconverter", "state.sqlite")
+			}
+
+			if !runDoctor(cmd.OutOrStdout(), vipsPath, dbPath) {
+				return fmt.Errorf("обнаружены критические проблемы")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&vipsPath, "vips-path", "", "Путь к бинарнику vips (по умолчанию автопоиск)")
+	cmd.Flags().StringVar(&dbPath, "db", "", "Путь к SQLite базе (по умолчанию <out>/.photoconverter/state.sqlite)")
+	cmd.Flags().StringVar(&outDir, "out", ".", "Выходная директория (для пути к БД по умолчанию)")
+
+	return cmd
+}
+
+// runDoctor выполняет проверки и печатает чек-лист.
+// Возвращает false, если найдены критические проблемы.
+func runDoctor(w io.Writer, vipsPath, dbPath string) bool {
+	ok := true
+
+	fmt.Fprintln(w, "🩺 Диагностика окружения:")
+	fmt.Fprintln(w)
+
+	// vips
+	info, err := vipsfinder.NewFinder(vipsPath).Find()
+	if err != nil {
+		fmt.Fprintf(w, "❌ vips: %v\n", err)
+		ok = false
+	} else {
+		fmt.Fprintf(w, "✅ vips: %s (версия %s)\n", info.Path, info.Version)
+
+		// Форматы
+		if _, err := info.GetSupportedFormats(); err != nil {
+			fmt.Fprintf(w, "⚠️  форматы: не удалось получить список: %v\n", err)
+		} else {
+			for _, format := range doctorFormats {
+				if info.SupportsFormat(format) {
+					fmt.Fprintf(w, "✅ формат %s: поддерживается\n", format)
+				} else {
+					fmt.Fprintf(w, "❌ формат %s: не поддерживается этой сборкой libvips\n", format)
+				}
+			}
+		}
+	}
+
+	// vipsheader (опционально, для проверки результатов)
+	foundVips := ""
+	if err == nil {
+		foundVips = info.Path
+	}
+	if path := converter.FindVipsheader(foundVips); path != "" {
+		fmt.Fprintf(w, "✅ vipsheader: %s\n", path)
+	} else {
+		fmt.Fprintln(w, "⚠️  vipsheader: не найден (результаты проверяются только на непустой размер)")
+	}
+
+	// exiftool (опционально)
+	if path := converter.FindExiftool(); path != "" {
+		fmt.Fprintf(w, "✅ exiftool: %s\n", path)
+	} else {
+		fmt.Fprintln(w, "⚠️  exiftool: не найден (нужен для --strip-gps и --copy-metadata)")
+	}
+
+	// БД
+	if err := checkWritable(filepath.Dir(dbPath)); err != nil {
+		fmt.Fprintf(w, "❌ БД %s: нет доступа на запись: %v\n", dbPath, err)
+		ok = false
+	} else {
+		fmt.Fprintf(w, "✅ БД %s: доступна на запись\n", dbPath)
+	}
+
+	// Ресурсы
+	fmt.Fprintf(w, "✅ CPU: %d\n", runtime.NumCPU())
+	if mem, err := worker.ReadSystemMemory(); err != nil {
+		fmt.Fprintf(w, "⚠️  память: не удалось определить: %v\n", err)
+	} else {
+		fmt.Fprintf(w, "✅ память: %s доступно из %s\n",
+			worker.FormatBytes(int64(mem.Available)), worker.FormatBytes(int64(mem.Total)))
+	}
+
+	fmt.Fprintln(w)
+	if ok {
+		fmt.Fprintln(w, "Всё готово к работе.")
+	} else {
+		fmt.Fprintln(w, "Обнаружены критические проблемы.")
+	}
+	return ok
+}
+
+// checkWritable проверяет, что в директорию можно записывать.
+// Если директории ещё нет, проверяется ближайшая существующая родительская
+// (сама директория будет создана при первом запуске конвертации).
+func checkWritable(dir string) error {
+	for {
+		st, err := os.Stat(dir)
+		if err == nil {
+			if !st.IsDir() {
+				return fmt.Errorf("%s не является директорией", dir)
+			}
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+	}
+
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	_ = f.Close()
+	return os.Remove(name)
+}
+
+/*
+Возможные расширения:
+- Добавить вывод в JSON для CI
+- Проверять свободное место на диске выходной директории
+*/
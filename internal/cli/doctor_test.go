@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeVipsScript имитирует vips с поддержкой webp и heif (.heic/.avif), но без jxl.
+// Использует только встроенные команды shell: тесты подменяют PATH.
+const fakeVipsScript = `#!/bin/sh
+case "$1" in
+--version) echo "vips-8.15.1" ;;
+-l)
+  echo "      VipsForeignSaveJpegFile (jpegsave), save image to jpeg file (.jpg, .jpeg, .jpe), priority=0, mono rgb cmyk"
+  echo "      VipsForeignSaveWebpFile (webpsave), save as WebP (.webp), priority=0, rgb alpha"
+  echo "      VipsForeignSaveHeifFile (heifsave), save image in HEIF format (.heic, .heif, .avif), priority=0, rgb alpha"
+  ;;
+esac
+`
+
+// writeFakeVips создаёт исполняемый скрипт-заглушку vips.
+func writeFakeVips(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "vips")
+	if err := os.WriteFile(path, []byte(fakeVipsScript), 0755); err != nil {
+		t.Fatalf("не удалось создать fake vips: %v", err)
+	}
+	return path
+}
+
+// isolateEnv изолирует тест от установленных vips/exiftool и кэша пользователя.
+func isolateEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("PHOTOCONVERTER_VIPS", "")
+	t.Setenv("PHOTOCONVERTER_EXIFTOOL", "")
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+}
+
+func TestDoctor_FakeVips(t *testing.T) {
+	isolateEnv(t)
+
+	var out bytes.Buffer
+	cmd := newDoctorCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--vips-path", writeFakeVips(t), "--db", filepath.Join(t.TempDir(), "db", "state.sqlite")})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("doctor: %v\n%s", err, out.String())
+	}
+
+	got := out.String()
+	for _, line := range []string{
+		"✅ vips: ",
+		"(версия 8.15.1)",
+		"✅ формат webp: поддерживается",
+		"✅ формат avif: поддерживается",
+		"✅ формат heic: поддерживается",
+		"❌ формат jxl: не поддерживается этой сборкой libvips",
+		"⚠️  exiftool: не найден",
+		"доступна на запись",
+		"✅ CPU: ",
+	} {
+		if !strings.Contains(got, line) {
+			t.Errorf("в выводе нет %q:\n%s", line, got)
+		}
+	}
+}
+
+func TestDoctor_MissingVips(t *testing.T) {
+	isolateEnv(t)
+
+	var out bytes.Buffer
+	cmd := newDoctorCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"--vips-path", filepath.Join(t.TempDir(), "nope"), "--db", filepath.Join(t.TempDir(), "state.sqlite")})
+
+	if err := cmd.Execute(); err == nil {
+		t.Fatal("doctor без vips должен завершаться с ошибкой")
+	}
+	if !strings.Contains(out.String(), "❌ vips: ") {
+		t.Errorf("в выводе нет строки об отсутствии vips:\n%s", out.String())
+	}
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/artemshloyda/photoconverter/internal/storage"
+)
+
+// applyIgnores снимает пометки игнорирования (--clear-ignored) и помечает
+// игнорируемыми исходники из --ignore-file до начала обработки.
+func applyIgnores(store *storage.Storage) error {
+	if cfg.ClearIgnored {
+		cleared, err := store.ClearIgnored()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "🧹 Снято пометок игнорирования: %d\n", cleared)
+	}
+	if cfg.IgnoreFile == "" {
+		return nil
+	}
+
+	paths, err := readIgnoreFile(cfg.IgnoreFile)
+	if err != nil {
+		return err
+	}
+	added, err := store.IgnoreSources(paths)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "🚫 Игнорируемых исходников: +%d (в списке %d)\n", added, len(paths))
+	return nil
+}
+
+// readIgnoreFile читает список игнорируемых исходников: по одному пути
+// на строку, относительные пути - от текущей директории. Пустые строки
+// и строки, начинающиеся с #, пропускаются.
+func readIgnoreFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть --ignore-file: %w", err)
+	}
+	defer f.Close()
+
+	var paths []string
+	lines := bufio.NewScanner(f)
+	lines.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		abs, err := filepath.Abs(line)
+		if err != nil {
+			return nil, fmt.Errorf("--ignore-file: %s: %w", line, err)
+		}
+		paths = append(paths, abs)
+	}
+	if err := lines.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать --ignore-file: %w", err)
+	}
+	return paths, nil
+}
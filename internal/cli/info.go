@@ -0,0 +1,96 @@
+// Package cli содержит команду просмотра сведений об изображении.
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/artemshloyda/photoconverter/internal/converter"
+	"github.com/artemshloyda/photoconverter/internal/vipsfinder"
+)
+
+// newInfoCmd создаёт команду info.
+func newInfoCmd() *cobra.Command {
+	var (
+		vipsPath   string
+		jsonOutput bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "info <file>...",
+		Aliases: []string{"probe"},
+		Short:   "Показать сведения об изображении без конвертации",
+		Long: `Читает заголовок изображения через vipsheader (рядом с найденным vips)
+и выводит размеры, формат, число каналов, цветовое пространство, наличие
+альфа-канала, число страниц и встроенный ICC профиль. Пиксели не декодируются.
+
+Примеры:
+  photoconverter info photo.heic
+  photoconverter info --json ./photos/*.jpg`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vips, err := vipsfinder.NewFinder(vipsPath).Find()
+			if err != nil {
+				return err
+			}
+			vipsheader := converter.FindVipsheader(vips.Path)
+			if vipsheader == "" {
+				return fmt.Errorf("vipsheader не найден рядом с %s и в PATH", vips.Path)
+			}
+
+			infos := make([]*converter.ImageInfo, 0, len(args))
+			for _, path := range args {
+				info, err := converter.ReadImageInfo(cmd.Context(), vipsheader, path)
+				if err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+				infos = append(infos, info)
+			}
+
+			if jsonOutput {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				if len(infos) == 1 {
+					return enc.Encode(infos[0])
+				}
+				return enc.Encode(infos)
+			}
+			for _, info := range infos {
+				printImageInfo(cmd.OutOrStdout(), info)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&vipsPath, "vips-path", "", "Путь к бинарнику vips (по умолчанию автопоиск)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Вывести сведения в JSON")
+
+	return cmd
+}
+
+// printImageInfo печатает сведения об изображении.
+func printImageInfo(w io.Writer, info *converter.ImageInfo) {
+	alpha := "нет"
+	if info.HasAlpha {
+		alpha = "есть"
+	}
+	profile := "нет"
+	if info.ProfileSize > 0 {
+		profile = fmt.Sprintf("есть (%d байт)", info.ProfileSize)
+	}
+
+	fmt.Fprintf(w, "📄 %s\n", info.Path)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  Размеры:\t%dx%d\n", info.Width, info.Height)
+	fmt.Fprintf(tw, "  Формат:\t%s (%s)\n", dashIfEmpty(info.Format), dashIfEmpty(info.BandFormat))
+	fmt.Fprintf(tw, "  Каналы:\t%d\n", info.Bands)
+	fmt.Fprintf(tw, "  Цветовое пространство:\t%s\n", dashIfEmpty(info.Interpretation))
+	fmt.Fprintf(tw, "  Альфа-канал:\t%s\n", alpha)
+	fmt.Fprintf(tw, "  Страниц:\t%d\n", info.Pages)
+	fmt.Fprintf(tw, "  ICC профиль:\t%s\n", profile)
+	_ = tw.Flush()
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/converter"
+)
+
+// fakeVipsheaderScript имитирует vipsheader -a для HEIC 4032x3024 с ICC профилем.
+const fakeVipsheaderScript = `#!/bin/sh
+echo "$2: 4032x3024 uchar, 3 bands, srgb, heifload"
+echo "width: 4032"
+echo "height: 3024"
+echo "bands: 3"
+echo "format: uchar"
+echo "coding: none"
+echo "interpretation: srgb"
+echo "vips-loader: heifload"
+echo "n-pages: 2"
+echo "icc-profile-data: 560 bytes of binary data"
+`
+
+func TestInfo_FakeVipsheader(t *testing.T) {
+	isolateEnv(t)
+	vips := writeFakeVips(t)
+	if err := os.WriteFile(filepath.Join(filepath.Dir(vips), "vipsheader"), []byte(fakeVipsheaderScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	cmd := newInfoCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--vips-path", vips, "photo.heic"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("info: %v", err)
+	}
+	for _, want := range []string{"📄 photo.heic", "4032x3024", "heif (uchar)", "srgb", "Альфа-канал:", "Страниц:", "есть (560 байт)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("вывод не содержит %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	cmd = newInfoCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--vips-path", vips, "--json", "photo.heic"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("info --json: %v", err)
+	}
+	var info converter.ImageInfo
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+		t.Fatalf("некорректный JSON %q: %v", out.String(), err)
+	}
+	want := converter.ImageInfo{Path: "photo.heic", Width: 4032, Height: 3024, Format: "heif", BandFormat: "uchar",
+		Bands: 3, Interpretation: "srgb", Pages: 2, ProfileSize: 560}
+	if info != want {
+		t.Errorf("info = %+v, want %+v", info, want)
+	}
+}
+
+func TestInfo_RealVips(t *testing.T) {
+	vips, err := exec.LookPath("vips")
+	if err != nil {
+		t.Skip("vips не установлен")
+	}
+	if converter.FindVipsheader(vips) == "" {
+		t.Skip("vipsheader не установлен")
+	}
+
+	path := filepath.Join(t.TempDir(), "alpha.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 64, 48))); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	cmd := newInfoCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--vips-path", vips, "--json", path})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("info: %v", err)
+	}
+	var info converter.ImageInfo
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+		t.Fatalf("некорректный JSON %q: %v", out.String(), err)
+	}
+	if info.Width != 64 || info.Height != 48 || info.Format != "png" || !info.HasAlpha {
+		t.Errorf("info = %+v, want 64x48 png с альфа-каналом", info)
+	}
+}
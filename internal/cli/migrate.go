@@ -0,0 +1,89 @@
+// Package cli содержит команду миграции схемы БД.
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/artemshloyda/photoconverter/internal/storage"
+)
+
+// newMigrateCmd создаёт команду migrate.
+func newMigrateCmd() *cobra.Command {
+	var (
+		dbPath   string
+		target   int
+		noBackup bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Перевести схему базы данных на указанную версию",
+		Long: `Применяет или откатывает миграции схемы БД до версии --to
+(по умолчанию - последняя). Нужен, например, перед возвратом на старую
+версию программы: она не открывает БД с более новой схемой.
+
+Откат удаляет добавленные колонки вместе с данными, поэтому перед
+изменением схемы создаётся резервная копия <db>.bak (кроме --no-backup).
+
+Примеры:
+  photoconverter migrate --db ./out/.photoconverter/state.sqlite --to 1
+  photoconverter migrate --db ./state.sqlite`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if target == 0 {
+				target = storage.SchemaVersion()
+			}
+			if target < 1 || target > storage.SchemaVersion() {
+				return fmt.Errorf("неизвестная версия схемы %d (доступны 1..%d)", target, storage.SchemaVersion())
+			}
+			if _, err := os.Stat(dbPath); err != nil {
+				return fmt.Errorf("БД не найдена: %w", err)
+			}
+
+			store, err := storage.NewWithOptions(dbPath, storage.Options{NoMigrate: true})
+			if err != nil {
+				return fmt.Errorf("не удалось открыть БД: %w", err)
+			}
+			defer func() { _ = store.Close() }()
+
+			current, err := store.CurrentSchemaVersion()
+			if err != nil {
+				return fmt.Errorf("не удалось прочитать версию схемы: %w", err)
+			}
+			out := cmd.OutOrStdout()
+			if current == target {
+				fmt.Fprintf(out, "Схема уже версии %d\n", current)
+				return nil
+			}
+
+			if !noBackup {
+				backupPath, err := store.Backup()
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "💾 Резервная копия БД: %s\n", backupPath)
+			}
+
+			if err := store.MigrateTo(target); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Схема переведена с версии %d на %d\n", current, target)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dbPath, "db", "", "Путь к SQLite базе данных")
+	cmd.Flags().IntVar(&target, "to", 0, "Целевая версия схемы (0 = последняя)")
+	cmd.Flags().BoolVar(&noBackup, "no-backup", false, "Не создавать резервную копию перед изменением схемы")
+	_ = cmd.MarkFlagRequired("db")
+
+	return cmd
+}
+
+/*
+Возможные расширения:
+- Вывод списка версий и их описаний (--list)
+- Пробный прогон без изменений (--dry-run)
+*/
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/storage"
+)
+
+// runMigrate выполняет команду migrate и возвращает её вывод.
+func runMigrate(t *testing.T, args ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	cmd := newMigrateCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("migrate %v: %v", args, err)
+	}
+	return out.String()
+}
+
+func TestMigrate_RollbackAndUpgrade(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "state.sqlite")
+	store, err := storage.New(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+
+	out := runMigrate(t, "--db", dbPath, "--to", "1")
+	if !strings.Contains(out, "на 1") {
+		t.Errorf("вывод отката: %q", out)
+	}
+	if _, err := os.Stat(dbPath + storage.BackupSuffix); err != nil {
+		t.Errorf("резервная копия перед откатом: %v", err)
+	}
+
+	// Откатывать уже нечего
+	if out := runMigrate(t, "--db", dbPath, "--to", "1"); !strings.Contains(out, "уже версии 1") {
+		t.Errorf("повторный откат: %q", out)
+	}
+
+	// Без --to схема поднимается до последней версии
+	runMigrate(t, "--db", dbPath, "--no-backup")
+	store, err = storage.NewWithOptions(dbPath, storage.Options{NoMigrate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+	if v, _ := store.CurrentSchemaVersion(); v != storage.SchemaVersion() {
+		t.Errorf("версия после подъёма = %d, want %d", v, storage.SchemaVersion())
+	}
+}
+
+func TestMigrate_MissingDB(t *testing.T) {
+	cmd := newMigrateCmd()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--db", filepath.Join(t.TempDir(), "none.sqlite")})
+	if err := cmd.Execute(); err == nil {
+		t.Error("ожидалась ошибка для несуществующей БД")
+	}
+}
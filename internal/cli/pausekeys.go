@@ -0,0 +1,88 @@
+// Package cli содержит управление паузой с клавиатуры.
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/term"
+
+	"github.com/artemshloyda/photoconverter/internal/progress"
+	"github.com/artemshloyda/photoconverter/internal/worker"
+)
+
+// pauseKeysEnabled сообщает, можно ли управлять паузой с клавиатуры:
+// stdin - терминал и не занят списком файлов, прогресс выводится баром
+// (без --quiet).
+func pauseKeysEnabled() bool {
+	return !cfg.NoProgress &&
+		!cfg.Quiet() &&
+		cfg.ProgressFormat != progress.FormatJSON &&
+		cfg.FromFile != "-" &&
+		term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// startPauseKeys переключает паузу пула по пробелу или клавише p.
+// Терминал переводится в посимвольный режим без эха; возвращённая функция
+// останавливает чтение stdin и восстанавливает терминал. Чтение также
+// прекращается при отмене ctx. Начатые конвертации на паузе завершаются,
+// новые файлы не берутся.
+func startPauseKeys(ctx context.Context, pool *worker.Pool) (stop func()) {
+	if !pauseKeysEnabled() {
+		return func() {}
+	}
+
+	restore, err := setCbreak(int(os.Stdin.Fd()))
+	cbreak := err == nil
+	if !cbreak {
+		restore = func() {}
+	}
+	fmt.Fprintln(stdout, "⏯️  Пробел - пауза/продолжение")
+
+	ctx, cancel := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		readPauseKeys(ctx, os.Stdin, cbreak, func() { pool.TogglePause() })
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel()
+			// В посимвольном режиме чтение возвращается по таймауту, поэтому
+			// читатель завершается до восстановления терминала и не забирает
+			// ввод после остановки. Без него Read ждёт Enter - не ждём
+			if cbreak {
+				<-stopped
+			}
+			restore()
+		})
+	}
+}
+
+// readPauseKeys читает клавиши из r и вызывает toggle на пробел или p, пока
+// не отменён ctx. С timeouts пустое чтение (io.EOF) - таймаут посимвольного
+// режима терминала: проверяется ctx и чтение продолжается.
+func readPauseKeys(ctx context.Context, r io.Reader, timeouts bool, toggle func()) {
+	buf := make([]byte, 1)
+	for ctx.Err() == nil {
+		n, err := r.Read(buf)
+		if n == 1 && (buf[0] == ' ' || buf[0] == 'p' || buf[0] == 'P') {
+			toggle()
+		}
+		if err != nil && !(timeouts && errors.Is(err, io.EOF)) {
+			return
+		}
+	}
+}
+
+/*
+Возможные расширения:
+- Клавиши для изменения числа воркеров на лету
+- Вывод текущих файлов по клавише
+*/
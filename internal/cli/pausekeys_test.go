@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+// keyReader имитирует терминал в посимвольном режиме: отдаёт keys по одному
+// байту, затем пустые чтения (io.EOF) по таймауту.
+type keyReader struct {
+	keys []byte
+}
+
+func (r *keyReader) Read(buf []byte) (int, error) {
+	if len(r.keys) == 0 {
+		time.Sleep(time.Millisecond)
+		return 0, io.EOF
+	}
+	buf[0] = r.keys[0]
+	r.keys = r.keys[1:]
+	return 1, nil
+}
+
+func TestReadPauseKeys(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	toggles := make(chan struct{}, 10)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		readPauseKeys(ctx, &keyReader{keys: []byte(" xpP\n")}, true, func() { toggles <- struct{}{} })
+	}()
+
+	// Пробел, p и P переключают паузу, остальные клавиши игнорируются
+	for i := 0; i < 3; i++ {
+		select {
+		case <-toggles:
+		case <-time.After(time.Second):
+			t.Fatalf("переключений %d, want 3", i)
+		}
+	}
+
+	// Таймауты чтения не завершают читатель, отмена контекста - завершает
+	select {
+	case <-stopped:
+		t.Fatal("читатель завершился до отмены контекста")
+	case <-time.After(20 * time.Millisecond):
+	}
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("читатель не остановлен после отмены контекста")
+	}
+	if len(toggles) != 0 {
+		t.Errorf("лишних переключений: %d", len(toggles))
+	}
+
+	// Без посимвольного режима io.EOF - конец ввода
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		readPauseKeys(context.Background(), &keyReader{}, false, func() {})
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("читатель не завершился на конце ввода")
+	}
+}
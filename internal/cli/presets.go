@@ -3,7 +3,10 @@ package cli
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
@@ -32,12 +35,18 @@ func newPresetsCmd() *cobra.Command {
   photoconverter presets list
 
   # Удалить пресет
-  photoconverter presets delete my-project`,
+  photoconverter presets delete my-project
+
+  # Перенести пресет на другую машину
+  photoconverter presets export my-project > preset.yaml
+  photoconverter presets import preset.yaml --name my-project`,
 	}
 
 	cmd.AddCommand(newPresetsListCmd())
 	cmd.AddCommand(newPresetsDeleteCmd())
 	cmd.AddCommand(newPresetsShowCmd())
+	cmd.AddCommand(newPresetsExportCmd())
+	cmd.AddCommand(newPresetsImportCmd())
 
 	return cmd
 }
@@ -158,7 +167,7 @@ func newPresetsShowCmd() *cobra.Command {
 
 			if fc.Processing != nil {
 				fmt.Println("Processing:")
-				if fc.Processing.Workers > 0 {
+				if fc.Processing.Workers != 0 {
 					fmt.Printf("  workers: %d\n", fc.Processing.Workers)
 				}
 				if fc.Processing.Mode != "" {
@@ -174,9 +183,98 @@ func newPresetsShowCmd() *cobra.Command {
 	}
 }
 
+// newPresetsExportCmd создаёт команду для экспорта пресета.
+func newPresetsExportCmd() *cobra.Command {
+	var outPath string
+
+	cmd := &cobra.Command{
+		Use:   "export [name]",
+		Short: "Выгрузить пресет в stdout или файл",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outPath == "" {
+				return config.ExportPreset(args[0], cmd.OutOrStdout())
+			}
+
+			f, err := os.Create(outPath)
+			if err != nil {
+				return fmt.Errorf("не удалось создать файл: %w", err)
+			}
+			if err := config.ExportPreset(args[0], f); err != nil {
+				_ = f.Close()
+				_ = os.Remove(outPath)
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return fmt.Errorf("не удалось записать файл: %w", err)
+			}
+
+			fmt.Fprintf(cmd.ErrOrStderr(), "✅ Пресет '%s' выгружен в %s\n", args[0], outPath)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&outPath, "output", "o", "", "Путь к файлу (по умолчанию stdout)")
+
+	return cmd
+}
+
+// newPresetsImportCmd создаёт команду для импорта пресета.
+func newPresetsImportCmd() *cobra.Command {
+	var (
+		name  string
+		force bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import [file]",
+		Short: "Загрузить пресет из файла (- для stdin)",
+		Long: `Проверяет файл пресета и копирует его в директорию пресетов.
+
+Имя берётся из --name или из имени файла; недопустимые символы
+отбрасываются. Существующий пресет перезаписывается только с --force.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			src := args[0]
+
+			var (
+				data []byte
+				err  error
+			)
+			if src == "-" {
+				data, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				data, err = os.ReadFile(src)
+			}
+			if err != nil {
+				return fmt.Errorf("не удалось прочитать пресет: %w", err)
+			}
+
+			if name == "" {
+				if src == "-" {
+					return fmt.Errorf("при чтении из stdin укажите --name")
+				}
+				base := filepath.Base(src)
+				name = strings.TrimSuffix(base, filepath.Ext(base))
+			}
+
+			path, err := config.ImportPreset(name, data, force)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "✅ Пресет импортирован: %s\n", path)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "Имя пресета (по умолчанию - имя файла)")
+	cmd.Flags().BoolVar(&force, "force", false, "Перезаписать существующий пресет")
+
+	return cmd
+}
+
 /*
 Возможные расширения:
-- Добавить команду 'presets export' для экспорта в файл
-- Добавить команду 'presets import' для импорта из файла
 - Добавить команду 'presets copy' для копирования пресета
 */
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// runPresets выполняет команду presets и возвращает stdout.
+func runPresets(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	cmd := newPresetsCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs(args)
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestPresets_ExportImportRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	src := config.DefaultConfig()
+	src.OutputFormat = "avif"
+	src.Quality = 55
+	src.Workers = 3
+	src.MaxWidth = 1600
+	if _, err := config.SavePreset("orig", src); err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := runPresets(t, "export", "orig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "preset.yaml")
+	if err := os.WriteFile(file, []byte(exported), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Недопустимые символы в имени отбрасываются
+	if _, err := runPresets(t, "import", file, "--name", "copy/of orig"); err != nil {
+		t.Fatal(err)
+	}
+
+	want, _, err := config.LoadPreset("orig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, path, err := config.LoadPreset("copyoforig")
+	if err != nil || got == nil {
+		t.Fatalf("импортированный пресет не загружается: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("пресет после export/import:\n%+v\nwant\n%+v", got, want)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != exported {
+		t.Error("содержимое файла пресета изменилось при импорте")
+	}
+
+	// Перезапись только с --force
+	if _, err := runPresets(t, "import", file, "--name", "copyoforig"); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("повторный импорт без --force: %v, want ошибку", err)
+	}
+	if _, err := runPresets(t, "import", file, "--name", "copyoforig", "--force"); err != nil {
+		t.Errorf("импорт с --force: %v", err)
+	}
+
+	// Экспорт в файл через --output
+	outFile := filepath.Join(t.TempDir(), "out.yaml")
+	if _, err := runPresets(t, "export", "orig", "-o", outFile); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(outFile); string(data) != exported {
+		t.Error("export -o записал другое содержимое")
+	}
+}
+
+func TestPresets_ImportRejectsInvalid(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+
+	for name, data := range map[string]string{
+		"unknown-key": "output:\n  qualty: 80\n",
+		"bad-quality": "output:\n  quality: 500\n",
+		"bad-mode":    "processing:\n  mode: fast\n",
+	} {
+		file := filepath.Join(dir, name+".yaml")
+		if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := runPresets(t, "import", file); err == nil {
+			t.Errorf("%s: импорт некорректного пресета должен вернуть ошибку", name)
+		}
+		if config.PresetExists(name) {
+			t.Errorf("%s: некорректный пресет сохранён", name)
+		}
+	}
+
+	if _, err := runPresets(t, "export", "missing"); err == nil {
+		t.Error("export несуществующего пресета должен вернуть ошибку")
+	}
+}
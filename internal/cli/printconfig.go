@@ -0,0 +1,41 @@
+// Package cli содержит вывод итоговой конфигурации (--print-config).
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// printConfigFormat - формат вывода итоговой конфигурации (пусто = не выводить).
+var printConfigFormat string
+
+// printConfig выводит конфигурацию после применения пресета, конфиг файла
+// и CLI флагов в формате json или yaml. Длительности выводятся в наносекундах.
+func printConfig(w io.Writer, c *config.Config, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(c)
+	case "yaml":
+		data, err := yaml.Marshal(c)
+		if err != nil {
+			return fmt.Errorf("ошибка сериализации конфигурации: %w", err)
+		}
+		_, err = w.Write(data)
+		return err
+	default:
+		return fmt.Errorf("неизвестный формат --print-config: %s (доступны: json, yaml)", format)
+	}
+}
+
+/*
+Возможные расширения:
+- Пометка источника каждого значения (флаг, конфиг файл, пресет, по умолчанию)
+- Вывод в формате конфиг файла (--save-config в stdout)
+*/
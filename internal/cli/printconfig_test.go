@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// runPrintConfig выполняет корневую команду с чистой конфигурацией и возвращает вывод.
+func runPrintConfig(t *testing.T, args ...string) string {
+	t.Helper()
+	oldCfg, oldFormat, oldConfigPath := cfg, printConfigFormat, configPath
+	cfg, printConfigFormat, configPath = config.DefaultConfig(), "", ""
+	t.Cleanup(func() { cfg, printConfigFormat, configPath = oldCfg, oldFormat, oldConfigPath })
+
+	var out bytes.Buffer
+	cmd := NewRootCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("%v: %v", args, err)
+	}
+	return out.String()
+}
+
+func TestPrintConfig_CLIOverridesFile(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "photoconverter.yaml")
+	data := "output:\n  quality: 50\n  format: jpg\nprocessing:\n  workers: 3\n"
+	if err := os.WriteFile(configFile, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out")
+
+	dump := runPrintConfig(t, "--config", configFile, "--in", dir, "--out", out, "--quality", "90", "--print-config")
+
+	var got config.Config
+	if err := json.Unmarshal([]byte(dump), &got); err != nil {
+		t.Fatalf("вывод не является JSON: %v\n%s", err, dump)
+	}
+	if got.Quality != 90 {
+		t.Errorf("Quality = %d, want 90 (CLI важнее конфиг файла)", got.Quality)
+	}
+	if got.OutputFormat != "jpg" || got.Workers != 3 {
+		t.Errorf("значения из конфиг файла: format=%s workers=%d, want jpg/3", got.OutputFormat, got.Workers)
+	}
+	if want := filepath.Join(out, ".photoconverter", "state.sqlite"); got.DBPath != want {
+		t.Errorf("DBPath = %q, want %q (путь по умолчанию)", got.DBPath, want)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("--print-config не должен запускать конвертацию: %v", err)
+	}
+}
+
+func TestPrintConfig_YAML(t *testing.T) {
+	dir := t.TempDir()
+	dump := runPrintConfig(t, "--in", dir, "--out", dir, "--print-config=yaml")
+	if !strings.Contains(dump, "quality: ") || strings.HasPrefix(strings.TrimSpace(dump), "{") {
+		t.Errorf("ожидался YAML, получено:\n%s", dump)
+	}
+}
+
+func TestPrintConfig_EffectFlagsOverrideFile(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "photoconverter.yaml")
+	if err := os.WriteFile(configFile, []byte("output:\n  blur: 5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var got config.Config
+	dump := runPrintConfig(t, "--config", configFile, "--in", dir, "--out", dir, "--blur", "2", "--print-config")
+	if err := json.Unmarshal([]byte(dump), &got); err != nil {
+		t.Fatalf("вывод не является JSON: %v\n%s", err, dump)
+	}
+	if got.Blur != 2 {
+		t.Errorf("Blur = %v, want 2 (CLI важнее конфиг файла)", got.Blur)
+	}
+
+	if err := os.WriteFile(configFile, []byte("output:\n  pixelate: 16\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got = config.Config{}
+	dump = runPrintConfig(t, "--config", configFile, "--in", dir, "--out", dir, "--pixelate", "4", "--print-config")
+	if err := json.Unmarshal([]byte(dump), &got); err != nil {
+		t.Fatalf("вывод не является JSON: %v\n%s", err, dump)
+	}
+	if got.Pixelate != 4 {
+		t.Errorf("Pixelate = %d, want 4 (CLI важнее конфиг файла)", got.Pixelate)
+	}
+}
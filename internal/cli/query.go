@@ -0,0 +1,186 @@
+// Package cli содержит команду просмотра записей задач в БД.
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/artemshloyda/photoconverter/internal/storage"
+)
+
+// jobRecord - запись задачи для вывода командой query.
+type jobRecord struct {
+	ID            int64      `json:"id"`
+	Status        string     `json:"status"`
+	Src           string     `json:"src"`
+	SrcSize       int64      `json:"src_size"`
+	SrcMtime      int64      `json:"src_mtime"`
+	Dst           string     `json:"dst,omitempty"`
+	DstSize       *int64     `json:"dst_size,omitempty"`
+	Format        string     `json:"format"`
+	Params        string     `json:"params"`
+	ParamsHash    string     `json:"params_hash"`
+	ContentSHA256 string     `json:"content_sha256,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+}
+
+// newJobRecord преобразует задачу из БД в запись для вывода.
+func newJobRecord(job storage.Job) jobRecord {
+	r := jobRecord{
+		ID:         job.ID,
+		Status:     string(job.Status),
+		Src:        job.SrcPath,
+		SrcSize:    job.SrcSize,
+		SrcMtime:   job.SrcMtime,
+		DstSize:    job.DstSize,
+		Format:     job.OutFormat,
+		Params:     job.OutParams,
+		ParamsHash: job.OutParamsHash,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	if job.DstPath != nil {
+		r.Dst = *job.DstPath
+	}
+	if job.ContentSHA256 != nil {
+		r.ContentSHA256 = *job.ContentSHA256
+	}
+	if job.Error != nil {
+		r.Error = *job.Error
+	}
+	return r
+}
+
+// newQueryCmd создаёт команду query.
+func newQueryCmd() *cobra.Command {
+	var (
+		dbPath, src, status string
+		jsonOutput          bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "query",
+		Short: "Показать записи задач из базы данных",
+		Long: `Выводит сохранённые записи задач: статус, выходной файл, параметры,
+ошибку и время. Фильтры --src и --status можно сочетать.
+
+Примеры:
+  photoconverter query --db ./out/.photoconverter/state.sqlite --src ./photos/IMG_0001.jpg
+  photoconverter query --db ./state.sqlite --status failed
+  photoconverter query --db ./state.sqlite --status failed --json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if src == "" && status == "" {
+				return fmt.Errorf("укажите --src или --status")
+			}
+			switch storage.JobStatus(status) {
+			case "", storage.StatusOK, storage.StatusFailed, storage.StatusInProgress:
+			default:
+				return fmt.Errorf("неизвестный статус: %s (доступны: ok, failed, in_progress)", status)
+			}
+
+			store, err := storage.New(dbPath)
+			if err != nil {
+				return fmt.Errorf("не удалось открыть БД: %w", err)
+			}
+			defer func() { _ = store.Close() }()
+
+			jobs, err := queryJobs(store, src, storage.JobStatus(status))
+			if err != nil {
+				return err
+			}
+
+			records := make([]jobRecord, 0, len(jobs))
+			for _, job := range jobs {
+				records = append(records, newJobRecord(job))
+			}
+
+			if jsonOutput {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(records)
+			}
+			printJobTable(cmd.OutOrStdout(), records)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dbPath, "db", "", "Путь к SQLite базе данных")
+	cmd.Flags().StringVar(&src, "src", "", "Исходный файл (путь приводится к абсолютному, как в БД)")
+	cmd.Flags().StringVar(&status, "status", "", "Только задачи со статусом: ok, failed, in_progress")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Вывести записи в JSON")
+	_ = cmd.MarkFlagRequired("db")
+
+	return cmd
+}
+
+// queryJobs выбирает задачи по исходному файлу и/или статусу.
+func queryJobs(store *storage.Storage, src string, status storage.JobStatus) ([]storage.Job, error) {
+	if src == "" {
+		return store.ListJobsByStatus(status)
+	}
+
+	// Пути в БД абсолютные; записи из архивов (archive.zip!/file) сохраняются как есть
+	if abs, err := filepath.Abs(src); err == nil {
+		src = abs
+	}
+	jobs, err := store.FindJobsBySrc(src)
+	if err != nil || status == "" {
+		return jobs, err
+	}
+
+	filtered := jobs[:0]
+	for _, job := range jobs {
+		if job.Status == status {
+			filtered = append(filtered, job)
+		}
+	}
+	return filtered, nil
+}
+
+// printJobTable печатает записи таблицей.
+func printJobTable(w io.Writer, records []jobRecord) {
+	if len(records) == 0 {
+		fmt.Fprintln(w, "Записей не найдено")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tSRC\tDST\tFORMAT\tPARAMS\tSTARTED\tFINISHED\tERROR")
+	for _, r := range records {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.ID, r.Status, r.Src, dashIfEmpty(r.Dst), r.Format, r.Params,
+			formatJobTime(r.StartedAt), formatJobTime(r.FinishedAt), dashIfEmpty(strings.Join(strings.Fields(r.Error), " ")))
+	}
+	_ = tw.Flush()
+}
+
+// formatJobTime форматирует время задачи для таблицы.
+func formatJobTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// dashIfEmpty заменяет пустую строку прочерком.
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+/*
+Возможные расширения:
+- Фильтр по времени запуска (--since)
+- Поиск по выходному файлу (--dst)
+*/
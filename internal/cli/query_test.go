@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/storage"
+)
+
+// seedQueryDB создаёт БД с задачами: a.jpg (failed, затем ok с другими
+// параметрами), b.jpg (failed), c.jpg (in_progress).
+func seedQueryDB(t *testing.T) (dbPath, srcDir string) {
+	t.Helper()
+	dir := t.TempDir()
+	dbPath = filepath.Join(dir, "state.sqlite")
+	srcDir = filepath.Join(dir, "in")
+
+	store, err := storage.New(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	start := func(name, params string) int64 {
+		info := storage.FileInfo{Path: filepath.Join(srcDir, name), Size: 10, Mtime: 1}
+		res, err := store.TryStartJob(info, "webp", params, params+"-hash", false)
+		if err != nil || !res.Started {
+			t.Fatalf("TryStartJob(%s): %+v, %v", name, res, err)
+		}
+		return res.JobID
+	}
+
+	_ = store.FinalizeJobFailed(start("a.jpg", `{"q":80}`), "vips copy failed:\nVipsJpeg: premature end")
+	_ = store.FinalizeJobOK(start("a.jpg", `{"q":90}`), filepath.Join(dir, "out", "a.webp"), 5)
+	_ = store.FinalizeJobFailed(start("b.jpg", `{"q":80}`), "not a known file format")
+	start("c.jpg", `{"q":80}`)
+	return dbPath, srcDir
+}
+
+// runQuery выполняет команду query и возвращает её вывод.
+func runQuery(t *testing.T, args ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	cmd := newQueryCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("query %v: %v", args, err)
+	}
+	return out.String()
+}
+
+func TestQuery_BySrc(t *testing.T) {
+	dbPath, srcDir := seedQueryDB(t)
+
+	var records []jobRecord
+	out := runQuery(t, "--db", dbPath, "--src", filepath.Join(srcDir, "a.jpg"), "--json")
+	if err := json.Unmarshal([]byte(out), &records); err != nil {
+		t.Fatalf("JSON: %v\n%s", err, out)
+	}
+	if len(records) != 2 {
+		t.Fatalf("записей %d, want 2:\n%s", len(records), out)
+	}
+	if records[0].Status != "failed" || !strings.Contains(records[0].Error, "premature end") || records[0].Params != `{"q":80}` {
+		t.Errorf("первая запись: %+v", records[0])
+	}
+	if records[1].Status != "ok" || filepath.Base(records[1].Dst) != "a.webp" || records[1].StartedAt == nil {
+		t.Errorf("вторая запись: %+v", records[1])
+	}
+
+	// Фильтры сочетаются
+	out = runQuery(t, "--db", dbPath, "--src", filepath.Join(srcDir, "a.jpg"), "--status", "ok", "--json")
+	records = nil
+	if err := json.Unmarshal([]byte(out), &records); err != nil || len(records) != 1 || records[0].Status != "ok" {
+		t.Errorf("--src + --status ok: %v\n%s", err, out)
+	}
+}
+
+func TestQuery_StatusTable(t *testing.T) {
+	dbPath, _ := seedQueryDB(t)
+
+	out := runQuery(t, "--db", dbPath, "--status", "failed")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "ID") {
+		t.Fatalf("ожидались заголовок и две строки:\n%s", out)
+	}
+	if !strings.Contains(lines[1], "a.jpg") || !strings.Contains(lines[1], "vips copy failed: VipsJpeg: premature end") {
+		t.Errorf("строка a.jpg: %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "b.jpg") || !strings.Contains(lines[2], "not a known file format") {
+		t.Errorf("строка b.jpg: %q", lines[2])
+	}
+	if strings.Contains(out, "c.jpg") {
+		t.Errorf("in_progress задача не должна попасть в выборку failed:\n%s", out)
+	}
+}
+
+func TestQuery_Validation(t *testing.T) {
+	dbPath, _ := seedQueryDB(t)
+
+	for _, args := range [][]string{
+		{"--db", dbPath},
+		{"--db", dbPath, "--status", "done"},
+	} {
+		cmd := newQueryCmd()
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err == nil {
+			t.Errorf("query %v: ожидалась ошибка", args)
+		}
+	}
+}
@@ -3,22 +3,39 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/artemshloyda/photoconverter/internal/archive"
+	"github.com/artemshloyda/photoconverter/internal/cache"
 	"github.com/artemshloyda/photoconverter/internal/config"
 	"github.com/artemshloyda/photoconverter/internal/converter"
+	"github.com/artemshloyda/photoconverter/internal/distributed"
+	"github.com/artemshloyda/photoconverter/internal/dupes"
+	"github.com/artemshloyda/photoconverter/internal/estimate"
+	"github.com/artemshloyda/photoconverter/internal/httpserver"
+	"github.com/artemshloyda/photoconverter/internal/magickfinder"
+	"github.com/artemshloyda/photoconverter/internal/manifest"
+	"github.com/artemshloyda/photoconverter/internal/metrics"
 	"github.com/artemshloyda/photoconverter/internal/progress"
+	"github.com/artemshloyda/photoconverter/internal/runlog"
 	"github.com/artemshloyda/photoconverter/internal/scanner"
+	"github.com/artemshloyda/photoconverter/internal/status"
 	"github.com/artemshloyda/photoconverter/internal/storage"
 	"github.com/artemshloyda/photoconverter/internal/vipsfinder"
 	"github.com/artemshloyda/photoconverter/internal/watcher"
+	"github.com/artemshloyda/photoconverter/internal/webhook"
 	"github.com/artemshloyda/photoconverter/internal/worker"
 )
 
@@ -45,6 +62,34 @@ var savePresetName string
 // loadPresetName содержит имя пресета для загрузки.
 var loadPresetName string
 
+// shardSpec содержит часть файлов для обработки в виде K/N (--shard).
+var shardSpec string
+
+// formatMapPairs содержит соответствия расширение=формат (--map heic=jpg).
+var formatMapPairs []string
+
+// formatConcurrencyPairs содержит ограничения формат=число (--concurrency-per-format avif=2).
+var formatConcurrencyPairs []string
+
+// continueOnError - явный выбор поведения по умолчанию (противоположность --fail-fast).
+var continueOnError bool
+
+// statusReporter отдаёт JSON статус запуска (--status-addr), nil если выключен.
+var statusReporter *status.Reporter
+
+// stdout - вывод сообщений о ходе запуска (параметры, итоги); с --quiet -
+// io.Discard, ошибки и предупреждения по-прежнему выводятся в stderr.
+var stdout io.Writer = os.Stdout
+
+// humanOutput возвращает поток для сообщений и отчётов для человека: stderr,
+// если stdout занят машиночитаемым выводом (--worker-stdin, --progress-format json).
+func humanOutput() io.Writer {
+	if cfg.WorkerStdin || cfg.ProgressFormat == progress.FormatJSON {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
 // NewRootCmd создаёт корневую команду CLI.
 func NewRootCmd() *cobra.Command {
 	rootCmd := &cobra.Command{
@@ -74,34 +119,82 @@ func NewRootCmd() *cobra.Command {
 	flags := rootCmd.Flags()
 
 	// Входные параметры
-	flags.StringVar(&cfg.InputDir, "in", "", "Директория с исходными изображениями (обязательно)")
+	flags.StringVar(&cfg.InputDir, "in", "", "Директория с исходными изображениями или один файл (обязательно)")
 	flags.StringVar(&cfg.OutputDir, "out", "", "Директория для сохранения результатов (обязательно)")
 	flags.StringSliceVar(&cfg.InputExtensions, "in-ext", cfg.InputExtensions,
 		"Расширения входных файлов через запятую (например: jpg,png,heic)")
+	flags.BoolVar(&cfg.DetectByContent, "detect-by-content", false, "Принимать файлы с другим расширением, если формат по содержимому входит в --in-ext")
+	flags.StringSliceVar(&cfg.Include, "include", nil, "Glob-шаблоны включаемых файлов (например: \"2024/**\", \"*.heic\")")
+	flags.StringSliceVar(&cfg.Exclude, "exclude", nil, "Glob-шаблоны исключаемых файлов (например: \"**/raw/**\")")
+	flags.StringVar(&cfg.IgnoreFile, "ignore-file", "", "Файл со списком исходников, которые больше не конвертировать (по одному на строку, # - комментарий)")
+	flags.BoolVar(&cfg.ClearIgnored, "clear-ignored", false, "Снять все пометки игнорирования (--ignore-file) перед запуском")
+	flags.StringVar(&cfg.FromFile, "from-file", "", "Файл со списком входных путей, по одному на строку (\"-\" = stdin)")
 
 	// Выходные параметры
 	outFormat := flags.String("out-format", string(cfg.OutputFormat),
 		"Выходной формат: webp, jpg, png, avif, tiff, heic, jxl")
+	flags.StringVar(&cfg.OutputExtension, "out-ext", "", "Расширение выходных файлов вместо расширения формата (например jpeg для jpg, tif для tiff)")
+	flags.StringArrayVar(&formatMapPairs, "map", nil, "Выходной формат по расширению исходника: ext=format, можно повторять (например --map heic=jpg --map png=webp)")
 	flags.IntVar(&cfg.Quality, "quality", cfg.Quality, "Качество для lossy форматов (1-100)")
 	flags.BoolVar(&cfg.StripMetadata, "strip", cfg.StripMetadata, "Удалить метаданные из изображений")
 
 	// Resize параметры
 	flags.IntVar(&cfg.MaxWidth, "max-width", cfg.MaxWidth, "Максимальная ширина изображения (0 = без ограничения)")
 	flags.IntVar(&cfg.MaxHeight, "max-height", cfg.MaxHeight, "Максимальная высота изображения (0 = без ограничения)")
+	flags.Var(newByteSizeValue(&cfg.MaxFileSize), "max-file-size", "Предельный размер выходного файла (например 500KB, 5MB): при превышении качество снижается")
+	flags.StringVar(&cfg.CropAspect, "crop", cfg.CropAspect, "Обрезать до соотношения сторон (например 16:9, 1:1) в размер --max-width/--max-height")
+	flags.StringVar(&cfg.CropMode, "crop-mode", cfg.CropMode, "Режим обрезки: centre (по центру), attention (по заметной области)")
+	flags.BoolVar(&cfg.AllImages, "all-images", false, "Выгружать все изображения многокадрового HEIC/HEIF с суффиксом номера (по умолчанию только основное)")
+	flags.BoolVar(&cfg.PreserveAnimation, "preserve-animation", false, "Сохранять анимацию GIF/WebP при выходе в webp (иначе остаётся первый кадр)")
+	flags.BoolVar(&cfg.SmartCrop, "smart-crop", false, "Обрезка по заметной области (attention); без --crop заполняет рамку --max-width x --max-height")
+	flags.Float64Var(&cfg.Sharpen, "sharpen", cfg.Sharpen, "Повысить резкость после уменьшения (sigma; без значения - 1.0)")
+	flags.Lookup("sharpen").NoOptDefVal = "1"
+	flags.BoolVar(&cfg.SharpenAlways, "sharpen-always", cfg.SharpenAlways, "Повышать резкость и без resize")
 
 	// Профиль качества
-	preset := flags.String("preset", "", "Профиль качества: web, print, archive, thumbnail")
+	preset := flags.String("preset", "", "Профиль качества: web, print, archive, thumbnail, instagram, telegram, email")
+	flags.StringSliceVar(&cfg.MultiPresets, "multi-preset", nil, "Конвертировать каждый файл по нескольким пресетам в <out>/<пресет> (например: web,thumbnail)")
 
 	// Режим работы
 	mode := flags.String("mode", string(cfg.Mode), "Режим: skip (по умолчанию) или dedup")
+	flags.StringVar(&cfg.DedupHash, "dedup-hash", cfg.DedupHash, "Алгоритм хэша содержимого в режиме dedup: sha256, blake3, xxhash")
+	flags.BoolVar(&cfg.DedupQuick, "dedup-quick", false, "В режиме dedup хэшировать только размер, начало и конец файла (совпадения проверяются полным хэшем)")
+	flags.Int64Var(&cfg.DedupQuickBytes, "dedup-quick-bytes", cfg.DedupQuickBytes, "Сколько байт с начала и с конца файла учитывать в --dedup-quick")
+	flags.StringVar(&cfg.DedupLink, "dedup-link", cfg.DedupLink, "На месте дубликата в дереве выхода: none, symlink или hardlink на уже сконвертированный файл")
 	flags.BoolVar(&cfg.KeepTree, "keep-tree", cfg.KeepTree, "Сохранять структуру директорий")
+	flags.BoolVar(&cfg.OrganizeByDate, "organize-by-date", false, "Раскладывать результаты по директориям даты съёмки (EXIF, без него - mtime) вместо структуры входа")
+	flags.StringVar(&cfg.DateFolderTemplate, "date-folder", cfg.DateFolderTemplate, "Шаблон директории даты для --organize-by-date (YYYY, MM, DD)")
+	flags.StringVar(&cfg.FlatNaming, "flat-naming", "", "Имена без --keep-tree: basename, hashed (с хэшем директории) или pathjoined (путь через _)")
 	flags.BoolVar(&cfg.DryRun, "dry-run", cfg.DryRun, "Симуляция без реальной конвертации")
+	flags.BoolVar(&cfg.NoVerify, "no-verify", false, "Не проверять результат конвертации через vipsheader (быстрее)")
+	flags.BoolVar(&cfg.NoOOMRetry, "no-oom-retry", false, "Не повторять конвертацию с уменьшением изображения при нехватке памяти у vips")
+	flags.BoolVar(&cfg.NoDirConfig, "no-dir-config", false, "Не применять настройки поддиректорий из "+config.DirConfigName)
+	flags.BoolVar(&cfg.Resume, "resume", false, "Продолжить прерванный запуск: засчитать уже записанные результаты, остальное повторить")
+	flags.BoolVar(&cfg.Force, "force", false, "Конвертировать заново, игнорируя результаты прошлых запусков в БД (перезаписывает выходные файлы)")
+	flags.BoolVar(&cfg.DeleteSource, "delete-source", false, "Удалять исходник после успешной проверенной конвертации (необратимо, требует --i-understand)")
+	flags.StringVar(&cfg.TrashDir, "trash-dir", "", "Перемещать исходники в директорию после успешной конвертации (безопасная замена --delete-source)")
+	flags.BoolVar(&cfg.IUnderstand, "i-understand", false, "Подтвердить удаление исходников для --delete-source")
+	flags.BoolVar(&cfg.Mirror, "mirror", false, "Удалять результаты исходников, удалённых из входной директории (выход повторяет вход)")
+	flags.BoolVar(&cfg.SkipExistingOutput, "skip-existing-output", false, "Пропускать файлы, выходной файл которых уже существует (даже если его нет в БД)")
+	flags.BoolVar(&cfg.VerifyOutputs, "verify-outputs", false, "Перед пропуском уже сконвертированного файла проверять результат: удалённый или изменённый конвертировать заново")
+	flags.StringVar(&cfg.OnConflict, "on-conflict", cfg.OnConflict, "Выходной файл есть на диске, но не в БД: overwrite (перезаписать), skip (пропустить) или rename (записать в name-1.ext)")
+	flags.BoolVar(&cfg.Force, "overwrite", false, "Синоним --force")
+	flags.BoolVar(&cfg.ReportDuplicates, "report-duplicates", false, "Вывести группы одинаковых исходных файлов и выйти (без конвертации)")
+	flags.BoolVar(&cfg.Estimate, "estimate", false, "Оценить размер результата по выборке файлов и выйти (без конвертации)")
+	flags.Float64Var(&cfg.EstimateSample, "estimate-sample", cfg.EstimateSample, "Доля файлов, конвертируемых для --estimate (0.05 = 5%)")
+	flags.BoolVar(&cfg.JSONOutput, "json", false, "Выводить отчёт --report-duplicates или --estimate в JSON")
 	flags.BoolVar(&cfg.Watch, "watch", cfg.Watch, "Режим слежения за директорией")
+	flags.DurationVar(&cfg.WatchDebounce, "watch-debounce", cfg.WatchDebounce, "Пауза после последнего изменения файла перед обработкой в watch режиме")
+	flags.BoolVar(&cfg.WatchInitialScan, "watch-initial-scan", cfg.WatchInitialScan, "В watch режиме сначала обработать уже существующие файлы")
 
 	// Производительность
-	flags.IntVar(&cfg.Workers, "workers", cfg.Workers, "Количество параллельных воркеров")
+	flags.IntVar(&cfg.Workers, "workers", cfg.Workers, "Количество параллельных воркеров (-1 = автоподбор по размеру файлов и памяти)")
+	flags.StringArrayVar(&formatConcurrencyPairs, "concurrency-per-format", nil, "Наибольшее число одновременных конвертаций в формат: format=N, можно повторять (например --concurrency-per-format avif=2)")
+	flags.DurationVar(&cfg.Timeout, "timeout", cfg.Timeout, "Таймаут конвертации одного файла (например 30s, 2m)")
 	flags.BoolVar(&cfg.Stream, "stream", cfg.Stream, "Потоковый режим без предварительного подсчёта файлов")
-	flags.IntVar(&cfg.MaxMemoryMB, "max-memory", cfg.MaxMemoryMB, "Ограничение памяти в МБ (0 = без ограничения)")
+	flags.IntVar(&cfg.MaxMemoryMB, "max-memory", cfg.MaxMemoryMB, "Ограничение памяти в МБ (0 = без ограничения, -1 = авто по свободной памяти)")
+	flags.Float64Var(&cfg.MaxMegapixels, "max-megapixels", 0, "Предельный размер исходника в мегапикселях (0 = без ограничения)")
+	flags.StringVar(&cfg.MegapixelsAction, "max-megapixels-action", config.MegapixelsSkip, "Исходники больше --max-megapixels: skip (пропустить) или downscale (уменьшить до лимита)")
 	flags.BoolVar(&cfg.UseGPU, "gpu", cfg.UseGPU, "Использовать GPU ускорение (OpenCL)")
 
 	// Водяной знак
@@ -111,66 +204,150 @@ func NewRootCmd() *cobra.Command {
 	flags.IntVar(&cfg.WatermarkScale, "watermark-scale", 0, "Масштаб водяного знака в % от изображения (0 = без масштабирования)")
 
 	// Метаданные
-	flags.BoolVar(&cfg.CopyMetadata, "copy-metadata", cfg.CopyMetadata, "Копировать EXIF/IPTC метаданные из исходного файла")
+	flags.BoolVar(&cfg.CopyMetadata, "copy-metadata", cfg.CopyMetadata, "Копировать EXIF/XMP/ICC метаданные из исходного файла (требуется exiftool)")
+	flags.BoolVar(&cfg.StripGPS, "strip-gps", cfg.StripGPS, "Удалить только GPS теги, сохранив остальные EXIF (требуется exiftool)")
 
 	// Цветовые профили
 	flags.StringVar(&cfg.ColorProfile, "color-profile", "", "Целевой цветовой профиль (srgb, adobergb, p3)")
+	flags.BoolVar(&cfg.EmbedSRGB, "embed-srgb", false, "Встраивать профиль sRGB в результаты исходников без ICC профиля")
+
+	// Эффекты и обработка
+	flags.Float64Var(&cfg.Blur, "blur", 0, "Размыть изображение целиком (sigma гауссова размытия)")
+	flags.IntVar(&cfg.Pixelate, "pixelate", 0, "Пикселизировать блоками указанного размера в пикселях")
+	flags.BoolVar(&cfg.Grayscale, "grayscale", false, "Перевести изображения в оттенки серого")
+	flags.BoolVar(&cfg.Sepia, "sepia", false, "Тонировать изображения в сепию")
+	flags.IntVar(&cfg.DPI, "dpi", 0, "Плотность печати в метаданных результата (например 300; размер в пикселях не меняется)")
 
 	// PDF экспорт
 	flags.BoolVar(&cfg.PDFOutput, "pdf", cfg.PDFOutput, "Создать PDF альбом из изображений")
 	flags.StringVar(&cfg.PDFPath, "pdf-output", "", "Путь к выходному PDF файлу")
 	flags.StringVar(&cfg.PDFPageSize, "pdf-size", "a4", "Размер страницы PDF (a4, letter, a3)")
 	flags.IntVar(&cfg.PDFQuality, "pdf-quality", 85, "Качество изображений в PDF (1-100)")
+	flags.StringVar(&cfg.PDFLayout, "pdf-layout", "single", "Изображений на странице PDF: single, 2up, 4up, 9up")
+	flags.BoolVar(&cfg.PDFCaptions, "pdf-captions", false, "Печатать имя файла под каждым изображением в PDF")
+	flags.StringVar(&cfg.PDFTitle, "pdf-title", "", "Заголовок обложки PDF (пусто = без обложки)")
+	flags.BoolVar(&cfg.PDFPageNumbers, "pdf-page-numbers", false, "Печатать номера страниц в PDF")
+	flags.IntVar(&cfg.PDFMargin, "pdf-margin", 0, "Поля страницы PDF в мм (0 = без полей в макете single, 10 мм в сетках)")
+	flags.StringVar(&cfg.PDFBackground, "pdf-background", "", "Цвет страницы PDF: #rrggbb (по умолчанию белый)")
+	flags.BoolVar(&cfg.PDFForce, "pdf-force", false, "Собрать PDF заново, даже если изображения и настройки не изменились")
 
 	// Распределённая обработка
 	flags.StringVar(&cfg.RedisURL, "redis", "", "URL Redis для распределённой обработки (redis://host:6379)")
 	flags.StringVar(&cfg.WorkerMode, "worker-mode", "", "Режим работы: master (раздаёт задачи) или worker (выполняет)")
+	flags.BoolVar(&cfg.WorkerStdin, "worker-stdin", false, "Выполнять задачи из stdin (JSON по строке), результаты - в stdout")
 
 	// Кэширование
 	flags.BoolVar(&cfg.CacheEnabled, "cache", false, "Включить кэширование промежуточных результатов")
 	flags.StringVar(&cfg.CacheDir, "cache-dir", "", "Директория для кэша (по умолчанию .photoconverter/cache)")
+	flags.BoolVar(&cfg.CacheVerify, "cache-verify", false, "Проверять контрольную сумму SHA256 записей кэша (повреждённые записи конвертируются заново)")
 
 	// Сортировка/приоритизация
 	flags.StringVar(&cfg.SortBy, "sort-by", "name", "Сортировка файлов: name, date, size")
 	flags.BoolVar(&cfg.SortDesc, "sort-desc", false, "Сортировка по убыванию (новые/большие первыми)")
+	flags.IntVar(&cfg.Skip, "skip", 0, "Пропустить первые N подходящих файлов (в порядке обхода)")
+	flags.IntVar(&cfg.Limit, "limit", 0, "Обработать не больше N подходящих файлов после --skip (0 = все)")
+	flags.StringVar(&shardSpec, "shard", "", "Обработать только свою часть файлов: K/N (например 1/4), разбиение по хэшу пути")
 
 	// Пути
 	flags.StringVar(&cfg.DBPath, "db", cfg.DBPath, "Путь к SQLite базе данных")
+	flags.BoolVar(&cfg.NoBackup, "no-backup", false, "Не создавать резервную копию БД (<db>.bak) перед миграцией схемы")
+	flags.IntVar(&cfg.DBBatchSize, "db-batch", cfg.DBBatchSize, "Записывать завершения задач в БД пакетами по N (0 = по одной)")
+	flags.DurationVar(&cfg.DBBatchInterval, "db-batch-interval", cfg.DBBatchInterval, "Максимальная задержка записи неполного пакета в БД")
 	flags.StringVar(&cfg.VipsPath, "vips-path", cfg.VipsPath, "Путь к бинарнику vips")
+	flags.StringVar(&cfg.Backend, "backend", config.BackendVips, "Движок конвертации: vips или magick (ImageMagick)")
+	flags.StringVar(&cfg.MagickPath, "magick-path", cfg.MagickPath, "Путь к ImageMagick (magick или convert)")
+	flags.BoolVar(&cfg.MagickFallback, "magick-fallback", false, "Конвертировать через ImageMagick файлы, формат которых vips не читает (PSD, часть RAW)")
+	flags.StringVar(&cfg.RawDecoderPath, "raw-decoder-path", cfg.RawDecoderPath, "Путь к декодеру RAW (dcraw или dcraw_emu из libraw)")
 
 	// Вывод
-	flags.BoolVarP(&cfg.Verbose, "verbose", "v", cfg.Verbose, "Подробный вывод")
+	flags.CountVarP(&cfg.Verbosity, "verbose", "v", "Подробный вывод: -v - каждый файл, -vv - отладка (командные строки vips и итоговая конфигурация)")
+	quiet := flags.BoolP("quiet", "q", false, "Выводить только ошибки и предупреждения (для cron)")
 	flags.BoolVar(&cfg.NoProgress, "no-progress", cfg.NoProgress, "Отключить прогресс-бар")
+	flags.StringVar(&cfg.ProgressFormat, "progress-format", cfg.ProgressFormat, "Формат прогресса: bar или json (JSON-строки в stdout, сообщения - в stderr)")
+	flags.BoolVar(&cfg.PreserveMtime, "preserve-mtime", cfg.PreserveMtime, "Сохранять время модификации исходника у выходных файлов")
+	flags.BoolVar(&cfg.ProgressByBytes, "progress-bytes", cfg.ProgressByBytes, "Прогресс по объёму данных (MB/s и ETA по размеру файлов)")
+	flags.BoolVar(&cfg.FailFast, "fail-fast", false, "Остановиться на первой ошибке конвертации (начатые файлы дорабатываются)")
+	flags.BoolVar(&continueOnError, "continue-on-error", true, "Обрабатывать все файлы, даже если часть завершилась ошибкой (по умолчанию)")
+	rootCmd.MarkFlagsMutuallyExclusive("fail-fast", "continue-on-error")
+	flags.StringVar(&cfg.QuarantineDir, "quarantine", "", "Копировать файлы с ошибкой конвертации в директорию (с .error.txt рядом)")
+	flags.StringVar(&cfg.LogFile, "log-file", "", "Журнал обработки файлов (JSON Lines, дозапись)")
+	flags.StringVar(&cfg.MetricsAddr, "metrics-addr", "", "Адрес HTTP-сервера метрик Prometheus (например :9090, путь /metrics)")
+	flags.StringVar(&cfg.StatusAddr, "status-addr", "", "Адрес HTTP-сервера с JSON статусом запуска (например :8080)")
+	flags.StringVar(&cfg.WebhookURL, "webhook", "", "POST JSON с итогами запуска на URL после завершения")
+	flags.StringVar(&cfg.ManifestPath, "manifest", "", "Записать JSON манифест запуска (файлы, размеры, итоги, конфигурация)")
+	flags.BoolVar(&cfg.ReportFailures, "report-failures", false, "После запуска вывести ошибки, сгруппированные по причинам, с примерами файлов")
+	flags.BoolVar(&cfg.ReportByDir, "report-by-dir", false, "После запуска вывести размеры и экономию по директориям верхнего уровня")
 
 	// Конфигурационный файл
 	flags.StringVar(&configPath, "config", "", "Путь к файлу конфигурации (YAML)")
 	flags.StringVar(&saveConfigPath, "save-config", "", "Сохранить текущие настройки в YAML файл и выйти")
+	flags.StringVar(&printConfigFormat, "print-config", "", "Вывести итоговую конфигурацию (json или yaml) и выйти")
+	flags.Lookup("print-config").NoOptDefVal = "json"
 
 	// Именованные пресеты
 	flags.StringVar(&savePresetName, "save-preset", "", "Сохранить текущие настройки как именованный пресет")
 	flags.StringVar(&loadPresetName, "load-preset", "", "Загрузить именованный пресет")
 
+	// Упаковка результатов
+	flags.StringVar(&cfg.ZipOutput, "zip", "", "Упаковать результаты в zip архив после конвертации")
+	flags.BoolVar(&cfg.ZipRemoveFiles, "zip-remove", false, "Удалить упакованные файлы из выходной директории (требует --zip)")
+
 	// Флаги --in и --out НЕ обязательны, если есть конфиг файл
 	// Валидация происходит в PreRunE после загрузки конфига
 
 	// Парсинг конфигурации и enum-флагов
 	rootCmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		// --continue-on-error=false равносилен --fail-fast
+		if cmd.Flags().Changed("continue-on-error") && !continueOnError {
+			cfg.FailFast = true
+		}
+
+		if shardSpec != "" {
+			index, total, err := config.ParseShard(shardSpec)
+			if err != nil {
+				return err
+			}
+			cfg.ShardIndex, cfg.ShardTotal = index, total
+		}
+
 		// Сохраняем значения CLI флагов ДО загрузки конфига
 		// (Cobra уже применила их к cfg)
 		cliInputDir := cfg.InputDir
 		cliOutputDir := cfg.OutputDir
 		cliInputExtensions := cfg.InputExtensions
+		cliInclude := cfg.Include
+		cliExclude := cfg.Exclude
 		cliQuality := cfg.Quality
 		cliStripMetadata := cfg.StripMetadata
 		cliKeepTree := cfg.KeepTree
+		cliFlatNaming := cfg.FlatNaming
+		cliDateFolder := cfg.DateFolderTemplate
+		cliOutputExtension := cfg.OutputExtension
 		cliWorkers := cfg.Workers
+		cliDedupHash := cfg.DedupHash
+		cliDedupQuick := cfg.DedupQuick
+		cliDedupQuickBytes := cfg.DedupQuickBytes
+		cliDedupLink := cfg.DedupLink
+		cliOnConflict := cfg.OnConflict
 		cliDryRun := cfg.DryRun
-		cliVerbose := cfg.Verbose
+		cliVerbosity := cfg.Verbosity
 		cliNoProgress := cfg.NoProgress
 		cliDBPath := cfg.DBPath
 		cliVipsPath := cfg.VipsPath
+		cliMagickPath := cfg.MagickPath
+		cliRawDecoderPath := cfg.RawDecoderPath
+		cliBackend := cfg.Backend
 		cliMaxWidth := cfg.MaxWidth
 		cliMaxHeight := cfg.MaxHeight
+		cliCropAspect := cfg.CropAspect
+		cliCropMode := cfg.CropMode
+		cliSharpen := cfg.Sharpen
+		cliBlur := cfg.Blur
+		cliPixelate := cfg.Pixelate
+		cliDPI := cfg.DPI
+		cliMaxMegapixels := cfg.MaxMegapixels
+		cliMegapixelsAction := cfg.MegapixelsAction
+		cliMaxFileSize := cfg.MaxFileSize
 		cliWatch := cfg.Watch
 
 		// Загружаем именованный пресет (если указан)
@@ -180,8 +357,8 @@ func NewRootCmd() *cobra.Command {
 				return err
 			}
 			fc.ApplyToConfig(cfg)
-			if cfg.Verbose {
-				fmt.Printf("📦 Загружен пресет '%s': %s\n", loadPresetName, loadedPath)
+			if cfg.Verbose() {
+				fmt.Fprintf(humanOutput(), "📦 Загружен пресет '%s': %s\n", loadPresetName, loadedPath)
 			}
 		}
 
@@ -193,8 +370,8 @@ func NewRootCmd() *cobra.Command {
 		if fc != nil {
 			// Применяем настройки из файла
 			fc.ApplyToConfig(cfg)
-			if cfg.Verbose {
-				fmt.Printf("📄 Загружен конфиг: %s\n", loadedPath)
+			if cfg.Verbose() {
+				fmt.Fprintf(humanOutput(), "📄 Загружен конфиг: %s\n", loadedPath)
 			}
 		}
 
@@ -223,6 +400,12 @@ func NewRootCmd() *cobra.Command {
 		if len(cliInputExtensions) > 0 && cmd.Flags().Changed("in-ext") {
 			cfg.InputExtensions = cliInputExtensions
 		}
+		if cmd.Flags().Changed("include") {
+			cfg.Include = cliInclude
+		}
+		if cmd.Flags().Changed("exclude") {
+			cfg.Exclude = cliExclude
+		}
 		if cmd.Flags().Changed("quality") {
 			cfg.Quality = cliQuality
 		}
@@ -232,14 +415,58 @@ func NewRootCmd() *cobra.Command {
 		if cmd.Flags().Changed("keep-tree") {
 			cfg.KeepTree = cliKeepTree
 		}
+		if cmd.Flags().Changed("flat-naming") {
+			cfg.FlatNaming = cliFlatNaming
+		}
+		if cmd.Flags().Changed("date-folder") {
+			cfg.DateFolderTemplate = cliDateFolder
+		}
+		if cmd.Flags().Changed("out-ext") {
+			cfg.OutputExtension = strings.TrimPrefix(cliOutputExtension, ".")
+		}
+		if cmd.Flags().Changed("map") {
+			formatMap, err := config.ParseFormatMap(formatMapPairs)
+			if err != nil {
+				return err
+			}
+			cfg.FormatMap = formatMap
+		}
 		if cmd.Flags().Changed("workers") {
 			cfg.Workers = cliWorkers
 		}
+		if cmd.Flags().Changed("concurrency-per-format") {
+			limits, err := config.ParseFormatConcurrency(formatConcurrencyPairs)
+			if err != nil {
+				return err
+			}
+			cfg.ConcurrencyPerFormat = limits
+		}
+		if cmd.Flags().Changed("dedup-hash") {
+			cfg.DedupHash = cliDedupHash
+		}
+		if cmd.Flags().Changed("dedup-quick") {
+			cfg.DedupQuick = cliDedupQuick
+		}
+		if cmd.Flags().Changed("dedup-quick-bytes") {
+			cfg.DedupQuickBytes = cliDedupQuickBytes
+		}
+		if cmd.Flags().Changed("dedup-link") {
+			cfg.DedupLink = cliDedupLink
+		}
+		if cmd.Flags().Changed("on-conflict") {
+			cfg.OnConflict = cliOnConflict
+		}
 		if cmd.Flags().Changed("dry-run") {
 			cfg.DryRun = cliDryRun
 		}
 		if cmd.Flags().Changed("verbose") {
-			cfg.Verbose = cliVerbose
+			cfg.Verbosity = cliVerbosity
+		}
+		if *quiet {
+			if cmd.Flags().Changed("verbose") {
+				return fmt.Errorf("--quiet несовместим с --verbose")
+			}
+			cfg.Verbosity = config.VerbosityQuiet
 		}
 		if cmd.Flags().Changed("no-progress") {
 			cfg.NoProgress = cliNoProgress
@@ -250,12 +477,48 @@ func NewRootCmd() *cobra.Command {
 		if cliVipsPath != "" && cmd.Flags().Changed("vips-path") {
 			cfg.VipsPath = cliVipsPath
 		}
+		if cmd.Flags().Changed("magick-path") {
+			cfg.MagickPath = cliMagickPath
+		}
+		if cmd.Flags().Changed("raw-decoder-path") {
+			cfg.RawDecoderPath = cliRawDecoderPath
+		}
+		if cmd.Flags().Changed("backend") {
+			cfg.Backend = cliBackend
+		}
 		if cmd.Flags().Changed("max-width") {
 			cfg.MaxWidth = cliMaxWidth
 		}
 		if cmd.Flags().Changed("max-height") {
 			cfg.MaxHeight = cliMaxHeight
 		}
+		if cmd.Flags().Changed("crop") {
+			cfg.CropAspect = cliCropAspect
+		}
+		if cmd.Flags().Changed("crop-mode") {
+			cfg.CropMode = cliCropMode
+		}
+		if cmd.Flags().Changed("sharpen") {
+			cfg.Sharpen = cliSharpen
+		}
+		if cmd.Flags().Changed("blur") {
+			cfg.Blur = cliBlur
+		}
+		if cmd.Flags().Changed("pixelate") {
+			cfg.Pixelate = cliPixelate
+		}
+		if cmd.Flags().Changed("dpi") {
+			cfg.DPI = cliDPI
+		}
+		if cmd.Flags().Changed("max-megapixels") {
+			cfg.MaxMegapixels = cliMaxMegapixels
+		}
+		if cmd.Flags().Changed("max-megapixels-action") {
+			cfg.MegapixelsAction = cliMegapixelsAction
+		}
+		if cmd.Flags().Changed("max-file-size") {
+			cfg.MaxFileSize = cliMaxFileSize
+		}
 		if cmd.Flags().Changed("watch") {
 			cfg.Watch = cliWatch
 		}
@@ -278,12 +541,12 @@ func NewRootCmd() *cobra.Command {
 		}
 
 		// Проверяем обязательные поля после загрузки конфига
-		// (--save-config не требует --in/--out заполненными)
-		if saveConfigPath == "" {
-			if cfg.InputDir == "" {
-				return fmt.Errorf("входная директория не указана (--in или в конфиг файле)")
+		// (--save-config и --print-config не требуют --in/--out заполненными)
+		if saveConfigPath == "" && printConfigFormat == "" {
+			if cfg.InputDir == "" && cfg.FromFile == "" {
+				return fmt.Errorf("входная директория не указана (--in, --from-file или в конфиг файле)")
 			}
-			if cfg.OutputDir == "" {
+			if cfg.OutputDir == "" && !cfg.ReportDuplicates && !cfg.Estimate {
 				return fmt.Errorf("выходная директория не указана (--out или в конфиг файле)")
 			}
 		}
@@ -294,7 +557,12 @@ func NewRootCmd() *cobra.Command {
 	// Подкоманды
 	rootCmd.AddCommand(newVersionCmd())
 	rootCmd.AddCommand(newStatsCmd())
+	rootCmd.AddCommand(newQueryCmd())
+	rootCmd.AddCommand(newMigrateCmd())
 	rootCmd.AddCommand(newPresetsCmd())
+	rootCmd.AddCommand(newDoctorCmd())
+	rootCmd.AddCommand(newInfoCmd())
+	rootCmd.AddCommand(newCompareCmd())
 
 	return rootCmd
 }
@@ -302,6 +570,10 @@ func NewRootCmd() *cobra.Command {
 // runConvert выполняет основную логику конвертации.
 func runConvert(cmd *cobra.Command, args []string) error {
 	startTime := time.Now()
+	stdout = humanOutput()
+	if cfg.Quiet() {
+		stdout = io.Discard
+	}
 
 	// Сохранение конфигурации если указан флаг --save-config
 	// (выполняется до валидации, т.к. не требует полной конфигурации)
@@ -310,7 +582,7 @@ func runConvert(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("ошибка сохранения конфигурации: %w", err)
 		}
-		fmt.Printf("💾 Конфигурация сохранена в: %s\n", savedPath)
+		fmt.Fprintf(stdout, "💾 Конфигурация сохранена в: %s\n", savedPath)
 		return nil
 	}
 
@@ -321,13 +593,36 @@ func runConvert(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("ошибка сохранения пресета: %w", err)
 		}
-		fmt.Printf("📦 Пресет '%s' сохранён в: %s\n", savePresetName, savedPath)
+		fmt.Fprintf(stdout, "📦 Пресет '%s' сохранён в: %s\n", savePresetName, savedPath)
 		return nil
 	}
 
 	// Валидация конфигурации (только для реальной конвертации)
-	if err := cfg.Validate(); err != nil {
-		return fmt.Errorf("ошибка конфигурации: %w", err)
+	validateErr := cfg.Validate()
+
+	// Вывод итоговой конфигурации (--print-config): после Validate,
+	// которая заполняет производные значения (путь к БД по умолчанию)
+	if printConfigFormat != "" {
+		if err := printConfig(cmd.OutOrStdout(), cfg, printConfigFormat); err != nil {
+			return err
+		}
+		if validateErr != nil {
+			return fmt.Errorf("ошибка конфигурации: %w", validateErr)
+		}
+		return nil
+	}
+
+	if validateErr != nil {
+		return fmt.Errorf("ошибка конфигурации: %w", validateErr)
+	}
+	if cfg.Debug() {
+		fmt.Fprintln(stdout, "🐞 Итоговая конфигурация:")
+		if err := printConfig(stdout, cfg, "json"); err != nil {
+			return err
+		}
+	}
+	if (cfg.DeleteSource || cfg.TrashDir != "") && scanner.IsArchive(cfg.InputDir) {
+		return fmt.Errorf("--delete-source и --trash-dir не поддерживают архивы: %s", cfg.InputDir)
 	}
 
 	// Создаём контекст с обработкой сигналов
@@ -339,123 +634,378 @@ func runConvert(cmd *cobra.Command, args []string) error {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-sigChan
-		fmt.Println("\n⚠️  Получен сигнал завершения, останавливаем...")
+		fmt.Fprintln(stdout, "\n⚠️  Получен сигнал завершения, останавливаем...")
 		cancel()
 	}()
 
-	// Ищем vips
-	finder := vipsfinder.NewFinder(cfg.VipsPath)
-	vipsInfo, err := finder.Find()
-	if err != nil {
-		return err
+	// Отчёт о дубликатах не требует vips и выходной директории
+	if cfg.ReportDuplicates {
+		return runDuplicateReport(ctx)
+	}
+
+	// Ищем движки конвертации: vips (кроме --backend magick) и ImageMagick
+	var vipsPath, magickPath string
+	if cfg.Backend != config.BackendMagick {
+		var err error
+		if vipsPath, err = findVips(); err != nil {
+			return err
+		}
+	}
+	if cfg.Backend == config.BackendMagick || cfg.MagickFallback {
+		magickInfo, err := magickfinder.NewFinder(cfg.MagickPath).Find()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "📦 Найден ImageMagick: %s (версия %s)\n", magickInfo.Path, magickInfo.Version)
+		magickPath = magickInfo.Path
+	}
+
+	// Оценка размера не использует БД и выходную директорию
+	if cfg.Estimate {
+		conv, err := converter.NewBackend(cfg, vipsPath, magickPath)
+		if err != nil {
+			return err
+		}
+		return runEstimate(ctx, conv)
+	}
+
+	// Задачи внешнего оркестратора: без сканирования, БД и очереди
+	if cfg.WorkerStdin {
+		conv, err := converter.NewBackend(cfg, vipsPath, magickPath)
+		if err != nil {
+			return err
+		}
+		if err := conv.CheckHealth(); err != nil {
+			return err
+		}
+		hostname, _ := os.Hostname()
+		return distributed.ServeStdin(ctx, conv, fmt.Sprintf("%s-%d", hostname, os.Getpid()), os.Stdin, os.Stdout)
 	}
-	fmt.Printf("📦 Найден vips: %s (версия %s)\n", vipsInfo.Path, vipsInfo.Version)
 
 	// Инициализируем хранилище
-	store, err := storage.New(cfg.DBPath)
+	store, err := storage.NewWithOptions(cfg.DBPath, storage.Options{NoBackup: cfg.NoBackup})
 	if err != nil {
 		return fmt.Errorf("не удалось инициализировать БД: %w", err)
 	}
-	defer func() { _ = store.Close() }()
+	if store.BackupPath != "" {
+		fmt.Fprintf(stdout, "💾 Схема БД обновлена, резервная копия: %s\n", store.BackupPath)
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Ошибка записи в БД: %v\n", err)
+		}
+	}()
 
-	// Очищаем прерванные задачи
-	cleaned, err := store.CleanupInProgress()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "⚠️  Не удалось очистить in_progress: %v\n", err)
-	} else if cleaned > 0 {
-		fmt.Printf("🧹 Очищено %d прерванных задач\n", cleaned)
+	// Очищаем прерванные задачи (с --resume они разбираются после создания пула)
+	if !cfg.Resume {
+		cleaned, err := store.CleanupInProgress()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Не удалось очистить in_progress: %v\n", err)
+		} else if cleaned > 0 {
+			fmt.Fprintf(stdout, "🧹 Очищено %d прерванных задач\n", cleaned)
+		}
+	}
+
+	// Пометки игнорирования (--ignore-file, --clear-ignored)
+	if err := applyIgnores(store); err != nil {
+		return err
 	}
 
 	// Создаём конвертер
-	conv := converter.New(vipsInfo.Path, cfg)
-	if err := conv.CheckVipsHealth(); err != nil {
+	conv, err := converter.NewBackend(cfg, vipsPath, magickPath)
+	if err != nil {
+		return err
+	}
+	if err := conv.CheckHealth(); err != nil {
 		return err
 	}
 
 	// Создаём пул воркеров
 	pool := worker.New(cfg, store, conv)
 
+	// Кэш результатов
+	if cfg.CacheEnabled {
+		c, err := cache.New(cfg)
+		if err != nil {
+			return err
+		}
+		pool.SetCache(c)
+	}
+
+	// Продолжение прерванного запуска
+	if cfg.Resume {
+		resumed, err := pool.Resume(ctx)
+		if err != nil {
+			return fmt.Errorf("не удалось разобрать прерванные задачи: %w", err)
+		}
+		if resumed.Finalized > 0 || resumed.Retried > 0 {
+			fmt.Fprintf(stdout, "♻️  Прерванные задачи: %d завершены по готовым результатам, %d будут повторены\n",
+				resumed.Finalized, resumed.Retried)
+		}
+	}
+
+	// Журнал обработки
+	if cfg.LogFile != "" {
+		runLog, err := runlog.Open(cfg.LogFile)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = runLog.Close() }()
+		pool.SetRunLog(runLog)
+	}
+
+	// Пакетная запись завершений задач (dry-run в БД не пишет)
+	if cfg.DBBatchSize > 0 && !cfg.DryRun {
+		store.EnableBatching(cfg.DBBatchSize, cfg.DBBatchInterval)
+	}
+
+	// Метрики Prometheus
+	if cfg.MetricsAddr != "" {
+		m := metrics.New()
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", m.Handler())
+		srv, err := httpserver.Start(ctx, cfg.MetricsAddr, mux)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = srv.Close() }()
+		pool.SetMetrics(m)
+		fmt.Fprintf(stdout, "📈 Метрики: http://%s/metrics\n", srv.Addr())
+	}
+
+	// JSON статус запуска
+	if cfg.StatusAddr != "" {
+		statusReporter = status.New(pool, startTime)
+		srv, err := httpserver.Start(ctx, cfg.StatusAddr, statusReporter)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = srv.Close() }()
+		fmt.Fprintf(stdout, "📡 Статус: http://%s/\n", srv.Addr())
+	}
+
 	// Выводим параметры
-	fmt.Printf("🚀 Запуск конвертации:\n")
-	fmt.Printf("   Вход: %s\n", cfg.InputDir)
-	fmt.Printf("   Выход: %s\n", cfg.OutputDir)
-	fmt.Printf("   Формат: %s (качество: %d)\n", cfg.OutputFormat, cfg.Quality)
+	fmt.Fprintf(stdout, "🚀 Запуск конвертации:\n")
+	if cfg.FromFile != "" {
+		fmt.Fprintf(stdout, "   Вход: список файлов %s\n", cfg.FromFile)
+	} else {
+		fmt.Fprintf(stdout, "   Вход: %s\n", cfg.InputDir)
+	}
+	fmt.Fprintf(stdout, "   Выход: %s\n", cfg.OutputDir)
+	fmt.Fprintf(stdout, "   Формат: %s (качество: %d)\n", cfg.OutputFormat, cfg.Quality)
 	if cfg.MaxWidth > 0 || cfg.MaxHeight > 0 {
-		fmt.Printf("   Resize: max %dx%d\n", cfg.MaxWidth, cfg.MaxHeight)
+		if cfg.CropAspect != "" {
+			width, height := cfg.CropSize()
+			fmt.Fprintf(stdout, "   Resize: %dx%d (обрезка до %s, %s)\n", width, height, cfg.CropAspect, cfg.EffectiveCropMode())
+		} else {
+			fmt.Fprintf(stdout, "   Resize: max %dx%d\n", cfg.MaxWidth, cfg.MaxHeight)
+		}
+	}
+	if cfg.MaxFileSize > 0 {
+		fmt.Fprintf(stdout, "   Размер файла: не больше %s\n", config.FormatByteSize(cfg.MaxFileSize))
+	}
+	if len(cfg.MultiPresets) > 0 {
+		fmt.Fprintf(stdout, "   Пресеты: %s\n", strings.Join(cfg.MultiPresets, ", "))
+	} else if cfg.Preset != "" {
+		fmt.Fprintf(stdout, "   Пресет: %s\n", cfg.Preset)
+	}
+	fmt.Fprintf(stdout, "   Режим: %s\n", cfg.Mode)
+	if cfg.Force {
+		fmt.Fprintln(stdout, "   Принудительно: да (результаты прошлых запусков игнорируются)")
+	}
+	if cfg.DeleteSource {
+		fmt.Fprintln(stdout, "   🗑️  Исходники удаляются после успешной конвертации (--delete-source)")
 	}
-	if cfg.Preset != "" {
-		fmt.Printf("   Пресет: %s\n", cfg.Preset)
+	if cfg.TrashDir != "" {
+		fmt.Fprintf(stdout, "   🗑️  Исходники перемещаются в корзину: %s\n", cfg.TrashDir)
+	}
+	if cfg.Workers == config.WorkersAuto {
+		fmt.Fprintf(stdout, "   Воркеров: авто (1-%d)\n", cfg.MaxWorkers())
+	} else {
+		fmt.Fprintf(stdout, "   Воркеров: %d\n", cfg.Workers)
+	}
+	if cfg.MaxMemoryMB == config.MemoryAuto {
+		fmt.Fprintln(stdout, "   Память: авто (по доступной памяти системы)")
+	} else if cfg.MaxMemoryMB > 0 {
+		fmt.Fprintf(stdout, "   Память: до %d МБ\n", cfg.MaxMemoryMB)
 	}
-	fmt.Printf("   Режим: %s\n", cfg.Mode)
-	fmt.Printf("   Воркеров: %d\n", cfg.Workers)
 	if cfg.DryRun {
-		fmt.Println("   ⚠️  Dry-run режим (без реальной конвертации)")
+		fmt.Fprintln(stdout, "   ⚠️  Dry-run режим (без реальной конвертации)")
 	}
 	if cfg.Watch {
-		fmt.Println("   👁️  Watch режим (слежение за директорией)")
+		fmt.Fprintln(stdout, "   👁️  Watch режим (слежение за директорией)")
 	}
-	fmt.Println()
+	fmt.Fprintln(stdout)
 
 	// Watch mode или обычный режим
 	if cfg.Watch {
+		if scanner.IsArchive(cfg.InputDir) {
+			return fmt.Errorf("watch режим не поддерживает архивы: %s", cfg.InputDir)
+		}
 		return runWatchMode(ctx, pool)
 	}
 
-	return runNormalMode(ctx, pool, startTime)
+	return runNormalMode(ctx, pool, store, startTime)
 }
 
 // runNormalMode выполняет обычную конвертацию.
-func runNormalMode(ctx context.Context, pool *worker.Pool, startTime time.Time) error {
-	// Создаём сканер
-	scan := scanner.New(cfg)
+func runNormalMode(ctx context.Context, pool *worker.Pool, store *storage.Storage, startTime time.Time) error {
+	// Источник файлов: директория, архив или список путей
+	src, closeSrc, err := openSource()
+	if err != nil {
+		return err
+	}
+	defer closeSrc()
 
 	var fileCount int64 = -1 // -1 означает неизвестное количество (streaming режим)
+	var totalBytes int64
 
 	// В обычном режиме считаем файлы для прогресс-бара
-	if !cfg.Stream {
-		fileCount, _ = scan.CountFiles()
-		if cfg.Verbose {
-			fmt.Printf("📁 Найдено файлов для обработки: %d\n", fileCount)
+	// (список из --from-file читается потоково, количество заранее неизвестно)
+	if !cfg.Stream && src.count != nil {
+		fileCount, totalBytes, _ = src.count()
+		// Каждый файл конвертируется в каждый вариант (--multi-preset)
+		fileCount *= int64(pool.Variants())
+		totalBytes *= int64(pool.Variants())
+		if statusReporter != nil {
+			statusReporter.SetExpected(fileCount)
+		}
+		if cfg.Verbose() {
+			fmt.Fprintf(stdout, "📁 Найдено файлов для обработки: %d\n", fileCount)
 		}
-	} else if cfg.Verbose {
-		fmt.Println("🌊 Потоковый режим: обработка файлов по мере обнаружения")
+	} else if cfg.Verbose() {
+		fmt.Fprintln(stdout, "🌊 Потоковый режим: обработка файлов по мере обнаружения")
 	}
 
-	// Запускаем сканирование
-	files, errChan := scan.Scan(ctx)
+	// Запускаем сканирование; при --fail-fast пул отменяет его на первой ошибке,
+	// а начатые конвертации идут под исходным контекстом и дорабатываются
+	scanCtx, cancelScan := context.WithCancel(ctx)
+	defer cancelScan()
+	pool.SetCancel(cancelScan)
+	files, errChan := src.scan(scanCtx)
 
 	// Создаём прогресс-бар
 	progressBar := progress.New(progress.Options{
 		Total:       fileCount,
 		Description: "🔄 Конвертация",
-		Disabled:    cfg.NoProgress || cfg.DryRun || cfg.Stream,
+		Disabled:    cfg.NoProgress || (cfg.ProgressFormat != progress.FormatJSON && (cfg.DryRun || cfg.Stream || cfg.Quiet())),
+		Format:      cfg.ProgressFormat,
+		ByBytes:     cfg.ProgressByBytes,
+		TotalBytes:  totalBytes,
 	})
 	pool.SetProgressBar(progressBar)
 
+	// Пауза по пробелу в интерактивном терминале
+	stopPauseKeys := startPauseKeys(ctx, pool)
+
 	// Запускаем обработку
 	stats := pool.Process(ctx, files, errChan)
+	stopPauseKeys()
 
 	// Завершаем прогресс-бар
 	progressBar.Finish()
 
+	// --mirror: удаляем результаты исходников, которых больше нет
+	// (не после остановки по --fail-fast или сигналу)
+	var mirrored worker.MirrorStats
+	var mirrorErr error
+	if cfg.Mirror && !stats.Stopped && ctx.Err() == nil {
+		mirrored, mirrorErr = pool.Mirror(ctx)
+	}
+
 	// Выводим результаты
 	duration := time.Since(startTime)
-	fmt.Println()
-	fmt.Printf("📊 Результаты:\n")
-	fmt.Printf("   Обработано: %d\n", stats.Processed)
-	fmt.Printf("   Пропущено: %d\n", stats.Skipped)
-	fmt.Printf("   Ошибок: %d\n", stats.Failed)
-	fmt.Printf("   Время: %s\n", duration.Round(time.Millisecond))
+	fmt.Fprintln(stdout)
+	fmt.Fprintf(stdout, "📊 Результаты:\n")
+	fmt.Fprintf(stdout, "   Обработано: %d\n", stats.Processed)
+	fmt.Fprintf(stdout, "   Пропущено: %d\n", stats.Skipped)
+	fmt.Fprintf(stdout, "   Ошибок: %d\n", stats.Failed)
+	fmt.Fprintf(stdout, "   Время: %s\n", duration.Round(time.Millisecond))
+	if stats.Processed > 0 {
+		fmt.Fprintf(stdout, "   Время на файл: в среднем %s, медиана %s\n",
+			stats.AvgDuration().Round(time.Millisecond), stats.MedianDuration.Round(time.Millisecond))
+		if secs := duration.Seconds(); secs > 0 {
+			fmt.Fprintf(stdout, "   Пропускная способность: %.1f файл/с, %s/с\n",
+				float64(stats.Processed)/secs, worker.FormatBytes(int64(float64(stats.InputBytes)/secs)))
+		}
+	}
+	if stats.Stopped {
+		fmt.Fprintln(stdout, "   ⛔ Остановлено после первой ошибки (--fail-fast)")
+	}
+	if stats.Downscaled > 0 {
+		fmt.Fprintf(stdout, "   📐 Уменьшено из-за нехватки памяти: %d\n", stats.Downscaled)
+	}
+	if stats.CacheHits > 0 {
+		fmt.Fprintf(stdout, "   ♻️  Из кэша: %d\n", stats.CacheHits)
+	}
+	if cfg.DeleteSource && !cfg.DryRun {
+		fmt.Fprintf(stdout, "   Удалено исходников: %d\n", stats.Deleted)
+	}
+	if cfg.TrashDir != "" && !cfg.DryRun {
+		fmt.Fprintf(stdout, "   Перемещено в корзину: %d\n", stats.Trashed)
+	}
+	if cfg.Mirror {
+		switch {
+		case mirrorErr != nil:
+			fmt.Fprintf(os.Stderr, "⚠️  Ошибка --mirror: %v\n", mirrorErr)
+		case cfg.DryRun:
+			fmt.Fprintf(stdout, "   🪞 Будет удалено результатов удалённых исходников: %d (%s)\n",
+				mirrored.Removed, worker.FormatBytes(mirrored.RemovedBytes))
+		default:
+			fmt.Fprintf(stdout, "   🪞 Удалено результатов удалённых исходников: %d (%s)\n",
+				mirrored.Removed, worker.FormatBytes(mirrored.RemovedBytes))
+		}
+		if mirrored.Kept > 0 {
+			fmt.Fprintf(stdout, "   ⚠️  Оставлено изменённых или внешних результатов: %d\n", mirrored.Kept)
+		}
+	}
+
+	if cfg.DryRun {
+		printDryRunPlan(humanOutput(), stats.Plan, cfg.CacheEnabled)
+	}
 
 	// Расширенная статистика размеров
 	if stats.InputBytes > 0 {
-		fmt.Printf("   Размер входных: %s\n", worker.FormatBytes(stats.InputBytes))
-		fmt.Printf("   Размер выходных: %s\n", worker.FormatBytes(stats.OutputBytes))
+		fmt.Fprintf(stdout, "   Размер входных: %s\n", worker.FormatBytes(stats.InputBytes))
+		fmt.Fprintf(stdout, "   Размер выходных: %s\n", worker.FormatBytes(stats.OutputBytes))
 		saved := stats.SavedBytes()
 		if saved > 0 {
-			fmt.Printf("   💾 Экономия: %s (%.1f%%)\n", worker.FormatBytes(saved), stats.SavedPercent())
+			fmt.Fprintf(stdout, "   💾 Экономия: %s (%.1f%%)\n", worker.FormatBytes(saved), stats.SavedPercent())
 		} else if saved < 0 {
-			fmt.Printf("   ⚠️  Увеличение: %s (+%.1f%%)\n", worker.FormatBytes(-saved), -stats.SavedPercent())
+			fmt.Fprintf(stdout, "   ⚠️  Увеличение: %s (+%.1f%%)\n", worker.FormatBytes(-saved), -stats.SavedPercent())
+		}
+	}
+
+	// Ошибки по причинам
+	if cfg.ReportFailures && stats.Failed > 0 {
+		printFailures(humanOutput(), stats.Failures)
+	}
+
+	// Экономия по директориям верхнего уровня (по всем успешным задачам БД)
+	if cfg.ReportByDir && !cfg.DryRun {
+		dirs, err := store.StatsByDir(reportRoot())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Ошибка отчёта по директориям: %v\n", err)
+		} else {
+			printDirStats(humanOutput(), dirs)
+		}
+	}
+
+	// Манифест запуска
+	if cfg.ManifestPath != "" {
+		if err := writeManifest(store, pool, stats, startTime); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Ошибка записи манифеста: %v\n", err)
+		} else {
+			fmt.Fprintf(stdout, "📝 Манифест сохранён: %s\n", cfg.ManifestPath)
+		}
+	}
+
+	// Уведомление о завершении; ошибка отправки не влияет на результат запуска
+	if cfg.WebhookURL != "" {
+		if err := notifyWebhook(ctx, stats, startTime); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Не удалось отправить webhook: %v\n", err)
+		} else if cfg.Verbose() {
+			fmt.Fprintf(stdout, "📨 Webhook отправлен: %s\n", cfg.WebhookURL)
 		}
 	}
 
@@ -465,16 +1015,299 @@ func runNormalMode(ctx context.Context, pool *worker.Pool, startTime time.Time)
 
 	// PDF экспорт если включён
 	if cfg.PDFOutput {
-		if err := exportToPDF(ctx); err != nil {
-			fmt.Printf("⚠️  Ошибка PDF экспорта: %v\n", err)
+		if err := exportToPDF(ctx, store); err != nil {
+			fmt.Fprintf(stdout, "⚠️  Ошибка PDF экспорта: %v\n", err)
+		}
+	}
+
+	// Упаковка результатов в zip
+	if cfg.ZipOutput != "" {
+		if cfg.DryRun {
+			fmt.Fprintln(stdout, "📦 [dry-run] Упаковка в zip пропущена")
+		} else if err := packageZip(); err != nil {
+			return fmt.Errorf("ошибка упаковки в zip: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// outputFormats возвращает все выходные форматы запуска (с учётом --multi-preset).
+func outputFormats() []string {
+	if len(cfg.MultiPresets) == 0 {
+		return []string{string(cfg.OutputFormat)}
+	}
+
+	var formats []string
+	seen := make(map[string]bool)
+	for _, name := range cfg.MultiPresets {
+		format := string(config.Presets[config.Preset(name)].Format)
+		if !seen[format] {
+			seen[format] = true
+			formats = append(formats, format)
+		}
+	}
+	return formats
+}
+
+// fileSource - источник входных файлов.
+type fileSource struct {
+	// scan запускает перечисление файлов.
+	scan func(ctx context.Context) (<-chan scanner.File, <-chan error)
+
+	// count считает файлы и их суммарный размер заранее (nil = количество неизвестно).
+	count func() (files, bytes int64, err error)
+}
+
+// runDuplicateReport ищет одинаковые исходные файлы и печатает отчёт (текст или JSON).
+func runDuplicateReport(ctx context.Context) error {
+	src, closeSrc, err := openSource()
+	if err != nil {
+		return err
+	}
+	defer closeSrc()
+
+	files, errs := src.scan(ctx)
+	report, err := dupes.Find(ctx, files, cfg.MaxWorkers())
+	if err != nil {
+		return fmt.Errorf("ошибка поиска дубликатов: %w", err)
+	}
+	if err := <-errs; err != nil {
+		return fmt.Errorf("ошибка сканирования: %w", err)
+	}
+
+	if cfg.JSONOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	if len(report.Groups) == 0 {
+		fmt.Printf("✅ Дубликатов не найдено (проверено файлов: %d)\n", report.Files)
+		return nil
+	}
+
+	for i, g := range report.Groups {
+		fmt.Printf("🔁 Группа %d (файлов: %d, по %s, sha256 %s)\n",
+			i+1, len(g.Paths), worker.FormatBytes(g.Size), g.SHA256[:12])
+		for _, p := range g.Paths {
+			fmt.Printf("   %s\n", p)
 		}
 	}
+	fmt.Printf("\n📊 Проверено файлов: %d, групп дубликатов: %d, лишних копий: %d, занято впустую: %s\n",
+		report.Files, len(report.Groups), report.DuplicateFiles, worker.FormatBytes(report.WastedBytes))
+	return nil
+}
+
+// runEstimate конвертирует выборку файлов во временную директорию
+// и печатает прогноз размера результата для всего набора (текст или JSON).
+func runEstimate(ctx context.Context, conv converter.Converter) error {
+	src, closeSrc, err := openSource()
+	if err != nil {
+		return err
+	}
+	defer closeSrc()
+
+	scanned, errs := src.scan(ctx)
+	var files []scanner.File
+	for f := range scanned {
+		files = append(files, f)
+	}
+	if err := <-errs; err != nil {
+		return fmt.Errorf("ошибка сканирования: %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("нет файлов для оценки")
+	}
+
+	result, err := estimate.Run(ctx, cfg, conv, files)
+	if err != nil {
+		return err
+	}
+	if result.SampleFiles == 0 {
+		return fmt.Errorf("не удалось сконвертировать ни одного файла выборки (ошибок: %d)", result.SampleFailed)
+	}
 
+	if cfg.JSONOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+
+	fmt.Printf("📏 Оценка по выборке: %d из %d файлов (%s -> %s)\n", result.SampleFiles, result.Files,
+		worker.FormatBytes(result.SampleInputBytes), worker.FormatBytes(result.SampleOutputBytes))
+	if result.SampleFailed > 0 {
+		fmt.Printf("   ⚠️  Файлов выборки с ошибкой: %d (в оценку не вошли)\n", result.SampleFailed)
+	}
+	fmt.Printf("   Исходники: %s\n", worker.FormatBytes(result.InputBytes))
+	fmt.Printf("   Прогноз результата: %s (±%.0f%%)\n", worker.FormatBytes(result.ProjectedOutputBytes), result.Margin*100)
+	if result.ProjectedSavingsBytes >= 0 {
+		fmt.Printf("   Прогноз экономии: %s (%.1f%%)\n", worker.FormatBytes(result.ProjectedSavingsBytes), result.SavingsPercent)
+	} else {
+		fmt.Printf("   ⚠️  Результат больше исходников на %s (%.1f%%)\n", worker.FormatBytes(-result.ProjectedSavingsBytes), -result.SavingsPercent)
+	}
+	fmt.Println("\nℹ️  Это прогноз по выборке: погрешность указана для доверия 95% и растёт, если файлы")
+	fmt.Println("   сильно различаются по содержимому и размеру. Для точной оценки увеличьте --estimate-sample.")
+	return nil
+}
+
+// openSource выбирает источник файлов: --from-file, архив в --in или директорию.
+// Возвращённая функция освобождает ресурсы источника (временные файлы архива).
+func openSource() (fileSource, func(), error) {
+	switch {
+	case cfg.FromFile != "":
+		list, closeList, err := openFileList(cfg.FromFile)
+		if err != nil {
+			return fileSource{}, nil, err
+		}
+		return fileSource{
+			scan: func(ctx context.Context) (<-chan scanner.File, <-chan error) {
+				return scanner.FromList(ctx, cfg, list)
+			},
+		}, closeList, nil
+
+	case scanner.IsArchive(cfg.InputDir):
+		archive, err := scanner.OpenArchive(cfg, cfg.InputDir)
+		if err != nil {
+			return fileSource{}, nil, err
+		}
+		closeArchive := func() {
+			if err := archive.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Не удалось удалить временные файлы архива: %v\n", err)
+			}
+		}
+		return fileSource{scan: archive.Scan, count: archive.Count}, closeArchive, nil
+
+	default:
+		scan := scanner.New(cfg)
+		return fileSource{scan: scan.Scan, count: scan.Count}, func() {}, nil
+	}
+}
+
+// openFileList открывает список входных путей для --from-file ("-" = stdin).
+func openFileList(path string) (io.Reader, func(), error) {
+	if path == "-" {
+		return os.Stdin, func() {}, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("не удалось открыть список файлов: %w", err)
+	}
+	return f, func() { _ = f.Close() }, nil
+}
+
+// printDryRunPlan выводит итоги dry-run по категориям; с кэшем -
+// сколько файлов плана возьмётся из кэша и сколько будет сконвертировано.
+func printDryRunPlan(w io.Writer, plan worker.PlanStats, cacheEnabled bool) {
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "📋 План (dry-run):\n")
+	fmt.Fprintf(w, "   %-9s %6d  новые файлы\n", worker.PlanNew, plan.New)
+	fmt.Fprintf(w, "   %-9s %6d  уже сконвертированы\n", worker.PlanSkip, plan.Skip)
+	fmt.Fprintf(w, "   %-9s %6d  прошлая попытка завершилась ошибкой\n", worker.PlanRetry, plan.Retry)
+	fmt.Fprintf(w, "   %-9s %6d  результат существует, но не записан в БД (будет перезаписан)\n", worker.PlanOverwrite, plan.Overwrite)
+	if cacheEnabled {
+		fmt.Fprintf(w, "   Из кэша: %d, конвертаций: %d, пропусков по БД: %d\n", plan.CacheHits, plan.Conversions(), plan.Skip)
+	}
+}
+
+// writeManifest записывает JSON манифест по задачам текущего запуска.
+// В dry-run БД не меняется, поэтому используется план из пула.
+func writeManifest(store *storage.Storage, pool *worker.Pool, stats worker.Stats, startTime time.Time) error {
+	jobs := pool.PlannedJobs()
+	if !cfg.DryRun {
+		var err error
+		if jobs, err = store.ListJobsForRun(startTime); err != nil {
+			return err
+		}
+	}
+
+	m := manifest.Build(cfg, jobs, manifest.Totals{
+		Processed:   stats.Processed,
+		Skipped:     stats.Skipped,
+		Failed:      stats.Failed,
+		Total:       stats.Total,
+		InputBytes:  stats.InputBytes,
+		OutputBytes: stats.OutputBytes,
+	}, startTime, time.Now())
+
+	return m.WriteFile(cfg.ManifestPath)
+}
+
+// notifyWebhook отправляет итоги запуска на cfg.WebhookURL.
+// Отправка не прерывается по Ctrl+C: уведомление о прерванном запуске тоже нужно.
+func notifyWebhook(ctx context.Context, stats worker.Stats, startTime time.Time) error {
+	return webhook.New().Send(context.WithoutCancel(ctx), cfg.WebhookURL, webhookPayload(stats, startTime, time.Now()))
+}
+
+// findVips ищет vips с версией, достаточной для выходных форматов,
+// и проверяет поддержку форматов до начала конвертации.
+func findVips() (string, error) {
+	finder := vipsfinder.NewFinder(cfg.VipsPath)
+	formats := outputFormats()
+	for _, format := range formats {
+		if v := vipsfinder.MinVersionForFormat(format); vipsfinder.CompareVersions(v, finder.MinVersion) > 0 {
+			finder.MinVersion = v
+		}
+	}
+	vipsInfo, err := finder.Find()
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(stdout, "📦 Найден vips: %s (версия %s)\n", vipsInfo.Path, vipsInfo.Version)
+
+	for _, format := range formats {
+		if err := vipsInfo.CheckFormat(format); err != nil {
+			return "", err
+		}
+	}
+	return vipsInfo.Path, nil
+}
+
+// webhookPayload собирает тело уведомления из итоговой статистики.
+func webhookPayload(stats worker.Stats, startTime, finishTime time.Time) webhook.Payload {
+	status := "ok"
+	if stats.Failed > 0 {
+		status = "failed"
+	}
+	return webhook.Payload{
+		Status:       status,
+		DryRun:       cfg.DryRun,
+		Processed:    stats.Processed,
+		Skipped:      stats.Skipped,
+		Failed:       stats.Failed,
+		Total:        stats.Total,
+		InputBytes:   stats.InputBytes,
+		OutputBytes:  stats.OutputBytes,
+		SavedBytes:   stats.SavedBytes(),
+		SavedPercent: stats.SavedPercent(),
+		StartedAt:    startTime,
+		FinishedAt:   finishTime,
+		DurationSec:  finishTime.Sub(startTime).Seconds(),
+	}
+}
+
+// packageZip упаковывает выходную директорию в zip архив (--zip).
+func packageZip() error {
+	files, err := archive.WriteZip(cfg.OutputDir, cfg.ZipOutput)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "📦 Архив сохранён: %s (файлов: %d)\n", cfg.ZipOutput, len(files))
+
+	if cfg.ZipRemoveFiles {
+		if err := archive.RemoveFiles(cfg.OutputDir, files); err != nil {
+			return err
+		}
+		if cfg.Verbose() {
+			fmt.Fprintf(stdout, "🧹 Удалено файлов из %s: %d\n", cfg.OutputDir, len(files))
+		}
+	}
 	return nil
 }
 
 // exportToPDF создаёт PDF альбом из обработанных изображений.
-func exportToPDF(ctx context.Context) error {
+// Порядок страниц задаётся --sort-by/--sort-desc по данным исходных файлов из БД.
+func exportToPDF(ctx context.Context, store *storage.Storage) error {
 	pdfExporter := converter.NewPDFExporter(cfg.VipsPath, cfg)
 
 	// Собираем изображения
@@ -483,6 +1316,22 @@ func exportToPDF(ctx context.Context) error {
 		return fmt.Errorf("не удалось собрать изображения: %w", err)
 	}
 
+	// Сортировка по дате/размеру должна учитывать исходные файлы, а не результаты
+	sources, err := store.SourcesByDstPath()
+	if err != nil {
+		return err
+	}
+	for i := range images {
+		path := images[i].Path
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		if src, ok := sources[path]; ok {
+			images[i].Size = src.Size
+			images[i].Mtime = src.Mtime
+		}
+	}
+
 	if len(images) == 0 {
 		return fmt.Errorf("нет изображений для PDF")
 	}
@@ -493,13 +1342,19 @@ func exportToPDF(ctx context.Context) error {
 		pdfPath = filepath.Join(cfg.OutputDir, "album.pdf")
 	}
 
-	fmt.Printf("📚 Создание PDF альбома (%d изображений)...\n", len(images))
+	// PDF собран из тех же изображений с теми же настройками - не пересобираем
+	if !cfg.PDFForce && pdfExporter.UpToDate(images, pdfPath) {
+		fmt.Fprintf(stdout, "📚 PDF не изменился: %s (пересобрать: --pdf-force)\n", pdfPath)
+		return nil
+	}
+
+	fmt.Fprintf(stdout, "📚 Создание PDF альбома (%d изображений)...\n", len(images))
 
 	if err := pdfExporter.ExportToPDF(ctx, images, pdfPath); err != nil {
 		return err
 	}
 
-	fmt.Printf("✅ PDF сохранён: %s\n", pdfPath)
+	fmt.Fprintf(stdout, "✅ PDF сохранён: %s\n", pdfPath)
 	return nil
 }
 
@@ -518,15 +1373,17 @@ func runWatchMode(ctx context.Context, pool *worker.Pool) error {
 		return fmt.Errorf("ошибка запуска watch: %w", err)
 	}
 
-	fmt.Println("👁️  Слежение запущено. Нажмите Ctrl+C для остановки.")
+	fmt.Fprintln(stdout, "👁️  Слежение запущено. Нажмите Ctrl+C для остановки.")
 
 	// Прогресс-бар для watch mode (без общего счётчика)
 	progressBar := progress.New(progress.Options{
 		Total:       -1, // Бесконечный режим
 		Description: "👁️ Watch",
-		Disabled:    cfg.NoProgress,
+		Disabled:    cfg.NoProgress || (cfg.ProgressFormat != progress.FormatJSON && cfg.Quiet()),
+		Format:      cfg.ProgressFormat,
 	})
 	pool.SetProgressBar(progressBar)
+	defer startPauseKeys(ctx, pool)()
 
 	// Канал для получения статистики
 	statsChan := make(chan worker.Stats, 1)
@@ -541,15 +1398,15 @@ func runWatchMode(ctx context.Context, pool *worker.Pool) error {
 	select {
 	case <-ctx.Done():
 		// Контекст отменён (Ctrl+C)
-		fmt.Println("\n⏹️  Останавливаем слежение...")
+		fmt.Fprintln(stdout, "\n⏹️  Останавливаем слежение...")
 	case stats := <-statsChan:
 		// Обработка завершилась (не должно происходить в watch mode)
 		progressBar.Finish()
-		fmt.Println()
-		fmt.Printf("📊 Результаты watch режима:\n")
-		fmt.Printf("   Обработано: %d\n", stats.Processed)
-		fmt.Printf("   Пропущено: %d\n", stats.Skipped)
-		fmt.Printf("   Ошибок: %d\n", stats.Failed)
+		fmt.Fprintln(stdout)
+		fmt.Fprintf(stdout, "📊 Результаты watch режима:\n")
+		fmt.Fprintf(stdout, "   Обработано: %d\n", stats.Processed)
+		fmt.Fprintf(stdout, "   Пропущено: %d\n", stats.Skipped)
+		fmt.Fprintf(stdout, "   Ошибок: %d\n", stats.Failed)
 		return nil
 	}
 
@@ -557,11 +1414,11 @@ func runWatchMode(ctx context.Context, pool *worker.Pool) error {
 	stats := <-statsChan
 	progressBar.Finish()
 
-	fmt.Println()
-	fmt.Printf("📊 Результаты watch режима:\n")
-	fmt.Printf("   Обработано: %d\n", stats.Processed)
-	fmt.Printf("   Пропущено: %d\n", stats.Skipped)
-	fmt.Printf("   Ошибок: %d\n", stats.Failed)
+	fmt.Fprintln(stdout)
+	fmt.Fprintf(stdout, "📊 Результаты watch режима:\n")
+	fmt.Fprintf(stdout, "   Обработано: %d\n", stats.Processed)
+	fmt.Fprintf(stdout, "   Пропущено: %d\n", stats.Skipped)
+	fmt.Fprintf(stdout, "   Ошибок: %d\n", stats.Failed)
 
 	return nil
 }
@@ -605,16 +1462,107 @@ func newStatsCmd() *cobra.Command {
 			fmt.Printf("   Ошибок: %d\n", failed)
 			fmt.Printf("   В процессе: %d\n", inProgress)
 
+			if byFormat, _ := cmd.Flags().GetBool("by-format"); byFormat {
+				formats, err := store.StatsByFormat()
+				if err != nil {
+					return err
+				}
+				printFormatStats(cmd.OutOrStdout(), formats)
+			}
+
 			return nil
 		},
 	}
 
 	cmd.Flags().String("db", "", "Путь к SQLite базе данных")
+	cmd.Flags().Bool("by-format", false, "Разбивка по выходным форматам")
 	_ = cmd.MarkFlagRequired("db")
 
 	return cmd
 }
 
+// reportRoot возвращает префикс путей исходников для --report-by-dir:
+// входная директория или архив; для --from-file - пусто (группировка
+// по родительской директории файла).
+func reportRoot() string {
+	if cfg.FromFile != "" {
+		return ""
+	}
+	abs, err := filepath.Abs(cfg.InputDir)
+	if err != nil {
+		abs = cfg.InputDir
+	}
+	if scanner.IsArchive(cfg.InputDir) {
+		return abs + "!/"
+	}
+	if cfg.InputIsFile() {
+		abs = filepath.Dir(abs)
+	}
+	return abs + string(filepath.Separator)
+}
+
+// printDirStats печатает размеры и экономию по директориям верхнего уровня.
+func printDirStats(w io.Writer, dirs []storage.DirStats) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "📂 По директориям:")
+	if len(dirs) == 0 {
+		fmt.Fprintln(w, "   нет успешных задач")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "   ДИРЕКТОРИЯ	ФАЙЛОВ	ВХОД	ВЫХОД	ЭКОНОМИЯ")
+	for _, d := range dirs {
+		fmt.Fprintf(tw, "   %s	%d	%s	%s	%.1f%%\n",
+			d.Dir, d.Files, worker.FormatBytes(d.SrcBytes), worker.FormatBytes(d.DstBytes), d.SavedPercent())
+	}
+	_ = tw.Flush()
+}
+
+// printFailures печатает ошибки запуска по причинам с примерами файлов.
+func printFailures(w io.Writer, groups []worker.FailureGroup) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "❌ Ошибки по причинам:")
+	for _, g := range groups {
+		fmt.Fprintf(w, "   %d %s: %s\n", g.Count, pluralFiles(g.Count), g.Reason)
+		for _, e := range g.Examples {
+			fmt.Fprintf(w, "      %s: %s\n", e.Path, e.Error)
+		}
+		if more := g.Count - int64(len(g.Examples)); more > 0 {
+			fmt.Fprintf(w, "      … и ещё %d\n", more)
+		}
+	}
+}
+
+// pluralFiles возвращает слово "файл" в форме для числа n.
+func pluralFiles(n int64) string {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return "файл"
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return "файла"
+	default:
+		return "файлов"
+	}
+}
+
+// printFormatStats печатает статистику задач по выходным форматам.
+func printFormatStats(w io.Writer, formats []storage.FormatStats) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "📦 По форматам:")
+	if len(formats) == 0 {
+		fmt.Fprintln(w, "   нет задач")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "   ФОРМАТ\tВСЕГО\tOK\tОШИБОК\tВ ПРОЦЕССЕ\tВХОД\tВЫХОД")
+	for _, f := range formats {
+		fmt.Fprintf(tw, "   %s\t%d\t%d\t%d\t%d\t%s\t%s\n",
+			f.Format, f.Total, f.OK, f.Failed, f.InProgress,
+			worker.FormatBytes(f.SrcBytes), worker.FormatBytes(f.DstBytes))
+	}
+	_ = tw.Flush()
+}
+
 // Execute запускает CLI.
 func Execute() {
 	if err := NewRootCmd().Execute(); err != nil {
@@ -0,0 +1,21 @@
+package cli
+
+import "golang.org/x/sys/unix"
+
+// setCbreak отключает построчный ввод и эхо терминала fd (Ctrl+C продолжает работать).
+// Без ввода чтение возвращается пустым через 0,1 с, чтобы читатель мог
+// остановиться. Возвращает функцию восстановления прежнего режима.
+func setCbreak(fd int) (func(), error) {
+	old, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return nil, err
+	}
+	t := *old
+	t.Lflag &^= unix.ICANON | unix.ECHO
+	t.Cc[unix.VMIN] = 0
+	t.Cc[unix.VTIME] = 1
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, &t); err != nil {
+		return nil, err
+	}
+	return func() { _ = unix.IoctlSetTermios(fd, unix.TCSETS, old) }, nil
+}
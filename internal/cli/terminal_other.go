@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package cli
+
+import "errors"
+
+// setCbreak не поддерживается: клавиша паузы срабатывает после Enter.
+func setCbreak(fd int) (func(), error) {
+	return nil, errors.New("посимвольный ввод не поддерживается")
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/webhook"
+	"github.com/artemshloyda/photoconverter/internal/worker"
+)
+
+func TestNotifyWebhook_SendsStats(t *testing.T) {
+	received := make(chan webhook.Payload, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p webhook.Payload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("тело запроса: %v", err)
+		}
+		received <- p
+	}))
+	defer srv.Close()
+
+	old := cfg.WebhookURL
+	cfg.WebhookURL = srv.URL
+	defer func() { cfg.WebhookURL = old }()
+
+	stats := worker.Stats{Processed: 5, Skipped: 2, Failed: 1, Total: 8, InputBytes: 4000, OutputBytes: 1000}
+	if err := notifyWebhook(t.Context(), stats, time.Now().Add(-2*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	p := <-received
+	if p.Status != "failed" || p.Processed != 5 || p.Skipped != 2 || p.Failed != 1 || p.Total != 8 {
+		t.Errorf("счётчики: %+v", p)
+	}
+	if p.InputBytes != 4000 || p.OutputBytes != 1000 || p.SavedBytes != 3000 || p.SavedPercent != 75 {
+		t.Errorf("размеры: %+v", p)
+	}
+	if p.DurationSec < 2 {
+		t.Errorf("duration_sec = %v, want >= 2", p.DurationSec)
+	}
+}
@@ -2,11 +2,16 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 // BatchPreset представляет именованный пресет конфигурации.
@@ -174,6 +179,88 @@ func DeletePreset(name string) error {
 	return nil
 }
 
+// ExportPreset записывает файл пресета в w без изменений (с комментариями).
+func ExportPreset(name string, w io.Writer) error {
+	presetPath, err := GetPresetPath(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(presetPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("пресет '%s' не найден", name)
+		}
+		return fmt.Errorf("не удалось прочитать пресет: %w", err)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("не удалось записать пресет: %w", err)
+	}
+	return nil
+}
+
+// ParsePreset разбирает и проверяет содержимое файла пресета.
+// Неизвестные ключи считаются ошибкой, итоговые настройки проверяются
+// так же, как при запуске конвертации.
+func ParsePreset(data []byte) (*FileConfig, error) {
+	var fc FileConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&fc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("ошибка парсинга YAML: %w", err)
+	}
+
+	// Директории в пресете необязательны: подставляем заглушки для проверки
+	cfg := DefaultConfig()
+	cfg.InputDir, cfg.OutputDir = ".", "."
+	fc.ApplyToConfig(cfg)
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("некорректный пресет: %w", err)
+	}
+
+	return &fc, nil
+}
+
+// ImportPreset проверяет содержимое пресета и сохраняет его под именем name
+// (очищенным от небезопасных символов). Существующий пресет перезаписывается
+// только при force. Возвращает путь к сохранённому файлу.
+func ImportPreset(name string, data []byte, force bool) (string, error) {
+	if _, err := ParsePreset(data); err != nil {
+		return "", err
+	}
+
+	presetPath, err := GetPresetPath(name)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := EnsurePresetsDir(); err != nil {
+		return "", err
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !force {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(presetPath, flags, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return "", fmt.Errorf("пресет '%s' уже существует (используйте --force для перезаписи)", sanitizePresetName(name))
+		}
+		return "", fmt.Errorf("не удалось сохранить пресет: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return "", fmt.Errorf("не удалось сохранить пресет: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("не удалось сохранить пресет: %w", err)
+	}
+
+	return presetPath, nil
+}
+
 // PresetExists проверяет существование пресета.
 func PresetExists(name string) bool {
 	presetPath, err := GetPresetPath(name)
@@ -189,6 +276,5 @@ func PresetExists(name string) bool {
 Возможные расширения:
 - Добавить описание к пресетам
 - Добавить теги для группировки пресетов
-- Добавить импорт/экспорт пресетов
 - Добавить наследование пресетов (extends)
 */
@@ -6,9 +6,12 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Mode определяет режим работы утилиты.
@@ -21,6 +24,30 @@ const (
 	ModeDedup Mode = "dedup"
 )
 
+// MemoryAuto - значение MaxMemoryMB для адаптивного ограничения памяти
+// по доступной памяти системы.
+const MemoryAuto = -1
+
+// WorkersAuto - значение Workers для автоподбора числа воркеров по ходу
+// обработки (по среднему размеру файлов, памяти и пропускной способности).
+const WorkersAuto = -1
+
+// Уровни подробности вывода (Config.Verbosity).
+const (
+	// VerbosityQuiet - только ошибки и предупреждения (--quiet, для cron).
+	VerbosityQuiet = -1
+
+	// VerbosityNormal - параметры запуска, прогресс и итоги.
+	VerbosityNormal = 0
+
+	// VerbosityVerbose - дополнительно каждый обработанный и пропущенный файл (-v).
+	VerbosityVerbose = 1
+
+	// VerbosityDebug - дополнительно итоговая конфигурация и командные строки
+	// vips и других программ для каждого файла (-vv).
+	VerbosityDebug = 2
+)
+
 // OutputFormat определяет выходной формат изображения.
 type OutputFormat string
 
@@ -34,9 +61,100 @@ const (
 	FormatJXL  OutputFormat = "jxl"
 )
 
+// formatExtensions - расширения, допустимые для формата кроме его имени.
+// vips и ImageMagick выбирают кодировщик по расширению, поэтому допустимы
+// только расширения, которые они относят к тому же формату.
+var formatExtensions = map[OutputFormat][]string{
+	FormatJPEG: {"jpeg", "jpe"},
+	FormatTIFF: {"tif"},
+	FormatHEIC: {"heif"},
+}
+
+// IsExtensionOf проверяет, подходит ли расширение ext (без точки) формату f.
+func (f OutputFormat) IsExtensionOf(ext string) bool {
+	ext = strings.ToLower(ext)
+	if ext == string(f) {
+		return true
+	}
+	for _, e := range formatExtensions[f] {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidFormats возвращает список выходных форматов.
+func ValidFormats() []OutputFormat {
+	return []OutputFormat{FormatWebP, FormatJPEG, FormatPNG, FormatAVIF, FormatTIFF, FormatHEIC, FormatJXL}
+}
+
+// Valid проверяет, является ли f известным выходным форматом.
+func (f OutputFormat) Valid() bool {
+	for _, v := range ValidFormats() {
+		if f == v {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseFormatMap разбирает соответствия "ext=format" (--map heic=jpg)
+// в карту расширение исходника -> выходной формат.
+func ParseFormatMap(pairs []string) (map[string]OutputFormat, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	m := make(map[string]OutputFormat, len(pairs))
+	for _, pair := range pairs {
+		ext, format, ok := strings.Cut(pair, "=")
+		ext = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(ext)), ".")
+		f := OutputFormat(strings.ToLower(strings.TrimSpace(format)))
+		if !ok || ext == "" || !f.Valid() {
+			return nil, fmt.Errorf("неверное соответствие --map %q: ожидается расширение=формат (доступны форматы: %v)", pair, ValidFormats())
+		}
+		m[ext] = f
+	}
+	return m, nil
+}
+
+// ParseFormatConcurrency разбирает ограничения "format=N"
+// (--concurrency-per-format avif=2) в карту формат -> число конвертаций.
+func ParseFormatConcurrency(pairs []string) (map[OutputFormat]int, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	m := make(map[OutputFormat]int, len(pairs))
+	for _, pair := range pairs {
+		format, value, ok := strings.Cut(pair, "=")
+		f := OutputFormat(strings.ToLower(strings.TrimSpace(format)))
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || !f.Valid() || err != nil || n < 1 {
+			return nil, fmt.Errorf("неверное ограничение --concurrency-per-format %q: ожидается формат=число >= 1 (доступны форматы: %v)", pair, ValidFormats())
+		}
+		m[f] = n
+	}
+	return m, nil
+}
+
+// WithFormatFor возвращает конфигурацию для исходника srcPath: копию
+// с форматом из FormatMap по расширению исходника или саму c, если
+// расширения нет в карте. Хэш параметров выхода копии отражает её формат.
+func (c *Config) WithFormatFor(srcPath string) *Config {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(srcPath)), ".")
+	format, ok := c.FormatMap[ext]
+	if !ok || format == c.OutputFormat {
+		return c
+	}
+	v := *c
+	v.OutputFormat = format
+	return &v
+}
+
 // Config содержит все настройки для конвертации.
 type Config struct {
-	// InputDir - директория с исходными изображениями.
+	// InputDir - директория с исходными изображениями
+	// (или один файл, см. InputIsFile).
 	InputDir string
 
 	// OutputDir - директория для сохранения результатов.
@@ -45,57 +163,300 @@ type Config struct {
 	// InputExtensions - список расширений входных файлов (без точки, lowercase).
 	InputExtensions []string
 
+	// DetectByContent - принимать и файлы с другим расширением (или без него),
+	// если формат по сигнатуре в начале файла входит в InputExtensions.
+	DetectByContent bool
+
+	// Include - glob-шаблоны включаемых файлов относительно входной директории (пусто = все).
+	Include []string
+
+	// Exclude - glob-шаблоны исключаемых файлов (например "**/raw/**").
+	Exclude []string
+
+	// FromFile - файл со списком входных путей ("-" = stdin) вместо обхода InputDir.
+	FromFile string
+
+	// IgnoreFile - файл со списком исходников, которые помечаются в БД
+	// игнорируемыми: они пропускаются без конвертации и в следующих запусках.
+	IgnoreFile string
+
+	// ClearIgnored - снять все пометки игнорирования перед запуском.
+	ClearIgnored bool
+
 	// OutputFormat - формат выходных файлов.
 	OutputFormat OutputFormat
 
+	// OutputExtension - расширение выходных файлов без точки вместо
+	// расширения формата (jpeg вместо jpg; пусто = по формату, см. OutputExt).
+	OutputExtension string
+
+	// FormatMap - выходной формат по расширению исходника (без точки,
+	// lowercase; --map heic=jpg). Для расширений вне карты - OutputFormat.
+	FormatMap map[string]OutputFormat
+
 	// Quality - качество для lossy форматов (1-100).
 	Quality int
 
-	// Workers - количество параллельных воркеров.
+	// Workers - количество параллельных воркеров (-1 = автоподбор, см. WorkersAuto).
 	Workers int
 
+	// ConcurrencyPerFormat - наибольшее число одновременных конвертаций
+	// в выходной формат (avif: 2); остальные форматы ограничены только Workers.
+	ConcurrencyPerFormat map[OutputFormat]int
+
+	// Timeout - таймаут конвертации одного файла (0 = по умолчанию, 5 минут).
+	Timeout time.Duration
+
 	// DBPath - путь к SQLite базе данных.
 	DBPath string
 
+	// NoBackup - не создавать резервную копию БД перед миграцией старой схемы.
+	NoBackup bool
+
+	// DBBatchSize - сколько завершений задач записывать в БД одной транзакцией
+	// (0 = каждое отдельно).
+	DBBatchSize int
+
+	// DBBatchInterval - максимальная задержка записи неполного пакета.
+	DBBatchInterval time.Duration
+
 	// Mode - режим работы (skip/dedup).
 	Mode Mode
 
+	// DedupHash - алгоритм хэша содержимого в режиме dedup: sha256, blake3, xxhash.
+	DedupHash string
+
+	// DedupQuick - в режиме dedup хэшировать только размер, начало и конец файла;
+	// совпадения быстрых ключей подтверждаются полным хэшем.
+	DedupQuick bool
+
+	// DedupQuickBytes - сколько байт с начала и с конца файла учитывать в быстром ключе.
+	DedupQuickBytes int64
+
+	// DedupLink - что создавать на месте дубликата в дереве выхода (режим dedup
+	// с KeepTree): none (ничего), symlink или hardlink на уже сконвертированный файл.
+	DedupLink string
+
 	// KeepTree - сохранять структуру директорий.
 	KeepTree bool
 
+	// FlatNaming - имена файлов в плоской структуре (без KeepTree):
+	// basename (по умолчанию), hashed или pathjoined, см. FlatNaming* константы.
+	FlatNaming string
+
+	// OrganizeByDate - раскладывать результаты по директориям даты съёмки
+	// (EXIF DateTimeOriginal, без EXIF - mtime исходника) вместо KeepTree.
+	// Имена внутри директории даты - по FlatNaming.
+	OrganizeByDate bool
+
+	// DateFolderTemplate - шаблон директории даты: YYYY, MM, DD заменяются
+	// годом, месяцем и днём (по умолчанию YYYY/MM/DD).
+	DateFolderTemplate string
+
 	// DryRun - режим симуляции без реальной конвертации.
 	DryRun bool
 
+	// NoVerify - не проверять результат конвертации через vipsheader.
+	NoVerify bool
+
+	// NoDirConfig - не применять настройки поддиректорий (DirConfigName).
+	NoDirConfig bool
+
+	// NoOOMRetry - не повторять конвертацию с уменьшением изображения,
+	// если vips не хватило памяти.
+	NoOOMRetry bool
+
+	// Force - игнорировать задачи прошлых запусков в БД и конвертировать
+	// все файлы заново, перезаписывая существующие результаты.
+	Force bool
+
+	// Resume - при старте разобрать прерванные задачи: готовые результаты
+	// засчитать, остальные выполнить заново (вместо пометки failed).
+	Resume bool
+
+	// DeleteSource - удалять исходник после успешной и проверенной конвертации
+	// (требует IUnderstand).
+	DeleteSource bool
+
+	// TrashDir - директория, куда исходники перемещаются после успешной
+	// конвертации (с сохранением относительного пути) вместо удаления.
+	TrashDir string
+
+	// Mirror - после обработки удалять результаты (и задачи в БД) исходников,
+	// которых больше нет во входной директории: выход повторяет вход.
+	Mirror bool
+
+	// IUnderstand - явное подтверждение необратимого удаления исходников (--i-understand).
+	IUnderstand bool
+
+	// SkipExistingOutput - пропускать файл, если выходной файл уже есть на диске,
+	// независимо от БД (например, после удаления БД или от другой утилиты).
+	SkipExistingOutput bool
+
+	// VerifyOutputs - перед пропуском уже успешного файла проверять его
+	// результат: удалённый или изменившийся в размере (по dst_size в БД)
+	// результат конвертируется заново.
+	VerifyOutputs bool
+
+	// OnConflict - что делать с выходным файлом, который уже есть на диске,
+	// но не записан в БД: overwrite (перезаписать), skip (пропустить файл)
+	// или rename (записать результат в name-1.ext).
+	OnConflict string
+
+	// ReportDuplicates - только вывести группы одинаковых исходных файлов, без конвертации.
+	ReportDuplicates bool
+
+	// Estimate - только оценить размер результата по выборке файлов, без конвертации.
+	Estimate bool
+
+	// EstimateSample - доля файлов, конвертируемых для оценки (0 < x <= 1).
+	EstimateSample float64
+
+	// JSONOutput - выводить отчёт (--report-duplicates) в JSON.
+	JSONOutput bool
+
 	// VipsPath - путь к vips бинарнику (опционально).
 	VipsPath string
 
+	// Backend - движок конвертации (пусто = vips, см. Backend* константы).
+	Backend string
+
+	// MagickPath - путь к ImageMagick (magick или convert; пусто = автопоиск).
+	MagickPath string
+
+	// MagickFallback - конвертировать через ImageMagick файлы, формат
+	// которых vips не читает (PSD, часть RAW).
+	MagickFallback bool
+
+	// RawDecoderPath - путь к декодеру RAW (dcraw или dcraw_emu из libraw;
+	// пусто = автопоиск). RAW исходники декодируются им в TIFF перед vips.
+	RawDecoderPath string
+
 	// StripMetadata - удалять метаданные из изображений.
 	StripMetadata bool
 
-	// Verbose - подробный вывод.
-	Verbose bool
+	// StripGPS - удалять только GPS теги, сохраняя остальные метаданные (требуется exiftool).
+	StripGPS bool
+
+	// Verbosity - подробность вывода: VerbosityQuiet, VerbosityNormal,
+	// VerbosityVerbose (-v) или VerbosityDebug (-vv).
+	Verbosity int
 
 	// NoProgress - отключить прогресс-бар.
 	NoProgress bool
 
+	// ProgressFormat - формат вывода прогресса: bar (по умолчанию) или json.
+	ProgressFormat string
+
+	// PreserveMtime - переносить время модификации и доступа исходника на результат.
+	PreserveMtime bool
+
+	// ProgressByBytes - прогресс по объёму данных: бар растёт на размер файла,
+	// скорость (MB/s) и ETA считаются в байтах.
+	ProgressByBytes bool
+
+	// FailFast - остановить запуск на первой ошибке конвертации
+	// (по умолчанию обрабатываются все файлы).
+	FailFast bool
+
+	// QuarantineDir - директория для копий файлов, которые не удалось сконвертировать
+	// (пусто = не копировать).
+	QuarantineDir string
+
+	// LogFile - путь к журналу обработки (JSON Lines, пусто = не писать).
+	LogFile string
+
+	// MetricsAddr - адрес HTTP-сервера метрик Prometheus (пусто = выключен).
+	MetricsAddr string
+
+	// StatusAddr - адрес HTTP-сервера с JSON статусом запуска (пусто = выключен).
+	StatusAddr string
+
+	// WebhookURL - URL для POST-уведомления с итогами запуска (пусто = не отправлять).
+	WebhookURL string
+
+	// ManifestPath - путь к JSON манифесту запуска (пусто = не писать).
+	ManifestPath string
+
+	// ReportByDir - после запуска вывести размеры и экономию по директориям
+	// верхнего уровня входа.
+	ReportByDir bool
+
+	// ReportFailures - после запуска вывести ошибки, сгруппированные
+	// по причинам, с примерами файлов.
+	ReportFailures bool
+
+	// ZipOutput - путь к zip архиву с результатами (пусто = не упаковывать).
+	ZipOutput string
+
+	// ZipRemoveFiles - удалить упакованные файлы из OutputDir после создания архива.
+	ZipRemoveFiles bool
+
 	// MaxWidth - максимальная ширина изображения (0 = без ограничения).
 	MaxWidth int
 
 	// MaxHeight - максимальная высота изображения (0 = без ограничения).
 	MaxHeight int
 
+	// CropAspect - соотношение сторон результата ("16:9", "1:1"; пусто = без обрезки).
+	// Размер задают MaxWidth/MaxHeight, лишнее обрезается.
+	CropAspect string
+
+	// CropMode - какую часть оставлять при обрезке: centre (по центру)
+	// или attention (самую «интересную», vips smartcrop). Пусто = centre.
+	CropMode string
+
+	// SmartCrop - обрезка по заметной области (attention): с CropAspect
+	// равносильна CropMode attention, без него результат заполняет рамку
+	// MaxWidth x MaxHeight целиком (превью одного размера).
+	SmartCrop bool
+
+	// AllImages - выгружать все изображения многокадрового HEIC/HEIF (серии,
+	// Live Photos): основное пишется как обычно, остальные - с суффиксом
+	// номера. Без флага конвертируется только основное изображение.
+	AllImages bool
+
+	// PreserveAnimation - сохранять анимацию GIF/WebP, если выходной формат
+	// её поддерживает (webp). Иначе, как и без флага, остаётся первый кадр.
+	PreserveAnimation bool
+
+	// MaxFileSize - предельный размер выходного файла в байтах (0 = без ограничения).
+	// Если результат больше, файл перекодируется с меньшим качеством.
+	MaxFileSize int64
+
 	// Preset - профиль качества (web, print, archive).
 	Preset string
 
+	// MultiPresets - пресеты, по каждому из которых конвертируется каждый файл
+	// в поддиректорию <OutputDir>/<пресет> (пусто = один выход).
+	MultiPresets []string
+
 	// Watch - режим слежения за директорией.
 	Watch bool
 
+	// WatchDebounce - время ожидания после последнего события перед обработкой файла
+	// в режиме слежения (0 = 500ms).
+	WatchDebounce time.Duration
+
+	// WatchInitialScan - в режиме слежения сначала обработать уже существующие файлы.
+	WatchInitialScan bool
+
 	// Stream - потоковый режим без предварительного подсчёта файлов.
 	Stream bool
 
-	// MaxMemoryMB - ограничение использования памяти в мегабайтах (0 = без ограничения).
+	// MaxMemoryMB - ограничение использования памяти в мегабайтах
+	// (0 = без ограничения, -1 = адаптивно по доступной памяти системы).
 	MaxMemoryMB int
 
+	// MaxMegapixels - предельный размер исходника в мегапикселях (0 = без
+	// ограничения): гигапиксельные сканы не загружаются в память целиком.
+	// Что делать с большими файлами, задаёт MegapixelsAction.
+	MaxMegapixels float64
+
+	// MegapixelsAction - действие с исходником больше MaxMegapixels:
+	// skip (пропустить, по умолчанию) или downscale (уменьшить до лимита).
+	MegapixelsAction string
+
 	// UseGPU - использовать GPU ускорение (OpenCL).
 	UseGPU bool
 
@@ -111,12 +472,40 @@ type Config struct {
 	// WatermarkScale - масштаб водяного знака относительно изображения (0-100, 0 = без масштабирования).
 	WatermarkScale int
 
-	// CopyMetadata - копировать метаданные из исходного файла.
+	// CopyMetadata - явно копировать метаданные (EXIF/XMP/ICC) из исходного файла
+	// (требуется exiftool; несовместимо со StripMetadata).
 	CopyMetadata bool
 
 	// ColorProfile - целевой цветовой профиль (srgb, adobergb, p3).
 	ColorProfile string
 
+	// EmbedSRGB - встраивать стандартный профиль sRGB в результат,
+	// если у исходника нет ICC профиля (пиксели не меняются).
+	EmbedSRGB bool
+
+	// Sharpen - sigma нерезкого маскирования (vips sharpen) после resize
+	// (0 = выключено).
+	Sharpen float64
+
+	// SharpenAlways - повышать резкость и без resize.
+	SharpenAlways bool
+
+	// Blur - sigma гауссова размытия всего изображения (0 = выключено).
+	Blur float64
+
+	// Pixelate - размер блока пикселизации в пикселях (0 = выключено).
+	Pixelate int
+
+	// Grayscale - перевести изображение в оттенки серого.
+	Grayscale bool
+
+	// Sepia - тонировать изображение в сепию (оттенки серого с тёплым тоном).
+	Sepia bool
+
+	// DPI - плотность печати, записываемая в метаданные результата
+	// (0 = как у исходника). Размер в пикселях не меняется.
+	DPI int
+
 	// PDFOutput - создать PDF альбом из изображений.
 	PDFOutput bool
 
@@ -129,60 +518,458 @@ type Config struct {
 	// PDFQuality - качество изображений в PDF (1-100).
 	PDFQuality int
 
+	// PDFLayout - количество изображений на странице PDF: single, 2up, 4up, 9up.
+	PDFLayout string
+
+	// PDFCaptions - печатать имя файла под каждым изображением в PDF.
+	PDFCaptions bool
+
+	// PDFTitle - заголовок обложки PDF (пусто = без обложки).
+	PDFTitle string
+
+	// PDFPageNumbers - печатать номера страниц "n / total" в PDF.
+	PDFPageNumbers bool
+
+	// PDFForce - собирать PDF, даже если изображения и настройки не изменились.
+	PDFForce bool
+
+	// PDFMargin - поля страницы PDF в миллиметрах (0 = без полей в макете
+	// single и 10 мм в сетках).
+	PDFMargin int
+
+	// PDFBackground - цвет страницы PDF в виде #rrggbb (пусто = белый).
+	PDFBackground string
+
 	// RedisURL - URL для подключения к Redis (распределённая обработка).
 	RedisURL string
 
 	// WorkerMode - режим работы: master (раздаёт задачи) или worker (выполняет).
 	WorkerMode string
 
+	// BalanceBySize - master добавляет задачи в очередь от больших файлов
+	// к меньшим, чтобы воркеры получали близкую нагрузку в байтах.
+	BalanceBySize bool
+
+	// MaxAttempts - попыток задачи в распределённой очереди до переноса
+	// в dead-letter (0 = без повторов: неудачная задача сразу считается ошибкой).
+	MaxAttempts int
+
+	// StatsInterval - период вывода статистики очереди master-узлом (0 = не выводить).
+	StatsInterval time.Duration
+
+	// WorkerStdin - выполнять задачи из stdin (JSON по строке) и писать
+	// результаты в stdout, без сканирования входа, БД и очереди.
+	WorkerStdin bool
+
 	// CacheEnabled - включить кэширование промежуточных результатов.
 	CacheEnabled bool
 
-	// CacheDir - директория для кэша.
+	// CacheDir - директория для кэша: пусто - <out>/.photoconverter/cache,
+	// CacheDirGlobal - общий кэш пользователя, иначе путь. Общий кэш и кэш
+	// по пути ищут записи по содержимому исходника, а не по его пути.
 	CacheDir string
 
+	// CacheVerify - проверять SHA256 записей кэша при копировании из кэша:
+	// повреждённая запись удаляется и считается промахом.
+	CacheVerify bool
+
 	// SortBy - сортировка файлов: name, date, size.
 	SortBy string
 
 	// SortDesc - сортировка по убыванию.
 	SortDesc bool
+
+	// Skip - пропустить первые N подходящих файлов (в порядке обхода).
+	Skip int
+
+	// Limit - обработать не больше N подходящих файлов после Skip (0 = все).
+	Limit int
+
+	// ShardIndex и ShardTotal - обрабатывать только файлы своей части
+	// (--shard K/N: ShardIndex = K-1, ShardTotal = N; 0 = без разбиения).
+	ShardIndex int
+	ShardTotal int
 }
 
 // DefaultConfig возвращает конфигурацию по умолчанию.
 func DefaultConfig() *Config {
 	return &Config{
-		InputExtensions: []string{"jpg", "jpeg", "png", "heic", "heif", "webp", "tiff", "arw", "raw"},
-		OutputFormat:    FormatJPEG,
-		Quality:         80,
-		Workers:         runtime.NumCPU(),
-		Mode:            ModeSkip,
-		KeepTree:        true,
-		DryRun:          false,
-		StripMetadata:   false,
-		Verbose:         false,
+		InputExtensions:  []string{"jpg", "jpeg", "png", "heic", "heif", "webp", "tiff", "arw", "raw"},
+		OutputFormat:     FormatJPEG,
+		Quality:          80,
+		Workers:          runtime.NumCPU(),
+		Mode:             ModeSkip,
+		DedupHash:        "sha256",
+		DedupLink:        DedupLinkNone,
+		OnConflict:       OnConflictOverwrite,
+		Timeout:          5 * time.Minute,
+		DBBatchSize:      64,
+		DBBatchInterval:  500 * time.Millisecond,
+		DedupQuickBytes:  1 << 20,
+		EstimateSample:   0.05,
+		KeepTree:         true,
+		DryRun:           false,
+		StripMetadata:    false,
+		ProgressFormat:   "bar",
+		WatchDebounce:    500 * time.Millisecond,
+		WatchInitialScan: true,
+
+		DateFolderTemplate: DefaultDateFolderTemplate,
+	}
+}
+
+// CacheDirGlobal - значение CacheDir для общего кэша пользователя
+// (~/.cache/photoconverter/cache на Linux).
+const CacheDirGlobal = "global"
+
+// DefaultDateFolderTemplate - шаблон директории даты по умолчанию (--date-folder).
+const DefaultDateFolderTemplate = "YYYY/MM/DD"
+
+// validateDateFolderTemplate проверяет шаблон директории даты: относительный
+// путь внутри --out хотя бы с одним элементом даты.
+func validateDateFolderTemplate(tmpl string) error {
+	clean := filepath.Clean(filepath.FromSlash(tmpl))
+	if tmpl == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("шаблон --date-folder должен быть относительным путём внутри --out: %q", tmpl)
+	}
+	if !strings.Contains(tmpl, "YYYY") && !strings.Contains(tmpl, "MM") && !strings.Contains(tmpl, "DD") {
+		return fmt.Errorf("шаблон --date-folder не содержит YYYY, MM или DD: %q", tmpl)
+	}
+	return nil
+}
+
+// DateFolder возвращает директорию даты t относительно выходной директории:
+// DateFolderTemplate с YYYY, MM и DD, заменёнными годом, месяцем и днём.
+func (c *Config) DateFolder(t time.Time) string {
+	tmpl := c.DateFolderTemplate
+	if tmpl == "" {
+		tmpl = DefaultDateFolderTemplate
 	}
+	r := strings.NewReplacer(
+		"YYYY", fmt.Sprintf("%04d", t.Year()),
+		"MM", fmt.Sprintf("%02d", int(t.Month())),
+		"DD", fmt.Sprintf("%02d", t.Day()),
+	)
+	return filepath.Clean(filepath.FromSlash(r.Replace(tmpl)))
 }
 
+// Виды ссылок на дубликаты (--dedup-link).
+const (
+	// DedupLinkNone - дубликат только пропускается.
+	DedupLinkNone = "none"
+	// DedupLinkSymlink - символическая ссылка (относительная) на результат.
+	DedupLinkSymlink = "symlink"
+	// DedupLinkHardlink - жёсткая ссылка, при ошибке (другая ФС) - копия.
+	DedupLinkHardlink = "hardlink"
+)
+
+// Политики для выходного файла, которого нет в БД (--on-conflict).
+const (
+	// OnConflictOverwrite - результат перезаписывает файл.
+	OnConflictOverwrite = "overwrite"
+	// OnConflictSkip - файл пропускается, существующий результат остаётся.
+	OnConflictSkip = "skip"
+	// OnConflictRename - результат пишется в первый свободный name-N.ext.
+	OnConflictRename = "rename"
+)
+
+// Имена файлов в плоской структуре (--flat-naming).
+const (
+	// FlatNamingBasename - только имя файла (одинаковые имена из разных директорий совпадают).
+	FlatNamingBasename = "basename"
+	// FlatNamingHashed - имя файла с коротким хэшем директории: IMG_0001_1a2b3c4d.jpg.
+	FlatNamingHashed = "hashed"
+	// FlatNamingPathJoined - относительный путь через подчёркивания: 2024_may_IMG_0001.jpg.
+	FlatNamingPathJoined = "pathjoined"
+)
+
+// Движки конвертации (--backend).
+const (
+	// BackendVips - vips CLI (по умолчанию).
+	BackendVips = "vips"
+	// BackendMagick - ImageMagick (magick или convert).
+	BackendMagick = "magick"
+)
+
+// Действия с исходниками больше --max-megapixels.
+const (
+	// MegapixelsSkip - пропустить файл с указанием причины.
+	MegapixelsSkip = "skip"
+	// MegapixelsDownscale - уменьшить изображение до лимита с сохранением пропорций.
+	MegapixelsDownscale = "downscale"
+)
+
 // Validate проверяет корректность конфигурации.
 func (c *Config) Validate() error {
-	if c.InputDir == "" {
+	if c.InputDir == "" && c.FromFile == "" && !c.WorkerStdin {
 		return fmt.Errorf("входная директория не указана (--in)")
 	}
-	if c.OutputDir == "" {
+	if c.MaxAttempts < 0 {
+		return fmt.Errorf("число попыток задачи не может быть отрицательным: %d", c.MaxAttempts)
+	}
+	if c.StatsInterval < 0 {
+		return fmt.Errorf("период статистики очереди не может быть отрицательным: %s", c.StatsInterval)
+	}
+	if c.WorkerStdin && (c.Watch || c.FromFile != "") {
+		return fmt.Errorf("--worker-stdin несовместим с --watch и --from-file")
+	}
+	if c.FromFile != "" && c.Watch {
+		return fmt.Errorf("--from-file несовместим с --watch")
+	}
+	if c.Watch && c.InputIsFile() {
+		return fmt.Errorf("--watch требует директорию в --in, получен файл: %s", c.InputDir)
+	}
+	if c.FailFast && c.Watch {
+		return fmt.Errorf("--fail-fast несовместим с --watch")
+	}
+	if c.Skip < 0 {
+		return fmt.Errorf("--skip не может быть отрицательным: %d", c.Skip)
+	}
+	if c.Limit < 0 {
+		return fmt.Errorf("--limit не может быть отрицательным: %d", c.Limit)
+	}
+	if c.ShardTotal < 0 || (c.ShardTotal > 0 && (c.ShardIndex < 0 || c.ShardIndex >= c.ShardTotal)) {
+		return fmt.Errorf("некорректная часть: %d/%d (ожидается K/N, 1 <= K <= N)", c.ShardIndex+1, c.ShardTotal)
+	}
+	if (c.Skip > 0 || c.Limit > 0) && c.Watch {
+		return fmt.Errorf("--skip и --limit несовместимы с --watch")
+	}
+	if c.DBBatchSize < 0 {
+		return fmt.Errorf("размер пакета БД не может быть отрицательным: %d", c.DBBatchSize)
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("таймаут не может быть отрицательным: %s", c.Timeout)
+	}
+	if c.Force && c.DryRun {
+		return fmt.Errorf("--force несовместим с --dry-run")
+	}
+	if c.DeleteSource && !c.IUnderstand {
+		return fmt.Errorf("--delete-source необратимо удаляет исходники: подтвердите флагом --i-understand")
+	}
+	if c.DeleteSource && c.TrashDir != "" {
+		return fmt.Errorf("--delete-source и --trash-dir взаимоисключающие")
+	}
+	if c.TrashDir != "" && c.InputDir != "" && IsSubPath(c.InputDir, c.TrashDir) {
+		return fmt.Errorf("--trash-dir не может быть внутри входной директории: файлы корзины будут обработаны заново")
+	}
+	if c.QuarantineDir != "" && c.InputDir != "" && IsSubPath(c.InputDir, c.QuarantineDir) {
+		return fmt.Errorf("--quarantine не может быть внутри входной директории: файлы карантина будут обработаны заново")
+	}
+	if c.DeleteSource && c.NoVerify {
+		return fmt.Errorf("--delete-source несовместим с --no-verify: исходник удаляется только после проверки результата")
+	}
+	if c.Force && c.SkipExistingOutput {
+		return fmt.Errorf("--force несовместим с --skip-existing-output")
+	}
+	switch c.OnConflict {
+	case "", OnConflictOverwrite:
+	case OnConflictSkip, OnConflictRename:
+		if c.Force {
+			return fmt.Errorf("--force несовместим с --on-conflict %s: результаты прошлых запусков перезаписываются", c.OnConflict)
+		}
+		if c.SkipExistingOutput && c.OnConflict == OnConflictRename {
+			return fmt.Errorf("--skip-existing-output несовместим с --on-conflict %s", OnConflictRename)
+		}
+	default:
+		return fmt.Errorf("неизвестная политика --on-conflict: %s (доступны: %s, %s, %s)", c.OnConflict, OnConflictOverwrite, OnConflictSkip, OnConflictRename)
+	}
+	if c.Mirror && (c.DeleteSource || c.TrashDir != "") {
+		return fmt.Errorf("--mirror несовместим с --delete-source и --trash-dir: результаты удалённых исходников будут удалены")
+	}
+	if c.Mirror && (c.FromFile != "" || c.Watch || c.InputIsFile()) {
+		return fmt.Errorf("--mirror работает только с директорией в --in (без --from-file, архивов и --watch)")
+	}
+	if c.Resume && c.DryRun {
+		return fmt.Errorf("--resume несовместим с --dry-run")
+	}
+	if (c.IgnoreFile != "" || c.ClearIgnored) && c.DryRun {
+		return fmt.Errorf("--ignore-file и --clear-ignored несовместимы с --dry-run: они меняют БД")
+	}
+	if c.OutputDir == "" && !c.ReportDuplicates && !c.Estimate {
 		return fmt.Errorf("выходная директория не указана (--out)")
 	}
+	if c.Estimate && (c.EstimateSample <= 0 || c.EstimateSample > 1) {
+		return fmt.Errorf("доля выборки --estimate-sample должна быть в диапазоне (0, 1]: %g", c.EstimateSample)
+	}
+	if c.OutputExtension != "" && !c.OutputFormat.IsExtensionOf(c.OutputExtension) {
+		return fmt.Errorf("расширение --out-ext %s не подходит формату %s", c.OutputExtension, c.OutputFormat)
+	}
+	for ext, format := range c.FormatMap {
+		if ext == "" || !format.Valid() {
+			return fmt.Errorf("неверное соответствие --map %s=%s (доступны форматы: %v)", ext, format, ValidFormats())
+		}
+	}
 	if len(c.InputExtensions) == 0 {
 		return fmt.Errorf("не указаны расширения входных файлов (--in-ext)")
 	}
 	if c.Quality < 1 || c.Quality > 100 {
 		return fmt.Errorf("качество должно быть от 1 до 100, получено: %d", c.Quality)
 	}
-	if c.Workers < 1 {
-		return fmt.Errorf("количество воркеров должно быть >= 1, получено: %d", c.Workers)
+	if c.Workers < 1 && c.Workers != WorkersAuto {
+		return fmt.Errorf("количество воркеров должно быть >= 1 или -1 (авто), получено: %d", c.Workers)
+	}
+	for format, n := range c.ConcurrencyPerFormat {
+		if !format.Valid() || n < 1 {
+			return fmt.Errorf("неверное ограничение --concurrency-per-format %s=%d: ожидается формат=число >= 1 (доступны форматы: %v)", format, n, ValidFormats())
+		}
 	}
 	if c.Mode != ModeSkip && c.Mode != ModeDedup {
 		return fmt.Errorf("неизвестный режим: %s (доступны: skip, dedup)", c.Mode)
 	}
+	switch c.DedupHash {
+	case "", "sha256", "blake3", "xxhash":
+	default:
+		return fmt.Errorf("неизвестный алгоритм хэша: %s (доступны: sha256, blake3, xxhash)", c.DedupHash)
+	}
+	switch c.DedupLink {
+	case "", DedupLinkNone:
+	case DedupLinkSymlink, DedupLinkHardlink:
+		if c.Mode != ModeDedup {
+			return fmt.Errorf("--dedup-link работает только с --mode dedup")
+		}
+		if !c.KeepTree {
+			return fmt.Errorf("--dedup-link требует --keep-tree: без него дубликаты пишутся в один файл по хэшу")
+		}
+	default:
+		return fmt.Errorf("неизвестный вид ссылки: %s (доступны: none, symlink, hardlink)", c.DedupLink)
+	}
+	switch c.Backend {
+	case "", BackendVips:
+		// Файлы, отданные ImageMagick, потеряли бы эти настройки, а задачи
+		// записались бы успешными с тем же хэшем параметров
+		if flag := c.magickUnsupported(); c.MagickFallback && flag != "" {
+			return fmt.Errorf("%s не поддерживается с --magick-fallback", flag)
+		}
+	case BackendMagick:
+		if c.MagickFallback {
+			return fmt.Errorf("--magick-fallback имеет смысл только с --backend %s", BackendVips)
+		}
+		if flag := c.magickUnsupported(); flag != "" {
+			return fmt.Errorf("%s не поддерживается с --backend %s", flag, BackendMagick)
+		}
+	default:
+		return fmt.Errorf("неизвестный движок конвертации: %s (доступны: %s, %s)", c.Backend, BackendVips, BackendMagick)
+	}
+	if c.OrganizeByDate {
+		if err := validateDateFolderTemplate(c.DateFolderTemplate); err != nil {
+			return err
+		}
+		if c.DedupLink != "" && c.DedupLink != DedupLinkNone {
+			return fmt.Errorf("--dedup-link несовместим с --organize-by-date")
+		}
+	}
+	switch c.FlatNaming {
+	case "", FlatNamingBasename, FlatNamingHashed, FlatNamingPathJoined:
+	default:
+		return fmt.Errorf("неизвестный вид имён: %s (доступны: %s, %s, %s)",
+			c.FlatNaming, FlatNamingBasename, FlatNamingHashed, FlatNamingPathJoined)
+	}
+	if c.DedupQuick {
+		if c.Mode != ModeDedup {
+			return fmt.Errorf("--dedup-quick работает только с --mode dedup")
+		}
+		if c.DedupQuickBytes < 1 {
+			return fmt.Errorf("--dedup-quick-bytes должен быть больше 0, получено: %d", c.DedupQuickBytes)
+		}
+	}
+	if c.WebhookURL != "" && !strings.HasPrefix(c.WebhookURL, "http://") && !strings.HasPrefix(c.WebhookURL, "https://") {
+		return fmt.Errorf("webhook должен быть http(s) URL, получено: %s", c.WebhookURL)
+	}
+	if c.ProgressFormat != "" && c.ProgressFormat != "bar" && c.ProgressFormat != "json" {
+		return fmt.Errorf("неизвестный формат прогресса: %s (доступны: bar, json)", c.ProgressFormat)
+	}
+	if c.WatchDebounce < 0 {
+		return fmt.Errorf("время debounce не может быть отрицательным: %s", c.WatchDebounce)
+	}
+	if c.CopyMetadata && c.StripMetadata {
+		return fmt.Errorf("--copy-metadata и --strip взаимоисключающие: нельзя одновременно копировать и удалять метаданные")
+	}
+	switch c.PDFLayout {
+	case "", "single", "2up", "4up", "9up":
+	default:
+		return fmt.Errorf("неизвестный макет PDF: %s (доступны: single, 2up, 4up, 9up)", c.PDFLayout)
+	}
+	if c.PDFMargin < 0 {
+		return fmt.Errorf("поля PDF не могут быть отрицательными, получено: %d мм", c.PDFMargin)
+	}
+	if side := pdfPageShortSide(c.PDFPageSize); 2*c.PDFMargin >= side {
+		return fmt.Errorf("поля PDF %d мм не помещаются на страницу %s (меньшая сторона %d мм)", c.PDFMargin, c.PDFPageSize, side)
+	}
+	if c.PDFBackground != "" {
+		if _, _, _, err := ParseColor(c.PDFBackground); err != nil {
+			return err
+		}
+	}
+	seenPresets := make(map[string]bool)
+	for _, name := range c.MultiPresets {
+		if _, ok := Presets[Preset(name)]; !ok {
+			return fmt.Errorf("неизвестный пресет в --multi-preset: %s (доступны: %v)", name, ValidPresets())
+		}
+		if seenPresets[name] {
+			return fmt.Errorf("пресет %s указан в --multi-preset несколько раз", name)
+		}
+		seenPresets[name] = true
+	}
+	if c.CropAspect != "" {
+		if _, _, err := ParseAspect(c.CropAspect); err != nil {
+			return err
+		}
+		if c.MaxWidth <= 0 && c.MaxHeight <= 0 {
+			return fmt.Errorf("--crop требует --max-width или --max-height")
+		}
+	}
+	switch c.CropMode {
+	case "", CropCentre, CropAttention:
+	default:
+		return fmt.Errorf("неизвестный режим обрезки: %s (доступны: %s, %s)", c.CropMode, CropCentre, CropAttention)
+	}
+	if c.SmartCrop {
+		if c.CropMode == CropCentre {
+			return fmt.Errorf("--smart-crop несовместим с --crop-mode %s", CropCentre)
+		}
+		if c.CropAspect == "" && (c.MaxWidth <= 0 || c.MaxHeight <= 0) {
+			return fmt.Errorf("--smart-crop требует --crop или оба размера --max-width и --max-height")
+		}
+	}
+	if c.Sharpen < 0 {
+		return fmt.Errorf("sigma резкости не может быть отрицательной: %g", c.Sharpen)
+	}
+	if c.Blur < 0 {
+		return fmt.Errorf("sigma размытия не может быть отрицательной: %g", c.Blur)
+	}
+	if c.Pixelate < 0 {
+		return fmt.Errorf("размер блока пикселизации не может быть отрицательным: %d", c.Pixelate)
+	}
+	if c.Blur > 0 && c.Pixelate > 0 {
+		return fmt.Errorf("--blur и --pixelate взаимоисключающие")
+	}
+	if c.Grayscale && c.Sepia {
+		return fmt.Errorf("--grayscale и --sepia взаимоисключающие: сепия уже строится по оттенкам серого")
+	}
+	if c.DPI < 0 {
+		return fmt.Errorf("--dpi не может быть отрицательным: %d", c.DPI)
+	}
+	if c.EmbedSRGB && c.StripMetadata {
+		return fmt.Errorf("--embed-srgb несовместим с --strip: strip удаляет профиль из результата")
+	}
+	if c.MaxFileSize < 0 {
+		return fmt.Errorf("--max-file-size не может быть отрицательным: %d", c.MaxFileSize)
+	}
+	if c.ZipRemoveFiles && c.ZipOutput == "" {
+		return fmt.Errorf("--zip-remove требует --zip")
+	}
+	if c.MaxMegapixels < 0 {
+		return fmt.Errorf("--max-megapixels не может быть отрицательным: %g", c.MaxMegapixels)
+	}
+	switch c.MegapixelsAction {
+	case "", MegapixelsSkip, MegapixelsDownscale:
+	default:
+		return fmt.Errorf("неизвестное действие для больших изображений: %s (доступны: %s, %s)",
+			c.MegapixelsAction, MegapixelsSkip, MegapixelsDownscale)
+	}
+	if c.MaxMemoryMB < MemoryAuto {
+		return fmt.Errorf("ограничение памяти должно быть >= 0 или -1 (авто), получено: %d", c.MaxMemoryMB)
+	}
 
 	// Устанавливаем путь к БД по умолчанию
 	if c.DBPath == "" {
@@ -192,6 +979,124 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// Режимы обрезки (значения --crop=... у vips thumbnail).
+const (
+	// CropCentre - обрезка по центру.
+	CropCentre = "centre"
+	// CropAttention - обрезка по самой заметной области (vips smartcrop).
+	CropAttention = "attention"
+)
+
+// magickUnsupported возвращает первый включённый флаг, который движок
+// ImageMagick не поддерживает (пусто - все поддерживаются).
+func (c *Config) magickUnsupported() string {
+	switch {
+	case c.SmartCrop || c.CropMode == CropAttention:
+		return "обрезка по заметной области"
+	case c.Pixelate > 0:
+		return "--pixelate"
+	case c.WatermarkPath != "":
+		return "--watermark"
+	case c.ColorProfile != "":
+		return "--color-profile"
+	case c.EmbedSRGB:
+		return "--embed-srgb"
+	case c.MaxFileSize > 0:
+		return "--max-file-size"
+	case c.AllImages:
+		return "--all-images"
+	case c.PreserveAnimation:
+		return "--preserve-animation"
+	case c.CopyMetadata || c.StripGPS:
+		return "перенос метаданных (exiftool)"
+	}
+	return ""
+}
+
+// EffectiveCropMode возвращает режим обрезки с учётом значения по умолчанию.
+func (c *Config) EffectiveCropMode() string {
+	if c.SmartCrop {
+		return CropAttention
+	}
+	if c.CropMode == "" {
+		return CropCentre
+	}
+	return c.CropMode
+}
+
+// ParseShard разбирает часть вида "K/N" (--shard) и возвращает индекс
+// части с нуля и число частей.
+func ParseShard(s string) (index, total int, err error) {
+	parts := strings.Split(s, "/")
+	if len(parts) == 2 {
+		k, errK := strconv.Atoi(strings.TrimSpace(parts[0]))
+		n, errN := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if errK == nil && errN == nil && n > 0 && k >= 1 && k <= n {
+			return k - 1, n, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("некорректная часть: %q (пример: 1/4, K от 1 до N)", s)
+}
+
+// ParseAspect разбирает соотношение сторон вида "16:9".
+func ParseAspect(s string) (w, h int, err error) {
+	parts := strings.Split(s, ":")
+	if len(parts) == 2 {
+		w, errW := strconv.Atoi(strings.TrimSpace(parts[0]))
+		h, errH := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if errW == nil && errH == nil && w > 0 && h > 0 {
+			return w, h, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("некорректное соотношение сторон: %q (пример: 16:9, 1:1)", s)
+}
+
+// ParseColor разбирает цвет вида "#rrggbb" или "#rgb" (решётка необязательна).
+func ParseColor(s string) (r, g, b int, err error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		if v, err := strconv.ParseUint(hex, 16, 32); err == nil {
+			return int(v >> 16), int(v >> 8 & 0xff), int(v & 0xff), nil
+		}
+	}
+	return 0, 0, 0, fmt.Errorf("некорректный цвет: %q (пример: #ffffff, #333)", s)
+}
+
+// pdfPageShortSide возвращает меньшую сторону страницы PDF в миллиметрах
+// (неизвестный размер считается A4, как при экспорте).
+func pdfPageShortSide(pageSize string) int {
+	switch strings.ToLower(pageSize) {
+	case "a3":
+		return 297
+	case "letter":
+		return 215
+	default:
+		return 210
+	}
+}
+
+// CropSize возвращает итоговый размер при обрезке до CropAspect: наибольший
+// прямоугольник с этим соотношением, вписанный в MaxWidth x MaxHeight
+// (незаданная сторона вычисляется из другой).
+func (c *Config) CropSize() (width, height int) {
+	aw, ah, err := ParseAspect(c.CropAspect)
+	if err != nil {
+		return c.MaxWidth, c.MaxHeight
+	}
+
+	width = c.MaxWidth
+	if c.MaxHeight > 0 {
+		if byHeight := c.MaxHeight * aw / ah; width <= 0 || byHeight < width {
+			width = byHeight
+		}
+	}
+	height = (width*ah + aw/2) / aw
+	return width, height
+}
+
 // OutputParams возвращает параметры выхода в виде JSON.
 func (c *Config) OutputParams() string {
 	params := map[string]interface{}{
@@ -201,6 +1106,60 @@ func (c *Config) OutputParams() string {
 		"max_width":      c.MaxWidth,
 		"max_height":     c.MaxHeight,
 	}
+	// Добавляются только при включении, чтобы не менять хэш существующих задач
+	if ext := c.OutputExt(); ext != string(c.OutputFormat) {
+		params["extension"] = ext
+	}
+	if c.StripGPS {
+		params["strip_gps"] = true
+	}
+	if c.CopyMetadata {
+		params["copy_metadata"] = true
+	}
+	if c.CropAspect != "" {
+		params["crop"] = c.CropAspect
+		params["crop_mode"] = c.EffectiveCropMode()
+	}
+	if c.SmartCrop {
+		params["smart_crop"] = true
+	}
+	if c.AllImages {
+		params["all_images"] = true
+	}
+	if c.PreserveAnimation {
+		params["preserve_animation"] = true
+	}
+	if c.MaxFileSize > 0 {
+		params["max_file_size"] = c.MaxFileSize
+	}
+	if c.Sharpen > 0 {
+		params["sharpen"] = c.Sharpen
+		if c.SharpenAlways {
+			params["sharpen_always"] = true
+		}
+	}
+	if c.Blur > 0 {
+		params["blur"] = c.Blur
+	}
+	if c.Pixelate > 0 {
+		params["pixelate"] = c.Pixelate
+	}
+	if c.Grayscale {
+		params["grayscale"] = true
+	}
+	if c.Sepia {
+		params["sepia"] = true
+	}
+	if c.DPI > 0 {
+		params["dpi"] = c.DPI
+	}
+	if c.EmbedSRGB {
+		params["embed_srgb"] = true
+	}
+	// Пропуск не меняет результаты, уменьшение - меняет
+	if c.MaxMegapixels > 0 && c.MegapixelsAction == MegapixelsDownscale {
+		params["max_megapixels"] = c.MaxMegapixels
+	}
 	b, _ := json.Marshal(params)
 	return string(b)
 }
@@ -222,6 +1181,80 @@ func (c *Config) HasInputExtension(ext string) bool {
 	return false
 }
 
+// MaxWorkers возвращает верхнюю границу числа воркеров: Workers,
+// а в режиме автоподбора - удвоенное число CPU (конвертации частично ждут диск).
+func (c *Config) MaxWorkers() int {
+	if c.Workers == WorkersAuto {
+		return 2 * runtime.NumCPU()
+	}
+	return c.Workers
+}
+
+// IsSubPath сообщает, совпадает ли path с dir или лежит внутри неё
+// (пути приводятся к абсолютным).
+func IsSubPath(dir, path string) bool {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// InputIsFile сообщает, указан ли в --in отдельный файл вместо директории.
+// Архивы тоже являются файлами - их распознаёт scanner.IsArchive.
+func (c *Config) InputIsFile() bool {
+	if c.InputDir == "" {
+		return false
+	}
+	info, err := os.Stat(c.InputDir)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// OutputExt возвращает расширение выходных файлов без точки: OutputExtension,
+// если оно подходит формату, иначе имя формата (варианты --multi-preset
+// с другим форматом получают расширение своего формата).
+func (c *Config) OutputExt() string {
+	if c.OutputExtension != "" && c.OutputFormat.IsExtensionOf(c.OutputExtension) {
+		return c.OutputExtension
+	}
+	return string(c.OutputFormat)
+}
+
+// Quiet сообщает, выводятся ли только ошибки и предупреждения.
+func (c *Config) Quiet() bool {
+	return c.Verbosity <= VerbosityQuiet
+}
+
+// Verbose сообщает, выводится ли каждый обработанный файл (-v и выше).
+func (c *Config) Verbose() bool {
+	return c.Verbosity >= VerbosityVerbose
+}
+
+// Debug сообщает, выводятся ли командные строки внешних программ (-vv).
+func (c *Config) Debug() bool {
+	return c.Verbosity >= VerbosityDebug
+}
+
+// HasQuality сообщает, управляет ли Quality размером файла в выходном формате.
+func (c *Config) HasQuality() bool {
+	switch c.OutputFormat {
+	case FormatWebP, FormatJPEG, FormatAVIF, FormatHEIC, FormatJXL:
+		return true
+	}
+	return false
+}
+
+// KeepsAnimation сообщает, сохраняется ли анимация входа: включён
+// PreserveAnimation и выходной формат поддерживает анимацию.
+func (c *Config) KeepsAnimation() bool {
+	return c.PreserveAnimation && c.OutputFormat == FormatWebP
+}
+
 // VipsOutputSuffix возвращает суффикс для vips с параметрами.
 // Например: "output.webp[Q=80,strip]"
 func (c *Config) VipsOutputSuffix() string {
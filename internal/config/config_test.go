@@ -1,7 +1,10 @@
 package config
 
 import (
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -109,6 +112,90 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "auto workers",
+			cfg: &Config{
+				InputDir:        "/input",
+				OutputDir:       "/output",
+				InputExtensions: []string{"jpg"},
+				OutputFormat:    FormatWebP,
+				Quality:         85,
+				Workers:         WorkersAuto,
+				Mode:            ModeSkip,
+			},
+			wantErr: false,
+		},
+		{
+			name: "delete source without confirmation",
+			cfg: &Config{
+				InputDir:        "/input",
+				OutputDir:       "/output",
+				InputExtensions: []string{"jpg"},
+				OutputFormat:    FormatWebP,
+				Quality:         85,
+				Workers:         4,
+				Mode:            ModeSkip,
+				DeleteSource:    true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "trash dir inside input",
+			cfg: &Config{
+				InputDir:        "/input",
+				OutputDir:       "/output",
+				InputExtensions: []string{"jpg"},
+				OutputFormat:    FormatWebP,
+				Quality:         85,
+				Workers:         4,
+				Mode:            ModeSkip,
+				TrashDir:        "/input/.trash",
+			},
+			wantErr: true,
+		},
+		{
+			name: "quarantine dir inside input",
+			cfg: &Config{
+				InputDir:        "/input",
+				OutputDir:       "/output",
+				InputExtensions: []string{"jpg"},
+				OutputFormat:    FormatWebP,
+				Quality:         85,
+				Workers:         4,
+				Mode:            ModeSkip,
+				QuarantineDir:   "/input/quarantine",
+			},
+			wantErr: true,
+		},
+		{
+			name: "copy metadata with strip",
+			cfg: &Config{
+				InputDir:        "/input",
+				OutputDir:       "/output",
+				InputExtensions: []string{"jpg"},
+				OutputFormat:    FormatWebP,
+				Quality:         85,
+				Workers:         4,
+				Mode:            ModeSkip,
+				StripMetadata:   true,
+				CopyMetadata:    true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown dedup hash",
+			cfg: &Config{
+				InputDir:        "/input",
+				OutputDir:       "/output",
+				InputExtensions: []string{"jpg"},
+				OutputFormat:    FormatWebP,
+				Quality:         85,
+				Workers:         4,
+				Mode:            ModeDedup,
+				DedupHash:       "md5",
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -121,6 +208,25 @@ func TestConfig_Validate(t *testing.T) {
 	}
 }
 
+func TestIsSubPath(t *testing.T) {
+	tests := []struct {
+		dir, path string
+		want      bool
+	}{
+		{"/input", "/input", true},
+		{"/input", "/input/2024/a.jpg", true},
+		{"/input/", "/input/.trash", true},
+		{"/input", "/input-trash", false},
+		{"/input", "/", false},
+		{"/input/sub", "/input/sub/../other", false},
+	}
+	for _, tt := range tests {
+		if got := IsSubPath(tt.dir, tt.path); got != tt.want {
+			t.Errorf("IsSubPath(%q, %q) = %v, want %v", tt.dir, tt.path, got, tt.want)
+		}
+	}
+}
+
 func TestConfig_HasInputExtension(t *testing.T) {
 	cfg := &Config{
 		InputExtensions: []string{"jpg", "jpeg", "png"},
@@ -215,6 +321,23 @@ func TestConfig_OutputParams(t *testing.T) {
 	}
 }
 
+func TestConfig_OutputParams_StripGPS(t *testing.T) {
+	cfg := &Config{OutputFormat: FormatJPEG, Quality: 80}
+	before := cfg.OutputParamsHash()
+
+	if strings.Contains(cfg.OutputParams(), "strip_gps") {
+		t.Errorf("strip_gps не должен попадать в параметры по умолчанию: %s", cfg.OutputParams())
+	}
+
+	cfg.StripGPS = true
+	if !strings.Contains(cfg.OutputParams(), `"strip_gps":true`) {
+		t.Errorf("OutputParams() = %s, want strip_gps", cfg.OutputParams())
+	}
+	if cfg.OutputParamsHash() == before {
+		t.Error("StripGPS должен менять хэш параметров")
+	}
+}
+
 func TestOutputFormat_String(t *testing.T) {
 	tests := []struct {
 		format OutputFormat
@@ -255,3 +378,365 @@ func TestMode_String(t *testing.T) {
 		})
 	}
 }
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "1024", want: 1024},
+		{in: "500KB", want: 500 << 10},
+		{in: "5MB", want: 5 << 20},
+		{in: "5mb", want: 5 << 20},
+		{in: "1.5M", want: 3 << 19},
+		{in: "2GiB", want: 2 << 30},
+		{in: "100B", want: 100},
+		{in: "MB", wantErr: true},
+		{in: "-1MB", wantErr: true},
+		{in: "5XB", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseByteSize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+		if !tt.wantErr {
+			if back, _ := ParseByteSize(FormatByteSize(got)); back != got {
+				t.Errorf("FormatByteSize(%d) = %q не разбирается обратно", got, FormatByteSize(got))
+			}
+		}
+	}
+}
+
+func TestConfig_CropAspect(t *testing.T) {
+	base := func() *Config {
+		cfg := DefaultConfig()
+		cfg.InputDir, cfg.OutputDir = "/in", "/out"
+		return cfg
+	}
+
+	for _, tt := range []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr bool
+	}{
+		{name: "16:9 с шириной", modify: func(c *Config) { c.CropAspect, c.MaxWidth = "16:9", 1920 }},
+		{name: "attention", modify: func(c *Config) { c.CropAspect, c.CropMode, c.MaxHeight = "1:1", CropAttention, 500 }},
+		{name: "без размера", modify: func(c *Config) { c.CropAspect = "1:1" }, wantErr: true},
+		{name: "неверное соотношение", modify: func(c *Config) { c.CropAspect, c.MaxWidth = "16x9", 100 }, wantErr: true},
+		{name: "нулевая сторона", modify: func(c *Config) { c.CropAspect, c.MaxWidth = "0:1", 100 }, wantErr: true},
+		{name: "неизвестный режим", modify: func(c *Config) { c.CropAspect, c.CropMode, c.MaxWidth = "1:1", "edge", 100 }, wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base()
+			tt.modify(cfg)
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	cfg := base()
+	cfg.CropAspect, cfg.MaxWidth = "16:9", 1600
+	if w, h := cfg.CropSize(); w != 1600 || h != 900 {
+		t.Errorf("CropSize() = %dx%d, want 1600x900", w, h)
+	}
+	params := cfg.OutputParams()
+	if !strings.Contains(params, `"crop":"16:9"`) || !strings.Contains(params, `"crop_mode":"centre"`) {
+		t.Errorf("OutputParams() = %s, want crop и crop_mode", params)
+	}
+}
+
+func TestConfig_BlurPixelateExclusive(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.InputDir, cfg.OutputDir = "/in", "/out"
+	cfg.Blur, cfg.Pixelate = 2, 10
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() с --blur и --pixelate должна вернуть ошибку")
+	}
+
+	cfg.Blur = 0
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() только с --pixelate: %v", err)
+	}
+	if !strings.Contains(cfg.OutputParams(), `"pixelate":10`) {
+		t.Errorf("OutputParams() = %s, want pixelate", cfg.OutputParams())
+	}
+}
+
+func TestParseShard(t *testing.T) {
+	if index, total, err := ParseShard("1/4"); err != nil || index != 0 || total != 4 {
+		t.Errorf("ParseShard(1/4) = %d, %d, %v; want 0, 4", index, total, err)
+	}
+	for _, s := range []string{"0/4", "5/4", "1/0", "1", "a/b", "1/2/3"} {
+		if _, _, err := ParseShard(s); err == nil {
+			t.Errorf("ParseShard(%q): ожидалась ошибка", s)
+		}
+	}
+}
+
+func TestParseFormatMap(t *testing.T) {
+	m, err := ParseFormatMap([]string{"HEIC=jpg", ".png=webp", "tif=png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]OutputFormat{"heic": FormatJPEG, "png": FormatWebP, "tif": FormatPNG}
+	if len(m) != len(want) {
+		t.Fatalf("ParseFormatMap = %v, want %v", m, want)
+	}
+	for ext, format := range want {
+		if m[ext] != format {
+			t.Errorf("m[%s] = %s, want %s", ext, m[ext], format)
+		}
+	}
+	for _, s := range []string{"heic", "heic=bmp", "=jpg", "heic="} {
+		if _, err := ParseFormatMap([]string{s}); err == nil {
+			t.Errorf("ParseFormatMap(%q): ожидалась ошибка", s)
+		}
+	}
+
+	cfg := DefaultConfig()
+	cfg.OutputFormat = FormatAVIF
+	cfg.FormatMap = m
+	if got := cfg.WithFormatFor("/in/a.HEIC"); got.OutputFormat != FormatJPEG || got.OutputParamsHash() == cfg.OutputParamsHash() {
+		t.Errorf("WithFormatFor(a.HEIC): формат %s, хэш должен отличаться", got.OutputFormat)
+	}
+	if got := cfg.WithFormatFor("/in/a.jpg"); got != cfg {
+		t.Error("WithFormatFor(a.jpg): ожидалась исходная конфигурация")
+	}
+}
+
+func TestParseFormatConcurrency(t *testing.T) {
+	limits, err := ParseFormatConcurrency([]string{"AVIF=2", "webp = 3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits[FormatAVIF] != 2 || limits[FormatWebP] != 3 {
+		t.Errorf("ParseFormatConcurrency = %v", limits)
+	}
+	for _, pair := range []string{"avif", "avif=0", "avif=x", "bmp=2"} {
+		if _, err := ParseFormatConcurrency([]string{pair}); err == nil {
+			t.Errorf("ParseFormatConcurrency(%q) должна вернуть ошибку", pair)
+		}
+	}
+}
+
+func TestConfig_MagickBackend(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(cfg *Config)
+		wantErr bool
+	}{
+		{name: "resize и качество", setup: func(cfg *Config) { cfg.MaxWidth, cfg.Quality = 800, 90 }},
+		{name: "smart crop", setup: func(cfg *Config) { cfg.SmartCrop = true; cfg.MaxWidth, cfg.MaxHeight = 100, 100 }, wantErr: true},
+		{name: "fallback с движком magick", setup: func(cfg *Config) { cfg.MagickFallback = true }, wantErr: true},
+		{name: "неизвестный движок", setup: func(cfg *Config) { cfg.Backend = "gimp" }, wantErr: true},
+		{name: "fallback с --strip-gps", setup: func(cfg *Config) { cfg.Backend, cfg.MagickFallback, cfg.StripGPS = BackendVips, true, true }, wantErr: true},
+		{name: "fallback с --pixelate", setup: func(cfg *Config) { cfg.Backend, cfg.MagickFallback, cfg.Pixelate = BackendVips, true, 8 }, wantErr: true},
+		{name: "fallback с --max-file-size", setup: func(cfg *Config) { cfg.Backend, cfg.MagickFallback, cfg.MaxFileSize = BackendVips, true, 1<<20 }, wantErr: true},
+		{name: "fallback с resize", setup: func(cfg *Config) { cfg.Backend, cfg.MagickFallback, cfg.MaxWidth = BackendVips, true, 800 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.InputDir, cfg.OutputDir = "/in", "/out"
+			cfg.Backend = BackendMagick
+			tt.setup(cfg)
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_MirrorExclusive(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.InputDir, cfg.OutputDir = t.TempDir(), "/out"
+	cfg.Mirror = true
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() с --mirror: %v", err)
+	}
+
+	cfg.TrashDir = "/trash"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() с --mirror и --trash-dir должна вернуть ошибку")
+	}
+	cfg.TrashDir = ""
+	cfg.FromFile = "list.txt"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() с --mirror и --from-file должна вернуть ошибку")
+	}
+}
+
+func TestConfig_OutputExtension(t *testing.T) {
+	tests := []struct {
+		format  OutputFormat
+		ext     string
+		wantErr bool
+		wantExt string
+	}{
+		{format: FormatJPEG, ext: "", wantExt: "jpg"},
+		{format: FormatJPEG, ext: "jpeg", wantExt: "jpeg"},
+		{format: FormatJPEG, ext: "JPG", wantExt: "JPG"},
+		{format: FormatTIFF, ext: "tif", wantExt: "tif"},
+		{format: FormatHEIC, ext: "heif", wantExt: "heif"},
+		{format: FormatWebP, ext: "jpeg", wantErr: true, wantExt: "webp"},
+		{format: FormatJPEG, ext: "png", wantErr: true, wantExt: "jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format)+"/"+tt.ext, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.InputDir, cfg.OutputDir = "/in", "/out"
+			cfg.OutputFormat = tt.format
+			cfg.OutputExtension = tt.ext
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := cfg.OutputExt(); got != tt.wantExt {
+				t.Errorf("OutputExt() = %q, want %q", got, tt.wantExt)
+			}
+		})
+	}
+
+	// Расширение по умолчанию не меняет хэш параметров существующих задач
+	cfg := DefaultConfig()
+	hash := cfg.OutputParamsHash()
+	cfg.OutputExtension = "jpg"
+	if cfg.OutputParamsHash() != hash {
+		t.Error("OutputParamsHash() изменился при расширении, совпадающем с форматом")
+	}
+	cfg.OutputExtension = "jpeg"
+	if cfg.OutputParamsHash() == hash {
+		t.Error("OutputParamsHash() не изменился при другом расширении")
+	}
+}
+
+func TestConfig_PDFMarginBackground(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.InputDir, cfg.OutputDir = "/in", "/out"
+	cfg.PDFPageSize = "a4"
+	cfg.PDFMargin = 20
+	cfg.PDFBackground = "#336699"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	cfg.PDFMargin = 105
+	if err := cfg.Validate(); err == nil {
+		t.Error("поля 105 мм на A4 (210 мм) должны быть ошибкой")
+	}
+	cfg.PDFMargin = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("отрицательные поля должны быть ошибкой")
+	}
+
+	cfg.PDFMargin = 0
+	cfg.PDFBackground = "blue"
+	if err := cfg.Validate(); err == nil {
+		t.Error("некорректный цвет должен быть ошибкой")
+	}
+
+	for s, want := range map[string][3]int{"#336699": {51, 102, 153}, "fff": {255, 255, 255}, "#000": {0, 0, 0}} {
+		r, g, b, err := ParseColor(s)
+		if err != nil || [3]int{r, g, b} != want {
+			t.Errorf("ParseColor(%q) = %d %d %d, %v; want %v", s, r, g, b, err, want)
+		}
+	}
+}
+
+func TestConfig_DateFolder(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.InputDir, cfg.OutputDir = "/in", "/out"
+	cfg.OrganizeByDate = true
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() с --organize-by-date: %v", err)
+	}
+
+	date := time.Date(2021, 7, 4, 9, 15, 0, 0, time.Local)
+	for tmpl, want := range map[string]string{
+		"":                "2021/07/04",
+		"YYYY/MM/DD":      "2021/07/04",
+		"YYYY-MM":         "2021-07",
+		"photos/YYYY/DD":  "photos/2021/04",
+		"YYYY/MM/../DD/.": "2021/04",
+	} {
+		cfg.DateFolderTemplate = tmpl
+		if got := cfg.DateFolder(date); got != filepath.FromSlash(want) {
+			t.Errorf("DateFolder(%q) = %q, want %q", tmpl, got, want)
+		}
+	}
+
+	for _, tmpl := range []string{"", "photos", "/YYYY", "../YYYY", "YYYY/../../MM"} {
+		cfg.DateFolderTemplate = tmpl
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Validate() с шаблоном %q должна вернуть ошибку", tmpl)
+		}
+	}
+
+	cfg.DateFolderTemplate = DefaultDateFolderTemplate
+	cfg.DedupLink = DedupLinkHardlink
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() с --organize-by-date и --dedup-link должна вернуть ошибку")
+	}
+}
+
+func TestConfig_EmbedSRGB(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.InputDir, cfg.OutputDir = "/in", "/out"
+	hash := cfg.OutputParamsHash()
+	cfg.EmbedSRGB = true
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() с --embed-srgb: %v", err)
+	}
+	if cfg.OutputParamsHash() == hash {
+		t.Error("OutputParamsHash() не изменился с --embed-srgb")
+	}
+
+	cfg.StripMetadata = true
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() с --embed-srgb и --strip должна вернуть ошибку")
+	}
+	cfg.StripMetadata = false
+	cfg.Backend = BackendMagick
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() с --embed-srgb и --backend magick должна вернуть ошибку")
+	}
+}
+
+func TestConfig_OnConflict(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.InputDir, cfg.OutputDir = "/in", "/out"
+	hash := cfg.OutputParamsHash()
+	for _, policy := range []string{OnConflictOverwrite, OnConflictSkip, OnConflictRename} {
+		cfg.OnConflict = policy
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate() с --on-conflict %s: %v", policy, err)
+		}
+		if cfg.OutputParamsHash() != hash {
+			t.Errorf("OutputParamsHash() изменился с --on-conflict %s", policy)
+		}
+	}
+
+	cfg.OnConflict = "ask"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() с неизвестной политикой должна вернуть ошибку")
+	}
+	cfg.OnConflict = OnConflictRename
+	cfg.Force = true
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() с --force и --on-conflict rename должна вернуть ошибку")
+	}
+	cfg.Force = false
+	cfg.SkipExistingOutput = true
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() с --skip-existing-output и --on-conflict rename должна вернуть ошибку")
+	}
+}
@@ -0,0 +1,25 @@
+package config
+
+import "fmt"
+
+// DirConfigName - имя файла настроек поддиректории: параметры выхода из него
+// действуют на файлы этой директории и вложенных (до следующего такого файла).
+const DirConfigName = ".photoconverter.yaml"
+
+// WithDirOverride возвращает копию конфигурации с параметрами выхода
+// (секция output, кроме dir) из настроек поддиректории fc. Пути, параметры
+// обработки и запуска берутся из c: они общие для всего запуска.
+func (c *Config) WithDirOverride(fc *FileConfig) (*Config, error) {
+	v := *c
+	if fc == nil || fc.Output == nil {
+		return &v, nil
+	}
+
+	output := *fc.Output
+	output.Dir = ""
+	(&FileConfig{Output: &output}).ApplyToConfig(&v)
+	if err := v.Validate(); err != nil {
+		return nil, fmt.Errorf("настройки %s: %w", DirConfigName, err)
+	}
+	return &v, nil
+}
@@ -33,6 +33,15 @@ type InputConfig struct {
 
 	// Extensions - список расширений входных файлов.
 	Extensions []string `yaml:"extensions,omitempty"`
+
+	// DetectByContent - определять формат и по содержимому файла.
+	DetectByContent bool `yaml:"detect_by_content,omitempty"`
+
+	// Include - glob-шаблоны включаемых файлов.
+	Include []string `yaml:"include,omitempty"`
+
+	// Exclude - glob-шаблоны исключаемых файлов.
+	Exclude []string `yaml:"exclude,omitempty"`
 }
 
 // OutputConfig содержит настройки выходных данных.
@@ -43,6 +52,12 @@ type OutputConfig struct {
 	// Format - выходной формат (webp, jpg, png, avif, tiff, heic, jxl).
 	Format string `yaml:"format,omitempty"`
 
+	// Extension - расширение выходных файлов вместо расширения формата (jpeg вместо jpg).
+	Extension string `yaml:"extension,omitempty"`
+
+	// FormatMap - выходной формат по расширению исходника (heic: jpg).
+	FormatMap map[string]string `yaml:"format_map,omitempty"`
+
 	// Quality - качество для lossy форматов (1-100).
 	Quality int `yaml:"quality,omitempty"`
 
@@ -52,27 +67,99 @@ type OutputConfig struct {
 	// KeepTree - сохранять структуру директорий.
 	KeepTree *bool `yaml:"keep_tree,omitempty"`
 
+	// FlatNaming - имена в плоской структуре: basename, hashed, pathjoined.
+	FlatNaming string `yaml:"flat_naming,omitempty"`
+
+	// OrganizeByDate - раскладывать результаты по директориям даты съёмки.
+	OrganizeByDate bool `yaml:"organize_by_date,omitempty"`
+
+	// DateFolder - шаблон директории даты (YYYY/MM/DD).
+	DateFolder string `yaml:"date_folder,omitempty"`
+
 	// MaxWidth - максимальная ширина изображения.
 	MaxWidth int `yaml:"max_width,omitempty"`
 
 	// MaxHeight - максимальная высота изображения.
 	MaxHeight int `yaml:"max_height,omitempty"`
+
+	// CropAspect - соотношение сторон с обрезкой ("16:9", "1:1").
+	CropAspect string `yaml:"crop,omitempty"`
+
+	// CropMode - режим обрезки: centre, attention.
+	CropMode string `yaml:"crop_mode,omitempty"`
+
+	// SmartCrop - обрезка по заметной области (attention).
+	SmartCrop bool `yaml:"smart_crop,omitempty"`
+
+	// AllImages - все изображения многокадрового HEIC/HEIF.
+	AllImages bool `yaml:"all_images,omitempty"`
+
+	// PreserveAnimation - сохранять анимацию GIF/WebP.
+	PreserveAnimation bool `yaml:"preserve_animation,omitempty"`
+
+	// Sharpen - sigma повышения резкости после resize (0 = выключено).
+	Sharpen float64 `yaml:"sharpen,omitempty"`
+
+	// SharpenAlways - повышать резкость и без resize.
+	SharpenAlways bool `yaml:"sharpen_always,omitempty"`
+
+	// Blur - sigma размытия (0 = выключено).
+	Blur float64 `yaml:"blur,omitempty"`
+
+	// Pixelate - размер блока пикселизации (0 = выключено).
+	Pixelate int `yaml:"pixelate,omitempty"`
+
+	// Grayscale - перевести в оттенки серого.
+	Grayscale bool `yaml:"grayscale,omitempty"`
+
+	// Sepia - тонировать в сепию.
+	Sepia bool `yaml:"sepia,omitempty"`
+
+	// DPI - плотность печати в метаданных результата (0 = как у исходника).
+	DPI int `yaml:"dpi,omitempty"`
+
+	// EmbedSRGB - встраивать sRGB в результаты исходников без ICC профиля.
+	EmbedSRGB bool `yaml:"embed_srgb,omitempty"`
+
+	// MaxFileSize - предельный размер выходного файла в байтах.
+	MaxFileSize int64 `yaml:"max_file_size,omitempty"`
 }
 
 // ProcessingConfig содержит настройки обработки.
 type ProcessingConfig struct {
-	// Workers - количество параллельных воркеров.
+	// Workers - количество параллельных воркеров (-1 = автоподбор).
 	Workers int `yaml:"workers,omitempty"`
 
+	// ConcurrencyPerFormat - предел одновременных конвертаций по выходному формату (avif: 2).
+	ConcurrencyPerFormat map[string]int `yaml:"concurrency_per_format,omitempty"`
+
+	// OnConflict - выходной файл есть на диске, но не в БД (overwrite, skip, rename).
+	OnConflict string `yaml:"on_conflict,omitempty"`
+
 	// Mode - режим работы (skip/dedup).
 	Mode string `yaml:"mode,omitempty"`
 
+	// DedupHash - алгоритм хэша содержимого в режиме dedup (sha256, blake3, xxhash).
+	DedupHash string `yaml:"dedup_hash,omitempty"`
+
+	// DedupQuick - быстрые ключи содержимого (размер, начало и конец файла).
+	DedupQuick bool `yaml:"dedup_quick,omitempty"`
+
+	// DedupQuickBytes - сколько байт с начала и с конца файла учитывать в быстром ключе.
+	DedupQuickBytes int64 `yaml:"dedup_quick_bytes,omitempty"`
+
+	// DedupLink - ссылка на месте дубликата (none, symlink, hardlink).
+	DedupLink string `yaml:"dedup_link,omitempty"`
+
 	// DryRun - режим симуляции.
 	DryRun bool `yaml:"dry_run,omitempty"`
 
-	// Verbose - подробный вывод.
+	// Verbose - подробный вывод (то же, что verbosity: 1; оставлен для старых конфигов).
 	Verbose bool `yaml:"verbose,omitempty"`
 
+	// Verbosity - подробность вывода: -1 тихо, 0 обычно, 1 подробно, 2 отладка.
+	Verbosity int `yaml:"verbosity,omitempty"`
+
 	// NoProgress - отключить прогресс-бар.
 	NoProgress bool `yaml:"no_progress,omitempty"`
 
@@ -85,11 +172,23 @@ type ProcessingConfig struct {
 	// Stream - потоковый режим без предварительного подсчёта файлов.
 	Stream bool `yaml:"stream,omitempty"`
 
-	// MaxMemoryMB - ограничение памяти в мегабайтах.
+	// MaxMemoryMB - ограничение памяти в мегабайтах (-1 = адаптивно).
 	MaxMemoryMB int `yaml:"max_memory_mb,omitempty"`
 
+	// MaxMegapixels - предельный размер исходника в мегапикселях (0 = без ограничения).
+	MaxMegapixels float64 `yaml:"max_megapixels,omitempty"`
+
+	// MegapixelsAction - skip или downscale для исходников больше MaxMegapixels.
+	MegapixelsAction string `yaml:"megapixels_action,omitempty"`
+
 	// UseGPU - использовать GPU ускорение (OpenCL).
 	UseGPU bool `yaml:"use_gpu,omitempty"`
+
+	// Backend - движок конвертации: vips или magick.
+	Backend string `yaml:"backend,omitempty"`
+
+	// MagickFallback - ImageMagick для форматов, которые vips не читает.
+	MagickFallback bool `yaml:"magick_fallback,omitempty"`
 }
 
 // PathsConfig содержит настройки путей.
@@ -99,6 +198,12 @@ type PathsConfig struct {
 
 	// VipsPath - путь к бинарнику vips.
 	VipsPath string `yaml:"vips_path,omitempty"`
+
+	// MagickPath - путь к ImageMagick.
+	MagickPath string `yaml:"magick_path,omitempty"`
+
+	// RawDecoderPath - путь к декодеру RAW (dcraw/dcraw_emu).
+	RawDecoderPath string `yaml:"raw_decoder_path,omitempty"`
 }
 
 // DefaultConfigPaths возвращает список путей для поиска конфигурационного файла.
@@ -191,37 +296,96 @@ func FromConfig(cfg *Config) *FileConfig {
 
 	return &FileConfig{
 		Input: &InputConfig{
-			Dir:        cfg.InputDir,
-			Extensions: cfg.InputExtensions,
+			Dir:             cfg.InputDir,
+			Extensions:      cfg.InputExtensions,
+			DetectByContent: cfg.DetectByContent,
+			Include:         cfg.Include,
+			Exclude:         cfg.Exclude,
 		},
 		Output: &OutputConfig{
-			Dir:           cfg.OutputDir,
-			Format:        string(cfg.OutputFormat),
-			Quality:       cfg.Quality,
-			StripMetadata: cfg.StripMetadata,
-			KeepTree:      &keepTree,
-			MaxWidth:      cfg.MaxWidth,
-			MaxHeight:     cfg.MaxHeight,
+			Dir:               cfg.OutputDir,
+			Format:            string(cfg.OutputFormat),
+			Extension:         cfg.OutputExtension,
+			FormatMap:         formatMapToFile(cfg.FormatMap),
+			Quality:           cfg.Quality,
+			StripMetadata:     cfg.StripMetadata,
+			KeepTree:          &keepTree,
+			FlatNaming:        cfg.FlatNaming,
+			OrganizeByDate:    cfg.OrganizeByDate,
+			DateFolder:        cfg.DateFolderTemplate,
+			MaxWidth:          cfg.MaxWidth,
+			MaxHeight:         cfg.MaxHeight,
+			CropAspect:        cfg.CropAspect,
+			CropMode:          cfg.CropMode,
+			SmartCrop:         cfg.SmartCrop,
+			AllImages:         cfg.AllImages,
+			PreserveAnimation: cfg.PreserveAnimation,
+			Sharpen:           cfg.Sharpen,
+			SharpenAlways:     cfg.SharpenAlways,
+			Blur:              cfg.Blur,
+			Pixelate:          cfg.Pixelate,
+			Grayscale:         cfg.Grayscale,
+			Sepia:             cfg.Sepia,
+			DPI:               cfg.DPI,
+			EmbedSRGB:         cfg.EmbedSRGB,
+			MaxFileSize:       cfg.MaxFileSize,
 		},
 		Processing: &ProcessingConfig{
-			Workers:     cfg.Workers,
-			Mode:        string(cfg.Mode),
-			DryRun:      cfg.DryRun,
-			Verbose:     cfg.Verbose,
-			NoProgress:  cfg.NoProgress,
-			Preset:      cfg.Preset,
-			Watch:       cfg.Watch,
-			Stream:      cfg.Stream,
-			MaxMemoryMB: cfg.MaxMemoryMB,
-			UseGPU:      cfg.UseGPU,
+			Workers:          cfg.Workers,
+			Mode:             string(cfg.Mode),
+			DedupHash:        cfg.DedupHash,
+			DedupQuick:       cfg.DedupQuick,
+			DedupQuickBytes:  cfg.DedupQuickBytes,
+			DedupLink:        cfg.DedupLink,
+			DryRun:           cfg.DryRun,
+			Verbosity:        cfg.Verbosity,
+			NoProgress:       cfg.NoProgress,
+			Preset:           cfg.Preset,
+			Watch:            cfg.Watch,
+			Stream:           cfg.Stream,
+			MaxMemoryMB:      cfg.MaxMemoryMB,
+			MaxMegapixels:    cfg.MaxMegapixels,
+			MegapixelsAction: cfg.MegapixelsAction,
+			UseGPU:           cfg.UseGPU,
+			Backend:          cfg.Backend,
+			MagickFallback:   cfg.MagickFallback,
+
+			ConcurrencyPerFormat: concurrencyToFile(cfg.ConcurrencyPerFormat),
+			OnConflict:           cfg.OnConflict,
 		},
 		Paths: &PathsConfig{
-			DB:       dbPath,
-			VipsPath: cfg.VipsPath,
+			DB:             dbPath,
+			VipsPath:       cfg.VipsPath,
+			MagickPath:     cfg.MagickPath,
+			RawDecoderPath: cfg.RawDecoderPath,
 		},
 	}
 }
 
+// formatMapToFile преобразует FormatMap для сохранения в YAML.
+func formatMapToFile(m map[string]OutputFormat) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for ext, format := range m {
+		out[ext] = string(format)
+	}
+	return out
+}
+
+// concurrencyToFile преобразует ConcurrencyPerFormat для сохранения в YAML.
+func concurrencyToFile(m map[OutputFormat]int) map[string]int {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]int, len(m))
+	for format, n := range m {
+		out[string(format)] = n
+	}
+	return out
+}
+
 // SaveToFile сохраняет конфигурацию в указанный файл YAML.
 func (fc *FileConfig) SaveToFile(path string) error {
 	// Создаём директорию если не существует
@@ -281,6 +445,15 @@ func (fc *FileConfig) ApplyToConfig(cfg *Config) {
 		if len(fc.Input.Extensions) > 0 {
 			cfg.InputExtensions = fc.Input.Extensions
 		}
+		if fc.Input.DetectByContent {
+			cfg.DetectByContent = true
+		}
+		if len(fc.Input.Include) > 0 {
+			cfg.Include = fc.Input.Include
+		}
+		if len(fc.Input.Exclude) > 0 {
+			cfg.Exclude = fc.Input.Exclude
+		}
 	}
 
 	// Output
@@ -291,6 +464,15 @@ func (fc *FileConfig) ApplyToConfig(cfg *Config) {
 		if fc.Output.Format != "" {
 			cfg.OutputFormat = OutputFormat(fc.Output.Format)
 		}
+		if fc.Output.Extension != "" {
+			cfg.OutputExtension = strings.TrimPrefix(fc.Output.Extension, ".")
+		}
+		if len(fc.Output.FormatMap) > 0 {
+			cfg.FormatMap = make(map[string]OutputFormat, len(fc.Output.FormatMap))
+			for ext, format := range fc.Output.FormatMap {
+				cfg.FormatMap[strings.TrimPrefix(strings.ToLower(ext), ".")] = OutputFormat(strings.ToLower(format))
+			}
+		}
 		if fc.Output.Quality > 0 {
 			cfg.Quality = fc.Output.Quality
 		}
@@ -300,27 +482,101 @@ func (fc *FileConfig) ApplyToConfig(cfg *Config) {
 		if fc.Output.KeepTree != nil {
 			cfg.KeepTree = *fc.Output.KeepTree
 		}
+		if fc.Output.FlatNaming != "" {
+			cfg.FlatNaming = fc.Output.FlatNaming
+		}
+		if fc.Output.OrganizeByDate {
+			cfg.OrganizeByDate = true
+		}
+		if fc.Output.DateFolder != "" {
+			cfg.DateFolderTemplate = fc.Output.DateFolder
+		}
 		if fc.Output.MaxWidth > 0 {
 			cfg.MaxWidth = fc.Output.MaxWidth
 		}
 		if fc.Output.MaxHeight > 0 {
 			cfg.MaxHeight = fc.Output.MaxHeight
 		}
+		if fc.Output.CropAspect != "" {
+			cfg.CropAspect = fc.Output.CropAspect
+		}
+		if fc.Output.CropMode != "" {
+			cfg.CropMode = fc.Output.CropMode
+		}
+		if fc.Output.SmartCrop {
+			cfg.SmartCrop = true
+		}
+		if fc.Output.AllImages {
+			cfg.AllImages = true
+		}
+		if fc.Output.PreserveAnimation {
+			cfg.PreserveAnimation = true
+		}
+		if fc.Output.Sharpen > 0 {
+			cfg.Sharpen = fc.Output.Sharpen
+		}
+		if fc.Output.SharpenAlways {
+			cfg.SharpenAlways = true
+		}
+		if fc.Output.Blur > 0 {
+			cfg.Blur = fc.Output.Blur
+		}
+		if fc.Output.Pixelate > 0 {
+			cfg.Pixelate = fc.Output.Pixelate
+		}
+		if fc.Output.Grayscale {
+			cfg.Grayscale = true
+		}
+		if fc.Output.Sepia {
+			cfg.Sepia = true
+		}
+		if fc.Output.DPI > 0 {
+			cfg.DPI = fc.Output.DPI
+		}
+		if fc.Output.EmbedSRGB {
+			cfg.EmbedSRGB = true
+		}
+		if fc.Output.MaxFileSize > 0 {
+			cfg.MaxFileSize = fc.Output.MaxFileSize
+		}
 	}
 
 	// Processing
 	if fc.Processing != nil {
-		if fc.Processing.Workers > 0 {
+		if fc.Processing.Workers > 0 || fc.Processing.Workers == WorkersAuto {
 			cfg.Workers = fc.Processing.Workers
 		}
+		if len(fc.Processing.ConcurrencyPerFormat) > 0 {
+			cfg.ConcurrencyPerFormat = make(map[OutputFormat]int, len(fc.Processing.ConcurrencyPerFormat))
+			for format, n := range fc.Processing.ConcurrencyPerFormat {
+				cfg.ConcurrencyPerFormat[OutputFormat(strings.ToLower(format))] = n
+			}
+		}
 		if fc.Processing.Mode != "" {
 			cfg.Mode = Mode(fc.Processing.Mode)
 		}
+		if fc.Processing.DedupHash != "" {
+			cfg.DedupHash = fc.Processing.DedupHash
+		}
+		if fc.Processing.DedupQuick {
+			cfg.DedupQuick = true
+		}
+		if fc.Processing.DedupQuickBytes > 0 {
+			cfg.DedupQuickBytes = fc.Processing.DedupQuickBytes
+		}
+		if fc.Processing.DedupLink != "" {
+			cfg.DedupLink = fc.Processing.DedupLink
+		}
+		if fc.Processing.OnConflict != "" {
+			cfg.OnConflict = fc.Processing.OnConflict
+		}
 		if fc.Processing.DryRun {
 			cfg.DryRun = true
 		}
-		if fc.Processing.Verbose {
-			cfg.Verbose = true
+		if fc.Processing.Verbosity != 0 {
+			cfg.Verbosity = fc.Processing.Verbosity
+		} else if fc.Processing.Verbose {
+			cfg.Verbosity = VerbosityVerbose
 		}
 		if fc.Processing.NoProgress {
 			cfg.NoProgress = true
@@ -334,12 +590,24 @@ func (fc *FileConfig) ApplyToConfig(cfg *Config) {
 		if fc.Processing.Stream {
 			cfg.Stream = true
 		}
-		if fc.Processing.MaxMemoryMB > 0 {
+		if fc.Processing.MaxMemoryMB != 0 {
 			cfg.MaxMemoryMB = fc.Processing.MaxMemoryMB
 		}
+		if fc.Processing.MaxMegapixels > 0 {
+			cfg.MaxMegapixels = fc.Processing.MaxMegapixels
+		}
+		if fc.Processing.MegapixelsAction != "" {
+			cfg.MegapixelsAction = fc.Processing.MegapixelsAction
+		}
 		if fc.Processing.UseGPU {
 			cfg.UseGPU = true
 		}
+		if fc.Processing.Backend != "" {
+			cfg.Backend = fc.Processing.Backend
+		}
+		if fc.Processing.MagickFallback {
+			cfg.MagickFallback = true
+		}
 	}
 
 	// Paths
@@ -350,6 +618,12 @@ func (fc *FileConfig) ApplyToConfig(cfg *Config) {
 		if fc.Paths.VipsPath != "" {
 			cfg.VipsPath = fc.Paths.VipsPath
 		}
+		if fc.Paths.MagickPath != "" {
+			cfg.MagickPath = fc.Paths.MagickPath
+		}
+		if fc.Paths.RawDecoderPath != "" {
+			cfg.RawDecoderPath = fc.Paths.RawDecoderPath
+		}
 	}
 }
 
@@ -370,12 +644,24 @@ input:
     - heic
     - heif
     - webp
+  # Принимать и файлы с другим расширением (или без него), если формат
+  # по сигнатуре в начале файла входит в extensions
+  # detect_by_content: true
+  # Glob-шаблоны относительно входной директории (** - любое число директорий)
+  # include:
+  #   - "2024/**"
+  # exclude:
+  #   - "**/raw/**"
 
 output:
   # Директория для результатов
   dir: "./converted"
   # Выходной формат: webp, jpg, png, avif, tiff, heic, jxl
   format: webp
+  # Выходной формат по расширению исходника (остальные - в format)
+  # format_map:
+  #   heic: jpg
+  #   png: webp
   # Качество для lossy форматов (1-100)
   quality: 85
   # Удалять метаданные
@@ -390,8 +676,8 @@ processing:
   mode: skip
   # Симуляция без реальной конвертации
   dry_run: false
-  # Подробный вывод
-  verbose: false
+  # Подробность вывода: -1 только ошибки, 0 обычно, 1 каждый файл, 2 отладка
+  verbosity: 0
   # Отключить прогресс-бар
   no_progress: false
 
@@ -1,6 +1,11 @@
 // Package config содержит конфигурацию приложения.
 package config
 
+import (
+	"fmt"
+	"path/filepath"
+)
+
 // Preset определяет профиль качества.
 type Preset string
 
@@ -13,6 +18,13 @@ const (
 	PresetArchive Preset = "archive"
 	// PresetThumbnail - превью: webp, качество 60, max-width 300.
 	PresetThumbnail Preset = "thumbnail"
+	// PresetInstagram - квадрат 1080x1080 с обрезкой по центру: jpg, качество 85, strip metadata.
+	PresetInstagram Preset = "instagram"
+	// PresetTelegram - webp, качество 80, длинная сторона 1280, strip metadata.
+	PresetTelegram Preset = "telegram"
+	// PresetEmail - вложение в письмо: jpg, качество 85, max 1920x1920, strip metadata,
+	// не больше 1MB (качество снижается до попадания в лимит).
+	PresetEmail Preset = "email"
 )
 
 // PresetConfig содержит настройки для пресета.
@@ -27,6 +39,10 @@ type PresetConfig struct {
 	MaxHeight int
 	// StripMetadata - удалять метаданные.
 	StripMetadata bool
+	// CropAspect - соотношение сторон с обрезкой (пусто = без обрезки).
+	CropAspect string
+	// MaxFileSize - предельный размер файла в байтах (0 = без ограничения).
+	MaxFileSize int64
 }
 
 // Presets содержит все доступные пресеты.
@@ -59,6 +75,29 @@ var Presets = map[Preset]PresetConfig{
 		MaxHeight:     300,
 		StripMetadata: true,
 	},
+	PresetInstagram: {
+		Format:        FormatJPEG,
+		Quality:       85,
+		MaxWidth:      1080,
+		MaxHeight:     1080,
+		StripMetadata: true,
+		CropAspect:    "1:1",
+	},
+	PresetTelegram: {
+		Format:        FormatWebP,
+		Quality:       80,
+		MaxWidth:      1280,
+		MaxHeight:     1280,
+		StripMetadata: true,
+	},
+	PresetEmail: {
+		Format:        FormatJPEG,
+		Quality:       85,
+		MaxWidth:      1920,
+		MaxHeight:     1920,
+		StripMetadata: true,
+		MaxFileSize:   1 << 20,
+	},
 }
 
 // ApplyPreset применяет пресет к конфигурации.
@@ -74,10 +113,25 @@ func (c *Config) ApplyPreset(preset string) bool {
 	c.MaxWidth = p.MaxWidth
 	c.MaxHeight = p.MaxHeight
 	c.StripMetadata = p.StripMetadata
+	c.CropAspect = p.CropAspect
+	c.MaxFileSize = p.MaxFileSize
 
 	return true
 }
 
+// PresetVariant возвращает копию конфигурации с применённым пресетом и выходной
+// директорией <OutputDir>/<preset> (для --multi-preset).
+func (c *Config) PresetVariant(preset string) (*Config, error) {
+	v := *c
+	if !v.ApplyPreset(preset) {
+		return nil, fmt.Errorf("неизвестный пресет: %s (доступны: %v)", preset, ValidPresets())
+	}
+	v.Preset = preset
+	v.MultiPresets = nil
+	v.OutputDir = filepath.Join(c.OutputDir, preset)
+	return &v, nil
+}
+
 // ValidPresets возвращает список доступных пресетов.
 func ValidPresets() []string {
 	return []string{
@@ -85,12 +139,13 @@ func ValidPresets() []string {
 		string(PresetPrint),
 		string(PresetArchive),
 		string(PresetThumbnail),
+		string(PresetInstagram),
+		string(PresetTelegram),
+		string(PresetEmail),
 	}
 }
 
 /*
 Возможные расширения:
 - Добавить пользовательские пресеты из конфигурационного файла
-- Добавить пресет для социальных сетей (instagram, telegram)
-- Добавить пресет для email (ограничение по размеру файла)
 */
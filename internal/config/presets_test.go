@@ -1,6 +1,7 @@
 package config
 
 import (
+	"path/filepath"
 	"testing"
 )
 
@@ -40,6 +41,27 @@ func TestApplyPreset(t *testing.T) {
 			wantFormat: FormatWebP,
 			wantQual:   60,
 		},
+		{
+			name:       "instagram preset",
+			preset:     "instagram",
+			wantOK:     true,
+			wantFormat: FormatJPEG,
+			wantQual:   85,
+		},
+		{
+			name:       "telegram preset",
+			preset:     "telegram",
+			wantOK:     true,
+			wantFormat: FormatWebP,
+			wantQual:   80,
+		},
+		{
+			name:       "email preset",
+			preset:     "email",
+			wantOK:     true,
+			wantFormat: FormatJPEG,
+			wantQual:   85,
+		},
 		{
 			name:   "unknown preset",
 			preset: "unknown",
@@ -75,7 +97,7 @@ func TestValidPresets(t *testing.T) {
 		t.Error("ValidPresets() returned empty slice")
 	}
 
-	expected := []string{"web", "print", "archive", "thumbnail"}
+	expected := []string{"web", "print", "archive", "thumbnail", "instagram", "telegram", "email"}
 	if len(presets) != len(expected) {
 		t.Errorf("ValidPresets() returned %d presets, want %d", len(presets), len(expected))
 	}
@@ -142,3 +164,85 @@ func TestPresetThumbnailSettings(t *testing.T) {
 		t.Errorf("Thumbnail preset MaxHeight = %d, want 300", cfg.MaxHeight)
 	}
 }
+
+func TestPresetVariant(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = "/tmp/out"
+	cfg.MultiPresets = []string{"web", "thumbnail"}
+
+	v, err := cfg.PresetVariant("thumbnail")
+	if err != nil {
+		t.Fatalf("PresetVariant: %v", err)
+	}
+	if v.OutputDir != filepath.Join("/tmp/out", "thumbnail") {
+		t.Errorf("OutputDir = %q, want /tmp/out/thumbnail", v.OutputDir)
+	}
+	if v.MaxWidth != 300 || len(v.MultiPresets) != 0 {
+		t.Errorf("variant MaxWidth=%d MultiPresets=%v, want 300 and empty", v.MaxWidth, v.MultiPresets)
+	}
+	if cfg.MaxWidth != 0 || cfg.OutputDir != "/tmp/out" {
+		t.Error("PresetVariant must not modify the base config")
+	}
+	if v.OutputParamsHash() == cfg.OutputParamsHash() {
+		t.Error("variant must have a different output params hash")
+	}
+
+	if _, err := cfg.PresetVariant("unknown"); err == nil {
+		t.Error("expected error for unknown preset")
+	}
+}
+
+func TestPresetSocialSettings(t *testing.T) {
+	tests := []struct {
+		preset     string
+		wantWidth  int
+		wantHeight int
+		wantCrop   string
+	}{
+		{preset: "instagram", wantWidth: 1080, wantHeight: 1080, wantCrop: "1:1"},
+		{preset: "telegram", wantWidth: 1280, wantHeight: 1280, wantCrop: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.preset, func(t *testing.T) {
+			cfg := DefaultConfig()
+			if !cfg.ApplyPreset(tt.preset) {
+				t.Fatalf("ApplyPreset(%s) = false", tt.preset)
+			}
+			if cfg.MaxWidth != tt.wantWidth || cfg.MaxHeight != tt.wantHeight {
+				t.Errorf("размер = %dx%d, want %dx%d", cfg.MaxWidth, cfg.MaxHeight, tt.wantWidth, tt.wantHeight)
+			}
+			if cfg.CropAspect != tt.wantCrop {
+				t.Errorf("CropAspect = %q, want %q", cfg.CropAspect, tt.wantCrop)
+			}
+			if !cfg.StripMetadata {
+				t.Error("пресет должен удалять метаданные")
+			}
+		})
+	}
+
+	// Обрезка не должна переноситься на следующий пресет
+	cfg := DefaultConfig()
+	cfg.ApplyPreset("instagram")
+	cfg.ApplyPreset("web")
+	if cfg.CropAspect != "" {
+		t.Error("CropAspect остался после смены пресета")
+	}
+}
+
+func TestPresetEmailSettings(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ApplyPreset("email")
+
+	if cfg.MaxFileSize != 1<<20 {
+		t.Errorf("Email preset MaxFileSize = %d, want 1MB", cfg.MaxFileSize)
+	}
+	if !cfg.HasQuality() {
+		t.Error("Email preset должен использовать формат с качеством")
+	}
+
+	cfg.ApplyPreset("print")
+	if cfg.MaxFileSize != 0 {
+		t.Errorf("MaxFileSize остался после смены пресета: %d", cfg.MaxFileSize)
+	}
+}
@@ -0,0 +1,59 @@
+// Package config содержит разбор размеров в байтах.
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits - множители суффиксов размера (двоичные: 1KB = 1024 байта).
+var sizeUnits = []struct {
+	suffix string
+	mult   float64
+}{
+	{"kib", 1 << 10}, {"mib", 1 << 20}, {"gib", 1 << 30},
+	{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30},
+	{"k", 1 << 10}, {"m", 1 << 20}, {"g", 1 << 30},
+	{"b", 1},
+}
+
+// ParseByteSize разбирает размер вида "5MB", "1.5m", "512KiB" или "1024".
+// Суффиксы двоичные и не зависят от регистра; без суффикса - байты.
+func ParseByteSize(s string) (int64, error) {
+	str := strings.ToLower(strings.TrimSpace(s))
+	mult := 1.0
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(str, u.suffix) {
+			str = strings.TrimSpace(strings.TrimSuffix(str, u.suffix))
+			mult = u.mult
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(str, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("некорректный размер: %q (примеры: 500KB, 5MB, 1.5GB)", s)
+	}
+	return int64(n * mult), nil
+}
+
+// FormatByteSize форматирует размер крупнейшей единицей, которая делит его
+// без остатка ("5MB", "1536KB", "100B"), так что ParseByteSize возвращает
+// исходное значение.
+func FormatByteSize(n int64) string {
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}} {
+		if n > 0 && n%u.mult == 0 {
+			return strconv.FormatInt(n/u.mult, 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(n, 10) + "B"
+}
+
+/*
+Возможные расширения:
+- Десятичные единицы (1MB = 1000000) по отдельному флагу
+*/
@@ -0,0 +1,232 @@
+package contenthash
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// Параметры BLAKE3.
+const (
+	blake3BlockLen = 64
+	blake3ChunkLen = 1024
+	blake3OutLen   = 32
+
+	flagChunkStart = 1 << 0
+	flagChunkEnd   = 1 << 1
+	flagParent     = 1 << 2
+	flagRoot       = 1 << 3
+)
+
+var blake3IV = [8]uint32{
+	0x6A09E667, 0xBB67AE85, 0x3C6EF372, 0xA54FF53A,
+	0x510E527F, 0x9B05688C, 0x1F83D9AB, 0x5BE0CD19,
+}
+
+// blake3Schedule - порядок слов сообщения в каждом из 7 раундов
+// (перестановка BLAKE3, применённая заранее).
+var blake3Schedule = [7][16]uint8{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{2, 6, 3, 10, 7, 0, 4, 13, 1, 11, 12, 5, 9, 14, 15, 8},
+	{3, 4, 10, 12, 13, 2, 7, 14, 6, 5, 9, 0, 11, 15, 8, 1},
+	{10, 7, 12, 9, 14, 3, 13, 15, 4, 0, 11, 2, 5, 8, 1, 6},
+	{12, 13, 9, 11, 15, 10, 14, 8, 7, 2, 5, 3, 0, 1, 6, 4},
+	{9, 14, 11, 5, 8, 12, 15, 1, 13, 3, 0, 10, 2, 6, 4, 7},
+	{11, 15, 5, 0, 1, 9, 8, 6, 14, 10, 2, 12, 3, 4, 7, 13},
+}
+
+// blake3G - четвертьраунд BLAKE3.
+func blake3G(a, b, c, d, mx, my uint32) (uint32, uint32, uint32, uint32) {
+	a += b + mx
+	d = bits.RotateLeft32(d^a, -16)
+	c += d
+	b = bits.RotateLeft32(b^c, -12)
+	a += b + my
+	d = bits.RotateLeft32(d^a, -8)
+	c += d
+	b = bits.RotateLeft32(b^c, -7)
+	return a, b, c, d
+}
+
+// blake3Compress - функция сжатия BLAKE3.
+func blake3Compress(cv *[8]uint32, m *[16]uint32, counter uint64, blockLen, flags uint32) [16]uint32 {
+	s0, s1, s2, s3, s4, s5, s6, s7 := cv[0], cv[1], cv[2], cv[3], cv[4], cv[5], cv[6], cv[7]
+	s8, s9, s10, s11 := blake3IV[0], blake3IV[1], blake3IV[2], blake3IV[3]
+	s12, s13, s14, s15 := uint32(counter), uint32(counter>>32), blockLen, flags
+
+	for r := range blake3Schedule {
+		k := &blake3Schedule[r]
+		s0, s4, s8, s12 = blake3G(s0, s4, s8, s12, m[k[0]], m[k[1]])
+		s1, s5, s9, s13 = blake3G(s1, s5, s9, s13, m[k[2]], m[k[3]])
+		s2, s6, s10, s14 = blake3G(s2, s6, s10, s14, m[k[4]], m[k[5]])
+		s3, s7, s11, s15 = blake3G(s3, s7, s11, s15, m[k[6]], m[k[7]])
+		s0, s5, s10, s15 = blake3G(s0, s5, s10, s15, m[k[8]], m[k[9]])
+		s1, s6, s11, s12 = blake3G(s1, s6, s11, s12, m[k[10]], m[k[11]])
+		s2, s7, s8, s13 = blake3G(s2, s7, s8, s13, m[k[12]], m[k[13]])
+		s3, s4, s9, s14 = blake3G(s3, s4, s9, s14, m[k[14]], m[k[15]])
+	}
+
+	return [16]uint32{
+		s0 ^ s8, s1 ^ s9, s2 ^ s10, s3 ^ s11, s4 ^ s12, s5 ^ s13, s6 ^ s14, s7 ^ s15,
+		s8 ^ cv[0], s9 ^ cv[1], s10 ^ cv[2], s11 ^ cv[3], s12 ^ cv[4], s13 ^ cv[5], s14 ^ cv[6], s15 ^ cv[7],
+	}
+}
+
+func blake3Words(b []byte) [16]uint32 {
+	var buf [blake3BlockLen]byte
+	copy(buf[:], b)
+	var w [16]uint32
+	for i := range w {
+		w[i] = binary.LittleEndian.Uint32(buf[4*i:])
+	}
+	return w
+}
+
+// blake3Output - узел дерева перед финальным сжатием.
+type blake3Output struct {
+	cv       [8]uint32
+	block    [16]uint32
+	counter  uint64
+	blockLen uint32
+	flags    uint32
+}
+
+func (o *blake3Output) chainingValue() [8]uint32 {
+	s := blake3Compress(&o.cv, &o.block, o.counter, o.blockLen, o.flags)
+	var cv [8]uint32
+	copy(cv[:], s[:8])
+	return cv
+}
+
+func (o *blake3Output) rootBytes() [blake3OutLen]byte {
+	s := blake3Compress(&o.cv, &o.block, 0, o.blockLen, o.flags|flagRoot)
+	var out [blake3OutLen]byte
+	for i := 0; i < 8; i++ {
+		binary.LittleEndian.PutUint32(out[4*i:], s[i])
+	}
+	return out
+}
+
+func blake3Parent(left, right [8]uint32) blake3Output {
+	var block [16]uint32
+	copy(block[:8], left[:])
+	copy(block[8:], right[:])
+	return blake3Output{cv: blake3IV, block: block, blockLen: blake3BlockLen, flags: flagParent}
+}
+
+// blake3Chunk - состояние текущего чанка (1024 байта).
+type blake3Chunk struct {
+	cv         [8]uint32
+	counter    uint64
+	block      [blake3BlockLen]byte
+	blockLen   int
+	compressed int
+}
+
+func newBlake3Chunk(counter uint64) blake3Chunk {
+	return blake3Chunk{cv: blake3IV, counter: counter}
+}
+
+func (c *blake3Chunk) len() int {
+	return blake3BlockLen*c.compressed + c.blockLen
+}
+
+func (c *blake3Chunk) startFlag() uint32 {
+	if c.compressed == 0 {
+		return flagChunkStart
+	}
+	return 0
+}
+
+func (c *blake3Chunk) update(p []byte) {
+	for len(p) > 0 {
+		// Полный блок сжимаем, только когда пришли следующие данные:
+		// последний блок чанка сжимается с флагом CHUNK_END
+		if c.blockLen == blake3BlockLen {
+			words := blake3Words(c.block[:])
+			s := blake3Compress(&c.cv, &words, c.counter, blake3BlockLen, c.startFlag())
+			copy(c.cv[:], s[:8])
+			c.compressed++
+			c.blockLen = 0
+		}
+		n := copy(c.block[c.blockLen:], p)
+		c.blockLen += n
+		p = p[n:]
+	}
+}
+
+func (c *blake3Chunk) output() blake3Output {
+	return blake3Output{
+		cv:       c.cv,
+		block:    blake3Words(c.block[:c.blockLen]),
+		counter:  c.counter,
+		blockLen: uint32(c.blockLen),
+		flags:    c.startFlag() | flagChunkEnd,
+	}
+}
+
+// blake3Hasher - потоковый BLAKE3 (режим hash, 32 байта вывода).
+type blake3Hasher struct {
+	chunk blake3Chunk
+	stack [][8]uint32
+}
+
+// NewBLAKE3 создаёт хэш BLAKE3 с выводом 32 байта.
+func NewBLAKE3() hash.Hash {
+	return &blake3Hasher{chunk: newBlake3Chunk(0)}
+}
+
+// Reset сбрасывает состояние.
+func (h *blake3Hasher) Reset() {
+	h.chunk = newBlake3Chunk(0)
+	h.stack = h.stack[:0]
+}
+
+// Size возвращает размер хэша в байтах.
+func (h *blake3Hasher) Size() int { return blake3OutLen }
+
+// BlockSize возвращает размер блока.
+func (h *blake3Hasher) BlockSize() int { return blake3BlockLen }
+
+// Write добавляет данные к хэшу.
+func (h *blake3Hasher) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		// Чанк закрываем, только когда пришли следующие данные:
+		// последний чанк может оказаться корнем дерева
+		if h.chunk.len() == blake3ChunkLen {
+			out := h.chunk.output()
+			total := h.chunk.counter + 1
+			h.addChunkCV(out.chainingValue(), total)
+			h.chunk = newBlake3Chunk(total)
+		}
+		take := blake3ChunkLen - h.chunk.len()
+		if take > len(p) {
+			take = len(p)
+		}
+		h.chunk.update(p[:take])
+		p = p[take:]
+	}
+	return n, nil
+}
+
+// addChunkCV добавляет chaining value чанка в стек, объединяя полные поддеревья.
+func (h *blake3Hasher) addChunkCV(cv [8]uint32, totalChunks uint64) {
+	for totalChunks&1 == 0 {
+		parent := blake3Parent(h.stack[len(h.stack)-1], cv)
+		cv = parent.chainingValue()
+		h.stack = h.stack[:len(h.stack)-1]
+		totalChunks >>= 1
+	}
+	h.stack = append(h.stack, cv)
+}
+
+// Sum добавляет хэш к b, не меняя состояние.
+func (h *blake3Hasher) Sum(b []byte) []byte {
+	out := h.chunk.output()
+	for i := len(h.stack) - 1; i >= 0; i-- {
+		out = blake3Parent(h.stack[i], out.chainingValue())
+	}
+	root := out.rootBytes()
+	return append(b, root[:]...)
+}
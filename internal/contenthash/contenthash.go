@@ -0,0 +1,51 @@
+// Package contenthash содержит алгоритмы хэширования содержимого файлов
+// для режима dedup: sha256 (по умолчанию), blake3 и xxhash (XXH64).
+//
+// blake3 и xxhash реализованы без внешних зависимостей. Для dedup важна только
+// устойчивость к случайным совпадениям внутри одной библиотеки, поэтому
+// некриптографический xxhash подходит и заметно быстрее sha256.
+package contenthash
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+)
+
+// Поддерживаемые алгоритмы.
+const (
+	// SHA256 - crypto/sha256 (по умолчанию, совместим с существующими базами).
+	SHA256 = "sha256"
+
+	// BLAKE3 - криптографический хэш. Реализация скалярная (без SIMD), поэтому
+	// обгоняет sha256 только на процессорах без SHA-расширений.
+	BLAKE3 = "blake3"
+
+	// XXHash - некриптографический XXH64, самый быстрый вариант.
+	XXHash = "xxhash"
+)
+
+// Algorithms возвращает список поддерживаемых алгоритмов.
+func Algorithms() []string {
+	return []string{SHA256, BLAKE3, XXHash}
+}
+
+// New создаёт хэш по имени алгоритма (пустое имя = sha256).
+func New(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "", SHA256:
+		return sha256.New(), nil
+	case BLAKE3:
+		return NewBLAKE3(), nil
+	case XXHash:
+		return NewXXH64(), nil
+	default:
+		return nil, fmt.Errorf("неизвестный алгоритм хэширования: %s", algorithm)
+	}
+}
+
+/*
+Возможные расширения:
+- BLAKE3 с ключом (keyed hash) и произвольной длиной вывода
+- XXH3 (128 бит) вместо XXH64
+*/
@@ -0,0 +1,99 @@
+package contenthash
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestXXH64_Vectors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", "ef46db3751d8e999"},
+		{"a", "d24ec4f1a98c6e5b"},
+		{"abc", "44bc2cf5ad770999"},
+		{"Nobody inspects the spammish repetition", "fbcea83c8a378bf1"},
+	}
+	for _, tt := range tests {
+		h := NewXXH64()
+		_, _ = h.Write([]byte(tt.input))
+		if got := hex.EncodeToString(h.Sum(nil)); got != tt.want {
+			t.Errorf("xxh64(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestBLAKE3_Vectors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262"},
+		{"abc", "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85"},
+	}
+	for _, tt := range tests {
+		h := NewBLAKE3()
+		_, _ = h.Write([]byte(tt.input))
+		if got := hex.EncodeToString(h.Sum(nil)); got != tt.want {
+			t.Errorf("blake3(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+// Официальные векторы BLAKE3: вход - байты i%251, проверяют дерево из нескольких чанков.
+func TestBLAKE3_MultiChunkVectors(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{1024, "42214739f095a406f3fc83deb889744ac00df831c10daa55189b5d121c855af7"},
+		{1025, "d00278ae47eb27b34faecf67b4fe263f82d5412916c1ffd97c8cb7fb814b8444"},
+		{2048, "e776b6028c7cd22a4d0ba182a8bf62205d2ef576467e838ed6f2529b85fba24a"},
+	}
+	for _, tt := range tests {
+		h := NewBLAKE3()
+		_, _ = h.Write(testInput(tt.n))
+		if got := hex.EncodeToString(h.Sum(nil)); got != tt.want {
+			t.Errorf("blake3(%d байт) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+}
+
+// Результат не должен зависеть от того, какими кусками пишутся данные.
+func TestNew_StreamingMatchesSingleWrite(t *testing.T) {
+	data := testInput(10*1024 + 37)
+	for _, algo := range Algorithms() {
+		whole, err := New(algo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = whole.Write(data)
+
+		parts, _ := New(algo)
+		for rest := data; len(rest) > 0; {
+			n := 7
+			if n > len(rest) {
+				n = len(rest)
+			}
+			_, _ = parts.Write(rest[:n])
+			rest = rest[n:]
+		}
+
+		if a, b := hex.EncodeToString(whole.Sum(nil)), hex.EncodeToString(parts.Sum(nil)); a != b {
+			t.Errorf("%s: потоковая запись %s != %s", algo, b, a)
+		}
+	}
+
+	if _, err := New("md5"); err == nil {
+		t.Error("New(md5) должен вернуть ошибку")
+	}
+}
+
+func testInput(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
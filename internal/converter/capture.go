@@ -0,0 +1,69 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// exifDateLayout - формат даты EXIF: "2023:05:14 10:22:33".
+const exifDateLayout = "2006:01:02 15:04:05"
+
+// parseExifDate разбирает дату EXIF в начале s. vipsheader дописывает
+// к значению описание: "2023:05:14 10:22:33 (2023:05:14 10:22:33, ASCII, ...)".
+// Дата EXIF без часового пояса считается местным временем.
+func parseExifDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < len(exifDateLayout) {
+		return time.Time{}, fmt.Errorf("нет даты съёмки в EXIF")
+	}
+	t, err := time.ParseInLocation(exifDateLayout, s[:len(exifDateLayout)], time.Local)
+	if err != nil || t.Year() < 1 {
+		return time.Time{}, fmt.Errorf("неверная дата съёмки в EXIF: %q", s)
+	}
+	return t, nil
+}
+
+// CaptureTime возвращает дату съёмки из EXIF DateTimeOriginal
+// (vipsheader -f exif-ifd2-DateTimeOriginal).
+func (c *VipsConverter) CaptureTime(ctx context.Context, path string) (time.Time, error) {
+	if c.vipsheaderPath == "" {
+		return time.Time{}, fmt.Errorf("vipsheader не найден")
+	}
+	return readCaptureTime(ctx, c.vipsheaderPath, "-f", "exif-ifd2-DateTimeOriginal", path)
+}
+
+// CaptureTime возвращает дату съёмки из EXIF DateTimeOriginal (identify).
+func (c *MagickConverter) CaptureTime(ctx context.Context, path string) (time.Time, error) {
+	if c.identify[0] == "" {
+		return time.Time{}, fmt.Errorf("identify не найден")
+	}
+	args := append(c.identify[1:len(c.identify):len(c.identify)], "-format", "%[EXIF:DateTimeOriginal]", path+"[0]")
+	return readCaptureTime(ctx, c.identify[0], args...)
+}
+
+// readCaptureTime запускает программу чтения заголовка и разбирает дату из stdout.
+func readCaptureTime(ctx context.Context, name string, args ...string) (time.Time, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	logCommand(ctx, cmd)
+	if err := cmd.Run(); err != nil {
+		return time.Time{}, fmt.Errorf("%s: %s", filepath.Base(name), strings.TrimSpace(err.Error()+": "+stderr.String()))
+	}
+	return parseExifDate(stdout.String())
+}
+
+// BuildDstPathDated строит путь к выходному файлу в директории даты date
+// (--organize-by-date): <out>/<DateFolder>/<имя по FlatNaming>.<ext>.
+func BuildDstPathDated(cfg *config.Config, relPath string, date time.Time) string {
+	p := dstPaths{cfg}
+	return filepath.Join(cfg.OutputDir, cfg.DateFolder(date), p.flatName(relPath)+"."+cfg.OutputExt())
+}
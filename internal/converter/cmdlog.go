@@ -0,0 +1,71 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// commandLogKey - ключ контекста с журналом командных строк.
+type commandLogKey struct{}
+
+// WithCommandLog возвращает контекст, в котором конвертер передаёт log
+// командную строку каждой внешней программы (vips, vipsheader, exiftool,
+// ImageMagick, декодер RAW) перед её запуском.
+func WithCommandLog(ctx context.Context, log func(cmdline string)) context.Context {
+	return context.WithValue(ctx, commandLogKey{}, log)
+}
+
+// logCommand передаёт командную строку cmd журналу из ctx, если он задан.
+func logCommand(ctx context.Context, cmd *exec.Cmd) {
+	if log, ok := ctx.Value(commandLogKey{}).(func(string)); ok {
+		log(formatCommandLine(cmd.Args))
+	}
+}
+
+// shellSafe - аргументы, которые shell передаёт как есть, без кавычек.
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// formatCommandLine собирает argv в строку, которую можно вставить в shell:
+// аргументы с пробелами и спецсимволами (в том числе [Q=80]) берутся
+// в одинарные кавычки.
+func formatCommandLine(argv []string) string {
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		if shellSafe.MatchString(arg) {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// CommandError - ошибка внешней программы вместе с её командной строкой.
+type CommandError struct {
+	// Cmdline - командная строка в формате formatCommandLine.
+	Cmdline string
+
+	// Err - ошибка запуска или код завершения.
+	Err error
+}
+
+// Error возвращает текст исходной ошибки (командная строка выводится отдельно).
+func (e *CommandError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap возвращает исходную ошибку.
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// withCommand дописывает к ошибке командную строку, если она известна.
+func withCommand(err error, cmdline string) error {
+	if cmdline == "" {
+		return err
+	}
+	return fmt.Errorf("%w; команда: %s", err, cmdline)
+}
@@ -0,0 +1,157 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// Converter - движок конвертации изображений. Пул воркеров работает только
+// через этот интерфейс: реализация по умолчанию - VipsConverter (vips CLI),
+// в тестах её заменяет заглушка, не требующая vips.
+type Converter interface {
+	// Convert конвертирует srcPath в dstPath. Запись атомарная: при ошибке
+	// dstPath не создаётся.
+	Convert(ctx context.Context, srcPath, dstPath string) *ConvertResult
+
+	// BuildDstPath строит путь к выходному файлу по абсолютному пути исходника.
+	BuildDstPath(srcPath string) string
+
+	// BuildDstPathRel строит путь к выходному файлу по относительному пути.
+	BuildDstPathRel(relPath string) string
+
+	// BuildDstPathDedup строит путь к выходному файлу по хэшу содержимого.
+	BuildDstPathDedup(contentSHA256 string) string
+
+	// WithConfig возвращает конвертер того же движка с другой конфигурацией.
+	WithConfig(cfg *config.Config) Converter
+
+	// ImageSize возвращает размеры изображения без декодирования пикселей.
+	ImageSize(ctx context.Context, path string) (width, height int, err error)
+
+	// CaptureTime возвращает дату съёмки из EXIF (DateTimeOriginal).
+	CaptureTime(ctx context.Context, path string) (time.Time, error)
+
+	// VerifyOutput проверяет, что path - читаемое изображение.
+	VerifyOutput(ctx context.Context, path string) error
+
+	// CheckHealth проверяет, что движок доступен и работает.
+	CheckHealth() error
+}
+
+var _ Converter = (*VipsConverter)(nil)
+
+// NewBackend создаёт конвертер движка cfg.Backend (пусто = vips).
+// vipsPath и magickPath - пути к найденным vips и ImageMagick (magickPath
+// нужен для --backend magick и --magick-fallback).
+func NewBackend(cfg *config.Config, vipsPath, magickPath string) (Converter, error) {
+	switch cfg.Backend {
+	case "", config.BackendVips:
+		conv := Converter(New(vipsPath, cfg))
+		if cfg.MagickFallback {
+			if magickPath == "" {
+				return nil, fmt.Errorf("--magick-fallback: ImageMagick не найден")
+			}
+			conv = WithFallback(conv, NewMagick(magickPath, cfg))
+		}
+		return conv, nil
+	case config.BackendMagick:
+		if magickPath == "" {
+			return nil, fmt.Errorf("--backend magick: ImageMagick не найден")
+		}
+		return NewMagick(magickPath, cfg), nil
+	default:
+		return nil, fmt.Errorf("неизвестный движок конвертации: %s", cfg.Backend)
+	}
+}
+
+// IsUnsupportedFormat сообщает, что конвертация не удалась, потому что
+// движок не умеет читать формат исходника (vips: "is not a known file format"
+// или RAW без декодера).
+func IsUnsupportedFormat(res *ConvertResult) bool {
+	if res.Success {
+		return false
+	}
+	if errors.Is(res.Error, ErrNoRawDecoder) {
+		return true
+	}
+	msg := res.Stderr
+	if res.Error != nil {
+		msg += res.Error.Error()
+	}
+	return strings.Contains(msg, "not a known file format")
+}
+
+// outOfMemoryMessages - признаки нехватки памяти в stderr vips и libvips
+// (glib, libheif, libjpeg, C++ загрузчики).
+var outOfMemoryMessages = []string{
+	"out of memory",
+	"memory allocation failed",
+	"cannot allocate memory",
+	"failed to allocate",
+	"std::bad_alloc",
+}
+
+// IsOutOfMemory сообщает, что конвертация не удалась из-за нехватки памяти
+// (по stderr и тексту ошибки).
+func IsOutOfMemory(res *ConvertResult) bool {
+	if res.Success {
+		return false
+	}
+	msg := res.Stderr
+	if res.Error != nil {
+		msg += res.Error.Error()
+	}
+	msg = strings.ToLower(msg)
+	for _, m := range outOfMemoryMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// fallbackConverter конвертирует основным движком, а файлы, формат которых
+// он не читает, - запасным (--magick-fallback). Пути, проверка результата и
+// состояние движка - от основного.
+type fallbackConverter struct {
+	Converter
+	fallback Converter
+}
+
+// WithFallback возвращает конвертер, передающий fallback файлы
+// неподдерживаемых primary форматов.
+func WithFallback(primary, fallback Converter) Converter {
+	return &fallbackConverter{Converter: primary, fallback: fallback}
+}
+
+// Convert конвертирует основным движком, при неподдерживаемом формате - запасным.
+func (f *fallbackConverter) Convert(ctx context.Context, srcPath, dstPath string) *ConvertResult {
+	res := f.Converter.Convert(ctx, srcPath, dstPath)
+	if !IsUnsupportedFormat(res) {
+		return res
+	}
+	fb := f.fallback.Convert(ctx, srcPath, dstPath)
+	fb.Duration += res.Duration
+	if !fb.Success {
+		fb.Error = fmt.Errorf("%v; запасной движок: %w", res.Error, fb.Error)
+	}
+	return fb
+}
+
+// WithConfig возвращает пару движков с другой конфигурацией.
+func (f *fallbackConverter) WithConfig(cfg *config.Config) Converter {
+	return &fallbackConverter{Converter: f.Converter.WithConfig(cfg), fallback: f.fallback.WithConfig(cfg)}
+}
+
+// ImageSize читает размеры основным движком, при ошибке - запасным.
+func (f *fallbackConverter) ImageSize(ctx context.Context, path string) (width, height int, err error) {
+	if width, height, err = f.Converter.ImageSize(ctx, path); err == nil {
+		return width, height, nil
+	}
+	return f.fallback.ImageSize(ctx, path)
+}
@@ -0,0 +1,108 @@
+package converter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ImageInfo - сведения об изображении из заголовка (vipsheader -a),
+// без декодирования пикселей.
+type ImageInfo struct {
+	// Path - путь к файлу.
+	Path string `json:"path"`
+
+	// Width, Height - размеры первой страницы в пикселях.
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// Format - формат файла по загрузчику vips (jpeg, png, heif, ...).
+	Format string `json:"format"`
+
+	// BandFormat - формат значений каналов (uchar, ushort, float, ...).
+	BandFormat string `json:"band_format"`
+
+	// Bands - число каналов.
+	Bands int `json:"bands"`
+
+	// Interpretation - цветовое пространство (srgb, b-w, cmyk, ...).
+	Interpretation string `json:"interpretation"`
+
+	// HasAlpha - есть ли альфа-канал.
+	HasAlpha bool `json:"has_alpha"`
+
+	// Pages - число страниц (кадров, изображений HEIF).
+	Pages int `json:"pages"`
+
+	// ProfileSize - размер встроенного ICC профиля в байтах (0 = нет профиля).
+	ProfileSize int `json:"profile_size"`
+}
+
+// ReadImageInfo читает заголовок изображения через vipsheader -a.
+func ReadImageInfo(ctx context.Context, vipsheaderPath, path string) (*ImageInfo, error) {
+	if vipsheaderPath == "" {
+		return nil, fmt.Errorf("vipsheader не найден")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, vipsheaderPath, "-a", path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("vipsheader: %s", strings.TrimSpace(err.Error()+": "+stderr.String()))
+	}
+	return parseImageInfo(path, stdout.String())
+}
+
+// parseImageInfo разбирает вывод vipsheader -a: строки "поле: значение".
+// Первая строка - сводка "path: WxH ...", она пропускается.
+func parseImageInfo(path, output string) (*ImageInfo, error) {
+	info := &ImageInfo{Path: path, Pages: 1}
+	fields := make(map[string]string)
+	sc := bufio.NewScanner(strings.NewReader(output))
+	for sc.Scan() {
+		if key, value, ok := strings.Cut(sc.Text(), ": "); ok {
+			fields[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	var err error
+	if info.Width, err = strconv.Atoi(fields["width"]); err != nil {
+		return nil, fmt.Errorf("не удалось разобрать вывод vipsheader: нет ширины")
+	}
+	if info.Height, err = strconv.Atoi(fields["height"]); err != nil {
+		return nil, fmt.Errorf("не удалось разобрать вывод vipsheader: нет высоты")
+	}
+	info.Bands, _ = strconv.Atoi(fields["bands"])
+	info.BandFormat = fields["format"]
+	info.Interpretation = fields["interpretation"]
+	// jpegload -> jpeg, heifload_source -> heif
+	loader, _, _ := strings.Cut(fields["vips-loader"], "_")
+	info.Format = strings.TrimSuffix(loader, "load")
+	if pages, err := strconv.Atoi(fields["n-pages"]); err == nil && pages > 0 {
+		info.Pages = pages
+	}
+	// "icc-profile-data: 3144 bytes of binary data"
+	if profile, ok := fields["icc-profile-data"]; ok {
+		size, _, _ := strings.Cut(profile, " ")
+		info.ProfileSize, _ = strconv.Atoi(size)
+	}
+	info.HasAlpha = hasAlpha(info.Bands, info.Interpretation)
+	return info, nil
+}
+
+// hasAlpha определяет наличие альфа-канала по числу каналов и цветовому
+// пространству, как vips_image_hasalpha: серый + альфа, RGB + альфа, CMYK + альфа.
+func hasAlpha(bands int, interpretation string) bool {
+	switch interpretation {
+	case "b-w", "grey16":
+		return bands == 2
+	case "cmyk":
+		return bands == 5
+	default:
+		return bands == 4
+	}
+}
@@ -0,0 +1,254 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// MagickConverter выполняет конвертацию через ImageMagick: magick
+// (ImageMagick 7) или convert (ImageMagick 6). Поддерживает качество,
+// resize, обрезку по центру, удаление метаданных, оттенки серого, сепию,
+// резкость, размытие и DPI; остальные операции отклоняет Config.Validate.
+type MagickConverter struct {
+	// magickPath - путь к magick или convert.
+	magickPath string
+
+	// identify - команда чтения размеров: [magick identify] или [identify].
+	identify []string
+
+	// cfg - конфигурация.
+	cfg *config.Config
+
+	// timeout - таймаут на конвертацию одного файла.
+	timeout time.Duration
+}
+
+var _ Converter = (*MagickConverter)(nil)
+
+// NewMagick создаёт MagickConverter. Для ImageMagick 6 (convert) размеры
+// читает identify рядом с convert или из PATH.
+// Таймаут на файл берётся из cfg.Timeout (по умолчанию 5 минут).
+func NewMagick(magickPath string, cfg *config.Config) *MagickConverter {
+	timeout := 5 * time.Minute
+	if cfg.Timeout > 0 {
+		timeout = cfg.Timeout
+	}
+
+	identify := []string{magickPath, "identify"}
+	if name := strings.TrimSuffix(filepath.Base(magickPath), ".exe"); name == "convert" {
+		candidate := filepath.Join(filepath.Dir(magickPath), strings.Replace(filepath.Base(magickPath), "convert", "identify", 1))
+		if _, err := os.Stat(candidate); err != nil {
+			candidate, _ = exec.LookPath("identify")
+		}
+		identify = []string{candidate}
+	}
+
+	return &MagickConverter{
+		magickPath: magickPath,
+		identify:   identify,
+		cfg:        cfg,
+		timeout:    timeout,
+	}
+}
+
+// WithConfig возвращает конвертер с тем же ImageMagick и таймаутом,
+// но другой конфигурацией.
+func (c *MagickConverter) WithConfig(cfg *config.Config) Converter {
+	return &MagickConverter{
+		magickPath: c.magickPath,
+		identify:   c.identify,
+		cfg:        cfg,
+		timeout:    c.timeout,
+	}
+}
+
+// magickArgs формирует аргументы ImageMagick:
+// input[0] [-resize ...] [операции] [-strip] [-quality Q] output.
+// [0] - только первый кадр многокадровых файлов (PSD, GIF, TIFF).
+func (c *MagickConverter) magickArgs(srcPath, outPath string) []string {
+	args := []string{srcPath + "[0]"}
+
+	switch {
+	case c.cfg.CropAspect != "":
+		// Заполняем прямоугольник целиком, лишнее обрезается по центру
+		width, height := c.cfg.CropSize()
+		size := fmt.Sprintf("%dx%d", width, height)
+		args = append(args, "-resize", size+"^", "-gravity", "center", "-extent", size)
+	case c.cfg.MaxWidth > 0 || c.cfg.MaxHeight > 0:
+		// Вписывание в рамку с сохранением пропорций, как vips thumbnail
+		var geometry string
+		if c.cfg.MaxWidth > 0 {
+			geometry = strconv.Itoa(c.cfg.MaxWidth)
+		}
+		if c.cfg.MaxHeight > 0 {
+			geometry += "x" + strconv.Itoa(c.cfg.MaxHeight)
+		}
+		args = append(args, "-resize", geometry)
+	}
+
+	resizes := c.cfg.CropAspect != "" || c.cfg.MaxWidth > 0 || c.cfg.MaxHeight > 0
+	if c.cfg.Sharpen > 0 && (resizes || c.cfg.SharpenAlways) {
+		args = append(args, "-sharpen", "0x"+strconv.FormatFloat(c.cfg.Sharpen, 'f', -1, 64))
+	}
+	if c.cfg.Blur > 0 {
+		args = append(args, "-blur", "0x"+strconv.FormatFloat(c.cfg.Blur, 'f', -1, 64))
+	}
+	switch {
+	case c.cfg.Sepia:
+		args = append(args, "-sepia-tone", "80%")
+	case c.cfg.Grayscale:
+		args = append(args, "-colorspace", "Gray")
+	}
+	if c.cfg.DPI > 0 {
+		args = append(args, "-units", "PixelsPerInch", "-density", strconv.Itoa(c.cfg.DPI))
+	}
+	if c.cfg.StripMetadata {
+		args = append(args, "-strip")
+	}
+	if c.cfg.HasQuality() {
+		args = append(args, "-quality", strconv.Itoa(c.cfg.Quality))
+	}
+
+	return append(args, outPath)
+}
+
+// Convert конвертирует файл из srcPath в dstPath через ImageMagick.
+// Запись атомарная: результат пишется во временный файл и переименовывается.
+func (c *MagickConverter) Convert(ctx context.Context, srcPath, dstPath string) *ConvertResult {
+	start := time.Now()
+
+	dstDir := filepath.Dir(dstPath)
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return &ConvertResult{
+			Success:  false,
+			Error:    fmt.Errorf("не удалось создать директорию %s: %w", dstDir, err),
+			Duration: time.Since(start),
+		}
+	}
+
+	// ImageMagick, как и vips, определяет формат по расширению
+	tmpPath := TempPath(dstPath)
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.magickPath, c.magickArgs(srcPath, tmpPath)...)
+	cmd.Stderr = &stderr
+	logCommand(ctx, cmd)
+	if err := cmd.Run(); err != nil {
+		_ = os.Remove(tmpPath)
+		command := formatCommandLine(cmd.Args)
+		if ctx.Err() == context.DeadlineExceeded {
+			return &ConvertResult{
+				Success:  false,
+				Error:    withCommand(fmt.Errorf("magick не уложился в таймаут %s (timed out)", c.timeout), command),
+				Stderr:   stderr.String(),
+				Command:  command,
+				Duration: time.Since(start),
+			}
+		}
+		return &ConvertResult{
+			Success:  false,
+			Error:    withCommand(fmt.Errorf("magick failed: %s", strings.TrimSpace(err.Error()+": "+stderr.String())), command),
+			Stderr:   stderr.String(),
+			Command:  command,
+			Duration: time.Since(start),
+		}
+	}
+
+	if !c.cfg.NoVerify {
+		if err := c.VerifyOutput(ctx, tmpPath); err != nil {
+			_ = os.Remove(tmpPath)
+			return &ConvertResult{Success: false, Error: err, Stderr: stderr.String(), Duration: time.Since(start)}
+		}
+	}
+
+	if err := os.Rename(tmpPath, dstPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return &ConvertResult{
+			Success:  false,
+			Error:    fmt.Errorf("не удалось переименовать %s -> %s: %w", tmpPath, dstPath, err),
+			Duration: time.Since(start),
+		}
+	}
+
+	return &ConvertResult{
+		Success:  true,
+		DstPath:  dstPath,
+		Stderr:   stderr.String(),
+		Duration: time.Since(start),
+	}
+}
+
+// ImageSize возвращает размеры первого кадра изображения (identify).
+func (c *MagickConverter) ImageSize(ctx context.Context, path string) (width, height int, err error) {
+	if c.identify[0] == "" {
+		return 0, 0, fmt.Errorf("identify не найден")
+	}
+	args := append(c.identify[1:len(c.identify):len(c.identify)], "-format", "%w %h", path+"[0]")
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.identify[0], args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	logCommand(ctx, cmd)
+	if err := cmd.Run(); err != nil {
+		return 0, 0, fmt.Errorf("identify: %s", strings.TrimSpace(err.Error()+": "+stderr.String()))
+	}
+	if _, err := fmt.Sscanf(stdout.String(), "%d %d", &width, &height); err != nil {
+		return 0, 0, fmt.Errorf("не удалось разобрать вывод identify: %s", strings.TrimSpace(stdout.String()))
+	}
+	return width, height, nil
+}
+
+// VerifyOutput проверяет, что результат - непустое изображение
+// с ненулевыми размерами.
+func (c *MagickConverter) VerifyOutput(ctx context.Context, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("проверка результата: %w", err)
+	}
+	if info.Size() == 0 {
+		return fmt.Errorf("проверка результата: magick создал пустой файл")
+	}
+	width, height, err := c.ImageSize(ctx, path)
+	if err != nil {
+		return fmt.Errorf("проверка результата: %w", err)
+	}
+	if width == 0 || height == 0 {
+		return fmt.Errorf("проверка результата: нулевые размеры изображения %dx%d", width, height)
+	}
+	return nil
+}
+
+// BuildDstPath строит путь к выходному файлу.
+func (c *MagickConverter) BuildDstPath(srcPath string) string {
+	return dstPaths{c.cfg}.BuildDstPath(srcPath)
+}
+
+// BuildDstPathRel строит путь к выходному файлу по пути относительно входа.
+func (c *MagickConverter) BuildDstPathRel(relPath string) string {
+	return dstPaths{c.cfg}.BuildDstPathRel(relPath)
+}
+
+// BuildDstPathDedup строит путь для режима dedup (по хэшу содержимого).
+func (c *MagickConverter) BuildDstPathDedup(contentSHA256 string) string {
+	return dstPaths{c.cfg}.BuildDstPathDedup(contentSHA256)
+}
+
+// CheckHealth проверяет работоспособность ImageMagick.
+func (c *MagickConverter) CheckHealth() error {
+	if err := exec.Command(c.magickPath, "-version").Run(); err != nil {
+		return fmt.Errorf("ImageMagick не работает: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package converter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+func TestMagickArgs(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(cfg *config.Config)
+		want  []string
+	}{
+		{
+			name:  "качество по умолчанию",
+			setup: func(cfg *config.Config) {},
+			want:  []string{"in.psd[0]", "-quality", "85", "out.webp"},
+		},
+		{
+			name: "resize и strip",
+			setup: func(cfg *config.Config) {
+				cfg.MaxWidth, cfg.MaxHeight = 1920, 1080
+				cfg.StripMetadata = true
+			},
+			want: []string{"in.psd[0]", "-resize", "1920x1080", "-strip", "-quality", "85", "out.webp"},
+		},
+		{
+			name:  "только высота",
+			setup: func(cfg *config.Config) { cfg.MaxHeight = 600 },
+			want:  []string{"in.psd[0]", "-resize", "x600", "-quality", "85", "out.webp"},
+		},
+		{
+			name: "обрезка 1:1",
+			setup: func(cfg *config.Config) {
+				cfg.MaxWidth = 400
+				cfg.CropAspect = "1:1"
+			},
+			want: []string{"in.psd[0]", "-resize", "400x400^", "-gravity", "center", "-extent", "400x400", "-quality", "85", "out.webp"},
+		},
+		{
+			name: "png: оттенки серого и DPI без качества",
+			setup: func(cfg *config.Config) {
+				cfg.OutputFormat = config.FormatPNG
+				cfg.Grayscale = true
+				cfg.DPI = 300
+			},
+			want: []string{"in.psd[0]", "-colorspace", "Gray", "-units", "PixelsPerInch", "-density", "300", "out.webp"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Quality = 85
+			tt.setup(cfg)
+			got := NewMagick("magick", cfg).magickArgs("in.psd", "out.webp")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("magickArgs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeMagickScript имитирует magick: пишет аргументы в $MAGICK_LOG,
+// identify печатает размеры, конвертация копирует вход (без [0]) в последний аргумент.
+const fakeMagickScript = `#!/bin/sh
+echo "$*" >> "$MAGICK_LOG"
+case "$1" in
+-version) echo "Version: ImageMagick 7.1.1-15 Q16"; exit 0 ;;
+identify) echo "64 48"; exit 0 ;;
+esac
+for out; do :; done
+cp "${1%\[0\]}" "$out"
+`
+
+// unsupportedVipsScript имитирует vips без поддержки PSD; для "broken" -
+// ошибка другого рода.
+const unsupportedVipsScript = `#!/bin/sh
+case "$2" in
+*.psd) echo "VipsForeignLoad: \"$2\" is not a known file format" >&2; exit 1 ;;
+*broken*) echo "VipsJpeg: Premature end of JPEG file" >&2; exit 1 ;;
+esac
+cp "$2" "${3%%\[*}"
+`
+
+// writeFakeMagick создаёт заглушку magick и возвращает путь к ней и журнал вызовов.
+func writeFakeMagick(t *testing.T) (magick, logPath string) {
+	t.Helper()
+	dir := t.TempDir()
+	magick = filepath.Join(dir, "magick")
+	if err := os.WriteFile(magick, []byte(fakeMagickScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	logPath = filepath.Join(dir, "magick.log")
+	t.Setenv("MAGICK_LOG", logPath)
+	return magick, logPath
+}
+
+func TestMagickConvert(t *testing.T) {
+	magick, logPath := writeFakeMagick(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "layers.psd")
+	if err := os.WriteFile(src, []byte("psd"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.OutputFormat = config.FormatJPEG
+	cfg.Quality = 90
+	cfg.StripMetadata = true
+
+	dst := filepath.Join(dir, "out", "layers.jpg")
+	res := NewMagick(magick, cfg).Convert(context.Background(), src, dst)
+	if !res.Success {
+		t.Fatalf("Convert: %v", res.Error)
+	}
+	if data, err := os.ReadFile(dst); err != nil || string(data) != "psd" {
+		t.Errorf("результат = %q, %v", data, err)
+	}
+
+	data, _ := os.ReadFile(logPath)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	wantConvert := src + "[0] -strip -quality 90 " + TempPath(dst)
+	if len(lines) != 2 || lines[0] != wantConvert || !strings.HasPrefix(lines[1], "identify -format") {
+		t.Errorf("вызовы magick = %q, want конвертация %q и проверка identify", lines, wantConvert)
+	}
+}
+
+func TestConvert_MagickFallback(t *testing.T) {
+	magick, logPath := writeFakeMagick(t)
+	dir := t.TempDir()
+	vips := filepath.Join(dir, "vips")
+	if err := os.WriteFile(vips, []byte(unsupportedVipsScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.NoVerify = true
+	cfg.MagickFallback = true
+	conv, err := NewBackend(cfg, vips, magick)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		file        string
+		wantSuccess bool
+		wantMagick  bool
+	}{
+		{name: "vips читает формат", file: "photo.jpg", wantSuccess: true},
+		{name: "PSD через ImageMagick", file: "layers.psd", wantSuccess: true, wantMagick: true},
+		{name: "другая ошибка vips без fallback", file: "broken.jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = os.Remove(logPath)
+			src := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(src, []byte("image"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			res := conv.Convert(context.Background(), src, filepath.Join(t.TempDir(), "out.webp"))
+			if res.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (%v)", res.Success, tt.wantSuccess, res.Error)
+			}
+			if _, err := os.Stat(logPath); (err == nil) != tt.wantMagick {
+				t.Errorf("ImageMagick вызван: %v, want %v", err == nil, tt.wantMagick)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// Package converter содержит пост-обработку метаданных через exiftool.
+package converter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// ExiftoolEnv - переменная окружения с путём к exiftool.
+const ExiftoolEnv = "PHOTOCONVERTER_EXIFTOOL"
+
+// ErrNoExiftool - для --copy-metadata или --strip-gps нужен exiftool, а он не найден.
+// Без него результат сохранил бы GPS теги, а задача записалась бы успешной.
+var ErrNoExiftool = errors.New("exiftool не найден: --copy-metadata и --strip-gps требуют exiftool (установите его или укажите " + ExiftoolEnv + ")")
+
+// FindExiftool ищет exiftool: сначала в переменной окружения, затем в PATH.
+// Возвращает пустую строку, если exiftool не найден.
+func FindExiftool() string {
+	if path := os.Getenv(ExiftoolEnv); path != "" {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	if path, err := exec.LookPath("exiftool"); err == nil {
+		return path
+	}
+	return ""
+}
+
+// SetExiftoolPath устанавливает путь к exiftool (пусто = exiftool недоступен).
+func (c *VipsConverter) SetExiftoolPath(path string) {
+	c.exiftoolPath = path
+}
+
+// needsStripGPS проверяет, нужно ли отдельно удалять GPS теги.
+// При полном удалении метаданных GPS удаляется самим vips.
+func (c *VipsConverter) needsStripGPS() bool {
+	return c.cfg.StripGPS && !c.cfg.StripMetadata
+}
+
+// needsCopyMetadata проверяет, нужно ли явно копировать метаданные из источника.
+func (c *VipsConverter) needsCopyMetadata() bool {
+	return c.cfg.CopyMetadata && !c.cfg.StripMetadata
+}
+
+// postProcessMetadata выполняет пост-обработку метаданных выходного файла:
+// копирование EXIF/XMP/ICC из источника и удаление GPS тегов.
+// Если exiftool недоступен, возвращает ErrNoExiftool.
+func (c *VipsConverter) postProcessMetadata(ctx context.Context, srcPath, imagePath string) error {
+	if !c.needsCopyMetadata() && !c.needsStripGPS() {
+		return nil
+	}
+
+	if c.exiftoolPath == "" {
+		return ErrNoExiftool
+	}
+
+	// Копируем метаданные из источника: vips сохраняет их не для всех форматов.
+	// ICC профиль считается "небезопасным" тегом и указывается отдельно.
+	if c.needsCopyMetadata() {
+		if err := c.runExiftool(ctx, "-overwrite_original", "-tagsFromFile", srcPath,
+			"-all:all", "-icc_profile", imagePath); err != nil {
+			return err
+		}
+	}
+
+	// Удаляем только GPS теги (EXIF GPS IFD и XMP GPS), остальное сохраняем
+	if c.needsStripGPS() {
+		return c.runExiftool(ctx, "-overwrite_original", "-gps:all=", "-xmp-exif:gps*=", imagePath)
+	}
+	return nil
+}
+
+// checkExiftool проверяет, что exiftool найден, если он нужен конфигурации.
+func (c *VipsConverter) checkExiftool() error {
+	if (c.needsCopyMetadata() || c.needsStripGPS()) && c.exiftoolPath == "" {
+		return ErrNoExiftool
+	}
+	return nil
+}
+
+// runExiftool запускает exiftool с указанными аргументами.
+func (c *VipsConverter) runExiftool(ctx context.Context, args ...string) error {
+	cmd := exec.CommandContext(ctx, c.exiftoolPath, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	logCommand(ctx, cmd)
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return fmt.Errorf("exiftool failed: %w: %s", err, stderr.String())
+		}
+		return fmt.Errorf("exiftool failed: %w", err)
+	}
+	return nil
+}
+
+/*
+Возможные расширения:
+- Добавить удаление отдельных групп тегов (MakerNotes, IPTC)
+- Добавить запись собственных тегов (автор, копирайт)
+*/
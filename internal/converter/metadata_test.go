@@ -0,0 +1,178 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// fakeVipsScript имитирует vips: копирует вход в выходной путь (без параметров в [...]).
+const fakeVipsScript = `#!/bin/sh
+case "$1" in
+--version) echo "vips-8.15.0"; exit 0 ;;
+esac
+out="${3%%\[*}"
+cp "$2" "$out"
+`
+
+// writeFakeVips создаёт исполняемый скрипт-заглушку vips.
+func writeFakeVips(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "vips")
+	if err := os.WriteFile(path, []byte(fakeVipsScript), 0755); err != nil {
+		t.Fatalf("не удалось создать fake vips: %v", err)
+	}
+	return path
+}
+
+// writeTestJPEG создаёт небольшой JPEG файл.
+func writeTestJPEG(t *testing.T, path string) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for x := 0; x < 16; x++ {
+		for y := 0; y < 16; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 16), G: uint8(y * 16), B: 128, A: 255})
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := jpeg.Encode(f, img, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// requireExiftool возвращает путь к exiftool или пропускает тест.
+func requireExiftool(t *testing.T) string {
+	t.Helper()
+	path := FindExiftool()
+	if path == "" {
+		t.Skip("exiftool не установлен")
+	}
+	return path
+}
+
+// readTag читает значение тега через exiftool.
+func readTag(t *testing.T, exiftool, path, tag string) string {
+	t.Helper()
+	out, err := exec.Command(exiftool, "-s3", "-"+tag, path).Output()
+	if err != nil {
+		t.Fatalf("exiftool -%s %s: %v", tag, path, err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func newTestConverter(t *testing.T, cfg *config.Config) *VipsConverter {
+	t.Helper()
+	cfg.OutputDir = t.TempDir()
+	return New(writeFakeVips(t), cfg)
+}
+
+func TestStripGPS_RemovesGPSKeepsModel(t *testing.T) {
+	exiftool := requireExiftool(t)
+
+	src := filepath.Join(t.TempDir(), "gps.jpg")
+	writeTestJPEG(t, src)
+	tag := exec.Command(exiftool, "-overwrite_original",
+		"-Model=TestCam X100", "-GPSLatitude=55.7558", "-GPSLatitudeRef=N",
+		"-GPSLongitude=37.6173", "-GPSLongitudeRef=E", src)
+	if out, err := tag.CombinedOutput(); err != nil {
+		t.Fatalf("не удалось записать теги: %v: %s", err, out)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.StripGPS = true
+	conv := newTestConverter(t, cfg)
+
+	dst := filepath.Join(cfg.OutputDir, "gps.jpg")
+	res := conv.Convert(context.Background(), src, dst)
+	if !res.Success {
+		t.Fatalf("Convert: %v", res.Error)
+	}
+
+	if got := readTag(t, exiftool, dst, "GPSLatitude"); got != "" {
+		t.Errorf("GPSLatitude = %q, ожидалось удаление", got)
+	}
+	if got := readTag(t, exiftool, dst, "GPSLongitude"); got != "" {
+		t.Errorf("GPSLongitude = %q, ожидалось удаление", got)
+	}
+	if got := readTag(t, exiftool, dst, "Model"); got != "TestCam X100" {
+		t.Errorf("Model = %q, want %q", got, "TestCam X100")
+	}
+}
+
+func TestStripGPS_WithoutExiftool(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "photo.jpg")
+	writeTestJPEG(t, src)
+
+	cfg := config.DefaultConfig()
+	cfg.StripGPS = true
+	conv := newTestConverter(t, cfg)
+	conv.SetExiftoolPath("")
+
+	if err := conv.CheckHealth(); !errors.Is(err, ErrNoExiftool) {
+		t.Errorf("CheckHealth() = %v, want ErrNoExiftool", err)
+	}
+
+	// Результат с GPS тегами не должен записываться успешным
+	dst := filepath.Join(cfg.OutputDir, "photo.jpg")
+	res := conv.Convert(context.Background(), src, dst)
+	if res.Success || !errors.Is(res.Error, ErrNoExiftool) {
+		t.Fatalf("без exiftool: success=%v err=%v, want ErrNoExiftool", res.Success, res.Error)
+	}
+	if _, err := os.Stat(dst); err == nil {
+		t.Error("выходной файл не должен создаваться")
+	}
+
+	// Без --strip-gps и --copy-metadata exiftool не нужен
+	cfg.StripGPS = false
+	if err := conv.CheckHealth(); err != nil {
+		t.Errorf("CheckHealth() без пост-обработки метаданных: %v", err)
+	}
+}
+
+func TestCopyMetadata_JPEGToWebP(t *testing.T) {
+	exiftool := requireExiftool(t)
+	vipsPath, err := exec.LookPath("vips")
+	if err != nil {
+		t.Skip("vips не установлен")
+	}
+
+	src := filepath.Join(t.TempDir(), "exif.jpg")
+	writeTestJPEG(t, src)
+	tag := exec.Command(exiftool, "-overwrite_original",
+		"-Make=TestMaker", "-Model=TestCam X100", "-DateTimeOriginal=2024:05:01 12:00:00", src)
+	if out, err := tag.CombinedOutput(); err != nil {
+		t.Fatalf("не удалось записать теги: %v: %s", err, out)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.OutputFormat = config.FormatWebP
+	cfg.CopyMetadata = true
+	cfg.OutputDir = t.TempDir()
+	conv := New(vipsPath, cfg)
+
+	dst := filepath.Join(cfg.OutputDir, "exif.webp")
+	res := conv.Convert(context.Background(), src, dst)
+	if !res.Success {
+		t.Fatalf("Convert: %v", res.Error)
+	}
+
+	if got := readTag(t, exiftool, dst, "Model"); got != "TestCam X100" {
+		t.Errorf("Model = %q, want %q", got, "TestCam X100")
+	}
+	if got := readTag(t, exiftool, dst, "DateTimeOriginal"); got != "2024:05:01 12:00:00" {
+		t.Errorf("DateTimeOriginal = %q", got)
+	}
+}
@@ -0,0 +1,119 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isHEIF проверяет, является ли файл HEIC/HEIF (может содержать несколько
+// изображений: серии, Live Photos).
+func isHEIF(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".heic", ".heif":
+		return true
+	}
+	return false
+}
+
+// isAnimatable проверяет, может ли файл быть анимированным (GIF, WebP).
+func isAnimatable(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gif", ".webp":
+		return true
+	}
+	return false
+}
+
+// animationLoad возвращает параметры загрузки для анимированного входа:
+// "[n=-1]" (все кадры), если анимация сохраняется (Config.KeepsAnimation)
+// и во входе больше одного кадра (vipsheader -f n-pages). Без vipsheader
+// загружаются все кадры - для неанимированного файла это тот же один кадр.
+// Пусто - только первый кадр, как vips загружает по умолчанию.
+func (c *VipsConverter) animationLoad(ctx context.Context, srcPath string) string {
+	if !c.cfg.KeepsAnimation() || !isAnimatable(srcPath) {
+		return ""
+	}
+	if c.vipsheaderPath != "" {
+		if pages, err := c.headerInt(ctx, srcPath, "n-pages"); err == nil && pages <= 1 {
+			return ""
+		}
+	}
+	return "[n=-1]"
+}
+
+// PagePath возвращает путь результата для изображения номер page
+// многокадрового файла: out/IMG_0001.jpg -> out/IMG_0001_2.jpg.
+func PagePath(dstPath string, page int) string {
+	ext := filepath.Ext(dstPath)
+	return strings.TrimSuffix(dstPath, ext) + "_" + strconv.Itoa(page) + ext
+}
+
+// headerInt читает целочисленное поле заголовка изображения
+// (vipsheader -f field, например n-pages).
+func (c *VipsConverter) headerInt(ctx context.Context, path, field string) (int, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.vipsheaderPath, "-f", field, path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	logCommand(ctx, cmd)
+	if err := cmd.Run(); err != nil {
+		return 0, fmt.Errorf("vipsheader -f %s: %s", field, strings.TrimSpace(err.Error()+": "+stderr.String()))
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(stdout.String()))
+	if err != nil {
+		return 0, fmt.Errorf("vipsheader -f %s: не число: %q", field, strings.TrimSpace(stdout.String()))
+	}
+	return v, nil
+}
+
+// convertImages выгружает остальные изображения многокадрового HEIC/HEIF
+// (AllImages): основное уже записано в dstPath, каждое другое изображение
+// номер N (с нуля, как page в vips) пишется в PagePath(dstPath, N).
+// Ошибка любого изображения - ошибка всего файла, записанные результаты удаляются.
+func (c *VipsConverter) convertImages(ctx context.Context, srcPath, dstPath string, res *ConvertResult) *ConvertResult {
+	start := time.Now()
+
+	if c.vipsheaderPath == "" {
+		c.pagesWarning.Do(func() {
+			fmt.Fprintln(os.Stderr, "⚠️  vipsheader не найден: --all-images выгружает только основное изображение")
+		})
+		return res
+	}
+
+	pages, err := c.headerInt(ctx, srcPath, "n-pages")
+	if err != nil || pages <= 1 {
+		// Одно изображение (или n-pages не задан) - основного достаточно
+		return res
+	}
+	// heif-primary - номер основного изображения, обычно 0
+	primary, err := c.headerInt(ctx, srcPath, "heif-primary")
+	if err != nil {
+		primary = 0
+	}
+
+	for page := 0; page < pages; page++ {
+		if page == primary {
+			continue
+		}
+		pagePath := PagePath(dstPath, page)
+		pageRes := c.convertImage(ctx, srcPath, fmt.Sprintf("[page=%d]", page), pagePath)
+		if !pageRes.Success {
+			for _, path := range append(res.Extra, dstPath) {
+				_ = os.Remove(path)
+			}
+			pageRes.Error = fmt.Errorf("изображение %d из %d: %w", page+1, pages, pageRes.Error)
+			pageRes.Duration = res.Duration + time.Since(start)
+			return pageRes
+		}
+		res.Extra = append(res.Extra, pagePath)
+	}
+	res.Duration += time.Since(start)
+	return res
+}
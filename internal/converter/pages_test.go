@@ -0,0 +1,201 @@
+package converter
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// pagesVipsScript имитирует vips: пишет аргумент входа в $VIPS_LOG и копирует
+// вход в выход (без параметров в [...]).
+const pagesVipsScript = `#!/bin/sh
+echo "$2" >> "$VIPS_LOG"
+cp "${2%%\[*}" "${3%%\[*}"
+`
+
+// pagesHeaderScript имитирует vipsheader для HEIF из трёх изображений,
+// основное - второе.
+const pagesHeaderScript = `#!/bin/sh
+case "$2" in
+n-pages) echo 3 ;;
+heif-primary) echo 1 ;;
+esac
+`
+
+func TestConvert_AllImagesHEIF(t *testing.T) {
+	dir := t.TempDir()
+	vips := filepath.Join(dir, "vips")
+	if err := os.WriteFile(vips, []byte(pagesVipsScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "vipsheader"), []byte(pagesHeaderScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "burst.heic")
+	if err := os.WriteFile(src, []byte("heif"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		allImages bool
+		wantFiles []string
+		wantLoads []string
+	}{
+		{
+			name:      "по умолчанию только основное",
+			wantFiles: []string{"burst.jpg"},
+			wantLoads: []string{src},
+		},
+		{
+			name:      "--all-images",
+			allImages: true,
+			wantFiles: []string{"burst.jpg", "burst_0.jpg", "burst_2.jpg"},
+			wantLoads: []string{src, src + "[page=0]", src + "[page=2]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logPath := filepath.Join(t.TempDir(), "vips.log")
+			t.Setenv("VIPS_LOG", logPath)
+
+			cfg := config.DefaultConfig()
+			cfg.OutputFormat = config.FormatJPEG
+			cfg.NoVerify = true
+			cfg.AllImages = tt.allImages
+
+			outDir := t.TempDir()
+			dst := filepath.Join(outDir, "burst.jpg")
+			res := New(vips, cfg).Convert(context.Background(), src, dst)
+			if !res.Success {
+				t.Fatalf("Convert: %v", res.Error)
+			}
+			if len(res.Extra) != len(tt.wantFiles)-1 {
+				t.Errorf("Extra = %v, want %d файлов", res.Extra, len(tt.wantFiles)-1)
+			}
+
+			entries, _ := os.ReadDir(outDir)
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Name())
+			}
+			if !reflect.DeepEqual(got, tt.wantFiles) {
+				t.Errorf("результаты = %v, want %v", got, tt.wantFiles)
+			}
+			data, _ := os.ReadFile(logPath)
+			if loads := strings.Fields(string(data)); !reflect.DeepEqual(loads, tt.wantLoads) {
+				t.Errorf("входы vips = %v, want %v", loads, tt.wantLoads)
+			}
+		})
+	}
+}
+
+// animatedHeaderScript имитирует vipsheader для анимации из 4 кадров.
+const animatedHeaderScript = `#!/bin/sh
+echo 4
+`
+
+func TestConvert_PreserveAnimationLoad(t *testing.T) {
+	dir := t.TempDir()
+	vips := filepath.Join(dir, "vips")
+	if err := os.WriteFile(vips, []byte(pagesVipsScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "vipsheader"), []byte(animatedHeaderScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "anim.gif")
+	if err := os.WriteFile(src, []byte("gif"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		preserve bool
+		format   config.OutputFormat
+		wantLoad string
+	}{
+		{name: "без флага - первый кадр", format: config.FormatWebP, wantLoad: src},
+		{name: "webp - все кадры", preserve: true, format: config.FormatWebP, wantLoad: src + "[n=-1]"},
+		{name: "jpg - первый кадр", preserve: true, format: config.FormatJPEG, wantLoad: src},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logPath := filepath.Join(t.TempDir(), "vips.log")
+			t.Setenv("VIPS_LOG", logPath)
+
+			cfg := config.DefaultConfig()
+			cfg.OutputFormat = tt.format
+			cfg.NoVerify = true
+			cfg.PreserveAnimation = tt.preserve
+
+			dst := filepath.Join(t.TempDir(), "anim."+string(tt.format))
+			if res := New(vips, cfg).Convert(context.Background(), src, dst); !res.Success {
+				t.Fatalf("Convert: %v", res.Error)
+			}
+			data, _ := os.ReadFile(logPath)
+			if got := strings.TrimSpace(string(data)); got != tt.wantLoad {
+				t.Errorf("вход vips = %q, want %q", got, tt.wantLoad)
+			}
+		})
+	}
+}
+
+func TestConvert_AnimatedGIFToWebPRealVips(t *testing.T) {
+	vipsPath, err := exec.LookPath("vips")
+	if err != nil {
+		t.Skip("vips не установлен")
+	}
+	headerPath := FindVipsheader(vipsPath)
+	if headerPath == "" {
+		t.Skip("vipsheader не установлен")
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "anim.gif")
+	palette := color.Palette{color.Black, color.White}
+	anim := &gif.GIF{}
+	for i := 0; i < 3; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 32, 32), palette)
+		for x := 0; x < 32; x++ {
+			frame.SetColorIndex(x, i*10, 1)
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gif.EncodeAll(f, anim); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.OutputFormat = config.FormatWebP
+	cfg.PreserveAnimation = true
+
+	dst := filepath.Join(dir, "anim.webp")
+	if res := New(vipsPath, cfg).Convert(context.Background(), src, dst); !res.Success {
+		t.Fatalf("Convert: %v", res.Error)
+	}
+	out, err := exec.Command(headerPath, "-f", "n-pages", dst).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "3" {
+		t.Errorf("кадров в результате = %s, want 3", got)
+	}
+}
@@ -0,0 +1,80 @@
+package converter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// dstPaths строит пути выходных файлов по конфигурации; общий для всех
+// движков конвертации.
+type dstPaths struct {
+	cfg *config.Config
+}
+
+// BuildDstPath строит путь к выходному файлу.
+func (p dstPaths) BuildDstPath(srcPath string) string {
+	// Получаем относительный путь от входной директории
+	relPath, err := filepath.Rel(p.cfg.InputDir, srcPath)
+	if err != nil || relPath == "." {
+		// Fallback на имя файла (в том числе когда --in - сам файл)
+		relPath = filepath.Base(srcPath)
+	}
+	return p.BuildDstPathRel(relPath)
+}
+
+// BuildDstPathRel строит путь к выходному файлу по пути относительно входа
+// (директории, архива или базы списка файлов).
+func (p dstPaths) BuildDstPathRel(relPath string) string {
+	if p.cfg.KeepTree {
+		// Сохраняем структуру директорий
+		// Меняем расширение на выходной формат
+		ext := filepath.Ext(relPath)
+		relPath = strings.TrimSuffix(relPath, ext) + "." + p.cfg.OutputExt()
+		return filepath.Join(p.cfg.OutputDir, relPath)
+	}
+
+	// Плоская структура: имя файла по --flat-naming
+	return filepath.Join(p.cfg.OutputDir, p.flatName(relPath)+"."+p.cfg.OutputExt())
+}
+
+// flatName возвращает имя файла без расширения для плоской структуры.
+// hashed добавляет хэш директории только файлам из поддиректорий, поэтому
+// имена не зависят от порядка обработки и совпадают между запусками.
+func (p dstPaths) flatName(relPath string) string {
+	baseName := filepath.Base(relPath)
+	baseName = strings.TrimSuffix(baseName, filepath.Ext(baseName))
+	dir := filepath.ToSlash(filepath.Dir(relPath))
+	if dir == "." {
+		return baseName
+	}
+
+	switch p.cfg.FlatNaming {
+	case config.FlatNamingHashed:
+		sum := sha256.Sum256([]byte(dir))
+		return baseName + "_" + hex.EncodeToString(sum[:4])
+	case config.FlatNamingPathJoined:
+		return strings.ReplaceAll(dir, "/", "_") + "_" + baseName
+	default:
+		return baseName
+	}
+}
+
+// BuildDstPathDedup строит путь для режима dedup (по хэшу содержимого).
+func (p dstPaths) BuildDstPathDedup(contentSHA256 string) string {
+	// Используем первые 16 символов хэша как имя файла
+	// (без префикса алгоритма вроде "xxhash:", см. --dedup-hash)
+	shortHash := contentSHA256
+	if i := strings.LastIndexByte(shortHash, ':'); i >= 0 {
+		shortHash = shortHash[i+1:]
+	}
+	if len(shortHash) > 16 {
+		shortHash = shortHash[:16]
+	}
+
+	fileName := shortHash + "." + p.cfg.OutputExt()
+	return filepath.Join(p.cfg.OutputDir, fileName)
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"html"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -23,6 +24,18 @@ type PDFExporter struct {
 	cfg *config.Config
 }
 
+// PDFImage - изображение для PDF с данными для сортировки страниц.
+type PDFImage struct {
+	// Path - путь к изображению.
+	Path string
+
+	// Size - размер исходного файла в байтах (если известен, иначе - самого изображения).
+	Size int64
+
+	// Mtime - время модификации исходного файла (unix timestamp).
+	Mtime int64
+}
+
 // NewPDFExporter создаёт новый PDFExporter.
 func NewPDFExporter(vipsPath string, cfg *config.Config) *PDFExporter {
 	return &PDFExporter{
@@ -46,18 +59,106 @@ func PDFPageDimensions(pageSize string) (width, height int) {
 	}
 }
 
+// PDFMargin - поля страницы и промежуток между ячейками сетки в пикселях
+// (10 мм при 300 DPI).
+const PDFMargin = 118
+
+// PDFCaptionHeight - высота полосы подписи под изображением в пикселях (~7.5 мм при 300 DPI).
+const PDFCaptionHeight = 90
+
+// PDFCaptionCharWidth - средняя ширина символа подписи в пикселях (шрифт 10pt при 300 DPI),
+// используется для сокращения длинных имён файлов.
+const PDFCaptionCharWidth = 25
+
+// PDFPageNumberWidth - ширина области номера страницы в пикселях.
+const PDFPageNumberWidth = 400
+
+// PDFGrid возвращает количество колонок и строк сетки для макета PDF
+// (single, 2up, 4up, 9up). Неизвестный макет считается single.
+func PDFGrid(layout string) (cols, rows int) {
+	switch strings.ToLower(layout) {
+	case "2up":
+		return 1, 2
+	case "4up":
+		return 2, 2
+	case "9up":
+		return 3, 3
+	default:
+		return 1, 1
+	}
+}
+
+// PDFCellDimensions возвращает размер ячейки сетки в пикселях:
+// доступное место страницы за вычетом полей margin делится между ячейками
+// с промежутком margin между ними.
+func PDFCellDimensions(pageSize, layout string, margin int) (width, height int) {
+	pageWidth, pageHeight := PDFPageDimensions(pageSize)
+	cols, rows := PDFGrid(layout)
+
+	width = (pageWidth - 2*margin - (cols-1)*margin) / cols
+	height = (pageHeight - 2*margin - (rows-1)*margin) / rows
+	return width, height
+}
+
+// PDFMarginPixels переводит поля страницы из миллиметров в пиксели при 300 DPI.
+func PDFMarginPixels(mm int) int {
+	return (mm*300*10 + 127) / 254
+}
+
+// margin возвращает поля страницы и промежуток между ячейками в пикселях:
+// --pdf-margin или PDFMargin по умолчанию.
+func (p *PDFExporter) margin() int {
+	if p.cfg.PDFMargin > 0 {
+		return PDFMarginPixels(p.cfg.PDFMargin)
+	}
+	return PDFMargin
+}
+
+// background возвращает цвет страницы для --background vips ("r g b").
+func (p *PDFExporter) background() string {
+	r, g, b, err := config.ParseColor(p.cfg.PDFBackground)
+	if err != nil {
+		return "255 255 255"
+	}
+	return fmt.Sprintf("%d %d %d", r, g, b)
+}
+
+// embedsPage проверяет, нужно ли размещать страницу макета single на листе
+// с полями или цветным фоном (без них изображение занимает всю страницу).
+func (p *PDFExporter) embedsPage() bool {
+	return p.cfg.PDFMargin > 0 || p.cfg.PDFBackground != ""
+}
+
 // ExportToPDF создаёт PDF из списка изображений.
-// Использует vips для создания PDF.
-func (p *PDFExporter) ExportToPDF(ctx context.Context, images []string, outputPath string) error {
-	if len(images) == 0 {
+// vips подготавливает страницы в JPEG, многостраничный PDF собирается без vips
+// (libvips не умеет сохранять PDF).
+// Порядок страниц соответствует --sort-by/--sort-desc.
+func (p *PDFExporter) ExportToPDF(ctx context.Context, entries []PDFImage, outputPath string) error {
+	if len(entries) == 0 {
 		return fmt.Errorf("нет изображений для создания PDF")
 	}
 
-	// Сортируем изображения по имени
-	sort.Strings(images)
+	// Сортируем изображения так же, как при сканировании
+	entries = append([]PDFImage(nil), entries...)
+	SortPDFImages(entries, p.cfg.SortBy, p.cfg.SortDesc)
+	images := make([]string, len(entries))
+	for i, e := range entries {
+		images[i] = e.Path
+	}
 
-	// Определяем размер страницы
+	// Определяем размер страницы и ячейки сетки
 	pageWidth, pageHeight := PDFPageDimensions(p.cfg.PDFPageSize)
+	cols, rows := PDFGrid(p.cfg.PDFLayout)
+	perPage := cols * rows
+	cellWidth, cellHeight := pageWidth, pageHeight
+	if perPage > 1 {
+		cellWidth, cellHeight = PDFCellDimensions(p.cfg.PDFPageSize, p.cfg.PDFLayout, p.margin())
+	} else if p.cfg.PDFMargin > 0 {
+		cellWidth, cellHeight = PDFCellDimensions(p.cfg.PDFPageSize, p.cfg.PDFLayout, PDFMarginPixels(p.cfg.PDFMargin))
+	}
+	if cellWidth <= 0 || cellHeight <= 0 {
+		return fmt.Errorf("поля PDF %d мм не оставляют места для изображений (макет %s)", p.cfg.PDFMargin, p.cfg.PDFLayout)
+	}
 
 	// Создаём директорию для PDF
 	pdfDir := filepath.Dir(outputPath)
@@ -72,82 +173,350 @@ func (p *PDFExporter) ExportToPDF(ctx context.Context, images []string, outputPa
 	}
 	defer os.RemoveAll(tmpDir)
 
-	// Подготавливаем изображения (resize под размер страницы)
+	// Под подписью резервируется полоса внизу ячейки
+	imageHeight := cellHeight
+	if p.cfg.PDFCaptions {
+		imageHeight -= PDFCaptionHeight
+	}
+
+	// Подготавливаем изображения (resize под размер страницы или ячейки)
 	var preparedImages []string
 	for i, img := range images {
-		tmpImg := filepath.Join(tmpDir, fmt.Sprintf("page_%04d.jpg", i))
+		tmpImg := filepath.Join(tmpDir, fmt.Sprintf("image_%04d.jpg", i))
 
-		// Используем vips thumbnail для подгонки под размер страницы
-		args := []string{
+		// Используем vips thumbnail для подгонки под размер ячейки
+		err := p.runVips(ctx,
 			"thumbnail",
 			img,
 			fmt.Sprintf("%s[Q=%d]", tmpImg, p.cfg.PDFQuality),
-			fmt.Sprintf("%d", pageWidth),
-			fmt.Sprintf("--height=%d", pageHeight),
+			fmt.Sprintf("%d", cellWidth),
+			fmt.Sprintf("--height=%d", imageHeight),
+		)
+		if err != nil {
+			return fmt.Errorf("ошибка подготовки изображения %s: %w", img, err)
 		}
 
-		cmd := exec.CommandContext(ctx, p.vipsPath, args...)
-		var stderr bytes.Buffer
-		cmd.Stderr = &stderr
+		// Подпись с именем файла под изображением
+		if p.cfg.PDFCaptions {
+			captioned := filepath.Join(tmpDir, fmt.Sprintf("image_%04d_caption.jpg", i))
+			if err := p.addCaption(ctx, tmpImg, captioned, filepath.Base(img), cellWidth); err != nil {
+				return fmt.Errorf("ошибка добавления подписи к %s: %w", img, err)
+			}
+			tmpImg = captioned
+		}
 
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("ошибка подготовки изображения %s: %s", img, stderr.String())
+		// Страница single с полями или фоном - изображение по центру листа
+		if perPage == 1 && p.embedsPage() {
+			page := filepath.Join(tmpDir, fmt.Sprintf("image_%04d_page.jpg", i))
+			if err := p.embedPage(ctx, tmpImg, page, pageWidth, pageHeight); err != nil {
+				return fmt.Errorf("ошибка размещения изображения %s на странице: %w", img, err)
+			}
+			tmpImg = page
 		}
 
 		preparedImages = append(preparedImages, tmpImg)
 	}
 
-	// Создаём PDF с помощью vips arrayjoin + dzsave или просто копируем первое изображение как PDF
-	// vips поддерживает создание PDF напрямую
-	if len(preparedImages) == 1 {
-		// Для одного изображения просто конвертируем в PDF
-		args := []string{
-			"copy",
-			preparedImages[0],
-			outputPath,
+	// Собираем страницы: при сетке несколько изображений объединяются в одну страницу
+	if perPage > 1 {
+		var pages []string
+		for start := 0; start < len(preparedImages); start += perPage {
+			end := start + perPage
+			if end > len(preparedImages) {
+				end = len(preparedImages)
+			}
+
+			page := filepath.Join(tmpDir, fmt.Sprintf("page_%04d.jpg", len(pages)))
+			if err := p.composeGridPage(ctx, preparedImages[start:end], page, cols, cellWidth, cellHeight, pageWidth, pageHeight); err != nil {
+				return err
+			}
+			pages = append(pages, page)
 		}
-		cmd := exec.CommandContext(ctx, p.vipsPath, args...)
-		var stderr bytes.Buffer
-		cmd.Stderr = &stderr
+		preparedImages = pages
+	}
 
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("ошибка создания PDF: %s", stderr.String())
+	// Номера страниц "n / total" (обложка не нумеруется)
+	if p.cfg.PDFPageNumbers {
+		numbered := make([]string, len(preparedImages))
+		for i, page := range preparedImages {
+			numbered[i] = filepath.Join(tmpDir, fmt.Sprintf("page_%04d_numbered.jpg", i))
+			if err := p.numberPage(ctx, page, numbered[i], i+1, len(preparedImages), pageWidth, pageHeight); err != nil {
+				return err
+			}
 		}
-	} else {
-		// Для нескольких изображений используем arrayjoin
-		// vips arrayjoin "img1 img2 img3" output.pdf --across 1
-		imgList := strings.Join(preparedImages, " ")
-		args := []string{
-			"arrayjoin",
-			imgList,
-			outputPath,
-			"--across", "1",
+		preparedImages = numbered
+	}
+
+	// Обложка с заголовком первой страницей
+	if p.cfg.PDFTitle != "" {
+		cover := filepath.Join(tmpDir, "cover.jpg")
+		if err := p.makeCover(ctx, cover, p.cfg.PDFTitle, pageWidth, pageHeight); err != nil {
+			return err
 		}
-		cmd := exec.CommandContext(ctx, p.vipsPath, args...)
-		var stderr bytes.Buffer
-		cmd.Stderr = &stderr
-
-		if err := cmd.Run(); err != nil {
-			// Если arrayjoin не работает, пробуем альтернативный метод
-			// Создаём PDF из первого изображения (упрощённая версия)
-			args = []string{
-				"copy",
-				preparedImages[0],
-				outputPath,
-			}
-			cmd = exec.CommandContext(ctx, p.vipsPath, args...)
-			if err := cmd.Run(); err != nil {
-				return fmt.Errorf("ошибка создания PDF: %s", stderr.String())
-			}
+		preparedImages = append([]string{cover}, preparedImages...)
+	}
+
+	// Собираем многостраничный PDF: каждая подготовленная страница - отдельная страница PDF
+	if err := writeJPEGPDF(outputPath, preparedImages, pageWidth, pageHeight); err != nil {
+		return fmt.Errorf("ошибка создания PDF: %w", err)
+	}
+
+	// Список изображений для пропуска пересборки без изменений; без него
+	// следующий запуск просто соберёт PDF заново
+	if err := p.writeSources(entries, outputPath); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+	}
+
+	return nil
+}
+
+// embedPage размещает изображение по центру листа pageWidth x pageHeight
+// цвета --pdf-background (vips embed).
+func (p *PDFExporter) embedPage(ctx context.Context, imgPath, outPath string, pageWidth, pageHeight int) error {
+	info, err := readJPEGInfo(imgPath)
+	if err != nil {
+		return err
+	}
+	return p.runVips(ctx,
+		"embed",
+		imgPath,
+		fmt.Sprintf("%s[Q=%d]", outPath, p.cfg.PDFQuality),
+		fmt.Sprintf("%d", (pageWidth-info.width)/2),
+		fmt.Sprintf("%d", (pageHeight-info.height)/2),
+		fmt.Sprintf("%d", pageWidth),
+		fmt.Sprintf("%d", pageHeight),
+		"--extend", "background",
+		"--background", p.background(),
+	)
+}
+
+// addCaption рисует подпись (vips text) на белой полосе шириной width
+// и присоединяет её под изображением (vips join).
+func (p *PDFExporter) addCaption(ctx context.Context, imgPath, outPath, caption string, width int) error {
+	bandPath := strings.TrimSuffix(outPath, ".jpg") + "_band.v"
+
+	text := truncateCaption(caption, width/PDFCaptionCharWidth)
+	if err := p.renderLabel(ctx, bandPath, text, "sans 10", "centre", width, PDFCaptionHeight); err != nil {
+		return fmt.Errorf("не удалось отрисовать подпись: %w", err)
+	}
+
+	err := p.runVips(ctx,
+		"join",
+		imgPath,
+		bandPath,
+		fmt.Sprintf("%s[Q=%d]", outPath, p.cfg.PDFQuality),
+		"vertical",
+		"--align", "centre",
+		"--expand",
+		"--background", "255",
+	)
+	if err != nil {
+		return fmt.Errorf("не удалось присоединить подпись: %w", err)
+	}
+	return nil
+}
+
+// renderLabel рисует текст (vips text) чёрным на белом прямоугольнике width x height;
+// gravity задаёт положение текста в прямоугольнике (centre, east, ...).
+func (p *PDFExporter) renderLabel(ctx context.Context, outPath, text, font, gravity string, width, height int) error {
+	base := strings.TrimSuffix(outPath, filepath.Ext(outPath))
+	textPath := base + "_text.v"
+	invertedPath := base + "_inverted.v"
+
+	// Текст рендерится как маска (белый текст на чёрном) - инвертируем в чёрный на белом
+	if err := p.runVips(ctx, "text", textPath, html.EscapeString(text), "--dpi", "300", "--font", font); err != nil {
+		return err
+	}
+	if err := p.runVips(ctx, "invert", textPath, invertedPath); err != nil {
+		return err
+	}
+	return p.runVips(ctx,
+		"gravity",
+		invertedPath,
+		outPath,
+		gravity,
+		fmt.Sprintf("%d", width),
+		fmt.Sprintf("%d", height),
+		"--extend", "white",
+	)
+}
+
+// makeCover создаёт обложку: заголовок по центру белой страницы.
+func (p *PDFExporter) makeCover(ctx context.Context, outPath, title string, pageWidth, pageHeight int) error {
+	if err := p.renderLabel(ctx, outPath, title, "sans bold 32", "centre", pageWidth, pageHeight); err != nil {
+		return fmt.Errorf("не удалось создать обложку: %w", err)
+	}
+	return nil
+}
+
+// numberPage размещает страницу на листе pageWidth x pageHeight и вставляет
+// номер "n / total" в правый нижний угол (в поле страницы).
+func (p *PDFExporter) numberPage(ctx context.Context, pagePath, outPath string, n, total, pageWidth, pageHeight int) error {
+	base := strings.TrimSuffix(outPath, ".jpg")
+	fullPath := base + "_full.v"
+	labelPath := base + "_label.v"
+
+	// Страница одиночного макета меньше листа - центрируем её
+	err := p.runVips(ctx,
+		"gravity",
+		pagePath,
+		fullPath,
+		"centre",
+		fmt.Sprintf("%d", pageWidth),
+		fmt.Sprintf("%d", pageHeight),
+		"--extend", "background",
+		"--background", p.background(),
+	)
+	if err != nil {
+		return fmt.Errorf("не удалось разместить страницу %d: %w", n, err)
+	}
+
+	label := fmt.Sprintf("%d / %d", n, total)
+	if err := p.renderLabel(ctx, labelPath, label, "sans 8", "east", PDFPageNumberWidth, PDFCaptionHeight); err != nil {
+		return fmt.Errorf("не удалось отрисовать номер страницы %d: %w", n, err)
+	}
+
+	err = p.runVips(ctx,
+		"insert",
+		fullPath,
+		labelPath,
+		fmt.Sprintf("%s[Q=%d]", outPath, p.cfg.PDFQuality),
+		fmt.Sprintf("%d", pageWidth-p.margin()-PDFPageNumberWidth),
+		fmt.Sprintf("%d", pageHeight-(p.margin()+PDFCaptionHeight)/2),
+	)
+	if err != nil {
+		return fmt.Errorf("не удалось вставить номер страницы %d: %w", n, err)
+	}
+	return nil
+}
+
+// truncateCaption сокращает подпись до maxRunes символов, заменяя середину
+// многоточием и сохраняя расширение файла.
+func truncateCaption(caption string, maxRunes int) string {
+	runes := []rune(caption)
+	if maxRunes < 5 {
+		maxRunes = 5
+	}
+	if len(runes) <= maxRunes {
+		return caption
+	}
+
+	// Конец (обычно расширение) важнее середины имени
+	tail := len([]rune(filepath.Ext(caption))) + 2
+	if tail > maxRunes/2 {
+		tail = maxRunes / 2
+	}
+	head := maxRunes - tail - 1
+	return string(runes[:head]) + "…" + string(runes[len(runes)-tail:])
+}
+
+// composeGridPage объединяет изображения в сетку (vips arrayjoin) и размещает её
+// на странице цвета --pdf-background с полями (vips embed).
+func (p *PDFExporter) composeGridPage(ctx context.Context, cells []string, pagePath string, cols, cellWidth, cellHeight, pageWidth, pageHeight int) error {
+	gridPath := strings.TrimSuffix(pagePath, ".jpg") + "_grid.v"
+
+	// arrayjoin принимает список изображений одной строкой через пробел, поэтому
+	// ячейки передаются относительно директории страницы: путь временной
+	// директории может содержать пробелы, имена ячеек - нет
+	dir := filepath.Dir(pagePath)
+	names := make([]string, len(cells))
+	for i, cell := range cells {
+		name, err := filepath.Rel(dir, cell)
+		if err != nil || strings.ContainsAny(name, " \t\n") {
+			return fmt.Errorf("ошибка сборки сетки страницы: недопустимый путь ячейки %s", cell)
 		}
+		names[i] = name
+	}
+
+	// Каждая ячейка занимает cellWidth x cellHeight, между ячейками - поле,
+	// изображение выравнивается по центру ячейки
+	err := p.runVipsIn(ctx, dir,
+		"arrayjoin",
+		strings.Join(names, " "),
+		gridPath,
+		"--across", fmt.Sprintf("%d", cols),
+		"--hspacing", fmt.Sprintf("%d", cellWidth),
+		"--vspacing", fmt.Sprintf("%d", cellHeight),
+		"--shim", fmt.Sprintf("%d", p.margin()),
+		"--halign", "centre",
+		"--valign", "centre",
+		"--background", p.background(),
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка сборки сетки страницы: %w", err)
 	}
 
+	err = p.runVips(ctx,
+		"embed",
+		gridPath,
+		fmt.Sprintf("%s[Q=%d]", pagePath, p.cfg.PDFQuality),
+		fmt.Sprintf("%d", p.margin()),
+		fmt.Sprintf("%d", p.margin()),
+		fmt.Sprintf("%d", pageWidth),
+		fmt.Sprintf("%d", pageHeight),
+		"--extend", "background",
+		"--background", p.background(),
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка размещения сетки на странице: %w", err)
+	}
 	return nil
 }
 
+// runVips выполняет команду vips и возвращает ошибку с выводом stderr.
+func (p *PDFExporter) runVips(ctx context.Context, args ...string) error {
+	return p.runVipsIn(ctx, "", args...)
+}
+
+// runVipsIn выполняет команду vips в рабочей директории dir (пусто - текущая).
+func (p *PDFExporter) runVipsIn(ctx context.Context, dir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, p.vipsPath, args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// SortPDFImages сортирует изображения по критерию sortBy: "name" (по пути),
+// "date" (по времени модификации), "size" (по размеру). desc - по убыванию.
+// При равенстве ключа порядок определяется путём.
+func SortPDFImages(images []PDFImage, sortBy string, desc bool) {
+	sort.SliceStable(images, func(i, j int) bool {
+		a, b := images[i], images[j]
+		if desc {
+			a, b = b, a
+		}
+		switch sortBy {
+		case "date":
+			if a.Mtime != b.Mtime {
+				return a.Mtime < b.Mtime
+			}
+		case "size":
+			if a.Size != b.Size {
+				return a.Size < b.Size
+			}
+		}
+		return a.Path < b.Path
+	})
+}
+
 // CollectImages собирает все обработанные изображения из выходной директории.
-func (p *PDFExporter) CollectImages() ([]string, error) {
-	var images []string
+// Размер и время модификации берутся из самих файлов; вызывающий код может
+// заменить их данными исходных файлов.
+func (p *PDFExporter) CollectImages() ([]PDFImage, error) {
+	var images []PDFImage
+
+	supportedExts := map[string]bool{
+		"jpg": true, "jpeg": true, "png": true, "webp": true,
+		"tiff": true, "heic": true, "avif": true, "jxl": true,
+	}
 
 	err := filepath.WalkDir(p.cfg.OutputDir, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
@@ -159,13 +528,19 @@ func (p *PDFExporter) CollectImages() ([]string, error) {
 
 		// Проверяем расширение
 		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
-		supportedExts := map[string]bool{
-			"jpg": true, "jpeg": true, "png": true, "webp": true,
-			"tiff": true, "heic": true, "avif": true, "jxl": true,
+		if !supportedExts[ext] {
+			return nil
 		}
-		if supportedExts[ext] {
-			images = append(images, path)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
 		}
+		images = append(images, PDFImage{
+			Path:  path,
+			Size:  info.Size(),
+			Mtime: info.ModTime().Unix(),
+		})
 
 		return nil
 	})
@@ -175,8 +550,6 @@ func (p *PDFExporter) CollectImages() ([]string, error) {
 
 /*
 Возможные расширения:
-- Добавить поддержку разных макетов (1 фото на страницу, 2, 4, 9)
-- Добавить поддержку подписей к изображениям
-- Добавить поддержку обложки
+- Добавить размеры изображения в подпись
 - Добавить поддержку оглавления
 */
@@ -0,0 +1,543 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// fakePDFVipsScript имитирует vips для PDF экспорта: записывает вызовы в calls.log
+// рядом со скриптом и копирует (первый) вход в выходной путь без параметров в [...].
+// text копирует sample.jpg, join склеивает входы, insert копирует основное изображение.
+const fakePDFVipsScript = `#!/bin/sh
+dir=$(dirname "$0")
+echo "$*" >> "$dir/calls.log"
+out="${3%%\[*}"
+case "$1" in
+text) cp "$dir/sample.jpg" "${2%%\[*}" ;;
+join) cat "$2" "$3" > "${4%%\[*}" ;;
+insert) cp "$2" "${4%%\[*}" ;;
+arrayjoin) set -- $2; cp "$1" "$out" ;;
+*) cp "$2" "$out" ;;
+esac
+`
+
+// newPDFTestEnv создаёт fake vips, n тестовых изображений и конфиг для PDF экспорта.
+// Время модификации изображений возрастает с номером.
+func newPDFTestEnv(t *testing.T, n int) (*PDFExporter, []PDFImage, string) {
+	t.Helper()
+
+	vipsDir := t.TempDir()
+	vipsPath := filepath.Join(vipsDir, "vips")
+	if err := os.WriteFile(vipsPath, []byte(fakePDFVipsScript), 0755); err != nil {
+		t.Fatalf("не удалось создать fake vips: %v", err)
+	}
+	writeTestJPEG(t, filepath.Join(vipsDir, "sample.jpg"))
+
+	imgDir := t.TempDir()
+	var images []PDFImage
+	for i := 0; i < n; i++ {
+		path := filepath.Join(imgDir, fmt.Sprintf("img_%02d.jpg", i))
+		writeTestJPEG(t, path)
+		images = append(images, PDFImage{Path: path, Size: int64(1000 - i), Mtime: int64(1700000000 + i*60)})
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.PDFPageSize = "a4"
+	cfg.PDFQuality = 85
+
+	return NewPDFExporter(vipsPath, cfg), images, filepath.Join(vipsDir, "calls.log")
+}
+
+// readVipsCalls возвращает вызовы fake vips, начинающиеся с команды op.
+func readVipsCalls(t *testing.T, logPath, op string) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("не удалось прочитать журнал вызовов: %v", err)
+	}
+	var calls []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if strings.HasPrefix(line, op+" ") {
+			calls = append(calls, line)
+		}
+	}
+	return calls
+}
+
+func TestPDFCellDimensions(t *testing.T) {
+	pageWidth, pageHeight := PDFPageDimensions("a4")
+
+	tests := []struct {
+		layout     string
+		cols, rows int
+	}{
+		{"single", 1, 1},
+		{"2up", 1, 2},
+		{"4up", 2, 2},
+		{"9up", 3, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			cols, rows := PDFGrid(tt.layout)
+			if cols != tt.cols || rows != tt.rows {
+				t.Fatalf("PDFGrid(%s) = %dx%d, want %dx%d", tt.layout, cols, rows, tt.cols, tt.rows)
+			}
+
+			w, h := PDFCellDimensions("a4", tt.layout, PDFMargin)
+			// Ячейки с полями и промежутками должны помещаться на страницу
+			usedW := cols*w + (cols+1)*PDFMargin
+			usedH := rows*h + (rows+1)*PDFMargin
+			if usedW > pageWidth || usedH > pageHeight {
+				t.Errorf("grid %dx%d of %dx%d cells does not fit page %dx%d", cols, rows, w, h, pageWidth, pageHeight)
+			}
+			if pageWidth-usedW >= cols || pageHeight-usedH >= rows {
+				t.Errorf("cells %dx%d leave unused space on page %dx%d", w, h, pageWidth, pageHeight)
+			}
+		})
+	}
+}
+
+func TestExportToPDF_4upPages(t *testing.T) {
+	exporter, images, callsLog := newPDFTestEnv(t, 8)
+	exporter.cfg.PDFLayout = "4up"
+
+	out := filepath.Join(t.TempDir(), "album.pdf")
+	if err := exporter.ExportToPDF(context.Background(), images, out); err != nil {
+		t.Fatalf("ExportToPDF: %v", err)
+	}
+
+	// Каждое изображение уменьшается до размера ячейки
+	cellW, cellH := PDFCellDimensions("a4", "4up", PDFMargin)
+	thumbnails := readVipsCalls(t, callsLog, "thumbnail")
+	if len(thumbnails) != 8 {
+		t.Fatalf("thumbnail calls = %d, want 8", len(thumbnails))
+	}
+	if want := fmt.Sprintf(" %d --height=%d", cellW, cellH); !strings.HasSuffix(thumbnails[0], want) {
+		t.Errorf("thumbnail call %q does not end with %q", thumbnails[0], want)
+	}
+
+	// 8 изображений по 4 на страницу - 2 страницы сетки 2x2
+	var grids []string
+	for _, call := range readVipsCalls(t, callsLog, "arrayjoin") {
+		if strings.Contains(call, "--across 2") {
+			grids = append(grids, call)
+		}
+	}
+	if len(grids) != 2 {
+		t.Fatalf("grid pages = %d, want 2: %v", len(grids), grids)
+	}
+	for _, grid := range grids {
+		if n := len(strings.Fields(strings.SplitN(grid, " --", 2)[0])) - 2; n != 4 {
+			t.Errorf("grid page has %d images, want 4: %q", n, grid)
+		}
+	}
+	if embeds := readVipsCalls(t, callsLog, "embed"); len(embeds) != 2 {
+		t.Errorf("embed calls = %d, want 2", len(embeds))
+	}
+	if pages := countPDFPages(t, out); pages != 2 {
+		t.Errorf("PDF pages = %d, want 2", pages)
+	}
+}
+
+func TestExportToPDF_4upTempDirWithSpaces(t *testing.T) {
+	exporter, images, callsLog := newPDFTestEnv(t, 4)
+	exporter.cfg.PDFLayout = "4up"
+
+	// Временная директория с пробелом в пути (например, профиль пользователя в Windows)
+	tmp := filepath.Join(t.TempDir(), "temp dir")
+	if err := os.Mkdir(tmp, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TMPDIR", tmp)
+
+	out := filepath.Join(t.TempDir(), "album.pdf")
+	if err := exporter.ExportToPDF(context.Background(), images, out); err != nil {
+		t.Fatalf("ExportToPDF: %v", err)
+	}
+	grids := readVipsCalls(t, callsLog, "arrayjoin")
+	if len(grids) != 1 {
+		t.Fatalf("arrayjoin calls = %d, want 1", len(grids))
+	}
+	list := strings.SplitN(strings.TrimPrefix(grids[0], "arrayjoin "), " "+tmp, 2)[0]
+	if fields := strings.Fields(list); len(fields) != 4 || strings.Contains(list, tmp) {
+		t.Errorf("список ячеек %q, want 4 относительных пути", list)
+	}
+}
+
+func TestTruncateCaption(t *testing.T) {
+	tests := []struct {
+		caption string
+		max     int
+		want    string
+	}{
+		{"short.jpg", 20, "short.jpg"},
+		{"IMG_20240615_123456_beach_sunset.jpg", 20, "IMG_20240615_…et.jpg"},
+		{"фотография_с_очень_длинным_именем.webp", 16, "фотограф…ем.webp"},
+	}
+	for _, tt := range tests {
+		got := truncateCaption(tt.caption, tt.max)
+		if got != tt.want {
+			t.Errorf("truncateCaption(%q, %d) = %q, want %q", tt.caption, tt.max, got, tt.want)
+		}
+		if n := len([]rune(got)); n > tt.max {
+			t.Errorf("truncateCaption(%q, %d) has %d runes", tt.caption, tt.max, n)
+		}
+	}
+}
+
+func TestExportToPDF_Captions(t *testing.T) {
+	export := func(captions bool) ([]byte, string, int) {
+		exporter, images, callsLog := newPDFTestEnv(t, 3)
+		exporter.cfg.PDFCaptions = captions
+
+		out := filepath.Join(t.TempDir(), "album.pdf")
+		if err := exporter.ExportToPDF(context.Background(), images, out); err != nil {
+			t.Fatalf("ExportToPDF(captions=%v): %v", captions, err)
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data, callsLog, countPDFPages(t, out)
+	}
+
+	plain, _, plainPages := export(false)
+	captioned, captionedLog, captionedPages := export(true)
+
+	if len(captioned) <= len(plain) {
+		t.Errorf("captioned PDF size %d, want larger than plain %d", len(captioned), len(plain))
+	}
+
+	// Количество страниц не меняется
+	if plainPages != 3 || captionedPages != 3 {
+		t.Errorf("page count: plain %d, captioned %d, want 3", plainPages, captionedPages)
+	}
+
+	texts := readVipsCalls(t, captionedLog, "text")
+	if len(texts) != 3 {
+		t.Fatalf("text calls = %d, want 3", len(texts))
+	}
+	if !strings.Contains(texts[0], "img_00.jpg") {
+		t.Errorf("caption %q does not contain file name", texts[0])
+	}
+	if joins := readVipsCalls(t, captionedLog, "join"); len(joins) != 3 {
+		t.Errorf("join calls = %d, want 3", len(joins))
+	}
+
+	// Под подпись резервируется место: изображение уменьшается до высоты без полосы
+	pageW, pageH := PDFPageDimensions("a4")
+	want := fmt.Sprintf(" %d --height=%d", pageW, pageH-PDFCaptionHeight)
+	if thumb := readVipsCalls(t, captionedLog, "thumbnail")[0]; !strings.HasSuffix(thumb, want) {
+		t.Errorf("thumbnail call %q does not end with %q", thumb, want)
+	}
+}
+
+func TestExportToPDF_SortDateDesc(t *testing.T) {
+	exporter, images, callsLog := newPDFTestEnv(t, 3)
+	exporter.cfg.SortBy = "date"
+	exporter.cfg.SortDesc = true
+
+	// Порядок на входе не должен влиять на порядок страниц
+	shuffled := []PDFImage{images[1], images[0], images[2]}
+
+	out := filepath.Join(t.TempDir(), "album.pdf")
+	if err := exporter.ExportToPDF(context.Background(), shuffled, out); err != nil {
+		t.Fatalf("ExportToPDF: %v", err)
+	}
+
+	thumbnails := readVipsCalls(t, callsLog, "thumbnail")
+	if len(thumbnails) != 3 {
+		t.Fatalf("thumbnail calls = %d, want 3", len(thumbnails))
+	}
+	for i, want := range []string{images[2].Path, images[1].Path, images[0].Path} {
+		if got := strings.Fields(thumbnails[i])[1]; got != want {
+			t.Errorf("page %d = %s, want %s", i+1, got, want)
+		}
+	}
+}
+
+func TestSortPDFImages(t *testing.T) {
+	images := []PDFImage{
+		{Path: "b.jpg", Size: 300, Mtime: 100},
+		{Path: "a.jpg", Size: 100, Mtime: 300},
+		{Path: "c.jpg", Size: 200, Mtime: 200},
+	}
+
+	tests := []struct {
+		sortBy string
+		desc   bool
+		want   []string
+	}{
+		{"name", false, []string{"a.jpg", "b.jpg", "c.jpg"}},
+		{"name", true, []string{"c.jpg", "b.jpg", "a.jpg"}},
+		{"date", false, []string{"b.jpg", "c.jpg", "a.jpg"}},
+		{"date", true, []string{"a.jpg", "c.jpg", "b.jpg"}},
+		{"size", false, []string{"a.jpg", "c.jpg", "b.jpg"}},
+		{"size", true, []string{"b.jpg", "c.jpg", "a.jpg"}},
+	}
+	for _, tt := range tests {
+		sorted := append([]PDFImage(nil), images...)
+		SortPDFImages(sorted, tt.sortBy, tt.desc)
+		for i, want := range tt.want {
+			if sorted[i].Path != want {
+				t.Errorf("SortPDFImages(%s, desc=%v)[%d] = %s, want %s", tt.sortBy, tt.desc, i, sorted[i].Path, want)
+			}
+		}
+	}
+}
+
+// pdfPageRe находит объекты страниц PDF (но не /Pages).
+var pdfPageRe = regexp.MustCompile(`/Type /Page\b`)
+
+// countPDFPages возвращает количество страниц в PDF файле.
+func countPDFPages(t *testing.T, path string) int {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("не удалось прочитать PDF: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "%PDF-") {
+		t.Fatalf("%s не является PDF", path)
+	}
+	return len(pdfPageRe.FindAll(data, -1))
+}
+
+func TestExportToPDF_CoverAddsPage(t *testing.T) {
+	export := func(title string) (int, string) {
+		exporter, images, callsLog := newPDFTestEnv(t, 3)
+		exporter.cfg.PDFTitle = title
+
+		out := filepath.Join(t.TempDir(), "album.pdf")
+		if err := exporter.ExportToPDF(context.Background(), images, out); err != nil {
+			t.Fatalf("ExportToPDF(title=%q): %v", title, err)
+		}
+		return countPDFPages(t, out), callsLog
+	}
+
+	plain, _ := export("")
+	withCover, callsLog := export("Свадьба 2024")
+
+	if withCover != plain+1 {
+		t.Fatalf("pages with cover = %d, want %d", withCover, plain+1)
+	}
+	texts := readVipsCalls(t, callsLog, "text")
+	if len(texts) != 1 || !strings.Contains(texts[0], "Свадьба 2024") {
+		t.Errorf("cover text calls = %v, want one with title", texts)
+	}
+}
+
+func TestExportToPDF_PageNumbers(t *testing.T) {
+	exporter, images, callsLog := newPDFTestEnv(t, 3)
+	exporter.cfg.PDFTitle = "Album"
+	exporter.cfg.PDFPageNumbers = true
+
+	out := filepath.Join(t.TempDir(), "album.pdf")
+	if err := exporter.ExportToPDF(context.Background(), images, out); err != nil {
+		t.Fatalf("ExportToPDF: %v", err)
+	}
+
+	// Номера только на страницах с изображениями, обложка не входит в total
+	var labels []string
+	for _, call := range readVipsCalls(t, callsLog, "text") {
+		if strings.Contains(call, " / ") {
+			labels = append(labels, strings.Join(strings.Fields(call)[2:5], " "))
+		}
+	}
+	want := []string{"1 / 3", "2 / 3", "3 / 3"}
+	if strings.Join(labels, ",") != strings.Join(want, ",") {
+		t.Errorf("page labels = %v, want %v", labels, want)
+	}
+	if inserts := readVipsCalls(t, callsLog, "insert"); len(inserts) != 3 {
+		t.Errorf("insert calls = %d, want 3", len(inserts))
+	}
+	if pages := countPDFPages(t, out); pages != 4 {
+		t.Errorf("pages = %d, want 4 (cover + 3)", pages)
+	}
+}
+
+func TestExportToPDF_ThreePages(t *testing.T) {
+	exporter, images, _ := newPDFTestEnv(t, 3)
+
+	out := filepath.Join(t.TempDir(), "album.pdf")
+	if err := exporter.ExportToPDF(context.Background(), images, out); err != nil {
+		t.Fatalf("ExportToPDF: %v", err)
+	}
+	if pages := countPDFPages(t, out); pages != 3 {
+		t.Fatalf("PDF pages = %d, want 3", pages)
+	}
+}
+
+func TestExportToPDF_BrokenPageFails(t *testing.T) {
+	exporter, images, _ := newPDFTestEnv(t, 3)
+
+	// Второе изображение не JPEG - PDF не должен собираться без него
+	if err := os.WriteFile(images[1].Path, []byte("not a jpeg"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(t.TempDir(), "album.pdf")
+	err := exporter.ExportToPDF(context.Background(), images, out)
+	if err == nil {
+		t.Fatal("expected error for broken page, got nil")
+	}
+	if !strings.Contains(err.Error(), "страница 2") {
+		t.Errorf("error %q does not name the broken page", err)
+	}
+	if _, statErr := os.Stat(out); !os.IsNotExist(statErr) {
+		t.Errorf("partial PDF left behind: %v", statErr)
+	}
+}
+
+func TestExportToPDF_JoinFailureLeavesNoFile(t *testing.T) {
+	exporter, images, callsLog := newPDFTestEnv(t, 3)
+	exporter.cfg.PDFCaptions = true
+
+	// vips падает на склейке подписи с изображением второй страницы
+	vipsDir := filepath.Dir(callsLog)
+	script := strings.Replace(fakePDFVipsScript, "join) cat",
+		`join) case "$2" in *image_0001*) echo "join failed" >&2; exit 1 ;; esac; cat`, 1)
+	if err := os.WriteFile(filepath.Join(vipsDir, "vips"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	outDir := t.TempDir()
+	out := filepath.Join(outDir, "album.pdf")
+	err := exporter.ExportToPDF(context.Background(), images, out)
+	if err == nil {
+		t.Fatal("ExportToPDF с ошибкой join должен вернуть ошибку")
+	}
+	if joins := readVipsCalls(t, callsLog, "join"); len(joins) != 2 {
+		t.Errorf("join calls = %d, want 2 (экспорт прерван на второй странице)", len(joins))
+	}
+	entries, err := os.ReadDir(outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("после ошибки в директории PDF остались файлы: %v", entries)
+	}
+}
+
+func TestWriteJPEGPDF_Atomic(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "page.jpg")
+	writeTestJPEG(t, page)
+	out := filepath.Join(dir, "album.pdf")
+	if err := os.WriteFile(out, []byte("old album"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ошибка записи не трогает PDF прошлого экспорта
+	broken := filepath.Join(dir, "missing.jpg")
+	if err := writeJPEGPDF(out, []string{page, broken}, 100, 100); err == nil {
+		t.Fatal("writeJPEGPDF с отсутствующей страницей должен вернуть ошибку")
+	}
+	if data, _ := os.ReadFile(out); string(data) != "old album" {
+		t.Errorf("прошлый PDF изменён: %q", data)
+	}
+
+	if err := writeJPEGPDF(out, []string{page}, 100, 100); err != nil {
+		t.Fatalf("writeJPEGPDF: %v", err)
+	}
+	if pages := countPDFPages(t, out); pages != 1 {
+		t.Errorf("PDF pages = %d, want 1", pages)
+	}
+	if _, err := os.Stat(out + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("временный файл не удалён: %v", err)
+	}
+}
+
+func TestPDFExporter_UpToDate(t *testing.T) {
+	exporter, images, _ := newPDFTestEnv(t, 3)
+	out := filepath.Join(t.TempDir(), "album.pdf")
+
+	if exporter.UpToDate(images, out) {
+		t.Fatal("UpToDate до сборки PDF = true")
+	}
+	if err := exporter.ExportToPDF(context.Background(), images, out); err != nil {
+		t.Fatalf("ExportToPDF: %v", err)
+	}
+	if !exporter.UpToDate(images, out) {
+		t.Fatal("повторный запуск без изменений должен пропускать сборку PDF")
+	}
+
+	// Изменённое изображение - PDF пересобирается
+	mtime := time.Now().Add(time.Hour)
+	if err := os.Chtimes(images[1].Path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if exporter.UpToDate(images, out) {
+		t.Fatal("после изменения изображения UpToDate = true")
+	}
+	if err := exporter.ExportToPDF(context.Background(), images, out); err != nil {
+		t.Fatalf("ExportToPDF: %v", err)
+	}
+	if !exporter.UpToDate(images, out) {
+		t.Fatal("после пересборки UpToDate = false")
+	}
+
+	// Другие настройки или набор изображений - тоже пересборка
+	layout := exporter.cfg.PDFLayout
+	exporter.cfg.PDFLayout = "4up"
+	if exporter.UpToDate(images, out) {
+		t.Error("после смены --pdf-layout UpToDate = true")
+	}
+	exporter.cfg.PDFLayout = layout
+	if exporter.UpToDate(images[:2], out) {
+		t.Error("после удаления изображения UpToDate = true")
+	}
+}
+
+func TestExportToPDF_MarginBackground(t *testing.T) {
+	exporter, images, callsLog := newPDFTestEnv(t, 2)
+	exporter.cfg.PDFMargin = 20
+	exporter.cfg.PDFBackground = "#336699"
+
+	out := filepath.Join(t.TempDir(), "album.pdf")
+	if err := exporter.ExportToPDF(context.Background(), images, out); err != nil {
+		t.Fatalf("ExportToPDF: %v", err)
+	}
+
+	// Изображение уменьшается до страницы за вычетом полей
+	pageW, pageH := PDFPageDimensions("a4")
+	margin := PDFMarginPixels(20)
+	if margin != 236 {
+		t.Errorf("PDFMarginPixels(20) = %d, want 236", margin)
+	}
+	thumbnails := readVipsCalls(t, callsLog, "thumbnail")
+	if want := fmt.Sprintf(" %d --height=%d", pageW-2*margin, pageH-2*margin); len(thumbnails) == 0 || !strings.HasSuffix(thumbnails[0], want) {
+		t.Errorf("thumbnail calls %q, want suffix %q", thumbnails, want)
+	}
+
+	// Каждая страница - лист во всю страницу, изображение (16x16) по центру внутри полей
+	embeds := readVipsCalls(t, callsLog, "embed")
+	if len(embeds) != 2 {
+		t.Fatalf("embed calls = %d, want 2: %v", len(embeds), embeds)
+	}
+	for _, call := range embeds {
+		var in, outPath string
+		var x, y, w, h int
+		if _, err := fmt.Sscanf(call, "embed %s %s %d %d %d %d", &in, &outPath, &x, &y, &w, &h); err != nil {
+			t.Fatalf("embed call %q: %v", call, err)
+		}
+		if w != pageW || h != pageH {
+			t.Errorf("page = %dx%d, want %dx%d", w, h, pageW, pageH)
+		}
+		if x < margin || y < margin || x+16 > pageW-margin || y+16 > pageH-margin {
+			t.Errorf("image at %d,%d is outside the %dpx margin", x, y, margin)
+		}
+		if !strings.HasSuffix(call, "--extend background --background 51 102 153") {
+			t.Errorf("embed call %q does not use background #336699", call)
+		}
+	}
+}
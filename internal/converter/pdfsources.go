@@ -0,0 +1,94 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// pdfSourcesSuffix - суффикс файла рядом с PDF со списком изображений
+// и настроек, из которых он собран.
+const pdfSourcesSuffix = ".sources.json"
+
+// pdfSources - изображения и настройки, из которых собран PDF.
+type pdfSources struct {
+	// Settings - настройки, влияющие на содержимое PDF.
+	Settings map[string]any `json:"settings"`
+
+	// Images - изображения в порядке страниц.
+	Images []pdfSource `json:"images"`
+}
+
+// pdfSource - изображение страницы PDF.
+type pdfSource struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+
+	// Mtime - время модификации изображения в наносекундах.
+	Mtime int64 `json:"mtime"`
+}
+
+// sources собирает список изображений entries в порядке страниц с размером
+// и временем модификации самих файлов и настройки PDF.
+func (p *PDFExporter) sources(entries []PDFImage) (*pdfSources, error) {
+	entries = append([]PDFImage(nil), entries...)
+	SortPDFImages(entries, p.cfg.SortBy, p.cfg.SortDesc)
+
+	s := &pdfSources{
+		Settings: map[string]any{
+			"page_size":    p.cfg.PDFPageSize,
+			"quality":      p.cfg.PDFQuality,
+			"layout":       p.cfg.PDFLayout,
+			"captions":     p.cfg.PDFCaptions,
+			"title":        p.cfg.PDFTitle,
+			"page_numbers": p.cfg.PDFPageNumbers,
+			"margin":       p.cfg.PDFMargin,
+			"background":   p.cfg.PDFBackground,
+		},
+		Images: make([]pdfSource, len(entries)),
+	}
+	for i, e := range entries {
+		info, err := os.Stat(e.Path)
+		if err != nil {
+			return nil, err
+		}
+		s.Images[i] = pdfSource{Path: e.Path, Size: info.Size(), Mtime: info.ModTime().UnixNano()}
+	}
+	return s, nil
+}
+
+// writeSources записывает рядом с PDF список изображений и настроек, из
+// которых он собран.
+func (p *PDFExporter) writeSources(entries []PDFImage, outputPath string) error {
+	s, err := p.sources(entries)
+	if err != nil {
+		return fmt.Errorf("не удалось записать список изображений PDF: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("не удалось записать список изображений PDF: %w", err)
+	}
+	if err := os.WriteFile(outputPath+pdfSourcesSuffix, data, 0644); err != nil {
+		return fmt.Errorf("не удалось записать список изображений PDF: %w", err)
+	}
+	return nil
+}
+
+// UpToDate проверяет, что PDF outputPath существует и собран из тех же
+// изображений entries (пути, размеры, время модификации) с теми же настройками.
+func (p *PDFExporter) UpToDate(entries []PDFImage, outputPath string) bool {
+	if _, err := os.Stat(outputPath); err != nil {
+		return false
+	}
+	recorded, err := os.ReadFile(outputPath + pdfSourcesSuffix)
+	if err != nil {
+		return false
+	}
+	s, err := p.sources(entries)
+	if err != nil {
+		return false
+	}
+	current, err := json.MarshalIndent(s, "", "  ")
+	return err == nil && bytes.Equal(recorded, current)
+}
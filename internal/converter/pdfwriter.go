@@ -0,0 +1,205 @@
+package converter
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"os"
+)
+
+// pdfPointsPerPixel - перевод пикселей страницы (300 DPI) в пункты PDF (72 на дюйм).
+const pdfPointsPerPixel = 72.0 / 300.0
+
+// countingWriter считает записанные байты (для таблицы xref).
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write реализует io.Writer.
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// jpegInfo - параметры JPEG, нужные для встраивания в PDF.
+type jpegInfo struct {
+	width, height int
+	colorSpace    string
+	size          int64
+}
+
+// readJPEGInfo читает размеры и цветовое пространство JPEG без декодирования пикселей.
+func readJPEGInfo(path string) (jpegInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return jpegInfo{}, fmt.Errorf("не удалось открыть %s: %w", path, err)
+	}
+	defer f.Close()
+
+	cfg, err := jpeg.DecodeConfig(f)
+	if err != nil {
+		return jpegInfo{}, fmt.Errorf("%s не является JPEG: %w", path, err)
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return jpegInfo{}, fmt.Errorf("не удалось получить размер %s: %w", path, err)
+	}
+
+	info := jpegInfo{width: cfg.Width, height: cfg.Height, size: stat.Size()}
+	switch cfg.ColorModel {
+	case color.GrayModel:
+		info.colorSpace = "/DeviceGray"
+	case color.CMYKModel:
+		// Adobe JPEG хранит CMYK инвертированным
+		info.colorSpace = "/DeviceCMYK /Decode [1 0 1 0 1 0 1 0]"
+	default:
+		info.colorSpace = "/DeviceRGB"
+	}
+	return info, nil
+}
+
+// writeJPEGPDF собирает многостраничный PDF: каждый JPEG - отдельная страница
+// размером pageWidth x pageHeight пикселей (300 DPI). Изображение вписывается
+// в страницу с сохранением пропорций и центрируется. JPEG встраивается без
+// перекодирования (DCTDecode). PDF пишется в outputPath.tmp и переименовывается
+// после записи, поэтому прерванный экспорт не оставляет неполный файл под
+// итоговым именем (и не портит PDF прошлого экспорта).
+func writeJPEGPDF(outputPath string, pages []string, pageWidth, pageHeight int) error {
+	if len(pages) == 0 {
+		return fmt.Errorf("нет страниц для PDF")
+	}
+
+	// Проверяем все страницы до создания файла, чтобы не оставить неполный PDF
+	infos := make([]jpegInfo, len(pages))
+	for i, page := range pages {
+		info, err := readJPEGInfo(page)
+		if err != nil {
+			return fmt.Errorf("страница %d: %w", i+1, err)
+		}
+		infos[i] = info
+	}
+
+	tmpPath := outputPath + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("не удалось создать PDF: %w", err)
+	}
+
+	if err := writePDFObjects(f, pages, infos, pageWidth, pageHeight); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("не удалось записать PDF: %w", err)
+	}
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("не удалось переименовать %s -> %s: %w", tmpPath, outputPath, err)
+	}
+	return nil
+}
+
+// writePDFObjects записывает объекты PDF, таблицу xref и trailer.
+// Объекты: 1 - Catalog, 2 - Pages, далее по три на страницу (Page, Contents, Image).
+func writePDFObjects(out io.Writer, pages []string, infos []jpegInfo, pageWidth, pageHeight int) error {
+	bw := bufio.NewWriter(out)
+	w := &countingWriter{w: bw}
+
+	totalObjects := 2 + 3*len(pages)
+	offsets := make([]int64, totalObjects+1)
+
+	beginObject := func(id int) {
+		offsets[id] = w.n
+		fmt.Fprintf(w, "%d 0 obj\n", id)
+	}
+
+	fmt.Fprint(w, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	beginObject(1)
+	fmt.Fprint(w, "<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+
+	beginObject(2)
+	fmt.Fprint(w, "<< /Type /Pages /Kids [")
+	for i := range pages {
+		fmt.Fprintf(w, " %d 0 R", 3+3*i)
+	}
+	fmt.Fprintf(w, " ] /Count %d >>\nendobj\n", len(pages))
+
+	mediaW := float64(pageWidth) * pdfPointsPerPixel
+	mediaH := float64(pageHeight) * pdfPointsPerPixel
+
+	for i, page := range pages {
+		info := infos[i]
+		pageID, contentID, imageID := 3+3*i, 4+3*i, 5+3*i
+
+		// Вписываем изображение в страницу с сохранением пропорций
+		scale := mediaW / float64(info.width)
+		if s := mediaH / float64(info.height); s < scale {
+			scale = s
+		}
+		drawW := float64(info.width) * scale
+		drawH := float64(info.height) * scale
+		content := fmt.Sprintf("q %.2f 0 0 %.2f %.2f %.2f cm /Im0 Do Q\n",
+			drawW, drawH, (mediaW-drawW)/2, (mediaH-drawH)/2)
+
+		beginObject(pageID)
+		fmt.Fprintf(w, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>\nendobj\n",
+			mediaW, mediaH, imageID, contentID)
+
+		beginObject(contentID)
+		fmt.Fprintf(w, "<< /Length %d >>\nstream\n%sendstream\nendobj\n", len(content), content)
+
+		beginObject(imageID)
+		fmt.Fprintf(w, "<< /Type /XObject /Subtype /Image /Width %d /Height %d "+
+			"/ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n",
+			info.width, info.height, info.colorSpace, info.size)
+		if err := copyFile(w, page, info.size); err != nil {
+			return fmt.Errorf("страница %d: %w", i+1, err)
+		}
+		fmt.Fprint(w, "\nendstream\nendobj\n")
+	}
+
+	xrefOffset := w.n
+	fmt.Fprintf(w, "xref\n0 %d\n0000000000 65535 f \n", totalObjects+1)
+	for id := 1; id <= totalObjects; id++ {
+		fmt.Fprintf(w, "%010d 00000 n \n", offsets[id])
+	}
+	fmt.Fprintf(w, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", totalObjects+1, xrefOffset)
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("не удалось записать PDF: %w", err)
+	}
+	return nil
+}
+
+// copyFile дописывает содержимое файла в w; размер должен совпасть с ожидаемым.
+func copyFile(w io.Writer, path string, size int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть %s: %w", path, err)
+	}
+	defer f.Close()
+
+	n, err := io.Copy(w, f)
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать %s: %w", path, err)
+	}
+	if n != size {
+		return fmt.Errorf("размер %s изменился во время записи PDF", path)
+	}
+	return nil
+}
+
+/*
+Возможные расширения:
+- Встраивание PNG с прозрачностью (FlateDecode + SMask)
+- Метаданные документа (Title, Author, CreationDate)
+- Оглавление (Outlines) по директориям
+*/
@@ -0,0 +1,138 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RawDecoderEnv - переменная окружения с путём к декодеру RAW.
+const RawDecoderEnv = "PHOTOCONVERTER_RAW_DECODER"
+
+// ErrNoRawDecoder - исходник в формате RAW, а декодер RAW не найден.
+// Пул воркеров пропускает такие файлы, а не считает их ошибкой.
+var ErrNoRawDecoder = errors.New("декодер RAW не найден (установите dcraw или libraw, либо укажите --raw-decoder-path)")
+
+// rawExtensions - расширения RAW форматов камер, которые vips не читает
+// напрямую и которые декодируются через dcraw/libraw.
+var rawExtensions = map[string]bool{
+	".arw": true, ".raw": true, ".cr2": true, ".cr3": true, ".nef": true,
+	".nrw": true, ".orf": true, ".rw2": true, ".raf": true, ".pef": true,
+	".srw": true, ".dng": true,
+}
+
+// IsRaw проверяет, является ли файл RAW снимком камеры (по расширению).
+func IsRaw(path string) bool {
+	return rawExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// FindRawDecoder ищет декодер RAW: customPath, переменная окружения
+// PHOTOCONVERTER_RAW_DECODER, затем dcraw и dcraw_emu (libraw) в PATH.
+// Возвращает пустую строку, если декодер не найден.
+func FindRawDecoder(customPath string) string {
+	for _, path := range []string{customPath, os.Getenv(RawDecoderEnv)} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	for _, name := range []string{"dcraw", "dcraw_emu"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// SetRawDecoderPath устанавливает путь к декодеру RAW (пусто = недоступен).
+func (c *VipsConverter) SetRawDecoderPath(path string) {
+	c.rawDecoderPath = path
+}
+
+// isLibraw проверяет, является ли декодер dcraw_emu из libraw.
+func (c *VipsConverter) isLibraw() bool {
+	return strings.HasPrefix(filepath.Base(c.rawDecoderPath), "dcraw_emu")
+}
+
+// rawDecoderArgs формирует аргументы декодера: баланс белого камеры (-w)
+// и 16-битный TIFF (-T -6). dcraw пишет результат в stdout (-c),
+// dcraw_emu - в файл, указанный после -Z.
+func (c *VipsConverter) rawDecoderArgs(srcPath, tiffPath string) []string {
+	args := []string{"-w", "-T", "-6"}
+	if c.isLibraw() {
+		return append(args, "-Z", tiffPath, srcPath)
+	}
+	return append(args, "-c", srcPath)
+}
+
+// decodeRaw декодирует RAW исходник во временный TIFF, который затем
+// обрабатывает vips. Вызывающий удаляет возвращённый файл.
+func (c *VipsConverter) decodeRaw(ctx context.Context, srcPath string) (string, error) {
+	if c.rawDecoderPath == "" {
+		// С --magick-fallback RAW конвертирует ImageMagick, предупреждать не о чем
+		if !c.cfg.MagickFallback {
+			c.rawWarning.Do(func() {
+				fmt.Fprintf(os.Stderr, "⚠️  %v: RAW файлы пропускаются\n", ErrNoRawDecoder)
+			})
+		}
+		return "", ErrNoRawDecoder
+	}
+
+	tmp, err := os.CreateTemp("", "photoconverter-raw-*.tiff")
+	if err != nil {
+		return "", fmt.Errorf("не удалось создать временный файл для RAW: %w", err)
+	}
+	tiffPath := tmp.Name()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.rawDecoderPath, c.rawDecoderArgs(srcPath, tiffPath)...)
+	if !c.isLibraw() {
+		cmd.Stdout = tmp
+	}
+	cmd.Stderr = &stderr
+	logCommand(ctx, cmd)
+	err = cmd.Run()
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		if info, statErr := os.Stat(tiffPath); statErr != nil || info.Size() == 0 {
+			err = fmt.Errorf("декодер не записал изображение")
+		}
+	}
+	if err != nil {
+		_ = os.Remove(tiffPath)
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("декодер RAW не уложился в таймаут %s (timed out)", c.timeout)
+		}
+		return "", fmt.Errorf("декодер RAW failed: %s", strings.TrimSpace(err.Error()+": "+stderr.String()))
+	}
+	return tiffPath, nil
+}
+
+// convertRaw декодирует RAW исходник в TIFF и конвертирует его через vips.
+// Метаданные результата (--copy-metadata) берутся из TIFF декодера.
+func (c *VipsConverter) convertRaw(ctx context.Context, srcPath, dstPath string) *ConvertResult {
+	start := time.Now()
+
+	tiffPath, err := c.decodeRaw(ctx, srcPath)
+	if err != nil {
+		return &ConvertResult{Success: false, Error: err, Duration: time.Since(start)}
+	}
+	defer os.Remove(tiffPath)
+
+	res := c.convertImage(ctx, tiffPath, "", dstPath)
+	res.Duration = time.Since(start)
+	return res
+}
@@ -0,0 +1,109 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// fakeDcrawScript имитирует dcraw -c: пишет TIFF в stdout.
+const fakeDcrawScript = `#!/bin/sh
+printf 'II*\000decoded'
+`
+
+// fakeDcrawEmuScript имитирует dcraw_emu -Z: пишет TIFF в файл после -Z.
+const fakeDcrawEmuScript = `#!/bin/sh
+while [ "$1" != "-Z" ]; do shift; done
+printf 'II*\000decoded' > "$2"
+`
+
+func TestConvert_RawDecoder(t *testing.T) {
+	tests := []struct {
+		name    string
+		decoder string
+		script  string
+	}{
+		{name: "dcraw", decoder: "dcraw", script: fakeDcrawScript},
+		{name: "libraw dcraw_emu", decoder: "dcraw_emu", script: fakeDcrawEmuScript},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			vips := filepath.Join(dir, "vips")
+			if err := os.WriteFile(vips, []byte(pagesVipsScript), 0755); err != nil {
+				t.Fatal(err)
+			}
+			decoder := filepath.Join(dir, tt.decoder)
+			if err := os.WriteFile(decoder, []byte(tt.script), 0755); err != nil {
+				t.Fatal(err)
+			}
+			logPath := filepath.Join(dir, "vips.log")
+			t.Setenv("VIPS_LOG", logPath)
+
+			src := filepath.Join(dir, "DSC0001.ARW")
+			if err := os.WriteFile(src, []byte("raw"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := config.DefaultConfig()
+			cfg.OutputFormat = config.FormatJPEG
+			cfg.NoVerify = true
+			cfg.RawDecoderPath = decoder
+
+			dst := filepath.Join(dir, "out", "DSC0001.jpg")
+			res := New(vips, cfg).Convert(context.Background(), src, dst)
+			if !res.Success {
+				t.Fatalf("Convert: %v", res.Error)
+			}
+
+			// vips получает TIFF декодера, а не RAW
+			data, _ := os.ReadFile(logPath)
+			input := strings.TrimSpace(string(data))
+			if !strings.HasSuffix(input, ".tiff") || strings.Contains(input, "\n") {
+				t.Errorf("вход vips = %q, want один временный .tiff", input)
+			}
+			if out, err := os.ReadFile(dst); err != nil || string(out) != "II*\x00decoded" {
+				t.Errorf("результат = %q, %v", out, err)
+			}
+			if _, err := os.Stat(input); !os.IsNotExist(err) {
+				t.Errorf("временный TIFF не удалён: %s", input)
+			}
+		})
+	}
+}
+
+func TestConvert_RawWithoutDecoder(t *testing.T) {
+	dir := t.TempDir()
+	vips := filepath.Join(dir, "vips")
+	if err := os.WriteFile(vips, []byte(pagesVipsScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	logPath := filepath.Join(dir, "vips.log")
+	t.Setenv("VIPS_LOG", logPath)
+	src := filepath.Join(dir, "shot.nef")
+	if err := os.WriteFile(src, []byte("raw"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.NoVerify = true
+	conv := New(vips, cfg)
+	conv.SetRawDecoderPath("")
+
+	res := conv.Convert(context.Background(), src, filepath.Join(dir, "out", "shot.webp"))
+	if res.Success || !errors.Is(res.Error, ErrNoRawDecoder) {
+		t.Fatalf("Convert = %v, %v, want ErrNoRawDecoder", res.Success, res.Error)
+	}
+	if !IsUnsupportedFormat(res) {
+		t.Error("RAW без декодера должен передаваться --magick-fallback")
+	}
+	if _, err := os.Stat(logPath); err == nil {
+		t.Error("vips не должен вызываться без декодера")
+	}
+}
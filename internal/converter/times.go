@@ -0,0 +1,35 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// FileTimes возвращает время доступа и модификации файла.
+// Где время доступа недоступно, вместо него возвращается время модификации.
+func FileTimes(path string) (atime, mtime time.Time, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return accessTime(info), info.ModTime(), nil
+}
+
+// PreserveTimes переносит на выходной файл dst время доступа и модификации
+// исходника src (--preserve-mtime).
+func PreserveTimes(src, dst string) error {
+	atime, mtime, err := FileTimes(src)
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать время исходника: %w", err)
+	}
+	if err := os.Chtimes(dst, atime, mtime); err != nil {
+		return fmt.Errorf("не удалось установить время результата: %w", err)
+	}
+	return nil
+}
+
+/*
+Возможные расширения:
+- Перенос времени создания (birth time) на macOS и Windows
+*/
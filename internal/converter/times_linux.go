@@ -0,0 +1,15 @@
+package converter
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// accessTime возвращает время доступа из stat.
+func accessTime(info os.FileInfo) time.Time {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Atim.Unix())
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package converter
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime возвращает время модификации: время доступа из stat не читается.
+func accessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
@@ -0,0 +1,105 @@
+package converter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// headerDimensionsRe выделяет размеры из вывода vipsheader:
+// "out.webp: 1920x1080 uchar, 3 bands, srgb, webpload".
+var headerDimensionsRe = regexp.MustCompile(`:\s*(\d+)x(\d+)\s`)
+
+// FindVipsheader ищет vipsheader рядом с vips, затем в PATH.
+// Возвращает пустую строку, если vipsheader не найден.
+func FindVipsheader(vipsPath string) string {
+	if vipsPath != "" {
+		// vips.exe -> vipsheader.exe
+		name := strings.Replace(filepath.Base(vipsPath), "vips", "vipsheader", 1)
+		candidate := filepath.Join(filepath.Dir(vipsPath), name)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	if path, err := exec.LookPath("vipsheader"); err == nil {
+		return path
+	}
+	return ""
+}
+
+// VerifyOutput проверяет, что результат конвертации - непустой читаемый файл
+// изображения с ненулевыми размерами (через vipsheader). Без vipsheader
+// проверяется только размер файла.
+func (c *VipsConverter) VerifyOutput(ctx context.Context, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("проверка результата: %w", err)
+	}
+	if info.Size() == 0 {
+		return fmt.Errorf("проверка результата: vips создал пустой файл")
+	}
+
+	if c.vipsheaderPath == "" {
+		c.verifyWarning.Do(func() {
+			fmt.Fprintln(os.Stderr, "⚠️  vipsheader не найден: результат проверяется только на непустой размер")
+		})
+		return nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.vipsheaderPath, path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	logCommand(ctx, cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("проверка результата: vipsheader не смог прочитать файл: %s",
+			strings.TrimSpace(err.Error()+": "+stderr.String()))
+	}
+
+	m := headerDimensionsRe.FindStringSubmatch(stdout.String())
+	if m == nil {
+		return fmt.Errorf("проверка результата: не удалось разобрать вывод vipsheader: %s",
+			strings.TrimSpace(stdout.String()))
+	}
+	width, _ := strconv.Atoi(m[1])
+	height, _ := strconv.Atoi(m[2])
+	if width == 0 || height == 0 {
+		return fmt.Errorf("проверка результата: нулевые размеры изображения %dx%d", width, height)
+	}
+	return nil
+}
+
+// ImageSize возвращает размеры изображения по заголовку (vipsheader),
+// не декодируя пиксели.
+func (c *VipsConverter) ImageSize(ctx context.Context, path string) (width, height int, err error) {
+	if c.vipsheaderPath == "" {
+		return 0, 0, fmt.Errorf("vipsheader не найден")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.vipsheaderPath, path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	logCommand(ctx, cmd)
+	if err := cmd.Run(); err != nil {
+		return 0, 0, fmt.Errorf("vipsheader: %s", strings.TrimSpace(err.Error()+": "+stderr.String()))
+	}
+	m := headerDimensionsRe.FindStringSubmatch(stdout.String())
+	if m == nil {
+		return 0, 0, fmt.Errorf("не удалось разобрать вывод vipsheader: %s", strings.TrimSpace(stdout.String()))
+	}
+	width, _ = strconv.Atoi(m[1])
+	height, _ = strconv.Atoi(m[2])
+	return width, height, nil
+}
+
+/*
+Возможные расширения:
+- Сравнение размеров результата с ожидаемыми после resize
+- Полное декодирование (vips avg) для выявления повреждённых данных внутри файла
+*/
@@ -4,18 +4,21 @@ package converter
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/artemshloyda/photoconverter/internal/config"
 )
 
-// Converter выполняет конвертацию изображений через внешний vips.
-type Converter struct {
+// VipsConverter выполняет конвертацию изображений через внешний vips.
+type VipsConverter struct {
 	// vipsPath - путь к бинарнику vips.
 	vipsPath string
 
@@ -24,6 +27,25 @@ type Converter struct {
 
 	// timeout - таймаут на конвертацию одного файла.
 	timeout time.Duration
+
+	// exiftoolPath - путь к exiftool для пост-обработки метаданных (пусто = недоступен).
+	exiftoolPath string
+
+	// vipsheaderPath - путь к vipsheader для проверки результата (пусто = недоступен).
+	vipsheaderPath string
+
+	// verifyWarning - однократное предупреждение об отсутствии vipsheader.
+	verifyWarning sync.Once
+
+	// pagesWarning - однократное предупреждение, что без vipsheader
+	// изображения многокадрового файла не перечислить.
+	pagesWarning sync.Once
+
+	// rawDecoderPath - путь к декодеру RAW (пусто = недоступен).
+	rawDecoderPath string
+
+	// rawWarning - однократное предупреждение об отсутствии декодера RAW.
+	rawWarning sync.Once
 }
 
 // ConvertResult содержит результат конвертации.
@@ -40,26 +62,198 @@ type ConvertResult struct {
 	// Stderr - вывод stderr от vips.
 	Stderr string
 
+	// Command - командная строка vips (или ImageMagick), завершившейся
+	// ошибкой, для воспроизведения вручную (пусто при успехе).
+	Command string
+
 	// Duration - время конвертации.
 	Duration time.Duration
+
+	// Quality - качество, с которым записан файл при подгонке под
+	// MaxFileSize (0, если лимит не задан).
+	Quality int
+
+	// Extra - дополнительные выходные файлы (остальные изображения
+	// многокадрового HEIC/HEIF с AllImages).
+	Extra []string
+}
+
+// minFitQuality - нижняя граница качества при подгонке под MaxFileSize.
+const minFitQuality = 20
+
+// New создаёт новый VipsConverter.
+// Таймаут на файл берётся из cfg.Timeout (по умолчанию 5 минут).
+func New(vipsPath string, cfg *config.Config) *VipsConverter {
+	timeout := 5 * time.Minute
+	if cfg.Timeout > 0 {
+		timeout = cfg.Timeout
+	}
+	return &VipsConverter{
+		vipsPath:       vipsPath,
+		cfg:            cfg,
+		timeout:        timeout,
+		exiftoolPath:   FindExiftool(),
+		vipsheaderPath: FindVipsheader(vipsPath),
+		rawDecoderPath: FindRawDecoder(cfg.RawDecoderPath),
+	}
 }
 
-// New создаёт новый Converter.
-func New(vipsPath string, cfg *config.Config) *Converter {
-	return &Converter{
-		vipsPath: vipsPath,
-		cfg:      cfg,
-		timeout:  5 * time.Minute, // Таймаут по умолчанию
+// WithConfig возвращает конвертер с тем же vips/exiftool и таймаутом,
+// но другой конфигурацией (например, для варианта --multi-preset).
+func (c *VipsConverter) WithConfig(cfg *config.Config) Converter {
+	return &VipsConverter{
+		vipsPath:       c.vipsPath,
+		cfg:            cfg,
+		timeout:        c.timeout,
+		exiftoolPath:   c.exiftoolPath,
+		vipsheaderPath: c.vipsheaderPath,
+		rawDecoderPath: c.rawDecoderPath,
 	}
 }
 
+// TempPath возвращает путь временного файла, в который пишется результат
+// до атомарного переименования в dstPath (расширение сохраняется: vips
+// определяет формат по нему).
+func TempPath(dstPath string) string {
+	ext := filepath.Ext(dstPath)
+	return strings.TrimSuffix(dstPath, ext) + ".converting" + ext
+}
+
 // SetTimeout устанавливает таймаут на конвертацию.
-func (c *Converter) SetTimeout(d time.Duration) {
+func (c *VipsConverter) SetTimeout(d time.Duration) {
 	c.timeout = d
 }
 
+// thumbnailArgs формирует аргументы vips thumbnail:
+// vips thumbnail input output width [--height=height] [--crop=centre|attention].
+func (c *VipsConverter) thumbnailArgs(srcPath, outWithParams string) []string {
+	args := []string{"thumbnail", srcPath, outWithParams}
+
+	if c.cfg.CropAspect != "" {
+		// Заполняем прямоугольник нужных пропорций целиком, лишнее обрезается
+		// по центру или по заметной области (vips smartcrop)
+		width, height := c.cfg.CropSize()
+		return append(args, fmt.Sprintf("%d", width), fmt.Sprintf("--height=%d", height),
+			"--crop="+c.cfg.EffectiveCropMode())
+	}
+
+	if c.cfg.SmartCrop && c.cfg.MaxWidth > 0 && c.cfg.MaxHeight > 0 {
+		// --smart-crop без --crop: превью ровно MaxWidth x MaxHeight,
+		// обрезка вокруг заметной области
+		return append(args, fmt.Sprintf("%d", c.cfg.MaxWidth), fmt.Sprintf("--height=%d", c.cfg.MaxHeight),
+			"--crop="+config.CropAttention)
+	}
+
+	// Определяем размер для thumbnail
+	// vips thumbnail использует width как основной параметр
+	width := c.cfg.MaxWidth
+	if width == 0 {
+		width = 100000 // Большое число = без ограничения по ширине
+	}
+	args = append(args, fmt.Sprintf("%d", width))
+
+	if c.cfg.MaxHeight > 0 {
+		args = append(args, fmt.Sprintf("--height=%d", c.cfg.MaxHeight))
+	}
+
+	return args
+}
+
 // Convert конвертирует файл из srcPath в dstPath.
-func (c *Converter) Convert(ctx context.Context, srcPath, dstPath string) *ConvertResult {
+// С MaxFileSize результат, превышающий лимит, перекодируется с меньшим качеством.
+// С AllImages остальные изображения многокадрового HEIC/HEIF пишутся рядом
+// с dstPath (см. convertImages), с PreserveAnimation анимация GIF/WebP
+// загружается целиком (см. animationLoad). RAW исходники предварительно
+// декодируются в TIFF (см. convertRaw).
+func (c *VipsConverter) Convert(ctx context.Context, srcPath, dstPath string) *ConvertResult {
+	if IsRaw(srcPath) {
+		return c.convertRaw(ctx, srcPath, dstPath)
+	}
+	res := c.convertImage(ctx, srcPath, c.animationLoad(ctx, srcPath), dstPath)
+	if res.Success && c.cfg.AllImages && isHEIF(srcPath) {
+		return c.convertImages(ctx, srcPath, dstPath, res)
+	}
+	return res
+}
+
+// convertImage конвертирует одно изображение srcPath с параметрами загрузки
+// load (например "[page=1]"; пусто = по умолчанию) в dstPath.
+func (c *VipsConverter) convertImage(ctx context.Context, srcPath, load, dstPath string) *ConvertResult {
+	if c.cfg.MaxFileSize > 0 && c.cfg.HasQuality() {
+		return c.convertToSize(ctx, srcPath, load, dstPath)
+	}
+	return c.convert(ctx, srcPath, load, dstPath, c.cfg.VipsOutputSuffix())
+}
+
+// convertToSize конвертирует файл и, если он больше MaxFileSize, двоичным
+// поиском подбирает наибольшее качество в [minFitQuality, Quality), при
+// котором файл укладывается в лимит. Если не укладывается и минимальное
+// качество, остаётся результат с минимальным качеством и выводится предупреждение.
+func (c *VipsConverter) convertToSize(ctx context.Context, srcPath, load, dstPath string) *ConvertResult {
+	start := time.Now()
+
+	// encode записывает dstPath с качеством q и возвращает размер результата
+	encode := func(q int) (*ConvertResult, int64) {
+		qcfg := *c.cfg
+		qcfg.Quality = q
+		res := c.convert(ctx, srcPath, load, dstPath, qcfg.VipsOutputSuffix())
+		if !res.Success {
+			return res, 0
+		}
+		info, err := os.Stat(dstPath)
+		if err != nil {
+			return &ConvertResult{Success: false, Error: fmt.Errorf("не удалось проверить размер %s: %w", dstPath, err)}, 0
+		}
+		res.Quality = q
+		return res, info.Size()
+	}
+
+	res, size := encode(c.cfg.Quality)
+	if !res.Success || size <= c.cfg.MaxFileSize || c.cfg.Quality <= minFitQuality {
+		res.Duration = time.Since(start)
+		return c.warnOversize(res, dstPath, size)
+	}
+
+	// Текущий файл на диске записан с качеством written
+	lo, hi := minFitQuality, c.cfg.Quality-1
+	best, written := 0, c.cfg.Quality
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		res, size = encode(mid)
+		if !res.Success {
+			res.Duration = time.Since(start)
+			return res
+		}
+		written = mid
+		if size <= c.cfg.MaxFileSize {
+			best, lo = mid, mid+1
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	switch {
+	case best == 0 && written != minFitQuality:
+		res, size = encode(minFitQuality)
+	case best != 0 && written != best:
+		res, size = encode(best)
+	}
+	res.Duration = time.Since(start)
+	return c.warnOversize(res, dstPath, size)
+}
+
+// warnOversize предупреждает, если успешный результат не уложился в MaxFileSize.
+func (c *VipsConverter) warnOversize(res *ConvertResult, dstPath string, size int64) *ConvertResult {
+	if res.Success && size > c.cfg.MaxFileSize {
+		fmt.Fprintf(os.Stderr, "⚠️  %s: %s при качестве %d, больше лимита %s\n",
+			dstPath, config.FormatByteSize(size), res.Quality, config.FormatByteSize(c.cfg.MaxFileSize))
+	}
+	return res
+}
+
+// convert выполняет одну конвертацию srcPath в dstPath с параметрами
+// загрузки load (например "[page=1]") и выхода suffix (например "[Q=80,strip]").
+func (c *VipsConverter) convert(ctx context.Context, srcPath, load, dstPath, suffix string) *ConvertResult {
 	start := time.Now()
 
 	// Создаём директорию для выходного файла
@@ -72,15 +266,28 @@ func (c *Converter) Convert(ctx context.Context, srcPath, dstPath string) *Conve
 		}
 	}
 
+	// --embed-srgb: результат исходника без ICC профиля получает профиль sRGB
+	if c.cfg.EmbedSRGB && c.lacksProfile(ctx, srcPath) {
+		suffix = withSaveOption(suffix, "profile=srgb")
+	}
+
 	// Атомарная запись: пишем во временный файл с правильным расширением,
 	// затем переименовываем. vips определяет формат по расширению файла.
-	dstExt := filepath.Ext(dstPath)
-	dstBase := strings.TrimSuffix(dstPath, dstExt)
-	tmpPath := dstBase + ".converting" + dstExt
+	tmpPath := TempPath(dstPath)
 
 	// Формируем выходной путь с параметрами vips
 	// Например: output.webp[Q=80,strip]
-	outWithParams := tmpPath + c.cfg.VipsOutputSuffix()
+	outWithParams := tmpPath + suffix
+
+	// С операциями после resize (резкость, тонирование) основной шаг пишет
+	// промежуточный файл без потерь (формат vips .v), а сохранение
+	// с параметрами делает последняя операция
+	ops := c.postOps()
+	var stagePath string
+	if len(ops) > 0 {
+		stagePath = strings.TrimSuffix(tmpPath, filepath.Ext(tmpPath)) + ".stage.v"
+		outWithParams = stagePath
+	}
 
 	// Создаём контекст с таймаутом
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
@@ -88,27 +295,12 @@ func (c *Converter) Convert(ctx context.Context, srcPath, dstPath string) *Conve
 
 	// Выбираем команду: thumbnail (с resize) или copy (без resize)
 	var cmd *exec.Cmd
-	if c.cfg.MaxWidth > 0 || c.cfg.MaxHeight > 0 {
+	if c.resizes() {
 		// Используем vips thumbnail для resize
-		// vips thumbnail input output width --height=height
-		args := []string{"thumbnail", srcPath, outWithParams}
-
-		// Определяем размер для thumbnail
-		// vips thumbnail использует width как основной параметр
-		width := c.cfg.MaxWidth
-		if width == 0 {
-			width = 100000 // Большое число = без ограничения по ширине
-		}
-		args = append(args, fmt.Sprintf("%d", width))
-
-		if c.cfg.MaxHeight > 0 {
-			args = append(args, fmt.Sprintf("--height=%d", c.cfg.MaxHeight))
-		}
-
-		cmd = exec.CommandContext(ctx, c.vipsPath, args...)
+		cmd = exec.CommandContext(ctx, c.vipsPath, c.thumbnailArgs(srcPath+load, outWithParams)...)
 	} else {
 		// Обычная конвертация без resize
-		cmd = exec.CommandContext(ctx, c.vipsPath, "copy", srcPath, outWithParams)
+		cmd = exec.CommandContext(ctx, c.vipsPath, "copy", srcPath+load, outWithParams)
 	}
 
 	var stderr bytes.Buffer
@@ -120,7 +312,18 @@ func (c *Converter) Convert(ctx context.Context, srcPath, dstPath string) *Conve
 		cmd.Env = append(cmd.Env, "VIPS_OPENCL=1")
 	}
 
+	logCommand(ctx, cmd)
 	err := cmd.Run()
+	if err != nil {
+		err = &CommandError{Cmdline: formatCommandLine(cmd.Args), Err: err}
+	}
+
+	if stagePath != "" {
+		if err == nil {
+			err = c.applyOps(ctx, ops, stagePath, tmpPath+suffix, &stderr)
+		}
+		_ = os.Remove(stagePath)
+	}
 
 	// Применяем цветовой профиль если указан
 	if err == nil && c.cfg.ColorProfile != "" {
@@ -140,15 +343,33 @@ func (c *Converter) Convert(ctx context.Context, srcPath, dstPath string) *Conve
 		// Удаляем временный файл при ошибке
 		_ = os.Remove(tmpPath)
 
+		// Команда, завершившаяся ошибкой: основной шаг или операция цепочки
+		var command string
+		var cmdErr *CommandError
+		if errors.As(err, &cmdErr) {
+			command = cmdErr.Cmdline
+		}
+
+		if ctx.Err() == context.DeadlineExceeded {
+			return &ConvertResult{
+				Success:  false,
+				Error:    withCommand(fmt.Errorf("vips не уложился в таймаут %s (timed out)", c.timeout), command),
+				Stderr:   stderr.String(),
+				Command:  command,
+				Duration: duration,
+			}
+		}
+
 		errMsg := err.Error()
 		if stderr.Len() > 0 {
-			errMsg = fmt.Sprintf("%s: %s", err.Error(), stderr.String())
+			errMsg = fmt.Sprintf("%s: %s", err.Error(), strings.TrimSpace(stderr.String()))
 		}
 
 		return &ConvertResult{
 			Success:  false,
-			Error:    fmt.Errorf("vips copy failed: %s", errMsg),
+			Error:    withCommand(fmt.Errorf("vips copy failed: %s", errMsg), command),
 			Stderr:   stderr.String(),
+			Command:  command,
 			Duration: duration,
 		}
 	}
@@ -163,6 +384,29 @@ func (c *Converter) Convert(ctx context.Context, srcPath, dstPath string) *Conve
 		}
 	}
 
+	// Пост-обработка метаданных (exiftool)
+	if err := c.postProcessMetadata(ctx, srcPath, tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return &ConvertResult{
+			Success:  false,
+			Error:    err,
+			Duration: time.Since(start),
+		}
+	}
+
+	// Проверяем, что vips действительно записал читаемое изображение
+	if !c.cfg.NoVerify {
+		if err := c.VerifyOutput(ctx, tmpPath); err != nil {
+			_ = os.Remove(tmpPath)
+			return &ConvertResult{
+				Success:  false,
+				Error:    err,
+				Stderr:   stderr.String(),
+				Duration: time.Since(start),
+			}
+		}
+	}
+
 	// Переименовываем временный файл в финальный
 	if err := os.Rename(tmpPath, dstPath); err != nil {
 		_ = os.Remove(tmpPath)
@@ -181,8 +425,149 @@ func (c *Converter) Convert(ctx context.Context, srcPath, dstPath string) *Conve
 	}
 }
 
+// sepiaTone - множители каналов R, G, B для сепии поверх оттенков серого:
+// белый становится тёплым светло-коричневым.
+const sepiaTone = "1.0 0.89 0.71"
+
+// sepiaConstants возвращает множители и смещения vips linear для сепии
+// изображения из bands полос: vips требует одну константу или по одной
+// на полосу. R, G, B умножаются на sepiaTone, остальные полосы (альфа-канал)
+// проходят без изменений.
+func sepiaConstants(bands int) (mul, add string) {
+	mul, add = sepiaTone, "0 0 0"
+	for i := 3; i < bands; i++ {
+		mul += " 1"
+		add += " 0"
+	}
+	return mul, add
+}
+
+// mmPerInch - миллиметров в дюйме: vips хранит разрешение в пикселях на мм.
+const mmPerInch = 25.4
+
+// vipsOp формирует аргументы операции vips, читающей in и пишущей out.
+// Вызывается непосредственно перед запуском, когда in уже записан.
+type vipsOp func(in, out string) ([]string, error)
+
+// resizes проверяет, выполняется ли resize (шаг vips thumbnail).
+func (c *VipsConverter) resizes() bool {
+	return c.cfg.MaxWidth > 0 || c.cfg.MaxHeight > 0
+}
+
+// postOps возвращает операции после resize в порядке выполнения:
+// резкость (только после resize, если не SharpenAlways), размытие
+// (vips gaussblur) или пикселизация, затем оттенки серого (vips colourspace
+// b-w) или сепия - серое возвращается в sRGB (три равных канала), и каналы
+// умножаются на sepiaTone (vips linear). Последней DPI записывает плотность
+// (vips copy --xres/--yres в пикселях на миллиметр).
+func (c *VipsConverter) postOps() []vipsOp {
+	var ops []vipsOp
+
+	if c.cfg.Sharpen > 0 && (c.resizes() || c.cfg.SharpenAlways) {
+		sigma := "--sigma=" + strconv.FormatFloat(c.cfg.Sharpen, 'f', -1, 64)
+		ops = append(ops, func(in, out string) ([]string, error) {
+			return []string{"sharpen", in, out, sigma}, nil
+		})
+	}
+
+	switch {
+	case c.cfg.Blur > 0:
+		sigma := strconv.FormatFloat(c.cfg.Blur, 'f', -1, 64)
+		ops = append(ops, func(in, out string) ([]string, error) {
+			return []string{"gaussblur", in, out, sigma}, nil
+		})
+	case c.cfg.Pixelate > 1:
+		ops = append(ops, pixelateOps(c.cfg.Pixelate)...)
+	}
+
+	if c.cfg.Grayscale || c.cfg.Sepia {
+		ops = append(ops, func(in, out string) ([]string, error) {
+			return []string{"colourspace", in, out, "b-w"}, nil
+		})
+	}
+	if c.cfg.Sepia {
+		ops = append(ops,
+			func(in, out string) ([]string, error) {
+				return []string{"colourspace", in, out, "srgb"}, nil
+			},
+			func(in, out string) ([]string, error) {
+				_, _, bands, err := readVipsHeader(in)
+				if err != nil {
+					return nil, err
+				}
+				mul, add := sepiaConstants(bands)
+				return []string{"linear", in, out, mul, add, "--uchar"}, nil
+			})
+	}
+
+	if c.cfg.DPI > 0 {
+		res := strconv.FormatFloat(float64(c.cfg.DPI)/mmPerInch, 'f', -1, 64)
+		ops = append(ops, func(in, out string) ([]string, error) {
+			return []string{"copy", in, out, "--xres=" + res, "--yres=" + res}, nil
+		})
+	}
+
+	return ops
+}
+
+// pixelateOps возвращает операции пикселизации блоками block x block
+// с сохранением размера: изображение дополняется копией краёв до кратного
+// блоку размера (embed), усредняется по блокам (shrink), блоки растягиваются
+// обратно (zoom), и дополнение обрезается (crop).
+func pixelateOps(block int) []vipsOp {
+	var width, height int
+	n := strconv.Itoa(block)
+	return []vipsOp{
+		func(in, out string) ([]string, error) {
+			var err error
+			if width, height, err = readVipsSize(in); err != nil {
+				return nil, err
+			}
+			padW := (width + block - 1) / block * block
+			padH := (height + block - 1) / block * block
+			return []string{"embed", in, out, "0", "0", strconv.Itoa(padW), strconv.Itoa(padH), "--extend=copy"}, nil
+		},
+		func(in, out string) ([]string, error) {
+			return []string{"shrink", in, out, n, n}, nil
+		},
+		func(in, out string) ([]string, error) {
+			return []string{"zoom", in, out, n, n}, nil
+		},
+		func(in, out string) ([]string, error) {
+			return []string{"crop", in, out, "0", "0", strconv.Itoa(width), strconv.Itoa(height)}, nil
+		},
+	}
+}
+
+// applyOps выполняет ops над stagePath по цепочке промежуточных файлов .v,
+// последняя операция пишет outWithParams. Stderr команд дописывается в stderr.
+func (c *VipsConverter) applyOps(ctx context.Context, ops []vipsOp, stagePath, outWithParams string, stderr *bytes.Buffer) error {
+	in := stagePath
+	for i, op := range ops {
+		out := outWithParams
+		if i < len(ops)-1 {
+			out = fmt.Sprintf("%s.%d.v", strings.TrimSuffix(stagePath, ".v"), i)
+			defer func(path string) { _ = os.Remove(path) }(out)
+		}
+
+		args, err := op(in, out)
+		if err != nil {
+			return err
+		}
+		cmd := exec.CommandContext(ctx, c.vipsPath, args...)
+		cmd.Env = os.Environ()
+		cmd.Stderr = stderr
+		logCommand(ctx, cmd)
+		if err := cmd.Run(); err != nil {
+			return &CommandError{Cmdline: formatCommandLine(cmd.Args), Err: err}
+		}
+		in = out
+	}
+	return nil
+}
+
 // applyColorProfile применяет цветовой профиль к изображению.
-func (c *Converter) applyColorProfile(ctx context.Context, imagePath string) error {
+func (c *VipsConverter) applyColorProfile(ctx context.Context, imagePath string) error {
 	// Определяем intent для цветового профиля
 	// vips icc_transform для конвертации профилей
 	var profileName string
@@ -214,6 +599,7 @@ func (c *Converter) applyColorProfile(ctx context.Context, imagePath string) err
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
+	logCommand(ctx, cmd)
 	if err := cmd.Run(); err != nil {
 		// Если icc_transform не поддерживается, пропускаем
 		// vips может не иметь встроенных профилей
@@ -229,9 +615,24 @@ func (c *Converter) applyColorProfile(ctx context.Context, imagePath string) err
 	return nil
 }
 
+// lacksProfile проверяет по заголовку (vipsheader), что у изображения нет
+// встроенного ICC профиля. Если заголовок не прочитан, считается, что профиль есть.
+func (c *VipsConverter) lacksProfile(ctx context.Context, path string) bool {
+	info, err := ReadImageInfo(ctx, c.vipsheaderPath, path)
+	return err == nil && info.ProfileSize == 0
+}
+
+// withSaveOption добавляет параметр сохранения vips к суффиксу "[Q=80,strip]".
+func withSaveOption(suffix, option string) string {
+	if suffix == "" {
+		return "[" + option + "]"
+	}
+	return strings.TrimSuffix(suffix, "]") + "," + option + "]"
+}
+
 // applyWatermark накладывает водяной знак на изображение.
 // Возвращает nil если успешно, или ConvertResult с ошибкой.
-func (c *Converter) applyWatermark(ctx context.Context, imagePath string) *ConvertResult {
+func (c *VipsConverter) applyWatermark(ctx context.Context, imagePath string) *ConvertResult {
 	// Определяем gravity для позиции
 	gravity := "south-east" // bottomright по умолчанию
 	switch c.cfg.WatermarkPosition {
@@ -281,6 +682,7 @@ func (c *Converter) applyWatermark(ctx context.Context, imagePath string) *Conve
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
+	logCommand(ctx, cmd)
 	if err := cmd.Run(); err != nil {
 		return &ConvertResult{
 			Success: false,
@@ -302,48 +704,28 @@ func (c *Converter) applyWatermark(ctx context.Context, imagePath string) *Conve
 }
 
 // BuildDstPath строит путь к выходному файлу.
-func (c *Converter) BuildDstPath(srcPath string) string {
-	// Получаем относительный путь от входной директории
-	relPath, err := filepath.Rel(c.cfg.InputDir, srcPath)
-	if err != nil {
-		// Fallback на имя файла
-		relPath = filepath.Base(srcPath)
-	}
-
-	if c.cfg.KeepTree {
-		// Сохраняем структуру директорий
-		// Меняем расширение на выходной формат
-		ext := filepath.Ext(relPath)
-		relPath = strings.TrimSuffix(relPath, ext) + "." + string(c.cfg.OutputFormat)
-		return filepath.Join(c.cfg.OutputDir, relPath)
-	}
+func (c *VipsConverter) BuildDstPath(srcPath string) string {
+	return dstPaths{c.cfg}.BuildDstPath(srcPath)
+}
 
-	// Плоская структура: только имя файла
-	baseName := filepath.Base(srcPath)
-	ext := filepath.Ext(baseName)
-	baseName = strings.TrimSuffix(baseName, ext) + "." + string(c.cfg.OutputFormat)
-	return filepath.Join(c.cfg.OutputDir, baseName)
+// BuildDstPathRel строит путь к выходному файлу по пути относительно входа.
+func (c *VipsConverter) BuildDstPathRel(relPath string) string {
+	return dstPaths{c.cfg}.BuildDstPathRel(relPath)
 }
 
 // BuildDstPathDedup строит путь для режима dedup (по хэшу содержимого).
-func (c *Converter) BuildDstPathDedup(contentSHA256 string) string {
-	// Используем первые 16 символов хэша как имя файла
-	shortHash := contentSHA256
-	if len(shortHash) > 16 {
-		shortHash = shortHash[:16]
-	}
-
-	fileName := shortHash + "." + string(c.cfg.OutputFormat)
-	return filepath.Join(c.cfg.OutputDir, fileName)
+func (c *VipsConverter) BuildDstPathDedup(contentSHA256 string) string {
+	return dstPaths{c.cfg}.BuildDstPathDedup(contentSHA256)
 }
 
-// CheckVipsHealth проверяет работоспособность vips.
-func (c *Converter) CheckVipsHealth() error {
+// CheckHealth проверяет работоспособность vips и наличие exiftool,
+// если он нужен (--copy-metadata, --strip-gps).
+func (c *VipsConverter) CheckHealth() error {
 	cmd := exec.Command(c.vipsPath, "--version")
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("vips не работает: %w", err)
 	}
-	return nil
+	return c.checkExiftool()
 }
 
 /*
@@ -0,0 +1,991 @@
+package converter
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+func TestThumbnailArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		preset string
+		width  int
+		height int
+		want   []string
+	}{
+		{
+			name:   "instagram - квадрат с обрезкой",
+			preset: "instagram",
+			want:   []string{"thumbnail", "in.jpg", "out.jpg", "1080", "--height=1080", "--crop=centre"},
+		},
+		{
+			name:   "telegram - вписывание по длинной стороне",
+			preset: "telegram",
+			want:   []string{"thumbnail", "in.jpg", "out.jpg", "1280", "--height=1280"},
+		},
+		{
+			name:  "только высота",
+			width: 0, height: 600,
+			want: []string{"thumbnail", "in.jpg", "out.jpg", "100000", "--height=600"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			if tt.preset != "" {
+				cfg.ApplyPreset(tt.preset)
+			} else {
+				cfg.MaxWidth, cfg.MaxHeight = tt.width, tt.height
+			}
+			got := New("vips", cfg).thumbnailArgs("in.jpg", "out.jpg")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("thumbnailArgs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThumbnailArgs_CropAspect(t *testing.T) {
+	tests := []struct {
+		name          string
+		aspect, mode  string
+		width, height int
+		want          []string
+	}{
+		{
+			name:   "квадрат по меньшей стороне",
+			aspect: "1:1", width: 800, height: 500,
+			want: []string{"500", "--height=500", "--crop=centre"},
+		},
+		{
+			name:   "16:9 по ширине",
+			aspect: "16:9", mode: config.CropAttention, width: 1920,
+			want: []string{"1920", "--height=1080", "--crop=attention"},
+		},
+		{
+			name:   "16:9 вписан в рамку",
+			aspect: "16:9", width: 1920, height: 720,
+			want: []string{"1280", "--height=720", "--crop=centre"},
+		},
+		{
+			name:   "3:4 по высоте",
+			aspect: "3:4", height: 800,
+			want: []string{"600", "--height=800", "--crop=centre"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.CropAspect, cfg.CropMode = tt.aspect, tt.mode
+			cfg.MaxWidth, cfg.MaxHeight = tt.width, tt.height
+
+			got := New("vips", cfg).thumbnailArgs("in.jpg", "out.jpg")
+			want := append([]string{"thumbnail", "in.jpg", "out.jpg"}, tt.want...)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("thumbnailArgs = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestConvert_CropAspectRealVips(t *testing.T) {
+	vipsPath, err := exec.LookPath("vips")
+	if err != nil {
+		t.Skip("vips не установлен")
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "landscape.jpg")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Источник 4:3
+	if err := jpeg.Encode(f, image.NewRGBA(image.Rect(0, 0, 400, 300)), nil); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.OutputFormat = config.FormatJPEG
+	cfg.CropAspect = "1:1"
+	cfg.CropMode = config.CropAttention
+	cfg.MaxWidth = 200
+
+	dst := filepath.Join(dir, "square.jpg")
+	if res := New(vipsPath, cfg).Convert(context.Background(), src, dst); !res.Success {
+		t.Fatalf("Convert: %v", res.Error)
+	}
+
+	out, err := os.Open(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	ic, err := jpeg.DecodeConfig(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ic.Width != 200 || ic.Height != 200 {
+		t.Errorf("размер результата = %dx%d, want 200x200", ic.Width, ic.Height)
+	}
+}
+
+func TestThumbnailArgs_SmartCrop(t *testing.T) {
+	tests := []struct {
+		name          string
+		aspect        string
+		width, height int
+		want          []string
+	}{
+		{
+			name:  "превью заполняет рамку",
+			width: 300, height: 200,
+			want: []string{"300", "--height=200", "--crop=attention"},
+		},
+		{
+			name:   "с --crop как attention",
+			aspect: "1:1", width: 800, height: 500,
+			want: []string{"500", "--height=500", "--crop=attention"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.SmartCrop = true
+			cfg.CropAspect = tt.aspect
+			cfg.MaxWidth, cfg.MaxHeight = tt.width, tt.height
+
+			got := New("vips", cfg).thumbnailArgs("in.jpg", "out.jpg")
+			want := append([]string{"thumbnail", "in.jpg", "out.jpg"}, tt.want...)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("thumbnailArgs = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestConvert_SmartCropRealVips(t *testing.T) {
+	vipsPath, err := exec.LookPath("vips")
+	if err != nil {
+		t.Skip("vips не установлен")
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "subject.png")
+	// Светлый «объект» у правого края тёмного кадра 400x300
+	img := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	for y := 100; y < 200; y++ {
+		for x := 320; x < 390; x++ {
+			img.Set(x, y, color.RGBA{R: 255, G: 200, B: 40, A: 255})
+		}
+	}
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.OutputFormat = config.FormatPNG
+	cfg.SmartCrop = true
+	cfg.MaxWidth, cfg.MaxHeight = 120, 150
+
+	dst := filepath.Join(dir, "thumb.png")
+	if res := New(vipsPath, cfg).Convert(context.Background(), src, dst); !res.Success {
+		t.Fatalf("Convert: %v", res.Error)
+	}
+
+	out, err := os.Open(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	ic, err := png.DecodeConfig(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ic.Width != 120 || ic.Height != 150 {
+		t.Errorf("размер результата = %dx%d, want 120x150", ic.Width, ic.Height)
+	}
+}
+
+// sizedVipsScript имитирует vips: размер результата - Q*1000 байт.
+const sizedVipsScript = `#!/bin/sh
+q=$(echo "$3" | sed -n 's/.*Q=\([0-9]*\).*/\1/p')
+out="${3%%\[*}"
+head -c $((q * 1000)) /dev/zero > "$out"
+`
+
+func TestConvert_MaxFileSize(t *testing.T) {
+	dir := t.TempDir()
+	vips := filepath.Join(dir, "vips")
+	if err := os.WriteFile(vips, []byte(sizedVipsScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "large.jpg")
+	if err := os.WriteFile(src, make([]byte, 1<<20), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		limit       int64
+		wantQuality int
+		wantSize    int64
+	}{
+		{name: "укладывается сразу", limit: 100000, wantQuality: 90, wantSize: 90000},
+		{name: "снижение качества", limit: 45500, wantQuality: 45, wantSize: 45000},
+		{name: "не укладывается и при минимуме", limit: 1000, wantQuality: minFitQuality, wantSize: minFitQuality * 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.OutputFormat = config.FormatJPEG
+			cfg.Quality = 90
+			cfg.NoVerify = true
+			cfg.MaxFileSize = tt.limit
+
+			dst := filepath.Join(t.TempDir(), "large.jpg")
+			res := New(vips, cfg).Convert(context.Background(), src, dst)
+			if !res.Success {
+				t.Fatalf("Convert: %v", res.Error)
+			}
+			if res.Quality != tt.wantQuality {
+				t.Errorf("Quality = %d, want %d", res.Quality, tt.wantQuality)
+			}
+			info, err := os.Stat(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Size() != tt.wantSize {
+				t.Errorf("размер = %d, want %d", info.Size(), tt.wantSize)
+			}
+			if tt.wantQuality != minFitQuality && info.Size() > tt.limit {
+				t.Errorf("размер %d больше лимита %d", info.Size(), tt.limit)
+			}
+		})
+	}
+}
+
+// loggingVipsScript имитирует vips: пишет имя операции в $VIPS_LOG и копирует
+// вход в выход (без параметров в [...]).
+const loggingVipsScript = `#!/bin/sh
+echo "$1" >> "$VIPS_LOG"
+out="${3%%\[*}"
+cp "$2" "$out"
+`
+
+func TestConvert_ToneChain(t *testing.T) {
+	dir := t.TempDir()
+	vips := filepath.Join(dir, "vips")
+	if err := os.WriteFile(vips, []byte(loggingVipsScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Fake vips копирует файл, поэтому промежуточные файлы - заголовок .v исходника
+	src := filepath.Join(dir, "color.jpg")
+	writeVipsHeader(t, src, 16, 16, 3)
+
+	tests := []struct {
+		name    string
+		sepia   bool
+		wantOps string
+	}{
+		{name: "grayscale", wantOps: "copy colourspace"},
+		{name: "sepia", sepia: true, wantOps: "copy colourspace colourspace linear"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logPath := filepath.Join(t.TempDir(), "vips.log")
+			t.Setenv("VIPS_LOG", logPath)
+
+			cfg := config.DefaultConfig()
+			cfg.NoVerify = true
+			cfg.Grayscale, cfg.Sepia = !tt.sepia, tt.sepia
+
+			outDir := t.TempDir()
+			dst := filepath.Join(outDir, "color.webp")
+			if res := New(vips, cfg).Convert(context.Background(), src, dst); !res.Success {
+				t.Fatalf("Convert: %v", res.Error)
+			}
+
+			data, _ := os.ReadFile(logPath)
+			if got := strings.Join(strings.Fields(string(data)), " "); got != tt.wantOps {
+				t.Errorf("операции vips = %q, want %q", got, tt.wantOps)
+			}
+			entries, _ := os.ReadDir(outDir)
+			if len(entries) != 1 || entries[0].Name() != "color.webp" {
+				t.Errorf("в выходной директории остались промежуточные файлы: %v", entries)
+			}
+		})
+	}
+}
+
+func TestConvert_GrayscaleAndSepiaRealVips(t *testing.T) {
+	vipsPath, err := exec.LookPath("vips")
+	if err != nil {
+		t.Skip("vips не установлен")
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "color.jpg")
+	writeTestJPEG(t, src)
+
+	convert := func(src string, grayscale, sepia bool, name string) image.Image {
+		t.Helper()
+		cfg := config.DefaultConfig()
+		cfg.OutputFormat = config.FormatPNG
+		cfg.Grayscale, cfg.Sepia = grayscale, sepia
+
+		dst := filepath.Join(dir, name)
+		if res := New(vipsPath, cfg).Convert(context.Background(), src, dst); !res.Success {
+			t.Fatalf("Convert(%s): %v", name, res.Error)
+		}
+		f, err := os.Open(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		img, err := png.Decode(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return img
+	}
+
+	gray := convert(src, true, false, "gray.png")
+	sepia := convert(src, false, true, "sepia.png")
+	for _, p := range []image.Point{{0, 0}, {15, 0}, {0, 15}, {8, 8}, {15, 15}} {
+		r, g, b, _ := gray.At(p.X, p.Y).RGBA()
+		if r != g || g != b {
+			t.Errorf("grayscale %v: R=%d G=%d B=%d, want R=G=B", p, r, g, b)
+		}
+		r, g, b, _ = sepia.At(p.X, p.Y).RGBA()
+		if r < g || g < b {
+			t.Errorf("sepia %v: R=%d G=%d B=%d, want R>=G>=B", p, r, g, b)
+		}
+	}
+
+	// PNG с альфа-каналом: сепия сохраняет прозрачность
+	rgba := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			rgba.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 16), G: 128, B: uint8(y * 16), A: uint8(x * 16)})
+		}
+	}
+	alphaSrc := filepath.Join(dir, "alpha.png")
+	f, err := os.Create(alphaSrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, rgba); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	sepiaAlpha := convert(alphaSrc, false, true, "sepia-alpha.png")
+	for _, p := range []image.Point{{1, 0}, {8, 8}, {15, 15}} {
+		r, g, b, a := sepiaAlpha.At(p.X, p.Y).RGBA()
+		if _, _, _, want := rgba.At(p.X, p.Y).RGBA(); a>>8 != want>>8 {
+			t.Errorf("sepia RGBA %v: альфа %d, want %d", p, a>>8, want>>8)
+		}
+		if a > 0 && (r < g || g < b) {
+			t.Errorf("sepia RGBA %v: R=%d G=%d B=%d, want R>=G>=B", p, r, g, b)
+		}
+	}
+}
+
+func TestPostOps_SepiaAlpha(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Sepia = true
+	ops := New("vips", cfg).postOps()
+	linear := ops[len(ops)-1]
+
+	tests := []struct {
+		name     string
+		bands    int
+		mul, add string
+	}{
+		{name: "RGB", bands: 3, mul: "1.0 0.89 0.71", add: "0 0 0"},
+		{name: "RGBA: альфа-канал без изменений", bands: 4, mul: "1.0 0.89 0.71 1", add: "0 0 0 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stage := filepath.Join(t.TempDir(), "in.v")
+			writeVipsHeader(t, stage, 16, 16, tt.bands)
+			args, err := linear(stage, "out")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"linear", stage, "out", tt.mul, tt.add, "--uchar"}
+			if !reflect.DeepEqual(args, want) {
+				t.Errorf("linear = %q, want %q", args, want)
+			}
+		})
+	}
+}
+
+func TestPostOps_Sharpen(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxWidth int
+		always   bool
+		want     [][]string
+	}{
+		{
+			name:     "resize + sharpen",
+			maxWidth: 800,
+			want:     [][]string{{"sharpen", "in.v", "out", "--sigma=1.5"}},
+		},
+		{
+			name: "без resize резкость не повышается",
+			want: nil,
+		},
+		{
+			name:   "без resize с SharpenAlways",
+			always: true,
+			want:   [][]string{{"sharpen", "in.v", "out", "--sigma=1.5"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.MaxWidth = tt.maxWidth
+			cfg.Sharpen = 1.5
+			cfg.SharpenAlways = tt.always
+
+			var got [][]string
+			for _, op := range New("vips", cfg).postOps() {
+				args, err := op("in.v", "out")
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, args)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("postOps = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvert_SharpenAfterThumbnail(t *testing.T) {
+	dir := t.TempDir()
+	vips := filepath.Join(dir, "vips")
+	if err := os.WriteFile(vips, []byte(loggingVipsScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "photo.jpg")
+	writeTestJPEG(t, src)
+	logPath := filepath.Join(dir, "vips.log")
+	t.Setenv("VIPS_LOG", logPath)
+
+	cfg := config.DefaultConfig()
+	cfg.NoVerify = true
+	cfg.MaxWidth = 8
+	cfg.Sharpen = 1
+	cfg.Grayscale = true
+
+	if res := New(vips, cfg).Convert(context.Background(), src, filepath.Join(dir, "out", "photo.webp")); !res.Success {
+		t.Fatalf("Convert: %v", res.Error)
+	}
+
+	data, _ := os.ReadFile(logPath)
+	if got, want := strings.Join(strings.Fields(string(data)), " "), "thumbnail sharpen colourspace"; got != want {
+		t.Errorf("операции vips = %q, want %q", got, want)
+	}
+	if !strings.Contains(cfg.OutputParams(), `"sharpen":1`) {
+		t.Errorf("OutputParams() = %s, want sharpen", cfg.OutputParams())
+	}
+}
+
+// writeVipsHeader создаёт файл с заголовком формата .v (little-endian).
+func writeVipsHeader(t *testing.T, path string, width, height, bands int) {
+	t.Helper()
+	header := make([]byte, 64)
+	binary.BigEndian.PutUint32(header[0:4], vipsMagicIntel)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(width))
+	binary.LittleEndian.PutUint32(header[8:12], uint32(height))
+	binary.LittleEndian.PutUint32(header[12:16], uint32(bands))
+	if err := os.WriteFile(path, header, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostOps_BlurAndPixelate(t *testing.T) {
+	stage := filepath.Join(t.TempDir(), "in.v")
+	writeVipsHeader(t, stage, 101, 50, 3)
+
+	tests := []struct {
+		name   string
+		modify func(c *config.Config)
+		want   [][]string
+	}{
+		{
+			name:   "blur",
+			modify: func(c *config.Config) { c.Blur = 4 },
+			want:   [][]string{{"gaussblur", stage, "out", "4"}},
+		},
+		{
+			name:   "pixelate сохраняет размер",
+			modify: func(c *config.Config) { c.Pixelate = 10 },
+			want: [][]string{
+				{"embed", stage, "out", "0", "0", "110", "50", "--extend=copy"},
+				{"shrink", stage, "out", "10", "10"},
+				{"zoom", stage, "out", "10", "10"},
+				{"crop", stage, "out", "0", "0", "101", "50"},
+			},
+		},
+		{
+			name:   "pixelate и оттенки серого",
+			modify: func(c *config.Config) { c.Pixelate, c.Grayscale = 25, true },
+			want: [][]string{
+				{"embed", stage, "out", "0", "0", "125", "50", "--extend=copy"},
+				{"shrink", stage, "out", "25", "25"},
+				{"zoom", stage, "out", "25", "25"},
+				{"crop", stage, "out", "0", "0", "101", "50"},
+				{"colourspace", stage, "out", "b-w"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			tt.modify(cfg)
+
+			var got [][]string
+			for _, op := range New("vips", cfg).postOps() {
+				args, err := op(stage, "out")
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, args)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("postOps =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+
+	if _, _, err := readVipsSize(writeTestFile(t)); err == nil {
+		t.Error("readVipsSize(не .v файл) должна вернуть ошибку")
+	}
+}
+
+// writeTestFile создаёт файл, не являющийся файлом формата vips.
+func writeTestFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	writeTestJPEG(t, path)
+	return path
+}
+
+func TestConvert_BlurPixelateRealVips(t *testing.T) {
+	vipsPath, err := exec.LookPath("vips")
+	if err != nil {
+		t.Skip("vips не установлен")
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "screen.jpg")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := jpeg.Encode(f, image.NewRGBA(image.Rect(0, 0, 101, 57)), nil); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	for name, modify := range map[string]func(c *config.Config){
+		"blur":     func(c *config.Config) { c.Blur = 3 },
+		"pixelate": func(c *config.Config) { c.Pixelate = 8 },
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.OutputFormat = config.FormatPNG
+			modify(cfg)
+
+			dst := filepath.Join(dir, name+".png")
+			if res := New(vipsPath, cfg).Convert(context.Background(), src, dst); !res.Success {
+				t.Fatalf("Convert: %v", res.Error)
+			}
+			out, err := os.Open(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			ic, err := png.DecodeConfig(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ic.Width != 101 || ic.Height != 57 {
+				t.Errorf("размер = %dx%d, want 101x57", ic.Width, ic.Height)
+			}
+		})
+	}
+}
+
+func TestBuildDstPathRel_FlatNaming(t *testing.T) {
+	tests := []struct {
+		naming string
+		want   []string
+	}{
+		{naming: "", want: []string{"IMG_0001.jpg", "IMG_0001.jpg", "IMG_0001.jpg"}},
+		{naming: config.FlatNamingHashed, want: []string{"IMG_0001.jpg", "IMG_0001_d848d30f.jpg", "IMG_0001_538f716b.jpg"}},
+		{naming: config.FlatNamingPathJoined, want: []string{"IMG_0001.jpg", "2024_may_IMG_0001.jpg", "2024_june_IMG_0001.jpg"}},
+	}
+	rels := []string{"IMG_0001.jpg", filepath.Join("2024", "may", "IMG_0001.jpg"), filepath.Join("2024", "june", "IMG_0001.jpg")}
+
+	for _, tt := range tests {
+		t.Run("naming="+tt.naming, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.OutputDir = "out"
+			cfg.KeepTree = false
+			cfg.FlatNaming = tt.naming
+			c := New("vips", cfg)
+			for i, rel := range rels {
+				want := filepath.Join("out", tt.want[i])
+				if got := c.BuildDstPathRel(rel); got != want {
+					t.Errorf("BuildDstPathRel(%q) = %q, want %q", rel, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConvert_OutputExtension(t *testing.T) {
+	dir := t.TempDir()
+	vips := filepath.Join(dir, "vips")
+	// Пишет выходной путь vips (с параметрами) в $VIPS_LOG
+	script := `#!/bin/sh
+echo "$3" >> "$VIPS_LOG"
+out="${3%%\[*}"
+cp "$2" "$out"
+`
+	if err := os.WriteFile(vips, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	logPath := filepath.Join(dir, "vips.log")
+	t.Setenv("VIPS_LOG", logPath)
+
+	in := filepath.Join(dir, "in")
+	if err := os.MkdirAll(in, 0755); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(in, "photo.png")
+	if err := os.WriteFile(src, []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.InputDir = in
+	cfg.OutputDir = filepath.Join(dir, "out")
+	cfg.OutputFormat = config.FormatJPEG
+	cfg.OutputExtension = "jpeg"
+	cfg.NoVerify = true
+	c := New(vips, cfg)
+
+	dst := c.BuildDstPath(src)
+	if want := filepath.Join(cfg.OutputDir, "photo.jpeg"); dst != want {
+		t.Fatalf("BuildDstPath = %q, want %q", dst, want)
+	}
+	if res := c.Convert(context.Background(), src, dst); !res.Success {
+		t.Fatalf("Convert: %v", res.Error)
+	}
+	if _, err := os.Stat(dst); err != nil {
+		t.Fatalf("результат не записан: %v", err)
+	}
+
+	// Кодировщик выбирается по формату: vips получает jpeg с качеством
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); !strings.HasSuffix(got, ".jpeg"+cfg.VipsOutputSuffix()) {
+		t.Errorf("выход vips = %q, want суффикс %q", got, ".jpeg"+cfg.VipsOutputSuffix())
+	}
+}
+
+func TestConvert_FailureReportsCommand(t *testing.T) {
+	dir := t.TempDir()
+	vips := filepath.Join(dir, "vips")
+	// Падает на операции из $VIPS_FAIL, остальные копируют вход в выход
+	script := `#!/bin/sh
+if [ "$1" = "$VIPS_FAIL" ]; then
+  echo "VipsJpeg: Premature end of JPEG file" >&2
+  exit 1
+fi
+out="${3%%\[*}"
+cp "$2" "$out"
+`
+	if err := os.WriteFile(vips, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "my photo.jpg")
+	if err := os.WriteFile(src, []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		fail    string
+		sharpen float64
+		want    []string
+	}{
+		{name: "основной шаг", fail: "copy", want: []string{vips, "copy", "'" + src + "'", "[Q=85"}},
+		{name: "операция цепочки", fail: "sharpen", sharpen: 1, want: []string{vips, "sharpen", "--sigma=1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("VIPS_FAIL", tt.fail)
+			cfg := config.DefaultConfig()
+			cfg.OutputFormat = config.FormatJPEG
+			cfg.Quality = 85
+			cfg.Sharpen = tt.sharpen
+			cfg.SharpenAlways = tt.sharpen > 0
+			cfg.NoVerify = true
+
+			res := New(vips, cfg).Convert(context.Background(), src, filepath.Join(t.TempDir(), "out.jpg"))
+			if res.Success {
+				t.Fatal("конвертация должна завершиться ошибкой")
+			}
+			if !strings.HasPrefix(res.Command, vips+" "+tt.fail+" ") {
+				t.Errorf("Command = %q, want команду %s", res.Command, tt.fail)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(res.Error.Error(), want) {
+					t.Errorf("ошибка %q не содержит %q", res.Error, want)
+				}
+			}
+			if !strings.Contains(res.Error.Error(), "Premature end of JPEG file") {
+				t.Errorf("ошибка %q не содержит stderr vips", res.Error)
+			}
+		})
+	}
+}
+
+func TestPostOps_DPI(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.DPI = 254
+	cfg.Grayscale = true
+
+	var got [][]string
+	for _, op := range New("vips", cfg).postOps() {
+		args, err := op("in.v", "out")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, args)
+	}
+	want := [][]string{
+		{"colourspace", "in.v", "out", "b-w"},
+		{"copy", "in.v", "out", "--xres=10", "--yres=10"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("postOps = %v, want %v", got, want)
+	}
+	if !strings.Contains(cfg.OutputParams(), `"dpi":254`) {
+		t.Errorf("OutputParams() = %s, want dpi", cfg.OutputParams())
+	}
+}
+
+func TestConvert_DPIRealVips(t *testing.T) {
+	vipsPath, err := exec.LookPath("vips")
+	if err != nil {
+		t.Skip("vips не установлен")
+	}
+	headerPath := FindVipsheader(vipsPath)
+	if headerPath == "" {
+		t.Skip("vipsheader не установлен")
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "print.jpg")
+	writeTestJPEG(t, src)
+
+	cfg := config.DefaultConfig()
+	cfg.OutputFormat = config.FormatJPEG
+	cfg.DPI = 300
+
+	dst := filepath.Join(dir, "out", "print.jpg")
+	if res := New(vipsPath, cfg).Convert(context.Background(), src, dst); !res.Success {
+		t.Fatalf("Convert: %v", res.Error)
+	}
+
+	// vipsheader возвращает разрешение в пикселях на мм
+	out, err := exec.Command(headerPath, "-f", "xres", dst).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	xres, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		t.Fatalf("xres %q: %v", out, err)
+	}
+	if dpi := xres * mmPerInch; dpi < 299.5 || dpi > 300.5 {
+		t.Errorf("плотность результата = %.1f dpi, want 300", dpi)
+	}
+}
+
+func TestIsOutOfMemory(t *testing.T) {
+	cases := []struct {
+		res  *ConvertResult
+		want bool
+	}{
+		{&ConvertResult{Stderr: "vips: Out of memory -- wanted 2147483648 bytes\n", Error: errors.New("vips copy failed")}, true},
+		{&ConvertResult{Error: errors.New("vips copy failed: exit status 1: heif: memory allocation failed")}, true},
+		{&ConvertResult{Stderr: "terminate called after throwing an instance of 'std::bad_alloc'", Error: errors.New("vips copy failed")}, true},
+		{&ConvertResult{Error: errors.New("vips copy failed: VipsForeignLoad: not a known file format")}, false},
+		{&ConvertResult{Success: true, Stderr: "out of memory"}, false},
+	}
+	for i, c := range cases {
+		if got := IsOutOfMemory(c.res); got != c.want {
+			t.Errorf("случай %d: IsOutOfMemory = %v, want %v", i, got, c.want)
+		}
+	}
+}
+
+func TestParseExifDate(t *testing.T) {
+	want := time.Date(2021, 7, 4, 9, 15, 0, 0, time.Local)
+	for _, out := range []string{
+		"2021:07:04 09:15:00",
+		"2021:07:04 09:15:00 (2021:07:04 09:15:00, ASCII, 20 components, 20 bytes)\n",
+	} {
+		got, err := parseExifDate(out)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseExifDate(%q) = %v, %v; want %v", out, got, err, want)
+		}
+	}
+	for _, out := range []string{"", "0000:00:00 00:00:00", "    :  :     :  :  "} {
+		if _, err := parseExifDate(out); err == nil {
+			t.Errorf("parseExifDate(%q) должна вернуть ошибку", out)
+		}
+	}
+}
+
+// profileVipsheaderScript имитирует vipsheader -a: ICC профиль есть
+// только у файлов с "tagged" в имени.
+const profileVipsheaderScript = `#!/bin/sh
+echo "$2: 64x48 uchar, 3 bands, srgb, pngload"
+echo "width: 64"
+echo "height: 48"
+echo "bands: 3"
+echo "interpretation: srgb"
+case "$2" in
+*tagged*) echo "icc-profile-data: 3144 bytes of binary data" ;;
+esac
+`
+
+func TestConvert_EmbedSRGB(t *testing.T) {
+	dir := t.TempDir()
+	vips := filepath.Join(dir, "vips")
+	// Пишет выходной путь vips (с параметрами) в $VIPS_LOG
+	script := `#!/bin/sh
+echo "$3" >> "$VIPS_LOG"
+out="${3%%\[*}"
+cp "$2" "$out"
+`
+	if err := os.WriteFile(vips, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "vipsheader"), []byte(profileVipsheaderScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+	logPath := filepath.Join(dir, "vips.log")
+	t.Setenv("VIPS_LOG", logPath)
+
+	cfg := config.DefaultConfig()
+	cfg.OutputFormat = config.FormatJPEG
+	cfg.EmbedSRGB = true
+	cfg.NoVerify = true
+	c := New(vips, cfg)
+
+	for _, name := range []string{"plain.png", "tagged.png"} {
+		src := filepath.Join(dir, name)
+		if err := os.WriteFile(src, []byte("image"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if res := c.Convert(context.Background(), src, filepath.Join(dir, "out", name+".jpg")); !res.Success {
+			t.Fatalf("Convert %s: %v", name, res.Error)
+		}
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("вызовов vips %d, want 2: %q", len(lines), lines)
+	}
+	// Профиль добавляется только исходнику без профиля, к остальным параметрам
+	if want := "[Q=80,profile=srgb]"; !strings.HasSuffix(lines[0], want) {
+		t.Errorf("выход vips без профиля = %q, want суффикс %q", lines[0], want)
+	}
+	if strings.Contains(lines[1], "profile=") {
+		t.Errorf("выход vips с профилем = %q, профиль не должен меняться", lines[1])
+	}
+	if !strings.Contains(cfg.OutputParams(), `"embed_srgb":true`) {
+		t.Errorf("OutputParams() = %s, want embed_srgb", cfg.OutputParams())
+	}
+}
+
+func TestConvert_EmbedSRGBRealVips(t *testing.T) {
+	vipsPath, err := exec.LookPath("vips")
+	if err != nil {
+		t.Skip("vips не установлен")
+	}
+	headerPath := FindVipsheader(vipsPath)
+	if headerPath == "" {
+		t.Skip("vipsheader не установлен")
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "plain.jpg")
+	writeTestJPEG(t, src)
+	if info, err := ReadImageInfo(context.Background(), headerPath, src); err != nil || info.ProfileSize != 0 {
+		t.Fatalf("исходник с профилем: %+v, %v", info, err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.OutputFormat = config.FormatJPEG
+	cfg.EmbedSRGB = true
+
+	dst := filepath.Join(dir, "out", "plain.jpg")
+	if res := New(vipsPath, cfg).Convert(context.Background(), src, dst); !res.Success {
+		t.Fatalf("Convert: %v", res.Error)
+	}
+	info, err := ReadImageInfo(context.Background(), headerPath, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ProfileSize == 0 {
+		t.Error("в результат не встроен профиль sRGB")
+	}
+}
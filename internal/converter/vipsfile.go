@@ -0,0 +1,55 @@
+// Package converter содержит чтение заголовка файлов формата vips (.v).
+package converter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Магические числа формата .v: порядок байт полей заголовка.
+// Само число всегда записано в big-endian.
+const (
+	vipsMagicIntel = 0xb6a6f208 // поля в little-endian
+	vipsMagicSparc = 0x08f2a6b6 // поля в big-endian
+)
+
+// readVipsSize читает ширину и высоту изображения из заголовка файла .v
+// (промежуточные файлы цепочки операций), не запуская vipsheader.
+func readVipsSize(path string) (width, height int, err error) {
+	width, height, _, err = readVipsHeader(path)
+	return width, height, err
+}
+
+// readVipsHeader читает ширину, высоту и число полос (каналов)
+// изображения из заголовка файла .v.
+func readVipsHeader(path string) (width, height, bands int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer f.Close()
+
+	var header [16]byte
+	if _, err := io.ReadFull(f, header[:]); err != nil {
+		return 0, 0, 0, fmt.Errorf("не удалось прочитать заголовок %s: %w", path, err)
+	}
+
+	var order binary.ByteOrder
+	switch binary.BigEndian.Uint32(header[0:4]) {
+	case vipsMagicIntel:
+		order = binary.LittleEndian
+	case vipsMagicSparc:
+		order = binary.BigEndian
+	default:
+		return 0, 0, 0, fmt.Errorf("%s: не файл формата vips", path)
+	}
+
+	return int(order.Uint32(header[4:8])), int(order.Uint32(header[8:12])), int(order.Uint32(header[12:16])), nil
+}
+
+/*
+Возможные расширения:
+- Чтение интерпретации из заголовка
+*/
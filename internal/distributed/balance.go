@@ -0,0 +1,86 @@
+package distributed
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+)
+
+// SortBySize упорядочивает задачи от больших файлов к меньшим (при равном
+// размере - по пути). Воркеры, забирающие из такой очереди следующую задачу,
+// как только освободились, получают близкую суммарную нагрузку в байтах:
+// большие файлы распределяются первыми, мелкие выравнивают остаток.
+func SortBySize(tasks []*Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Size != tasks[j].Size {
+			return tasks[i].Size > tasks[j].Size
+		}
+		return tasks[i].FilePath < tasks[j].FilePath
+	})
+}
+
+// AssignBySize распределяет задачи между workers воркерами жадно: задачи от
+// больших к меньшим, каждая - воркеру с наименьшей суммой байт на этот момент.
+// Для известного числа воркеров с отдельными очередями.
+func AssignBySize(tasks []*Task, workers int) [][]*Task {
+	if workers < 1 {
+		workers = 1
+	}
+	sorted := append([]*Task(nil), tasks...)
+	SortBySize(sorted)
+
+	assigned := make([][]*Task, workers)
+	loads := make(workerLoads, workers)
+	for i := range loads {
+		loads[i] = workerLoad{index: i}
+	}
+	heap.Init(&loads)
+	for _, task := range sorted {
+		least := &loads[0]
+		assigned[least.index] = append(assigned[least.index], task)
+		least.bytes += task.Size
+		heap.Fix(&loads, 0)
+	}
+	return assigned
+}
+
+// workerLoad - суммарный размер задач воркера.
+type workerLoad struct {
+	index int
+	bytes int64
+}
+
+// workerLoads - min-heap воркеров по нагрузке (при равенстве - по номеру).
+type workerLoads []workerLoad
+
+func (h workerLoads) Len() int { return len(h) }
+func (h workerLoads) Less(i, j int) bool {
+	if h[i].bytes != h[j].bytes {
+		return h[i].bytes < h[j].bytes
+	}
+	return h[i].index < h[j].index
+}
+func (h workerLoads) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *workerLoads) Push(x any)   { *h = append(*h, x.(workerLoad)) }
+func (h *workerLoads) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// Enqueue добавляет задачи в очередь master-узла. С Config.BalanceBySize
+// задачи добавляются от больших файлов к меньшим (SortBySize), иначе -
+// в исходном порядке.
+func (m *Manager) Enqueue(ctx context.Context, tasks []*Task) error {
+	if m.cfg.BalanceBySize {
+		tasks = append([]*Task(nil), tasks...)
+		SortBySize(tasks)
+	}
+	for _, task := range tasks {
+		if err := m.queue.Push(ctx, task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package distributed
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// mixedTasks создаёт задачи вперемешку из мелких файлов и нескольких больших
+// в конце (как после сканирования директории с крупными TIFF).
+func mixedTasks() []*Task {
+	rng := rand.New(rand.NewSource(1))
+	var tasks []*Task
+	for i := 0; i < 200; i++ {
+		tasks = append(tasks, &Task{ID: fmt.Sprint(i), FilePath: fmt.Sprintf("/in/small_%03d.jpg", i), Size: 100<<10 + rng.Int63n(5<<20)})
+	}
+	for i := 0; i < 12; i++ {
+		tasks = append(tasks, &Task{ID: fmt.Sprint(200 + i), FilePath: fmt.Sprintf("/in/big_%02d.tiff", i), Size: 50<<20 + rng.Int63n(450<<20)})
+	}
+	return tasks
+}
+
+// imbalance возвращает разброс нагрузки воркеров: (max - min) / среднее.
+func imbalance(loads []int64) float64 {
+	minLoad, maxLoad, total := loads[0], loads[0], int64(0)
+	for _, l := range loads {
+		minLoad, maxLoad, total = min(minLoad, l), max(maxLoad, l), total+l
+	}
+	return float64(maxLoad-minLoad) / (float64(total) / float64(len(loads)))
+}
+
+// pullLoads моделирует n воркеров, забирающих задачи из очереди менеджера:
+// следующую задачу берёт воркер, раньше всех закончивший предыдущие
+// (время обработки пропорционально размеру).
+func pullLoads(t *testing.T, balance bool, tasks []*Task, n int) []int64 {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.BalanceBySize = balance
+	m, err := NewManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	ctx := context.Background()
+	if err := m.Enqueue(ctx, tasks); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	loads := make([]int64, n)
+	for range tasks {
+		task, err := m.Queue().Pop(ctx)
+		if err != nil {
+			t.Fatalf("Pop: %v", err)
+		}
+		free := 0
+		for w := range loads {
+			if loads[w] < loads[free] {
+				free = w
+			}
+		}
+		loads[free] += task.Size
+	}
+	return loads
+}
+
+func TestManager_EnqueueBalanceBySize(t *testing.T) {
+	const tolerance = 0.05
+	for _, n := range []int{2, 3, 4} {
+		t.Run(fmt.Sprintf("%d workers", n), func(t *testing.T) {
+			tasks := mixedTasks()
+			if got := imbalance(pullLoads(t, true, tasks, n)); got > tolerance {
+				t.Errorf("разброс нагрузки с --balance-by-size = %.3f, want <= %.2f", got, tolerance)
+			}
+			// Без балансировки большие файлы в конце достаются кому придётся
+			if got := imbalance(pullLoads(t, false, tasks, n)); got <= tolerance {
+				t.Errorf("разброс без балансировки = %.3f, тест не проверяет балансировку", got)
+			}
+		})
+	}
+}
+
+func TestAssignBySize(t *testing.T) {
+	tasks := mixedTasks()
+	assigned := AssignBySize(tasks, 4)
+	if len(assigned) != 4 {
+		t.Fatalf("воркеров %d, want 4", len(assigned))
+	}
+
+	seen := make(map[string]bool)
+	loads := make([]int64, len(assigned))
+	for w, list := range assigned {
+		for _, task := range list {
+			if seen[task.ID] {
+				t.Fatalf("задача %s назначена дважды", task.ID)
+			}
+			seen[task.ID] = true
+			loads[w] += task.Size
+		}
+	}
+	if len(seen) != len(tasks) {
+		t.Fatalf("назначено %d задач, want %d", len(seen), len(tasks))
+	}
+	if got := imbalance(loads); got > 0.05 {
+		t.Errorf("разброс нагрузки = %.3f (%v), want <= 0.05", got, loads)
+	}
+}
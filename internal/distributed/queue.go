@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/artemshloyda/photoconverter/internal/config"
@@ -35,6 +36,12 @@ type Task struct {
 	// Error - ошибка (если есть).
 	Error string `json:"error,omitempty"`
 
+	// Attempts - число неудачных попыток обработки.
+	Attempts int `json:"attempts,omitempty"`
+
+	// DstPath - путь к результату выполненной задачи.
+	DstPath string `json:"dst_path,omitempty"`
+
 	// WorkerID - ID воркера, обрабатывающего задачу.
 	WorkerID string `json:"worker_id,omitempty"`
 
@@ -57,9 +64,13 @@ type Queue interface {
 	// Complete отмечает задачу как выполненную.
 	Complete(ctx context.Context, taskID string) error
 
-	// Fail отмечает задачу как неудачную.
+	// Fail отмечает задачу как неудачную. Очередь с лимитом попыток
+	// возвращает задачу в ожидание, а после последней - в dead-letter.
 	Fail(ctx context.Context, taskID string, err error) error
 
+	// DeadLetters возвращает задачи, исчерпавшие попытки.
+	DeadLetters(ctx context.Context) ([]*Task, error)
+
 	// Stats возвращает статистику очереди.
 	Stats(ctx context.Context) (*QueueStats, error)
 
@@ -73,32 +84,67 @@ type QueueStats struct {
 	Processing int64 `json:"processing"`
 	Done       int64 `json:"done"`
 	Failed     int64 `json:"failed"`
+	Dead       int64 `json:"dead"`
 }
 
 // InMemoryQueue реализует очередь в памяти (для одной машины).
+// Безопасна для использования из нескольких горутин.
 type InMemoryQueue struct {
-	tasks   chan *Task
-	done    map[string]bool
-	failed  map[string]string
-	pending int64
+	tasks chan *Task
+
+	// mu защищает счётчики и множества ниже.
+	mu         sync.Mutex
+	done       map[string]bool
+	failed     map[string]string
+	pending    int64
+	processing int64
+
+	// inflight - задачи, взятые воркерами (для повтора по Fail).
+	inflight map[string]*Task
+
+	// dead - задачи, исчерпавшие maxAttempts попыток, в порядке переноса.
+	dead []*Task
+
+	// maxAttempts - попыток задачи до переноса в dead (0 = без повторов).
+	maxAttempts int
+
+	// closed - очередь закрыта Close, повтор задачи в неё невозможен.
+	closed bool
 }
 
 // NewInMemoryQueue создаёт новую in-memory очередь.
 func NewInMemoryQueue(bufferSize int) *InMemoryQueue {
 	return &InMemoryQueue{
-		tasks:  make(chan *Task, bufferSize),
-		done:   make(map[string]bool),
-		failed: make(map[string]string),
+		tasks:    make(chan *Task, bufferSize),
+		done:     make(map[string]bool),
+		failed:   make(map[string]string),
+		inflight: make(map[string]*Task),
 	}
 }
 
+// SetMaxAttempts задаёт число попыток задачи: неудачная задача возвращается
+// в очередь, пока попыток меньше n, затем переносится в dead-letter.
+// 0 - без повторов (задача сразу считается неудачной).
+func (q *InMemoryQueue) SetMaxAttempts(n int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.maxAttempts = n
+}
+
 // Push добавляет задачу в очередь.
 func (q *InMemoryQueue) Push(ctx context.Context, task *Task) error {
+	// Счётчик увеличивается до отправки: иначе Pop успевает уменьшить его раньше
+	q.mu.Lock()
+	q.pending++
+	q.mu.Unlock()
+
 	select {
 	case q.tasks <- task:
-		q.pending++
 		return nil
 	case <-ctx.Done():
+		q.mu.Lock()
+		q.pending--
+		q.mu.Unlock()
 		return ctx.Err()
 	}
 }
@@ -107,7 +153,11 @@ func (q *InMemoryQueue) Push(ctx context.Context, task *Task) error {
 func (q *InMemoryQueue) Pop(ctx context.Context) (*Task, error) {
 	select {
 	case task := <-q.tasks:
+		q.mu.Lock()
 		q.pending--
+		q.processing++
+		q.inflight[task.ID] = task
+		q.mu.Unlock()
 		return task, nil
 	case <-ctx.Done():
 		return nil, ctx.Err()
@@ -116,27 +166,84 @@ func (q *InMemoryQueue) Pop(ctx context.Context) (*Task, error) {
 
 // Complete отмечает задачу как выполненную.
 func (q *InMemoryQueue) Complete(ctx context.Context, taskID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 	q.done[taskID] = true
+	q.finishProcessing(taskID)
 	return nil
 }
 
-// Fail отмечает задачу как неудачную.
+// Fail отмечает задачу как неудачную. С лимитом попыток задача
+// возвращается в очередь, а после последней попытки переносится в dead-letter.
+// Повтор не ждёт места в очереди: если очередь закрыта или заполнена,
+// задача сразу считается неудачной.
 func (q *InMemoryQueue) Fail(ctx context.Context, taskID string, err error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	task := q.inflight[taskID]
+	q.finishProcessing(taskID)
+	if q.maxAttempts <= 0 || task == nil {
+		q.failed[taskID] = err.Error()
+		return nil
+	}
+
+	task.Attempts++
+	task.Error = err.Error()
+	if task.Attempts >= q.maxAttempts {
+		task.Status = StatusDead
+		q.dead = append(q.dead, task)
+		return nil
+	}
+
+	// Отправка под q.mu: Close не закроет канал между проверкой и отправкой
+	if !q.closed {
+		select {
+		case q.tasks <- task:
+			task.Status = StatusPending
+			q.pending++
+			return nil
+		default:
+		}
+	}
+	task.Status = StatusFailed
 	q.failed[taskID] = err.Error()
 	return nil
 }
 
+// finishProcessing уменьшает число задач в обработке после Complete или Fail.
+// Вызывается под q.mu.
+func (q *InMemoryQueue) finishProcessing(taskID string) {
+	delete(q.inflight, taskID)
+	if q.processing > 0 {
+		q.processing--
+	}
+}
+
+// DeadLetters возвращает задачи, исчерпавшие попытки, в порядке переноса.
+func (q *InMemoryQueue) DeadLetters(ctx context.Context) ([]*Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]*Task(nil), q.dead...), nil
+}
+
 // Stats возвращает статистику очереди.
 func (q *InMemoryQueue) Stats(ctx context.Context) (*QueueStats, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 	return &QueueStats{
-		Pending: q.pending,
-		Done:    int64(len(q.done)),
-		Failed:  int64(len(q.failed)),
+		Pending:    q.pending,
+		Processing: q.processing,
+		Done:       int64(len(q.done)),
+		Failed:     int64(len(q.failed)),
+		Dead:       int64(len(q.dead)),
 	}, nil
 }
 
 // Close закрывает очередь.
 func (q *InMemoryQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
 	close(q.tasks)
 	return nil
 }
@@ -149,7 +256,7 @@ func TaskFromFile(file scanner.File) *Task {
 		RelPath:  file.RelPath,
 		Size:     file.Info.Size,
 		ModTime:  time.Unix(file.Info.Mtime, 0),
-		Status:   "pending",
+		Status:   StatusPending,
 	}
 }
 
@@ -179,9 +286,9 @@ func NewManager(cfg *config.Config) (*Manager, error) {
 	if cfg.RedisURL != "" {
 		// TODO: Реализовать RedisQueue
 		// Пока используем in-memory
-		queue = NewInMemoryQueue(10000)
+		queue = newInMemoryQueue(cfg)
 	} else {
-		queue = NewInMemoryQueue(10000)
+		queue = newInMemoryQueue(cfg)
 	}
 
 	return &Manager{
@@ -191,6 +298,13 @@ func NewManager(cfg *config.Config) (*Manager, error) {
 	}, nil
 }
 
+// newInMemoryQueue создаёт in-memory очередь с лимитом попыток из конфигурации.
+func newInMemoryQueue(cfg *config.Config) *InMemoryQueue {
+	q := NewInMemoryQueue(10000)
+	q.SetMaxAttempts(cfg.MaxAttempts)
+	return q
+}
+
 // IsMaster возвращает true если это master-узел.
 func (m *Manager) IsMaster() bool {
 	return m.mode == "master" || m.mode == ""
@@ -227,7 +341,5 @@ func DeserializeTask(data []byte) (*Task, error) {
 Возможные расширения:
 - Реализовать RedisQueue для настоящей распределённой обработки
 - Добавить heartbeat для worker-ов
-- Добавить автоматический retry неудачных задач
-- Добавить балансировку нагрузки
 - Добавить мониторинг и метрики
 */
@@ -0,0 +1,154 @@
+package distributed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// Запускать с -race: Push, Pop, Complete, Fail и Stats из многих горутин.
+func TestInMemoryQueue_Concurrent(t *testing.T) {
+	const producers, consumers, perProducer = 8, 8, 250
+	total := producers * perProducer
+
+	q := NewInMemoryQueue(16)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				if err := q.Push(ctx, &Task{ID: fmt.Sprintf("%d-%d", p, i)}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(p)
+	}
+
+	var popped atomic.Int64
+	for c := 0; c < consumers; c++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := popped.Add(1); n <= int64(total); n = popped.Add(1) {
+				task, err := q.Pop(ctx)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if n%2 == 0 {
+					_ = q.Complete(ctx, task.ID)
+				} else {
+					_ = q.Fail(ctx, task.ID, errors.New("broken"))
+				}
+				if _, err := q.Stats(ctx); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats, err := q.Stats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := QueueStats{Done: int64(total / 2), Failed: int64(total / 2)}
+	if *stats != want {
+		t.Errorf("Stats = %+v, want %+v", *stats, want)
+	}
+}
+
+func TestInMemoryQueue_DeadLetter(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.MaxAttempts = 3
+	m, err := NewManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	q := m.Queue()
+	ctx := context.Background()
+
+	if err := q.Push(ctx, &Task{ID: "bad", FilePath: "/in/bad.jpg"}); err != nil {
+		t.Fatal(err)
+	}
+	// Первые две неудачи возвращают задачу в очередь, третья - в dead-letter
+	for attempt := 1; attempt <= 3; attempt++ {
+		task, err := q.Pop(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.ID != "bad" || task.Attempts != attempt-1 {
+			t.Fatalf("попытка %d: задача %s с %d неудачами", attempt, task.ID, task.Attempts)
+		}
+		if err := q.Fail(ctx, task.ID, fmt.Errorf("broken %d", attempt)); err != nil {
+			t.Fatal(err)
+		}
+
+		stats, _ := q.Stats(ctx)
+		want := QueueStats{Pending: 1}
+		if attempt == 3 {
+			want = QueueStats{Dead: 1}
+		}
+		if *stats != want {
+			t.Errorf("после попытки %d Stats = %+v, want %+v", attempt, *stats, want)
+		}
+	}
+
+	dead, err := q.DeadLetters(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].ID != "bad" || dead[0].Attempts != 3 || dead[0].Status != StatusDead || dead[0].Error != "broken 3" {
+		t.Fatalf("DeadLetters = %+v, want bad с 3 попытками", dead)
+	}
+}
+
+func TestInMemoryQueue_RetryClosedOrFull(t *testing.T) {
+	ctx := context.Background()
+
+	// Заполненная очередь: повтор не ждёт места, задача считается неудачной
+	q := NewInMemoryQueue(1)
+	q.SetMaxAttempts(3)
+	if err := q.Push(ctx, &Task{ID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Pop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Push(ctx, &Task{ID: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Fail(ctx, "a", fmt.Errorf("broken")); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ := q.Stats(ctx); *stats != (QueueStats{Pending: 1, Failed: 1}) {
+		t.Errorf("заполненная очередь: Stats = %+v, want pending 1, failed 1", *stats)
+	}
+
+	// Закрытая очередь: повтор не паникует, задача считается неудачной
+	q = NewInMemoryQueue(1)
+	q.SetMaxAttempts(3)
+	if err := q.Push(ctx, &Task{ID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Pop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+	if err := q.Fail(ctx, "a", fmt.Errorf("broken")); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ := q.Stats(ctx); *stats != (QueueStats{Failed: 1}) {
+		t.Errorf("закрытая очередь: Stats = %+v, want failed 1", *stats)
+	}
+}
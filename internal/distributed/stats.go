@@ -0,0 +1,51 @@
+package distributed
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// FormatStats возвращает строку статистики очереди.
+func FormatStats(s *QueueStats) string {
+	line := fmt.Sprintf("📬 Очередь: ожидают %d, в обработке %d, готово %d, ошибок %d",
+		s.Pending, s.Processing, s.Done, s.Failed)
+	if s.Dead > 0 {
+		line += fmt.Sprintf(", в dead-letter %d", s.Dead)
+	}
+	return line
+}
+
+// WatchStats выводит в w статистику очереди q каждые interval до отмены ctx.
+// Использует только Queue.Stats, поэтому работает с любой реализацией очереди.
+// Ошибка получения статистики выводится вместо неё и не прерывает цикл.
+func WatchStats(ctx context.Context, q Queue, interval time.Duration, w io.Writer) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats, err := q.Stats(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				fmt.Fprintf(w, "⚠️  Статистика очереди недоступна: %v\n", err)
+				continue
+			}
+			fmt.Fprintln(w, FormatStats(stats))
+		}
+	}
+}
+
+// WatchStats выводит статистику очереди master-узла каждые Config.StatsInterval
+// до отмены ctx. Для worker-узла или нулевого периода сразу возвращается.
+func (m *Manager) WatchStats(ctx context.Context, w io.Writer) {
+	if !m.IsMaster() || m.cfg.StatsInterval <= 0 {
+		return
+	}
+	WatchStats(ctx, m.queue, m.cfg.StatsInterval, w)
+}
@@ -0,0 +1,78 @@
+package distributed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+func TestManager_WatchStats(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.StatsInterval = 10 * time.Millisecond
+	m, err := NewManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	// 6 задач: 4 взяты воркерами, из них 2 выполнены и 1 с ошибкой
+	ctx := context.Background()
+	q := m.Queue()
+	for i := 0; i < 6; i++ {
+		if err := q.Push(ctx, &Task{ID: fmt.Sprint(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var popped []*Task
+	for i := 0; i < 4; i++ {
+		task, err := q.Pop(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		popped = append(popped, task)
+	}
+	_ = q.Complete(ctx, popped[0].ID)
+	_ = q.Complete(ctx, popped[1].ID)
+	_ = q.Fail(ctx, popped[2].ID, errors.New("broken"))
+
+	var out bytes.Buffer
+	watchCtx, cancel := context.WithTimeout(ctx, 55*time.Millisecond)
+	defer cancel()
+	m.WatchStats(watchCtx, &out)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("строк статистики %d, want >= 2 за 5 периодов:\n%s", len(lines), out.String())
+	}
+	want := FormatStats(&QueueStats{Pending: 2, Processing: 1, Done: 2, Failed: 1})
+	for _, line := range lines {
+		if line != want {
+			t.Errorf("строка %q, want %q", line, want)
+		}
+	}
+	if want != "📬 Очередь: ожидают 2, в обработке 1, готово 2, ошибок 1" {
+		t.Errorf("FormatStats = %q", want)
+	}
+}
+
+func TestManager_WatchStatsDisabled(t *testing.T) {
+	cfg := config.DefaultConfig()
+	m, err := NewManager(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	// Без --stats-interval цикл не запускается и не блокирует
+	var out bytes.Buffer
+	m.WatchStats(context.Background(), &out)
+	if out.Len() != 0 {
+		t.Errorf("без периода выведено %q", out.String())
+	}
+}
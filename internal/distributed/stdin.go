@@ -0,0 +1,96 @@
+package distributed
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/converter"
+)
+
+// Статусы задач.
+const (
+	StatusPending = "pending"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+
+	// StatusDead - задача исчерпала попытки и перенесена в dead-letter.
+	StatusDead = "dead"
+)
+
+// maxTaskLine - максимальная длина JSON строки задачи.
+const maxTaskLine = 1 << 20
+
+// ServeStdin выполняет задачи внешнего оркестратора без сканирования
+// и очереди (--worker-stdin): читает из r по задаче (Task в JSON) на строку,
+// конвертирует conv и пишет в w задачу со статусом done или failed, путём
+// результата и временем обработки, тоже по строке. Задачи выполняются по
+// очереди, результат каждой пишется сразу. Некорректная строка даёт
+// результат failed, а не останавливает обработку. Пустые строки пропускаются.
+func ServeStdin(ctx context.Context, conv converter.Converter, workerID string, r io.Reader, w io.Writer) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxTaskLine)
+	for sc.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+
+		task, err := DeserializeTask([]byte(line))
+		if err != nil {
+			task = &Task{Status: StatusFailed, Error: fmt.Sprintf("некорректная задача: %v", err)}
+		} else {
+			runTask(ctx, conv, workerID, task)
+		}
+
+		data, err := task.Serialize()
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("не удалось записать результат задачи %s: %w", task.ID, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("ошибка чтения задач: %w", err)
+	}
+	return nil
+}
+
+// runTask конвертирует исходник задачи и заполняет её результат.
+func runTask(ctx context.Context, conv converter.Converter, workerID string, task *Task) {
+	task.WorkerID = workerID
+	task.StartedAt = time.Now()
+	defer func() { task.FinishedAt = time.Now() }()
+
+	if task.FilePath == "" {
+		task.Status, task.Error = StatusFailed, "в задаче не указан file_path"
+		return
+	}
+	dstPath := conv.BuildDstPath(task.FilePath)
+	if task.RelPath != "" {
+		dstPath = conv.BuildDstPathRel(task.RelPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		task.Status, task.Error = StatusFailed, err.Error()
+		return
+	}
+
+	res := conv.Convert(ctx, task.FilePath, dstPath)
+	if !res.Success {
+		task.Status, task.Error = StatusFailed, "конвертация не удалась"
+		if res.Error != nil {
+			task.Error = res.Error.Error()
+		}
+		return
+	}
+	task.Status, task.Error, task.DstPath = StatusDone, "", dstPath
+}
@@ -0,0 +1,107 @@
+package distributed
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/converter"
+)
+
+// stubConverter - конвертер без vips: записывает в результат имя исходника.
+// Файлы с "broken" в имени - ошибка. Остальные методы интерфейса не нужны.
+type stubConverter struct {
+	converter.Converter
+	outDir string
+}
+
+func (s *stubConverter) Convert(_ context.Context, srcPath, dstPath string) *converter.ConvertResult {
+	if strings.Contains(srcPath, "broken") {
+		return &converter.ConvertResult{Error: errors.New("stub: not a known file format")}
+	}
+	if err := os.WriteFile(dstPath, []byte("converted:"+filepath.Base(srcPath)), 0644); err != nil {
+		return &converter.ConvertResult{Error: err}
+	}
+	return &converter.ConvertResult{Success: true, DstPath: dstPath}
+}
+
+func (s *stubConverter) BuildDstPath(srcPath string) string {
+	return s.BuildDstPathRel(filepath.Base(srcPath))
+}
+
+func (s *stubConverter) BuildDstPathRel(relPath string) string {
+	return filepath.Join(s.outDir, strings.TrimSuffix(relPath, filepath.Ext(relPath))+".webp")
+}
+
+func TestServeStdin(t *testing.T) {
+	outDir := t.TempDir()
+	conv := &stubConverter{outDir: outDir}
+
+	var input bytes.Buffer
+	for _, task := range []*Task{
+		{ID: "1", FilePath: "/photos/2024/a.jpg", RelPath: "2024/a.jpg", Status: StatusPending},
+		{ID: "2", FilePath: "/photos/broken.jpg", Status: StatusPending},
+	} {
+		data, err := task.Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		input.Write(append(data, '\n'))
+	}
+	input.WriteString("\n")
+
+	var output bytes.Buffer
+	if err := ServeStdin(context.Background(), conv, "node-1", &input, &output); err != nil {
+		t.Fatalf("ServeStdin: %v", err)
+	}
+
+	var results []*Task
+	sc := bufio.NewScanner(&output)
+	for sc.Scan() {
+		task, err := DeserializeTask(sc.Bytes())
+		if err != nil {
+			t.Fatalf("строка результата %q: %v", sc.Text(), err)
+		}
+		results = append(results, task)
+	}
+	if len(results) != 2 {
+		t.Fatalf("результатов %d, want 2:\n%s", len(results), output.String())
+	}
+
+	done := results[0]
+	wantDst := filepath.Join(outDir, "2024", "a.webp")
+	if done.ID != "1" || done.Status != StatusDone || done.Error != "" || done.DstPath != wantDst {
+		t.Errorf("результат 1 = %+v, want done с dst_path %s", done, wantDst)
+	}
+	if done.WorkerID != "node-1" || done.StartedAt.IsZero() || done.FinishedAt.Before(done.StartedAt) {
+		t.Errorf("результат 1: worker_id=%q started=%v finished=%v", done.WorkerID, done.StartedAt, done.FinishedAt)
+	}
+	if data, err := os.ReadFile(wantDst); err != nil || string(data) != "converted:a.jpg" {
+		t.Errorf("результат a = %q, %v", data, err)
+	}
+
+	failed := results[1]
+	if failed.ID != "2" || failed.Status != StatusFailed || !strings.Contains(failed.Error, "not a known file format") || failed.DstPath != "" {
+		t.Errorf("результат 2 = %+v, want failed с ошибкой конвертера", failed)
+	}
+}
+
+func TestServeStdin_InvalidLine(t *testing.T) {
+	var output bytes.Buffer
+	input := strings.NewReader("not json\n")
+	if err := ServeStdin(context.Background(), &stubConverter{outDir: t.TempDir()}, "node-1", input, &output); err != nil {
+		t.Fatalf("ServeStdin: %v", err)
+	}
+	task, err := DeserializeTask(bytes.TrimSpace(output.Bytes()))
+	if err != nil {
+		t.Fatalf("результат %q: %v", output.String(), err)
+	}
+	if task.Status != StatusFailed || !strings.Contains(task.Error, "некорректная задача") {
+		t.Errorf("результат = %+v, want failed для некорректной строки", task)
+	}
+}
@@ -0,0 +1,159 @@
+// Package dupes ищет одинаковые по содержимому исходные файлы.
+package dupes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+)
+
+// Group - набор файлов с одинаковым содержимым.
+type Group struct {
+	// SHA256 - хэш содержимого.
+	SHA256 string `json:"sha256"`
+
+	// Size - размер одного файла в байтах.
+	Size int64 `json:"size"`
+
+	// Paths - пути файлов группы (отсортированы).
+	Paths []string `json:"paths"`
+}
+
+// WastedBytes возвращает место, занятое лишними копиями.
+func (g Group) WastedBytes() int64 {
+	return g.Size * int64(len(g.Paths)-1)
+}
+
+// Report - отчёт о дубликатах.
+type Report struct {
+	// Files - количество проверенных файлов.
+	Files int `json:"files"`
+
+	// Groups - группы дубликатов (по убыванию занятого впустую места).
+	Groups []Group `json:"groups"`
+
+	// DuplicateFiles - количество лишних копий (без учёта первой в группе).
+	DuplicateFiles int `json:"duplicate_files"`
+
+	// WastedBytes - место, занятое лишними копиями.
+	WastedBytes int64 `json:"wasted_bytes"`
+}
+
+// Find читает файлы из канала и группирует одинаковые по содержимому.
+// SHA256 считается только для файлов, размер которых совпадает хотя бы
+// с одним другим файлом; хэширование выполняют не более workers горутин.
+func Find(ctx context.Context, files <-chan scanner.File, workers int) (*Report, error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Файлы разного размера не могут быть дубликатами
+	bySize := make(map[int64][]scanner.File)
+	report := &Report{Groups: []Group{}}
+	for f := range files {
+		report.Files++
+		bySize[f.Info.Size] = append(bySize[f.Info.Size], f)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var candidates []scanner.File
+	for _, group := range bySize {
+		if len(group) > 1 {
+			candidates = append(candidates, group...)
+		}
+	}
+
+	hashes, err := hashFiles(ctx, candidates, workers)
+	if err != nil {
+		return nil, err
+	}
+
+	byHash := make(map[string]*Group)
+	for i, f := range candidates {
+		g, ok := byHash[hashes[i]]
+		if !ok {
+			g = &Group{SHA256: hashes[i], Size: f.Info.Size}
+			byHash[hashes[i]] = g
+		}
+		g.Paths = append(g.Paths, f.Info.Path)
+	}
+
+	for _, g := range byHash {
+		if len(g.Paths) < 2 {
+			continue
+		}
+		sort.Strings(g.Paths)
+		report.Groups = append(report.Groups, *g)
+		report.DuplicateFiles += len(g.Paths) - 1
+		report.WastedBytes += g.WastedBytes()
+	}
+
+	sort.Slice(report.Groups, func(i, j int) bool {
+		a, b := report.Groups[i], report.Groups[j]
+		if a.WastedBytes() != b.WastedBytes() {
+			return a.WastedBytes() > b.WastedBytes()
+		}
+		return a.Paths[0] < b.Paths[0]
+	})
+
+	return report, nil
+}
+
+// hashFiles вычисляет SHA256 файлов в workers горутинах.
+// Результат i соответствует files[i].
+func hashFiles(ctx context.Context, files []scanner.File, workers int) ([]string, error) {
+	hashes := make([]string, len(files))
+	jobs := make(chan int)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				sum, err := scanner.ComputeSHA256(files[i].Path)
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = fmt.Errorf("%s: %w", files[i].Path, err)
+					}
+					mu.Unlock()
+					continue
+				}
+				hashes[i] = sum
+			}
+		}()
+	}
+
+	for i := range files {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			close(jobs)
+			wg.Wait()
+			return nil, ctx.Err()
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return hashes, nil
+}
+
+/*
+Возможные расширения:
+- Поиск похожих изображений по перцептивному хэшу
+- Сравнение только первых N КБ перед полным хэшем
+*/
@@ -0,0 +1,71 @@
+package dupes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+)
+
+func TestFind_GroupsIdenticalFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.jpg":        "same content",
+		"sub/copy.jpg": "same content",
+		"unique.jpg":   "different!!!", // тот же размер, другое содержимое
+		"small.jpg":    "x",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.InputDir = dir
+	cfg.InputExtensions = []string{"jpg"}
+
+	scanned, errs := scanner.New(cfg).Scan(context.Background())
+	report, err := Find(context.Background(), scanned, 2)
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+
+	if report.Files != 4 {
+		t.Errorf("Files = %d, want 4", report.Files)
+	}
+	if len(report.Groups) != 1 {
+		t.Fatalf("Groups = %+v, want exactly one group", report.Groups)
+	}
+
+	g := report.Groups[0]
+	wantA, _ := filepath.Abs(filepath.Join(dir, "a.jpg"))
+	wantCopy, _ := filepath.Abs(filepath.Join(dir, "sub", "copy.jpg"))
+	if len(g.Paths) != 2 || g.Paths[0] != wantA || g.Paths[1] != wantCopy {
+		t.Errorf("group paths = %v, want [%s %s]", g.Paths, wantA, wantCopy)
+	}
+
+	sum, err := scanner.ComputeSHA256(wantA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.SHA256 != sum {
+		t.Errorf("group SHA256 = %s, want %s", g.SHA256, sum)
+	}
+	if report.DuplicateFiles != 1 {
+		t.Errorf("DuplicateFiles = %d, want 1", report.DuplicateFiles)
+	}
+	if want := int64(len("same content")); report.WastedBytes != want {
+		t.Errorf("WastedBytes = %d, want %d", report.WastedBytes, want)
+	}
+}
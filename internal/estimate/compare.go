@@ -0,0 +1,80 @@
+package estimate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/converter"
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+)
+
+// FormatResult - результат конвертации выборки в один формат (подкоманда compare).
+type FormatResult struct {
+	Format config.OutputFormat `json:"format"`
+
+	// Files - успешно сконвертированные файлы, Failed - файлы с ошибкой.
+	Files  int `json:"files"`
+	Failed int `json:"failed"`
+
+	// InputBytes и OutputBytes - размеры исходников и результатов успешных файлов.
+	InputBytes  int64 `json:"input_bytes"`
+	OutputBytes int64 `json:"output_bytes"`
+
+	// Ratio - отношение размера результата к исходнику.
+	Ratio float64 `json:"ratio"`
+
+	// Duration - суммарное время конвертации файлов в формат.
+	Duration time.Duration `json:"duration_ns"`
+
+	// Error - первая ошибка конвертации (например, формат не поддерживается vips).
+	Error string `json:"error,omitempty"`
+}
+
+// Compare конвертирует files в каждый из formats с настройками cfg во
+// временную директорию и возвращает размеры и время по форматам в порядке
+// formats. Файлы конвертируются по одному, чтобы время форматов было
+// сопоставимо. Временные результаты удаляются.
+func Compare(ctx context.Context, cfg *config.Config, conv converter.Converter, files []scanner.File, formats []config.OutputFormat) ([]FormatResult, error) {
+	tmpDir, err := os.MkdirTemp("", "photoconverter-compare-*")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать временную директорию: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	results := make([]FormatResult, 0, len(formats))
+	for _, format := range formats {
+		fcfg := *cfg
+		fcfg.OutputFormat = format
+		fconv := conv.WithConfig(&fcfg)
+
+		r := FormatResult{Format: format}
+		for i, file := range files {
+			start := time.Now()
+			m, err := measure(ctx, fconv, file, filepath.Join(tmpDir, strconv.Itoa(i)+"."+fcfg.OutputExt()))
+			r.Duration += time.Since(start)
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if err != nil {
+				r.Failed++
+				if r.Error == "" {
+					r.Error = err.Error()
+				}
+				continue
+			}
+			r.Files++
+			r.InputBytes += m.InputBytes
+			r.OutputBytes += m.OutputBytes
+		}
+		if r.InputBytes > 0 {
+			r.Ratio = float64(r.OutputBytes) / float64(r.InputBytes)
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
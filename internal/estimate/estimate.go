@@ -0,0 +1,206 @@
+// Package estimate прогнозирует размер результата конвертации по выборке файлов.
+package estimate
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/converter"
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+)
+
+// Measurement - размер исходника и результата одного файла выборки.
+type Measurement struct {
+	InputBytes  int64
+	OutputBytes int64
+}
+
+// Result - прогноз размера результата для всего набора файлов.
+type Result struct {
+	// Files и InputBytes - количество и суммарный размер всех исходников.
+	Files      int   `json:"files"`
+	InputBytes int64 `json:"input_bytes"`
+
+	// SampleFiles - успешно сконвертированные файлы выборки,
+	// SampleFailed - файлы выборки с ошибкой (в прогноз не входят).
+	SampleFiles  int `json:"sample_files"`
+	SampleFailed int `json:"sample_failed"`
+
+	// SampleInputBytes и SampleOutputBytes - размеры исходников и результатов выборки.
+	SampleInputBytes  int64 `json:"sample_input_bytes"`
+	SampleOutputBytes int64 `json:"sample_output_bytes"`
+
+	// Ratio - отношение размера результата к исходнику по выборке.
+	Ratio float64 `json:"ratio"`
+
+	// Margin - относительная погрешность прогноза (0.1 = ±10%) с доверием 95%;
+	// 0, если файлов в выборке меньше двух.
+	Margin float64 `json:"margin"`
+
+	// ProjectedOutputBytes - прогноз суммарного размера результата.
+	ProjectedOutputBytes int64 `json:"projected_output_bytes"`
+
+	// ProjectedSavingsBytes - прогноз экономии (отрицательная - результат больше).
+	ProjectedSavingsBytes int64 `json:"projected_savings_bytes"`
+
+	// SavingsPercent - экономия в процентах от исходников.
+	SavingsPercent float64 `json:"savings_percent"`
+}
+
+// Select выбирает долю fraction файлов, равномерно по всему списку
+// (в порядке сканирования), чтобы выборка захватывала разные директории.
+// Для непустого списка выбирается хотя бы один файл.
+func Select(files []scanner.File, fraction float64) []scanner.File {
+	return SelectN(files, int(math.Ceil(float64(len(files))*fraction)))
+}
+
+// SelectN выбирает n файлов равномерно по всему списку (не меньше одного
+// и не больше len(files)).
+func SelectN(files []scanner.File, n int) []scanner.File {
+	if len(files) == 0 {
+		return nil
+	}
+	n = min(max(n, 1), len(files))
+
+	sample := make([]scanner.File, 0, n)
+	for i := 0; i < n; i++ {
+		sample = append(sample, files[i*len(files)/n])
+	}
+	return sample
+}
+
+// Extrapolate переносит отношение размеров выборки на весь набор:
+// files исходников суммарно inputBytes. Отношение считается по байтам
+// (большие файлы весят больше), погрешность - по разбросу отношений
+// отдельных файлов с поправкой на конечный набор.
+func Extrapolate(files int, inputBytes int64, samples []Measurement, failed int) *Result {
+	r := &Result{Files: files, InputBytes: inputBytes, SampleFiles: len(samples), SampleFailed: failed}
+	for _, m := range samples {
+		r.SampleInputBytes += m.InputBytes
+		r.SampleOutputBytes += m.OutputBytes
+	}
+	if r.SampleInputBytes == 0 {
+		return r
+	}
+
+	r.Ratio = float64(r.SampleOutputBytes) / float64(r.SampleInputBytes)
+	r.ProjectedOutputBytes = int64(math.Round(float64(inputBytes) * r.Ratio))
+	r.ProjectedSavingsBytes = inputBytes - r.ProjectedOutputBytes
+	if inputBytes > 0 {
+		r.SavingsPercent = float64(r.ProjectedSavingsBytes) / float64(inputBytes) * 100
+	}
+	r.Margin = margin(samples, files, r.Ratio)
+	return r
+}
+
+// margin возвращает относительную погрешность отношения: 1.96 стандартной
+// ошибки среднего отношения файлов, делённые на ratio.
+func margin(samples []Measurement, files int, ratio float64) float64 {
+	n := 0
+	var sum, sumSq float64
+	for _, m := range samples {
+		if m.InputBytes == 0 {
+			continue
+		}
+		x := float64(m.OutputBytes) / float64(m.InputBytes)
+		sum += x
+		sumSq += x * x
+		n++
+	}
+	if n < 2 || ratio == 0 {
+		return 0
+	}
+	mean := sum / float64(n)
+	variance := max((sumSq-float64(n)*mean*mean)/float64(n-1), 0)
+	stdErr := math.Sqrt(variance / float64(n))
+	// Выборка без возвращения: при выборке всего набора погрешности нет
+	if files > n {
+		stdErr *= math.Sqrt(float64(files-n) / float64(files-1))
+	} else {
+		stdErr = 0
+	}
+	return 1.96 * stdErr / ratio
+}
+
+// Run конвертирует выборку files (доля cfg.EstimateSample) во временную
+// директорию конвертером conv в cfg.MaxWorkers() потоков, измеряет размеры
+// и экстраполирует их на все files. Временные результаты удаляются.
+func Run(ctx context.Context, cfg *config.Config, conv converter.Converter, files []scanner.File) (*Result, error) {
+	var inputBytes int64
+	for _, f := range files {
+		inputBytes += f.Info.Size
+	}
+	sample := Select(files, cfg.EstimateSample)
+
+	tmpDir, err := os.MkdirTemp("", "photoconverter-estimate-*")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать временную директорию: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var (
+		wg           sync.WaitGroup
+		mu           sync.Mutex
+		measurements []Measurement
+		failed       int
+	)
+	jobs := make(chan int)
+	for w := 0; w < max(cfg.MaxWorkers(), 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				m, err := measure(ctx, conv, sample[i], filepath.Join(tmpDir, strconv.Itoa(i)+"."+cfg.OutputExt()))
+				mu.Lock()
+				if err == nil {
+					measurements = append(measurements, m)
+				} else {
+					failed++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range sample {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return Extrapolate(len(files), inputBytes, measurements, failed), nil
+}
+
+// measure конвертирует один файл выборки в dstPath и возвращает размеры
+// или ошибку конвертации. Дополнительные результаты (--all-images) входят
+// в размер результата.
+func measure(ctx context.Context, conv converter.Converter, file scanner.File, dstPath string) (Measurement, error) {
+	res := conv.Convert(ctx, file.Path, dstPath)
+	if !res.Success {
+		if res.Error == nil {
+			return Measurement{}, fmt.Errorf("конвертация %s не удалась", file.Path)
+		}
+		return Measurement{}, res.Error
+	}
+	m := Measurement{InputBytes: file.Info.Size}
+	for _, path := range append([]string{dstPath}, res.Extra...) {
+		if info, err := os.Stat(path); err == nil {
+			m.OutputBytes += info.Size()
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,123 @@
+package estimate
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/converter"
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+)
+
+// halfVipsScript имитирует vips copy: результат - первая половина исходника.
+const halfVipsScript = `#!/bin/sh
+out="${3%%\[*}"
+size=$(wc -c < "$2")
+head -c $((size / 2)) "$2" > "$out"
+`
+
+func TestSelect(t *testing.T) {
+	files := make([]scanner.File, 100)
+	for i := range files {
+		files[i].RelPath = fmt.Sprintf("%03d.jpg", i)
+	}
+
+	tests := []struct {
+		fraction  float64
+		wantCount int
+		wantFirst string
+		wantLast  string
+	}{
+		{fraction: 0.05, wantCount: 5, wantFirst: "000.jpg", wantLast: "080.jpg"},
+		{fraction: 0.001, wantCount: 1, wantFirst: "000.jpg", wantLast: "000.jpg"},
+		{fraction: 1, wantCount: 100, wantFirst: "000.jpg", wantLast: "099.jpg"},
+	}
+	for _, tt := range tests {
+		sample := Select(files, tt.fraction)
+		if len(sample) != tt.wantCount || sample[0].RelPath != tt.wantFirst || sample[len(sample)-1].RelPath != tt.wantLast {
+			t.Errorf("Select(%g): %d файлов %s..%s, want %d %s..%s", tt.fraction, len(sample),
+				sample[0].RelPath, sample[len(sample)-1].RelPath, tt.wantCount, tt.wantFirst, tt.wantLast)
+		}
+	}
+	if Select(nil, 0.5) != nil {
+		t.Error("Select пустого списка должен вернуть nil")
+	}
+}
+
+func TestExtrapolate(t *testing.T) {
+	// Одинаковое сжатие: прогноз точный, погрешности нет
+	uniform := []Measurement{{1000, 250}, {2000, 500}, {4000, 1000}}
+	r := Extrapolate(100, 1_000_000, uniform, 1)
+	if r.Ratio != 0.25 || r.ProjectedOutputBytes != 250_000 || r.ProjectedSavingsBytes != 750_000 {
+		t.Errorf("ratio=%g projected=%d savings=%d, want 0.25/250000/750000", r.Ratio, r.ProjectedOutputBytes, r.ProjectedSavingsBytes)
+	}
+	if r.SavingsPercent != 75 || r.Margin != 0 || r.SampleFiles != 3 || r.SampleFailed != 1 {
+		t.Errorf("savings=%g%% margin=%g sample=%d failed=%d, want 75/0/3/1", r.SavingsPercent, r.Margin, r.SampleFiles, r.SampleFailed)
+	}
+
+	// Отношение по байтам: большой файл весит больше
+	mixed := []Measurement{{1000, 900}, {9000, 900}}
+	r = Extrapolate(1000, 100_000, mixed, 0)
+	if math.Abs(r.Ratio-0.18) > 1e-9 || r.ProjectedOutputBytes != 18_000 {
+		t.Errorf("ratio=%g projected=%d, want 0.18/18000", r.Ratio, r.ProjectedOutputBytes)
+	}
+	if r.Margin <= 0 {
+		t.Errorf("margin=%g, разброс отношений должен давать погрешность", r.Margin)
+	}
+
+	// Результат больше исходников - отрицательная экономия
+	r = Extrapolate(10, 1000, []Measurement{{100, 150}}, 0)
+	if r.ProjectedSavingsBytes != -500 || r.SavingsPercent != -50 {
+		t.Errorf("savings=%d (%g%%), want -500 (-50%%)", r.ProjectedSavingsBytes, r.SavingsPercent)
+	}
+}
+
+func TestRun_UniformFixtures(t *testing.T) {
+	dir := t.TempDir()
+	vips := filepath.Join(dir, "vips")
+	if err := os.WriteFile(vips, []byte(halfVipsScript), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.InputDir = filepath.Join(dir, "in")
+	cfg.OutputFormat = config.FormatJPEG
+	cfg.NoVerify = true
+	cfg.Workers = 2
+	cfg.EstimateSample = 0.25
+	if err := os.MkdirAll(cfg.InputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		content := strings.Repeat("x", 1000)
+		if err := os.WriteFile(filepath.Join(cfg.InputDir, fmt.Sprintf("%02d.jpg", i)), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scanned, errs := scanner.New(cfg).Scan(context.Background())
+	var files []scanner.File
+	for f := range scanned {
+		files = append(files, f)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Run(context.Background(), cfg, converter.New(vips, cfg), files)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if r.Files != 20 || r.InputBytes != 20_000 || r.SampleFiles != 5 || r.SampleFailed != 0 {
+		t.Fatalf("files=%d input=%d sample=%d failed=%d, want 20/20000/5/0", r.Files, r.InputBytes, r.SampleFiles, r.SampleFailed)
+	}
+	if r.Ratio != 0.5 || r.ProjectedOutputBytes != 10_000 || r.SavingsPercent != 50 || r.Margin != 0 {
+		t.Errorf("ratio=%g projected=%d savings=%g%% margin=%g, want 0.5/10000/50/0",
+			r.Ratio, r.ProjectedOutputBytes, r.SavingsPercent, r.Margin)
+	}
+}
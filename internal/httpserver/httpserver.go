@@ -0,0 +1,77 @@
+// Package httpserver запускает HTTP-серверы метрик и статуса (--metrics-addr, --status-addr)
+// с остановкой по отмене контекста.
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Server - HTTP-сервер вспомогательных эндпоинтов.
+type Server struct {
+	srv       *http.Server
+	ln        net.Listener
+	done      chan struct{}
+	serveErr  error
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Start начинает обслуживать handler на addr.
+// Сервер останавливается при отмене ctx или вызове Close.
+func Start(ctx context.Context, addr string, handler http.Handler) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось запустить HTTP-сервер на %s: %w", addr, err)
+	}
+
+	s := &Server{
+		srv:  &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second},
+		ln:   ln,
+		done: make(chan struct{}),
+	}
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.serveErr = err
+		}
+		close(s.done)
+	}()
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = s.Close()
+		case <-s.done:
+		}
+	}()
+	return s, nil
+}
+
+// Addr возвращает адрес, на котором слушает сервер.
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Close корректно останавливает сервер, дожидаясь текущих запросов.
+func (s *Server) Close() error {
+	s.closeOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.closeErr = s.srv.Shutdown(ctx)
+		<-s.done
+		if s.closeErr == nil {
+			s.closeErr = s.serveErr
+		}
+	})
+	return s.closeErr
+}
+
+/*
+Возможные расширения:
+- TLS и базовая аутентификация
+- Один сервер для метрик и статуса при совпадающих адресах
+*/
@@ -0,0 +1,114 @@
+// Package magickfinder отвечает за поиск бинарника ImageMagick в системе.
+package magickfinder
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// versionRe выделяет версию из вывода "magick -version":
+// "Version: ImageMagick 7.1.1-15 Q16-HDRI x86_64 ...".
+var versionRe = regexp.MustCompile(`ImageMagick (\S+)`)
+
+// MagickInfo содержит информацию о найденном ImageMagick.
+type MagickInfo struct {
+	// Path - абсолютный путь к бинарнику (magick или convert).
+	Path string
+
+	// Version - версия ImageMagick (например, "7.1.1-15").
+	Version string
+}
+
+// Finder ищет бинарник ImageMagick.
+type Finder struct {
+	// CustomPath - пользовательский путь к magick (из флага --magick-path).
+	CustomPath string
+
+	// EnvVar - имя переменной окружения для пути к magick.
+	EnvVar string
+}
+
+// NewFinder создаёт новый Finder.
+func NewFinder(customPath string) *Finder {
+	return &Finder{
+		CustomPath: customPath,
+		EnvVar:     "PHOTOCONVERTER_MAGICK",
+	}
+}
+
+// Find ищет ImageMagick в следующем порядке:
+// 1. CustomPath (если задан)
+// 2. Переменная окружения PHOTOCONVERTER_MAGICK
+// 3. magick в PATH (ImageMagick 7)
+// 4. convert в PATH (ImageMagick 6)
+// 5. Рядом с исполняемым файлом в ./bin/<os-arch>/magick
+func (f *Finder) Find() (*MagickInfo, error) {
+	var candidates []string
+
+	if f.CustomPath != "" {
+		candidates = append(candidates, f.CustomPath)
+	}
+	if envPath := os.Getenv(f.EnvVar); envPath != "" {
+		candidates = append(candidates, envPath)
+	}
+	for _, name := range []string{"magick", "convert"} {
+		if path, err := exec.LookPath(name); err == nil {
+			candidates = append(candidates, path)
+		}
+	}
+	if execPath, err := os.Executable(); err == nil {
+		execDir := filepath.Dir(execPath)
+		platformDir := fmt.Sprintf("%s-%s", runtime.GOOS, runtime.GOARCH)
+		candidates = append(candidates,
+			filepath.Join(execDir, "bin", platformDir, magickBinaryName()),
+			filepath.Join(execDir, "bin", magickBinaryName()),
+		)
+	}
+
+	for _, path := range candidates {
+		if info, err := checkMagick(path); err == nil {
+			return info, nil
+		}
+	}
+
+	return nil, fmt.Errorf("ImageMagick не найден. Проверьте:\n"+
+		"  1. Установлен ли ImageMagick (apt install imagemagick / brew install imagemagick)\n"+
+		"  2. Установлена ли переменная окружения %s\n"+
+		"  3. Указан ли путь через флаг --magick-path", f.EnvVar)
+}
+
+// checkMagick проверяет, является ли путь рабочим ImageMagick. Вывод
+// "-version" должен упоминать ImageMagick: на Windows convert.exe -
+// системная утилита конвертации дисков.
+func checkMagick(path string) (*MagickInfo, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить абсолютный путь: %w", err)
+	}
+	if _, err := os.Stat(absPath); err != nil {
+		return nil, fmt.Errorf("файл не найден: %w", err)
+	}
+
+	output, err := exec.Command(absPath, "-version").Output()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось выполнить %s -version: %w", absPath, err)
+	}
+	m := versionRe.FindStringSubmatch(string(output))
+	if m == nil {
+		return nil, fmt.Errorf("%s не является ImageMagick: %s", absPath, strings.TrimSpace(string(output)))
+	}
+	return &MagickInfo{Path: absPath, Version: m[1]}, nil
+}
+
+// magickBinaryName возвращает имя бинарника magick для текущей ОС.
+func magickBinaryName() string {
+	if runtime.GOOS == "windows" {
+		return "magick.exe"
+	}
+	return "magick"
+}
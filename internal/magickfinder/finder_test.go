@@ -0,0 +1,83 @@
+package magickfinder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFakeBinary создаёт скрипт name, печатающий output на -version.
+func writeFakeBinary(t *testing.T, dir, name, output string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho '"+output+"'\n"), 0755); err != nil {
+		t.Fatalf("не удалось создать %s: %v", name, err)
+	}
+	return path
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name        string
+		binaries    map[string]string // имя -> вывод -version в PATH
+		custom      string            // вывод -version пользовательского бинарника
+		wantName    string
+		wantVersion string
+		wantErr     bool
+	}{
+		{
+			name:        "magick в PATH",
+			binaries:    map[string]string{"magick": "Version: ImageMagick 7.1.1-15 Q16-HDRI x86_64"},
+			wantName:    "magick",
+			wantVersion: "7.1.1-15",
+		},
+		{
+			name:        "convert ImageMagick 6",
+			binaries:    map[string]string{"convert": "Version: ImageMagick 6.9.11-60 Q16 x86_64"},
+			wantName:    "convert",
+			wantVersion: "6.9.11-60",
+		},
+		{
+			name:     "convert не из ImageMagick",
+			binaries: map[string]string{"convert": "Converts FAT volumes to NTFS."},
+			wantErr:  true,
+		},
+		{
+			name:        "пользовательский путь важнее PATH",
+			binaries:    map[string]string{"magick": "Version: ImageMagick 7.1.1-15 Q16"},
+			custom:      "Version: ImageMagick 7.0.10-0 Q8",
+			wantName:    "custom-magick",
+			wantVersion: "7.0.10-0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pathDir := t.TempDir()
+			for name, output := range tt.binaries {
+				writeFakeBinary(t, pathDir, name, output)
+			}
+			t.Setenv("PATH", pathDir)
+			t.Setenv("PHOTOCONVERTER_MAGICK", "")
+
+			var custom string
+			if tt.custom != "" {
+				custom = writeFakeBinary(t, t.TempDir(), "custom-magick", tt.custom)
+			}
+
+			info, err := NewFinder(custom).Find()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Find() = %+v, want ошибку", info)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Find(): %v", err)
+			}
+			if filepath.Base(info.Path) != tt.wantName || info.Version != tt.wantVersion {
+				t.Errorf("Find() = %s %s, want %s %s", info.Path, info.Version, tt.wantName, tt.wantVersion)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Package manifest формирует машиночитаемый отчёт о результатах запуска.
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/storage"
+)
+
+const (
+	// StatusOK - файл сконвертирован.
+	StatusOK = "ok"
+	// StatusFailed - ошибка конвертации.
+	StatusFailed = "failed"
+	// StatusPlanned - файл был бы сконвертирован (dry-run).
+	StatusPlanned = "planned"
+)
+
+// Manifest - отчёт о запуске конвертации.
+type Manifest struct {
+	// StartedAt - время начала запуска.
+	StartedAt time.Time `json:"started_at"`
+
+	// FinishedAt - время завершения запуска.
+	FinishedAt time.Time `json:"finished_at"`
+
+	// DryRun - запуск в режиме симуляции.
+	DryRun bool `json:"dry_run"`
+
+	// Totals - итоговая статистика запуска.
+	Totals Totals `json:"totals"`
+
+	// Config - эффективная конфигурация запуска.
+	Config RunConfig `json:"config"`
+
+	// Files - файлы, обработанные в этом запуске.
+	Files []File `json:"files"`
+}
+
+// Totals содержит итоговую статистику запуска.
+type Totals struct {
+	Processed   int64 `json:"processed"`
+	Skipped     int64 `json:"skipped"`
+	Failed      int64 `json:"failed"`
+	Total       int64 `json:"total"`
+	InputBytes  int64 `json:"input_bytes"`
+	OutputBytes int64 `json:"output_bytes"`
+}
+
+// RunConfig - эффективная конфигурация, с которой выполнялся запуск.
+type RunConfig struct {
+	InputDir      string          `json:"input_dir"`
+	OutputDir     string          `json:"output_dir"`
+	Mode          string          `json:"mode"`
+	Preset        string          `json:"preset,omitempty"`
+	Workers       int             `json:"workers"`
+	KeepTree      bool            `json:"keep_tree"`
+	OutputParams  json.RawMessage `json:"output_params"`
+	OutParamsHash string          `json:"out_params_hash"`
+}
+
+// File - запись о файле в манифесте.
+type File struct {
+	Src        string `json:"src"`
+	Dst        string `json:"dst,omitempty"`
+	InputSize  int64  `json:"input_size"`
+	OutputSize int64  `json:"output_size"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Build формирует манифест по задачам текущего запуска.
+func Build(cfg *config.Config, jobs []storage.Job, totals Totals, startedAt, finishedAt time.Time) *Manifest {
+	m := &Manifest{
+		StartedAt:  startedAt,
+		FinishedAt: finishedAt,
+		DryRun:     cfg.DryRun,
+		Totals:     totals,
+		Config: RunConfig{
+			InputDir:      cfg.InputDir,
+			OutputDir:     cfg.OutputDir,
+			Mode:          string(cfg.Mode),
+			Preset:        cfg.Preset,
+			Workers:       cfg.Workers,
+			KeepTree:      cfg.KeepTree,
+			OutputParams:  json.RawMessage(cfg.OutputParams()),
+			OutParamsHash: cfg.OutputParamsHash(),
+		},
+		Files: make([]File, 0, len(jobs)),
+	}
+
+	for _, job := range jobs {
+		f := File{
+			Src:       job.SrcPath,
+			InputSize: job.SrcSize,
+		}
+		if job.DstPath != nil {
+			f.Dst = *job.DstPath
+		}
+		if job.DstSize != nil {
+			f.OutputSize = *job.DstSize
+		}
+		if job.Error != nil {
+			f.Error = *job.Error
+		}
+
+		switch job.Status {
+		case storage.StatusOK:
+			f.Status = StatusOK
+			if cfg.DryRun {
+				f.Status = StatusPlanned
+			}
+		default:
+			f.Status = string(job.Status)
+		}
+		m.Files = append(m.Files, f)
+	}
+
+	return m
+}
+
+// WriteFile сохраняет манифест в JSON файл.
+// Запись атомарная: сначала во временный файл, затем переименование.
+func (m *Manifest) WriteFile(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать манифест: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("не удалось создать директорию манифеста: %w", err)
+		}
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("не удалось записать манифест: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("не удалось сохранить манифест: %w", err)
+	}
+	return nil
+}
+
+/*
+Возможные расширения:
+- Добавить пропущенные файлы в список (сейчас только в итогах)
+- Добавить формат CSV
+*/
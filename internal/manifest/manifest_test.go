@@ -0,0 +1,139 @@
+package manifest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/storage"
+)
+
+// newTestStorage создаёт хранилище с задачами: одна успешная и одна с ошибкой.
+func newTestStorage(t *testing.T, cfg *config.Config) *storage.Storage {
+	t.Helper()
+	st, err := storage.New(filepath.Join(t.TempDir(), "state.sqlite"))
+	if err != nil {
+		t.Fatalf("storage.New: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	start := func(path string, size int64) int64 {
+		res, err := st.TryStartJob(
+			storage.FileInfo{Path: path, Size: size, Mtime: 1},
+			string(cfg.OutputFormat), cfg.OutputParams(), cfg.OutputParamsHash(), false,
+		)
+		if err != nil || !res.Started {
+			t.Fatalf("TryStartJob(%s): %v", path, err)
+		}
+		return res.JobID
+	}
+
+	okID := start("/in/a.jpg", 1000)
+	if err := st.FinalizeJobOK(okID, "/out/a.webp", 400); err != nil {
+		t.Fatal(err)
+	}
+	failedID := start("/in/b.jpg", 2000)
+	if err := st.FinalizeJobFailed(failedID, "битый файл"); err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestManifest_WriteFile(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.InputDir = "/in"
+	cfg.OutputDir = "/out"
+	cfg.OutputFormat = config.FormatWebP
+
+	startedAt := time.Now().Add(-time.Second)
+	st := newTestStorage(t, cfg)
+
+	jobs, err := st.ListJobsForRun(startedAt)
+	if err != nil {
+		t.Fatalf("ListJobsForRun: %v", err)
+	}
+
+	m := Build(cfg, jobs, Totals{Processed: 1, Failed: 1, Total: 2, InputBytes: 1000, OutputBytes: 400}, startedAt, time.Now())
+	path := filepath.Join(t.TempDir(), "reports", "manifest.json")
+	if err := m.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("манифест не является JSON объектом: %v", err)
+	}
+	for _, key := range []string{"started_at", "finished_at", "dry_run", "totals", "config", "files"} {
+		if _, ok := raw[key]; !ok {
+			t.Errorf("в манифесте нет поля %q", key)
+		}
+	}
+
+	var got Manifest
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Totals.Processed != 1 || got.Totals.Failed != 1 || got.Totals.Total != 2 {
+		t.Errorf("totals = %+v", got.Totals)
+	}
+	if got.Config.InputDir != "/in" || got.Config.OutputDir != "/out" || got.Config.Mode != "skip" {
+		t.Errorf("config = %+v", got.Config)
+	}
+	var params map[string]interface{}
+	if err := json.Unmarshal(got.Config.OutputParams, &params); err != nil || params["format"] != "webp" {
+		t.Errorf("output_params = %s", got.Config.OutputParams)
+	}
+
+	if len(got.Files) != 2 {
+		t.Fatalf("files: %d, want 2", len(got.Files))
+	}
+	want := []File{
+		{Src: "/in/a.jpg", Dst: "/out/a.webp", InputSize: 1000, OutputSize: 400, Status: StatusOK},
+		{Src: "/in/b.jpg", InputSize: 2000, Status: StatusFailed, Error: "битый файл"},
+	}
+	for i, w := range want {
+		if got.Files[i] != w {
+			t.Errorf("files[%d] = %+v, want %+v", i, got.Files[i], w)
+		}
+	}
+}
+
+func TestManifest_DryRunPlanned(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.DryRun = true
+
+	startedAt := time.Now().Add(-time.Second)
+	st := newTestStorage(t, cfg)
+	jobs, err := st.ListJobsForRun(startedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := Build(cfg, jobs, Totals{}, startedAt, time.Now())
+	if !m.DryRun {
+		t.Error("dry_run = false")
+	}
+	if m.Files[0].Status != StatusPlanned || m.Files[0].Dst != "/out/a.webp" {
+		t.Errorf("files[0] = %+v, want planned с путём назначения", m.Files[0])
+	}
+}
+
+func TestListJobsForRun_ExcludesOlderJobs(t *testing.T) {
+	cfg := config.DefaultConfig()
+	st := newTestStorage(t, cfg)
+
+	jobs, err := st.ListJobsForRun(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 0 {
+		t.Errorf("задач после since: %d, want 0", len(jobs))
+	}
+}
@@ -0,0 +1,155 @@
+// Package metrics собирает метрики конвертации и отдаёт их по HTTP
+// в текстовом формате Prometheus.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DurationBuckets - верхние границы корзин гистограммы длительности конвертации (секунды).
+var DurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// Metrics хранит счётчики обработки файлов.
+// Все методы безопасны для вызова из нескольких горутин и на nil-получателе
+// (метрики выключены).
+type Metrics struct {
+	processed   atomic.Int64
+	skipped     atomic.Int64
+	failed      atomic.Int64
+	inFlight    atomic.Int64
+	inputBytes  atomic.Int64
+	outputBytes atomic.Int64
+
+	mu          sync.Mutex
+	bucketCount []uint64
+	durCount    uint64
+	durSum      float64
+}
+
+// New создаёт пустой набор метрик.
+func New() *Metrics {
+	return &Metrics{bucketCount: make([]uint64, len(DurationBuckets))}
+}
+
+// AddProcessed учитывает успешно сконвертированный файл и его размеры.
+func (m *Metrics) AddProcessed(inBytes, outBytes int64) {
+	if m == nil {
+		return
+	}
+	m.processed.Add(1)
+	m.inputBytes.Add(inBytes)
+	m.outputBytes.Add(outBytes)
+}
+
+// AddSkipped учитывает пропущенный файл.
+func (m *Metrics) AddSkipped() {
+	if m == nil {
+		return
+	}
+	m.skipped.Add(1)
+}
+
+// AddFailed учитывает n файлов, завершившихся ошибкой.
+func (m *Metrics) AddFailed(n int64) {
+	if m == nil {
+		return
+	}
+	m.failed.Add(n)
+}
+
+// StartConversion отмечает начало конвертации и возвращает функцию,
+// которую нужно вызвать по её окончании.
+func (m *Metrics) StartConversion() func() {
+	if m == nil {
+		return func() {}
+	}
+	m.inFlight.Add(1)
+	return func() { m.inFlight.Add(-1) }
+}
+
+// ObserveDuration добавляет длительность конвертации в гистограмму.
+func (m *Metrics) ObserveDuration(d time.Duration) {
+	if m == nil {
+		return
+	}
+	sec := d.Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, le := range DurationBuckets {
+		if sec <= le {
+			m.bucketCount[i]++
+		}
+	}
+	m.durCount++
+	m.durSum += sec
+}
+
+// WriteTo пишет метрики в текстовом формате Prometheus (version 0.0.4).
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+
+	counter := func(name, help string, v int64) {
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, v)
+	}
+	counter("photoconverter_files_processed_total", "Successfully converted files.", m.processed.Load())
+	counter("photoconverter_files_skipped_total", "Skipped files (already converted or duplicates).", m.skipped.Load())
+	counter("photoconverter_files_failed_total", "Files that failed to convert.", m.failed.Load())
+	counter("photoconverter_input_bytes_total", "Total size of successfully converted source files.", m.inputBytes.Load())
+	counter("photoconverter_output_bytes_total", "Total size of produced output files.", m.outputBytes.Load())
+
+	fmt.Fprintf(cw, "# HELP photoconverter_conversions_in_flight Conversions currently running.\n"+
+		"# TYPE photoconverter_conversions_in_flight gauge\nphotoconverter_conversions_in_flight %d\n", m.inFlight.Load())
+
+	m.mu.Lock()
+	buckets := append([]uint64(nil), m.bucketCount...)
+	count, sum := m.durCount, m.durSum
+	m.mu.Unlock()
+
+	const hist = "photoconverter_conversion_duration_seconds"
+	fmt.Fprintf(cw, "# HELP %s Duration of a single vips conversion.\n# TYPE %s histogram\n", hist, hist)
+	for i, le := range DurationBuckets {
+		fmt.Fprintf(cw, "%s_bucket{le=\"%s\"} %d\n", hist, strconv.FormatFloat(le, 'g', -1, 64), buckets[i])
+	}
+	fmt.Fprintf(cw, "%s_bucket{le=\"+Inf\"} %d\n", hist, count)
+	fmt.Fprintf(cw, "%s_sum %s\n%s_count %d\n", hist, strconv.FormatFloat(sum, 'g', -1, 64), hist, count)
+
+	return cw.n, cw.err
+}
+
+// Handler возвращает HTTP-обработчик, отдающий метрики.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = m.WriteTo(w)
+	})
+}
+
+// countingWriter считает записанные байты и запоминает первую ошибку.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+/*
+Возможные расширения:
+- Метки по формату и пресету (--multi-preset)
+- Гистограмма размеров выходных файлов
+- Метрики Go runtime (горутины, память)
+*/
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteTo_Histogram(t *testing.T) {
+	m := New()
+	m.ObserveDuration(30 * time.Millisecond)
+	m.ObserveDuration(2 * time.Second)
+
+	var sb strings.Builder
+	if _, err := m.WriteTo(&sb); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+
+	for _, want := range []string{
+		`photoconverter_conversion_duration_seconds_bucket{le="0.05"} 1`,
+		`photoconverter_conversion_duration_seconds_bucket{le="2.5"} 2`,
+		`photoconverter_conversion_duration_seconds_bucket{le="+Inf"} 2`,
+		"photoconverter_conversion_duration_seconds_sum 2.03",
+		"photoconverter_conversion_duration_seconds_count 2",
+		"# TYPE photoconverter_conversions_in_flight gauge",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("нет строки %q в выводе:\n%s", want, out)
+		}
+	}
+}
+
+func TestHandler_InFlight(t *testing.T) {
+	m := New()
+	done := m.StartConversion()
+
+	srv := httptest.NewServer(m.Handler())
+	defer srv.Close()
+
+	get := func() string {
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	if body := get(); !strings.Contains(body, "photoconverter_conversions_in_flight 1") {
+		t.Errorf("in-flight не учтён:\n%s", body)
+	}
+	done()
+	if body := get(); !strings.Contains(body, "photoconverter_conversions_in_flight 0") {
+		t.Errorf("in-flight не уменьшился:\n%s", body)
+	}
+}
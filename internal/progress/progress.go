@@ -2,6 +2,7 @@
 package progress
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -11,6 +12,80 @@ import (
 	"github.com/schollz/progressbar/v3"
 )
 
+const (
+	// FormatBar - интерактивный прогресс-бар для терминала.
+	FormatBar = "bar"
+	// FormatJSON - поток JSON-объектов (по одному на строку) для внешних программ.
+	FormatJSON = "json"
+)
+
+const (
+	// DefaultETASmoothing - коэффициент сглаживания ETA по умолчанию.
+	DefaultETASmoothing = 0.03
+
+	// etaWindow - сколько последних интервалов между файлами учитывает ETA.
+	etaWindow = 128
+)
+
+// Update описывает состояние прогресса на момент изменения.
+type Update struct {
+	// Event - тип события: processed, skipped, failed, phase, pause, resume, finish.
+	Event string `json:"event"`
+
+	// Processed - количество обработанных файлов.
+	Processed int64 `json:"processed"`
+
+	// Skipped - количество пропущенных файлов.
+	Skipped int64 `json:"skipped"`
+
+	// Failed - количество файлов с ошибками.
+	Failed int64 `json:"failed"`
+
+	// Total - общее количество файлов (-1 если неизвестно).
+	Total int64 `json:"total"`
+
+	// CurrentFile - файл, к которому относится событие (для finish и событий
+	// без файла - файл последнего события).
+	CurrentFile string `json:"current_file,omitempty"`
+
+	// ElapsedSec - время с начала обработки в секундах.
+	ElapsedSec float64 `json:"elapsed_sec"`
+
+	// Phase, PhaseDone, PhaseFailed, PhaseTotal - дополнительный этап
+	// (например, hash) и его счётчики. Событие этапа имеет Event = "phase".
+	Phase       string `json:"phase,omitempty"`
+	PhaseDone   int64  `json:"phase_done,omitempty"`
+	PhaseFailed int64  `json:"phase_failed,omitempty"`
+	PhaseTotal  int64  `json:"phase_total,omitempty"`
+
+	// DoneBytes, TotalBytes - обработанный и общий объём исходных файлов
+	// (только в режиме ByBytes).
+	DoneBytes  int64 `json:"done_bytes,omitempty"`
+	TotalBytes int64 `json:"total_bytes,omitempty"`
+}
+
+// Emitter получает обновления прогресса вместо TTY прогресс-бара.
+type Emitter interface {
+	// Emit вызывается при каждом изменении счётчиков.
+	Emit(u Update)
+}
+
+// JSONEmitter пишет каждое обновление как отдельную строку JSON.
+type JSONEmitter struct {
+	enc *json.Encoder
+}
+
+// NewJSONEmitter создаёт JSONEmitter, пишущий в w.
+func NewJSONEmitter(w io.Writer) *JSONEmitter {
+	return &JSONEmitter{enc: json.NewEncoder(w)}
+}
+
+// Emit записывает обновление одной строкой JSON.
+// Вызовы сериализуются мьютексом Bar.
+func (e *JSONEmitter) Emit(u Update) {
+	_ = e.enc.Encode(u)
+}
+
 // Bar представляет прогресс-бар с поддержкой ETA.
 type Bar struct {
 	// bar - внутренний progressbar.
@@ -39,6 +114,60 @@ type Bar struct {
 
 	// writer - куда выводить (по умолчанию os.Stderr).
 	writer io.Writer
+
+	// emitter - получатель машиночитаемых обновлений (вместо bar).
+	emitter Emitter
+
+	// current - файл последнего события processed, skipped или failed.
+	current string
+
+	// byBytes - прогресс по объёму данных вместо количества файлов.
+	byBytes bool
+
+	// totalBytes, doneBytes - общий и обработанный объём (режим byBytes).
+	totalBytes int64
+	doneBytes  int64
+
+	// description - подпись бара без состояния этапа.
+	description string
+
+	// phase - дополнительный этап, показываемый в подписи бара.
+	phase *Phase
+
+	// paused - обработка на паузе (отображается в подписи).
+	paused bool
+
+	// smoothing - коэффициент экспоненциального сглаживания ETA (0 < a <= 1).
+	smoothing float64
+
+	// lastTick - время последнего продвижения бара (или снятия паузы).
+	lastTick time.Time
+
+	// durations - кольцевой буфер последних интервалов между продвижениями
+	// бара в секундах на единицу прогресса (файл или байт в режиме byBytes);
+	// durationsNext - позиция следующей записи, durationsLen - число записей.
+	durations     [etaWindow]float64
+	durationsNext int
+	durationsLen  int
+
+	// now - источник времени (подменяется в тестах).
+	now func() time.Time
+}
+
+// Phase - дополнительный этап обработки (например, хэширование в режиме dedup),
+// идущий параллельно с основным. Его счётчик выводится в подписи основного бара,
+// а в JSON-режиме - отдельными событиями "phase".
+type Phase struct {
+	bar *Bar
+
+	// name - идентификатор этапа для JSON, label - подпись для бара.
+	name  string
+	label string
+
+	// total - количество файлов этапа (-1 или 0 = неизвестно).
+	total  int64
+	done   int64
+	failed int64
 }
 
 // Options содержит настройки для прогресс-бара.
@@ -52,40 +181,82 @@ type Options struct {
 	// Disabled - отключить прогресс-бар (только текстовый вывод).
 	Disabled bool
 
-	// Writer - куда выводить (по умолчанию os.Stderr).
+	// Writer - куда выводить (по умолчанию os.Stderr, для json - os.Stdout).
 	Writer io.Writer
+
+	// Format - формат вывода: bar (по умолчанию) или json.
+	Format string
+
+	// Emitter - пользовательский получатель обновлений.
+	// Если задан, используется вместо прогресс-бара.
+	Emitter Emitter
+
+	// ByBytes - показывать прогресс по объёму данных: бар растёт на размер
+	// файла, скорость и ETA считаются в байтах (точнее для файлов разного размера).
+	ByBytes bool
+
+	// TotalBytes - суммарный размер исходных файлов (для ByBytes).
+	TotalBytes int64
+
+	// ETASmoothing - коэффициент сглаживания ETA от 0 до 1 (0 = DefaultETASmoothing):
+	// чем больше, тем быстрее ETA реагирует на изменение скорости и тем сильнее
+	// скачет на файлах разного размера.
+	ETASmoothing float64
 }
 
 // New создаёт новый прогресс-бар.
 func New(opts Options) *Bar {
 	writer := opts.Writer
-	if writer == nil {
-		writer = os.Stderr
-	}
 
 	b := &Bar{
-		disabled:  opts.Disabled,
-		total:     opts.Total,
-		startTime: time.Now(),
-		writer:    writer,
+		disabled:   opts.Disabled,
+		total:      opts.Total,
+		startTime:  time.Now(),
+		byBytes:    opts.ByBytes,
+		totalBytes: opts.TotalBytes,
+		smoothing:  opts.ETASmoothing,
+		now:        time.Now,
+	}
+	b.lastTick = b.startTime
+	if b.smoothing <= 0 || b.smoothing > 1 {
+		b.smoothing = DefaultETASmoothing
 	}
 
-	if !opts.Disabled && opts.Total > 0 {
-		description := opts.Description
-		if description == "" {
-			description = "Обработка"
+	b.description = opts.Description
+	if b.description == "" {
+		b.description = "Обработка"
+	}
+
+	// Машиночитаемый режим: обновления уходят в Emitter,
+	// а текстовые сообщения - в stderr, чтобы не смешиваться с JSON
+	if !opts.Disabled && (opts.Emitter != nil || opts.Format == FormatJSON) {
+		b.emitter = opts.Emitter
+		if b.emitter == nil {
+			if writer == nil {
+				writer = os.Stdout
+			}
+			b.emitter = NewJSONEmitter(writer)
 		}
+		b.writer = os.Stderr
+		return b
+	}
+
+	if writer == nil {
+		writer = os.Stderr
+	}
+	b.writer = writer
 
-		b.bar = progressbar.NewOptions64(
-			opts.Total,
+	limit := opts.Total
+	if opts.ByBytes {
+		limit = opts.TotalBytes
+	}
+	if !opts.Disabled && limit > 0 {
+		options := []progressbar.Option{
 			progressbar.OptionSetWriter(writer),
 			progressbar.OptionEnableColorCodes(true),
-			progressbar.OptionShowBytes(false),
 			progressbar.OptionSetWidth(40),
 			progressbar.OptionShowCount(),
-			progressbar.OptionShowIts(),
-			progressbar.OptionSetItsString("файл"),
-			progressbar.OptionSetDescription(description),
+			progressbar.OptionSetDescription(b.description),
 			progressbar.OptionSetTheme(progressbar.Theme{
 				Saucer:        "[green]█[reset]",
 				SaucerHead:    "[green]▓[reset]",
@@ -96,48 +267,121 @@ func New(opts Options) *Bar {
 			progressbar.OptionOnCompletion(func() {
 				fmt.Fprintln(writer)
 			}),
-			progressbar.OptionSetPredictTime(true),
+			// Прогноз библиотеки считает скорость постоянной и скачет на файлах
+			// разного размера: вместо него в подписи выводится сглаженный ETA
+			progressbar.OptionSetPredictTime(false),
+			progressbar.OptionSetElapsedTime(true),
 			progressbar.OptionFullWidth(),
-		)
+		}
+		// В режиме ByBytes счётчик, скорость (MB/s) и ETA считаются в байтах
+		if opts.ByBytes {
+			options = append(options, progressbar.OptionShowBytes(true))
+		} else {
+			options = append(options, progressbar.OptionShowIts(), progressbar.OptionSetItsString("файл"))
+		}
+
+		b.bar = progressbar.NewOptions64(limit, options...)
 	}
 
 	return b
 }
 
-// Increment увеличивает счётчик на 1 (обработан файл).
-func (b *Bar) Increment() {
+// Increment увеличивает счётчик на 1 (обработан файл file размером size байт).
+func (b *Bar) Increment(file string, size int64) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	b.processed++
-
-	if b.bar != nil {
-		_ = b.bar.Add(1)
-	}
+	b.current = file
+	b.advance(size)
+	b.emit("processed")
 }
 
-// IncrementSkipped увеличивает счётчик пропущенных на 1.
-func (b *Bar) IncrementSkipped() {
+// IncrementSkipped увеличивает счётчик пропущенных на 1 (файл file).
+func (b *Bar) IncrementSkipped(file string, size int64) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	b.skipped++
+	b.current = file
+	b.advance(size)
+	b.emit("skipped")
+}
+
+// IncrementFailed увеличивает счётчик ошибок на 1 (файл file).
+func (b *Bar) IncrementFailed(file string, size int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failed++
+	b.current = file
+	b.advance(size)
+	b.emit("failed")
+}
 
+// advance продвигает бар на один файл или, в режиме byBytes, на его размер.
+// Вызывается под блокировкой mu.
+func (b *Bar) advance(size int64) {
+	step := int64(1)
+	if b.byBytes {
+		b.doneBytes += size
+		step = size
+	}
+	b.recordDuration(step)
 	if b.bar != nil {
-		_ = b.bar.Add(1)
+		b.describe()
+		_ = b.bar.Add64(step)
+	}
+}
+
+// recordDuration запоминает интервал с прошлого продвижения бара в расчёте
+// на единицу прогресса. Пустой шаг (файл 0 байт в режиме byBytes) не
+// записывается: его время достаётся следующему файлу.
+// Вызывается под блокировкой mu.
+func (b *Bar) recordDuration(step int64) {
+	if step <= 0 {
+		return
 	}
+	now := b.now()
+	b.durations[b.durationsNext] = now.Sub(b.lastTick).Seconds() / float64(step)
+	b.durationsNext = (b.durationsNext + 1) % etaWindow
+	b.durationsLen = min(b.durationsLen+1, etaWindow)
+	b.lastTick = now
 }
 
-// IncrementFailed увеличивает счётчик ошибок на 1.
-func (b *Bar) IncrementFailed() {
+// ETA возвращает оставшееся время по экспоненциальному скользящему среднему
+// последних интервалов между файлами. false, если оценки ещё нет: общее
+// количество неизвестно или не обработано ни одного файла.
+func (b *Bar) ETA() (time.Duration, bool) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
+	return b.eta()
+}
 
-	b.failed++
+// eta считает ETA. Вызывается под блокировкой mu.
+func (b *Bar) eta() (time.Duration, bool) {
+	remaining := b.total - b.processed - b.skipped - b.failed
+	if b.byBytes {
+		remaining = b.totalBytes - b.doneBytes
+	}
+	if b.durationsLen == 0 || b.total <= 0 || (b.byBytes && b.totalBytes <= 0) {
+		return 0, false
+	}
+	if remaining <= 0 {
+		return 0, true
+	}
 
-	if b.bar != nil {
-		_ = b.bar.Add(1)
+	// Вес интервала убывает в (1 - smoothing) раз с каждым более новым;
+	// деление на сумму весов убирает смещение к нулю в начале обработки
+	var sum, weights float64
+	weight := 1.0
+	for i := 1; i <= b.durationsLen; i++ {
+		sum += weight * b.durations[(b.durationsNext-i+etaWindow)%etaWindow]
+		weights += weight
+		weight *= 1 - b.smoothing
 	}
+	avg := sum / weights
+	return time.Duration(avg * float64(remaining) * float64(time.Second)), true
 }
 
 // SetTotal устанавливает общее количество элементов.
@@ -148,7 +392,19 @@ func (b *Bar) SetTotal(total int64) {
 
 	b.total = total
 
-	if b.bar != nil {
+	if b.bar != nil && !b.byBytes {
+		b.bar.ChangeMax64(total)
+	}
+}
+
+// SetTotalBytes устанавливает суммарный размер файлов (режим ByBytes).
+func (b *Bar) SetTotalBytes(total int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.totalBytes = total
+
+	if b.bar != nil && b.byBytes {
 		b.bar.ChangeMax64(total)
 	}
 }
@@ -161,6 +417,120 @@ func (b *Bar) Finish() {
 	if b.bar != nil {
 		_ = b.bar.Finish()
 	}
+	b.emit("finish")
+}
+
+// StartPhase добавляет дополнительный этап с идентификатором name, подписью label
+// и количеством файлов total.
+func (b *Bar) StartPhase(name, label string, total int64) *Phase {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.phase = &Phase{bar: b, name: name, label: label, total: total}
+	b.describe()
+	return b.phase
+}
+
+// Increment отмечает файл, прошедший этап.
+func (ph *Phase) Increment() {
+	ph.bar.mu.Lock()
+	defer ph.bar.mu.Unlock()
+
+	ph.done++
+	ph.bar.describe()
+	ph.bar.emit("phase")
+}
+
+// IncrementFailed отмечает файл, на котором этап завершился ошибкой.
+func (ph *Phase) IncrementFailed() {
+	ph.bar.mu.Lock()
+	defer ph.bar.mu.Unlock()
+
+	ph.failed++
+	ph.bar.describe()
+	ph.bar.emit("phase")
+}
+
+// Stats возвращает счётчики этапа.
+func (ph *Phase) Stats() (done, failed int64) {
+	ph.bar.mu.Lock()
+	defer ph.bar.mu.Unlock()
+	return ph.done, ph.failed
+}
+
+// SetPaused отмечает паузу обработки: к подписи бара добавляется "(пауза)",
+// в JSON-режиме отправляются события pause и resume.
+func (b *Bar) SetPaused(paused bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.paused == paused {
+		return
+	}
+	b.paused = paused
+	// Время паузы не входит в интервал до следующего файла
+	if !paused {
+		b.lastTick = b.now()
+	}
+	b.describe()
+	if b.bar != nil {
+		_ = b.bar.RenderBlank()
+	}
+	if paused {
+		b.emit("pause")
+	} else {
+		b.emit("resume")
+	}
+}
+
+// describe обновляет подпись бара с учётом этапа, паузы и ETA.
+// Вызывается под блокировкой mu.
+func (b *Bar) describe() {
+	if b.bar == nil {
+		return
+	}
+	description := b.description
+	if b.phase != nil {
+		progressed := b.phase.done + b.phase.failed
+		if b.phase.total > 0 {
+			description += fmt.Sprintf(" [%s %d/%d]", b.phase.label, progressed, b.phase.total)
+		} else {
+			description += fmt.Sprintf(" [%s %d]", b.phase.label, progressed)
+		}
+	}
+	if b.paused {
+		description += " (пауза)"
+	} else if eta, ok := b.eta(); ok && eta > 0 {
+		description += fmt.Sprintf(" (осталось ~%s)", eta.Round(time.Second))
+	}
+	b.bar.Describe(description)
+}
+
+// emit отправляет текущее состояние в Emitter.
+// Вызывается под блокировкой mu.
+func (b *Bar) emit(event string) {
+	if b.emitter == nil {
+		return
+	}
+
+	u := Update{
+		Event:       event,
+		Processed:   b.processed,
+		Skipped:     b.skipped,
+		Failed:      b.failed,
+		Total:       b.total,
+		CurrentFile: b.current,
+		ElapsedSec:  time.Since(b.startTime).Seconds(),
+		DoneBytes:   b.doneBytes,
+		TotalBytes:  b.totalBytes,
+	}
+	if b.phase != nil {
+		u.Phase = b.phase.name
+		u.PhaseDone = b.phase.done
+		u.PhaseFailed = b.phase.failed
+		u.PhaseTotal = b.phase.total
+	}
+	b.emitter.Emit(u)
 }
 
 // Clear очищает прогресс-бар (для вывода сообщений).
@@ -173,6 +543,13 @@ func (b *Bar) Clear() {
 	}
 }
 
+// Total возвращает общее количество элементов (-1 = неизвестно).
+func (b *Bar) Total() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.total
+}
+
 // Stats возвращает текущую статистику.
 func (b *Bar) Stats() (processed, skipped, failed int64) {
 	b.mu.Lock()
@@ -208,8 +585,6 @@ func (b *Bar) WriteMessage(format string, args ...interface{}) {
 
 /*
 Возможные расширения:
-- Добавить поддержку нескольких прогресс-баров (для разных этапов)
+- Отдельные строки терминала для этапов вместо подписи основного бара
 - Добавить историю скорости обработки
-- Добавить поддержку pause/resume
-- Добавить вывод в файл лога параллельно с прогресс-баром
 */
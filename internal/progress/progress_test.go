@@ -0,0 +1,258 @@
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBar_JSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	bar := New(Options{
+		Total:  3,
+		Format: FormatJSON,
+		Writer: &buf,
+	})
+
+	bar.Increment("a.jpg", 10)
+	bar.IncrementSkipped("b.jpg", 20)
+	bar.IncrementFailed("c.jpg", 30)
+	bar.Finish()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("получено %d строк, want 4:\n%s", len(lines), buf.String())
+	}
+
+	want := []Update{
+		{Event: "processed", Processed: 1, Total: 3, CurrentFile: "a.jpg"},
+		{Event: "skipped", Processed: 1, Skipped: 1, Total: 3, CurrentFile: "b.jpg"},
+		{Event: "failed", Processed: 1, Skipped: 1, Failed: 1, Total: 3, CurrentFile: "c.jpg"},
+		{Event: "finish", Processed: 1, Skipped: 1, Failed: 1, Total: 3, CurrentFile: "c.jpg"},
+	}
+
+	for i, line := range lines {
+		var got Update
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("строка %d не является JSON: %v (%q)", i, err, line)
+		}
+		got.ElapsedSec = 0
+		if got != want[i] {
+			t.Errorf("строка %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestBar_JSONFormatConcurrent(t *testing.T) {
+	const workers = 8
+	var buf bytes.Buffer
+	bar := New(Options{Total: workers, Format: FormatJSON, Writer: &buf})
+
+	// Каждое событие содержит файл своего воркера, а не последнего взятого в обработку
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bar.Increment(fmt.Sprintf("%d.jpg", i), 10)
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var u Update
+		if err := json.Unmarshal([]byte(line), &u); err != nil {
+			t.Fatal(err)
+		}
+		if seen[u.CurrentFile] {
+			t.Errorf("файл %q в нескольких событиях", u.CurrentFile)
+		}
+		seen[u.CurrentFile] = true
+	}
+	for i := 0; i < workers; i++ {
+		if name := fmt.Sprintf("%d.jpg", i); !seen[name] {
+			t.Errorf("нет события для %s", name)
+		}
+	}
+}
+
+func TestBar_JSONFormatDisabled(t *testing.T) {
+	var buf bytes.Buffer
+	bar := New(Options{
+		Total:    1,
+		Format:   FormatJSON,
+		Writer:   &buf,
+		Disabled: true,
+	})
+
+	bar.Increment("", 10)
+	bar.Finish()
+
+	if buf.Len() != 0 {
+		t.Errorf("отключённый прогресс не должен ничего выводить, получено: %q", buf.String())
+	}
+}
+
+func TestBar_ByBytes(t *testing.T) {
+	var buf bytes.Buffer
+	sizes := []int64{100, 5 << 20, 2048}
+	var total int64
+	for _, size := range sizes {
+		total += size
+	}
+
+	bar := New(Options{
+		Total:      int64(len(sizes)),
+		TotalBytes: total,
+		ByBytes:    true,
+		Writer:     &buf,
+	})
+
+	var done int64
+	for i, size := range sizes {
+		if i == 1 {
+			bar.IncrementSkipped("", size)
+		} else {
+			bar.Increment("", size)
+		}
+		done += size
+		if got := int64(bar.bar.State().CurrentNum); got != done {
+			t.Errorf("после файла %d бар = %d, want %d байт", i, got, done)
+		}
+	}
+	if got := bar.bar.GetMax64(); got != total {
+		t.Errorf("максимум бара = %d, want %d", got, total)
+	}
+	bar.Finish()
+
+	if !strings.Contains(buf.String(), "/s") || strings.Contains(buf.String(), "файл/s") {
+		t.Errorf("ожидалась скорость в байтах, вывод: %q", buf.String())
+	}
+}
+
+func TestBar_ByBytesJSON(t *testing.T) {
+	var buf bytes.Buffer
+	bar := New(Options{Total: 2, TotalBytes: 300, ByBytes: true, Format: FormatJSON, Writer: &buf})
+	bar.Increment("", 100)
+	bar.IncrementFailed("", 200)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var last Update
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil {
+		t.Fatal(err)
+	}
+	if last.DoneBytes != 300 || last.TotalBytes != 300 || last.Processed != 1 || last.Failed != 1 {
+		t.Errorf("последнее обновление = %+v", last)
+	}
+}
+
+func TestBar_Phase(t *testing.T) {
+	var buf bytes.Buffer
+	bar := New(Options{Total: 2, Format: FormatJSON, Writer: &buf})
+	phase := bar.StartPhase("hash", "хэширование", 2)
+	phase.Increment()
+	phase.IncrementFailed()
+	bar.Increment("", 10)
+
+	var got []Update
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var u Update
+		if err := json.Unmarshal([]byte(line), &u); err != nil {
+			t.Fatal(err)
+		}
+		u.ElapsedSec = 0
+		got = append(got, u)
+	}
+	want := []Update{
+		{Event: "phase", Total: 2, Phase: "hash", PhaseDone: 1, PhaseTotal: 2},
+		{Event: "phase", Total: 2, Phase: "hash", PhaseDone: 1, PhaseFailed: 1, PhaseTotal: 2},
+		{Event: "processed", Processed: 1, Total: 2, Phase: "hash", PhaseDone: 1, PhaseFailed: 1, PhaseTotal: 2},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("получено %d обновлений, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("обновление %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestBar_ETASmoothing(t *testing.T) {
+	const total = 200
+	var buf bytes.Buffer
+	bar := New(Options{Total: total, Writer: &buf})
+
+	// Поддельные часы: девять быстрых файлов по 100 мс и один медленный на 5 с
+	clock := bar.startTime
+	bar.now = func() time.Time { return clock }
+	durationOf := func(i int) time.Duration {
+		if i%10 == 9 {
+			return 5 * time.Second
+		}
+		return 100 * time.Millisecond
+	}
+	mean := (9*100*time.Millisecond + 5*time.Second) / 10
+
+	if _, ok := bar.ETA(); ok {
+		t.Error("ETA до первого файла не должен быть известен")
+	}
+
+	var prev, prevPeriod time.Duration
+	for i := 0; i < total; i++ {
+		clock = clock.Add(durationOf(i))
+		bar.Increment("", 1)
+		eta, ok := bar.ETA()
+		if !ok {
+			t.Fatalf("файл %d: ETA неизвестен", i)
+		}
+		remaining := time.Duration(total - i - 1)
+		if i >= 10 {
+			// Ограничен: в пределах двух раз от ETA по средней скорости
+			if eta < mean*remaining/2 || eta > mean*remaining*2 {
+				t.Errorf("файл %d: ETA %s вне [%s, %s]", i, eta, mean*remaining/2, mean*remaining*2)
+			}
+		}
+		if i >= 30 {
+			// Почти монотонен: после разгона медленный файл не увеличивает ETA
+			// больше чем в полтора раза
+			if eta > prev*3/2 {
+				t.Errorf("файл %d: ETA вырос с %s до %s", i, prev, eta)
+			}
+		}
+		// В одной и той же фазе нагрузки ETA строго убывает
+		if i%10 == 9 {
+			if prevPeriod > 0 && eta >= prevPeriod {
+				t.Errorf("файл %d: ETA %s не меньше ETA прошлого периода %s", i, eta, prevPeriod)
+			}
+			prevPeriod = eta
+		}
+		prev = eta
+	}
+	if eta, ok := bar.ETA(); !ok || eta != 0 {
+		t.Errorf("ETA после всех файлов = %s, %v, want 0", eta, ok)
+	}
+}
+
+func TestBar_ETASmoothingFactor(t *testing.T) {
+	// Коэффициент 1 - ETA по последнему интервалу
+	bar := New(Options{Total: 10, Disabled: true, ETASmoothing: 1})
+	clock := bar.startTime
+	bar.now = func() time.Time { return clock }
+	for _, d := range []time.Duration{time.Second, 3 * time.Second} {
+		clock = clock.Add(d)
+		bar.Increment("", 1)
+	}
+	if eta, _ := bar.ETA(); eta != 8*3*time.Second {
+		t.Errorf("ETA = %s, want %s", eta, 8*3*time.Second)
+	}
+
+	if bar := New(Options{Total: 10, Disabled: true, ETASmoothing: 2}); bar.smoothing != DefaultETASmoothing {
+		t.Errorf("коэффициент вне (0, 1] = %g, want %g", bar.smoothing, DefaultETASmoothing)
+	}
+}
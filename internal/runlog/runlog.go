@@ -0,0 +1,102 @@
+// Package runlog ведёт журнал обработки файлов для аудита.
+package runlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// StatusOK - файл успешно сконвертирован.
+	StatusOK = "ok"
+	// StatusSkipped - файл пропущен.
+	StatusSkipped = "skipped"
+	// StatusFailed - ошибка обработки файла.
+	StatusFailed = "failed"
+	// StatusDryRun - файл был бы сконвертирован (dry-run).
+	StatusDryRun = "dry-run"
+)
+
+// Entry - одна запись журнала (одна строка JSON).
+type Entry struct {
+	// Time - время записи.
+	Time time.Time `json:"time"`
+
+	// Status - результат обработки (ok, skipped, failed, dry-run).
+	Status string `json:"status"`
+
+	// Src - путь к исходному файлу.
+	Src string `json:"src"`
+
+	// Dst - путь к выходному файлу (если известен).
+	Dst string `json:"dst,omitempty"`
+
+	// DurationSec - время конвертации в секундах.
+	DurationSec float64 `json:"duration_sec,omitempty"`
+
+	// Reason - причина пропуска.
+	Reason string `json:"reason,omitempty"`
+
+	// Error - текст ошибки.
+	Error string `json:"error,omitempty"`
+
+	// Downscaled - файл сконвертирован с уменьшением после нехватки памяти.
+	Downscaled bool `json:"downscaled,omitempty"`
+}
+
+// Logger дописывает записи в файл журнала в формате JSON Lines.
+// Безопасен для использования из нескольких горутин.
+type Logger struct {
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+// Open открывает файл журнала на дозапись, создавая его при необходимости.
+func Open(path string) (*Logger, error) {
+	if dir := filepath.Dir(path); dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("не удалось создать директорию журнала %s: %w", dir, err)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть журнал %s: %w", path, err)
+	}
+
+	return &Logger{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Write дописывает запись в журнал.
+// Если время записи не указано, подставляется текущее.
+func (l *Logger) Write(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.enc.Encode(e); err != nil {
+		return fmt.Errorf("не удалось записать журнал: %w", err)
+	}
+	return nil
+}
+
+// Close закрывает файл журнала.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
+
+/*
+Возможные расширения:
+- Добавить ротацию журнала по размеру
+- Добавить формат TSV
+*/
@@ -0,0 +1,332 @@
+package scanner
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/storage"
+)
+
+// Archive - источник файлов из zip/tar архива.
+// Подходящие файлы по одному извлекаются во временную директорию
+// (File.Temp): пул удаляет извлечённый файл после обработки, остатки
+// удаляются вместе с директорией в Close.
+type Archive struct {
+	cfg    *config.Config
+	filter *Filter
+
+	// path - абсолютный путь к архиву.
+	path string
+
+	// tempDir - директория для извлечённых файлов.
+	tempDir string
+}
+
+// archiveEntry - файл внутри архива.
+type archiveEntry struct {
+	// name - путь внутри архива (со слешами).
+	name  string
+	size  int64
+	mtime time.Time
+	open  func() (io.Reader, error)
+}
+
+// IsArchive проверяет, является ли путь поддерживаемым архивом (.zip, .tar, .tar.gz, .tgz).
+func IsArchive(p string) bool {
+	lower := strings.ToLower(p)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenArchive подготавливает архив к чтению и создаёт временную директорию.
+func OpenArchive(cfg *config.Config, archivePath string) (*Archive, error) {
+	absPath, err := filepath.Abs(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить абсолютный путь: %w", err)
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть архив: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s - директория, а не архив", archivePath)
+	}
+
+	tempDir, err := os.MkdirTemp("", "photoconverter-archive-*")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать временную директорию: %w", err)
+	}
+
+	return &Archive{
+		cfg:     cfg,
+		filter:  NewConfigFilter(cfg),
+		path:    absPath,
+		tempDir: tempDir,
+	}, nil
+}
+
+// Close удаляет временную директорию со всеми извлечёнными файлами.
+func (a *Archive) Close() error {
+	return os.RemoveAll(a.tempDir)
+}
+
+// Scan извлекает подходящие файлы архива во временную директорию и отправляет их в канал.
+// File.Path указывает на извлечённую копию, RelPath - путь внутри архива (для --keep-tree),
+// а Info.Path - "<архив>!/<путь внутри архива>", чтобы повторный запуск распознал
+// уже обработанные файлы. Канал закрывается после чтения всего архива.
+func (a *Archive) Scan(ctx context.Context) (<-chan File, <-chan error) {
+	files := make(chan File, 100)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(files)
+		defer close(errs)
+
+		err := a.walk(func(e archiveEntry) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			file, err := a.extract(e)
+			if err != nil {
+				return err
+			}
+
+			select {
+			case files <- file:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			return nil
+		})
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	return files, errs
+}
+
+// CountFiles возвращает количество подходящих файлов в архиве (для progress bar).
+func (a *Archive) CountFiles() (int64, error) {
+	count, _, err := a.Count()
+	return count, err
+}
+
+// Count возвращает количество подходящих файлов в архиве и их суммарный
+// распакованный размер.
+func (a *Archive) Count() (count, size int64, err error) {
+	err = a.walk(func(e archiveEntry) error {
+		count++
+		size += e.size
+		return nil
+	})
+	return count, size, err
+}
+
+// errWindowFull прекращает обход архива после --limit файлов.
+var errWindowFull = errors.New("лимит файлов исчерпан")
+
+// walk вызывает fn для каждого подходящего файла архива в окне --skip/--limit.
+func (a *Archive) walk(fn func(archiveEntry) error) error {
+	window := NewWindow(a.cfg.Skip, a.cfg.Limit)
+	inWindow := func(e archiveEntry) error {
+		if window.Full() {
+			return errWindowFull
+		}
+		if !window.Next() {
+			return nil
+		}
+		return fn(e)
+	}
+
+	var err error
+	lower := strings.ToLower(a.path)
+	if strings.HasSuffix(lower, ".zip") {
+		err = a.walkZip(inWindow)
+	} else {
+		err = a.walkTar(inWindow, strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz"))
+	}
+	if errors.Is(err, errWindowFull) {
+		return nil
+	}
+	return err
+}
+
+// walkZip перебирает файлы zip архива.
+func (a *Archive) walkZip(fn func(archiveEntry) error) error {
+	zr, err := zip.OpenReader(a.path)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть zip: %w", err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	for _, zf := range zr.File {
+		if !zf.Mode().IsRegular() || !a.accept(zf.Name) {
+			continue
+		}
+
+		var rc io.ReadCloser
+		entry := archiveEntry{
+			name:  zf.Name,
+			size:  int64(zf.UncompressedSize64),
+			mtime: zf.Modified,
+			open: func() (io.Reader, error) {
+				var err error
+				rc, err = zf.Open()
+				return rc, err
+			},
+		}
+		err := fn(entry)
+		if rc != nil {
+			_ = rc.Close()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkTar перебирает файлы tar архива (опционально сжатого gzip).
+func (a *Archive) walkTar(fn func(archiveEntry) error, gzipped bool) error {
+	f, err := os.Open(a.path)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть tar: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var r io.Reader = f
+	if gzipped {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("не удалось распаковать gzip: %w", err)
+		}
+		defer func() { _ = gz.Close() }()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("не удалось прочитать tar: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || !a.accept(hdr.Name) {
+			continue
+		}
+
+		entry := archiveEntry{
+			name:  hdr.Name,
+			size:  hdr.Size,
+			mtime: hdr.ModTime,
+			open:  func() (io.Reader, error) { return tr, nil },
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// accept проверяет путь внутри архива: безопасность, служебные файлы,
+// расширение и include/exclude шаблоны.
+func (a *Archive) accept(name string) bool {
+	clean, ok := cleanArchivePath(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Предупреждение: пропущен небезопасный путь в архиве: %s\n", name)
+		return false
+	}
+
+	// Пропускаем скрытые и служебные директории/файлы (.git, __MACOSX, ._*)
+	for _, part := range strings.Split(clean, "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return false
+		}
+	}
+
+	if !a.cfg.HasInputExtension(path.Ext(clean)) {
+		return false
+	}
+	return a.filter.Match(filepath.FromSlash(clean))
+}
+
+// extract копирует файл архива во временную директорию.
+func (a *Archive) extract(e archiveEntry) (File, error) {
+	clean, _ := cleanArchivePath(e.name)
+	relPath := filepath.FromSlash(clean)
+	dst := filepath.Join(a.tempDir, relPath)
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return File{}, fmt.Errorf("не удалось создать директорию для %s: %w", clean, err)
+	}
+
+	src, err := e.open()
+	if err != nil {
+		return File{}, fmt.Errorf("не удалось прочитать %s из архива: %w", clean, err)
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return File{}, fmt.Errorf("не удалось создать %s: %w", dst, err)
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		_ = out.Close()
+		_ = os.Remove(dst)
+		return File{}, fmt.Errorf("не удалось извлечь %s: %w", clean, err)
+	}
+	if err := out.Close(); err != nil {
+		_ = os.Remove(dst)
+		return File{}, fmt.Errorf("не удалось извлечь %s: %w", clean, err)
+	}
+	// Извлечённый файл получает время записи архива (для --preserve-mtime)
+	_ = os.Chtimes(dst, e.mtime, e.mtime)
+
+	return File{
+		Path:    dst,
+		RelPath: relPath,
+		Info: storage.FileInfo{
+			Path:  a.path + "!/" + clean,
+			Size:  e.size,
+			Mtime: e.mtime.Unix(),
+		},
+		Temp: true,
+	}, nil
+}
+
+// cleanArchivePath нормализует путь внутри архива.
+// Возвращает false для абсолютных путей и путей, выходящих за пределы архива.
+func cleanArchivePath(name string) (string, bool) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") {
+		return "", false
+	}
+	clean := path.Clean(name)
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", false
+	}
+	return clean, true
+}
+
+/*
+Возможные расширения:
+- Поддержка 7z и rar через внешние утилиты
+- Чтение вложенных архивов
+*/
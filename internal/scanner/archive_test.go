@@ -0,0 +1,174 @@
+package scanner
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// writeTestZip создаёт zip архив с указанными файлами (путь -> содержимое).
+func writeTestZip(t *testing.T, path string, entries map[string]string) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsArchive(t *testing.T) {
+	tests := map[string]bool{
+		"photos.zip":    true,
+		"photos.ZIP":    true,
+		"photos.tar":    true,
+		"photos.tar.gz": true,
+		"photos.tgz":    true,
+		"photos":        false,
+		"photo.jpg":     false,
+	}
+	for path, want := range tests {
+		if got := IsArchive(path); got != want {
+			t.Errorf("IsArchive(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestArchive_ZipNested(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "batch.zip")
+	writeTestZip(t, zipPath, map[string]string{
+		"top.jpg":                "top",
+		"2024/june/beach.jpg":    "beach",
+		"2024/june/notes.txt":    "skip",
+		"__MACOSX/2024/._a.jpg":  "skip",
+		"../escape.jpg":          "skip",
+		"2024/june/sunset.png":   "sunset",
+		".hidden/secret.jpg":     "skip",
+		"2024/june/empty-dir/":   "",
+		"2024/june/raw/raw1.jpg": "raw",
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.InputDir = zipPath
+	cfg.InputExtensions = []string{"jpg", "png"}
+	cfg.Exclude = []string{"**/raw/**"}
+
+	archive, err := OpenArchive(cfg, zipPath)
+	if err != nil {
+		t.Fatalf("OpenArchive: %v", err)
+	}
+
+	count, err := archive.CountFiles()
+	if err != nil {
+		t.Fatalf("CountFiles: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("CountFiles = %d, want 3", count)
+	}
+
+	files, errs := archive.Scan(context.Background())
+	got := make(map[string]File)
+	for f := range files {
+		got[filepath.ToSlash(f.RelPath)] = f
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+
+	var rels []string
+	for rel := range got {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	want := []string{"2024/june/beach.jpg", "2024/june/sunset.png", "top.jpg"}
+	if len(rels) != len(want) {
+		t.Fatalf("got files %v, want %v", rels, want)
+	}
+	for i := range want {
+		if rels[i] != want[i] {
+			t.Fatalf("got files %v, want %v", rels, want)
+		}
+	}
+
+	beach := got["2024/june/beach.jpg"]
+	data, err := os.ReadFile(beach.Path)
+	if err != nil {
+		t.Fatalf("extracted file not readable: %v", err)
+	}
+	if string(data) != "beach" {
+		t.Errorf("extracted content = %q, want %q", data, "beach")
+	}
+	if wantInfo := zipPath + "!/2024/june/beach.jpg"; beach.Info.Path != wantInfo {
+		t.Errorf("Info.Path = %q, want %q", beach.Info.Path, wantInfo)
+	}
+	if beach.Info.Size != int64(len("beach")) {
+		t.Errorf("Info.Size = %d, want %d", beach.Info.Size, len("beach"))
+	}
+	if !beach.Temp {
+		t.Error("извлечённый файл должен быть помечен Temp")
+	}
+
+	// Close удаляет все извлечённые файлы
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := os.Stat(beach.Path); !os.IsNotExist(err) {
+		t.Errorf("extracted file still exists after Close: %v", err)
+	}
+}
+
+func TestArchive_CleanupOnCancel(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "batch.zip")
+	writeTestZip(t, zipPath, map[string]string{
+		"a/1.jpg": "1",
+		"a/2.jpg": "2",
+		"b/3.jpg": "3",
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.InputExtensions = []string{"jpg"}
+
+	archive, err := OpenArchive(cfg, zipPath)
+	if err != nil {
+		t.Fatalf("OpenArchive: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	files, errs := archive.Scan(ctx)
+	for range files {
+	}
+	if err := <-errs; err == nil {
+		t.Error("expected context error after cancel")
+	}
+
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := os.Stat(archive.tempDir); !os.IsNotExist(err) {
+		t.Errorf("temp dir still exists after Close: %v", err)
+	}
+}
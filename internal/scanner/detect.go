@@ -0,0 +1,85 @@
+package scanner
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// sniffLen - сколько байт начала файла читается для определения формата.
+const sniffLen = 32
+
+// formatExtensions - расширения, под которыми формат может быть указан в --in-ext.
+var formatExtensions = map[string][]string{
+	"jpeg": {"jpg", "jpeg"},
+	"png":  {"png"},
+	"gif":  {"gif"},
+	"webp": {"webp"},
+	"tiff": {"tiff", "tif"},
+	"heif": {"heic", "heif"},
+	"avif": {"avif"},
+	"jxl":  {"jxl"},
+}
+
+// DetectFormat определяет формат изображения по сигнатуре в начале файла:
+// jpeg, png, gif, webp, tiff, heif, avif или jxl. Пустая строка - формат
+// не распознан или файл не читается.
+func DetectFormat(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffLen)
+	n, _ := io.ReadFull(f, head)
+	return detectFormat(head[:n])
+}
+
+// detectFormat определяет формат по первым байтам файла.
+func detectFormat(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8, 0xFF}):
+		return "jpeg"
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return "png"
+	case bytes.HasPrefix(head, []byte("GIF87a")), bytes.HasPrefix(head, []byte("GIF89a")):
+		return "gif"
+	case len(head) >= 12 && bytes.Equal(head[:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WEBP")):
+		return "webp"
+	case bytes.HasPrefix(head, []byte("II*\x00")), bytes.HasPrefix(head, []byte("MM\x00*")):
+		return "tiff"
+	case bytes.HasPrefix(head, []byte{0xFF, 0x0A}), bytes.HasPrefix(head, []byte("\x00\x00\x00\x0cJXL \r\n\x87\n")):
+		return "jxl"
+	case len(head) >= 12 && bytes.Equal(head[4:8], []byte("ftyp")):
+		// ISO BMFF: основной бренд определяет HEIF или AVIF
+		switch string(head[8:12]) {
+		case "avif", "avis":
+			return "avif"
+		case "heic", "heix", "heim", "heis", "hevc", "hevx", "mif1", "msf1":
+			return "heif"
+		}
+	}
+	return ""
+}
+
+// Accepts проверяет, что файл нужно обрабатывать: расширение входит
+// в --in-ext, а с --detect-by-content - или формат по содержимому входит
+// в --in-ext (JPEG с расширением .dat, файлы камер без расширения).
+func Accepts(cfg *config.Config, path string) bool {
+	if cfg.HasInputExtension(filepath.Ext(path)) {
+		return true
+	}
+	if !cfg.DetectByContent {
+		return false
+	}
+	for _, ext := range formatExtensions[DetectFormat(path)] {
+		if cfg.HasInputExtension("." + ext) {
+			return true
+		}
+	}
+	return false
+}
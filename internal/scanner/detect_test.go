@@ -0,0 +1,30 @@
+package scanner
+
+import "testing"
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		head string
+		want string
+	}{
+		{"\xFF\xD8\xFF\xE1\x00\x10Exif", "jpeg"},
+		{"\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "png"},
+		{"GIF89a\x01\x00", "gif"},
+		{"RIFF\x24\x00\x00\x00WEBPVP8 ", "webp"},
+		{"II*\x00\x08\x00\x00\x00", "tiff"},
+		{"MM\x00*\x00\x00\x00\x08", "tiff"},
+		{"\x00\x00\x00\x18ftypheic\x00\x00\x00\x00", "heif"},
+		{"\x00\x00\x00\x1cftypmif1\x00\x00\x00\x00", "heif"},
+		{"\x00\x00\x00\x1cftypavif\x00\x00\x00\x00", "avif"},
+		{"\xFF\x0A\xFA\x7F", "jxl"},
+		{"\x00\x00\x00\x0cJXL \r\n\x87\n", "jxl"},
+		{"\x00\x00\x00\x18ftypisom\x00\x00\x00\x00", ""},
+		{"plain text", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := detectFormat([]byte(tt.head)); got != tt.want {
+			t.Errorf("detectFormat(%q) = %q, want %q", tt.head, got, tt.want)
+		}
+	}
+}
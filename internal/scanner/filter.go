@@ -0,0 +1,159 @@
+// Package scanner содержит фильтрацию файлов по glob-шаблонам.
+package scanner
+
+import (
+	"hash/fnv"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// Filter отбирает файлы по include/exclude glob-шаблонам.
+// Используется и сканером, и watcher, чтобы режимы вели себя одинаково.
+//
+// Шаблоны применяются к пути относительно входной директории (разделитель "/"):
+//   - "*" - любые символы внутри одного сегмента пути;
+//   - "?" - один символ внутри сегмента;
+//   - "**" - любое количество сегментов (например "**/raw/**");
+//   - шаблон без "/" сравнивается с именем файла на любой глубине ("*.jpg").
+//
+// С разбиением на части (--shard) файл проходит фильтр, только если
+// относится к своей части (см. InShard).
+type Filter struct {
+	include []globPattern
+	exclude []globPattern
+
+	shardIndex int
+	shardTotal int
+}
+
+// globPattern - скомпилированный glob-шаблон.
+type globPattern struct {
+	re *regexp.Regexp
+
+	// baseOnly - шаблон без "/", сравнивается с именем файла.
+	baseOnly bool
+}
+
+// NewFilter создаёт фильтр из include и exclude шаблонов.
+// Пустой список include означает "все файлы".
+func NewFilter(include, exclude []string) *Filter {
+	return &Filter{
+		include: compileGlobs(include),
+		exclude: compileGlobs(exclude),
+	}
+}
+
+// NewConfigFilter создаёт фильтр по шаблонам и части (--shard) из конфигурации.
+func NewConfigFilter(cfg *config.Config) *Filter {
+	f := NewFilter(cfg.Include, cfg.Exclude)
+	f.shardIndex, f.shardTotal = cfg.ShardIndex, cfg.ShardTotal
+	return f
+}
+
+// Match проверяет, проходит ли файл фильтр.
+// relPath - путь относительно входной директории.
+func (f *Filter) Match(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+
+	if !InShard(relPath, f.shardIndex, f.shardTotal) {
+		return false
+	}
+
+	for _, g := range f.exclude {
+		if g.match(relPath) {
+			return false
+		}
+	}
+
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, g := range f.include {
+		if g.match(relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEmpty возвращает true, если фильтр не содержит шаблонов и разбиения на части.
+func (f *Filter) IsEmpty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0 && f.shardTotal <= 1
+}
+
+// InShard сообщает, относится ли файл к части index из total (--shard).
+// Часть определяется хэшем FNV-1a относительного пути (разделитель "/"),
+// поэтому разбиение не зависит от порядка обхода и не меняется для
+// существующих файлов при добавлении новых. total <= 1 - все файлы.
+func InShard(relPath string, index, total int) bool {
+	if total <= 1 {
+		return true
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(filepath.ToSlash(relPath)))
+	return h.Sum64()%uint64(total) == uint64(index)
+}
+
+// match сравнивает путь с шаблоном.
+func (g globPattern) match(relPath string) bool {
+	if g.baseOnly {
+		return g.re.MatchString(relPath[strings.LastIndex(relPath, "/")+1:])
+	}
+	return g.re.MatchString(relPath)
+}
+
+// compileGlobs компилирует список glob-шаблонов.
+func compileGlobs(patterns []string) []globPattern {
+	var res []globPattern
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		res = append(res, globPattern{
+			re:       regexp.MustCompile(globToRegexp(p)),
+			baseOnly: !strings.Contains(filepath.ToSlash(p), "/"),
+		})
+	}
+	return res
+}
+
+// globToRegexp преобразует glob-шаблон в регулярное выражение.
+// Все символы, кроме "*", "?" и "/", экранируются, поэтому результат
+// всегда является корректным регулярным выражением.
+func globToRegexp(pattern string) string {
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '*' && strings.HasPrefix(pattern[i:], "**/"):
+			// "**/" - ноль или больше директорий
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(pattern[i:], "**"):
+			// "**" в конце или внутри сегмента - любые символы
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	return sb.String()
+}
+
+/*
+Возможные расширения:
+- Добавить классы символов ([abc]) и альтернативы ({jpg,png})
+- Добавить отсечение исключённых директорий при обходе
+*/
@@ -0,0 +1,138 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+func TestFilter_Match(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		path    string
+		want    bool
+	}{
+		{name: "empty filter", path: "a/b.jpg", want: true},
+		{name: "exclude double star dir", exclude: []string{"**/raw/**"}, path: "2024/raw/a.jpg", want: false},
+		{name: "exclude double star top level", exclude: []string{"**/raw/**"}, path: "raw/a.jpg", want: false},
+		{name: "exclude double star other dir", exclude: []string{"**/raw/**"}, path: "2024/rawfiles/a.jpg", want: true},
+		{name: "include basename pattern", include: []string{"*.heic"}, path: "a/b/c.heic", want: true},
+		{name: "include basename pattern miss", include: []string{"*.heic"}, path: "a/b/c.jpg", want: false},
+		{name: "include dir pattern", include: []string{"2024/*"}, path: "2024/a.jpg", want: true},
+		{name: "single star does not cross dirs", include: []string{"2024/*"}, path: "2024/06/a.jpg", want: false},
+		{name: "double star crosses dirs", include: []string{"2024/**"}, path: "2024/06/a.jpg", want: true},
+		{name: "question mark", include: []string{"img_?.jpg"}, path: "img_1.jpg", want: true},
+		{name: "exclude wins over include", include: []string{"**"}, exclude: []string{"*.png"}, path: "x/a.png", want: false},
+		{name: "regexp chars escaped", include: []string{"a+b (1).jpg"}, path: "a+b (1).jpg", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFilter(tt.include, tt.exclude)
+			if got := f.Match(tt.path); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanner_Exclude(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.jpg", "raw/b.jpg", "2024/raw/c.jpg", "2024/d.jpg"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.InputDir = dir
+	cfg.Exclude = []string{"**/raw/**"}
+	s := New(cfg)
+
+	files, errs := s.Scan(context.Background())
+	var (
+		got       []string
+		scanBytes int64
+	)
+	for f := range files {
+		got = append(got, filepath.ToSlash(f.RelPath))
+		scanBytes += f.Info.Size
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+
+	want := []string{"2024/d.jpg", "a.jpg"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Scan() = %v, want %v", got, want)
+	}
+
+	count, err := s.CountFiles()
+	if err != nil || count != 2 {
+		t.Errorf("CountFiles() = %d, %v, want 2", count, err)
+	}
+	if _, size, err := s.Count(); err != nil || size != scanBytes {
+		t.Errorf("Count() size = %d, %v, want %d", size, err, scanBytes)
+	}
+}
+
+func TestScanner_Shards(t *testing.T) {
+	dir := t.TempDir()
+	var all []string
+	for i := 0; i < 40; i++ {
+		rel := filepath.Join(fmt.Sprintf("d%d", i%3), fmt.Sprintf("img%02d.jpg", i))
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(rel)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, rel), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, rel)
+	}
+	sort.Strings(all)
+
+	const total = 4
+	seen := make(map[string]int)
+	var union []string
+	for index := 0; index < total; index++ {
+		cfg := config.DefaultConfig()
+		cfg.InputDir = dir
+		cfg.InputExtensions = []string{"jpg"}
+		cfg.ShardIndex, cfg.ShardTotal = index, total
+
+		files, errs := New(cfg).Scan(context.Background())
+		n := 0
+		for f := range files {
+			if prev, ok := seen[f.RelPath]; ok {
+				t.Errorf("%s попал в части %d и %d", f.RelPath, prev+1, index+1)
+			}
+			seen[f.RelPath] = index
+			union = append(union, f.RelPath)
+			n++
+		}
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			t.Errorf("часть %d/%d пуста", index+1, total)
+		}
+	}
+
+	sort.Strings(union)
+	if !reflect.DeepEqual(union, all) {
+		t.Errorf("объединение частей: %d файлов, want %d", len(union), len(all))
+	}
+}
@@ -0,0 +1,115 @@
+package scanner
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/artemshloyda/photoconverter/internal/contenthash"
+)
+
+// hashBufferSize - размер буфера чтения при хэшировании.
+// Крупные блоки заметно снижают число системных вызовов на больших файлах.
+const hashBufferSize = 1 << 20
+
+var hashBuffers = sync.Pool{
+	New: func() any {
+		buf := make([]byte, hashBufferSize)
+		return &buf
+	},
+}
+
+// ComputeSHA256 вычисляет sha256 хэш файла.
+func ComputeSHA256(path string) (string, error) {
+	return ComputeHash(path, contenthash.SHA256)
+}
+
+// ComputeHash вычисляет хэш содержимого файла выбранным алгоритмом
+// (sha256, blake3, xxhash). Для sha256 возвращается hex без префикса
+// (совместимо с существующими базами), для остальных - "<алгоритм>:<hex>",
+// чтобы хэши разных алгоритмов в БД никогда не совпадали.
+func ComputeHash(path, algorithm string) (string, error) {
+	h, err := contenthash.New(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("не удалось открыть файл: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	buf := hashBuffers.Get().(*[]byte)
+	defer hashBuffers.Put(buf)
+
+	// Оборачиваем файл, чтобы io.CopyBuffer не ушёл в File.WriteTo с буфером 32 КБ
+	if _, err := io.CopyBuffer(h, struct{ io.Reader }{f}, *buf); err != nil {
+		return "", fmt.Errorf("не удалось прочитать файл: %w", err)
+	}
+
+	return formatHash(algorithm, h.Sum(nil)), nil
+}
+
+// ComputeQuickHash вычисляет быстрый ключ содержимого для --dedup-quick:
+// хэш от размера файла, первых и последних n байт. Файлы не больше 2n байт
+// хэшируются целиком, тогда full = true и ключ совпадает с ComputeHash.
+//
+// Разные файлы с одинаковыми размером, началом и концом получат одинаковый
+// ключ, поэтому совпадение быстрых ключей нужно подтверждать полным хэшем.
+func ComputeQuickHash(path, algorithm string, n int64) (key string, full bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false, fmt.Errorf("не удалось открыть файл: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", false, fmt.Errorf("не удалось получить размер файла: %w", err)
+	}
+	size := info.Size()
+	if size <= 2*n {
+		_ = f.Close()
+		key, err := ComputeHash(path, algorithm)
+		return key, true, err
+	}
+
+	h, err := contenthash.New(algorithm)
+	if err != nil {
+		return "", false, err
+	}
+
+	var sizeBuf [8]byte
+	binary.LittleEndian.PutUint64(sizeBuf[:], uint64(size))
+	_, _ = h.Write(sizeBuf[:])
+
+	buf := hashBuffers.Get().(*[]byte)
+	defer hashBuffers.Put(buf)
+
+	if _, err := io.CopyBuffer(h, io.NewSectionReader(f, 0, n), *buf); err != nil {
+		return "", false, fmt.Errorf("не удалось прочитать файл: %w", err)
+	}
+	if _, err := io.CopyBuffer(h, io.NewSectionReader(f, size-n, n), *buf); err != nil {
+		return "", false, fmt.Errorf("не удалось прочитать файл: %w", err)
+	}
+
+	return formatHash(algorithm, h.Sum(nil)), false, nil
+}
+
+// formatHash кодирует хэш в hex; для алгоритмов, кроме sha256, добавляет префикс.
+func formatHash(algorithm string, sum []byte) string {
+	hexSum := hex.EncodeToString(sum)
+	if algorithm == "" || algorithm == contenthash.SHA256 {
+		return hexSum
+	}
+	return algorithm + ":" + hexSum
+}
+
+/*
+Возможные расширения:
+- Выборка нескольких блоков из середины файла для быстрого ключа
+*/
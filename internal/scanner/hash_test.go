@@ -0,0 +1,112 @@
+package scanner
+
+import (
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/contenthash"
+)
+
+func TestComputeHash_Prefixes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.jpg")
+	if err := os.WriteFile(path, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		algorithm string
+		want      string
+	}{
+		{contenthash.SHA256, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{contenthash.BLAKE3, "blake3:6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85"},
+		{contenthash.XXHash, "xxhash:44bc2cf5ad770999"},
+	}
+	for _, tt := range tests {
+		got, err := ComputeHash(path, tt.algorithm)
+		if err != nil {
+			t.Fatalf("ComputeHash(%s): %v", tt.algorithm, err)
+		}
+		if got != tt.want {
+			t.Errorf("ComputeHash(%s) = %s, want %s", tt.algorithm, got, tt.want)
+		}
+	}
+
+	if _, err := ComputeHash(path, "md5"); err == nil || !strings.Contains(err.Error(), "md5") {
+		t.Errorf("ComputeHash(md5) error = %v", err)
+	}
+}
+
+// BenchmarkComputeHash сравнивает алгоритмы на большом файле:
+//
+//	go test -bench ComputeHash ./internal/scanner
+func BenchmarkComputeHash(b *testing.B) {
+	const size = 64 << 20
+
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		b.Fatal(err)
+	}
+	path := filepath.Join(b.TempDir(), "large.bin")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, algorithm := range contenthash.Algorithms() {
+		b.Run(algorithm, func(b *testing.B) {
+			b.SetBytes(size)
+			for i := 0; i < b.N; i++ {
+				if _, err := ComputeHash(path, algorithm); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestComputeQuickHash(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	a := write("a.jpg", "HEAD-middle-one-TAIL")
+	b := write("b.jpg", "HEAD-middle-two-TAIL")
+	c := write("c.jpg", "HEAD-middle-one-TAIL!") // другой размер
+
+	keyA, fullA, err := ComputeQuickHash(a, contenthash.XXHash, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyB, _, _ := ComputeQuickHash(b, contenthash.XXHash, 4)
+	keyC, _, _ := ComputeQuickHash(c, contenthash.XXHash, 4)
+
+	if fullA {
+		t.Error("файл больше 2n байт не должен хэшироваться целиком")
+	}
+	if keyA != keyB {
+		t.Errorf("одинаковые размер, начало и конец: ключи %s и %s должны совпасть", keyA, keyB)
+	}
+	if keyA == keyC {
+		t.Error("файлы разного размера получили одинаковый быстрый ключ")
+	}
+	if !strings.HasPrefix(keyA, "xxhash:") {
+		t.Errorf("ключ %s без префикса алгоритма", keyA)
+	}
+
+	// Маленький файл хэшируется целиком
+	key, full, err := ComputeQuickHash(a, contenthash.SHA256, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := ComputeHash(a, contenthash.SHA256)
+	if !full || key != want {
+		t.Errorf("ComputeQuickHash(n=10) = %s, full=%v; want %s, full=true", key, full, want)
+	}
+}
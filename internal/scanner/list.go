@@ -0,0 +1,132 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/storage"
+)
+
+// FromList читает список путей (по одному на строку) и отправляет файлы в канал
+// без обхода директории. Используется для --from-file (например, вывод find).
+//
+// Относительные пути разрешаются относительно текущей директории.
+// RelPath вычисляется относительно cfg.InputDir, если он задан и содержит файл,
+// иначе используется имя файла. Пустые строки пропускаются; несуществующие файлы
+// и файлы с неподходящим расширением пропускаются с предупреждением в stderr.
+// Канал закрывается после чтения всего списка.
+func FromList(ctx context.Context, cfg *config.Config, r io.Reader) (<-chan File, <-chan error) {
+	files := make(chan File, 100)
+	errs := make(chan error, 1)
+
+	filter := NewConfigFilter(cfg)
+	window := NewWindow(cfg.Skip, cfg.Limit)
+
+	baseDir := ""
+	if cfg.InputDir != "" {
+		if abs, err := filepath.Abs(cfg.InputDir); err == nil {
+			baseDir = abs
+		}
+	}
+
+	go func() {
+		defer close(files)
+		defer close(errs)
+
+		lines := bufio.NewScanner(r)
+		lines.Buffer(make([]byte, 64*1024), 1024*1024)
+
+		for lines.Scan() {
+			if ctx.Err() != nil {
+				errs <- ctx.Err()
+				return
+			}
+
+			line := strings.TrimRight(lines.Text(), "\r")
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+
+			file, ok := listFile(cfg, filter, baseDir, line)
+			if !ok {
+				continue
+			}
+			if window.Full() {
+				return
+			}
+			if !window.Next() {
+				continue
+			}
+
+			select {
+			case files <- file:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+
+		if err := lines.Err(); err != nil {
+			errs <- fmt.Errorf("не удалось прочитать список файлов: %w", err)
+		}
+	}()
+
+	return files, errs
+}
+
+// listFile проверяет путь из списка и строит File.
+// Возвращает false, если файл нужно пропустить.
+func listFile(cfg *config.Config, filter *Filter, baseDir, path string) (File, bool) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		absPath = path
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Предупреждение: пропущен %s: %v\n", path, err)
+		return File{}, false
+	}
+	if info.IsDir() {
+		fmt.Fprintf(os.Stderr, "Предупреждение: пропущен %s: это директория\n", path)
+		return File{}, false
+	}
+
+	if !Accepts(cfg, absPath) {
+		fmt.Fprintf(os.Stderr, "Предупреждение: пропущен %s: расширение не входит в --in-ext\n", path)
+		return File{}, false
+	}
+
+	relPath := filepath.Base(absPath)
+	if baseDir != "" {
+		if rel, err := filepath.Rel(baseDir, absPath); err == nil && !strings.HasPrefix(rel, "..") {
+			relPath = rel
+		}
+	}
+
+	if !filter.Match(relPath) {
+		return File{}, false
+	}
+
+	return File{
+		Path:    absPath,
+		RelPath: relPath,
+		Info: storage.FileInfo{
+			Path:  absPath,
+			Size:  info.Size(),
+			Mtime: info.ModTime().Unix(),
+		},
+	}, true
+}
+
+/*
+Возможные расширения:
+- Поддержка списков, разделённых нулевым байтом (find -print0)
+- Раскрытие директорий из списка в их содержимое
+*/
@@ -0,0 +1,97 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+func TestFromList(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{
+		filepath.Join(dir, "a.jpg"),
+		filepath.Join(dir, "sub", "b.png"),
+		filepath.Join(dir, "c.jpg"),
+	}
+	for _, p := range paths {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	list := strings.Join([]string{
+		paths[0],
+		"",
+		paths[1],
+		filepath.Join(dir, "missing.jpg"),
+		filepath.Join(dir, "notes.txt"),
+		paths[2],
+	}, "\n") + "\n"
+
+	cfg := config.DefaultConfig()
+	cfg.InputDir = dir
+	cfg.InputExtensions = []string{"jpg", "png"}
+
+	files, errs := FromList(context.Background(), cfg, strings.NewReader(list))
+
+	var got []File
+	for f := range files {
+		got = append(got, f)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("FromList: %v", err)
+	}
+
+	wantRel := []string{"a.jpg", filepath.Join("sub", "b.png"), "c.jpg"}
+	if len(got) != len(wantRel) {
+		t.Fatalf("got %d files, want %d: %+v", len(got), len(wantRel), got)
+	}
+	for i, f := range got {
+		if f.Path != paths[i] {
+			t.Errorf("file %d: Path = %q, want %q", i, f.Path, paths[i])
+		}
+		if f.RelPath != wantRel[i] {
+			t.Errorf("file %d: RelPath = %q, want %q", i, f.RelPath, wantRel[i])
+		}
+		if f.Info.Size != 4 {
+			t.Errorf("file %d: Size = %d, want 4", i, f.Info.Size)
+		}
+	}
+}
+
+func TestFromList_NoBaseUsesBasename(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nested", "photo.jpg")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.InputExtensions = []string{"jpg"}
+
+	files, errs := FromList(context.Background(), cfg, strings.NewReader(path+"\n"))
+
+	var got []File
+	for f := range files {
+		got = append(got, f)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("FromList: %v", err)
+	}
+	if len(got) != 1 || got[0].RelPath != "photo.jpg" {
+		t.Fatalf("got %+v, want single file with RelPath photo.jpg", got)
+	}
+}
@@ -1,12 +1,10 @@
-// Package scanner отвечает за сканирование директорий с изображениями.
+// Package scanner отвечает за сканирование директорий с изображениями
+// (или одного файла, указанного в --in).
 package scanner
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -25,16 +23,24 @@ type File struct {
 
 	// RelPath - относительный путь от входной директории.
 	RelPath string
+
+	// Temp - Path указывает на временную копию (файл, извлечённый из архива),
+	// которая удаляется после обработки.
+	Temp bool
 }
 
 // Scanner сканирует директории с изображениями.
 type Scanner struct {
-	cfg *config.Config
+	cfg    *config.Config
+	filter *Filter
 }
 
 // New создаёт новый Scanner.
 func New(cfg *config.Config) *Scanner {
-	return &Scanner{cfg: cfg}
+	return &Scanner{
+		cfg:    cfg,
+		filter: NewConfigFilter(cfg),
+	}
 }
 
 // Scan запускает сканирование и отправляет найденные файлы в канал.
@@ -47,6 +53,7 @@ func (s *Scanner) Scan(ctx context.Context) (<-chan File, <-chan error) {
 		defer close(files)
 		defer close(errs)
 
+		window := s.window()
 		err := filepath.WalkDir(s.cfg.InputDir, func(path string, d os.DirEntry, err error) error {
 			// Проверяем контекст
 			select {
@@ -77,9 +84,27 @@ func (s *Scanner) Scan(ctx context.Context) (<-chan File, <-chan error) {
 				return nil
 			}
 
-			// Проверяем расширение
-			ext := filepath.Ext(path)
-			if !s.cfg.HasInputExtension(ext) {
+			// Проверяем расширение (или содержимое с --detect-by-content)
+			if !Accepts(s.cfg, path) {
+				if path == s.cfg.InputDir {
+					fmt.Fprintf(os.Stderr, "Предупреждение: %s не подходит под --in-ext, файл пропущен\n", path)
+				}
+				return nil
+			}
+
+			// Относительный путь
+			relPath := s.relPath(path)
+
+			// Проверяем include/exclude шаблоны
+			if !s.filter.Match(relPath) {
+				return nil
+			}
+
+			// --skip/--limit
+			if window.Full() {
+				return filepath.SkipAll
+			}
+			if !window.Next() {
 				return nil
 			}
 
@@ -90,9 +115,6 @@ func (s *Scanner) Scan(ctx context.Context) (<-chan File, <-chan error) {
 				return nil
 			}
 
-			// Относительный путь
-			relPath, _ := filepath.Rel(s.cfg.InputDir, path)
-
 			// Абсолютный путь
 			absPath, err := filepath.Abs(path)
 			if err != nil {
@@ -127,11 +149,32 @@ func (s *Scanner) Scan(ctx context.Context) (<-chan File, <-chan error) {
 	return files, errs
 }
 
+// window создаёт окно --skip/--limit для одного обхода.
+func (s *Scanner) window() *Window {
+	return NewWindow(s.cfg.Skip, s.cfg.Limit)
+}
+
+// relPath возвращает путь файла относительно входной директории.
+// Если в --in указан сам файл, это его имя: результат ляжет прямо в OutputDir.
+func (s *Scanner) relPath(path string) string {
+	relPath, err := filepath.Rel(s.cfg.InputDir, path)
+	if err != nil || relPath == "." {
+		return filepath.Base(path)
+	}
+	return relPath
+}
+
 // CountFiles возвращает количество файлов для обработки (для progress bar).
 func (s *Scanner) CountFiles() (int64, error) {
-	var count int64
+	count, _, err := s.Count()
+	return count, err
+}
 
-	err := filepath.WalkDir(s.cfg.InputDir, func(path string, d os.DirEntry, err error) error {
+// Count возвращает количество файлов для обработки и их суммарный размер
+// (для progress bar по объёму данных).
+func (s *Scanner) Count() (count, size int64, err error) {
+	window := s.window()
+	err = filepath.WalkDir(s.cfg.InputDir, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return nil // Игнорируем ошибки
 		}
@@ -144,31 +187,28 @@ func (s *Scanner) CountFiles() (int64, error) {
 			return nil
 		}
 
-		ext := filepath.Ext(path)
-		if s.cfg.HasInputExtension(ext) {
+		if !Accepts(s.cfg, path) {
+			return nil
+		}
+
+		relPath := s.relPath(path)
+		if s.filter.Match(relPath) {
+			if window.Full() {
+				return filepath.SkipAll
+			}
+			if !window.Next() {
+				return nil
+			}
 			count++
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
 		}
 
 		return nil
 	})
 
-	return count, err
-}
-
-// ComputeSHA256 вычисляет sha256 хэш файла.
-func ComputeSHA256(path string) (string, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return "", fmt.Errorf("не удалось открыть файл: %w", err)
-	}
-	defer func() { _ = f.Close() }()
-
-	h := sha256.New()
-	if _, err := io.Copy(h, f); err != nil {
-		return "", fmt.Errorf("не удалось прочитать файл: %w", err)
-	}
-
-	return hex.EncodeToString(h.Sum(nil)), nil
+	return count, size, err
 }
 
 // SortFiles сортирует файлы по заданному критерию.
@@ -218,8 +258,12 @@ func (s *Scanner) ScanSorted(ctx context.Context) (<-chan File, <-chan error) {
 				return nil
 			}
 
-			ext := filepath.Ext(path)
-			if !s.cfg.HasInputExtension(ext) {
+			if !Accepts(s.cfg, path) {
+				return nil
+			}
+
+			relPath := s.relPath(path)
+			if !s.filter.Match(relPath) {
 				return nil
 			}
 
@@ -227,8 +271,6 @@ func (s *Scanner) ScanSorted(ctx context.Context) (<-chan File, <-chan error) {
 			if err != nil {
 				return nil
 			}
-
-			relPath, _ := filepath.Rel(s.cfg.InputDir, path)
 			absPath, _ := filepath.Abs(path)
 			if absPath == "" {
 				absPath = path
@@ -251,8 +293,9 @@ func (s *Scanner) ScanSorted(ctx context.Context) (<-chan File, <-chan error) {
 			return
 		}
 
-		// Сортируем
+		// Сортируем и применяем --skip/--limit к отсортированному списку
 		SortFiles(allFiles, s.cfg.SortBy, s.cfg.SortDesc)
+		allFiles = s.window().apply(allFiles)
 
 		// Отправляем в канал
 		for _, file := range allFiles {
@@ -269,8 +312,6 @@ func (s *Scanner) ScanSorted(ctx context.Context) (<-chan File, <-chan error) {
 
 /*
 Возможные расширения:
-- Добавить поддержку glob-паттернов для фильтрации
-- Добавить поддержку exclude-паттернов
 - Добавить параллельное сканирование для больших директорий
 - Добавить поддержку symlinks
 */
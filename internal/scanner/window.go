@@ -0,0 +1,46 @@
+package scanner
+
+// Window отбирает диапазон подходящих файлов в порядке обхода (--skip, --limit):
+// первые skip файлов пропускаются, после limit отобранных обход можно прекратить.
+// Порядок обхода детерминирован (имена по алфавиту в каждой директории,
+// порядок строк списка или записей архива), поэтому с одинаковыми --skip и
+// --limit разные запуски и машины получают одни и те же файлы.
+//
+// Window применяется после фильтров по расширению и include/exclude.
+// Не безопасен для конкурентного использования.
+type Window struct {
+	skip  int
+	limit int
+	seen  int
+}
+
+// NewWindow создаёт окно. limit = 0 - без ограничения.
+func NewWindow(skip, limit int) *Window {
+	return &Window{skip: skip, limit: limit}
+}
+
+// Next учитывает очередной подходящий файл и сообщает, попадает ли он в окно.
+func (w *Window) Next() bool {
+	if w.Full() {
+		return false
+	}
+	w.seen++
+	return w.seen > w.skip
+}
+
+// Full сообщает, что лимит исчерпан и дальнейший обход не нужен.
+func (w *Window) Full() bool {
+	return w.limit > 0 && w.seen >= w.skip+w.limit
+}
+
+// apply возвращает часть files, попадающую в окно (для уже собранного списка).
+func (w *Window) apply(files []File) []File {
+	if w.skip >= len(files) {
+		return nil
+	}
+	files = files[w.skip:]
+	if w.limit > 0 && w.limit < len(files) {
+		files = files[:w.limit]
+	}
+	return files
+}
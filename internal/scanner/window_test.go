@@ -0,0 +1,72 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+func TestScan_SkipLimit(t *testing.T) {
+	dir := t.TempDir()
+	// Порядок создания не совпадает с порядком обхода
+	for _, name := range []string{"e.jpg", "b.jpg", "notes.txt", "d.jpg", "a.jpg", "f.jpg", "c.jpg"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.InputDir = dir
+	cfg.InputExtensions = []string{"jpg"}
+	cfg.Skip = 2
+	cfg.Limit = 2
+	s := New(cfg)
+
+	want := []string{"c.jpg", "d.jpg"}
+	for name, scan := range map[string]func(context.Context) (<-chan File, <-chan error){
+		"Scan":       s.Scan,
+		"ScanSorted": s.ScanSorted,
+	} {
+		files, errs := scan(context.Background())
+		var got []string
+		for f := range files {
+			got = append(got, f.RelPath)
+		}
+		if err := <-errs; err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: файлы %v, want %v", name, got, want)
+		}
+	}
+
+	if count, _, err := s.Count(); err != nil || count != 2 {
+		t.Errorf("Count = %d (%v), want 2", count, err)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	tests := []struct {
+		skip, limit int
+		want        []bool
+	}{
+		{skip: 0, limit: 0, want: []bool{true, true, true, true}},
+		{skip: 1, limit: 0, want: []bool{false, true, true, true}},
+		{skip: 1, limit: 2, want: []bool{false, true, true, false}},
+		{skip: 5, limit: 1, want: []bool{false, false, false, false}},
+	}
+	for _, tt := range tests {
+		w := NewWindow(tt.skip, tt.limit)
+		var got []bool
+		for range tt.want {
+			got = append(got, w.Next())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("skip=%d limit=%d: %v, want %v", tt.skip, tt.limit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Package status отдаёт текущий прогресс конвертации в JSON (--status-addr).
+package status
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/worker"
+)
+
+// Source - источник текущей статистики (worker.Pool).
+type Source interface {
+	GetStats() worker.Stats
+}
+
+// Snapshot - состояние запуска на момент запроса.
+type Snapshot struct {
+	// Processed, Skipped, Failed - завершённые задачи.
+	Processed int64 `json:"processed"`
+	Skipped   int64 `json:"skipped"`
+	Failed    int64 `json:"failed"`
+
+	// Done - всего завершено задач.
+	Done int64 `json:"done"`
+
+	// Expected - ожидаемое количество задач (-1 = неизвестно: потоковый и watch режимы).
+	Expected int64 `json:"expected"`
+
+	// InputBytes, OutputBytes - размеры сконвертированных файлов.
+	InputBytes  int64 `json:"input_bytes"`
+	OutputBytes int64 `json:"output_bytes"`
+
+	// ElapsedSec - время с начала запуска.
+	ElapsedSec float64 `json:"elapsed_sec"`
+
+	// RemainingSec - оценка оставшегося времени по средней скорости (нет, если Expected неизвестно).
+	RemainingSec *float64 `json:"remaining_sec,omitempty"`
+}
+
+// Reporter формирует снимки состояния. Счётчики читаются атомарно из Source,
+// без блокировок пула.
+type Reporter struct {
+	src      Source
+	start    time.Time
+	expected atomic.Int64
+}
+
+// New создаёт Reporter для запуска, начатого в start.
+func New(src Source, start time.Time) *Reporter {
+	r := &Reporter{src: src, start: start}
+	r.expected.Store(-1)
+	return r
+}
+
+// SetExpected задаёт ожидаемое количество задач (после подсчёта файлов).
+func (r *Reporter) SetExpected(n int64) {
+	r.expected.Store(n)
+}
+
+// Snapshot возвращает состояние запуска на момент now.
+func (r *Reporter) Snapshot(now time.Time) Snapshot {
+	stats := r.src.GetStats()
+	elapsed := now.Sub(r.start)
+
+	s := Snapshot{
+		Processed:   stats.Processed,
+		Skipped:     stats.Skipped,
+		Failed:      stats.Failed,
+		Done:        stats.Processed + stats.Skipped + stats.Failed,
+		Expected:    r.expected.Load(),
+		InputBytes:  stats.InputBytes,
+		OutputBytes: stats.OutputBytes,
+		ElapsedSec:  elapsed.Seconds(),
+	}
+
+	if s.Expected >= 0 && s.Done > 0 {
+		left := s.Expected - s.Done
+		if left < 0 {
+			left = 0
+		}
+		remaining := elapsed.Seconds() / float64(s.Done) * float64(left)
+		s.RemainingSec = &remaining
+	}
+	return s
+}
+
+// ServeHTTP отдаёт текущий снимок в JSON.
+func (r *Reporter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(r.Snapshot(time.Now()))
+}
+
+/*
+Возможные расширения:
+- Текущие обрабатываемые файлы по воркерам
+- Server-Sent Events вместо опроса
+*/
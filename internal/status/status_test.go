@@ -0,0 +1,78 @@
+package status
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/worker"
+)
+
+// fakeSource имитирует пул, счётчики которого растут во время запуска.
+type fakeSource struct {
+	processed atomic.Int64
+	failed    atomic.Int64
+}
+
+func (f *fakeSource) GetStats() worker.Stats {
+	return worker.Stats{Processed: f.processed.Load(), Failed: f.failed.Load()}
+}
+
+func TestReporter_CountsAdvance(t *testing.T) {
+	src := &fakeSource{}
+	r := New(src, time.Now())
+	r.SetExpected(4)
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	get := func() Snapshot {
+		t.Helper()
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		var s Snapshot
+		if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	s := get()
+	if s.Done != 0 || s.Expected != 4 || s.RemainingSec != nil {
+		t.Errorf("до обработки: %+v", s)
+	}
+
+	src.processed.Add(1)
+	src.failed.Add(1)
+	s = get()
+	if s.Processed != 1 || s.Failed != 1 || s.Done != 2 {
+		t.Errorf("после двух файлов: %+v", s)
+	}
+	if s.RemainingSec == nil {
+		t.Error("нет оценки оставшегося времени")
+	}
+}
+
+func TestReporter_Remaining(t *testing.T) {
+	src := &fakeSource{}
+	src.processed.Store(3)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := New(src, start)
+
+	if s := r.Snapshot(start.Add(6 * time.Second)); s.Expected != -1 || s.RemainingSec != nil {
+		t.Errorf("без ожидаемого количества: %+v", s)
+	}
+
+	r.SetExpected(10)
+	s := r.Snapshot(start.Add(6 * time.Second))
+	// 3 задачи за 6 секунд, осталось 7: 14 секунд
+	if s.RemainingSec == nil || *s.RemainingSec != 14 || s.ElapsedSec != 6 {
+		t.Errorf("оценка: %+v", s)
+	}
+}
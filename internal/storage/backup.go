@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// BackupSuffix - суффикс резервной копии БД перед миграцией.
+const BackupSuffix = ".bak"
+
+// Options - параметры открытия БД.
+type Options struct {
+	// NoBackup - не создавать резервную копию перед миграцией старой схемы.
+	NoBackup bool
+
+	// NoMigrate - открыть БД без миграций (для команды migrate).
+	NoMigrate bool
+}
+
+// storedSchemaVersion возвращает версию схемы существующей БД.
+// Для новой (пустой) БД возвращает -1, для БД без schema_info - 0.
+func (s *Storage) storedSchemaVersion() (int, error) {
+	var tables int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'jobs'").Scan(&tables)
+	if err != nil {
+		return 0, err
+	}
+	if tables == 0 {
+		return -1, nil
+	}
+
+	var value string
+	err = s.db.QueryRow("SELECT value FROM schema_info WHERE key = 'version'").Scan(&value)
+	if err == sql.ErrNoRows || (err != nil && strings.Contains(err.Error(), "no such table")) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("некорректная версия схемы %q: %w", value, err)
+	}
+	return version, nil
+}
+
+// Backup копирует БД в <путь>.bak, заменяя прежнюю копию.
+// Копия делается через VACUUM INTO: в неё попадают и данные из WAL.
+// Возвращает путь к копии.
+func (s *Storage) Backup() (string, error) {
+	backupPath := s.path + BackupSuffix
+	if err := os.Remove(backupPath); err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("не удалось удалить старую резервную копию: %w", err)
+	}
+	if _, err := s.db.Exec("VACUUM INTO ?", backupPath); err != nil {
+		return "", fmt.Errorf("не удалось создать резервную копию %s: %w", backupPath, err)
+	}
+	return backupPath, nil
+}
+
+/*
+Возможные расширения:
+- Хранить несколько резервных копий с версией в имени
+- Восстановление из резервной копии отдельной командой
+*/
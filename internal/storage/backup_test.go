@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeV1Fixture создаёт БД первой версии схемы (до dst_size и content_hash_kind)
+// с одной успешной задачей.
+func writeV1Fixture(t *testing.T, path string) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	for _, stmt := range []string{
+		`CREATE TABLE jobs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			src_path TEXT NOT NULL, src_size INTEGER NOT NULL, src_mtime INTEGER NOT NULL,
+			out_format TEXT NOT NULL, out_params TEXT NOT NULL, out_params_hash TEXT NOT NULL,
+			content_sha256 TEXT, dst_path TEXT, status TEXT NOT NULL, error TEXT,
+			started_at INTEGER, finished_at INTEGER
+		)`,
+		`CREATE TABLE schema_info (key TEXT PRIMARY KEY, value TEXT NOT NULL)`,
+		`INSERT INTO schema_info (key, value) VALUES ('version', '1')`,
+		`INSERT INTO jobs (src_path, src_size, src_mtime, out_format, out_params, out_params_hash, dst_path, status)
+		 VALUES ('/src/a.jpg', 10, 1, 'webp', '{}', 'h', '/out/a.webp', 'ok')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+}
+
+// hasColumn проверяет наличие колонки в таблице jobs.
+func hasColumn(t *testing.T, path, column string) bool {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('jobs') WHERE name = ?", column).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n > 0
+}
+
+func TestNew_BacksUpOldSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.sqlite")
+	writeV1Fixture(t, path)
+
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.BackupPath != path+BackupSuffix {
+		t.Errorf("BackupPath = %q, want %q", s.BackupPath, path+BackupSuffix)
+	}
+	jobs, err := s.FindJobsBySrc("/src/a.jpg")
+	if err != nil || len(jobs) != 1 {
+		t.Errorf("задача после миграции: %v, %v", jobs, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Копия снята до миграции: старая схема и данные
+	if hasColumn(t, path+BackupSuffix, "dst_size") {
+		t.Error("резервная копия содержит колонку, добавленную миграцией")
+	}
+	if !hasColumn(t, path, "dst_size") {
+		t.Error("основная БД не мигрирована")
+	}
+	bdb, err := sql.Open("sqlite3", path+BackupSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	err = bdb.QueryRow("SELECT COUNT(*) FROM jobs WHERE src_path = '/src/a.jpg'").Scan(&n)
+	_ = bdb.Close()
+	if err != nil || n != 1 {
+		t.Errorf("задача в резервной копии: n=%d, err=%v", n, err)
+	}
+
+	// Повторное открытие актуальной схемы копию не создаёт
+	if err := os.Remove(path + BackupSuffix); err != nil {
+		t.Fatal(err)
+	}
+	s, err = New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+	if s.BackupPath != "" {
+		t.Errorf("копия актуальной схемы: %q", s.BackupPath)
+	}
+	if _, err := os.Stat(path + BackupSuffix); !os.IsNotExist(err) {
+		t.Errorf("резервная копия не должна создаваться: %v", err)
+	}
+}
+
+func TestNew_NoBackupForNewOrDisabled(t *testing.T) {
+	dir := t.TempDir()
+
+	fresh, err := New(filepath.Join(dir, "fresh.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = fresh.Close()
+	if fresh.BackupPath != "" {
+		t.Errorf("новая БД: BackupPath = %q", fresh.BackupPath)
+	}
+
+	old := filepath.Join(dir, "old.sqlite")
+	writeV1Fixture(t, old)
+	s, err := NewWithOptions(old, Options{NoBackup: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Close()
+	if _, err := os.Stat(old + BackupSuffix); !os.IsNotExist(err) {
+		t.Errorf("с NoBackup копия не должна создаваться: %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// batchOpKind - вид отложенной записи.
+type batchOpKind int
+
+const (
+	opSetDst batchOpKind = iota
+	opOK
+	opFailed
+)
+
+// batchOp - отложенная запись о задаче.
+type batchOp struct {
+	kind       batchOpKind
+	jobID      int64
+	dstPath    string
+	dstSize    int64
+	errMsg     string
+	finishedAt int64
+}
+
+// batcher копит записи о завершении задач и записывает их транзакциями:
+// при накоплении size записей или раз в interval. Каждая запись вне пакета -
+// отдельная транзакция, а с MaxOpenConns=1 они выполняются строго по очереди,
+// поэтому на тысячах мелких файлов запись в БД становится узким местом.
+type batcher struct {
+	size     int
+	interval time.Duration
+
+	mu  sync.Mutex
+	ops []batchOp
+	// pendingOK - успешно завершённые, но ещё не записанные задачи (ID -> dst_path).
+	// Нужны, чтобы дубликаты по содержимому находились и до записи пакета.
+	pendingOK map[int64]string
+	err       error
+
+	// flushMu упорядочивает сброс пакетов: записи применяются в порядке поступления.
+	flushMu sync.Mutex
+
+	kick chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// EnableBatching включает пакетную запись FinalizeJobOK, FinalizeJobFailed
+// и SetJobDstPath: они ставятся в очередь, а фоновый flusher записывает их
+// транзакциями по size записей или раз в interval. Чтения, зависящие от
+// статусов задач, сначала сбрасывают очередь. Close записывает остаток.
+// При аварийном завершении несброшенные задачи остаются in_progress
+// и разбираются при следующем запуске (в том числе через --resume).
+func (s *Storage) EnableBatching(size int, interval time.Duration) {
+	if size < 1 || s.batch != nil {
+		return
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	b := &batcher{
+		size:      size,
+		interval:  interval,
+		pendingOK: make(map[int64]string),
+		kick:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	s.batch = b
+	go s.flushLoop(b)
+}
+
+// flushLoop сбрасывает очередь по таймеру или по заполнению пакета.
+func (s *Storage) flushLoop(b *batcher) {
+	defer close(b.done)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+		case <-b.kick:
+		}
+		_ = s.Flush()
+	}
+}
+
+// enqueue ставит запись в очередь. Возвращает false, если пакетная запись выключена.
+func (s *Storage) enqueue(op batchOp) bool {
+	b := s.batch
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	b.ops = append(b.ops, op)
+	if op.kind == opOK {
+		b.pendingOK[op.jobID] = op.dstPath
+	}
+	full := len(b.ops) >= b.size
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.kick <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+// Flush записывает накопленные записи одной транзакцией.
+// Возвращает первую ошибку записи пакетов с момента включения batching.
+func (s *Storage) Flush() error {
+	b := s.batch
+	if b == nil {
+		return nil
+	}
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	ops := b.ops
+	b.ops = nil
+	b.mu.Unlock()
+
+	if len(ops) > 0 {
+		err := s.applyBatch(ops)
+
+		b.mu.Lock()
+		for _, op := range ops {
+			if op.kind == opOK {
+				delete(b.pendingOK, op.jobID)
+			}
+		}
+		if err != nil && b.err == nil {
+			b.err = err
+		}
+		b.mu.Unlock()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+// applyBatch выполняет записи пакета в одной транзакции.
+// Конфликт уникального индекса при успешном завершении (дубликат по содержимому,
+// завершённый параллельно) помечает только эту задачу как failed - так же,
+// как без пакетной записи ошибка FinalizeJobOK приводит к failed.
+func (s *Storage) applyBatch(ops []batchOp) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, op := range ops {
+		if err := applyOp(tx, op); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("не удалось записать пакет задач: %w", err)
+	}
+	return nil
+}
+
+// applyOp выполняет одну запись пакета.
+func applyOp(tx *sql.Tx, op batchOp) error {
+	var err error
+	switch op.kind {
+	case opSetDst:
+		_, err = tx.Exec("UPDATE jobs SET dst_path = ? WHERE id = ?", op.dstPath, op.jobID)
+	case opOK:
+		_, err = tx.Exec(
+			"UPDATE jobs SET status = ?, dst_path = ?, dst_size = ?, finished_at = ? WHERE id = ?",
+			StatusOK, op.dstPath, op.dstSize, op.finishedAt, op.jobID,
+		)
+		if isUniqueConstraintError(err) {
+			_, err = tx.Exec(
+				"UPDATE jobs SET status = ?, error = ?, finished_at = ? WHERE id = ?",
+				StatusFailed, "не удалось обновить статус задачи: "+err.Error(), op.finishedAt, op.jobID,
+			)
+		}
+	case opFailed:
+		_, err = tx.Exec(
+			"UPDATE jobs SET status = ?, error = ?, finished_at = ? WHERE id = ?",
+			StatusFailed, op.errMsg, op.finishedAt, op.jobID,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("не удалось обновить задачу %d: %w", op.jobID, err)
+	}
+	return nil
+}
+
+// pendingContentJob ищет среди ещё не записанных успешных задач задачу другого
+// файла с тем же ключом содержимого. В БД такие задачи пока in_progress.
+// Вызывается, только если основной запрос по status='ok' ничего не нашёл.
+func (s *Storage) pendingContentJob(info FileInfo, outFormat, outParamsHash string) (srcPath, dstPath string, ok bool) {
+	b := s.batch
+	if b == nil {
+		return "", "", false
+	}
+	// Очередь могла быть записана между основным запросом и этим,
+	// поэтому учитываются и задачи, уже ставшие ok
+	rows, err := s.db.Query(`
+		SELECT id, src_path, dst_path, status FROM jobs
+		WHERE content_sha256 = ? AND COALESCE(content_hash_kind, 'full') = ?
+		  AND out_format = ? AND out_params_hash = ? AND status IN (?, ?)
+		  AND src_path != ?
+	`, info.ContentSHA256, info.hashKind(), outFormat, outParamsHash, StatusInProgress, StatusOK, info.Path)
+	if err != nil {
+		return "", "", false
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id     int64
+			src    string
+			dst    sql.NullString
+			status JobStatus
+		)
+		if err := rows.Scan(&id, &src, &dst, &status); err != nil {
+			return "", "", false
+		}
+		if status == StatusOK && dst.Valid {
+			return src, dst.String, true
+		}
+		b.mu.Lock()
+		pendingDst, pending := b.pendingOK[id]
+		b.mu.Unlock()
+		if pending {
+			return src, pendingDst, true
+		}
+	}
+	return "", "", false
+}
+
+// closeBatching останавливает flusher и записывает остаток очереди.
+func (s *Storage) closeBatching() error {
+	b := s.batch
+	if b == nil {
+		return nil
+	}
+	close(b.stop)
+	<-b.done
+	err := s.Flush()
+	s.batch = nil
+	return err
+}
+
+/*
+Возможные расширения:
+- Пакетная вставка задач (TryStartJob) с резервированием ID
+- Адаптивный размер пакета по времени транзакции
+*/
@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestStorage открывает чистую БД во временной директории.
+func newTestStorage(t testing.TB) (*Storage, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "state.sqlite")
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return s, path
+}
+
+// startJob начинает задачу для уникального исходного файла.
+func startJob(t testing.TB, s *Storage, i int64, content string, dedup bool) int64 {
+	t.Helper()
+	info := FileInfo{Path: fmt.Sprintf("/src/%06d.jpg", i), Size: 100, Mtime: 1, ContentSHA256: content}
+	res, err := s.TryStartJob(info, "webp", "{}", "h", dedup)
+	if err != nil || !res.Started {
+		t.Fatalf("TryStartJob(%d): %+v, %v", i, res, err)
+	}
+	return res.JobID
+}
+
+func TestBatching_NoLostFinalizations(t *testing.T) {
+	s, path := newTestStorage(t)
+	// Пакеты по 10, таймер не срабатывает: сбрасывает заполнение пакета и Close
+	s.EnableBatching(10, time.Hour)
+
+	const n = 25
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = startJob(t, s, int64(i), "", false)
+	}
+
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id int64) {
+			defer wg.Done()
+			var err error
+			if i%5 == 0 {
+				err = s.FinalizeJobFailed(id, "boom")
+			} else {
+				_ = s.SetJobDstPath(id, fmt.Sprintf("/out/%d.webp", i))
+				err = s.FinalizeJobOK(id, fmt.Sprintf("/out/%d.webp", i), int64(i))
+			}
+			if err != nil {
+				t.Error(err)
+			}
+		}(i, id)
+	}
+	wg.Wait()
+
+	// Заполненные пакеты записываются без явного Flush; хвост (меньше 10) ждёт Close
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var ok int64
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM jobs WHERE status != ?", StatusInProgress).Scan(&ok); err != nil {
+			t.Fatal(err)
+		}
+		if ok >= 20 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("заполненные пакеты не записаны: завершено %d", ok)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s2.Close() }()
+	total, ok, failed, inProgress, err := s2.GetStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != n || ok != 20 || failed != 5 || inProgress != 0 {
+		t.Errorf("total=%d ok=%d failed=%d in_progress=%d, want 25/20/5/0", total, ok, failed, inProgress)
+	}
+}
+
+func TestBatching_ReadsSeePendingFinalizations(t *testing.T) {
+	s, _ := newTestStorage(t)
+	defer func() { _ = s.Close() }()
+	s.EnableBatching(1000, time.Hour)
+
+	id := startJob(t, s, 1, "sha", true)
+	if err := s.FinalizeJobOK(id, "/out/1.webp", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	// Дубликат по содержимому находится до записи пакета
+	dup := FileInfo{Path: "/src/copy.jpg", Size: 100, Mtime: 1, ContentSHA256: "sha"}
+	res, err := s.TryStartJob(dup, "webp", "{}", "h", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Started || res.ExistingDstPath != "/out/1.webp" {
+		t.Errorf("дубликат не найден среди ожидающих записи: %+v", res)
+	}
+
+	// Чтение статусов сбрасывает очередь
+	jobs, err := s.ListJobsForRun(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].Status != StatusOK {
+		t.Errorf("задачи: %+v", jobs)
+	}
+}
+
+func TestBatching_DedupConflictMarksFailed(t *testing.T) {
+	s, _ := newTestStorage(t)
+	defer func() { _ = s.Close() }()
+	s.EnableBatching(1000, time.Hour)
+
+	// Две задачи с одним содержимым начаты параллельно, до завершения любой из них
+	a := startJob(t, s, 1, "sha", true)
+	b := startJob(t, s, 2, "sha", true)
+	_ = s.FinalizeJobOK(a, "/out/a.webp", 1)
+	_ = s.FinalizeJobOK(b, "/out/b.webp", 1)
+
+	if err := s.Flush(); err != nil {
+		t.Fatalf("конфликт индекса не должен ронять пакет: %v", err)
+	}
+	_, ok, failed, _, err := s.GetStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok != 1 || failed != 1 {
+		t.Errorf("ok=%d failed=%d, want 1/1", ok, failed)
+	}
+}
+
+// BenchmarkJobLifecycle измеряет запись задач в БД на мелких файлах:
+// TryStartJob + SetJobDstPath + FinalizeJobOK из нескольких воркеров.
+func BenchmarkJobLifecycle(b *testing.B) {
+	for _, batch := range []int{0, 64} {
+		b.Run(fmt.Sprintf("batch=%d", batch), func(b *testing.B) {
+			s, _ := newTestStorage(b)
+			s.EnableBatching(batch, 500*time.Millisecond)
+			var next atomic.Int64
+
+			b.SetParallelism(2)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					i := next.Add(1)
+					id := startJob(b, s, i, "", false)
+					dst := fmt.Sprintf("/out/%06d.webp", i)
+					_ = s.SetJobDstPath(id, dst)
+					_ = s.FinalizeJobOK(id, dst, 10)
+				}
+			})
+			if err := s.Close(); err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
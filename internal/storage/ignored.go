@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// IgnoreSources помечает исходники srcPaths (абсолютные пути) игнорируемыми:
+// пул пропускает их без попытки конвертации, пока пометка не снята.
+// Возвращает число новых пометок (уже игнорируемые не считаются).
+func (s *Storage) IgnoreSources(srcPaths []string) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().Unix()
+	var added int64
+	for _, path := range srcPaths {
+		res, err := tx.Exec("INSERT OR IGNORE INTO ignored (src_path, ignored_at) VALUES (?, ?)", path, now)
+		if err != nil {
+			return 0, fmt.Errorf("не удалось пометить %s игнорируемым: %w", path, err)
+		}
+		n, _ := res.RowsAffected()
+		added += n
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("не удалось сохранить игнорируемые исходники: %w", err)
+	}
+	return added, nil
+}
+
+// ClearIgnored снимает все пометки игнорирования и возвращает их число.
+func (s *Storage) ClearIgnored() (int64, error) {
+	res, err := s.db.Exec("DELETE FROM ignored")
+	if err != nil {
+		return 0, fmt.Errorf("не удалось снять пометки игнорирования: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// IgnoredSources возвращает множество игнорируемых исходников.
+func (s *Storage) IgnoredSources() (map[string]bool, error) {
+	rows, err := s.db.Query("SELECT src_path FROM ignored")
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить игнорируемые исходники: %w", err)
+	}
+	defer rows.Close()
+
+	ignored := make(map[string]bool)
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		ignored[path] = true
+	}
+	return ignored, rows.Err()
+}
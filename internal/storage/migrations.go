@@ -1,59 +1,151 @@
 // Package storage содержит миграции SQLite базы данных.
 package storage
 
-// migrations содержит SQL-миграции в порядке выполнения.
-var migrations = []string{
-	// Миграция 1: Создание таблицы jobs
-	`CREATE TABLE IF NOT EXISTS jobs (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		src_path TEXT NOT NULL,
-		src_size INTEGER NOT NULL,
-		src_mtime INTEGER NOT NULL,
-		out_format TEXT NOT NULL,
-		out_params TEXT NOT NULL,
-		out_params_hash TEXT NOT NULL,
-		content_sha256 TEXT,
-		dst_path TEXT,
-		status TEXT NOT NULL,
-		error TEXT,
-		started_at INTEGER,
-		finished_at INTEGER
-	);`,
-
-	// Миграция 2: Уникальный индекс для идемпотентности по источнику
-	// Гарантирует, что один и тот же файл (path+size+mtime) с теми же параметрами
-	// не будет обработан дважды.
-	`CREATE UNIQUE INDEX IF NOT EXISTS ux_jobs_src
-	ON jobs (src_path, src_size, src_mtime, out_format, out_params_hash);`,
-
-	// Миграция 3: Уникальный индекс для дедупликации по содержимому
-	// Гарантирует, что файлы с одинаковым содержимым (sha256) и параметрами
-	// не создадут дублирующиеся выходные файлы.
-	`CREATE UNIQUE INDEX IF NOT EXISTS ux_jobs_dedup
-	ON jobs (content_sha256, out_format, out_params_hash)
-	WHERE content_sha256 IS NOT NULL AND status='ok';`,
-
-	// Миграция 4: Индекс для быстрого поиска по статусу
-	`CREATE INDEX IF NOT EXISTS ix_jobs_status ON jobs (status);`,
-
-	// Миграция 5: Таблица метаданных для версионирования схемы
-	`CREATE TABLE IF NOT EXISTS schema_info (
-		key TEXT PRIMARY KEY,
-		value TEXT NOT NULL
-	);`,
-
-	// Миграция 6: Запись версии схемы
-	`INSERT OR REPLACE INTO schema_info (key, value) VALUES ('version', '1');`,
-}
-
-// GetMigrations возвращает список SQL-миграций.
-func GetMigrations() []string {
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Migration - версионированный шаг схемы.
+// Up переводит схему с версии Version-1 на Version, Down - обратно.
+// Шаги выполняются внутри транзакции и должны быть идемпотентными:
+// базы первой версии могли получить часть изменений до появления версий.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(tx *sql.Tx) error
+	Down        func(tx *sql.Tx) error
+}
+
+// migrations содержит шаги схемы в порядке версий (версия = индекс + 1).
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "таблица jobs, индексы и schema_info",
+		Up: execAll(
+			`CREATE TABLE IF NOT EXISTS jobs (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				src_path TEXT NOT NULL,
+				src_size INTEGER NOT NULL,
+				src_mtime INTEGER NOT NULL,
+				out_format TEXT NOT NULL,
+				out_params TEXT NOT NULL,
+				out_params_hash TEXT NOT NULL,
+				content_sha256 TEXT,
+				dst_path TEXT,
+				status TEXT NOT NULL,
+				error TEXT,
+				started_at INTEGER,
+				finished_at INTEGER
+			);`,
+
+			// Уникальный индекс для идемпотентности по источнику
+			// Гарантирует, что один и тот же файл (path+size+mtime) с теми же параметрами
+			// не будет обработан дважды.
+			`CREATE UNIQUE INDEX IF NOT EXISTS ux_jobs_src
+			ON jobs (src_path, src_size, src_mtime, out_format, out_params_hash);`,
+
+			// Уникальный индекс для дедупликации по содержимому
+			// Гарантирует, что файлы с одинаковым содержимым (sha256) и параметрами
+			// не создадут дублирующиеся выходные файлы.
+			`CREATE UNIQUE INDEX IF NOT EXISTS ux_jobs_dedup
+			ON jobs (content_sha256, out_format, out_params_hash)
+			WHERE content_sha256 IS NOT NULL AND status='ok';`,
+
+			// Индекс для быстрого поиска по статусу
+			`CREATE INDEX IF NOT EXISTS ix_jobs_status ON jobs (status);`,
+		),
+		// Первая версия - минимальная: откатывать дальше некуда
+		Down: nil,
+	},
+	{
+		Version:     2,
+		Description: "размер выходного файла (jobs.dst_size)",
+		Up:          addColumn("jobs", "dst_size", "INTEGER"),
+		Down:        dropColumn("jobs", "dst_size"),
+	},
+	{
+		Version:     3,
+		Description: "вид ключа содержимого (jobs.content_hash_kind: full, quick; NULL = full)",
+		Up:          addColumn("jobs", "content_hash_kind", "TEXT"),
+		Down:        dropColumn("jobs", "content_hash_kind"),
+	},
+	{
+		Version:     4,
+		Description: "индекс по выходному формату",
+		Up:          execAll(`CREATE INDEX IF NOT EXISTS ix_jobs_format ON jobs (out_format);`),
+		Down:        execAll(`DROP INDEX IF EXISTS ix_jobs_format;`),
+	},
+	{
+		Version:     5,
+		Description: "игнорируемые исходники (таблица ignored, --ignore-file)",
+		Up: execAll(`CREATE TABLE IF NOT EXISTS ignored (
+			src_path TEXT PRIMARY KEY,
+			ignored_at INTEGER NOT NULL
+		);`),
+		Down: execAll(`DROP TABLE IF EXISTS ignored;`),
+	},
+}
+
+// SchemaVersion возвращает версию схемы, которую создаёт код: растёт
+// с каждой новой миграцией. Базы с меньшей версией перед миграцией копируются
+// в резервный файл.
+func SchemaVersion() int {
+	return len(migrations)
+}
+
+// GetMigrations возвращает шаги схемы в порядке версий.
+func GetMigrations() []Migration {
 	return migrations
 }
 
+// execAll возвращает шаг, выполняющий SQL-выражения по порядку.
+func execAll(stmts ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, stmt := range stmts {
+			if _, err := tx.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// addColumn возвращает шаг, добавляющий колонку, если её ещё нет.
+// SQLite не поддерживает ADD COLUMN IF NOT EXISTS, поэтому наличие колонки
+// проверяется перед выполнением ALTER TABLE.
+func addColumn(table, column, definition string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		exists, err := columnExists(tx, table, column)
+		if err != nil || exists {
+			return err
+		}
+		_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+		return err
+	}
+}
+
+// dropColumn возвращает шаг, удаляющий колонку, если она есть.
+func dropColumn(table, column string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		exists, err := columnExists(tx, table, column)
+		if err != nil || !exists {
+			return err
+		}
+		_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column))
+		return err
+	}
+}
+
+// columnExists проверяет наличие колонки в таблице.
+func columnExists(tx *sql.Tx, table, column string) (bool, error) {
+	var n int
+	err := tx.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&n)
+	return n > 0, err
+}
+
 /*
 Возможные расширения:
 - Добавить таблицу для хранения статистики (общее время, количество файлов)
 - Добавить таблицу для хранения ошибок отдельно
-- Добавить поддержку отката миграций (down migrations)
 */
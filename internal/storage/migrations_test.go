@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// schemaSnapshot описывает схему: колонки jobs и индексы.
+type schemaSnapshot struct {
+	Columns []string
+	Indexes []string
+}
+
+// readSchema читает колонки таблицы jobs и имена пользовательских индексов.
+func readSchema(t *testing.T, s *Storage) schemaSnapshot {
+	t.Helper()
+	var snap schemaSnapshot
+	for query, dst := range map[string]*[]string{
+		"SELECT name FROM pragma_table_info('jobs') ORDER BY cid":                                                     &snap.Columns,
+		"SELECT name FROM sqlite_master WHERE type = 'index' AND sql IS NOT NULL AND tbl_name = 'jobs' ORDER BY name": &snap.Indexes,
+	} {
+		rows, err := s.db.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				t.Fatal(err)
+			}
+			*dst = append(*dst, name)
+		}
+		_ = rows.Close()
+	}
+	return snap
+}
+
+func TestMigrateTo_DownAndUp(t *testing.T) {
+	dir := t.TempDir()
+
+	// Эталон первой версии - свежая БД, мигрированная только до версии 1
+	ref, err := NewWithOptions(filepath.Join(dir, "v1.sqlite"), Options{NoMigrate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ref.MigrateTo(1); err != nil {
+		t.Fatal(err)
+	}
+	v1 := readSchema(t, ref)
+	_ = ref.Close()
+
+	s, err := New(filepath.Join(dir, "state.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+	latest := readSchema(t, s)
+	if got, _ := s.CurrentSchemaVersion(); got != SchemaVersion() {
+		t.Fatalf("версия после New = %d, want %d", got, SchemaVersion())
+	}
+
+	info := FileInfo{Path: "/src/a.jpg", Size: 10, Mtime: 1}
+	res, err := s.TryStartJob(info, "webp", "{}", "h", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.FinalizeJobOK(res.JobID, "/out/a.webp", 5); err != nil {
+		t.Fatal(err)
+	}
+
+	// Откат до версии 1: схема совпадает с эталоном, данные сохранены
+	if err := s.MigrateTo(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := readSchema(t, s); !reflect.DeepEqual(got, v1) {
+		t.Errorf("схема после отката:\n%+v\nwant\n%+v", got, v1)
+	}
+	if got, _ := s.CurrentSchemaVersion(); got != 1 {
+		t.Errorf("версия после отката = %d, want 1", got)
+	}
+	var n int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM jobs WHERE src_path = '/src/a.jpg'").Scan(&n); err != nil || n != 1 {
+		t.Errorf("задача после отката: n=%d, err=%v", n, err)
+	}
+
+	// Пошаговый подъём обратно до последней версии
+	for v := 2; v <= SchemaVersion(); v++ {
+		if err := s.MigrateTo(v); err != nil {
+			t.Fatalf("MigrateTo(%d): %v", v, err)
+		}
+	}
+	if got := readSchema(t, s); !reflect.DeepEqual(got, latest) {
+		t.Errorf("схема после подъёма:\n%+v\nwant\n%+v", got, latest)
+	}
+
+	if err := s.MigrateTo(0); err == nil {
+		t.Error("MigrateTo(0) должна вернуть ошибку")
+	}
+	if err := s.MigrateTo(SchemaVersion() + 1); err == nil {
+		t.Error("MigrateTo(неизвестная версия) должна вернуть ошибку")
+	}
+}
+
+func TestNew_RejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.sqlite")
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.db.Exec("UPDATE schema_info SET value = ? WHERE key = 'version'", SchemaVersion()+1); err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Close()
+
+	_, err = New(path)
+	if err == nil || !strings.Contains(err.Error(), "migrate --to") {
+		t.Errorf("New(новая схема) = %v, want ошибку с подсказкой migrate", err)
+	}
+}
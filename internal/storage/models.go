@@ -15,6 +15,14 @@ const (
 	StatusFailed JobStatus = "failed"
 )
 
+// Виды ключа содержимого в content_sha256.
+const (
+	// HashKindFull - хэш всего файла.
+	HashKindFull = "full"
+	// HashKindQuick - быстрый ключ: размер + первые и последние N байт (--dedup-quick).
+	HashKindQuick = "quick"
+)
+
 // Job представляет задачу конвертации изображения.
 type Job struct {
 	// ID - уникальный идентификатор задачи.
@@ -44,6 +52,9 @@ type Job struct {
 	// DstPath - путь к выходному файлу.
 	DstPath *string `db:"dst_path"`
 
+	// DstSize - размер выходного файла в байтах (nullable).
+	DstSize *int64 `db:"dst_size"`
+
 	// Status - статус задачи.
 	Status JobStatus `db:"status"`
 
@@ -70,6 +81,17 @@ type FileInfo struct {
 
 	// ContentSHA256 - sha256 хэш содержимого (опционально).
 	ContentSHA256 string
+
+	// ContentHashKind - вид ключа ContentSHA256: HashKindFull (по умолчанию) или HashKindQuick.
+	ContentHashKind string
+}
+
+// hashKind возвращает вид ключа содержимого (пусто = HashKindFull).
+func (f FileInfo) hashKind() string {
+	if f.ContentHashKind == "" {
+		return HashKindFull
+	}
+	return f.ContentHashKind
 }
 
 // JobResult содержит результат обработки задачи.
@@ -92,7 +114,7 @@ type StartJobResult struct {
 	// Started - была ли задача начата.
 	Started bool
 
-	// JobID - ID задачи (если начата).
+	// JobID - ID задачи (если начата) или уже успешной задачи исходника.
 	JobID int64
 
 	// SkipReason - причина пропуска (если не начата).
@@ -100,11 +122,62 @@ type StartJobResult struct {
 
 	// ExistingDstPath - путь к существующему выходному файлу (для dedup).
 	ExistingDstPath string
+
+	// ExistingDstSize - размер выходного файла, записанный при успешной
+	// задаче исходника (nil - не записан или файл пропущен как дубликат).
+	ExistingDstSize *int64
+
+	// Duplicate - файл пропущен как дубликат по содержимому другого файла.
+	Duplicate bool
+
+	// Retry - задача начата повторно после прошлой ошибки.
+	Retry bool
+}
+
+// DirStats - размеры успешных задач одной директории верхнего уровня.
+type DirStats struct {
+	// Dir - первый компонент пути относительно корня ("." - файлы в самом корне).
+	Dir string
+
+	// Files - количество успешных задач.
+	Files int64
+
+	// SrcBytes, DstBytes - суммарные размеры исходных и выходных файлов.
+	SrcBytes int64
+	DstBytes int64
+}
+
+// SavedBytes возвращает экономию места (отрицательная - результат больше).
+func (d DirStats) SavedBytes() int64 {
+	return d.SrcBytes - d.DstBytes
+}
+
+// SavedPercent возвращает экономию в процентах от входного размера.
+func (d DirStats) SavedPercent() float64 {
+	if d.SrcBytes == 0 {
+		return 0
+	}
+	return float64(d.SavedBytes()) / float64(d.SrcBytes) * 100
+}
+
+// FormatStats - статистика задач одного выходного формата.
+type FormatStats struct {
+	// Format - выходной формат.
+	Format string
+
+	// Total, OK, Failed, InProgress - количество задач по статусам.
+	Total      int64
+	OK         int64
+	Failed     int64
+	InProgress int64
+
+	// SrcBytes, DstBytes - суммарные размеры исходных и выходных файлов успешных задач.
+	SrcBytes int64
+	DstBytes int64
 }
 
 /*
 Возможные расширения:
 - Добавить поле для версии vips/параметров для инвалидации кэша
-- Добавить поле для размера выходного файла (для статистики)
 - Добавить поддержку тегов/категорий для группировки
 */
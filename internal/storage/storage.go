@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -15,10 +18,27 @@ import (
 // Storage предоставляет методы для работы с базой данных jobs.
 type Storage struct {
 	db *sql.DB
+
+	// batch - очередь пакетной записи завершений задач (nil = выключена).
+	batch *batcher
+
+	// path - путь к файлу БД (для резервной копии).
+	path string
+
+	// BackupPath - резервная копия, созданная перед миграцией (пусто = не создавалась).
+	BackupPath string
 }
 
 // New создаёт новое подключение к SQLite и выполняет миграции.
 func New(dbPath string) (*Storage, error) {
+	return NewWithOptions(dbPath, Options{})
+}
+
+// NewWithOptions создаёт подключение к SQLite с параметрами opts.
+// Если схема существующей БД старше текущей, перед миграцией создаётся
+// резервная копия <dbPath>.bak (если не задан opts.NoBackup).
+// БД со схемой новее текущей не открывается: её нужно откатить командой migrate.
+func NewWithOptions(dbPath string, opts Options) (*Storage, error) {
 	// Создаём директорию для БД, если не существует
 	dbDir := filepath.Dir(dbPath)
 	if err := os.MkdirAll(dbDir, 0755); err != nil {
@@ -42,10 +62,33 @@ func New(dbPath string) (*Storage, error) {
 	db.SetMaxOpenConns(1) // SQLite не поддерживает concurrent writes
 	db.SetMaxIdleConns(1)
 
-	s := &Storage{db: db}
+	s := &Storage{db: db, path: dbPath}
+	if opts.NoMigrate {
+		return s, nil
+	}
+
+	version, err := s.storedSchemaVersion()
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("не удалось прочитать версию схемы: %w", err)
+	}
+	if version > SchemaVersion() {
+		_ = db.Close()
+		return nil, newerSchemaError(version)
+	}
+
+	// Резервная копия старой схемы перед миграцией
+	if !opts.NoBackup && version >= 0 && version < SchemaVersion() {
+		backupPath, err := s.Backup()
+		if err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+		s.BackupPath = backupPath
+	}
 
 	// Выполняем миграции
-	if err := s.migrate(); err != nil {
+	if err := s.MigrateTo(SchemaVersion()); err != nil {
 		_ = db.Close()
 		return nil, fmt.Errorf("не удалось выполнить миграции: %w", err)
 	}
@@ -53,19 +96,92 @@ func New(dbPath string) (*Storage, error) {
 	return s, nil
 }
 
-// migrate выполняет все SQL-миграции.
-func (s *Storage) migrate() error {
-	for i, m := range GetMigrations() {
-		if _, err := s.db.Exec(m); err != nil {
-			return fmt.Errorf("миграция %d: %w", i+1, err)
+// MigrateTo переводит схему на версию target: применяет шаги Up, если текущая
+// версия ниже, или Down, если выше. Выполняются только нужные шаги, все
+// в одной транзакции; версия записывается в schema_info.
+// Откат удаляет колонки вместе с данными - сначала сделайте резервную копию (Backup).
+func (s *Storage) MigrateTo(target int) error {
+	if target < 1 || target > SchemaVersion() {
+		return fmt.Errorf("неизвестная версия схемы %d (доступны 1..%d)", target, SchemaVersion())
+	}
+	if err := s.Flush(); err != nil {
+		return err
+	}
+
+	current, err := s.storedSchemaVersion()
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать версию схемы: %w", err)
+	}
+	if current > SchemaVersion() {
+		return newerSchemaError(current)
+	}
+	if current < 0 {
+		current = 0
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("не удалось начать транзакцию: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`CREATE TABLE IF NOT EXISTS schema_info (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);`); err != nil {
+		return fmt.Errorf("не удалось создать schema_info: %w", err)
+	}
+
+	for v := current + 1; v <= target; v++ {
+		m := migrations[v-1]
+		if err := m.Up(tx); err != nil {
+			return fmt.Errorf("миграция %d (%s): %w", m.Version, m.Description, err)
 		}
 	}
+	for v := current; v > target; v-- {
+		m := migrations[v-1]
+		if err := m.Down(tx); err != nil {
+			return fmt.Errorf("откат миграции %d (%s): %w", m.Version, m.Description, err)
+		}
+	}
+
+	if _, err := tx.Exec(
+		"INSERT OR REPLACE INTO schema_info (key, value) VALUES ('version', ?)",
+		strconv.Itoa(target),
+	); err != nil {
+		return fmt.Errorf("не удалось записать версию схемы: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("не удалось применить миграции: %w", err)
+	}
 	return nil
 }
 
+// CurrentSchemaVersion возвращает версию схемы, записанную в БД
+// (0 - БД без версии или пустая).
+func (s *Storage) CurrentSchemaVersion() (int, error) {
+	version, err := s.storedSchemaVersion()
+	if version < 0 {
+		version = 0
+	}
+	return version, err
+}
+
+// newerSchemaError - ошибка открытия БД, мигрированной более новой версией программы.
+func newerSchemaError(version int) error {
+	return fmt.Errorf("схема БД (версия %d) новее поддерживаемой (версия %d): "+
+		"откатите её новой версией программы: photoconverter migrate --to %d",
+		version, SchemaVersion(), SchemaVersion())
+}
+
 // Close закрывает подключение к БД.
+// Перед закрытием записывает накопленные пакеты.
 func (s *Storage) Close() error {
-	return s.db.Close()
+	flushErr := s.closeBatching()
+	if err := s.db.Close(); err != nil {
+		return err
+	}
+	return flushErr
 }
 
 // TryStartJob пытается начать обработку файла.
@@ -73,21 +189,31 @@ func (s *Storage) Close() error {
 func (s *Storage) TryStartJob(info FileInfo, outFormat, outParams, outParamsHash string, dedupMode bool) (*StartJobResult, error) {
 	now := time.Now().Unix()
 
+	// В режиме dedup сначала ищем уже сконвертированный файл с тем же содержимым:
+	// уникальный индекс по content_sha256 действует только для status='ok',
+	// поэтому вставка дубликата прошла бы и упала лишь при завершении задачи
+	if dedupMode && info.ContentSHA256 != "" {
+		if result := s.findContentDuplicate(info, outFormat, outParamsHash); result != nil {
+			return result, nil
+		}
+	}
+
 	// Пытаемся вставить новую задачу
 	query := `
 		INSERT INTO jobs (src_path, src_size, src_mtime, out_format, out_params, out_params_hash, 
-		                  content_sha256, status, started_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		                  content_sha256, content_hash_kind, status, started_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	var contentSHA256 *string
+	var contentSHA256, hashKind *string
 	if dedupMode && info.ContentSHA256 != "" {
-		contentSHA256 = &info.ContentSHA256
+		kind := info.hashKind()
+		contentSHA256, hashKind = &info.ContentSHA256, &kind
 	}
 
 	result, err := s.db.Exec(query,
 		info.Path, info.Size, info.Mtime, outFormat, outParams, outParamsHash,
-		contentSHA256, StatusInProgress, now,
+		contentSHA256, hashKind, StatusInProgress, now,
 	)
 
 	if err != nil {
@@ -113,16 +239,7 @@ func (s *Storage) TryStartJob(info FileInfo, outFormat, outParams, outParamsHash
 // checkExistingJob проверяет существующую задачу и возвращает причину пропуска.
 func (s *Storage) checkExistingJob(info FileInfo, outFormat, outParamsHash string, dedupMode bool) (*StartJobResult, error) {
 	// Сначала проверяем по source path
-	var job Job
-	query := `
-		SELECT id, status, dst_path, error FROM jobs 
-		WHERE src_path = ? AND src_size = ? AND src_mtime = ? 
-		  AND out_format = ? AND out_params_hash = ?
-		LIMIT 1
-	`
-	err := s.db.QueryRow(query, info.Path, info.Size, info.Mtime, outFormat, outParamsHash).
-		Scan(&job.ID, &job.Status, &job.DstPath, &job.Error)
-
+	job, err := s.findSourceJob(info, outFormat, outParamsHash)
 	if err == nil {
 		switch job.Status {
 		case StatusOK:
@@ -132,8 +249,10 @@ func (s *Storage) checkExistingJob(info FileInfo, outFormat, outParamsHash strin
 			}
 			return &StartJobResult{
 				Started:         false,
+				JobID:           job.ID,
 				SkipReason:      "уже успешно обработан",
 				ExistingDstPath: dstPath,
+				ExistingDstSize: job.DstSize,
 			}, nil
 		case StatusInProgress:
 			return &StartJobResult{
@@ -146,25 +265,18 @@ func (s *Storage) checkExistingJob(info FileInfo, outFormat, outParamsHash strin
 				return nil, fmt.Errorf("не удалось удалить failed задачу: %w", err)
 			}
 			// Повторяем вставку
-			return s.TryStartJob(info, outFormat, "", outParamsHash, dedupMode)
+			result, err := s.TryStartJob(info, outFormat, "", outParamsHash, dedupMode)
+			if result != nil && result.Started {
+				result.Retry = true
+			}
+			return result, err
 		}
 	}
 
 	// Если режим dedup, проверяем по content_sha256
 	if dedupMode && info.ContentSHA256 != "" {
-		query = `
-			SELECT dst_path FROM jobs 
-			WHERE content_sha256 = ? AND out_format = ? AND out_params_hash = ? AND status = 'ok'
-			LIMIT 1
-		`
-		var dstPath *string
-		err := s.db.QueryRow(query, info.ContentSHA256, outFormat, outParamsHash).Scan(&dstPath)
-		if err == nil && dstPath != nil {
-			return &StartJobResult{
-				Started:         false,
-				SkipReason:      "дубликат по содержимому",
-				ExistingDstPath: *dstPath,
-			}, nil
+		if result := s.findContentDuplicate(info, outFormat, outParamsHash); result != nil {
+			return result, nil
 		}
 	}
 
@@ -174,12 +286,159 @@ func (s *Storage) checkExistingJob(info FileInfo, outFormat, outParamsHash strin
 	}, nil
 }
 
+// findSourceJob ищет задачу того же исходного файла (path+size+mtime) с теми же параметрами выхода.
+func (s *Storage) findSourceJob(info FileInfo, outFormat, outParamsHash string) (Job, error) {
+	var job Job
+	if err := s.Flush(); err != nil {
+		return job, err
+	}
+	query := `
+		SELECT id, status, dst_path, dst_size, error FROM jobs 
+		WHERE src_path = ? AND src_size = ? AND src_mtime = ? 
+		  AND out_format = ? AND out_params_hash = ?
+		LIMIT 1
+	`
+	err := s.db.QueryRow(query, info.Path, info.Size, info.Mtime, outFormat, outParamsHash).
+		Scan(&job.ID, &job.Status, &job.DstPath, &job.DstSize, &job.Error)
+	return job, err
+}
+
+// PlanJob возвращает решение, которое принял бы TryStartJob, не изменяя БД
+// (для dry-run). Started = true означает, что файл был бы сконвертирован;
+// Retry - что прошлая попытка завершилась ошибкой. JobID заполняется только
+// для уже успешной задачи.
+func (s *Storage) PlanJob(info FileInfo, outFormat, outParamsHash string, dedupMode bool) (*StartJobResult, error) {
+	if dedupMode && info.ContentSHA256 != "" {
+		if result := s.findContentDuplicate(info, outFormat, outParamsHash); result != nil {
+			return result, nil
+		}
+	}
+
+	job, err := s.findSourceJob(info, outFormat, outParamsHash)
+	if err == sql.ErrNoRows {
+		return &StartJobResult{Started: true}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("не удалось проверить задачу: %w", err)
+	}
+
+	switch job.Status {
+	case StatusOK:
+		dstPath := ""
+		if job.DstPath != nil {
+			dstPath = *job.DstPath
+		}
+		return &StartJobResult{JobID: job.ID, SkipReason: "уже успешно обработан", ExistingDstPath: dstPath, ExistingDstSize: job.DstSize}, nil
+	case StatusInProgress:
+		return &StartJobResult{SkipReason: "уже обрабатывается"}, nil
+	default:
+		return &StartJobResult{Started: true, Retry: true}, nil
+	}
+}
+
+// findContentDuplicate ищет успешную задачу другого файла с тем же содержимым
+// и параметрами выхода. Возвращает nil, если дубликата нет.
+func (s *Storage) findContentDuplicate(info FileInfo, outFormat, outParamsHash string) *StartJobResult {
+	_, dstPath, err := s.findContentJob(info, outFormat, outParamsHash)
+	if err != nil || dstPath == "" {
+		return nil
+	}
+	return &StartJobResult{
+		Started:         false,
+		SkipReason:      "дубликат по содержимому",
+		ExistingDstPath: dstPath,
+		Duplicate:       true,
+	}
+}
+
+// FindContentSource возвращает исходный путь успешной задачи другого файла
+// с тем же ключом содержимого (того же вида) и параметрами выхода.
+// Пустая строка - совпадений нет. Используется для проверки быстрых ключей
+// (--dedup-quick) полным хэшем.
+func (s *Storage) FindContentSource(info FileInfo, outFormat, outParamsHash string) (string, error) {
+	srcPath, _, err := s.findContentJob(info, outFormat, outParamsHash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("не удалось найти задачу по содержимому: %w", err)
+	}
+	return srcPath, nil
+}
+
+// findContentJob ищет успешную задачу другого файла с тем же ключом содержимого.
+// Задачи без content_hash_kind (старые базы) считаются полными хэшами.
+func (s *Storage) findContentJob(info FileInfo, outFormat, outParamsHash string) (srcPath, dstPath string, err error) {
+	query := `
+		SELECT src_path, dst_path FROM jobs 
+		WHERE content_sha256 = ? AND COALESCE(content_hash_kind, 'full') = ?
+		  AND out_format = ? AND out_params_hash = ? AND status = 'ok'
+		  AND src_path != ?
+		LIMIT 1
+	`
+	var dst *string
+	err = s.db.QueryRow(query, info.ContentSHA256, info.hashKind(), outFormat, outParamsHash, info.Path).
+		Scan(&srcPath, &dst)
+	if err == sql.ErrNoRows {
+		if src, pendingDst, ok := s.pendingContentJob(info, outFormat, outParamsHash); ok {
+			return src, pendingDst, nil
+		}
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if dst != nil {
+		dstPath = *dst
+	}
+	return srcPath, dstPath, nil
+}
+
+// LastJobID возвращает наибольший ID задачи (0 для пустой базы).
+func (s *Storage) LastJobID() (int64, error) {
+	var id sql.NullInt64
+	if err := s.db.QueryRow("SELECT MAX(id) FROM jobs").Scan(&id); err != nil {
+		return 0, fmt.Errorf("не удалось получить ID последней задачи: %w", err)
+	}
+	return id.Int64, nil
+}
+
+// ForgetJobs удаляет задачи с ID не больше maxID для исходного файла info
+// (с любыми размером и mtime) с теми же параметрами выхода, а в режиме dedup -
+// и успешные задачи с тем же ключом содержимого. Используется в --force:
+// следующий TryStartJob начнёт задачу заново, а задачи текущего запуска
+// (ID больше maxID) сохраняются, поэтому дубликаты внутри запуска по-прежнему пропускаются.
+func (s *Storage) ForgetJobs(info FileInfo, outFormat, outParamsHash string, dedupMode bool, maxID int64) error {
+	_, err := s.db.Exec(
+		"DELETE FROM jobs WHERE src_path = ? AND out_format = ? AND out_params_hash = ? AND id <= ?",
+		info.Path, outFormat, outParamsHash, maxID,
+	)
+	if err != nil {
+		return fmt.Errorf("не удалось удалить прежние задачи: %w", err)
+	}
+
+	if dedupMode && info.ContentSHA256 != "" {
+		_, err := s.db.Exec(`
+			DELETE FROM jobs
+			WHERE content_sha256 = ? AND out_format = ? AND out_params_hash = ? AND id <= ?`,
+			info.ContentSHA256, outFormat, outParamsHash, maxID,
+		)
+		if err != nil {
+			return fmt.Errorf("не удалось удалить прежние задачи по содержимому: %w", err)
+		}
+	}
+	return nil
+}
+
 // FinalizeJobOK помечает задачу как успешно завершённую.
-func (s *Storage) FinalizeJobOK(jobID int64, dstPath string) error {
+// dstSize - размер выходного файла в байтах (0 в dry-run режиме).
+func (s *Storage) FinalizeJobOK(jobID int64, dstPath string, dstSize int64) error {
 	now := time.Now().Unix()
+	if s.enqueue(batchOp{kind: opOK, jobID: jobID, dstPath: dstPath, dstSize: dstSize, finishedAt: now}) {
+		return nil
+	}
 	_, err := s.db.Exec(
-		"UPDATE jobs SET status = ?, dst_path = ?, finished_at = ? WHERE id = ?",
-		StatusOK, dstPath, now, jobID,
+		"UPDATE jobs SET status = ?, dst_path = ?, dst_size = ?, finished_at = ? WHERE id = ?",
+		StatusOK, dstPath, dstSize, now, jobID,
 	)
 	if err != nil {
 		return fmt.Errorf("не удалось обновить статус задачи: %w", err)
@@ -190,6 +449,9 @@ func (s *Storage) FinalizeJobOK(jobID int64, dstPath string) error {
 // FinalizeJobFailed помечает задачу как завершённую с ошибкой.
 func (s *Storage) FinalizeJobFailed(jobID int64, errMsg string) error {
 	now := time.Now().Unix()
+	if s.enqueue(batchOp{kind: opFailed, jobID: jobID, errMsg: errMsg, finishedAt: now}) {
+		return nil
+	}
 	_, err := s.db.Exec(
 		"UPDATE jobs SET status = ?, error = ?, finished_at = ? WHERE id = ?",
 		StatusFailed, errMsg, now, jobID,
@@ -200,6 +462,41 @@ func (s *Storage) FinalizeJobFailed(jobID int64, errMsg string) error {
 	return nil
 }
 
+// SetJobDstPath запоминает путь к выходному файлу начатой задачи,
+// чтобы --resume мог найти результат прерванной конвертации.
+func (s *Storage) SetJobDstPath(jobID int64, dstPath string) error {
+	if s.enqueue(batchOp{kind: opSetDst, jobID: jobID, dstPath: dstPath}) {
+		return nil
+	}
+	_, err := s.db.Exec("UPDATE jobs SET dst_path = ? WHERE id = ?", dstPath, jobID)
+	if err != nil {
+		return fmt.Errorf("не удалось сохранить путь результата: %w", err)
+	}
+	return nil
+}
+
+// DstPathTracked сообщает, записан ли dstPath выходным путём какой-либо
+// задачи, кроме exceptID (например, успешной задачи прежней версии исходника).
+func (s *Storage) DstPathTracked(dstPath string, exceptID int64) (bool, error) {
+	if err := s.Flush(); err != nil {
+		return false, err
+	}
+	var n int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM jobs WHERE dst_path = ? AND id != ?", dstPath, exceptID).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("не удалось проверить выходной путь: %w", err)
+	}
+	return n > 0, nil
+}
+
+// DeleteJob удаляет задачу: файл будет обработан заново как новый.
+func (s *Storage) DeleteJob(jobID int64) error {
+	if _, err := s.db.Exec("DELETE FROM jobs WHERE id = ?", jobID); err != nil {
+		return fmt.Errorf("не удалось удалить задачу: %w", err)
+	}
+	return nil
+}
+
 // UpdateContentSHA256 обновляет sha256 хэш содержимого для задачи.
 func (s *Storage) UpdateContentSHA256(jobID int64, sha256 string) error {
 	_, err := s.db.Exec(
@@ -212,8 +509,133 @@ func (s *Storage) UpdateContentSHA256(jobID int64, sha256 string) error {
 	return nil
 }
 
+// ListJobsForRun возвращает задачи, начатые не раньше since (задачи текущего запуска).
+func (s *Storage) ListJobsForRun(since time.Time) ([]Job, error) {
+	jobs, err := s.queryJobs("WHERE started_at >= ?", since.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить задачи запуска: %w", err)
+	}
+	return jobs, nil
+}
+
+// ListInProgress возвращает задачи, оставшиеся в статусе in_progress
+// (прерванные при предыдущем запуске).
+func (s *Storage) ListInProgress() ([]Job, error) {
+	jobs, err := s.queryJobs("WHERE status = ?", StatusInProgress)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить прерванные задачи: %w", err)
+	}
+	return jobs, nil
+}
+
+// FindJobsBySrc возвращает все задачи исходного файла srcPath
+// (с любыми размером, mtime и параметрами выхода).
+func (s *Storage) FindJobsBySrc(srcPath string) ([]Job, error) {
+	jobs, err := s.queryJobs("WHERE src_path = ?", srcPath)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось найти задачи %s: %w", srcPath, err)
+	}
+	return jobs, nil
+}
+
+// ListJobsByStatus возвращает задачи с указанным статусом.
+func (s *Storage) ListJobsByStatus(status JobStatus) ([]Job, error) {
+	jobs, err := s.queryJobs("WHERE status = ?", status)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить задачи со статусом %s: %w", status, err)
+	}
+	return jobs, nil
+}
+
+// queryJobs читает задачи с условием where, упорядоченные по ID.
+func (s *Storage) queryJobs(where string, args ...any) ([]Job, error) {
+	if err := s.Flush(); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`
+		SELECT id, src_path, src_size, src_mtime, out_format, out_params, out_params_hash,
+		       content_sha256, dst_path, dst_size, status, error, started_at, finished_at
+		FROM jobs
+		`+where+`
+		ORDER BY id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		var (
+			job        Job
+			startedAt  sql.NullInt64
+			finishedAt sql.NullInt64
+		)
+		if err := rows.Scan(
+			&job.ID, &job.SrcPath, &job.SrcSize, &job.SrcMtime, &job.OutFormat, &job.OutParams, &job.OutParamsHash,
+			&job.ContentSHA256, &job.DstPath, &job.DstSize, &job.Status, &job.Error, &startedAt, &finishedAt,
+		); err != nil {
+			return nil, fmt.Errorf("не удалось прочитать задачу: %w", err)
+		}
+		job.StartedAt = unixTimePtr(startedAt)
+		job.FinishedAt = unixTimePtr(finishedAt)
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать задачи: %w", err)
+	}
+	return jobs, nil
+}
+
+// SourcesByDstPath возвращает данные исходных файлов успешных задач
+// по абсолютному пути выходного файла.
+func (s *Storage) SourcesByDstPath() (map[string]FileInfo, error) {
+	if err := s.Flush(); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(
+		"SELECT src_path, src_size, src_mtime, dst_path FROM jobs WHERE status = ? AND dst_path IS NOT NULL",
+		StatusOK,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить исходные файлы: %w", err)
+	}
+	defer rows.Close()
+
+	sources := make(map[string]FileInfo)
+	for rows.Next() {
+		var (
+			info    FileInfo
+			dstPath string
+		)
+		if err := rows.Scan(&info.Path, &info.Size, &info.Mtime, &dstPath); err != nil {
+			return nil, fmt.Errorf("не удалось прочитать задачу: %w", err)
+		}
+		if abs, err := filepath.Abs(dstPath); err == nil {
+			dstPath = abs
+		}
+		sources[dstPath] = info
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать задачи: %w", err)
+	}
+	return sources, nil
+}
+
+// unixTimePtr преобразует nullable unix timestamp во время.
+func unixTimePtr(v sql.NullInt64) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	t := time.Unix(v.Int64, 0)
+	return &t
+}
+
 // GetStats возвращает статистику по задачам.
 func (s *Storage) GetStats() (total, ok, failed, inProgress int64, err error) {
+	if err = s.Flush(); err != nil {
+		return
+	}
 	err = s.db.QueryRow("SELECT COUNT(*) FROM jobs").Scan(&total)
 	if err != nil {
 		return
@@ -224,9 +646,115 @@ func (s *Storage) GetStats() (total, ok, failed, inProgress int64, err error) {
 	return
 }
 
+// StatsByFormat возвращает статистику задач, сгруппированную по выходному формату
+// (в алфавитном порядке форматов).
+func (s *Storage) StatsByFormat() ([]FormatStats, error) {
+	if err := s.Flush(); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`
+		SELECT out_format,
+		       COUNT(*),
+		       COALESCE(SUM(status = ?), 0),
+		       COALESCE(SUM(status = ?), 0),
+		       COALESCE(SUM(status = ?), 0),
+		       COALESCE(SUM(CASE WHEN status = ? THEN src_size END), 0),
+		       COALESCE(SUM(CASE WHEN status = ? THEN dst_size END), 0)
+		FROM jobs
+		GROUP BY out_format
+		ORDER BY out_format
+	`, StatusOK, StatusFailed, StatusInProgress, StatusOK, StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить статистику по форматам: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []FormatStats
+	for rows.Next() {
+		var fs FormatStats
+		if err := rows.Scan(&fs.Format, &fs.Total, &fs.OK, &fs.Failed, &fs.InProgress, &fs.SrcBytes, &fs.DstBytes); err != nil {
+			return nil, fmt.Errorf("не удалось прочитать статистику по форматам: %w", err)
+		}
+		stats = append(stats, fs)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать статистику по форматам: %w", err)
+	}
+	return stats, nil
+}
+
+// StatsByDir возвращает размеры успешных задач, сгруппированные по первому
+// компоненту пути исходника относительно root (в алфавитном порядке).
+// root - префикс путей, например "<вход>/" или "<архив>!/"; задачи вне него
+// не учитываются. Пустой root группирует по родительской директории файла.
+func (s *Storage) StatsByDir(root string) ([]DirStats, error) {
+	if err := s.Flush(); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(
+		"SELECT src_path, src_size, COALESCE(dst_size, 0) FROM jobs WHERE status = ?", StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить статистику по директориям: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make(map[string]*DirStats)
+	for rows.Next() {
+		var (
+			srcPath          string
+			srcSize, dstSize int64
+		)
+		if err := rows.Scan(&srcPath, &srcSize, &dstSize); err != nil {
+			return nil, fmt.Errorf("не удалось прочитать статистику по директориям: %w", err)
+		}
+
+		dir, ok := topDir(srcPath, root)
+		if !ok {
+			continue
+		}
+		g := groups[dir]
+		if g == nil {
+			g = &DirStats{Dir: dir}
+			groups[dir] = g
+		}
+		g.Files++
+		g.SrcBytes += srcSize
+		g.DstBytes += dstSize
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать статистику по директориям: %w", err)
+	}
+
+	stats := make([]DirStats, 0, len(groups))
+	for _, g := range groups {
+		stats = append(stats, *g)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Dir < stats[j].Dir })
+	return stats, nil
+}
+
+// topDir возвращает группу исходника для StatsByDir и false, если путь вне root.
+func topDir(srcPath, root string) (string, bool) {
+	if root == "" {
+		return filepath.Dir(srcPath), true
+	}
+	if !strings.HasPrefix(srcPath, root) {
+		return "", false
+	}
+	// Пути внутри архивов разделяются "/" на любой ОС
+	rel := strings.TrimLeft(strings.TrimPrefix(srcPath, root), "/"+string(filepath.Separator))
+	if i := strings.IndexAny(rel, "/"+string(filepath.Separator)); i >= 0 {
+		return rel[:i], true
+	}
+	return ".", true
+}
+
 // CleanupInProgress сбрасывает задачи со статусом in_progress в failed.
 // Вызывается при старте для очистки после аварийного завершения.
 func (s *Storage) CleanupInProgress() (int64, error) {
+	if err := s.Flush(); err != nil {
+		return 0, err
+	}
 	result, err := s.db.Exec(
 		"UPDATE jobs SET status = ?, error = ? WHERE status = ?",
 		StatusFailed, "прервано при предыдущем запуске", StatusInProgress,
@@ -265,5 +793,4 @@ func containsHelper(s, substr string) bool {
 Возможные расширения:
 - Добавить метод для экспорта статистики в JSON
 - Добавить метод для очистки старых записей
-- Добавить поддержку транзакций для batch-операций
 */
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestStatsByFormat(t *testing.T) {
+	s, _ := newTestStorage(t)
+	defer func() { _ = s.Close() }()
+
+	start := func(name, format string, size int64) int64 {
+		info := FileInfo{Path: "/src/" + name, Size: size, Mtime: 1}
+		res, err := s.TryStartJob(info, format, "{}", "h", false)
+		if err != nil || !res.Started {
+			t.Fatalf("TryStartJob(%s, %s): %+v, %v", name, format, res, err)
+		}
+		return res.JobID
+	}
+
+	for i := 0; i < 3; i++ {
+		name := fmt.Sprintf("%d.jpg", i)
+		_ = s.FinalizeJobOK(start(name, "webp", 1000), "/out/"+name+".webp", 200)
+		if i < 2 {
+			_ = s.FinalizeJobOK(start(name, "avif", 1000), "/out/"+name+".avif", 100)
+		}
+	}
+	_ = s.FinalizeJobFailed(start("bad.jpg", "avif", 500), "boom")
+	start("slow.jpg", "webp", 700)
+
+	stats, err := s.StatsByFormat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FormatStats{
+		{Format: "avif", Total: 3, OK: 2, Failed: 1, SrcBytes: 2000, DstBytes: 200},
+		{Format: "webp", Total: 4, OK: 3, InProgress: 1, SrcBytes: 3000, DstBytes: 600},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("получено %+v, want %+v", stats, want)
+	}
+	for i := range want {
+		if stats[i] != want[i] {
+			t.Errorf("формат %d: %+v, want %+v", i, stats[i], want[i])
+		}
+	}
+}
+
+func TestStatsByDir(t *testing.T) {
+	s, _ := newTestStorage(t)
+	defer func() { _ = s.Close() }()
+
+	add := func(path string, src, dst int64) {
+		info := FileInfo{Path: path, Size: src, Mtime: 1}
+		res, err := s.TryStartJob(info, "webp", "{}", "h", false)
+		if err != nil || !res.Started {
+			t.Fatalf("TryStartJob(%s): %+v, %v", path, res, err)
+		}
+		_ = s.FinalizeJobOK(res.JobID, path+".webp", dst)
+	}
+
+	add("/lib/a/1.jpg", 1000, 300)
+	add("/lib/a/sub/2.jpg", 1000, 200)
+	add("/lib/b/3.jpg", 2000, 1000)
+	add("/lib/root.jpg", 400, 100)
+	add("/other/4.jpg", 5000, 1)
+
+	stats, err := s.StatsByDir("/lib/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DirStats{
+		{Dir: ".", Files: 1, SrcBytes: 400, DstBytes: 100},
+		{Dir: "a", Files: 2, SrcBytes: 2000, DstBytes: 500},
+		{Dir: "b", Files: 1, SrcBytes: 2000, DstBytes: 1000},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("получено %+v, want %+v", stats, want)
+	}
+	for i := range want {
+		if stats[i] != want[i] {
+			t.Errorf("директория %d: %+v, want %+v", i, stats[i], want[i])
+		}
+	}
+	if got := stats[1].SavedBytes(); got != 1500 {
+		t.Errorf("SavedBytes = %d, want 1500", got)
+	}
+	if got := stats[2].SavedPercent(); got != 50 {
+		t.Errorf("SavedPercent = %v, want 50", got)
+	}
+}
+
+func TestIgnoredSources(t *testing.T) {
+	s, _ := newTestStorage(t)
+	defer func() { _ = s.Close() }()
+
+	added, err := s.IgnoreSources([]string{"/src/a.jpg", "/src/b.jpg", "/src/a.jpg"})
+	if err != nil || added != 2 {
+		t.Fatalf("IgnoreSources = %d, %v; want 2", added, err)
+	}
+	if added, err = s.IgnoreSources([]string{"/src/b.jpg"}); err != nil || added != 0 {
+		t.Errorf("повторная пометка = %d, %v; want 0", added, err)
+	}
+	ignored, err := s.IgnoredSources()
+	if err != nil || len(ignored) != 2 || !ignored["/src/a.jpg"] || !ignored["/src/b.jpg"] {
+		t.Errorf("IgnoredSources = %v, %v", ignored, err)
+	}
+
+	if cleared, err := s.ClearIgnored(); err != nil || cleared != 2 {
+		t.Errorf("ClearIgnored = %d, %v; want 2", cleared, err)
+	}
+	if ignored, _ := s.IgnoredSources(); len(ignored) != 0 {
+		t.Errorf("после ClearIgnored: %v", ignored)
+	}
+}
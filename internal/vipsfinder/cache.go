@@ -0,0 +1,107 @@
+// Package vipsfinder содержит кэш результатов проверки vips.
+package vipsfinder
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// cacheEntry - закэшированный результат проверки одного бинарника vips.
+// Запись действительна, пока не изменились размер и время модификации бинарника.
+type cacheEntry struct {
+	Size    int64    `json:"size"`
+	ModTime int64    `json:"mod_time"`
+	Info    VipsInfo `json:"info"`
+}
+
+// DefaultCachePath возвращает путь к кэшу по умолчанию
+// (~/.cache/photoconverter/vips.json на Linux).
+func DefaultCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "photoconverter", "vips.json")
+}
+
+// loadCached возвращает закэшированную информацию о vips, если бинарник не менялся.
+func (f *Finder) loadCached(absPath string) (*VipsInfo, bool) {
+	if f.CachePath == "" {
+		return nil, false
+	}
+
+	st, err := os.Stat(absPath)
+	if err != nil {
+		return nil, false
+	}
+
+	entries := f.readCache()
+	entry, ok := entries[absPath]
+	if !ok || entry.Size != st.Size() || entry.ModTime != st.ModTime().UnixNano() {
+		return nil, false
+	}
+	// Запись без списка форматов - неудачная проверка "vips -l", повторяем её
+	if len(entry.Info.SaveFormats) == 0 {
+		return nil, false
+	}
+
+	info := entry.Info
+	return &info, true
+}
+
+// saveCached сохраняет информацию о vips в кэш. Ошибки записи игнорируются:
+// кэш только ускоряет запуск.
+func (f *Finder) saveCached(info *VipsInfo) {
+	if f.CachePath == "" {
+		return
+	}
+
+	st, err := os.Stat(info.Path)
+	if err != nil {
+		return
+	}
+
+	entries := f.readCache()
+	entries[info.Path] = cacheEntry{
+		Size:    st.Size(),
+		ModTime: st.ModTime().UnixNano(),
+		Info:    *info,
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(f.CachePath), 0755); err != nil {
+		return
+	}
+
+	tmpPath := f.CachePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return
+	}
+	if err := os.Rename(tmpPath, f.CachePath); err != nil {
+		_ = os.Remove(tmpPath)
+	}
+}
+
+// readCache читает файл кэша. Повреждённый или отсутствующий кэш считается пустым.
+func (f *Finder) readCache() map[string]cacheEntry {
+	entries := make(map[string]cacheEntry)
+
+	data, err := os.ReadFile(f.CachePath)
+	if err != nil {
+		return entries
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return make(map[string]cacheEntry)
+	}
+	return entries
+}
+
+/*
+Возможные расширения:
+- Добавить флаг для сброса кэша
+- Хранить кэш рядом с БД для портативных установок
+*/
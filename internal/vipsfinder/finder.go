@@ -2,6 +2,7 @@
 package vipsfinder
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -13,10 +14,23 @@ import (
 // VipsInfo содержит информацию о найденном vips.
 type VipsInfo struct {
 	// Path - абсолютный путь к бинарнику vips.
-	Path string
+	Path string `json:"path"`
 
 	// Version - версия vips (например, "8.14.2").
-	Version string
+	Version string `json:"version"`
+
+	// SaveFormats - форматы, в которые умеет сохранять vips (из "vips -l").
+	// Пусто, если список получить не удалось.
+	SaveFormats []SaveFormat `json:"save_formats,omitempty"`
+}
+
+// SaveFormat описывает класс сохранения vips.
+type SaveFormat struct {
+	// Name - имя операции без суффикса "save" (например, "jpeg", "heif").
+	Name string `json:"name"`
+
+	// Suffixes - расширения файлов без точки (например, "jpg", "jpeg").
+	Suffixes []string `json:"suffixes"`
 }
 
 // Finder ищет бинарник vips.
@@ -26,6 +40,26 @@ type Finder struct {
 
 	// EnvVar - имя переменной окружения для пути к vips.
 	EnvVar string
+
+	// MinVersion - минимальная требуемая версия vips (например, "8.11").
+	// Пусто = без ограничения.
+	MinVersion string
+
+	// CachePath - файл кэша результатов проверки vips (пусто = без кэша).
+	CachePath string
+}
+
+// formatMinVersions - минимальные версии libvips для сохранения в формат.
+var formatMinVersions = map[string]string{
+	"heic": "8.8",
+	"avif": "8.9",
+	"jxl":  "8.11",
+}
+
+// MinVersionForFormat возвращает минимальную версию vips для выходного формата.
+// Пустая строка означает, что формат поддерживается любой версией.
+func MinVersionForFormat(format string) string {
+	return formatMinVersions[strings.ToLower(format)]
 }
 
 // NewFinder создаёт новый Finder.
@@ -33,6 +67,7 @@ func NewFinder(customPath string) *Finder {
 	return &Finder{
 		CustomPath: customPath,
 		EnvVar:     "PHOTOCONVERTER_VIPS",
+		CachePath:  DefaultCachePath(),
 	}
 }
 
@@ -74,10 +109,21 @@ func (f *Finder) Find() (*VipsInfo, error) {
 	}
 
 	// Проверяем каждого кандидата
+	var versionErr error
 	for _, path := range candidates {
-		if info, err := f.checkVips(path); err == nil {
+		info, err := f.checkVips(path)
+		if err == nil {
 			return info, nil
 		}
+		var tooOld *VersionError
+		if versionErr == nil && errors.As(err, &tooOld) {
+			versionErr = err
+		}
+	}
+
+	// Найден только vips устаревшей версии - сообщаем об этом явно
+	if versionErr != nil {
+		return nil, versionErr
 	}
 
 	return nil, fmt.Errorf("vips не найден. Проверьте:\n"+
@@ -100,38 +146,122 @@ func (f *Finder) checkVips(path string) (*VipsInfo, error) {
 		return nil, fmt.Errorf("не удалось получить абсолютный путь: %w", err)
 	}
 
-	// Пробуем получить версию
-	cmd := exec.Command(absPath, "--version")
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("не удалось выполнить vips --version: %w", err)
+	// Используем кэш, если бинарник не менялся
+	info, cached := f.loadCached(absPath)
+	if !cached {
+		// Пробуем получить версию
+		cmd := exec.Command(absPath, "--version")
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("не удалось выполнить vips --version: %w", err)
+		}
+
+		info = &VipsInfo{
+			Path:    absPath,
+			Version: parseVersion(string(output)),
+		}
+
+		// Список форматов нужен для предварительной проверки; ошибка не критична,
+		// но такой результат не кэшируется, чтобы следующий запуск повторил проверку
+		if _, err := info.GetSupportedFormats(); err == nil {
+			f.saveCached(info)
+		}
 	}
 
-	version := parseVersion(string(output))
+	// Проверяем минимальную версию
+	if f.MinVersion != "" && CompareVersions(info.Version, f.MinVersion) < 0 {
+		return nil, &VersionError{Path: absPath, Found: info.Version, Required: f.MinVersion}
+	}
 
-	return &VipsInfo{
-		Path:    absPath,
-		Version: version,
-	}, nil
+	return info, nil
 }
 
 // parseVersion извлекает версию из вывода "vips --version".
-// Пример вывода: "vips-8.14.2"
+// Пример вывода: "vips-8.14.2" или "vips-8.15.1-Tue Dec  5 12:00:00 UTC 2023"
 func parseVersion(output string) string {
 	output = strings.TrimSpace(output)
 
 	// Формат: "vips-8.14.2" или "vips 8.14.2"
 	if strings.HasPrefix(output, "vips-") {
-		return strings.TrimPrefix(output, "vips-")
-	}
-	if strings.HasPrefix(output, "vips ") {
-		return strings.TrimPrefix(output, "vips ")
+		output = strings.TrimPrefix(output, "vips-")
+	} else if strings.HasPrefix(output, "vips ") {
+		output = strings.TrimPrefix(output, "vips ")
+	} else {
+		// Возвращаем как есть
+		return output
 	}
 
-	// Возвращаем как есть
+	// Отбрасываем дату сборки и прочие суффиксы после версии
+	if i := strings.IndexAny(output, " \t\n"); i >= 0 {
+		output = output[:i]
+	}
+	if i := strings.Index(output, "-"); i >= 0 {
+		output = output[:i]
+	}
 	return output
 }
 
+// VersionError - найденный vips старше требуемой версии.
+type VersionError struct {
+	// Path - путь к найденному vips.
+	Path string
+
+	// Found - найденная версия.
+	Found string
+
+	// Required - минимальная требуемая версия.
+	Required string
+}
+
+// Error реализует интерфейс error.
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("vips %s (%s) слишком старый: требуется версия >= %s", e.Found, e.Path, e.Required)
+}
+
+// CompareVersions сравнивает версии vips покомпонентно как числа.
+// Возвращает -1, если a < b; 0, если a == b; 1, если a > b.
+// Недостающие компоненты считаются нулями ("8.9" == "8.9.0"),
+// нечисловые суффиксы компонентов игнорируются ("8.14.2rc1" == "8.14.2").
+func CompareVersions(a, b string) int {
+	pa := versionParts(a)
+	pb := versionParts(b)
+
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionParts разбирает версию на числовые компоненты.
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+
+	var parts []int
+	for _, p := range strings.Split(v, ".") {
+		n := 0
+		for _, c := range p {
+			if c < '0' || c > '9' {
+				break
+			}
+			n = n*10 + int(c-'0')
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
 // vipsBinaryName возвращает имя бинарника vips для текущей ОС.
 func vipsBinaryName() string {
 	if runtime.GOOS == "windows" {
@@ -140,35 +270,113 @@ func vipsBinaryName() string {
 	return "vips"
 }
 
-// GetSupportedFormats возвращает список поддерживаемых форматов vips.
+// GetSupportedFormats возвращает имена форматов, в которые умеет сохранять vips
+// (например, "jpeg", "webp", "heif"). Результат запоминается в SaveFormats.
 func (v *VipsInfo) GetSupportedFormats() ([]string, error) {
-	cmd := exec.Command(v.Path, "list", "classes")
-	output, err := cmd.Output()
-	if err != nil {
-		// Fallback на стандартный список
-		return []string{"jpeg", "png", "webp", "tiff", "heif", "avif"}, nil
+	if len(v.SaveFormats) == 0 {
+		cmd := exec.Command(v.Path, "-l")
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("не удалось выполнить vips -l: %w", err)
+		}
+
+		v.SaveFormats = parseSaveFormats(string(output))
+		if len(v.SaveFormats) == 0 {
+			return nil, fmt.Errorf("vips -l не вернул ни одного формата сохранения")
+		}
 	}
 
-	var formats []string
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		// Ищем форматы для сохранения
-		if strings.Contains(line, "save") {
-			formats = append(formats, line)
+	names := make([]string, 0, len(v.SaveFormats))
+	for _, sf := range v.SaveFormats {
+		names = append(names, sf.Name)
+	}
+	return names, nil
+}
+
+// SupportsFormat проверяет, умеет ли vips сохранять файлы с указанным расширением
+// (например, "jxl" или "avif"). Возвращает false, если список форматов неизвестен.
+func (v *VipsInfo) SupportsFormat(ext string) bool {
+	ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+	for _, sf := range v.SaveFormats {
+		for _, suffix := range sf.Suffixes {
+			if suffix == ext {
+				return true
+			}
 		}
 	}
+	return false
+}
+
+// CheckFormat проверяет поддержку выходного формата до начала конвертации.
+// Если список форматов получить не удалось, проверка пропускается.
+func (v *VipsInfo) CheckFormat(ext string) error {
+	if len(v.SaveFormats) == 0 || v.SupportsFormat(ext) {
+		return nil
+	}
+
+	var available []string
+	for _, sf := range v.SaveFormats {
+		available = append(available, sf.Suffixes...)
+	}
+	return fmt.Errorf("ваша libvips (%s, версия %s) собрана без поддержки %s; доступные форматы: %s",
+		v.Path, v.Version, ext, strings.Join(available, ", "))
+}
+
+// parseSaveFormats разбирает вывод "vips -l" и извлекает классы сохранения в файл.
+// Пример строки:
+//
+//	VipsForeignSaveJpegFile (jpegsave), save image to jpeg file (.jpg, .jpeg, .jpe), priority=0, ...
+//
+// Варианты _buffer, _target и _mime пропускаются - у них те же расширения.
+func parseSaveFormats(output string) []SaveFormat {
+	var formats []SaveFormat
+	seen := make(map[string]bool)
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+
+		// Имя операции в первых скобках: "(jpegsave)"
+		open := strings.Index(line, "(")
+		closing := strings.Index(line, ")")
+		if open < 0 || closing < open {
+			continue
+		}
+		op := line[open+1 : closing]
+		if !strings.HasSuffix(op, "save") {
+			continue
+		}
+		name := strings.TrimSuffix(op, "save")
+		if name == "" || seen[name] {
+			continue
+		}
+
+		// Расширения во вторых скобках: "(.jpg, .jpeg, .jpe)"
+		rest := line[closing+1:]
+		open = strings.Index(rest, "(")
+		closing = strings.Index(rest, ")")
+		if open < 0 || closing < open {
+			continue
+		}
+
+		var suffixes []string
+		for _, part := range strings.Split(rest[open+1:closing], ",") {
+			part = strings.TrimSpace(part)
+			if strings.HasPrefix(part, ".") {
+				suffixes = append(suffixes, strings.ToLower(strings.TrimPrefix(part, ".")))
+			}
+		}
+		if len(suffixes) == 0 {
+			continue
+		}
 
-	if len(formats) == 0 {
-		return []string{"jpeg", "png", "webp", "tiff", "heif", "avif"}, nil
+		seen[name] = true
+		formats = append(formats, SaveFormat{Name: name, Suffixes: suffixes})
 	}
 
-	return formats, nil
+	return formats
 }
 
 /*
 Возможные расширения:
-- Кэширование результата поиска
-- Проверка минимальной версии vips
 - Автоматическое скачивание portable vips
 */
@@ -0,0 +1,261 @@
+package vipsfinder
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeVipsList - вывод "vips -l" для vips, собранного без heif и jxl.
+const fakeVipsList = `VipsOperation (operation), operations
+  VipsForeign (foreign), load and save images and vips
+    VipsForeignLoad (foreignload), file loaders, priority=0
+      VipsForeignLoadJpegFile (jpegload), load jpeg from file (.jpg, .jpeg, .jpe), priority=50
+    VipsForeignSave (foreignsave), file savers
+      VipsForeignSaveJpeg (jpegsave_base), save jpeg, priority=0
+        VipsForeignSaveJpegFile (jpegsave), save image to jpeg file (.jpg, .jpeg, .jpe, .jfif), priority=0, mono rgb cmyk
+        VipsForeignSaveJpegBuffer (jpegsave_buffer), save image to jpeg buffer (.jpg, .jpeg, .jpe, .jfif), priority=0, mono rgb cmyk
+      VipsForeignSavePng (pngsave_base), save png, priority=0
+        VipsForeignSaveSpngFile (pngsave), save image to file as PNG (.png), priority=0, mono rgb alpha
+      VipsForeignSaveWebp (webpsave_base), save as WebP (.webp), priority=0, rgb alpha
+        VipsForeignSaveWebpFile (webpsave), save as WebP (.webp), priority=0, rgb alpha
+      VipsForeignSaveTiff (tiffsave_base), save image as tiff (.tif, .tiff), priority=0, any
+        VipsForeignSaveTiffFile (tiffsave), save image to tiff file (.tif, .tiff), priority=0, any
+`
+
+// writeFakeVips создаёт скрипт-заглушку vips, печатающий указанную версию
+// и список форматов fakeVipsList. Каждый запуск записывается в calls.log.
+// Скрипт использует только встроенные команды shell: тесты подменяют PATH.
+func writeFakeVips(t *testing.T, versionOutput string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vips")
+	script := "#!/bin/sh\n" +
+		"echo \"$1\" >> '" + filepath.Join(dir, "calls.log") + "'\n" +
+		"case \"$1\" in\n" +
+		"-l) echo '" + fakeVipsList + "' ;;\n" +
+		"*) echo '" + versionOutput + "' ;;\n" +
+		"esac\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("не удалось создать fake vips: %v", err)
+	}
+	return path
+}
+
+// vipsCalls возвращает количество запусков fake vips.
+func vipsCalls(t *testing.T, vipsPath string) int {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(vipsPath), "calls.log"))
+	if err != nil {
+		return 0
+	}
+	return strings.Count(string(data), "\n")
+}
+
+// newTestFinder создаёт Finder с кэшем во временной директории.
+func newTestFinder(t *testing.T, vipsPath string) *Finder {
+	t.Helper()
+	t.Setenv("PHOTOCONVERTER_VIPS", "")
+	t.Setenv("PATH", t.TempDir())
+
+	f := NewFinder(vipsPath)
+	f.CachePath = filepath.Join(t.TempDir(), "vips.json")
+	return f
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"8.14.2", "8.9", 1},
+		{"8.9", "8.14.2", -1},
+		{"8.9", "8.9.0", 0},
+		{"8.9.0", "8.9", 0},
+		{"8.11", "8.11.0", 0},
+		{"8.10.6", "8.11", -1},
+		{"8.15.1", "8.15.0", 1},
+		{"9.0", "8.99.99", 1},
+		{"8.14.2rc1", "8.14.2", 0},
+		{"v8.12", "8.12", 0},
+		{"", "8.0", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"_vs_"+tt.b, func(t *testing.T) {
+			if got := CompareVersions(tt.a, tt.b); got != tt.want {
+				t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"vips-8.14.2", "8.14.2"},
+		{"vips-8.14.2\n", "8.14.2"},
+		{"vips 8.9.1", "8.9.1"},
+		{"vips-8.15.1-Tue Dec  5 12:00:00 UTC 2023", "8.15.1"},
+		{"8.12.0", "8.12.0"},
+	}
+
+	for _, tt := range tests {
+		if got := parseVersion(tt.output); got != tt.want {
+			t.Errorf("parseVersion(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestMinVersionForFormat(t *testing.T) {
+	if got := MinVersionForFormat("jxl"); got != "8.11" {
+		t.Errorf("MinVersionForFormat(jxl) = %q, want 8.11", got)
+	}
+	if got := MinVersionForFormat("jpg"); got != "" {
+		t.Errorf("MinVersionForFormat(jpg) = %q, want пусто", got)
+	}
+}
+
+func TestFind_MinVersion(t *testing.T) {
+	vips := writeFakeVips(t, "vips-8.10.6")
+
+	f := newTestFinder(t, vips)
+	f.MinVersion = "8.11"
+	_, err := f.Find()
+
+	var verr *VersionError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Find() error = %v, want VersionError", err)
+	}
+	if verr.Found != "8.10.6" || verr.Required != "8.11" {
+		t.Errorf("VersionError = %+v", verr)
+	}
+
+	f.MinVersion = "8.10"
+	info, err := f.Find()
+	if err != nil {
+		t.Fatalf("Find() с подходящей версией: %v", err)
+	}
+	if info.Version != "8.10.6" {
+		t.Errorf("Version = %q, want 8.10.6", info.Version)
+	}
+}
+
+func TestParseSaveFormats(t *testing.T) {
+	formats := parseSaveFormats(fakeVipsList)
+
+	var names []string
+	for _, f := range formats {
+		names = append(names, f.Name)
+	}
+	if got := strings.Join(names, ","); got != "jpeg,png,webp,tiff" {
+		t.Errorf("форматы = %s, want jpeg,png,webp,tiff", got)
+	}
+	if got := strings.Join(formats[0].Suffixes, ","); got != "jpg,jpeg,jpe,jfif" {
+		t.Errorf("расширения jpeg = %s", got)
+	}
+}
+
+func TestFind_SupportedFormats(t *testing.T) {
+	vips := writeFakeVips(t, "vips-8.15.1")
+	info, err := newTestFinder(t, vips).Find()
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+
+	for _, ext := range []string{"jpg", "png", "webp", "tiff"} {
+		if err := info.CheckFormat(ext); err != nil {
+			t.Errorf("CheckFormat(%s): %v", ext, err)
+		}
+	}
+
+	err = info.CheckFormat("jxl")
+	if err == nil {
+		t.Fatal("CheckFormat(jxl) должен вернуть ошибку для vips без jxl")
+	}
+	if !strings.Contains(err.Error(), "без поддержки jxl") {
+		t.Errorf("ошибка не объясняет причину: %v", err)
+	}
+	if info.SupportsFormat("avif") || info.SupportsFormat("heic") {
+		t.Error("avif/heic не должны поддерживаться")
+	}
+}
+
+func TestFind_UsesCache(t *testing.T) {
+	vips := writeFakeVips(t, "vips-8.15.1")
+	f := newTestFinder(t, vips)
+
+	first, err := f.Find()
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	calls := vipsCalls(t, vips)
+	if calls != 2 {
+		t.Fatalf("первый поиск: %d запусков vips, want 2 (--version и -l)", calls)
+	}
+
+	second, err := f.Find()
+	if err != nil {
+		t.Fatalf("Find из кэша: %v", err)
+	}
+	if got := vipsCalls(t, vips); got != calls {
+		t.Errorf("повторный поиск запускал vips (%d запусков)", got-calls)
+	}
+	if second.Version != first.Version || len(second.SaveFormats) != len(first.SaveFormats) {
+		t.Errorf("кэш вернул %+v, want %+v", second, first)
+	}
+
+	// Изменение бинарника инвалидирует кэш
+	if err := os.WriteFile(vips, []byte("#!/bin/sh\necho vips-8.16.0\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	third, err := f.Find()
+	if err != nil {
+		t.Fatalf("Find после обновления: %v", err)
+	}
+	if third.Version != "8.16.0" {
+		t.Errorf("Version после обновления vips = %q, want 8.16.0", third.Version)
+	}
+}
+
+func TestFind_FailedFormatProbeNotCached(t *testing.T) {
+	dir := t.TempDir()
+	vips := filepath.Join(dir, "vips")
+	script := "#!/bin/sh\n" +
+		"echo \"$1\" >> '" + filepath.Join(dir, "calls.log") + "'\n" +
+		"case \"$1\" in\n" +
+		"-l) exit 1 ;;\n" +
+		"*) echo 'vips-8.15.1' ;;\n" +
+		"esac\n"
+	if err := os.WriteFile(vips, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f := newTestFinder(t, vips)
+
+	// Сбой "vips -l" не попадает в кэш: каждый поиск повторяет проверку
+	for i := 1; i <= 2; i++ {
+		info, err := f.Find()
+		if err != nil {
+			t.Fatalf("Find: %v", err)
+		}
+		if len(info.SaveFormats) != 0 {
+			t.Errorf("SaveFormats = %v, want пусто", info.SaveFormats)
+		}
+		if got := vipsCalls(t, vips); got != 2*i {
+			t.Errorf("поиск %d: %d запусков vips, want %d", i, got, 2*i)
+		}
+	}
+	if _, err := os.Stat(f.CachePath); err == nil {
+		t.Error("кэш записан после сбоя vips -l")
+	}
+
+	// Запись без форматов из прежнего кэша не используется
+	f.saveCached(&VipsInfo{Path: vips, Version: "8.15.1"})
+	if _, cached := f.loadCached(vips); cached {
+		t.Error("запись кэша без форматов использована")
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 
@@ -25,13 +24,55 @@ type Watcher struct {
 	// watcher - fsnotify watcher.
 	watcher *fsnotify.Watcher
 
+	// filter - include/exclude шаблоны (общие со сканером).
+	filter *scanner.Filter
+
 	// debounceTime - время ожидания перед обработкой файла.
 	// Нужно для того, чтобы файл успел полностью записаться.
 	debounceTime time.Duration
 
 	// pending - файлы, ожидающие обработки (для debounce).
-	pending map[string]time.Time
-	mu      sync.Mutex
+	pending map[string]*pendingFile
+
+	// emitted - уже отправленные файлы по пути и mtime: размер и время отправки.
+	// Нужно, чтобы файл из начального сканирования не был обработан повторно
+	// по событию Create/Write, если он не изменился. Записи старше emittedTTL
+	// удаляются (evictEmittedLocked).
+	emitted map[emittedKey]emittedFile
+
+	// evictedAt - время последней очистки emitted.
+	evictedAt time.Time
+
+	mu sync.Mutex
+}
+
+// DefaultDebounceTime - время debounce по умолчанию.
+const DefaultDebounceTime = 500 * time.Millisecond
+
+// emittedTTL - сколько помнить отправленный файл. События по файлам начального
+// сканирования приходят вскоре после него; более поздний повтор неизменённого
+// файла пропускает пул по БД.
+const emittedTTL = time.Minute
+
+// emittedKey - ключ отправленного файла: путь и mtime.
+type emittedKey struct {
+	path  string
+	mtime int64
+}
+
+// emittedFile - отправленный файл: размер и время отправки.
+type emittedFile struct {
+	size int64
+	at   time.Time
+}
+
+// pendingFile - файл, ожидающий окончания записи.
+type pendingFile struct {
+	// changedAt - время последнего события или изменения размера.
+	changedAt time.Time
+
+	// size - размер при последней проверке (-1 = ещё не проверялся).
+	size int64
 }
 
 // New создаёт новый Watcher.
@@ -41,11 +82,18 @@ func New(cfg *config.Config) (*Watcher, error) {
 		return nil, fmt.Errorf("не удалось создать watcher: %w", err)
 	}
 
+	debounce := cfg.WatchDebounce
+	if debounce <= 0 {
+		debounce = DefaultDebounceTime
+	}
+
 	return &Watcher{
 		cfg:          cfg,
 		watcher:      w,
-		debounceTime: 500 * time.Millisecond,
-		pending:      make(map[string]time.Time),
+		filter:       scanner.NewConfigFilter(cfg),
+		debounceTime: debounce,
+		pending:      make(map[string]*pendingFile),
+		emitted:      make(map[emittedKey]emittedFile),
 	}, nil
 }
 
@@ -55,23 +103,106 @@ func (w *Watcher) SetDebounceTime(d time.Duration) {
 }
 
 // Watch запускает слежение за директорией и возвращает канал с файлами.
+// Если включено начальное сканирование, сначала отправляются файлы,
+// уже находящиеся в директории. Канал закрывается после отмены контекста.
 func (w *Watcher) Watch(ctx context.Context) (<-chan scanner.File, error) {
-	// Добавляем директорию и все поддиректории
+	// Добавляем директорию и все поддиректории до начального сканирования,
+	// чтобы не пропустить файлы, появившиеся во время сканирования
 	if err := w.addRecursive(w.cfg.InputDir); err != nil {
 		return nil, err
 	}
 
 	files := make(chan scanner.File, 100)
 
+	var wg sync.WaitGroup
+
+	// Начальное сканирование существующих файлов
+	if w.cfg.WatchInitialScan {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.initialScan(ctx, files)
+		}()
+	}
+
 	// Горутина для обработки событий
-	go w.processEvents(ctx, files)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.processEvents(ctx)
+	}()
 
 	// Горутина для debounce
-	go w.processPending(ctx, files)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.processPending(ctx, files)
+	}()
+
+	// Закрываем канал, когда все отправители завершились
+	go func() {
+		wg.Wait()
+		close(files)
+	}()
 
 	return files, nil
 }
 
+// initialScan отправляет файлы, уже находящиеся во входной директории.
+func (w *Watcher) initialScan(ctx context.Context, files chan<- scanner.File) {
+	scanned, errs := scanner.New(w.cfg).Scan(ctx)
+	for file := range scanned {
+		if w.isEmitted(file.Info) {
+			continue
+		}
+		select {
+		case files <- file:
+			w.markEmitted(file.Info)
+		case <-ctx.Done():
+			return
+		}
+	}
+	if err := <-errs; err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "Ошибка начального сканирования: %v\n", err)
+	}
+}
+
+// isEmitted сообщает, что файл с тем же размером и mtime уже был отправлен.
+func (w *Watcher) isEmitted(info storage.FileInfo) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.isEmittedLocked(info)
+}
+
+// isEmittedLocked - версия isEmitted для вызова под w.mu.
+func (w *Watcher) isEmittedLocked(info storage.FileInfo) bool {
+	prev, ok := w.emitted[emittedKey{path: info.Path, mtime: info.Mtime}]
+	return ok && prev.size == info.Size
+}
+
+// markEmitted отмечает файл как отправленный. Вызывается после отправки
+// в канал: файл, не отправленный из-за отмены контекста, не отмечается.
+func (w *Watcher) markEmitted(info storage.FileInfo) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.emitted[emittedKey{path: info.Path, mtime: info.Mtime}] = emittedFile{size: info.Size, at: time.Now()}
+}
+
+// evictEmittedLocked удаляет записи emitted старше emittedTTL, чтобы при долгом
+// слежении карта не росла с каждым новым файлом. Карта обходится не чаще
+// раза в emittedTTL. Вызывается под w.mu.
+func (w *Watcher) evictEmittedLocked(now time.Time) {
+	if now.Sub(w.evictedAt) < emittedTTL {
+		return
+	}
+	w.evictedAt = now
+	for key, f := range w.emitted {
+		if now.Sub(f.at) >= emittedTTL {
+			delete(w.emitted, key)
+		}
+	}
+}
+
 // addRecursive добавляет директорию и все поддиректории в watcher.
 func (w *Watcher) addRecursive(dir string) error {
 	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
@@ -88,8 +219,7 @@ func (w *Watcher) addRecursive(dir string) error {
 }
 
 // processEvents обрабатывает события от fsnotify.
-func (w *Watcher) processEvents(ctx context.Context, files chan<- scanner.File) {
-	defer close(files)
+func (w *Watcher) processEvents(ctx context.Context) {
 	defer w.watcher.Close()
 
 	for {
@@ -121,15 +251,28 @@ func (w *Watcher) processEvents(ctx context.Context, files chan<- scanner.File)
 				continue
 			}
 
-			// Проверяем расширение
-			ext := strings.TrimPrefix(filepath.Ext(event.Name), ".")
-			if !w.cfg.HasInputExtension(ext) {
+			// Проверяем расширение (или содержимое с --detect-by-content)
+			if !scanner.Accepts(w.cfg, event.Name) {
+				continue
+			}
+
+			path := event.Name
+			if absPath, err := filepath.Abs(path); err == nil {
+				path = absPath
+			}
+
+			// Проверяем include/exclude шаблоны
+			if !w.matches(path) {
 				continue
 			}
 
 			// Добавляем в pending для debounce
 			w.mu.Lock()
-			w.pending[event.Name] = time.Now()
+			if p, ok := w.pending[path]; ok {
+				p.changedAt = time.Now()
+			} else {
+				w.pending[path] = &pendingFile{changedAt: time.Now(), size: -1}
+			}
 			w.mu.Unlock()
 
 		case err, ok := <-w.watcher.Errors:
@@ -151,46 +294,100 @@ func (w *Watcher) processPending(ctx context.Context, files chan<- scanner.File)
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			w.checkPending(files)
+			for _, file := range w.checkPending() {
+				select {
+				case files <- file:
+					w.markEmitted(file.Info)
+				case <-ctx.Done():
+					return
+				}
+			}
 		}
 	}
 }
 
-// checkPending проверяет pending файлы и отправляет готовые.
-func (w *Watcher) checkPending(files chan<- scanner.File) {
+// checkPending проверяет pending файлы и возвращает готовые к обработке.
+// Файл считается готовым, если с последнего события прошло debounceTime
+// и его размер не изменился между двумя последовательными проверками.
+// Это защищает от обработки файлов, которые ещё копируются (например, по сети).
+// Файлы, не подходящие под include/exclude, убираются из pending без проверки.
+// Возвращённые файлы не отмечаются отправленными: это делает processPending
+// после отправки в канал.
+func (w *Watcher) checkPending() []scanner.File {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	var ready []scanner.File
 	now := time.Now()
-	for path, addedAt := range w.pending {
-		if now.Sub(addedAt) < w.debounceTime {
+	w.evictEmittedLocked(now)
+	for path, p := range w.pending {
+		relPath, err := filepath.Rel(w.absInputDir(), path)
+		if err != nil {
+			relPath = filepath.Base(path)
+		}
+		if !w.filter.Match(relPath) {
+			delete(w.pending, path)
 			continue
 		}
 
-		// Файл готов к обработке
-		delete(w.pending, path)
+		if now.Sub(p.changedAt) < w.debounceTime {
+			continue
+		}
 
 		// Получаем информацию о файле
 		info, err := os.Stat(path)
 		if err != nil {
+			delete(w.pending, path)
 			continue
 		}
 
-		relPath, err := filepath.Rel(w.cfg.InputDir, path)
-		if err != nil {
-			relPath = filepath.Base(path)
+		// Размер ещё не проверялся или изменился - ждём следующей проверки
+		if info.Size() != p.size {
+			if p.size >= 0 {
+				p.changedAt = now
+			}
+			p.size = info.Size()
+			continue
+		}
+
+		// Файл готов к обработке
+		delete(w.pending, path)
+
+		fileInfo := storage.FileInfo{
+			Path:  path,
+			Size:  info.Size(),
+			Mtime: info.ModTime().Unix(),
 		}
 
-		files <- scanner.File{
+		// Пропускаем файлы, уже отправленные без изменений
+		if w.isEmittedLocked(fileInfo) {
+			continue
+		}
+
+		ready = append(ready, scanner.File{
 			Path:    path,
 			RelPath: relPath,
-			Info: storage.FileInfo{
-				Path:  path,
-				Size:  info.Size(),
-				Mtime: info.ModTime().Unix(),
-			},
-		}
+			Info:    fileInfo,
+		})
+	}
+	return ready
+}
+
+// matches проверяет абсолютный путь файла по include/exclude шаблонам.
+func (w *Watcher) matches(path string) bool {
+	relPath, err := filepath.Rel(w.absInputDir(), path)
+	if err != nil {
+		return false
+	}
+	return w.filter.Match(relPath)
+}
+
+// absInputDir возвращает абсолютный путь входной директории.
+func (w *Watcher) absInputDir() string {
+	if dir, err := filepath.Abs(w.cfg.InputDir); err == nil {
+		return dir
 	}
+	return w.cfg.InputDir
 }
 
 // Close закрывает watcher.
@@ -200,7 +397,6 @@ func (w *Watcher) Close() error {
 
 /*
 Возможные расширения:
-- Добавить фильтрацию по паттерну (glob)
 - Добавить обработку удаления файлов
 - Добавить обработку переименования файлов
 - Добавить rate limiting для большого количества файлов
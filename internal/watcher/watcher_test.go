@@ -0,0 +1,323 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+	"github.com/artemshloyda/photoconverter/internal/storage"
+)
+
+// newTestWatcher создаёт watcher для временной входной директории.
+func newTestWatcher(t *testing.T, cfg *config.Config) *Watcher {
+	t.Helper()
+	w, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	w.SetDebounceTime(50 * time.Millisecond)
+	t.Cleanup(func() { _ = w.Close() })
+	return w
+}
+
+// newTestConfig создаёт конфигурацию с временной входной директорией.
+func newTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.InputDir = t.TempDir()
+	cfg.OutputDir = t.TempDir()
+	return cfg
+}
+
+// writeFile создаёт файл с содержимым, при необходимости создавая директории.
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// collect читает файлы из канала в течение заданного времени.
+func collect(files <-chan scanner.File, d time.Duration) []string {
+	var got []string
+	timeout := time.After(d)
+	for {
+		select {
+		case f, ok := <-files:
+			if !ok {
+				return got
+			}
+			got = append(got, f.RelPath)
+		case <-timeout:
+			return got
+		}
+	}
+}
+
+func TestWatch_InitialScan(t *testing.T) {
+	cfg := newTestConfig(t)
+	writeFile(t, filepath.Join(cfg.InputDir, "a.jpg"), "a")
+	writeFile(t, filepath.Join(cfg.InputDir, "sub", "b.png"), "b")
+	writeFile(t, filepath.Join(cfg.InputDir, "notes.txt"), "txt")
+
+	w := newTestWatcher(t, cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	files, err := w.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	got := collect(files, 300*time.Millisecond)
+
+	// Новый файл после начального сканирования должен прийти через события
+	writeFile(t, filepath.Join(cfg.InputDir, "c.jpg"), "c")
+
+	got = append(got, collect(files, 600*time.Millisecond)...)
+	sort.Strings(got)
+	want := []string{"a.jpg", "c.jpg", filepath.Join("sub", "b.png")}
+	if len(got) != len(want) {
+		t.Fatalf("получено %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("files[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	// После отмены контекста канал закрывается
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		for range files {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("канал не закрыт после отмены контекста")
+	}
+}
+
+func TestWatch_InitialScanDisabled(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.WatchInitialScan = false
+	writeFile(t, filepath.Join(cfg.InputDir, "a.jpg"), "a")
+
+	w := newTestWatcher(t, cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	files, err := w.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	if got := collect(files, 300*time.Millisecond); len(got) != 0 {
+		t.Errorf("без начального сканирования получено %v", got)
+	}
+}
+
+func TestWatch_NoDuplicateAfterInitialScan(t *testing.T) {
+	cfg := newTestConfig(t)
+	path := filepath.Join(cfg.InputDir, "a.jpg")
+	writeFile(t, path, "a")
+
+	w := newTestWatcher(t, cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	files, err := w.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	if got := collect(files, 300*time.Millisecond); len(got) != 1 {
+		t.Fatalf("начальное сканирование: %v, want [a.jpg]", got)
+	}
+
+	// Событие для неизменённого файла (как Create, пришедший во время сканирования)
+	absPath, _ := filepath.Abs(path)
+	w.mu.Lock()
+	w.pending[absPath] = &pendingFile{changedAt: time.Now().Add(-time.Second), size: -1}
+	w.mu.Unlock()
+
+	if got := collect(files, 300*time.Millisecond); len(got) != 0 {
+		t.Errorf("файл обработан повторно: %v", got)
+	}
+}
+
+func TestWatch_ExcludedDirNeverEmitted(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.WatchInitialScan = false
+	cfg.Exclude = []string{"**/raw/**"}
+	if err := os.MkdirAll(filepath.Join(cfg.InputDir, "2024", "raw"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	w := newTestWatcher(t, cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	files, err := w.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	writeFile(t, filepath.Join(cfg.InputDir, "2024", "raw", "skip.jpg"), "raw")
+	writeFile(t, filepath.Join(cfg.InputDir, "2024", "keep.jpg"), "keep")
+
+	got := collect(files, 600*time.Millisecond)
+	if len(got) != 1 || got[0] != filepath.Join("2024", "keep.jpg") {
+		t.Errorf("получено %v, want [2024/keep.jpg]", got)
+	}
+}
+
+func TestCheckPending_WaitsForStableSize(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.WatchDebounce = 20 * time.Millisecond
+	w := newTestWatcher(t, cfg)
+	w.SetDebounceTime(cfg.WatchDebounce)
+
+	path := filepath.Join(cfg.InputDir, "big.jpg")
+	absPath, _ := filepath.Abs(path)
+	writeFile(t, path, "first chunk")
+
+	w.mu.Lock()
+	w.pending[absPath] = &pendingFile{changedAt: time.Now().Add(-time.Second), size: -1}
+	w.mu.Unlock()
+
+	// Первая проверка только запоминает размер
+	if got := w.checkPending(); len(got) != 0 {
+		t.Fatalf("файл отправлен до проверки стабильности размера: %v", got)
+	}
+
+	// Дописываем второй кусок: размер изменился
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(" + second chunk"); err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+
+	if got := w.checkPending(); len(got) != 0 {
+		t.Fatalf("растущий файл отправлен: %v", got)
+	}
+
+	// Размер стабилен и debounce прошёл
+	time.Sleep(2 * cfg.WatchDebounce)
+	got := w.checkPending()
+	if len(got) != 1 {
+		t.Fatalf("стабильный файл не отправлен: %v", got)
+	}
+	if want := int64(len("first chunk + second chunk")); got[0].Info.Size != want {
+		t.Errorf("размер = %d, want %d", got[0].Info.Size, want)
+	}
+}
+
+func TestCheckPending_FilterFirst(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Exclude = []string{"**/raw/**"}
+	w := newTestWatcher(t, cfg)
+
+	path := filepath.Join(cfg.InputDir, "raw", "skip.jpg")
+	absPath, _ := filepath.Abs(path)
+	writeFile(t, path, "raw")
+
+	// Исключённый файл убирается сразу, не дожидаясь debounce и стабильного размера
+	w.mu.Lock()
+	w.pending[absPath] = &pendingFile{changedAt: time.Now(), size: -1}
+	w.mu.Unlock()
+	if got := w.checkPending(); len(got) != 0 {
+		t.Fatalf("исключённый файл отправлен: %v", got)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.pending) != 0 {
+		t.Errorf("исключённый файл остался в pending: %v", w.pending)
+	}
+}
+
+func TestCheckPending_MarkedAfterSend(t *testing.T) {
+	cfg := newTestConfig(t)
+	w := newTestWatcher(t, cfg)
+
+	path := filepath.Join(cfg.InputDir, "a.jpg")
+	absPath, _ := filepath.Abs(path)
+	writeFile(t, path, "a")
+	ready := func() []scanner.File {
+		w.mu.Lock()
+		w.pending[absPath] = &pendingFile{changedAt: time.Now().Add(-time.Second), size: int64(len("a"))}
+		w.mu.Unlock()
+		return w.checkPending()
+	}
+
+	// Не отправленный файл (например, при отмене контекста) не считается отправленным
+	got := ready()
+	if len(got) != 1 {
+		t.Fatalf("файл не готов: %v", got)
+	}
+	if got := ready(); len(got) != 1 {
+		t.Fatalf("файл, не отправленный в канал, пропущен: %v", got)
+	}
+
+	w.markEmitted(got[0].Info)
+	if got := ready(); len(got) != 0 {
+		t.Errorf("отправленный файл возвращён повторно: %v", got)
+	}
+}
+
+func TestCheckPending_EvictsEmitted(t *testing.T) {
+	cfg := newTestConfig(t)
+	w := newTestWatcher(t, cfg)
+
+	old := storage.FileInfo{Path: "/in/old.jpg", Size: 1, Mtime: 100}
+	recent := storage.FileInfo{Path: "/in/recent.jpg", Size: 2, Mtime: 200}
+	w.markEmitted(old)
+	w.markEmitted(recent)
+
+	// Запись старше emittedTTL удаляется, свежая остаётся
+	w.mu.Lock()
+	key := emittedKey{path: old.Path, mtime: old.Mtime}
+	w.emitted[key] = emittedFile{size: old.Size, at: time.Now().Add(-2 * emittedTTL)}
+	w.evictedAt = time.Now().Add(-2 * emittedTTL)
+	w.mu.Unlock()
+
+	w.checkPending()
+	if w.isEmitted(old) {
+		t.Error("устаревшая запись не удалена")
+	}
+	if !w.isEmitted(recent) {
+		t.Error("свежая запись удалена")
+	}
+
+	// Изменённый файл (другой mtime) не считается отправленным
+	if w.isEmitted(storage.FileInfo{Path: recent.Path, Size: recent.Size, Mtime: recent.Mtime + 1}) {
+		t.Error("файл с другим mtime считается отправленным")
+	}
+}
+
+func TestNew_WatchDebounceFromConfig(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.WatchDebounce = 3 * time.Second
+	w, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if w.debounceTime != 3*time.Second {
+		t.Errorf("debounceTime = %s, want 3s", w.debounceTime)
+	}
+}
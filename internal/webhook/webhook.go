@@ -0,0 +1,118 @@
+// Package webhook отправляет уведомление о завершении запуска (--webhook).
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Payload - JSON тело уведомления с итогами запуска.
+type Payload struct {
+	// Status - итог запуска: ok или failed (есть ошибки конвертации).
+	Status string `json:"status"`
+
+	// DryRun - запуск в режиме dry-run.
+	DryRun bool `json:"dry_run"`
+
+	// Processed, Skipped, Failed, Total - счётчики задач.
+	Processed int64 `json:"processed"`
+	Skipped   int64 `json:"skipped"`
+	Failed    int64 `json:"failed"`
+	Total     int64 `json:"total"`
+
+	// InputBytes, OutputBytes - суммарные размеры входных и выходных файлов.
+	InputBytes  int64 `json:"input_bytes"`
+	OutputBytes int64 `json:"output_bytes"`
+
+	// SavedBytes, SavedPercent - экономия места.
+	SavedBytes   int64   `json:"saved_bytes"`
+	SavedPercent float64 `json:"saved_percent"`
+
+	// StartedAt, FinishedAt, DurationSec - время запуска.
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+	DurationSec float64   `json:"duration_sec"`
+}
+
+// Client отправляет уведомления с повторами.
+type Client struct {
+	// HTTPClient - HTTP-клиент (задаёт таймаут одной попытки).
+	HTTPClient *http.Client
+
+	// Retries - количество повторов после неудачной попытки.
+	Retries int
+
+	// Backoff - пауза перед первым повтором, удваивается с каждым повтором.
+	Backoff time.Duration
+}
+
+// New создаёт клиент: таймаут попытки 10 секунд, 3 повтора, пауза от 1 секунды.
+func New() *Client {
+	return &Client{
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		Retries:    3,
+		Backoff:    time.Second,
+	}
+}
+
+// Send отправляет payload POST-запросом на url.
+// Повторяет попытку при сетевой ошибке и ответах 5xx/429; ответ 4xx - окончательная ошибка.
+func (c *Client) Send(ctx context.Context, url string, p Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("не удалось сериализовать уведомление: %w", err)
+	}
+
+	backoff := c.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := c.post(ctx, url, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= c.Retries {
+			return fmt.Errorf("webhook %s: %w", url, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("webhook %s: %w", url, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post выполняет одну попытку отправки и сообщает, имеет ли смысл повторять.
+func (c *Client) post(ctx context.Context, url string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "photoconverter")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("ответ %s", resp.Status)
+}
+
+/*
+Возможные расширения:
+- Подпись тела запроса (HMAC) для проверки отправителя
+- Шаблоны тела для Slack/Telegram
+- Уведомления о каждом пакете в watch режиме
+*/
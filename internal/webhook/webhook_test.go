@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testClient возвращает клиент с короткими паузами между повторами.
+func testClient(retries int) *Client {
+	c := New()
+	c.Retries = retries
+	c.Backoff = time.Millisecond
+	return c
+}
+
+func TestSend_RetriesUntilSuccess(t *testing.T) {
+	var calls atomic.Int32
+	var got Payload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("тело запроса: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	want := Payload{Status: "ok", Processed: 3, Skipped: 1, InputBytes: 300, OutputBytes: 100, SavedBytes: 200}
+	if err := testClient(3).Send(context.Background(), srv.URL, want); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("попыток: %d, want 3", calls.Load())
+	}
+	if got != want {
+		t.Errorf("получено %+v, want %+v", got, want)
+	}
+}
+
+func TestSend_GivesUp(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		code  int
+		calls int32
+	}{
+		{"server error", http.StatusInternalServerError, 3},
+		{"client error", http.StatusBadRequest, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.WriteHeader(tc.code)
+			}))
+			defer srv.Close()
+
+			if err := testClient(2).Send(context.Background(), srv.URL, Payload{}); err == nil {
+				t.Error("ожидалась ошибка")
+			}
+			if calls.Load() != tc.calls {
+				t.Errorf("попыток: %d, want %d", calls.Load(), tc.calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+)
+
+const (
+	// autoTuneInterval - период пересчёта числа воркеров (--workers -1).
+	autoTuneInterval = 2 * time.Second
+
+	// autoTuneStart - число воркеров в начале: начинаем осторожно и растём,
+	// пока растёт пропускная способность.
+	autoTuneStart = 2
+
+	// autoTuneGain и autoTuneLoss - пороги изменения пропускной способности
+	// относительно прошлого интервала: выше gain - продолжаем в том же
+	// направлении, ниже loss - разворачиваемся, между ними - держим.
+	autoTuneGain = 1.05
+	autoTuneLoss = 0.95
+
+	// autoTunePressure - доля свободной памяти системы, ниже которой
+	// число воркеров уменьшается.
+	autoTunePressure = 0.1
+)
+
+// autoTuner подбирает число воркеров по ходу обработки: по пропускной
+// способности (байт исходников в секунду), среднему размеру файлов и
+// свободной памяти системы. Мелким файлам достаётся больше воркеров,
+// крупным RAW - меньше, чтобы не упираться в память.
+type autoTuner struct {
+	min, max int
+	reader   MemoryReader
+
+	mu       sync.Mutex
+	target   int
+	step     int     // направление изменения: +1 или -1
+	lastRate float64 // пропускная способность прошлого интервала
+
+	// Завершённые файлы за текущий интервал и за весь запуск
+	files, bytes           int64
+	totalFiles, totalBytes int64
+}
+
+// newAutoTuner создаёт autoTuner с границами [min, max].
+// reader - источник сведений о памяти системы (nil = без учёта памяти).
+func newAutoTuner(min, max int, reader MemoryReader) *autoTuner {
+	if max < min {
+		max = min
+	}
+	return &autoTuner{
+		min:    min,
+		max:    max,
+		reader: reader,
+		target: clampInt(autoTuneStart, min, max),
+		step:   1,
+	}
+}
+
+// observe учитывает завершённый файл размером size байт.
+func (t *autoTuner) observe(size int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.files++
+	t.bytes += size
+	t.totalFiles++
+	t.totalBytes += size
+}
+
+// Target возвращает текущее желаемое число воркеров.
+func (t *autoTuner) Target() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.target
+}
+
+// adjust подводит итог интервала длительностью elapsed и возвращает новое
+// число воркеров.
+func (t *autoTuner) adjust(elapsed time.Duration) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	limit := t.memoryLimit()
+
+	// За интервал не завершился ни один файл (крупные файлы или пауза) -
+	// сравнивать нечего, держим текущее число
+	if t.files > 0 && elapsed > 0 {
+		rate := float64(t.bytes) / elapsed.Seconds()
+		switch {
+		case t.lastRate == 0 || rate >= t.lastRate*autoTuneGain:
+			t.target += t.step
+		case rate <= t.lastRate*autoTuneLoss:
+			t.step = -t.step
+			t.target += t.step
+		}
+		t.lastRate = rate
+	}
+	t.files, t.bytes = 0, 0
+
+	t.target = clampInt(t.target, t.min, limit)
+	return t.target
+}
+
+// memoryLimit возвращает верхнюю границу числа воркеров по памяти.
+// Воркер со средним файлом занимает около 3x его размера (как в MemoryLimiter);
+// занятая воркерами память уже вычтена из свободной, поэтому граница - это
+// текущее число плюс столько файлов, сколько помещается в долю свободной памяти.
+// При нехватке памяти граница на единицу ниже текущего числа.
+// Вызывается под блокировкой mu.
+func (t *autoTuner) memoryLimit() int {
+	if t.reader == nil || t.totalFiles == 0 {
+		return t.max
+	}
+	mem, err := t.reader()
+	if err != nil {
+		return t.max
+	}
+	if mem.Total > 0 && float64(mem.Available) < float64(mem.Total)*autoTunePressure {
+		return clampInt(t.target-1, t.min, t.max)
+	}
+
+	perFile := 3 * float64(t.totalBytes) / float64(t.totalFiles)
+	if perFile <= 0 {
+		return t.max
+	}
+	spare := int(float64(mem.Available) * defaultMemoryFraction / perFile)
+	return clampInt(t.target+spare, t.min, t.max)
+}
+
+// runAutoWorkers запускает воркеров в режиме автоподбора (--workers -1).
+// Раз в tuneInterval autoTuner пересчитывает их число: недостающие воркеры
+// запускаются, лишние завершаются после текущего файла.
+func (p *Pool) runAutoWorkers(ctx context.Context, files <-chan scanner.File) {
+	tuner := newAutoTuner(1, p.cfg.MaxWorkers(), p.memReader)
+	p.tuner = tuner
+
+	var wg sync.WaitGroup
+	var stops []chan struct{}
+
+	// drained закрывается, когда воркер завершился не по команде
+	// (канал файлов исчерпан или контекст отменён)
+	drained := make(chan struct{})
+	var drainOnce sync.Once
+
+	resize := func(n int) {
+		for len(stops) < n {
+			stop := make(chan struct{})
+			stops = append(stops, stop)
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				p.worker(ctx, id, files, stop)
+				select {
+				case <-stop:
+				default:
+					drainOnce.Do(func() { close(drained) })
+				}
+			}(len(stops) - 1)
+		}
+		for len(stops) > n {
+			close(stops[len(stops)-1])
+			stops = stops[:len(stops)-1]
+		}
+	}
+
+	resize(tuner.Target())
+
+	ticker := time.NewTicker(p.tuneInterval)
+	defer ticker.Stop()
+	last := time.Now()
+
+	for {
+		select {
+		case <-drained:
+			wg.Wait()
+			return
+		case now := <-ticker.C:
+			n := tuner.adjust(now.Sub(last))
+			last = now
+			if n != len(stops) {
+				if p.verbose {
+					p.printMessage("⚙️  Воркеров: %d -> %d\n", len(stops), n)
+				}
+				resize(n)
+			}
+		}
+	}
+}
+
+// clampInt ограничивает v диапазоном [lo, hi].
+func clampInt(v, lo, hi int) int {
+	if v > hi {
+		v = hi
+	}
+	if v < lo {
+		v = lo
+	}
+	return v
+}
@@ -0,0 +1,119 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// runTunerIntervals прогоняет n интервалов по секунде: за интервал завершается
+// perWorker(target) файлов размера size на каждого воркера. Возвращает
+// историю числа воркеров и проверяет границы [min, max].
+func runTunerIntervals(t *testing.T, tuner *autoTuner, n int, size int64, perWorker func(target int) int) []int {
+	t.Helper()
+	var history []int
+	for i := 0; i < n; i++ {
+		target := tuner.Target()
+		for j := 0; j < target*perWorker(target); j++ {
+			tuner.observe(size)
+		}
+		got := tuner.adjust(time.Second)
+		if got < tuner.min || got > tuner.max {
+			t.Fatalf("интервал %d: воркеров %d, вне границ [%d, %d]", i, got, tuner.min, tuner.max)
+		}
+		history = append(history, got)
+	}
+	return history
+}
+
+func TestAutoTuner_SmallFilesScaleUp(t *testing.T) {
+	mem := &fakeMemory{}
+	mem.available.Store(800 << 20)
+	tuner := newAutoTuner(1, 8, mem.read)
+	if tuner.Target() != autoTuneStart {
+		t.Fatalf("начальное число воркеров %d, want %d", tuner.Target(), autoTuneStart)
+	}
+
+	// Мелкие файлы: каждый воркер добавляет пропускной способности
+	history := runTunerIntervals(t, tuner, 10, 100<<10, func(int) int { return 10 })
+	if got := history[len(history)-1]; got != 8 {
+		t.Errorf("мелкие файлы: воркеров %d, want 8 (история %v)", got, history)
+	}
+}
+
+func TestAutoTuner_ThroughputPeak(t *testing.T) {
+	tuner := newAutoTuner(1, 16, nil)
+
+	// Пропускная способность растёт до 4 воркеров, дальше падает
+	history := runTunerIntervals(t, tuner, 12, 1<<20, func(target int) int {
+		if target <= 4 {
+			return 10
+		}
+		return 10 * 4 / target / 2
+	})
+	for _, n := range history[4:] {
+		if n < 3 || n > 5 {
+			t.Errorf("воркеров %d далеко от пика 4 (история %v)", n, history)
+			break
+		}
+	}
+}
+
+func TestAutoTuner_LargeFilesLimitedByMemory(t *testing.T) {
+	mem := &fakeMemory{}
+	mem.available.Store(500 << 20) // под конвертации: 250 МБ
+	tuner := newAutoTuner(1, 8, mem.read)
+
+	// Крупные файлы: оценка 300 МБ на файл, в свободную память новый не помещается
+	history := runTunerIntervals(t, tuner, 5, 100<<20, func(int) int { return 1 })
+	for _, n := range history {
+		if n > autoTuneStart {
+			t.Fatalf("крупные файлы: воркеров %d, не должно расти (история %v)", n, history)
+		}
+	}
+
+	// Нехватка памяти: число воркеров снижается до минимума
+	mem.available.Store(50 << 20)
+	history = runTunerIntervals(t, tuner, 3, 100<<20, func(int) int { return 1 })
+	if got := history[len(history)-1]; got != 1 {
+		t.Errorf("нехватка памяти: воркеров %d, want 1 (история %v)", got, history)
+	}
+}
+
+func TestPool_WorkersAuto(t *testing.T) {
+	var names []string
+	for i := 0; i < 30; i++ {
+		names = append(names, fmt.Sprintf("f%02d.jpg", i))
+	}
+	cfg, pool := newTestEnv(t, names...)
+	cfg.Workers = config.WorkersAuto
+
+	// Файлы разного размера: от сотен байт до мегабайта
+	for i, name := range names {
+		data := strings.Repeat("x", 100<<(i%14))
+		if err := os.WriteFile(filepath.Join(cfg.InputDir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mem := &fakeMemory{}
+	mem.available.Store(800 << 20)
+	pool.memReader = mem.read
+	pool.tuneInterval = time.Millisecond
+
+	stats := runPool(t, cfg, pool)
+	if stats.Processed != int64(len(names)) || stats.Failed != 0 {
+		t.Fatalf("processed=%d failed=%d, want %d/0", stats.Processed, stats.Failed, len(names))
+	}
+	if n := pool.tuner.Target(); n < 1 || n > cfg.MaxWorkers() {
+		t.Errorf("воркеров %d, вне границ [1, %d]", n, cfg.MaxWorkers())
+	}
+	if pool.tuner.totalFiles != int64(len(names)) {
+		t.Errorf("учтено файлов %d, want %d", pool.tuner.totalFiles, len(names))
+	}
+}
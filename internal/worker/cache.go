@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/cache"
+	"github.com/artemshloyda/photoconverter/internal/converter"
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+)
+
+// SetCache устанавливает кэш результатов (--cache).
+func (p *Pool) SetCache(c *cache.Cache) {
+	p.cache = c
+}
+
+// cached проверяет, есть ли в кэше результат файла для варианта v
+// (план dry-run: кэш только читается).
+func (p *Pool) cached(file scanner.File, v variant) bool {
+	if p.cache == nil || !p.cache.IsEnabled() {
+		return false
+	}
+	c := p.cache.WithConfig(v.cfg)
+	source, err := c.Source(file.Path)
+	if err != nil {
+		return false
+	}
+	return c.Get(source, v.cfg.OutputParamsHash()) != ""
+}
+
+// convert конвертирует файл в dstPath конвертером conv. С --cache результат
+// сначала ищется в кэше, а успешная конвертация сохраняется в кэш
+// (кроме многокадровых результатов --all-images). Ошибки кэша не отменяют
+// конвертацию.
+func (p *Pool) convert(ctx context.Context, file scanner.File, v variant, conv converter.Converter, dstPath string) *converter.ConvertResult {
+	if p.cache == nil || !p.cache.IsEnabled() {
+		return conv.Convert(ctx, file.Path, dstPath)
+	}
+
+	c := p.cache.WithConfig(v.cfg)
+	paramsHash := v.cfg.OutputParamsHash()
+	source, err := c.Source(file.Path)
+	if err != nil {
+		p.logError(file.Path, fmt.Errorf("кэш: %w", err))
+		return conv.Convert(ctx, file.Path, dstPath)
+	}
+
+	if cachePath := c.Get(source, paramsHash); cachePath != "" {
+		start := time.Now()
+		err := c.CopyFromCache(cachePath, dstPath, file.Path)
+		if err == nil {
+			atomic.AddInt64(&p.stats.CacheHits, 1)
+			if p.debug {
+				p.printMessage("♻️  %s: результат из кэша %s\n", file.RelPath, cachePath)
+			}
+			return &converter.ConvertResult{Success: true, DstPath: dstPath, Duration: time.Since(start)}
+		}
+		if !errors.Is(err, cache.ErrCorrupt) || p.verbose {
+			p.logError(file.Path, fmt.Errorf("кэш: %w", err))
+		}
+	}
+
+	res := conv.Convert(ctx, file.Path, dstPath)
+	if res.Success && len(res.Extra) == 0 {
+		if err := c.Put(source, paramsHash, dstPath); err != nil {
+			p.logError(file.Path, fmt.Errorf("не удалось сохранить результат в кэш: %w", err))
+		}
+	}
+	return res
+}
@@ -0,0 +1,112 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/cache"
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// newCachedStubEnv создаёт окружение newStubEnv с кэшем в cacheDir.
+func newCachedStubEnv(t *testing.T, cacheDir string, names ...string) (*config.Config, *Pool, *stubConverter) {
+	t.Helper()
+	cfg, pool, stub := newStubEnv(t, 0, names...)
+	cfg.CacheEnabled = true
+	cfg.CacheDir = cacheDir
+	c, err := cache.New(cfg)
+	if err != nil {
+		t.Fatalf("cache.New: %v", err)
+	}
+	pool.SetCache(c)
+	return cfg, pool, stub
+}
+
+func TestPool_SharedCache(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+
+	// Первый запуск конвертирует и заполняет общий кэш
+	cfg1, pool1, stub1 := newCachedStubEnv(t, config.CacheDirGlobal, "photo.jpg")
+	if stats := runPool(t, cfg1, pool1); stats.Processed != 1 || stats.CacheHits != 0 || stub1.calls.Load() != 1 {
+		t.Fatalf("первый запуск: processed=%d hits=%d вызовов=%d, want 1/0/1", stats.Processed, stats.CacheHits, stub1.calls.Load())
+	}
+
+	// Тот же исходник в другой входной и выходной директории - из кэша
+	cfg2, pool2, stub2 := newCachedStubEnv(t, config.CacheDirGlobal, "photo.jpg")
+	if cfg2.OutputDir == cfg1.OutputDir {
+		t.Fatal("выходные директории совпадают")
+	}
+	stats := runPool(t, cfg2, pool2)
+	if stats.Processed != 1 || stats.CacheHits != 1 || stub2.calls.Load() != 0 {
+		t.Fatalf("второй запуск: processed=%d hits=%d вызовов=%d, want 1/1/0", stats.Processed, stats.CacheHits, stub2.calls.Load())
+	}
+	data, err := os.ReadFile(filepath.Join(cfg2.OutputDir, "photo.jpg"))
+	if err != nil || string(data) != "converted:photo.jpg" {
+		t.Errorf("результат из кэша = %q, %v", data, err)
+	}
+
+	// Другие параметры - другая запись
+	cfg3, pool3, stub3 := newCachedStubEnv(t, config.CacheDirGlobal, "photo.jpg")
+	cfg3.Quality = 50
+	if stats := runPool(t, cfg3, pool3); stats.CacheHits != 0 || stub3.calls.Load() != 1 {
+		t.Errorf("другое качество: hits=%d вызовов=%d, want 0/1", stats.CacheHits, stub3.calls.Load())
+	}
+}
+
+func TestPool_OutputDirCache(t *testing.T) {
+	// Кэш в выходной директории ищет записи по пути исходника
+	cfg, pool, stub := newCachedStubEnv(t, "", "photo.jpg")
+	runPool(t, cfg, pool)
+
+	cfg.Force = true
+	forced := New(cfg, pool.storage, stub)
+	forced.SetCache(pool.cache)
+	stats := runPool(t, cfg, forced)
+	if stats.CacheHits != 1 || stub.calls.Load() != 1 {
+		t.Errorf("повтор с --force: hits=%d вызовов=%d, want 1/1", stats.CacheHits, stub.calls.Load())
+	}
+	if _, err := os.Stat(filepath.Join(cfg.OutputDir, ".photoconverter", "cache")); err != nil {
+		t.Errorf("кэш по умолчанию в выходной директории: %v", err)
+	}
+}
+
+func TestPool_DryRunCacheHits(t *testing.T) {
+	cfg, pool, stub := newCachedStubEnv(t, t.TempDir(), "done.jpg")
+	runPool(t, cfg, pool)
+
+	// Новые файлы: результат cached.jpg уже есть в кэше, fresh.jpg - нет
+	for _, name := range []string{"cached.jpg", "fresh.jpg"} {
+		if err := os.WriteFile(filepath.Join(cfg.InputDir, name), []byte("image:"+name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	converted := filepath.Join(t.TempDir(), "cached.jpg")
+	if err := os.WriteFile(converted, []byte("converted:cached.jpg"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source, err := pool.cache.Source(filepath.Join(cfg.InputDir, "cached.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.cache.Put(source, cfg.OutputParamsHash(), converted); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.DryRun = true
+	stub.calls.Store(0)
+	planned := New(cfg, pool.storage, stub)
+	planned.SetCache(pool.cache)
+	plan := runPool(t, cfg, planned).Plan
+	if plan.New != 2 || plan.Skip != 1 || plan.CacheHits != 1 || plan.Conversions() != 1 {
+		t.Errorf("план = %+v (конвертаций %d), want NEW 2, SKIP 1, из кэша 1, конвертаций 1", plan, plan.Conversions())
+	}
+	if stub.calls.Load() != 0 {
+		t.Errorf("dry-run вызвал конвертер %d раз", stub.calls.Load())
+	}
+	if _, err := os.Stat(filepath.Join(cfg.OutputDir, "cached.jpg")); !os.IsNotExist(err) {
+		t.Errorf("dry-run скопировал результат из кэша: %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+	"github.com/artemshloyda/photoconverter/internal/storage"
+)
+
+// resolveConflict применяет --on-conflict к начатой задаче, выходной файл
+// которой уже есть на диске, но не записан в БД: skip удаляет задачу и
+// пропускает файл, rename возвращает первый свободный путь name-N.ext.
+// Возвращает путь для записи и skip = true, если файл пропущен.
+func (p *Pool) resolveConflict(file scanner.File, v variant, result *storage.StartJobResult, dstPath string) (string, bool) {
+	if v.cfg.OnConflict != config.OnConflictSkip && v.cfg.OnConflict != config.OnConflictRename {
+		return dstPath, false
+	}
+	if !p.untrackedOutput(file, dstPath, result.JobID) {
+		return dstPath, false
+	}
+
+	if v.cfg.OnConflict == config.OnConflictRename {
+		renamed := conflictFreePath(dstPath)
+		if p.verbose {
+			p.printMessage("📝 %s: %s уже существует, результат записывается в %s\n", file.RelPath, dstPath, renamed)
+		}
+		return renamed, false
+	}
+
+	if err := p.storage.DeleteJob(result.JobID); err != nil {
+		p.logError(file.Path, fmt.Errorf("--on-conflict: %w", err))
+	}
+	p.addSkipped(file, SkipReasonOutputExists)
+	return dstPath, true
+}
+
+// untrackedOutput сообщает, что dstPath есть на диске, но его не записывала
+// ни одна задача БД, кроме jobID. Результат прежней версии исходника
+// (исходник изменился после конвертации) конфликтом не считается.
+func (p *Pool) untrackedOutput(file scanner.File, dstPath string, jobID int64) bool {
+	if _, err := os.Lstat(dstPath); err != nil {
+		return false
+	}
+	tracked, err := p.storage.DstPathTracked(dstPath, jobID)
+	if err != nil {
+		p.logError(file.Path, fmt.Errorf("--on-conflict: %w", err))
+		return false
+	}
+	return !tracked
+}
+
+// conflictFreePath возвращает первый несуществующий путь вида name-N.ext
+// (N от 1) для занятого path.
+func conflictFreePath(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s-%d%s", base, i, ext)
+		if _, err := os.Lstat(candidate); errors.Is(err, fs.ErrNotExist) {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+func TestPool_OnConflict(t *testing.T) {
+	const existing = "чужой файл"
+
+	tests := []struct {
+		policy      string
+		wantCalls   int64
+		wantSkipped int64
+		wantA       string
+		wantRenamed string
+	}{
+		{policy: config.OnConflictOverwrite, wantCalls: 2, wantA: "converted:a.jpg"},
+		{policy: config.OnConflictSkip, wantCalls: 1, wantSkipped: 1, wantA: existing},
+		{policy: config.OnConflictRename, wantCalls: 2, wantA: existing, wantRenamed: "converted:a.jpg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			cfg, pool, stub := newStubEnv(t, 0, "a.jpg", "b.jpg")
+			cfg.OnConflict = tt.policy
+
+			// Выходной файл a есть на диске, но не в БД; a-1 тоже занят
+			ext := "." + string(cfg.OutputFormat)
+			dstA := filepath.Join(cfg.OutputDir, "a"+ext)
+			if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(dstA, []byte(existing), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(cfg.OutputDir, "a-1"+ext), []byte(existing), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			stats := runPool(t, cfg, pool)
+			if stub.calls.Load() != tt.wantCalls || stats.Skipped != tt.wantSkipped || stats.Failed != 0 {
+				t.Fatalf("вызовов=%d skipped=%d failed=%d, want %d/%d/0",
+					stub.calls.Load(), stats.Skipped, stats.Failed, tt.wantCalls, tt.wantSkipped)
+			}
+			if data, err := os.ReadFile(dstA); err != nil || string(data) != tt.wantA {
+				t.Errorf("a%s = %q, %v, want %q", ext, data, err, tt.wantA)
+			}
+			renamed := filepath.Join(cfg.OutputDir, "a-2"+ext)
+			data, err := os.ReadFile(renamed)
+			if tt.wantRenamed == "" {
+				if err == nil {
+					t.Errorf("%s не должен создаваться", renamed)
+				}
+			} else if string(data) != tt.wantRenamed {
+				t.Errorf("%s = %q, %v, want %q", renamed, data, err, tt.wantRenamed)
+			}
+
+			// Пропущенный файл не записан в БД и проверяется снова, остальные пропускаются по БД
+			stub.calls.Store(0)
+			stats = runPool(t, cfg, New(cfg, pool.storage, stub))
+			if stub.calls.Load() != 0 || stats.Skipped != 2 {
+				t.Errorf("повтор: вызовов=%d skipped=%d, want 0/2", stub.calls.Load(), stats.Skipped)
+			}
+		})
+	}
+}
+
+func TestPool_OnConflictTrackedOutput(t *testing.T) {
+	for _, policy := range []string{config.OnConflictSkip, config.OnConflictRename} {
+		t.Run(policy, func(t *testing.T) {
+			cfg, pool, stub := newStubEnv(t, 0, "a.jpg", "b.jpg")
+			cfg.OnConflict = policy
+			if stats := runPool(t, cfg, pool); stats.Processed != 2 {
+				t.Fatalf("processed=%d, want 2", stats.Processed)
+			}
+			ext := "." + string(cfg.OutputFormat)
+			dstA := filepath.Join(cfg.OutputDir, "a"+ext)
+
+			// Исходник изменился: результат прежней задачи - не чужой файл
+			src := filepath.Join(cfg.InputDir, "a.jpg")
+			if err := os.WriteFile(src, []byte("image:a.jpg изменён"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			later := time.Now().Add(time.Hour)
+			if err := os.Chtimes(src, later, later); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(dstA, []byte("старый результат"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			stub.calls.Store(0)
+			stats := runPool(t, cfg, New(cfg, pool.storage, stub))
+			if stats.Processed != 1 || stats.Skipped != 1 || stub.calls.Load() != 1 {
+				t.Fatalf("processed=%d skipped=%d вызовов=%d, want 1/1/1", stats.Processed, stats.Skipped, stub.calls.Load())
+			}
+			assertReconverted(t, cfg.OutputDir, ext)
+		})
+	}
+}
+
+func TestPool_OnConflictVerifyOutputs(t *testing.T) {
+	for _, policy := range []string{config.OnConflictSkip, config.OnConflictRename} {
+		t.Run(policy, func(t *testing.T) {
+			cfg, pool, stub := newStubEnv(t, 0, "a.jpg", "b.jpg")
+			if stats := runPool(t, cfg, pool); stats.Processed != 2 {
+				t.Fatalf("processed=%d, want 2", stats.Processed)
+			}
+			ext := "." + string(cfg.OutputFormat)
+			if err := os.Truncate(filepath.Join(cfg.OutputDir, "a"+ext), 3); err != nil {
+				t.Fatal(err)
+			}
+
+			// Задача, начатая заново по --verify-outputs, перезаписывает свой результат
+			cfg.OnConflict = policy
+			cfg.VerifyOutputs = true
+			stub.calls.Store(0)
+			stats := runPool(t, cfg, New(cfg, pool.storage, stub))
+			if stats.Processed != 1 || stats.Skipped != 1 || stub.calls.Load() != 1 {
+				t.Fatalf("processed=%d skipped=%d вызовов=%d, want 1/1/1", stats.Processed, stats.Skipped, stub.calls.Load())
+			}
+			assertReconverted(t, cfg.OutputDir, ext)
+		})
+	}
+}
+
+// assertReconverted проверяет, что результат a записан на прежнее место, а не в a-1.
+func assertReconverted(t *testing.T, outputDir, ext string) {
+	t.Helper()
+	if data, err := os.ReadFile(filepath.Join(outputDir, "a"+ext)); err != nil || string(data) != "converted:a.jpg" {
+		t.Errorf("a%s = %q, %v, want %q", ext, data, err, "converted:a.jpg")
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, "a-1"+ext)); err == nil {
+		t.Errorf("a-1%s не должен создаваться", ext)
+	}
+}
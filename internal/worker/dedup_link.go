@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// linkDuplicate создаёт на месте дубликата linkPath ссылку на уже
+// сконвертированный target (--dedup-link). Символическая ссылка делается
+// относительной, чтобы дерево выхода можно было переносить; жёсткая ссылка
+// при ошибке (например, другая файловая система) заменяется копией.
+// Существующий linkPath не трогается: ссылка могла остаться с прошлого запуска.
+// Возвращает false, если linkPath уже существовал.
+func linkDuplicate(mode, target, linkPath string) (bool, error) {
+	if _, err := os.Lstat(linkPath); err == nil {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(linkPath), 0755); err != nil {
+		return false, fmt.Errorf("не удалось создать директорию %s: %w", filepath.Dir(linkPath), err)
+	}
+
+	switch mode {
+	case config.DedupLinkSymlink:
+		rel, err := filepath.Rel(filepath.Dir(linkPath), target)
+		if err != nil {
+			rel = target
+		}
+		if err := os.Symlink(rel, linkPath); err != nil {
+			return false, fmt.Errorf("не удалось создать ссылку %s: %w", linkPath, err)
+		}
+	case config.DedupLinkHardlink:
+		if err := os.Link(target, linkPath); err != nil {
+			if err := copyFile(target, linkPath); err != nil {
+				_ = os.Remove(linkPath)
+				return false, fmt.Errorf("не удалось скопировать %s -> %s: %w", target, linkPath, err)
+			}
+		}
+	default:
+		return false, fmt.Errorf("неизвестный вид ссылки: %s", mode)
+	}
+	return true, nil
+}
+
+/*
+Возможные расширения:
+- Reflink (copy-on-write) на файловых системах с его поддержкой
+- Удаление ссылок, цель которых исчезла
+*/
@@ -0,0 +1,89 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+func TestPool_DedupLink(t *testing.T) {
+	for _, mode := range []string{config.DedupLinkSymlink, config.DedupLinkHardlink} {
+		t.Run(mode, func(t *testing.T) {
+			cfg, pool := newTestEnv(t)
+			cfg.Mode = config.ModeDedup
+			cfg.DedupLink = mode
+			// Один воркер: дубликат проверяется после завершения оригинала
+			cfg.Workers = 1
+
+			for _, rel := range []string{"x/a.jpg", "y/b.jpg"} {
+				path := filepath.Join(cfg.InputDir, rel)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte("same image"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			stats := runPool(t, cfg, pool)
+			if stats.Processed != 1 || stats.Skipped != 1 || stats.Failed != 0 {
+				t.Fatalf("processed=%d skipped=%d failed=%d, want 1/1/0", stats.Processed, stats.Skipped, stats.Failed)
+			}
+
+			originals, _ := filepath.Glob(filepath.Join(cfg.OutputDir, "x", "a.*"))
+			links, _ := filepath.Glob(filepath.Join(cfg.OutputDir, "y", "b.*"))
+			if len(originals) != 1 || len(links) != 1 {
+				t.Fatalf("результаты: x=%v y=%v, want по одному файлу", originals, links)
+			}
+
+			linkInfo, err := os.Lstat(links[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			origInfo, err := os.Stat(originals[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch mode {
+			case config.DedupLinkSymlink:
+				if linkInfo.Mode()&os.ModeSymlink == 0 {
+					t.Fatalf("%s не символическая ссылка", links[0])
+				}
+				if target, _ := os.Readlink(links[0]); filepath.IsAbs(target) {
+					t.Errorf("ссылка должна быть относительной: %s", target)
+				}
+				if resolved, err := os.Stat(links[0]); err != nil || !os.SameFile(resolved, origInfo) {
+					t.Errorf("ссылка не указывает на %s: %v", originals[0], err)
+				}
+			case config.DedupLinkHardlink:
+				if !os.SameFile(linkInfo, origInfo) {
+					t.Errorf("%s не жёсткая ссылка на %s", links[0], originals[0])
+				}
+			}
+
+			// Повторный прогон: ссылка уже есть, ошибок нет
+			if stats := runPool(t, cfg, New(cfg, pool.storage, pool.converter)); stats.Failed != 0 || stats.Skipped != 2 {
+				t.Errorf("повторный прогон: skipped=%d failed=%d, want 2/0", stats.Skipped, stats.Failed)
+			}
+		})
+	}
+}
+
+func TestLinkDuplicate_FailureLeavesNothing(t *testing.T) {
+	dir := t.TempDir()
+	// Жёсткую ссылку на директорию создать нельзя: срабатывает копирование,
+	// которое тоже завершается ошибкой и не оставляет мусора
+	target := filepath.Join(dir, "target")
+	if err := os.Mkdir(target, 0755); err != nil {
+		t.Fatal(err)
+	}
+	linkPath := filepath.Join(dir, "sub", "link")
+	if _, err := linkDuplicate(config.DedupLinkHardlink, target, linkPath); err == nil {
+		t.Error("linkDuplicate(директория) должна вернуть ошибку")
+	}
+	if _, err := os.Lstat(linkPath); !os.IsNotExist(err) {
+		t.Errorf("после ошибки остался %s", linkPath)
+	}
+}
@@ -0,0 +1,87 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+)
+
+// removeSource убирает исходник после успешной конвертации: удаляет его
+// (--delete-source) или перемещает в корзину (--trash-dir).
+// Результат к этому моменту записан в БД как ok (и проверен vipsheader).
+// Файл, изменившийся после сканирования, не трогается: результат
+// сконвертирован из прежней версии.
+func (p *Pool) removeSource(file scanner.File) error {
+	info, err := os.Stat(file.Path)
+	if err != nil {
+		return fmt.Errorf("не удалось проверить исходник: %w", err)
+	}
+	if info.Size() != file.Info.Size || info.ModTime().Unix() != file.Info.Mtime {
+		return fmt.Errorf("исходник изменился после сканирования, оставлен на месте")
+	}
+
+	if p.cfg.TrashDir != "" {
+		dst := p.trashPath(file)
+		if err := moveFile(file.Path, dst); err != nil {
+			return fmt.Errorf("--trash-dir: %w", err)
+		}
+		atomic.AddInt64(&p.stats.Trashed, 1)
+		if p.verbose {
+			p.printMessage("🗑️  В корзину: %s -> %s\n", file.RelPath, dst)
+		}
+		return nil
+	}
+
+	if err := os.Remove(file.Path); err != nil {
+		return fmt.Errorf("--delete-source: не удалось удалить исходник: %w", err)
+	}
+	atomic.AddInt64(&p.stats.Deleted, 1)
+	if p.verbose {
+		p.printMessage("🗑️  Удалён исходник: %s\n", file.RelPath)
+	}
+	return nil
+}
+
+// trashPath возвращает путь исходника в корзине с сохранением относительного пути.
+func (p *Pool) trashPath(file scanner.File) string {
+	if file.RelPath == "" {
+		return filepath.Join(p.cfg.TrashDir, filepath.Base(file.Path))
+	}
+	return filepath.Join(p.cfg.TrashDir, file.RelPath)
+}
+
+// moveFile перемещает src в dst. Если переименование невозможно (например,
+// корзина на другой файловой системе), файл копируется с сохранением прав
+// и времени модификации, затем исходник удаляется.
+// Существующий dst не перезаписывается.
+func moveFile(src, dst string) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("%s уже существует", dst)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("не удалось создать директорию %s: %w", filepath.Dir(dst), err)
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := copyFile(src, dst); err != nil {
+		_ = os.Remove(dst)
+		return fmt.Errorf("не удалось скопировать %s -> %s: %w", src, dst, err)
+	}
+	_ = os.Chmod(dst, info.Mode().Perm())
+	_ = os.Chtimes(dst, info.ModTime(), info.ModTime())
+	if err := os.Remove(src); err != nil {
+		// Исходник остался на месте - копия в корзине не нужна
+		_ = os.Remove(dst)
+		return fmt.Errorf("не удалось удалить исходник: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPool_DeleteSource(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg", "broken.jpg")
+	cfg.DeleteSource = true
+	cfg.IUnderstand = true
+
+	stats := runPool(t, cfg, pool)
+	if stats.Processed != 1 || stats.Failed != 1 || stats.Deleted != 1 {
+		t.Fatalf("processed=%d failed=%d deleted=%d, want 1/1/1", stats.Processed, stats.Failed, stats.Deleted)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.InputDir, "a.jpg")); !os.IsNotExist(err) {
+		t.Errorf("исходник успешно сконвертированного файла не удалён: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.OutputDir, "a."+string(cfg.OutputFormat))); err != nil {
+		t.Errorf("результат отсутствует: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.InputDir, "broken.jpg")); err != nil {
+		t.Errorf("исходник с ошибкой конвертации должен сохраниться: %v", err)
+	}
+}
+
+func TestPool_DeleteSourceDryRun(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg")
+	cfg.DeleteSource = true
+	cfg.IUnderstand = true
+	cfg.DryRun = true
+
+	if stats := runPool(t, cfg, pool); stats.Deleted != 0 {
+		t.Errorf("dry-run: deleted=%d, want 0", stats.Deleted)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.InputDir, "a.jpg")); err != nil {
+		t.Errorf("dry-run не должен удалять исходник: %v", err)
+	}
+}
+
+func TestPool_TrashDir(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg", "broken.jpg")
+	cfg.TrashDir = filepath.Join(t.TempDir(), "trash")
+
+	sub := filepath.Join(cfg.InputDir, "2024", "may")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, "b.jpg"), []byte("image:b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := runPool(t, cfg, pool)
+	if stats.Processed != 2 || stats.Trashed != 2 || stats.Deleted != 0 {
+		t.Fatalf("processed=%d trashed=%d deleted=%d, want 2/2/0", stats.Processed, stats.Trashed, stats.Deleted)
+	}
+	for _, rel := range []string{"a.jpg", filepath.Join("2024", "may", "b.jpg")} {
+		if _, err := os.Stat(filepath.Join(cfg.InputDir, rel)); !os.IsNotExist(err) {
+			t.Errorf("%s остался во входной директории: %v", rel, err)
+		}
+		data, err := os.ReadFile(filepath.Join(cfg.TrashDir, rel))
+		if err != nil {
+			t.Errorf("%s нет в корзине: %v", rel, err)
+		} else if len(data) == 0 {
+			t.Errorf("%s в корзине пустой", rel)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cfg.InputDir, "broken.jpg")); err != nil {
+		t.Errorf("исходник с ошибкой конвертации должен остаться на месте: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.TrashDir, "broken.jpg")); !os.IsNotExist(err) {
+		t.Errorf("исходник с ошибкой попал в корзину: %v", err)
+	}
+}
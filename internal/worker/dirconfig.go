@@ -0,0 +1,174 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+)
+
+// dirConfigs находит для файла ближайший файл настроек поддиректории
+// (config.DirConfigName) между директорией файла и --in и кэширует
+// разобранные настройки и варианты выхода на весь запуск. mu защищает только
+// карты: поиск и чтение файлов настроек идут без блокировки, и воркеры не
+// ждут друг друга на os.Stat. Воркеры, одновременно встретившие новую
+// директорию, могут прочитать её настройки каждый, но в кэш попадает и
+// используется результат первого.
+type dirConfigs struct {
+	mu sync.Mutex
+
+	// inputOnce вычисляет inputIsFile один раз за запуск.
+	inputOnce   sync.Once
+	inputIsFile bool
+
+	// nearest - путь к ближайшему файлу настроек для директории ("" - нет).
+	nearest map[string]string
+
+	// variants - варианты выхода с настройками файла (или ошибка чтения).
+	variants map[string]dirVariants
+
+	// formats - варианты с форматом из --map по файлу настроек ("" - без
+	// него) и расширению исходника.
+	formats map[string][]variant
+}
+
+// dirVariants - варианты выхода пула с наложенными настройками поддиректории.
+type dirVariants struct {
+	variants []variant
+	err      error
+}
+
+// fileVariants возвращает варианты выхода для файла: варианты пула
+// с наложенными настройками ближайшей поддиректории (или сами варианты пула,
+// если настроек нет) и форматом по расширению исходника из --map.
+func (p *Pool) fileVariants(file scanner.File) ([]variant, error) {
+	path := p.dirConfigPath(file)
+	variants, err := p.dirVariants(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ext := strings.ToLower(filepath.Ext(file.Path))
+	if !hasFormatMap(variants) || ext == "" {
+		return variants, nil
+	}
+	key := path + "\x00" + ext
+	p.dirConfigs.mu.Lock()
+	cached, ok := p.dirConfigs.formats[key]
+	p.dirConfigs.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+	mapped := make([]variant, 0, len(variants))
+	for _, v := range variants {
+		if vcfg := v.cfg.WithFormatFor(file.Path); vcfg != v.cfg {
+			v = variant{cfg: vcfg, converter: v.converter.WithConfig(vcfg)}
+		}
+		mapped = append(mapped, v)
+	}
+	p.dirConfigs.mu.Lock()
+	defer p.dirConfigs.mu.Unlock()
+	// Другой воркер мог вычислить варианты раньше - возвращаем его результат
+	if cached, ok := p.dirConfigs.formats[key]; ok {
+		return cached, nil
+	}
+	if p.dirConfigs.formats == nil {
+		p.dirConfigs.formats = make(map[string][]variant)
+	}
+	p.dirConfigs.formats[key] = mapped
+	return mapped, nil
+}
+
+// hasFormatMap сообщает, задана ли карта форматов (--map) хотя бы в одном варианте.
+func hasFormatMap(variants []variant) bool {
+	for _, v := range variants {
+		if len(v.cfg.FormatMap) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// dirConfigPath возвращает путь к ближайшему файлу настроек поддиректории
+// для файла или "", если настроек нет (или файл вне --in: архив,
+// --from-file вне --in).
+func (p *Pool) dirConfigPath(file scanner.File) string {
+	p.dirConfigs.inputOnce.Do(func() { p.dirConfigs.inputIsFile = p.cfg.InputIsFile() })
+	if p.cfg.NoDirConfig || p.dirConfigs.inputIsFile || !config.IsSubPath(p.cfg.InputDir, file.Path) {
+		return ""
+	}
+	return p.nearestDirConfig(filepath.Dir(file.Path))
+}
+
+// dirVariants возвращает варианты пула с наложенными настройками из файла
+// path (для "" - сами варианты пула).
+func (p *Pool) dirVariants(path string) ([]variant, error) {
+	if path == "" {
+		return p.variants, nil
+	}
+	p.dirConfigs.mu.Lock()
+	cached, ok := p.dirConfigs.variants[path]
+	p.dirConfigs.mu.Unlock()
+	if ok {
+		return cached.variants, cached.err
+	}
+
+	var res dirVariants
+	fc, err := config.LoadFromFile(path)
+	if err != nil {
+		res.err = err
+	} else {
+		for _, v := range p.variants {
+			vcfg, err := v.cfg.WithDirOverride(fc)
+			if err != nil {
+				res.err = err
+				break
+			}
+			res.variants = append(res.variants, variant{cfg: vcfg, converter: v.converter.WithConfig(vcfg)})
+		}
+	}
+	if res.err != nil {
+		res.variants = nil
+	}
+
+	p.dirConfigs.mu.Lock()
+	defer p.dirConfigs.mu.Unlock()
+	if cached, ok := p.dirConfigs.variants[path]; ok {
+		return cached.variants, cached.err
+	}
+	if p.dirConfigs.variants == nil {
+		p.dirConfigs.variants = make(map[string]dirVariants)
+	}
+	p.dirConfigs.variants[path] = res
+	return res.variants, res.err
+}
+
+// nearestDirConfig возвращает путь к ближайшему файлу настроек от dir вверх
+// до --in включительно.
+func (p *Pool) nearestDirConfig(dir string) string {
+	p.dirConfigs.mu.Lock()
+	path, ok := p.dirConfigs.nearest[dir]
+	p.dirConfigs.mu.Unlock()
+	if ok {
+		return path
+	}
+
+	path = ""
+	candidate := filepath.Join(dir, config.DirConfigName)
+	if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+		path = candidate
+	} else if parent := filepath.Dir(dir); parent != dir && config.IsSubPath(p.cfg.InputDir, parent) {
+		path = p.nearestDirConfig(parent)
+	}
+
+	p.dirConfigs.mu.Lock()
+	defer p.dirConfigs.mu.Unlock()
+	if p.dirConfigs.nearest == nil {
+		p.dirConfigs.nearest = make(map[string]string)
+	}
+	p.dirConfigs.nearest[dir] = path
+	return path
+}
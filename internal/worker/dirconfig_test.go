@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+func TestPool_DirConfigOverride(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg")
+	writeInput := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(cfg.InputDir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// album/ и вложенная album/raw/ - png; broken/ - некорректные настройки
+	writeInput("album/b.jpg", "image:b")
+	writeInput("album/raw/c.jpg", "image:c")
+	writeInput("album/"+config.DirConfigName, "output:\n  format: png\n  dir: /elsewhere\n")
+	writeInput("broken/d.jpg", "image:d")
+	writeInput("broken/"+config.DirConfigName, "output:\n  format: bmp\n")
+
+	stats := runPool(t, cfg, pool)
+	if stats.Processed != 3 || stats.Failed != 1 {
+		t.Fatalf("processed=%d failed=%d, want 3/1", stats.Processed, stats.Failed)
+	}
+	for _, rel := range []string{"a.jpg", "album/b.png", "album/raw/c.png"} {
+		if _, err := os.Stat(filepath.Join(cfg.OutputDir, rel)); err != nil {
+			t.Errorf("результат %s: %v", rel, err)
+		}
+	}
+	// Параметры vips - по формату поддиректории
+	data, err := os.ReadFile(filepath.Join(cfg.OutputDir, "album", "b.png"))
+	if err != nil || !strings.Contains(string(data), "b.converting.png") {
+		t.Errorf("результат b.png = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.OutputDir, "album", "b.jpg")); err == nil {
+		t.Error("файл из album/ сконвертирован в формат --out-format")
+	}
+
+	// Повторный запуск: задачи с настройками поддиректории найдены в БД
+	stats = runPool(t, cfg, pool)
+	if stats.Processed != 3 || stats.Skipped != 3 {
+		t.Errorf("повторный запуск: processed=%d skipped=%d, want 3/3 (счётчики накапливаются)", stats.Processed, stats.Skipped)
+	}
+
+	// --no-dir-config: файлы album/ конвертируются по общим настройкам
+	cfg.NoDirConfig = true
+	runPool(t, cfg, pool)
+	if _, err := os.Stat(filepath.Join(cfg.OutputDir, "album", "b.jpg")); err != nil {
+		t.Errorf("с --no-dir-config результат b.jpg: %v", err)
+	}
+}
+
+func TestPool_FormatMap(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.heic", "b.png", "c.jpg", "d.PNG")
+	cfg.OutputFormat = config.FormatAVIF
+	formatMap, err := config.ParseFormatMap([]string{"heic=jpg", ".png=WebP"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.FormatMap = formatMap
+
+	stats := runPool(t, cfg, pool)
+	if stats.Processed != 4 || stats.Failed != 0 {
+		t.Fatalf("processed=%d failed=%d, want 4/0", stats.Processed, stats.Failed)
+	}
+	// Несопоставленное расширение - в формат --out-format
+	for _, rel := range []string{"a.jpg", "b.webp", "c.avif", "d.webp"} {
+		data, err := os.ReadFile(filepath.Join(cfg.OutputDir, rel))
+		if err != nil {
+			t.Errorf("результат %s: %v", rel, err)
+			continue
+		}
+		// Параметры vips - по формату исходника
+		ext := filepath.Ext(rel)
+		if !strings.Contains(string(data), ".converting"+ext) {
+			t.Errorf("результат %s = %q", rel, data)
+		}
+	}
+
+	// Повторный запуск: задачи с форматом из --map найдены в БД
+	stats = runPool(t, cfg, pool)
+	if stats.Skipped != 4 {
+		t.Errorf("повторный запуск: skipped=%d, want 4", stats.Skipped)
+	}
+}
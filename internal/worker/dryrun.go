@@ -0,0 +1,178 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/runlog"
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+	"github.com/artemshloyda/photoconverter/internal/storage"
+)
+
+// PlanCategory - категория файла в плане dry-run.
+type PlanCategory string
+
+const (
+	// PlanNew - файл ещё не обрабатывался.
+	PlanNew PlanCategory = "NEW"
+	// PlanSkip - файл уже сконвертирован (или дубликат по содержимому).
+	PlanSkip PlanCategory = "SKIP"
+	// PlanRetry - прошлая попытка завершилась ошибкой или результат удалён
+	// либо изменён после конвертации (--verify-outputs).
+	PlanRetry PlanCategory = "RETRY"
+	// PlanOverwrite - выходной файл существует, но не записан в БД
+	// (с --on-conflict skip такой файл - SKIP, с rename - NEW под новым именем).
+	PlanOverwrite PlanCategory = "OVERWRITE"
+)
+
+// PlanStats - итоги dry-run по категориям.
+type PlanStats struct {
+	New       int64
+	Skip      int64
+	Retry     int64
+	Overwrite int64
+
+	// CacheHits - файлы NEW, RETRY и OVERWRITE, результат которых
+	// есть в кэше (--cache): они будут скопированы без конвертации.
+	CacheHits int64
+}
+
+// Conversions возвращает число файлов плана, которые придётся конвертировать.
+func (s PlanStats) Conversions() int64 {
+	return s.New + s.Retry + s.Overwrite - s.CacheHits
+}
+
+// PlannedJobs возвращает файлы, которые были бы сконвертированы в dry-run
+// (в формате задач БД, для манифеста).
+func (p *Pool) PlannedJobs() []storage.Job {
+	p.planMu.Lock()
+	defer p.planMu.Unlock()
+	return append([]storage.Job(nil), p.planned...)
+}
+
+// planVariant классифицирует файл в dry-run, не изменяя БД.
+func (p *Pool) planVariant(ctx context.Context, file scanner.File, v variant) {
+	outFormat, paramsHash := string(v.cfg.OutputFormat), v.cfg.OutputParamsHash()
+	dedup := v.cfg.Mode == config.ModeDedup
+
+	result, err := p.storage.PlanJob(file.Info, outFormat, paramsHash, dedup)
+	if err != nil {
+		err = fmt.Errorf("ошибка БД: %w", err)
+		p.logError(file.Path, err)
+		p.writeRunLog(runlog.Entry{Status: runlog.StatusFailed, Src: file.Info.Path, Error: err.Error()})
+		p.addFailed(1)
+		return
+	}
+
+	dstPath := p.dstPath(ctx, file, v)
+
+	category := PlanNew
+	switch {
+	case !result.Started && v.cfg.VerifyOutputs && outputChanged(result):
+		// --verify-outputs: результат удалён или изменён после конвертации
+		category = PlanRetry
+	case !result.Started:
+		category = PlanSkip
+	case dedup && !p.markPlannedContent(file.Info.ContentSHA256+"|"+outFormat+"|"+paramsHash):
+		// Дубликат файла, который уже попал в план этого запуска
+		category = PlanSkip
+		result.SkipReason = "дубликат по содержимому"
+	case result.Retry:
+		category = PlanRetry
+	default:
+		if _, err := os.Stat(dstPath); err == nil {
+			conflict := (v.cfg.OnConflict == config.OnConflictSkip || v.cfg.OnConflict == config.OnConflictRename) &&
+				p.untrackedOutput(file, dstPath, 0)
+			switch {
+			case v.cfg.SkipExistingOutput || conflict && v.cfg.OnConflict == config.OnConflictSkip:
+				category = PlanSkip
+				result.SkipReason = SkipReasonOutputExists
+			case conflict:
+				// Новый файл рядом с существующим
+				dstPath = conflictFreePath(dstPath)
+			default:
+				category = PlanOverwrite
+			}
+		}
+	}
+
+	if category == PlanSkip {
+		if p.verbose {
+			p.printMessage("⏭️  [dry-run] %-9s %s (%s)\n", category, file.RelPath, result.SkipReason)
+		}
+		p.writeRunLog(runlog.Entry{Status: runlog.StatusSkipped, Src: file.Info.Path, Reason: result.SkipReason})
+		if p.progress != nil {
+			p.progress.IncrementSkipped(file.RelPath, file.Info.Size)
+		}
+		atomic.AddInt64(&p.stats.Plan.Skip, 1)
+		atomic.AddInt64(&p.stats.Skipped, 1)
+		return
+	}
+
+	cached := p.cached(file, v)
+	if cached {
+		p.printMessage("🔄 [dry-run] %-9s %s -> %s (из кэша)\n", category, file.RelPath, dstPath)
+		atomic.AddInt64(&p.stats.Plan.CacheHits, 1)
+	} else {
+		p.printMessage("🔄 [dry-run] %-9s %s -> %s\n", category, file.RelPath, dstPath)
+	}
+	p.writeRunLog(runlog.Entry{Status: runlog.StatusDryRun, Src: file.Info.Path, Dst: dstPath})
+
+	p.planMu.Lock()
+	p.planned = append(p.planned, storage.Job{
+		SrcPath: file.Info.Path,
+		SrcSize: file.Info.Size,
+		DstPath: &dstPath,
+		Status:  storage.StatusOK,
+	})
+	p.planMu.Unlock()
+
+	switch category {
+	case PlanRetry:
+		atomic.AddInt64(&p.stats.Plan.Retry, 1)
+	case PlanOverwrite:
+		atomic.AddInt64(&p.stats.Plan.Overwrite, 1)
+	default:
+		atomic.AddInt64(&p.stats.Plan.New, 1)
+	}
+	if p.progress != nil {
+		p.progress.Increment(file.RelPath, file.Info.Size)
+	}
+	atomic.AddInt64(&p.stats.Processed, 1)
+}
+
+// markPlannedContent запоминает ключ содержимого в плане dry-run.
+// Возвращает false, если ключ уже был в плане.
+func (p *Pool) markPlannedContent(key string) bool {
+	p.planMu.Lock()
+	defer p.planMu.Unlock()
+	if p.plannedContent == nil {
+		p.plannedContent = make(map[string]bool)
+	}
+	if p.plannedContent[key] {
+		return false
+	}
+	p.plannedContent[key] = true
+	return true
+}
+
+// printMessage выводит сообщение, не ломая прогресс-бар (с --quiet - ничего).
+func (p *Pool) printMessage(format string, args ...any) {
+	if p.quiet {
+		return
+	}
+	if p.progress != nil && !p.progress.IsDisabled() {
+		p.progress.WriteMessage(format, args...)
+		return
+	}
+	fmt.Printf(format, args...)
+}
+
+/*
+Возможные расширения:
+- Оценка размера результатов по средней степени сжатия прошлых запусков
+- Вывод плана в JSON
+*/
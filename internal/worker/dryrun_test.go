@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+	"github.com/artemshloyda/photoconverter/internal/storage"
+)
+
+func TestPool_DryRunPlan(t *testing.T) {
+	cfg, pool := newTestEnv(t, "done.jpg", "failed.jpg", "new.jpg", "untracked.jpg")
+	st := pool.storage
+
+	// Информация о файлах в том виде, в каком её видит пул
+	infos := map[string]storage.FileInfo{}
+	files, _ := scanner.New(cfg).Scan(context.Background())
+	for f := range files {
+		infos[filepath.Base(f.Path)] = f.Info
+	}
+
+	outFormat, params, hash := string(cfg.OutputFormat), cfg.OutputParams(), cfg.OutputParamsHash()
+	start := func(name string) int64 {
+		res, err := st.TryStartJob(infos[name], outFormat, params, hash, false)
+		if err != nil || !res.Started {
+			t.Fatalf("TryStartJob(%s): %+v, %v", name, res, err)
+		}
+		return res.JobID
+	}
+	if err := st.FinalizeJobOK(start("done.jpg"), pool.converter.BuildDstPathRel("done.jpg"), 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.FinalizeJobFailed(start("failed.jpg"), "vips error"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Результат есть на диске, но не в БД
+	untracked := pool.converter.BuildDstPathRel("untracked.jpg")
+	if err := os.MkdirAll(filepath.Dir(untracked), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(untracked, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.DryRun = true
+	stats := runPool(t, cfg, pool)
+
+	want := PlanStats{New: 1, Skip: 1, Retry: 1, Overwrite: 1}
+	if stats.Plan != want {
+		t.Errorf("plan = %+v, want %+v", stats.Plan, want)
+	}
+	if stats.Processed != 3 || stats.Skipped != 1 {
+		t.Errorf("processed=%d skipped=%d, want 3/1", stats.Processed, stats.Skipped)
+	}
+	if n := len(pool.PlannedJobs()); n != 3 {
+		t.Errorf("PlannedJobs = %d, want 3", n)
+	}
+
+	// Dry-run не меняет БД: обычный запуск обрабатывает те же три файла
+	cfg.DryRun = false
+	stats = runPool(t, cfg, New(cfg, st, pool.converter))
+	if stats.Processed != 3 || stats.Skipped != 1 || stats.Failed != 0 {
+		t.Errorf("после dry-run: processed=%d skipped=%d failed=%d, want 3/1/0",
+			stats.Processed, stats.Skipped, stats.Failed)
+	}
+}
@@ -0,0 +1,134 @@
+package worker
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Ограничения сводки ошибок (--report-failures): память не растёт
+// с числом ошибок, считаются все, но примеры и причины ограничены.
+const (
+	// maxFailureGroups - число различных причин; остальные попадают
+	// в группу FailureReasonOther.
+	maxFailureGroups = 32
+
+	// maxFailureExamples - число примеров файлов в группе.
+	maxFailureExamples = 5
+
+	// maxFailureReasonLen - длина причины в символах.
+	maxFailureReasonLen = 80
+)
+
+// FailureReasonOther - группа ошибок сверх maxFailureGroups причин.
+const FailureReasonOther = "другие ошибки"
+
+// FailureGroup - ошибки с одной причиной для сводки --report-failures.
+type FailureGroup struct {
+	// Reason - причина: категория ошибки или начало сообщения.
+	Reason string
+
+	// Count - число ошибок с этой причиной.
+	Count int64
+
+	// Examples - первые файлы с этой причиной (не больше maxFailureExamples)
+	// и краткие сообщения ошибок.
+	Examples []FailureExample
+}
+
+// FailureExample - файл с ошибкой в сводке.
+type FailureExample struct {
+	Path  string
+	Error string
+}
+
+// failureReport накапливает ошибки запуска по причинам.
+type failureReport struct {
+	mu     sync.Mutex
+	groups map[string]*FailureGroup
+}
+
+// add учитывает ошибку файла path с сообщением msg.
+func (r *failureReport) add(path, msg string) {
+	msg = shortError(msg)
+	reason := FailureReason(msg)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.groups == nil {
+		r.groups = make(map[string]*FailureGroup)
+	}
+	g, ok := r.groups[reason]
+	if !ok {
+		if len(r.groups) >= maxFailureGroups {
+			reason = FailureReasonOther
+			g = r.groups[reason]
+		}
+		if g == nil {
+			g = &FailureGroup{Reason: reason}
+			r.groups[reason] = g
+		}
+	}
+	g.Count++
+	if len(g.Examples) < maxFailureExamples {
+		g.Examples = append(g.Examples, FailureExample{Path: path, Error: msg})
+	}
+}
+
+// list возвращает группы по убыванию числа ошибок.
+func (r *failureReport) list() []FailureGroup {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	groups := make([]FailureGroup, 0, len(r.groups))
+	for _, g := range r.groups {
+		c := *g
+		c.Examples = slices.Clone(g.Examples)
+		groups = append(groups, c)
+	}
+	slices.SortFunc(groups, func(a, b FailureGroup) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Reason, b.Reason)
+	})
+	return groups
+}
+
+// shortError убирает из сообщения ошибки командную строку (добавляется
+// к ошибкам vips и ImageMagick) и переносы строк stderr.
+func shortError(msg string) string {
+	msg, _, _ = strings.Cut(msg, "; команда: ")
+	return strings.Join(strings.Fields(msg), " ")
+}
+
+// FailureReason определяет причину ошибки для группировки: известную
+// категорию (неподдерживаемый формат, таймаут, нехватка памяти, ошибка БД)
+// или начало сообщения до первого ": ".
+func FailureReason(msg string) string {
+	lower := strings.ToLower(msg)
+	switch {
+	case strings.Contains(lower, "not a known file format"), strings.Contains(lower, "декодер raw не найден"):
+		return "неподдерживаемый формат"
+	case strings.Contains(lower, "timed out"):
+		return "таймаут"
+	case strings.Contains(lower, "out of memory"):
+		return "нехватка памяти"
+	case strings.HasPrefix(lower, "ошибка бд"), strings.HasPrefix(lower, "не удалось обновить бд"):
+		return "ошибка БД"
+	}
+
+	reason, _, _ := strings.Cut(shortError(msg), ": ")
+	if r := []rune(reason); len(r) > maxFailureReasonLen {
+		reason = string(r[:maxFailureReasonLen]) + "…"
+	}
+	return reason
+}
+
+// recordFailure учитывает ошибку файла в сводке (--report-failures).
+func (p *Pool) recordFailure(path, msg string) {
+	if p.cfg.ReportFailures {
+		p.failures.add(path, msg)
+	}
+}
@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/converter"
+)
+
+// failingConverter - stubConverter, который ошибается по имени исходника:
+// "corrupt" - неподдерживаемый формат, "slow" - таймаут, "odd" - прочая ошибка.
+type failingConverter struct {
+	*stubConverter
+}
+
+func (f failingConverter) Convert(ctx context.Context, srcPath, dstPath string) *converter.ConvertResult {
+	name := filepath.Base(srcPath)
+	switch {
+	case strings.HasPrefix(name, "corrupt"):
+		return &converter.ConvertResult{Error: errors.New("vips copy failed: exit status 1: VipsForeignLoad: \"" + name + "\" is not a known file format; команда: vips copy " + srcPath)}
+	case strings.HasPrefix(name, "slow"):
+		return &converter.ConvertResult{Error: errors.New("vips не уложился в таймаут 5m0s (timed out)")}
+	case strings.HasPrefix(name, "odd"):
+		return &converter.ConvertResult{Error: errors.New("exiftool failed: exit status 2:\nwarning")}
+	}
+	return f.stubConverter.Convert(ctx, srcPath, dstPath)
+}
+
+func TestPool_ReportFailures(t *testing.T) {
+	names := []string{"ok1.jpg", "ok2.jpg", "slow1.jpg", "odd.jpg"}
+	for _, n := range []string{"1", "2", "3", "4", "5", "6", "7"} {
+		names = append(names, "corrupt"+n+".jpg")
+	}
+	cfg, pool, stub := newStubEnv(t, 0, names...)
+	cfg.ReportFailures = true
+	pool = New(cfg, pool.storage, failingConverter{stub})
+
+	stats := runPool(t, cfg, pool)
+	if stats.Processed != 2 || stats.Failed != 9 {
+		t.Fatalf("processed=%d failed=%d, want 2/9", stats.Processed, stats.Failed)
+	}
+
+	want := []struct {
+		reason string
+		count  int64
+	}{
+		{"неподдерживаемый формат", 7},
+		{"exiftool failed", 1},
+		{"таймаут", 1},
+	}
+	if len(stats.Failures) != len(want) {
+		t.Fatalf("Failures = %+v, want %d групп", stats.Failures, len(want))
+	}
+	for i, w := range want {
+		g := stats.Failures[i]
+		if g.Reason != w.reason || g.Count != w.count {
+			t.Errorf("группа %d = %q/%d, want %q/%d", i, g.Reason, g.Count, w.reason, w.count)
+		}
+	}
+
+	// Примеры ограничены, команда и переносы строк убраны из сообщений
+	corrupt := stats.Failures[0]
+	if len(corrupt.Examples) != maxFailureExamples {
+		t.Errorf("примеров %d, want %d", len(corrupt.Examples), maxFailureExamples)
+	}
+	for _, e := range append(corrupt.Examples, stats.Failures[1].Examples...) {
+		if strings.Contains(e.Error, "команда") || strings.Contains(e.Error, "\n") || e.Path == "" {
+			t.Errorf("пример %+v", e)
+		}
+	}
+}
+
+func TestFailureReport_Bounded(t *testing.T) {
+	var r failureReport
+	for i := 0; i < maxFailureGroups+10; i++ {
+		r.add("/in/a.jpg", "ошибка "+strings.Repeat("x", i+1)+": подробности")
+	}
+	groups := r.list()
+	if len(groups) != maxFailureGroups+1 {
+		t.Fatalf("групп %d, want %d", len(groups), maxFailureGroups+1)
+	}
+	if groups[0].Reason != FailureReasonOther || groups[0].Count != 10 {
+		t.Errorf("первая группа = %q/%d, want %q/10", groups[0].Reason, groups[0].Count, FailureReasonOther)
+	}
+
+	if reason := FailureReason("ошибка БД: database is locked"); reason != "ошибка БД" {
+		t.Errorf("FailureReason = %q", reason)
+	}
+	if reason := FailureReason(strings.Repeat("я", 200)); len([]rune(reason)) != maxFailureReasonLen+1 {
+		t.Errorf("длинная причина не обрезана: %d символов", len([]rune(reason)))
+	}
+}
@@ -0,0 +1,40 @@
+package worker
+
+import (
+	"context"
+	"sync"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+)
+
+// formatLimiter ограничивает число одновременных конвертаций по выходному
+// формату (--concurrency-per-format): для каждого ограниченного формата -
+// семафор на заданное число конвертаций. Воркер, ждущий семафор, занят
+// и не берёт другие файлы, поэтому общее число конвертаций не превышает Workers.
+type formatLimiter struct {
+	once sync.Once
+	sems map[config.OutputFormat]chan struct{}
+}
+
+// acquireFormat занимает место конвертации в формат format. Блокирует,
+// пока заняты все места формата; без ограничения формата не ждёт.
+// Возвращает функцию освобождения места.
+func (p *Pool) acquireFormat(ctx context.Context, format config.OutputFormat) (release func(), err error) {
+	p.formatLimits.once.Do(func() {
+		p.formatLimits.sems = make(map[config.OutputFormat]chan struct{}, len(p.cfg.ConcurrencyPerFormat))
+		for f, n := range p.cfg.ConcurrencyPerFormat {
+			p.formatLimits.sems[f] = make(chan struct{}, n)
+		}
+	})
+
+	sem, ok := p.formatLimits.sems[format]
+	if !ok {
+		return func() {}, nil
+	}
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/converter"
+)
+
+// concurrencyTracker считает одновременные конвертации по выходному формату.
+type concurrencyTracker struct {
+	mu       sync.Mutex
+	inFlight map[string]int
+	peak     map[string]int
+}
+
+// trackingConverter - stubConverter, который конвертирует delay и отмечает
+// в tracker одновременные конвертации по расширению результата.
+type trackingConverter struct {
+	*stubConverter
+	delay   time.Duration
+	tracker *concurrencyTracker
+}
+
+func (c trackingConverter) Convert(ctx context.Context, srcPath, dstPath string) *converter.ConvertResult {
+	format := strings.TrimPrefix(filepath.Ext(dstPath), ".")
+	t := c.tracker
+	t.mu.Lock()
+	t.inFlight[format]++
+	t.peak[format] = max(t.peak[format], t.inFlight[format])
+	t.mu.Unlock()
+
+	time.Sleep(c.delay)
+
+	t.mu.Lock()
+	t.inFlight[format]--
+	t.mu.Unlock()
+	return c.stubConverter.Convert(ctx, srcPath, dstPath)
+}
+
+// WithConfig сохраняет общий tracker у конвертеров форматов из --map.
+func (c trackingConverter) WithConfig(cfg *config.Config) converter.Converter {
+	return trackingConverter{c.stubConverter.WithConfig(cfg).(*stubConverter), c.delay, c.tracker}
+}
+
+// newTrackingEnv создаёт пул с trackingConverter и workers воркерами.
+func newTrackingEnv(t *testing.T, workers int, names ...string) (*config.Config, *Pool, *concurrencyTracker) {
+	t.Helper()
+	cfg, pool, stub := newStubEnv(t, 0, names...)
+	cfg.Workers = workers
+	tracker := &concurrencyTracker{inFlight: map[string]int{}, peak: map[string]int{}}
+	return cfg, New(cfg, pool.storage, trackingConverter{stub, 50 * time.Millisecond, tracker}), tracker
+}
+
+func TestPool_ConcurrencyPerFormat(t *testing.T) {
+	const workers, avifLimit = 4, 1
+
+	var names []string
+	for i := 0; i < 6; i++ {
+		names = append(names, "a"+strconv.Itoa(i)+".png", "j"+strconv.Itoa(i)+".jpg")
+	}
+	cfg, pool, tracker := newTrackingEnv(t, workers, names...)
+	cfg.FormatMap = map[string]config.OutputFormat{"png": config.FormatAVIF}
+	cfg.ConcurrencyPerFormat = map[config.OutputFormat]int{config.FormatAVIF: avifLimit}
+
+	stats := runPool(t, cfg, pool)
+	if stats.Processed != int64(len(names)) {
+		t.Fatalf("processed=%d, want %d", stats.Processed, len(names))
+	}
+	if peak := tracker.peak["avif"]; peak != avifLimit {
+		t.Errorf("одновременных конвертаций в avif = %d, want %d", peak, avifLimit)
+	}
+
+	// Формат без ограничения использует все воркеры
+	jpegs := make([]string, 2*workers)
+	for i := range jpegs {
+		jpegs[i] = "j" + strconv.Itoa(i) + ".jpg"
+	}
+	cfg, pool, tracker = newTrackingEnv(t, workers, jpegs...)
+	cfg.ConcurrencyPerFormat = map[config.OutputFormat]int{config.FormatAVIF: avifLimit}
+
+	if stats := runPool(t, cfg, pool); stats.Processed != int64(len(jpegs)) {
+		t.Fatalf("processed=%d, want %d", stats.Processed, len(jpegs))
+	}
+	if peak := tracker.peak[string(config.FormatJPEG)]; peak != workers {
+		t.Errorf("одновременных конвертаций в jpg = %d, want %d", peak, workers)
+	}
+}
@@ -0,0 +1,40 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+)
+
+// SkipReasonIgnored - причина пропуска исходника, помеченного игнорируемым (--ignore-file).
+const SkipReasonIgnored = "ignored"
+
+// ignoredSources - игнорируемые исходники из БД, читаются один раз за пул.
+type ignoredSources struct {
+	once  sync.Once
+	paths map[string]bool
+}
+
+// isIgnored проверяет, помечен ли исходник игнорируемым. Если пометки
+// не удалось прочитать, файлы обрабатываются как обычно.
+func (p *Pool) isIgnored(file scanner.File) bool {
+	p.ignored.once.Do(func() {
+		paths, err := p.storage.IgnoredSources()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+		}
+		p.ignored.paths = paths
+	})
+	return p.ignored.paths[file.Info.Path]
+}
+
+// skipIgnored учитывает игнорируемый исходник пропущенным во всех вариантах.
+func (p *Pool) skipIgnored(file scanner.File) {
+	for range p.variants {
+		atomic.AddInt64(&p.stats.Total, 1)
+		p.addSkipped(file, SkipReasonIgnored)
+	}
+}
@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPool_IgnoredSources(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg", "corrupt.jpg")
+	corrupt := filepath.Join(cfg.InputDir, "corrupt.jpg")
+	dst := filepath.Join(cfg.OutputDir, "corrupt."+string(cfg.OutputFormat))
+
+	stats := runPool(t, cfg, pool)
+	if stats.Processed != 2 {
+		t.Fatalf("processed=%d, want 2", stats.Processed)
+	}
+
+	// Исходник изменился, но помечен игнорируемым: следующий запуск его не трогает
+	if err := os.WriteFile(corrupt, []byte("image:changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(dst); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.storage.IgnoreSources([]string{corrupt}); err != nil {
+		t.Fatal(err)
+	}
+	pool = New(cfg, pool.storage, pool.converter)
+	stats = runPool(t, cfg, pool)
+	if stats.Processed != 0 || stats.Skipped != 2 {
+		t.Fatalf("processed=%d skipped=%d, want 0/2", stats.Processed, stats.Skipped)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("игнорируемый исходник сконвертирован: %v", err)
+	}
+
+	// После снятия пометок исходник снова конвертируется
+	if _, err := pool.storage.ClearIgnored(); err != nil {
+		t.Fatal(err)
+	}
+	stats = runPool(t, cfg, New(cfg, pool.storage, pool.converter))
+	if stats.Processed != 1 {
+		t.Errorf("после ClearIgnored processed=%d, want 1", stats.Processed)
+	}
+	if _, err := os.Stat(dst); err != nil {
+		t.Errorf("результат после снятия пометки: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/converter"
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+)
+
+// SkipReasonTooLarge - причина пропуска исходника больше --max-megapixels.
+const SkipReasonTooLarge = "too many megapixels"
+
+// oomRetryMegapixels - размер в мегапикселях, до которого уменьшается
+// изображение при повторе после нехватки памяти (--no-oom-retry отключает).
+const oomRetryMegapixels = 50
+
+// fitMegapixels проверяет размер исходника по --max-megapixels до загрузки
+// изображения. Возвращает конвертер для файла: для исходника в пределах
+// лимита - конвертер варианта, для большего с downscale - конвертер,
+// уменьшающий изображение до лимита. skip - файл нужно пропустить.
+// Если размер не удалось определить, файл конвертируется без проверки.
+func (p *Pool) fitMegapixels(ctx context.Context, file scanner.File, v variant) (conv converter.Converter, skip bool) {
+	width, height, err := v.converter.ImageSize(ctx, file.Path)
+	if err != nil {
+		if p.verbose {
+			p.printMessage("⚠️  %s: размер не определён, --max-megapixels не проверяется: %v\n", file.RelPath, err)
+		}
+		return v.converter, false
+	}
+
+	limit := v.cfg.MaxMegapixels * 1e6
+	pixels := float64(width) * float64(height)
+	if pixels <= limit {
+		return v.converter, false
+	}
+
+	if v.cfg.MegapixelsAction != config.MegapixelsDownscale {
+		p.addSkipped(file, fmt.Sprintf("%s: %.1f MP", SkipReasonTooLarge, pixels/1e6))
+		return nil, true
+	}
+
+	fitCfg := fitPixels(v.cfg, width, height, limit)
+	if p.verbose {
+		p.printMessage("📐 %s: %dx%d (%.1f MP) уменьшается до %dx%d\n",
+			file.RelPath, width, height, pixels/1e6, fitCfg.MaxWidth, fitCfg.MaxHeight)
+	}
+	return v.converter.WithConfig(fitCfg), false
+}
+
+// fitPixels возвращает копию cfg, уменьшающую изображение width x height
+// до limit пикселей с сохранением пропорций; заданные меньшие размеры остаются.
+func fitPixels(cfg *config.Config, width, height int, limit float64) *config.Config {
+	scale := math.Sqrt(limit / (float64(width) * float64(height)))
+	fitWidth := max(int(float64(width)*scale), 1)
+	fitHeight := max(int(float64(height)*scale), 1)
+	fitCfg := *cfg
+	if fitCfg.MaxWidth == 0 || fitWidth < fitCfg.MaxWidth {
+		fitCfg.MaxWidth = fitWidth
+	}
+	if fitCfg.MaxHeight == 0 || fitHeight < fitCfg.MaxHeight {
+		fitCfg.MaxHeight = fitHeight
+	}
+	return &fitCfg
+}
+
+// retryDownscaled повторяет конвертацию файла, которому не хватило памяти,
+// с уменьшением до oomRetryMegapixels (и не больше половины пикселей
+// исходника). Возвращает nil, если размер не определён или уменьшать некуда.
+func (p *Pool) retryDownscaled(ctx context.Context, file scanner.File, v variant, dstPath string) *converter.ConvertResult {
+	width, height, err := v.converter.ImageSize(ctx, file.Path)
+	if err != nil {
+		return nil
+	}
+
+	pixels := float64(width) * float64(height)
+	limit := min(oomRetryMegapixels*1e6, pixels/2)
+	if v.cfg.MaxMegapixels > 0 && v.cfg.MegapixelsAction == config.MegapixelsDownscale {
+		limit = min(limit, v.cfg.MaxMegapixels*1e6)
+	}
+	fitCfg := fitPixels(v.cfg, width, height, limit)
+	if fitCfg.MaxWidth == v.cfg.MaxWidth && fitCfg.MaxHeight == v.cfg.MaxHeight {
+		return nil
+	}
+
+	p.printMessage("📐 %s: не хватило памяти, повтор с уменьшением %dx%d -> %dx%d\n",
+		file.RelPath, width, height, fitCfg.MaxWidth, fitCfg.MaxHeight)
+	res := v.converter.WithConfig(fitCfg).Convert(ctx, file.Path, dstPath)
+	if !res.Success {
+		res.Error = fmt.Errorf("не хватило памяти и после уменьшения до %dx%d: %w", fitCfg.MaxWidth, fitCfg.MaxHeight, res.Error)
+	}
+	return res
+}
@@ -0,0 +1,108 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/runlog"
+)
+
+func TestPool_MaxMegapixelsSkip(t *testing.T) {
+	cfg, pool := newTestEnv(t, "small.jpg", "huge.jpg")
+	cfg.MaxMegapixels = 100
+	logPath := filepath.Join(t.TempDir(), "run.jsonl")
+	logger, err := runlog.Open(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.SetRunLog(logger)
+
+	stats := runPool(t, cfg, pool)
+	_ = logger.Close()
+	if stats.Processed != 1 || stats.Skipped != 1 {
+		t.Fatalf("processed=%d skipped=%d, want 1/1", stats.Processed, stats.Skipped)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.OutputDir, "huge."+string(cfg.OutputFormat))); !os.IsNotExist(err) {
+		t.Errorf("большой исходник не должен конвертироваться: %v", err)
+	}
+
+	var reason string
+	for _, e := range readRunLog(t, logPath) {
+		if e.Status == runlog.StatusSkipped {
+			reason = e.Reason
+		}
+	}
+	if !strings.HasPrefix(reason, SkipReasonTooLarge) || !strings.Contains(reason, "1200.0 MP") {
+		t.Errorf("причина пропуска = %q, want %s с размером", reason, SkipReasonTooLarge)
+	}
+}
+
+func TestPool_MaxMegapixelsDownscale(t *testing.T) {
+	cfg, pool := newTestEnv(t, "small.jpg", "huge.jpg")
+	cfg.MaxMegapixels = 100
+	cfg.MegapixelsAction = config.MegapixelsDownscale
+
+	stats := runPool(t, cfg, pool)
+	if stats.Processed != 2 || stats.Skipped != 0 {
+		t.Fatalf("processed=%d skipped=%d, want 2/0", stats.Processed, stats.Skipped)
+	}
+
+	// Заглушка vips дописывает аргументы вызова в результат
+	huge, err := os.ReadFile(filepath.Join(cfg.OutputDir, "huge."+string(cfg.OutputFormat)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(huge), "thumbnail") || !strings.Contains(string(huge), " 11547 --height=8660") {
+		t.Errorf("большой исходник не уменьшен до 100 MP: %s", huge)
+	}
+	small, err := os.ReadFile(filepath.Join(cfg.OutputDir, "small."+string(cfg.OutputFormat)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(small), "thumbnail") {
+		t.Errorf("исходник в пределах лимита не должен уменьшаться: %s", small)
+	}
+}
+
+func TestPool_OOMRetryDownscale(t *testing.T) {
+	cfg, pool := newTestEnv(t, "small.jpg", "oom.jpg")
+	logPath := filepath.Join(t.TempDir(), "run.jsonl")
+	logger, err := runlog.Open(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.SetRunLog(logger)
+
+	stats := runPool(t, cfg, pool)
+	_ = logger.Close()
+	if stats.Processed != 2 || stats.Failed != 0 || stats.Downscaled != 1 {
+		t.Fatalf("processed=%d failed=%d downscaled=%d, want 2/0/1", stats.Processed, stats.Failed, stats.Downscaled)
+	}
+
+	// 20000x15000 (300 MP) уменьшен до 50 MP
+	data, err := os.ReadFile(filepath.Join(cfg.OutputDir, "oom."+string(cfg.OutputFormat)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "thumbnail") || !strings.Contains(string(data), " 8164 --height=6123") {
+		t.Errorf("после нехватки памяти исходник не уменьшен до 50 MP: %s", data)
+	}
+	for _, e := range readRunLog(t, logPath) {
+		if want := strings.Contains(e.Src, "oom"); e.Downscaled != want {
+			t.Errorf("%s: downscaled=%v, want %v", e.Src, e.Downscaled, want)
+		}
+	}
+}
+
+func TestPool_NoOOMRetry(t *testing.T) {
+	cfg, pool := newTestEnv(t, "oom.jpg")
+	cfg.NoOOMRetry = true
+
+	stats := runPool(t, cfg, pool)
+	if stats.Failed != 1 || stats.Downscaled != 0 {
+		t.Fatalf("failed=%d downscaled=%d, want 1/0", stats.Failed, stats.Downscaled)
+	}
+}
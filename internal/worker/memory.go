@@ -2,12 +2,35 @@
 package worker
 
 import (
+	"bufio"
 	"context"
+	"fmt"
+	"os"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
 )
 
+// defaultMemoryFraction - доля доступной памяти системы, которую может занять
+// обработка в адаптивном режиме.
+const defaultMemoryFraction = 0.5
+
+// SystemMemory содержит сведения о памяти системы.
+type SystemMemory struct {
+	// Total - общий объём памяти в байтах.
+	Total uint64
+
+	// Available - доступный объём памяти в байтах.
+	Available uint64
+}
+
+// MemoryReader считывает текущее состояние памяти системы.
+type MemoryReader func() (SystemMemory, error)
+
 // MemoryLimiter ограничивает использование памяти при обработке файлов.
 type MemoryLimiter struct {
 	// maxMemoryBytes - максимальное использование памяти в байтах.
@@ -21,11 +44,31 @@ type MemoryLimiter struct {
 
 	// enabled - включено ли ограничение.
 	enabled bool
+
+	// auto - адаптивный режим: лимит вычисляется по свободной памяти системы.
+	auto bool
+
+	// reader - источник сведений о памяти системы (для адаптивного режима).
+	reader MemoryReader
+
+	// fraction - доля доступной памяти, которую можно зарезервировать.
+	fraction float64
+
+	// baseline - доступная память системы на момент, когда не было
+	// резервирований (адаптивный режим): от неё считается бюджет резервирований.
+	baseline uint64
+
+	// pollInterval - интервал повторной проверки при нехватке памяти.
+	pollInterval time.Duration
 }
 
 // NewMemoryLimiter создаёт новый MemoryLimiter.
-// maxMemoryMB - ограничение в мегабайтах (0 = без ограничения).
+// maxMemoryMB - ограничение в мегабайтах (0 = без ограничения, -1 = адаптивный режим).
 func NewMemoryLimiter(maxMemoryMB int) *MemoryLimiter {
+	if maxMemoryMB == config.MemoryAuto {
+		return NewAdaptiveMemoryLimiter(ReadSystemMemory, defaultMemoryFraction)
+	}
+
 	if maxMemoryMB <= 0 {
 		return &MemoryLimiter{enabled: false}
 	}
@@ -33,6 +76,25 @@ func NewMemoryLimiter(maxMemoryMB int) *MemoryLimiter {
 	return &MemoryLimiter{
 		maxMemoryBytes: uint64(maxMemoryMB) * 1024 * 1024,
 		enabled:        true,
+		pollInterval:   100 * time.Millisecond,
+	}
+}
+
+// NewAdaptiveMemoryLimiter создаёт MemoryLimiter, который ограничивает число
+// одновременных конвертаций долей (fraction) доступной памяти системы.
+// Доступная память перечитывается при каждой попытке резервирования,
+// поэтому при её снижении новые конвертации ожидают освобождения.
+func NewAdaptiveMemoryLimiter(reader MemoryReader, fraction float64) *MemoryLimiter {
+	if fraction <= 0 || fraction > 1 {
+		fraction = defaultMemoryFraction
+	}
+
+	return &MemoryLimiter{
+		enabled:      true,
+		auto:         true,
+		reader:       reader,
+		fraction:     fraction,
+		pollInterval: 100 * time.Millisecond,
 	}
 }
 
@@ -56,14 +118,7 @@ func (ml *MemoryLimiter) Acquire(ctx context.Context, fileSize int64) (release f
 		}
 
 		ml.mu.Lock()
-		// Проверяем текущее использование памяти системой
-		var memStats runtime.MemStats
-		runtime.ReadMemStats(&memStats)
-		currentAlloc := memStats.Alloc
-
-		// Если есть место, резервируем
-		if ml.currentUsage+estimatedUsage <= ml.maxMemoryBytes &&
-			currentAlloc+estimatedUsage <= ml.maxMemoryBytes {
+		if ml.fits(estimatedUsage) {
 			ml.currentUsage += estimatedUsage
 			ml.mu.Unlock()
 
@@ -79,18 +134,64 @@ func (ml *MemoryLimiter) Acquire(ctx context.Context, fileSize int64) (release f
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
-		case <-time.After(100 * time.Millisecond):
+		case <-time.After(ml.pollInterval):
 			// Пробуем освободить память
 			runtime.GC()
 		}
 	}
 }
 
+// fits проверяет, можно ли зарезервировать estimatedUsage байт.
+// Вызывается под блокировкой mu.
+func (ml *MemoryLimiter) fits(estimatedUsage uint64) bool {
+	if !ml.auto {
+		// Проверяем текущее использование памяти процессом
+		var memStats runtime.MemStats
+		runtime.ReadMemStats(&memStats)
+
+		return ml.currentUsage+estimatedUsage <= ml.maxMemoryBytes &&
+			memStats.Alloc+estimatedUsage <= ml.maxMemoryBytes
+	}
+
+	mem, err := ml.reader()
+
+	// Одна конвертация выполняется всегда, иначе файл, оценка которого
+	// превышает бюджет, никогда не будет обработан. Без резервирований
+	// доступная память не включает наши конвертации - это база бюджета.
+	if ml.currentUsage == 0 {
+		if err == nil {
+			ml.baseline = mem.Available
+		}
+		return true
+	}
+
+	if err != nil {
+		// Не удалось прочитать состояние памяти - не блокируем обработку
+		return true
+	}
+
+	// Запущенные конвертации уже уменьшили доступную память, поэтому
+	// резервирования сравниваются с бюджетом от базы, а текущая доступная
+	// память - только с оценкой нового файла (иначе память учтена дважды)
+	baseline := ml.baseline
+	if baseline == 0 {
+		baseline = mem.Available
+	}
+	budget := uint64(float64(baseline) * ml.fraction)
+	headroom := uint64(float64(mem.Available) * ml.fraction)
+	return ml.currentUsage+estimatedUsage <= budget && estimatedUsage <= headroom
+}
+
 // IsEnabled возвращает true если ограничение включено.
 func (ml *MemoryLimiter) IsEnabled() bool {
 	return ml.enabled
 }
 
+// IsAuto возвращает true если включён адаптивный режим.
+func (ml *MemoryLimiter) IsAuto() bool {
+	return ml.auto
+}
+
 // CurrentUsage возвращает текущее зарезервированное использование памяти.
 func (ml *MemoryLimiter) CurrentUsage() uint64 {
 	ml.mu.Lock()
@@ -99,13 +200,77 @@ func (ml *MemoryLimiter) CurrentUsage() uint64 {
 }
 
 // MaxMemory возвращает максимальное ограничение памяти.
+// В адаптивном режиме возвращает текущий бюджет по доступной памяти системы.
 func (ml *MemoryLimiter) MaxMemory() uint64 {
+	if ml.auto {
+		mem, err := ml.reader()
+		if err != nil {
+			return 0
+		}
+		return uint64(float64(mem.Available) * ml.fraction)
+	}
 	return ml.maxMemoryBytes
 }
 
+// ReadSystemMemory считывает сведения о памяти системы из /proc/meminfo.
+// На системах без /proc возвращает ошибку.
+func ReadSystemMemory() (SystemMemory, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return SystemMemory{}, fmt.Errorf("не удалось прочитать память системы (%s): %w", runtime.GOOS, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var mem SystemMemory
+	var free, cached, buffers uint64
+	hasAvailable := false
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		// Значения в /proc/meminfo указаны в килобайтах
+		value *= 1024
+
+		switch fields[0] {
+		case "MemTotal:":
+			mem.Total = value
+		case "MemAvailable:":
+			mem.Available = value
+			hasAvailable = true
+		case "MemFree:":
+			free = value
+		case "Cached:":
+			cached = value
+		case "Buffers:":
+			buffers = value
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return SystemMemory{}, fmt.Errorf("ошибка чтения /proc/meminfo: %w", err)
+	}
+
+	// Старые ядра не сообщают MemAvailable
+	if !hasAvailable {
+		mem.Available = free + cached + buffers
+	}
+
+	if mem.Total == 0 {
+		return SystemMemory{}, fmt.Errorf("не удалось определить объём памяти системы")
+	}
+
+	return mem, nil
+}
+
 /*
 Возможные расширения:
 - Добавить метрики использования памяти
-- Добавить адаптивное ограничение на основе доступной памяти системы
 - Добавить приоритеты для разных типов файлов
+- Добавить чтение памяти системы на macOS/Windows (sysctl, GlobalMemoryStatusEx)
 */
@@ -0,0 +1,188 @@
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeMemory - подменяемый источник памяти системы для тестов.
+type fakeMemory struct {
+	available atomic.Uint64
+}
+
+func (m *fakeMemory) read() (SystemMemory, error) {
+	return SystemMemory{Total: 1 << 30, Available: m.available.Load()}, nil
+}
+
+func newTestAdaptiveLimiter(mem *fakeMemory) *MemoryLimiter {
+	ml := NewAdaptiveMemoryLimiter(mem.read, 0.5)
+	ml.pollInterval = 5 * time.Millisecond
+	return ml
+}
+
+func TestAdaptiveMemoryLimiter_BlocksUntilRelease(t *testing.T) {
+	mem := &fakeMemory{}
+	mem.available.Store(100 << 20) // бюджет: 50 МБ
+	ml := newTestAdaptiveLimiter(mem)
+
+	ctx := context.Background()
+	const fileSize = 10 << 20 // оценка: 30 МБ
+
+	release1, err := ml.Acquire(ctx, fileSize)
+	if err != nil {
+		t.Fatalf("первый Acquire: %v", err)
+	}
+
+	acquired := make(chan func(), 1)
+	go func() {
+		release2, err := ml.Acquire(ctx, fileSize)
+		if err != nil {
+			t.Errorf("второй Acquire: %v", err)
+			return
+		}
+		acquired <- release2
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("второй Acquire не должен проходить, пока бюджет занят")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release1()
+
+	select {
+	case release2 := <-acquired:
+		release2()
+	case <-time.After(time.Second):
+		t.Fatal("второй Acquire не прошёл после освобождения памяти")
+	}
+
+	if usage := ml.CurrentUsage(); usage != 0 {
+		t.Errorf("CurrentUsage() = %d, want 0", usage)
+	}
+}
+
+func TestAdaptiveMemoryLimiter_BacksOffOnLowMemory(t *testing.T) {
+	mem := &fakeMemory{}
+	mem.available.Store(1 << 30)
+	ml := newTestAdaptiveLimiter(mem)
+
+	ctx := context.Background()
+	const fileSize = 10 << 20
+
+	release1, err := ml.Acquire(ctx, fileSize)
+	if err != nil {
+		t.Fatalf("первый Acquire: %v", err)
+	}
+	defer release1()
+
+	// Свободная память системы упала - новые конвертации должны ждать
+	mem.available.Store(40 << 20)
+
+	acquired := make(chan func(), 1)
+	go func() {
+		release2, err := ml.Acquire(ctx, fileSize)
+		if err == nil {
+			acquired <- release2
+		}
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Acquire должен ждать при нехватке свободной памяти")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Память освободилась
+	mem.available.Store(1 << 30)
+
+	select {
+	case release2 := <-acquired:
+		release2()
+	case <-time.After(time.Second):
+		t.Fatal("Acquire не прошёл после восстановления свободной памяти")
+	}
+}
+
+func TestAdaptiveMemoryLimiter_NoDoubleCounting(t *testing.T) {
+	mem := &fakeMemory{}
+	mem.available.Store(200 << 20) // бюджет: 100 МБ
+	ml := newTestAdaptiveLimiter(mem)
+
+	ctx := context.Background()
+	const fileSize = 10 << 20 // оценка: 30 МБ
+
+	// Запущенная конвертация занимает свою оценку в памяти системы:
+	// три конвертации (90 МБ) укладываются в бюджет, хотя доступной памяти
+	// после двух остаётся 140 МБ (половина - 70 МБ)
+	var releases []func()
+	for i := 0; i < 3; i++ {
+		acquireCtx, cancel := context.WithTimeout(ctx, time.Second)
+		release, err := ml.Acquire(acquireCtx, fileSize)
+		cancel()
+		if err != nil {
+			t.Fatalf("Acquire %d: %v", i+1, err)
+		}
+		releases = append(releases, release)
+		mem.available.Store(mem.available.Load() - 30<<20)
+	}
+
+	// Четвёртая превышает бюджет
+	acquireCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := ml.Acquire(acquireCtx, fileSize); err == nil {
+		t.Error("Acquire сверх бюджета должен ждать")
+	}
+	for _, release := range releases {
+		release()
+	}
+}
+
+func TestAdaptiveMemoryLimiter_AllowsSingleOversizedFile(t *testing.T) {
+	mem := &fakeMemory{}
+	mem.available.Store(1 << 20)
+	ml := newTestAdaptiveLimiter(mem)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	release, err := ml.Acquire(ctx, 100<<20)
+	if err != nil {
+		t.Fatalf("Acquire для единственного файла не должен блокироваться: %v", err)
+	}
+	release()
+}
+
+func TestAdaptiveMemoryLimiter_ContextCancel(t *testing.T) {
+	mem := &fakeMemory{}
+	mem.available.Store(10 << 20)
+	ml := newTestAdaptiveLimiter(mem)
+
+	release, err := ml.Acquire(context.Background(), 1<<20)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	if _, err := ml.Acquire(ctx, 10<<20); err == nil {
+		t.Fatal("Acquire должен вернуть ошибку при отмене контекста")
+	}
+}
+
+func TestNewMemoryLimiter_Auto(t *testing.T) {
+	ml := NewMemoryLimiter(-1)
+	if !ml.IsEnabled() || !ml.IsAuto() {
+		t.Errorf("NewMemoryLimiter(-1): enabled=%v auto=%v, want true/true", ml.IsEnabled(), ml.IsAuto())
+	}
+
+	ml = NewMemoryLimiter(0)
+	if ml.IsEnabled() {
+		t.Error("NewMemoryLimiter(0) должен быть отключён")
+	}
+}
@@ -0,0 +1,131 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/storage"
+)
+
+// MirrorStats - итоги синхронизации выхода со входом (--mirror).
+type MirrorStats struct {
+	// Removed - удалённые выходные файлы исходников, которых больше нет.
+	Removed int64
+
+	// RemovedBytes - суммарный размер удалённых выходных файлов.
+	RemovedBytes int64
+
+	// Kept - выходные файлы, оставленные на месте: вне --out или изменённые
+	// после конвертации (задача из БД всё равно удаляется).
+	Kept int64
+}
+
+// Mirror удаляет результаты исходников, которых больше нет во входной
+// директории: для успешных задач с исходником внутри --in, который не
+// существует, удаляются выходной файл и задача в БД. Удаляются только файлы,
+// записанные этой программой: путь из БД внутри --out и размер совпадает с
+// записанным. Результат, на который ссылается задача существующего исходника
+// (dedup), не удаляется. В dry-run только выводится план.
+func (p *Pool) Mirror(ctx context.Context) (MirrorStats, error) {
+	var stats MirrorStats
+
+	// Недоступная входная директория (не смонтирован диск) не должна
+	// выглядеть как удаление всех исходников
+	if info, err := os.Stat(p.cfg.InputDir); err != nil || !info.IsDir() {
+		return stats, fmt.Errorf("--mirror: входная директория недоступна: %s", p.cfg.InputDir)
+	}
+	inputDir, err := filepath.Abs(p.cfg.InputDir)
+	if err != nil {
+		return stats, err
+	}
+
+	jobs, err := p.storage.ListJobsByStatus(storage.StatusOK)
+	if err != nil {
+		return stats, err
+	}
+
+	var orphans []storage.Job
+	live := make(map[string]bool)
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			return stats, ctx.Err()
+		}
+		if !config.IsSubPath(inputDir, job.SrcPath) {
+			if job.DstPath != nil {
+				live[*job.DstPath] = true
+			}
+			continue
+		}
+		_, err := os.Stat(job.SrcPath)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			orphans = append(orphans, job)
+		case job.DstPath != nil:
+			// Исходник есть (или недоступен) - результат не трогаем
+			live[*job.DstPath] = true
+		}
+	}
+
+	for _, job := range orphans {
+		if job.DstPath != nil && !live[*job.DstPath] {
+			if size, removed := p.removeMirrorOutput(job); removed {
+				stats.Removed++
+				stats.RemovedBytes += size
+			} else {
+				stats.Kept++
+			}
+		}
+		if p.cfg.DryRun {
+			continue
+		}
+		if err := p.storage.DeleteJob(job.ID); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// removeMirrorOutput удаляет выходной файл задачи с удалённым исходником
+// и опустевшие директории над ним (до --out). Возвращает размер файла
+// и false, если файл оставлен.
+func (p *Pool) removeMirrorOutput(job storage.Job) (int64, bool) {
+	dstPath := *job.DstPath
+	info, err := os.Stat(dstPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, true
+	}
+	if err != nil || !info.Mode().IsRegular() || !config.IsSubPath(p.cfg.OutputDir, dstPath) ||
+		(job.DstSize != nil && *job.DstSize != info.Size()) {
+		if p.verbose {
+			p.printMessage("⚠️  --mirror: %s оставлен (вне --out или изменён после конвертации)\n", dstPath)
+		}
+		return 0, false
+	}
+
+	if p.cfg.DryRun {
+		p.printMessage("🪞 [dry-run] будет удалён %s (исходник %s удалён)\n", dstPath, job.SrcPath)
+		return info.Size(), true
+	}
+	if err := os.Remove(dstPath); err != nil {
+		p.logError(job.SrcPath, fmt.Errorf("--mirror: %w", err))
+		return 0, false
+	}
+	if p.verbose {
+		p.printMessage("🪞 Удалён %s (исходник %s удалён)\n", dstPath, job.SrcPath)
+	}
+
+	// Удаляем опустевшие директории; os.Remove не удаляет непустую
+	outputDir, _ := filepath.Abs(p.cfg.OutputDir)
+	absDst, _ := filepath.Abs(dstPath)
+	for dir := filepath.Dir(absDst); config.IsSubPath(outputDir, dir) && dir != outputDir; dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return info.Size(), true
+}
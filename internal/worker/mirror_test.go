@@ -0,0 +1,110 @@
+package worker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPool_Mirror(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg", "b.jpg")
+	sub := filepath.Join(cfg.InputDir, "2024")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, "c.jpg"), []byte("image:c"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if stats := runPool(t, cfg, pool); stats.Processed != 3 {
+		t.Fatalf("processed=%d, want 3", stats.Processed)
+	}
+
+	// Исходники b и 2024/c удалены
+	ext := "." + string(cfg.OutputFormat)
+	for _, rel := range []string{"b.jpg", filepath.Join("2024", "c.jpg")} {
+		if err := os.Remove(filepath.Join(cfg.InputDir, rel)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Файл в --out, которого нет в БД, не удаляется
+	foreign := filepath.Join(cfg.OutputDir, "foreign"+ext)
+	if err := os.WriteFile(foreign, []byte("not ours"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dry-run: только план
+	cfg.Mirror, cfg.DryRun = true, true
+	if mirrored, err := pool.Mirror(context.Background()); err != nil || mirrored.Removed != 2 {
+		t.Fatalf("dry-run: removed=%d err=%v, want 2", mirrored.Removed, err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.OutputDir, "b"+ext)); err != nil {
+		t.Fatalf("dry-run удалил результат: %v", err)
+	}
+
+	cfg.DryRun = false
+	mirrored, err := pool.Mirror(context.Background())
+	if err != nil {
+		t.Fatalf("Mirror: %v", err)
+	}
+	if mirrored.Removed != 2 || mirrored.Kept != 0 {
+		t.Errorf("removed=%d kept=%d, want 2/0", mirrored.Removed, mirrored.Kept)
+	}
+	for name, wantExists := range map[string]bool{
+		"a" + ext:                      true,
+		"b" + ext:                      false,
+		filepath.Join("2024", "c"+ext): false,
+		"2024":                         false, // опустевшая директория удалена
+		"foreign" + ext:                true,
+	} {
+		_, err := os.Stat(filepath.Join(cfg.OutputDir, name))
+		if exists := err == nil; exists != wantExists {
+			t.Errorf("%s: существует=%v, want %v", name, exists, wantExists)
+		}
+	}
+
+	// Задачи удалены: повторный прогон ничего не удаляет, a пропускается по БД
+	if mirrored, err := pool.Mirror(context.Background()); err != nil || mirrored.Removed != 0 {
+		t.Errorf("повтор: removed=%d err=%v, want 0", mirrored.Removed, err)
+	}
+	if stats := runPool(t, cfg, New(cfg, pool.storage, pool.converter)); stats.Skipped != 1 || stats.Processed != 0 {
+		t.Errorf("повторный прогон: skipped=%d processed=%d, want 1/0", stats.Skipped, stats.Processed)
+	}
+}
+
+func TestPool_MirrorKeepsModifiedOutput(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg")
+	runPool(t, cfg, pool)
+
+	out := filepath.Join(cfg.OutputDir, "a."+string(cfg.OutputFormat))
+	if err := os.WriteFile(out, []byte("edited by hand, different size"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(cfg.InputDir, "a.jpg")); err != nil {
+		t.Fatal(err)
+	}
+
+	mirrored, err := pool.Mirror(context.Background())
+	if err != nil || mirrored.Removed != 0 || mirrored.Kept != 1 {
+		t.Fatalf("removed=%d kept=%d err=%v, want 0/1", mirrored.Removed, mirrored.Kept, err)
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("изменённый результат удалён: %v", err)
+	}
+}
+
+func TestPool_MirrorMissingInput(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg")
+	runPool(t, cfg, pool)
+
+	// Недоступная входная директория - ошибка, а не удаление всех результатов
+	if err := os.RemoveAll(cfg.InputDir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Mirror(context.Background()); err == nil {
+		t.Error("Mirror без входной директории должен вернуть ошибку")
+	}
+	if _, err := os.Stat(filepath.Join(cfg.OutputDir, "a."+string(cfg.OutputFormat))); err != nil {
+		t.Errorf("результат удалён: %v", err)
+	}
+}
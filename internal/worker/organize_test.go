@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPool_OrganizeByDate(t *testing.T) {
+	cfg, pool := newTestEnv(t, "plain.jpg")
+	cfg.OrganizeByDate = true
+	album := filepath.Join(cfg.InputDir, "album")
+	if err := os.MkdirAll(album, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(album, "exif_a.jpg"), []byte("image:exif_a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Без EXIF дата берётся из mtime исходника
+	mtime := time.Date(2019, 3, 2, 12, 0, 0, 0, time.Local)
+	if err := os.Chtimes(filepath.Join(cfg.InputDir, "plain.jpg"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := runPool(t, cfg, pool)
+	if stats.Processed != 2 {
+		t.Fatalf("processed=%d, want 2", stats.Processed)
+	}
+	ext := "." + cfg.OutputExt()
+	for _, rel := range []string{
+		filepath.Join("2021", "07", "04", "exif_a"+ext), // EXIF DateTimeOriginal, не album/
+		filepath.Join("2019", "03", "02", "plain"+ext),
+	} {
+		if _, err := os.Stat(filepath.Join(cfg.OutputDir, rel)); err != nil {
+			t.Errorf("результат %s: %v", rel, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cfg.OutputDir, "album")); !os.IsNotExist(err) {
+		t.Errorf("с --organize-by-date структура входа не сохраняется: %v", err)
+	}
+
+	// Шаблон директории даты
+	cfg.DateFolderTemplate = "YYYY-MM"
+	cfg.Force = true
+	runPool(t, cfg, pool)
+	if _, err := os.Stat(filepath.Join(cfg.OutputDir, "2021-07", "exif_a"+ext)); err != nil {
+		t.Errorf("результат по шаблону YYYY-MM: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package worker
+
+import (
+	"context"
+	"sync"
+)
+
+// pauseGate - общий затвор воркеров: на паузе воркеры не берут новые файлы,
+// а уже начатые конвертации завершаются.
+type pauseGate struct {
+	mu     sync.Mutex
+	paused bool
+
+	// resumed закрывается при снятии паузы.
+	resumed chan struct{}
+}
+
+// pause ставит затвор на паузу. Возвращает false, если пауза уже стоит.
+func (g *pauseGate) pause() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.paused {
+		return false
+	}
+	g.paused = true
+	g.resumed = make(chan struct{})
+	return true
+}
+
+// unpause снимает паузу. Возвращает false, если паузы не было.
+func (g *pauseGate) unpause() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.paused {
+		return false
+	}
+	g.paused = false
+	close(g.resumed)
+	return true
+}
+
+// isPaused сообщает, стоит ли пауза.
+func (g *pauseGate) isPaused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.paused
+}
+
+// wait блокируется, пока стоит пауза. Возвращает ошибку при отмене ctx.
+func (g *pauseGate) wait(ctx context.Context) error {
+	g.mu.Lock()
+	if !g.paused {
+		g.mu.Unlock()
+		return nil
+	}
+	resumed := g.resumed
+	g.mu.Unlock()
+
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pause приостанавливает обработку: воркеры (и этап хэширования) не берут
+// новые файлы, начатые конвертации завершаются. Имя Resume занято продолжением
+// прерванных задач (--resume), поэтому пауза снимается методом Unpause.
+func (p *Pool) Pause() {
+	if p.gate.pause() && p.progress != nil {
+		p.progress.SetPaused(true)
+	}
+}
+
+// Unpause снимает паузу, поставленную Pause.
+func (p *Pool) Unpause() {
+	if p.gate.unpause() && p.progress != nil {
+		p.progress.SetPaused(false)
+	}
+}
+
+// Paused сообщает, стоит ли обработка на паузе.
+func (p *Pool) Paused() bool {
+	return p.gate.isPaused()
+}
+
+// TogglePause ставит или снимает паузу и возвращает новое состояние.
+func (p *Pool) TogglePause() bool {
+	if p.Paused() {
+		p.Unpause()
+		return false
+	}
+	p.Pause()
+	return true
+}
+
+/*
+Возможные расширения:
+- Пауза по таймеру (--pause-after) и расписанию
+- Автоматическая пауза при нехватке места на диске
+*/
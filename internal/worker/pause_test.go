@@ -0,0 +1,108 @@
+package worker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/progress"
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+	"github.com/artemshloyda/photoconverter/internal/storage"
+)
+
+// waitStats ждёт, пока cond не станет true для статистики пула.
+func waitStats(t *testing.T, pool *Pool, cond func(Stats) bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond(pool.GetStats()) {
+		if time.Now().After(deadline) {
+			t.Fatalf("статистика не достигла ожидаемой: %+v", pool.GetStats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPool_PauseUnpause(t *testing.T) {
+	names := []string{"a.jpg", "b.jpg", "c.jpg"}
+	cfg, pool := newTestEnv(t, names...)
+
+	rec := &recordingEmitter{}
+	pool.SetProgressBar(progress.New(progress.Options{Total: 3, Emitter: rec}))
+
+	files := make(chan scanner.File, len(names))
+	fileFor := func(name string) scanner.File {
+		path := filepath.Join(cfg.InputDir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return scanner.File{
+			Path:    path,
+			RelPath: name,
+			Info:    storage.FileInfo{Path: path, Size: info.Size(), Mtime: info.ModTime().Unix()},
+		}
+	}
+
+	done := make(chan Stats, 1)
+	go func() { done <- pool.Process(context.Background(), files, nil) }()
+
+	files <- fileFor("a.jpg")
+	waitStats(t, pool, func(s Stats) bool { return s.Processed == 1 })
+
+	// На паузе новые файлы не берутся
+	pool.Pause()
+	if !pool.Paused() {
+		t.Fatal("Paused() = false после Pause")
+	}
+	files <- fileFor("b.jpg")
+	files <- fileFor("c.jpg")
+	time.Sleep(200 * time.Millisecond)
+	if s := pool.GetStats(); s.Total != 1 || s.Processed != 1 {
+		t.Fatalf("на паузе обработаны файлы: %+v", s)
+	}
+
+	if pool.TogglePause() {
+		t.Fatal("TogglePause должен снять паузу")
+	}
+	close(files)
+
+	stats := <-done
+	if stats.Processed != 3 || stats.Failed != 0 {
+		t.Errorf("processed=%d failed=%d, want 3/0", stats.Processed, stats.Failed)
+	}
+
+	var events []string
+	rec.mu.Lock()
+	for _, u := range rec.updates {
+		if u.Event == "pause" || u.Event == "resume" {
+			events = append(events, u.Event)
+		}
+	}
+	rec.mu.Unlock()
+	if len(events) != 2 || events[0] != "pause" || events[1] != "resume" {
+		t.Errorf("события паузы = %v, want [pause resume]", events)
+	}
+}
+
+func TestPool_PauseCancel(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg")
+	pool.Pause()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	files, errChan := scanner.New(cfg).Scan(ctx)
+	done := make(chan Stats, 1)
+	go func() { done <- pool.Process(ctx, files, errChan) }()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case stats := <-done:
+		if stats.Total != 0 {
+			t.Errorf("на паузе обработан файл: %+v", stats)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Process не завершился после отмены контекста на паузе")
+	}
+}
@@ -3,18 +3,32 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/artemshloyda/photoconverter/internal/cache"
 	"github.com/artemshloyda/photoconverter/internal/config"
 	"github.com/artemshloyda/photoconverter/internal/converter"
+	"github.com/artemshloyda/photoconverter/internal/metrics"
 	"github.com/artemshloyda/photoconverter/internal/progress"
+	"github.com/artemshloyda/photoconverter/internal/runlog"
 	"github.com/artemshloyda/photoconverter/internal/scanner"
 	"github.com/artemshloyda/photoconverter/internal/storage"
 )
 
+// SkipReasonOutputExists - причина пропуска при --skip-existing-output
+// и --on-conflict skip.
+const SkipReasonOutputExists = "output exists"
+
+// SkipReasonNoRawDecoder - причина пропуска RAW исходника без декодера RAW.
+const SkipReasonNoRawDecoder = "no RAW decoder"
+
 // Stats содержит статистику обработки.
 type Stats struct {
 	// Processed - количество обработанных файлов.
@@ -34,6 +48,35 @@ type Stats struct {
 
 	// OutputBytes - общий размер выходных файлов.
 	OutputBytes int64
+
+	// Plan - итоги dry-run по категориям.
+	Plan PlanStats
+
+	// Stopped - обработка остановлена после первой ошибки (--fail-fast).
+	Stopped bool
+
+	// Deleted - количество удалённых исходников (--delete-source).
+	Deleted int64
+
+	// Trashed - количество исходников, перемещённых в корзину (--trash-dir).
+	Trashed int64
+
+	// Downscaled - количество файлов, сконвертированных с уменьшением
+	// после нехватки памяти у vips.
+	Downscaled int64
+
+	// CacheHits - количество результатов, скопированных из кэша (--cache).
+	CacheHits int64
+
+	// ConvertTime - суммарное время успешных конвертаций.
+	ConvertTime time.Duration
+
+	// MedianDuration - медиана времени конвертации одного файла
+	// (по ограниченной выборке, см. durationSample).
+	MedianDuration time.Duration
+
+	// Failures - ошибки по причинам, по убыванию числа (--report-failures).
+	Failures []FailureGroup
 }
 
 // SavedBytes возвращает количество сэкономленных байт.
@@ -49,6 +92,14 @@ func (s *Stats) SavedPercent() float64 {
 	return float64(s.SavedBytes()) / float64(s.InputBytes) * 100
 }
 
+// AvgDuration возвращает среднее время конвертации одного файла.
+func (s *Stats) AvgDuration() time.Duration {
+	if s.Processed == 0 {
+		return 0
+	}
+	return s.ConvertTime / time.Duration(s.Processed)
+}
+
 // FormatBytes форматирует байты в человекочитаемый формат.
 func FormatBytes(bytes int64) string {
 	const unit = 1024
@@ -63,143 +114,492 @@ func FormatBytes(bytes int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
+// variant - вариант выхода: конфигурация и конвертер для неё.
+// В режиме --multi-preset каждый файл конвертируется во все варианты.
+type variant struct {
+	cfg       *config.Config
+	converter converter.Converter
+}
+
 // Pool управляет пулом воркеров для обработки файлов.
 type Pool struct {
 	cfg           *config.Config
 	storage       *storage.Storage
-	converter     *converter.Converter
+	converter     converter.Converter
+	variants      []variant
 	stats         Stats
+	quiet         bool
 	verbose       bool
+	debug         bool
 	progress      *progress.Bar
+	hashPhase     *progress.Phase
 	memoryLimiter *MemoryLimiter
+	runLog        *runlog.Logger
+	metrics       *metrics.Metrics
+
+	// durations - выборка длительностей конвертации для медианы.
+	durations durationSample
+
+	// gate приостанавливает выдачу файлов воркерам (Pause/Unpause).
+	gate pauseGate
+
+	// dirConfigs - кэш настроек поддиректорий (.photoconverter.yaml).
+	dirConfigs dirConfigs
+
+	// failures - ошибки запуска по причинам (--report-failures).
+	failures failureReport
+
+	// ignored - исходники, помеченные игнорируемыми (--ignore-file).
+	ignored ignoredSources
+
+	// formatLimits - семафоры конвертаций по выходному формату (--concurrency-per-format).
+	formatLimits formatLimiter
+
+	// cache - кэш результатов (--cache), nil - без кэша.
+	cache *cache.Cache
+
+	// Автоподбор числа воркеров (--workers -1): tuner текущего Process,
+	// период пересчёта и источник сведений о памяти системы
+	tuner        *autoTuner
+	tuneInterval time.Duration
+	memReader    MemoryReader
+
+	// --fail-fast: после первой ошибки новые файлы не начинаются,
+	// а cancel останавливает источник файлов
+	halted   atomic.Bool
+	haltOnce sync.Once
+	cancel   context.CancelFunc
+
+	// forceOnce фиксирует forceMaxJobID при первом вызове Process (--force):
+	// задачи с ID не больше него относятся к прошлым запускам.
+	forceOnce     sync.Once
+	forceMaxJobID int64
+	forceErr      error
+
+	// План dry-run: задачи для манифеста и ключи содержимого, уже попавшие в план
+	planMu         sync.Mutex
+	planned        []storage.Job
+	plannedContent map[string]bool
 }
 
 // New создаёт новый пул воркеров.
-func New(cfg *config.Config, st *storage.Storage, conv *converter.Converter) *Pool {
+// При заданном cfg.MultiPresets каждый файл конвертируется по каждому пресету
+// в поддиректорию <out>/<пресет>.
+func New(cfg *config.Config, st *storage.Storage, conv converter.Converter) *Pool {
+	variants := []variant{{cfg: cfg, converter: conv}}
+	if len(cfg.MultiPresets) > 0 {
+		variants = variants[:0]
+		for _, name := range cfg.MultiPresets {
+			vcfg, err := cfg.PresetVariant(name)
+			if err != nil {
+				// Имена пресетов проверяются в Config.Validate
+				continue
+			}
+			variants = append(variants, variant{cfg: vcfg, converter: conv.WithConfig(vcfg)})
+		}
+	}
+
 	return &Pool{
 		cfg:           cfg,
 		storage:       st,
 		converter:     conv,
-		verbose:       cfg.Verbose,
+		variants:      variants,
+		quiet:         cfg.Quiet(),
+		verbose:       cfg.Verbose(),
+		debug:         cfg.Debug(),
 		memoryLimiter: NewMemoryLimiter(cfg.MaxMemoryMB),
+		tuneInterval:  autoTuneInterval,
+		memReader:     ReadSystemMemory,
 	}
 }
 
+// Variants возвращает количество вариантов выхода на один входной файл.
+func (p *Pool) Variants() int {
+	return len(p.variants)
+}
+
 // SetProgressBar устанавливает прогресс-бар для отображения прогресса.
+// В режиме dedup на баре дополнительно показывается этап хэширования.
 func (p *Pool) SetProgressBar(bar *progress.Bar) {
 	p.progress = bar
+	p.hashPhase = nil
+	if bar != nil && p.cfg.Mode == config.ModeDedup {
+		// Каждый файл хэшируется один раз на все варианты (--multi-preset)
+		total := bar.Total()
+		if total > 0 {
+			total /= int64(len(p.variants))
+		}
+		p.hashPhase = bar.StartPhase("hash", "хэширование", total)
+	}
+}
+
+// SetRunLog устанавливает журнал обработки файлов.
+func (p *Pool) SetRunLog(l *runlog.Logger) {
+	p.runLog = l
+}
+
+// SetMetrics устанавливает метрики Prometheus (--metrics-addr).
+func (p *Pool) SetMetrics(m *metrics.Metrics) {
+	p.metrics = m
+}
+
+// SetCancel устанавливает функцию отмены контекста источника файлов.
+// При --fail-fast пул вызывает её на первой ошибке: сканирование
+// прекращается, а уже начатые конвертации завершаются штатно.
+func (p *Pool) SetCancel(cancel context.CancelFunc) {
+	p.cancel = cancel
 }
 
 // Process запускает обработку файлов из канала.
 func (p *Pool) Process(ctx context.Context, files <-chan scanner.File, errChan <-chan error) Stats {
-	var wg sync.WaitGroup
+	if p.cfg.Force {
+		p.forceOnce.Do(func() {
+			p.forceMaxJobID, p.forceErr = p.storage.LastJobID()
+		})
+		if p.forceErr != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка БД: %v\n", p.forceErr)
+			return p.stats
+		}
+	}
 
-	// Запускаем воркеров
-	for i := 0; i < p.cfg.Workers; i++ {
-		wg.Add(1)
-		go func(workerID int) {
-			defer wg.Done()
-			p.worker(ctx, workerID, files)
-		}(i)
+	// Режим dedup: файлы сначала проходят этап хэширования
+	if p.cfg.Mode == config.ModeDedup {
+		files = p.hashStage(ctx, files)
 	}
 
-	// Ждём завершения всех воркеров
-	wg.Wait()
+	if p.cfg.Workers == config.WorkersAuto {
+		p.runAutoWorkers(ctx, files)
+	} else {
+		var wg sync.WaitGroup
+
+		// Запускаем воркеров
+		for i := 0; i < p.cfg.Workers; i++ {
+			wg.Add(1)
+			go func(workerID int) {
+				defer wg.Done()
+				p.worker(ctx, workerID, files, nil)
+			}(i)
+		}
+
+		// Ждём завершения всех воркеров
+		wg.Wait()
+	}
 
-	// Проверяем ошибки сканирования
+	// Проверяем ошибки сканирования (отмена по --fail-fast ошибкой не считается)
 	select {
 	case err := <-errChan:
-		if err != nil {
+		if err != nil && !(p.halted.Load() && errors.Is(err, context.Canceled)) {
 			fmt.Fprintf(os.Stderr, "Ошибка сканирования: %v\n", err)
 		}
 	default:
 	}
 
+	p.stats.Stopped = p.halted.Load()
+	p.stats.MedianDuration = p.durations.median()
+	p.stats.Failures = p.failures.list()
 	return p.stats
 }
 
 // worker обрабатывает файлы из канала.
-func (p *Pool) worker(ctx context.Context, id int, files <-chan scanner.File) {
+// Закрытие stop завершает воркер после текущего файла (nil - без остановки).
+func (p *Pool) worker(ctx context.Context, id int, files <-chan scanner.File, stop <-chan struct{}) {
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-stop:
+			return
 		case file, ok := <-files:
 			if !ok {
 				return
 			}
+			// После остановки по --fail-fast канал только вычитывается,
+			// чтобы источник файлов завершился
+			if p.halted.Load() {
+				p.releaseFile(file)
+				continue
+			}
+			// На паузе новые файлы не начинаются: воркер мог ждать файл до паузы
+			if p.gate.wait(ctx) != nil {
+				return
+			}
 			p.processFile(ctx, file)
+			p.releaseFile(file)
+			if p.tuner != nil {
+				p.tuner.observe(file.Info.Size)
+			}
 		}
 	}
 }
 
-// processFile обрабатывает один файл.
+// processFile обрабатывает один файл во всех вариантах выхода.
 func (p *Pool) processFile(ctx context.Context, file scanner.File) {
+	// Игнорируемые исходники (--ignore-file) не конвертируются
+	if p.isIgnored(file) {
+		p.skipIgnored(file)
+		return
+	}
+
+	// Настройки поддиректории заменяют параметры выхода для файлов под ней
+	variants, err := p.fileVariants(file)
+	if err != nil {
+		p.logError(file.Path, err)
+		p.writeRunLog(runlog.Entry{Status: runlog.StatusFailed, Src: file.Info.Path, Error: err.Error()})
+		atomic.AddInt64(&p.stats.Total, int64(len(p.variants)))
+		if p.progress != nil {
+			for range p.variants {
+				p.progress.IncrementFailed(file.RelPath, file.Info.Size)
+			}
+		}
+		p.addFailed(int64(len(p.variants)))
+		return
+	}
+
+	converted := true
+	for _, v := range variants {
+		if ctx.Err() != nil || p.halted.Load() {
+			return
+		}
+		if !p.processVariant(ctx, file, v) {
+			converted = false
+		}
+	}
+
+	// --delete-source / --trash-dir: исходник убирается, только если сконвертирован во всех вариантах
+	if converted && (p.cfg.DeleteSource || p.cfg.TrashDir != "") && !p.cfg.DryRun {
+		if err := p.removeSource(file); err != nil {
+			p.logError(file.Path, err)
+		}
+	}
+}
+
+// releaseFile удаляет временную копию файла (File.Temp) после обработки,
+// чтобы файлы, извлечённые из архива, не копились до конца запуска.
+func (p *Pool) releaseFile(file scanner.File) {
+	if !file.Temp {
+		return
+	}
+	if err := os.Remove(file.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		p.logError(file.Path, err)
+	}
+}
+
+// hashStage вычисляет хэши содержимого для режима dedup (один раз на все варианты)
+// отдельным этапом: cfg.MaxWorkers() горутин хэшируют файлы и передают их на конвертацию.
+// Хэширование идёт с опережением конвертации и отображается на баре отдельным
+// счётчиком. Файлы, которые не удалось прочитать, сразу учитываются как ошибки.
+func (p *Pool) hashStage(ctx context.Context, files <-chan scanner.File) <-chan scanner.File {
+	hashed := make(chan scanner.File, 100)
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.cfg.MaxWorkers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var (
+					file scanner.File
+					ok   bool
+				)
+				select {
+				case <-ctx.Done():
+					return
+				case file, ok = <-files:
+					if !ok {
+						return
+					}
+				}
+				if p.halted.Load() {
+					p.releaseFile(file)
+					continue
+				}
+				if p.gate.wait(ctx) != nil {
+					return
+				}
+
+				if err := p.hashContent(&file); err != nil {
+					err = fmt.Errorf("не удалось вычислить хэш содержимого: %w", err)
+					p.logError(file.Path, err)
+					p.writeRunLog(runlog.Entry{Status: runlog.StatusFailed, Src: file.Info.Path, Error: err.Error()})
+					atomic.AddInt64(&p.stats.Total, int64(len(p.variants)))
+					p.addFailed(int64(len(p.variants)))
+					if p.hashPhase != nil {
+						p.hashPhase.IncrementFailed()
+					}
+					p.releaseFile(file)
+					continue
+				}
+				if p.hashPhase != nil {
+					p.hashPhase.Increment()
+				}
+
+				select {
+				case hashed <- file:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(hashed)
+	}()
+	return hashed
+}
+
+// hashContent заполняет ключ содержимого файла для режима dedup:
+// полный хэш или быстрый ключ (--dedup-quick).
+func (p *Pool) hashContent(file *scanner.File) error {
+	if !p.cfg.DedupQuick {
+		sum, err := scanner.ComputeHash(file.Path, p.cfg.DedupHash)
+		if err != nil {
+			return err
+		}
+		file.Info.ContentSHA256 = sum
+		file.Info.ContentHashKind = storage.HashKindFull
+		return nil
+	}
+
+	key, full, err := scanner.ComputeQuickHash(file.Path, p.cfg.DedupHash, p.cfg.DedupQuickBytes)
+	if err != nil {
+		return err
+	}
+	file.Info.ContentSHA256 = key
+	file.Info.ContentHashKind = storage.HashKindQuick
+	if full {
+		file.Info.ContentHashKind = storage.HashKindFull
+	}
+	return nil
+}
+
+// verifyQuickKey подтверждает совпадение быстрого ключа полным хэшем.
+// Если быстрый ключ совпал с уже сконвертированным файлом, сравниваются полные
+// хэши обоих файлов: при равенстве ключ остаётся быстрым (файл будет пропущен
+// как дубликат), иначе файл переходит на полный хэш и обрабатывается отдельно.
+// Исходник, который нельзя прочитать (удалён, находится в архиве), считается отличающимся.
+func (p *Pool) verifyQuickKey(info storage.FileInfo, path string, v variant) (storage.FileInfo, error) {
+	if info.ContentHashKind != storage.HashKindQuick {
+		return info, nil
+	}
+
+	other, err := p.storage.FindContentSource(info, string(v.cfg.OutputFormat), v.cfg.OutputParamsHash())
+	if err != nil || other == "" {
+		return info, err
+	}
+
+	full, err := scanner.ComputeHash(path, p.cfg.DedupHash)
+	if err != nil {
+		return info, err
+	}
+	if otherFull, err := scanner.ComputeHash(other, p.cfg.DedupHash); err == nil && otherFull == full {
+		return info, nil
+	}
+
+	info.ContentSHA256 = full
+	info.ContentHashKind = storage.HashKindFull
+	return info, nil
+}
+
+// processVariant конвертирует файл в один вариант выхода.
+// Возвращает true, если файл сконвертирован в этом запуске и записан в БД.
+func (p *Pool) processVariant(ctx context.Context, file scanner.File, v variant) bool {
 	atomic.AddInt64(&p.stats.Total, 1)
 
-	// Режим dedup: вычисляем sha256 перед проверкой
-	if p.cfg.Mode == config.ModeDedup {
-		sha256, err := scanner.ComputeSHA256(file.Path)
+	// --force: забываем задачи прошлых запусков для этого файла
+	if p.cfg.Force {
+		err := p.storage.ForgetJobs(file.Info, string(v.cfg.OutputFormat), v.cfg.OutputParamsHash(),
+			v.cfg.Mode == config.ModeDedup, p.forceMaxJobID)
 		if err != nil {
-			p.logError(file.Path, fmt.Errorf("не удалось вычислить sha256: %w", err))
-			atomic.AddInt64(&p.stats.Failed, 1)
-			return
+			err = fmt.Errorf("ошибка БД: %w", err)
+			p.logError(file.Path, err)
+			p.writeRunLog(runlog.Entry{Status: runlog.StatusFailed, Src: file.Info.Path, Error: err.Error()})
+			p.addFailed(1)
+			return false
+		}
+	}
+
+	// Быстрый ключ (--dedup-quick) при совпадении проверяется полным хэшем
+	if v.cfg.Mode == config.ModeDedup {
+		info, err := p.verifyQuickKey(file.Info, file.Path, v)
+		if err != nil {
+			err = fmt.Errorf("не удалось проверить дубликат полным хэшем: %w", err)
+			p.logError(file.Path, err)
+			p.writeRunLog(runlog.Entry{Status: runlog.StatusFailed, Src: file.Info.Path, Error: err.Error()})
+			p.addFailed(1)
+			return false
+		}
+		file.Info = info
+	}
+
+	// Dry run: только классифицируем файл, БД не меняется
+	if v.cfg.DryRun {
+		p.planVariant(ctx, file, v)
+		return false
+	}
+
+	// --skip-existing-output: готовый выходной файл не перезаписываем, даже если его нет в БД
+	dstPath := p.dstPath(ctx, file, v)
+	if v.cfg.SkipExistingOutput {
+		if _, err := os.Stat(dstPath); err == nil {
+			p.addSkipped(file, SkipReasonOutputExists)
+			return false
+		}
+	}
+
+	// --max-megapixels: размер исходника проверяется по заголовку до конвертации
+	conv := v.converter
+	if v.cfg.MaxMegapixels > 0 {
+		var skip bool
+		if conv, skip = p.fitMegapixels(ctx, file, v); skip {
+			return false
 		}
-		file.Info.ContentSHA256 = sha256
 	}
 
 	// Пытаемся начать задачу
 	result, err := p.storage.TryStartJob(
 		file.Info,
-		string(p.cfg.OutputFormat),
-		p.cfg.OutputParams(),
-		p.cfg.OutputParamsHash(),
-		p.cfg.Mode == config.ModeDedup,
+		string(v.cfg.OutputFormat),
+		v.cfg.OutputParams(),
+		v.cfg.OutputParamsHash(),
+		v.cfg.Mode == config.ModeDedup,
 	)
+	var restarted bool
+	if err == nil {
+		result, restarted, err = p.restartChangedOutput(file, v, result)
+	}
 
 	if err != nil {
-		p.logError(file.Path, fmt.Errorf("ошибка БД: %w", err))
-		atomic.AddInt64(&p.stats.Failed, 1)
-		return
+		err = fmt.Errorf("ошибка БД: %w", err)
+		p.logError(file.Path, err)
+		p.writeRunLog(runlog.Entry{Status: runlog.StatusFailed, Src: file.Info.Path, Error: err.Error()})
+		p.addFailed(1)
+		return false
 	}
 
 	if !result.Started {
-		// Файл пропущен
-		if p.verbose {
-			if p.progress != nil && !p.progress.IsDisabled() {
-				p.progress.WriteMessage("⏭️  Пропущен: %s (%s)\n", file.RelPath, result.SkipReason)
-			} else {
-				fmt.Printf("⏭️  Пропущен: %s (%s)\n", file.RelPath, result.SkipReason)
-			}
-		}
-		if p.progress != nil {
-			p.progress.IncrementSkipped()
+		// Дубликат по содержимому: ссылка на результат на месте файла в дереве (--dedup-link)
+		if result.Duplicate && v.cfg.DedupLink != "" && v.cfg.DedupLink != config.DedupLinkNone {
+			p.linkVariantDuplicate(ctx, file, v, result.ExistingDstPath)
 		}
-		atomic.AddInt64(&p.stats.Skipped, 1)
-		return
-	}
 
-	// Строим путь к выходному файлу
-	var dstPath string
-	if p.cfg.Mode == config.ModeDedup && !p.cfg.KeepTree {
-		dstPath = p.converter.BuildDstPathDedup(file.Info.ContentSHA256)
-	} else {
-		dstPath = p.converter.BuildDstPath(file.Path)
+		p.addSkipped(file, result.SkipReason)
+		return false
 	}
 
-	// Dry run mode
-	if p.cfg.DryRun {
-		if p.progress != nil && !p.progress.IsDisabled() {
-			p.progress.WriteMessage("🔄 [dry-run] %s -> %s\n", file.RelPath, dstPath)
-		} else {
-			fmt.Printf("🔄 [dry-run] %s -> %s\n", file.RelPath, dstPath)
+	// --on-conflict: выходной файл есть на диске, но не записан в БД
+	// (задача, начатая заново по --verify-outputs, его перезаписывает)
+	if !restarted {
+		var skip bool
+		if dstPath, skip = p.resolveConflict(file, v, result, dstPath); skip {
+			return false
 		}
-		_ = p.storage.FinalizeJobOK(result.JobID, dstPath)
-		if p.progress != nil {
-			p.progress.Increment()
-		}
-		atomic.AddInt64(&p.stats.Processed, 1)
-		return
+	}
+
+	// Запоминаем путь к выходному файлу для --resume
+	if err := p.storage.SetJobDstPath(result.JobID, dstPath); err != nil {
+		p.logError(file.Path, err)
 	}
 
 	// Ограничение памяти: ждём если превышен лимит
@@ -208,49 +608,249 @@ func (p *Pool) processFile(ctx context.Context, file scanner.File) {
 		if err != nil {
 			p.logError(file.Path, fmt.Errorf("memory limiter: %w", err))
 			_ = p.storage.FinalizeJobFailed(result.JobID, err.Error())
-			atomic.AddInt64(&p.stats.Failed, 1)
-			return
+			p.writeRunLog(runlog.Entry{Status: runlog.StatusFailed, Src: file.Info.Path, Dst: dstPath, Error: err.Error()})
+			p.addFailed(1)
+			return false
 		}
 		defer release()
 	}
 
-	// Выполняем конвертацию
-	convResult := p.converter.Convert(ctx, file.Path, dstPath)
+	// Ограничение конвертаций в формат (--concurrency-per-format): ждём свободное место
+	releaseFormat, err := p.acquireFormat(ctx, v.cfg.OutputFormat)
+	if err != nil {
+		p.logError(file.Path, fmt.Errorf("--concurrency-per-format: %w", err))
+		_ = p.storage.FinalizeJobFailed(result.JobID, err.Error())
+		p.writeRunLog(runlog.Entry{Status: runlog.StatusFailed, Src: file.Info.Path, Dst: dstPath, Error: err.Error()})
+		p.addFailed(1)
+		return false
+	}
+	defer releaseFormat()
+
+	// Выполняем конвертацию; с -vv выводим командные строки vips и других программ
+	convCtx := ctx
+	if p.debug {
+		convCtx = converter.WithCommandLog(ctx, func(cmdline string) {
+			p.printMessage("🔧 %s\n", cmdline)
+		})
+	}
+	done := p.metrics.StartConversion()
+	convResult := p.convert(convCtx, file, v, conv, dstPath)
+	done()
+	p.metrics.ObserveDuration(convResult.Duration)
+
+	// RAW без декодера: файл пропускается, задача удаляется, чтобы он
+	// сконвертировался при следующем запуске после установки декодера
+	if !convResult.Success && errors.Is(convResult.Error, converter.ErrNoRawDecoder) {
+		if err := p.storage.DeleteJob(result.JobID); err != nil {
+			p.logError(file.Path, err)
+		}
+		p.addSkipped(file, SkipReasonNoRawDecoder)
+		return false
+	}
+
+	// Нехватка памяти: повтор с теми же параметрами не поможет,
+	// поэтому один раз повторяем с уменьшением изображения
+	downscaled := false
+	if !convResult.Success && !v.cfg.NoOOMRetry && ctx.Err() == nil && converter.IsOutOfMemory(convResult) {
+		if retry := p.retryDownscaled(convCtx, file, v, dstPath); retry != nil {
+			retry.Duration += convResult.Duration
+			convResult, downscaled = retry, retry.Success
+		}
+	}
 
 	if !convResult.Success {
 		p.logError(file.Path, convResult.Error)
 		_ = p.storage.FinalizeJobFailed(result.JobID, convResult.Error.Error())
+		if p.cfg.QuarantineDir != "" {
+			if err := p.quarantine(file, convResult); err != nil {
+				p.logError(file.Path, err)
+			}
+		}
+		p.writeRunLog(runlog.Entry{
+			Status:      runlog.StatusFailed,
+			Src:         file.Info.Path,
+			Dst:         dstPath,
+			DurationSec: convResult.Duration.Seconds(),
+			Error:       convResult.Error.Error(),
+		})
 		if p.progress != nil {
-			p.progress.IncrementFailed()
+			p.progress.IncrementFailed(file.RelPath, file.Info.Size)
 		}
-		atomic.AddInt64(&p.stats.Failed, 1)
-		return
+		p.addFailed(1)
+		return false
 	}
 
 	// Успешно
-	if err := p.storage.FinalizeJobOK(result.JobID, dstPath); err != nil {
-		p.logError(file.Path, fmt.Errorf("не удалось обновить БД: %w", err))
-		atomic.AddInt64(&p.stats.Failed, 1)
-		return
+	// --preserve-mtime: результат получает время исходника (ошибка не отменяет конвертацию)
+	if p.cfg.PreserveMtime {
+		for _, path := range append([]string{dstPath}, convResult.Extra...) {
+			if err := converter.PreserveTimes(file.Path, path); err != nil {
+				p.logError(file.Path, err)
+			}
+		}
 	}
 
-	// Обновляем статистику размеров
-	atomic.AddInt64(&p.stats.InputBytes, file.Info.Size)
+	var dstSize, extraSize int64
 	if outInfo, err := os.Stat(dstPath); err == nil {
-		atomic.AddInt64(&p.stats.OutputBytes, outInfo.Size())
+		dstSize = outInfo.Size()
+	}
+	// Остальные изображения многокадрового файла (--all-images) учитываются
+	// в размере выхода, в БД записывается основной результат
+	for _, path := range convResult.Extra {
+		if outInfo, err := os.Stat(path); err == nil {
+			extraSize += outInfo.Size()
+		}
+	}
+	if err := p.storage.FinalizeJobOK(result.JobID, dstPath, dstSize); err != nil {
+		err = fmt.Errorf("не удалось обновить БД: %w", err)
+		p.logError(file.Path, err)
+		p.writeRunLog(runlog.Entry{Status: runlog.StatusFailed, Src: file.Info.Path, Dst: dstPath, Error: err.Error()})
+		p.addFailed(1)
+		return false
 	}
 
+	// Обновляем статистику размеров
+	atomic.AddInt64(&p.stats.InputBytes, file.Info.Size)
+	atomic.AddInt64(&p.stats.OutputBytes, dstSize+extraSize)
+	p.metrics.AddProcessed(file.Info.Size, dstSize+extraSize)
+
 	if p.verbose {
+		msg := fmt.Sprintf("✅ %s -> %s (%.2fs)\n", file.RelPath, dstPath, convResult.Duration.Seconds())
+		if len(convResult.Extra) > 0 {
+			msg = fmt.Sprintf("✅ %s -> %s (+%d изображений, %.2fs)\n", file.RelPath, dstPath, len(convResult.Extra), convResult.Duration.Seconds())
+		}
 		if p.progress != nil && !p.progress.IsDisabled() {
-			p.progress.WriteMessage("✅ %s -> %s (%.2fs)\n", file.RelPath, dstPath, convResult.Duration.Seconds())
+			p.progress.WriteMessage("%s", msg)
 		} else {
-			fmt.Printf("✅ %s -> %s (%.2fs)\n", file.RelPath, dstPath, convResult.Duration.Seconds())
+			fmt.Print(msg)
 		}
 	}
+	p.writeRunLog(runlog.Entry{
+		Status:      runlog.StatusOK,
+		Src:         file.Info.Path,
+		Dst:         dstPath,
+		DurationSec: convResult.Duration.Seconds(),
+		Downscaled:  downscaled,
+	})
+	if downscaled {
+		atomic.AddInt64(&p.stats.Downscaled, 1)
+	}
 	if p.progress != nil {
-		p.progress.Increment()
+		p.progress.Increment(file.RelPath, file.Info.Size)
 	}
+	p.observeDuration(convResult.Duration)
 	atomic.AddInt64(&p.stats.Processed, 1)
+	return true
+}
+
+// linkVariantDuplicate создаёт ссылку на target на месте дубликата file.
+// Ошибка только логируется: файл всё равно считается пропущенным дубликатом.
+func (p *Pool) linkVariantDuplicate(ctx context.Context, file scanner.File, v variant, target string) {
+	linkPath := p.dstPath(ctx, file, v)
+	if linkPath == target {
+		return
+	}
+	created, err := linkDuplicate(v.cfg.DedupLink, target, linkPath)
+	if err != nil {
+		p.logError(file.Path, err)
+		return
+	}
+	if created && p.verbose {
+		if p.progress != nil && !p.progress.IsDisabled() {
+			p.progress.WriteMessage("🔗 %s -> %s\n", linkPath, target)
+		} else {
+			fmt.Printf("🔗 %s -> %s\n", linkPath, target)
+		}
+	}
+}
+
+// dstPath строит путь к выходному файлу варианта; с --organize-by-date -
+// в директории даты съёмки исходника.
+func (p *Pool) dstPath(ctx context.Context, file scanner.File, v variant) string {
+	if !v.cfg.OrganizeByDate {
+		return buildDstPath(file, v)
+	}
+	relPath := file.RelPath
+	if relPath == "" {
+		relPath = filepath.Base(file.Path)
+	}
+	return converter.BuildDstPathDated(v.cfg, relPath, p.captureTime(ctx, file, v))
+}
+
+// captureTime возвращает дату съёмки исходника из EXIF, а без неё -
+// время модификации файла.
+func (p *Pool) captureTime(ctx context.Context, file scanner.File, v variant) time.Time {
+	t, err := v.converter.CaptureTime(ctx, file.Path)
+	if err != nil {
+		if p.debug {
+			p.printMessage("📅 %s: дата съёмки не прочитана, используется mtime: %v\n", file.RelPath, err)
+		}
+		return time.Unix(file.Info.Mtime, 0)
+	}
+	return t
+}
+
+// buildDstPath строит путь к выходному файлу варианта.
+func buildDstPath(file scanner.File, v variant) string {
+	switch {
+	case v.cfg.Mode == config.ModeDedup && !v.cfg.KeepTree:
+		return v.converter.BuildDstPathDedup(file.Info.ContentSHA256)
+	case file.RelPath != "":
+		return v.converter.BuildDstPathRel(file.RelPath)
+	default:
+		return v.converter.BuildDstPath(file.Path)
+	}
+}
+
+// writeRunLog дописывает запись в журнал обработки, если он включён.
+// Ошибки также учитываются в сводке --report-failures.
+func (p *Pool) writeRunLog(e runlog.Entry) {
+	if e.Status == runlog.StatusFailed {
+		p.recordFailure(e.Src, e.Error)
+	}
+	if p.runLog == nil {
+		return
+	}
+	if err := p.runLog.Write(e); err != nil {
+		p.logError(e.Src, err)
+	}
+}
+
+// addSkipped учитывает пропущенный файл в статистике, журнале и прогрессе.
+func (p *Pool) addSkipped(file scanner.File, reason string) {
+	if p.verbose {
+		if p.progress != nil && !p.progress.IsDisabled() {
+			p.progress.WriteMessage("⏭️  Пропущен: %s (%s)\n", file.RelPath, reason)
+		} else {
+			fmt.Printf("⏭️  Пропущен: %s (%s)\n", file.RelPath, reason)
+		}
+	}
+	p.writeRunLog(runlog.Entry{Status: runlog.StatusSkipped, Src: file.Info.Path, Reason: reason})
+	if p.progress != nil {
+		p.progress.IncrementSkipped(file.RelPath, file.Info.Size)
+	}
+	atomic.AddInt64(&p.stats.Skipped, 1)
+	p.metrics.AddSkipped()
+}
+
+// addFailed учитывает n неудачных задач в статистике и метриках.
+// При --fail-fast первая ошибка останавливает обработку.
+func (p *Pool) addFailed(n int64) {
+	atomic.AddInt64(&p.stats.Failed, n)
+	p.metrics.AddFailed(n)
+	if p.cfg.FailFast {
+		p.halt()
+	}
+}
+
+// halt прекращает выдачу новых файлов и отменяет источник файлов.
+func (p *Pool) halt() {
+	p.halted.Store(true)
+	p.haltOnce.Do(func() {
+		if p.cancel != nil {
+			p.cancel()
+		}
+	})
 }
 
 // logError логирует ошибку.
@@ -271,6 +871,14 @@ func (p *Pool) GetStats() Stats {
 		Total:       atomic.LoadInt64(&p.stats.Total),
 		InputBytes:  atomic.LoadInt64(&p.stats.InputBytes),
 		OutputBytes: atomic.LoadInt64(&p.stats.OutputBytes),
+		Stopped:     p.halted.Load(),
+		Deleted:     atomic.LoadInt64(&p.stats.Deleted),
+		Trashed:     atomic.LoadInt64(&p.stats.Trashed),
+		Downscaled:  atomic.LoadInt64(&p.stats.Downscaled),
+		CacheHits:   atomic.LoadInt64(&p.stats.CacheHits),
+
+		ConvertTime:    time.Duration(atomic.LoadInt64((*int64)(&p.stats.ConvertTime))),
+		MedianDuration: p.durations.median(),
 	}
 }
 
@@ -0,0 +1,895 @@
+package worker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/converter"
+	"github.com/artemshloyda/photoconverter/internal/metrics"
+	"github.com/artemshloyda/photoconverter/internal/progress"
+	"github.com/artemshloyda/photoconverter/internal/runlog"
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+	"github.com/artemshloyda/photoconverter/internal/storage"
+)
+
+// fakeVipsScript имитирует vips: копирует вход в выходной путь (без параметров в [...])
+// и дописывает аргументы вызова, чтобы размер результата зависел от параметров.
+// Файлы, в имени которых есть "broken", завершаются ошибкой; для "truncated"
+// vips завершается успешно, но оставляет пустой файл; "slow" зависает
+// после создания выходного файла; "oom" без уменьшения (thumbnail)
+// завершается нехваткой памяти.
+const fakeVipsScript = `#!/bin/sh
+case "$1" in
+--version) echo "vips-8.15.0"; exit 0 ;;
+esac
+out="${3%%\[*}"
+case "$2" in
+*broken*) echo "VipsForeignLoad: not a known file format" >&2; exit 1 ;;
+*truncated*) : > "$out"; exit 0 ;;
+*slow*) echo partial > "$out"; exec sleep 10 ;;
+*oom*) if [ "$1" != thumbnail ]; then echo "vips: out of memory" >&2; exit 1; fi ;;
+esac
+cp "$2" "$out"
+echo "$*" >> "$out"
+`
+
+// fakeVipsheaderScript имитирует vipsheader: пустой файл не читается,
+// файлы с "huge" в имени - гигапиксельные сканы 40000x30000, с "oom" - 20000x15000.
+// Дата съёмки (-f exif-ifd2-DateTimeOriginal) есть только у файлов с "exif".
+const fakeVipsheaderScript = `#!/bin/sh
+if [ "$1" = "-f" ]; then
+  case "$3" in
+  *exif*) echo "2021:07:04 09:15:00 (2021:07:04 09:15:00, ASCII, 20 components, 20 bytes)"; exit 0 ;;
+  esac
+  echo "vipsheader: field $2 not found" >&2
+  exit 1
+fi
+case "$1" in
+*huge*) echo "$1: 40000x30000 uchar, 3 bands, srgb, tiffload"; exit 0 ;;
+*oom*) echo "$1: 20000x15000 uchar, 3 bands, srgb, tiffload"; exit 0 ;;
+esac
+if [ -s "$1" ]; then
+  echo "$1: 64x48 uchar, 3 bands, srgb, jpegload"
+else
+  echo "vipsheader: unable to load $1" >&2
+  exit 1
+fi
+`
+
+// writeFakeVips создаёт исполняемые скрипты-заглушки vips и vipsheader.
+func writeFakeVips(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vips")
+	if err := os.WriteFile(path, []byte(fakeVipsScript), 0755); err != nil {
+		t.Fatalf("не удалось создать fake vips: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "vipsheader"), []byte(fakeVipsheaderScript), 0755); err != nil {
+		t.Fatalf("не удалось создать fake vipsheader: %v", err)
+	}
+	return path
+}
+
+// newTestEnv создаёт конфигурацию с входными файлами и пул воркеров с заглушкой vips.
+func newTestEnv(t *testing.T, names ...string) (*config.Config, *Pool) {
+	t.Helper()
+	root := t.TempDir()
+
+	cfg := config.DefaultConfig()
+	cfg.InputDir = filepath.Join(root, "in")
+	cfg.OutputDir = filepath.Join(root, "out")
+	cfg.DBPath = filepath.Join(root, "state.sqlite")
+	cfg.Workers = 2
+	cfg.MaxMemoryMB = 0
+
+	if err := os.MkdirAll(cfg.InputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(cfg.InputDir, name), []byte("image:"+name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	st, err := storage.New(cfg.DBPath)
+	if err != nil {
+		t.Fatalf("storage.New: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	conv := converter.New(writeFakeVips(t), cfg)
+	return cfg, New(cfg, st, conv)
+}
+
+// runPool прогоняет все файлы входной директории через пул.
+func runPool(t *testing.T, cfg *config.Config, pool *Pool) Stats {
+	t.Helper()
+	files, errChan := scanner.New(cfg).Scan(context.Background())
+	return pool.Process(context.Background(), files, errChan)
+}
+
+// readRunLog читает записи журнала обработки.
+func readRunLog(t *testing.T, path string) []runlog.Entry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("не удалось открыть журнал: %v", err)
+	}
+	defer f.Close()
+
+	var entries []runlog.Entry
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e runlog.Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("некорректная строка журнала %q: %v", sc.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestPool_RunLog(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg", "b.jpg", "broken.jpg")
+
+	logPath := filepath.Join(t.TempDir(), "logs", "run.jsonl")
+	rl, err := runlog.Open(logPath)
+	if err != nil {
+		t.Fatalf("runlog.Open: %v", err)
+	}
+	pool.SetRunLog(rl)
+
+	stats := runPool(t, cfg, pool)
+	if stats.Processed != 2 || stats.Failed != 1 {
+		t.Fatalf("первый прогон: processed=%d failed=%d, want 2/1", stats.Processed, stats.Failed)
+	}
+
+	// Второй прогон: успешные файлы пропускаются, ошибочный повторяется
+	runPool(t, cfg, pool)
+
+	if err := rl.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	entries := readRunLog(t, logPath)
+	if len(entries) != 6 {
+		t.Fatalf("записей в журнале: %d, want 6", len(entries))
+	}
+
+	counts := map[string]int{}
+	for _, e := range entries {
+		counts[e.Status]++
+		if e.Time.IsZero() {
+			t.Errorf("%s: пустое время", e.Src)
+		}
+		if !strings.HasPrefix(e.Src, cfg.InputDir) {
+			t.Errorf("src = %q, ожидается путь во входной директории", e.Src)
+		}
+
+		switch e.Status {
+		case runlog.StatusOK:
+			if !strings.HasPrefix(e.Dst, cfg.OutputDir) {
+				t.Errorf("%s: dst = %q", e.Src, e.Dst)
+			}
+			if e.Error != "" {
+				t.Errorf("%s: неожиданная ошибка %q", e.Src, e.Error)
+			}
+		case runlog.StatusFailed:
+			if filepath.Base(e.Src) != "broken.jpg" {
+				t.Errorf("неожиданная ошибка для %s", e.Src)
+			}
+			if e.Error == "" {
+				t.Errorf("%s: пустой текст ошибки", e.Src)
+			}
+		case runlog.StatusSkipped:
+			if e.Reason == "" {
+				t.Errorf("%s: пустая причина пропуска", e.Src)
+			}
+		}
+	}
+
+	if counts[runlog.StatusOK] != 2 || counts[runlog.StatusFailed] != 2 || counts[runlog.StatusSkipped] != 2 {
+		t.Errorf("статусы = %v, want ok=2 failed=2 skipped=2", counts)
+	}
+}
+
+func TestPool_MultiPreset(t *testing.T) {
+	cfg, base := newTestEnv(t, "photo.jpg")
+	cfg.MultiPresets = []string{"web", "thumbnail"}
+	pool := New(cfg, base.storage, base.converter)
+
+	if pool.Variants() != 2 {
+		t.Fatalf("Variants() = %d, want 2", pool.Variants())
+	}
+
+	stats := runPool(t, cfg, pool)
+	if stats.Processed != 2 || stats.Failed != 0 {
+		t.Fatalf("processed=%d failed=%d, want 2/0", stats.Processed, stats.Failed)
+	}
+
+	web, err := os.Stat(filepath.Join(cfg.OutputDir, "web", "photo.webp"))
+	if err != nil {
+		t.Fatalf("нет результата пресета web: %v", err)
+	}
+	thumb, err := os.Stat(filepath.Join(cfg.OutputDir, "thumbnail", "photo.webp"))
+	if err != nil {
+		t.Fatalf("нет результата пресета thumbnail: %v", err)
+	}
+	if web.Size() == thumb.Size() {
+		t.Errorf("размеры результатов совпадают (%d), ожидаются разные параметры", web.Size())
+	}
+
+	// Каждый вариант - отдельная задача в БД: повторный запуск пропускает оба
+	stats = runPool(t, cfg, New(cfg, base.storage, base.converter))
+	if stats.Skipped != 2 || stats.Processed != 0 {
+		t.Errorf("повторный запуск: skipped=%d processed=%d, want 2/0", stats.Skipped, stats.Processed)
+	}
+}
+
+func TestPool_DedupHash(t *testing.T) {
+	for _, algorithm := range []string{"sha256", "blake3", "xxhash"} {
+		t.Run(algorithm, func(t *testing.T) {
+			cfg, pool := newTestEnv(t, "a.jpg", "other.jpg")
+			cfg.Mode = config.ModeDedup
+			cfg.DedupHash = algorithm
+			// Один воркер: дубликат проверяется после завершения оригинала
+			cfg.Workers = 1
+			// Плоская структура: имена файлов строятся из хэша
+			cfg.KeepTree = false
+
+			// Копия a.jpg под другим именем должна распознаться по содержимому
+			data, err := os.ReadFile(filepath.Join(cfg.InputDir, "a.jpg"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(cfg.InputDir, "copy.jpg"), data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			stats := runPool(t, cfg, pool)
+			if stats.Processed != 2 || stats.Skipped != 1 || stats.Failed != 0 {
+				t.Errorf("processed=%d skipped=%d failed=%d, want 2/1/0",
+					stats.Processed, stats.Skipped, stats.Failed)
+			}
+
+			entries, err := os.ReadDir(cfg.OutputDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range entries {
+				if name := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())); len(name) != 16 || strings.Contains(name, ":") {
+					t.Errorf("имя выходного файла %q, ожидается 16 символов хэша без префикса алгоритма", e.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestPool_DedupQuickFallback(t *testing.T) {
+	cfg, pool := newTestEnv(t)
+	cfg.Mode = config.ModeDedup
+	cfg.DedupQuick = true
+	cfg.DedupQuickBytes = 4
+	cfg.Workers = 1
+
+	// b.jpg совпадает с a.jpg по размеру, началу и концу, но не по середине;
+	// c.jpg - точная копия a.jpg
+	files := map[string]string{
+		"a.jpg": "HEAD-middle-one-TAIL",
+		"b.jpg": "HEAD-middle-two-TAIL",
+		"c.jpg": "HEAD-middle-one-TAIL",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(cfg.InputDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logPath := filepath.Join(t.TempDir(), "run.jsonl")
+	rl, err := runlog.Open(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.SetRunLog(rl)
+
+	stats := runPool(t, cfg, pool)
+	if err := rl.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Processed != 2 || stats.Skipped != 1 || stats.Failed != 0 {
+		t.Fatalf("processed=%d skipped=%d failed=%d, want 2/1/0", stats.Processed, stats.Skipped, stats.Failed)
+	}
+	for _, e := range readRunLog(t, logPath) {
+		name := filepath.Base(e.Src)
+		if name == "b.jpg" && e.Status != runlog.StatusOK {
+			t.Errorf("b.jpg: статус %s, файл с другой серединой не должен считаться дубликатом", e.Status)
+		}
+		if name == "c.jpg" && e.Status != runlog.StatusSkipped {
+			t.Errorf("c.jpg: статус %s, точная копия должна быть пропущена", e.Status)
+		}
+	}
+}
+
+func TestPool_Force(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg", "b.jpg")
+
+	if stats := runPool(t, cfg, pool); stats.Processed != 2 {
+		t.Fatalf("первый прогон: processed=%d, want 2", stats.Processed)
+	}
+
+	// Состариваем результаты, чтобы заметить перезапись
+	old := time.Now().Add(-time.Hour)
+	outputs, err := filepath.Glob(filepath.Join(cfg.OutputDir, "*"))
+	if err != nil || len(outputs) != 2 {
+		t.Fatalf("выходные файлы: %v (%v)", outputs, err)
+	}
+	for _, out := range outputs {
+		if err := os.Chtimes(out, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Без --force файлы пропускаются
+	if stats := runPool(t, cfg, pool); stats.Skipped != 2 {
+		t.Fatalf("повторный прогон: skipped=%d, want 2", stats.Skipped)
+	}
+
+	cfg.Force = true
+	forced := New(cfg, pool.storage, pool.converter)
+	stats := runPool(t, cfg, forced)
+	if stats.Processed != 2 || stats.Skipped != 0 || stats.Failed != 0 {
+		t.Fatalf("--force: processed=%d skipped=%d failed=%d, want 2/0/0",
+			stats.Processed, stats.Skipped, stats.Failed)
+	}
+	for _, out := range outputs {
+		info, err := os.Stat(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.ModTime().After(old) {
+			t.Errorf("%s не перезаписан при --force", out)
+		}
+	}
+
+	// Новый результат записан в БД: следующий обычный прогон снова пропускает файлы
+	cfg.Force = false
+	if stats := runPool(t, cfg, New(cfg, pool.storage, pool.converter)); stats.Skipped != 2 {
+		t.Errorf("прогон после --force: skipped=%d, want 2", stats.Skipped)
+	}
+}
+
+// В режиме dedup --force забывает прошлые задачи, но дубликаты внутри запуска пропускаются.
+func TestPool_ForceDedup(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg")
+	cfg.Mode = config.ModeDedup
+	cfg.Workers = 1
+	data, err := os.ReadFile(filepath.Join(cfg.InputDir, "a.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.InputDir, "b.jpg"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats := runPool(t, cfg, pool); stats.Processed != 1 || stats.Skipped != 1 {
+		t.Fatalf("первый прогон: processed=%d skipped=%d, want 1/1", stats.Processed, stats.Skipped)
+	}
+
+	cfg.Force = true
+	stats := runPool(t, cfg, New(cfg, pool.storage, pool.converter))
+	if stats.Processed != 1 || stats.Skipped != 1 || stats.Failed != 0 {
+		t.Errorf("--force: processed=%d skipped=%d failed=%d, want 1/1/0",
+			stats.Processed, stats.Skipped, stats.Failed)
+	}
+}
+
+func TestPool_VerifyTruncatedOutput(t *testing.T) {
+	cfg, pool := newTestEnv(t, "ok.jpg", "truncated.jpg")
+
+	logPath := filepath.Join(t.TempDir(), "run.jsonl")
+	rl, err := runlog.Open(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.SetRunLog(rl)
+
+	stats := runPool(t, cfg, pool)
+	if err := rl.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Processed != 1 || stats.Failed != 1 {
+		t.Fatalf("processed=%d failed=%d, want 1/1", stats.Processed, stats.Failed)
+	}
+
+	for _, e := range readRunLog(t, logPath) {
+		if filepath.Base(e.Src) != "truncated.jpg" {
+			continue
+		}
+		if e.Status != runlog.StatusFailed || !strings.Contains(e.Error, "проверка результата") {
+			t.Errorf("truncated.jpg: status=%s error=%q, want failed с ошибкой проверки", e.Status, e.Error)
+		}
+	}
+
+	// Ни результата, ни временного файла не остаётся
+	matches, _ := filepath.Glob(filepath.Join(cfg.OutputDir, "truncated*"))
+	if len(matches) != 0 {
+		t.Errorf("остались файлы: %v", matches)
+	}
+
+	// Задача записана в БД как failed
+	jobs, err := pool.storage.ListJobsForRun(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range jobs {
+		if filepath.Base(job.SrcPath) == "truncated.jpg" && job.Status != storage.StatusFailed {
+			t.Errorf("truncated.jpg: статус задачи %s, want failed", job.Status)
+		}
+	}
+}
+
+func TestPool_NoVerify(t *testing.T) {
+	cfg, pool := newTestEnv(t, "truncated.jpg")
+	cfg.NoVerify = true
+
+	if stats := runPool(t, cfg, pool); stats.Processed != 1 || stats.Failed != 0 {
+		t.Errorf("--no-verify: processed=%d failed=%d, want 1/0", stats.Processed, stats.Failed)
+	}
+}
+
+func TestPool_Timeout(t *testing.T) {
+	cfg, pool := newTestEnv(t, "ok.jpg", "slow.jpg")
+	pool.converter.(*converter.VipsConverter).SetTimeout(300 * time.Millisecond)
+
+	logPath := filepath.Join(t.TempDir(), "run.jsonl")
+	rl, err := runlog.Open(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.SetRunLog(rl)
+
+	start := time.Now()
+	stats := runPool(t, cfg, pool)
+	if err := rl.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("обработка заняла %s, таймаут не сработал", elapsed)
+	}
+	if stats.Processed != 1 || stats.Failed != 1 {
+		t.Fatalf("processed=%d failed=%d, want 1/1", stats.Processed, stats.Failed)
+	}
+	for _, e := range readRunLog(t, logPath) {
+		if filepath.Base(e.Src) == "slow.jpg" && !strings.Contains(e.Error, "timed out") {
+			t.Errorf("slow.jpg: error=%q, ожидается сообщение о таймауте", e.Error)
+		}
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(cfg.OutputDir, "slow*"))
+	if len(matches) != 0 {
+		t.Errorf("временный файл не удалён: %v", matches)
+	}
+}
+
+func TestPool_RawWithoutDecoderSkipped(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg", "shot.arw")
+	pool.converter.(*converter.VipsConverter).SetRawDecoderPath("")
+
+	logPath := filepath.Join(t.TempDir(), "run.jsonl")
+	rl, err := runlog.Open(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.SetRunLog(rl)
+
+	stats := runPool(t, cfg, pool)
+	if err := rl.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Processed != 1 || stats.Skipped != 1 || stats.Failed != 0 {
+		t.Fatalf("processed=%d skipped=%d failed=%d, want 1/1/0", stats.Processed, stats.Skipped, stats.Failed)
+	}
+	for _, e := range readRunLog(t, logPath) {
+		if filepath.Base(e.Src) == "shot.arw" && e.Reason != SkipReasonNoRawDecoder {
+			t.Errorf("shot.arw: status=%s reason=%q, want %q", e.Status, e.Reason, SkipReasonNoRawDecoder)
+		}
+	}
+
+	// Задача не сохраняется: после установки декодера RAW будет сконвертирован
+	jobs, err := pool.storage.FindJobsBySrc(filepath.Join(cfg.InputDir, "shot.arw"))
+	if err != nil || len(jobs) != 0 {
+		t.Errorf("задачи shot.arw = %v, %v, want нет", jobs, err)
+	}
+}
+
+func TestPool_Metrics(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg", "broken.jpg")
+	m := metrics.New()
+	pool.SetMetrics(m)
+
+	runPool(t, cfg, pool)
+	// Повторный прогон: a.jpg пропускается, broken.jpg снова падает
+	again := New(cfg, pool.storage, pool.converter)
+	again.SetMetrics(m)
+	runPool(t, cfg, again)
+
+	srv := httptest.NewServer(m.Handler())
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	for _, want := range []string{
+		"photoconverter_files_processed_total 1",
+		"photoconverter_files_skipped_total 1",
+		"photoconverter_files_failed_total 2",
+		"photoconverter_conversions_in_flight 0",
+		"photoconverter_conversion_duration_seconds_count 3",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("нет строки %q в /metrics:\n%s", want, body)
+		}
+	}
+}
+
+func TestPool_Quarantine(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg")
+	cfg.QuarantineDir = filepath.Join(t.TempDir(), "quarantine")
+
+	sub := filepath.Join(cfg.InputDir, "sub")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, "broken.jpg"), []byte("corrupt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := runPool(t, cfg, pool)
+	if stats.Processed != 1 || stats.Failed != 1 {
+		t.Fatalf("processed=%d failed=%d, want 1/1", stats.Processed, stats.Failed)
+	}
+
+	qPath := filepath.Join(cfg.QuarantineDir, "sub", "broken.jpg")
+	data, err := os.ReadFile(qPath)
+	if err != nil {
+		t.Fatalf("файл не попал в карантин: %v", err)
+	}
+	if string(data) != "corrupt" {
+		t.Errorf("содержимое копии: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(sub, "broken.jpg")); err != nil {
+		t.Errorf("исходник должен остаться на месте: %v", err)
+	}
+
+	errText, err := os.ReadFile(qPath + ".error.txt")
+	if err != nil {
+		t.Fatalf("нет файла ошибки: %v", err)
+	}
+	if !strings.Contains(string(errText), "not a known file format") {
+		t.Errorf("в файле ошибки нет stderr vips:\n%s", errText)
+	}
+	if !strings.Contains(string(errText), "command:\n") || !strings.Contains(string(errText), " copy "+filepath.Join(sub, "broken.jpg")+" ") {
+		t.Errorf("в файле ошибки нет командной строки vips:\n%s", errText)
+	}
+
+	if _, err := os.Stat(filepath.Join(cfg.QuarantineDir, "a.jpg")); !os.IsNotExist(err) {
+		t.Errorf("успешно сконвертированный файл не должен попадать в карантин: %v", err)
+	}
+}
+
+func TestPool_DedupBatchedWrites(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg", "other.jpg")
+	cfg.Mode = config.ModeDedup
+	cfg.Workers = 1
+	// Пакет больше числа файлов: завершения ждут записи до конца прогона
+	pool.storage.EnableBatching(100, time.Hour)
+
+	data, err := os.ReadFile(filepath.Join(cfg.InputDir, "a.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.InputDir, "copy.jpg"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := runPool(t, cfg, pool)
+	if stats.Processed != 2 || stats.Skipped != 1 || stats.Failed != 0 {
+		t.Fatalf("processed=%d skipped=%d failed=%d, want 2/1/0", stats.Processed, stats.Skipped, stats.Failed)
+	}
+
+	// Повторный прогон видит записанные пакеты: всё пропускается
+	if stats := runPool(t, cfg, New(cfg, pool.storage, pool.converter)); stats.Skipped != 3 {
+		t.Errorf("повторный прогон: skipped=%d, want 3", stats.Skipped)
+	}
+	_, ok, _, inProgress, err := pool.storage.GetStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok != 2 || inProgress != 0 {
+		t.Errorf("ok=%d in_progress=%d, want 2/0", ok, inProgress)
+	}
+}
+
+// recordingEmitter запоминает обновления прогресса.
+type recordingEmitter struct {
+	mu      sync.Mutex
+	updates []progress.Update
+}
+
+func (e *recordingEmitter) Emit(u progress.Update) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.updates = append(e.updates, u)
+}
+
+func TestPool_DedupHashPhaseProgress(t *testing.T) {
+	cfg, pool := newTestEnv(t)
+	cfg.Mode = config.ModeDedup
+	cfg.KeepTree = false
+	// Один воркер: дубликат проверяется после завершения оригинала
+	cfg.Workers = 1
+
+	// Крупные файлы разного содержимого и копия одного из них
+	for i, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		data := bytes.Repeat([]byte{byte('a' + i)}, 4<<20)
+		if err := os.WriteFile(filepath.Join(cfg.InputDir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(cfg.InputDir, "a.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.InputDir, "copy.jpg"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &recordingEmitter{}
+	bar := progress.New(progress.Options{Total: 4, Emitter: rec})
+	pool.SetProgressBar(bar)
+
+	stats := runPool(t, cfg, pool)
+	if stats.Processed != 3 || stats.Skipped != 1 || stats.Failed != 0 {
+		t.Fatalf("processed=%d skipped=%d failed=%d, want 3/1/0", stats.Processed, stats.Skipped, stats.Failed)
+	}
+
+	var phaseEvents, convEvents int
+	firstConv := -1
+	for i, u := range rec.updates {
+		if u.Phase != "hash" || u.PhaseTotal != 4 {
+			t.Fatalf("обновление %d без этапа хэширования: %+v", i, u)
+		}
+		switch u.Event {
+		case "phase":
+			phaseEvents++
+		case "processed", "skipped":
+			convEvents++
+			if firstConv < 0 {
+				firstConv = i
+				if u.PhaseDone == 0 {
+					t.Error("конвертация началась до хэширования файла")
+				}
+			}
+		}
+	}
+	if phaseEvents != 4 || convEvents != 4 {
+		t.Errorf("событий хэширования %d, конвертации %d, want 4 и 4", phaseEvents, convEvents)
+	}
+	if done, failed := pool.hashPhase.Stats(); done != 4 || failed != 0 {
+		t.Errorf("этап хэширования: done=%d failed=%d, want 4/0", done, failed)
+	}
+}
+
+func TestPool_PreserveMtime(t *testing.T) {
+	for _, preserve := range []bool{true, false} {
+		cfg, pool := newTestEnv(t, "a.jpg")
+		cfg.PreserveMtime = preserve
+
+		src := filepath.Join(cfg.InputDir, "a.jpg")
+		mtime := time.Date(2019, 7, 14, 12, 30, 0, 0, time.UTC)
+		if err := os.Chtimes(src, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+
+		if stats := runPool(t, cfg, pool); stats.Processed != 1 {
+			t.Fatalf("processed=%d, want 1", stats.Processed)
+		}
+		outputs, err := filepath.Glob(filepath.Join(cfg.OutputDir, "a.*"))
+		if err != nil || len(outputs) != 1 {
+			t.Fatalf("выходные файлы: %v (%v)", outputs, err)
+		}
+		info, err := os.Stat(outputs[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		diff := info.ModTime().Sub(mtime).Abs()
+		if preserve && diff > time.Second {
+			t.Errorf("--preserve-mtime: mtime результата %v, want %v", info.ModTime(), mtime)
+		}
+		if !preserve && diff < time.Hour {
+			t.Errorf("без --preserve-mtime mtime результата не должен меняться: %v", info.ModTime())
+		}
+	}
+}
+
+func TestPool_FailFast(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a_broken.jpg", "b.jpg", "c.jpg", "d.jpg", "e.jpg")
+	cfg.FailFast = true
+	cfg.Workers = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool.SetCancel(cancel)
+
+	files, errChan := scanner.New(cfg).Scan(ctx)
+	stats := pool.Process(context.Background(), files, errChan)
+
+	if stats.Failed != 1 || stats.Processed != 0 || !stats.Stopped {
+		t.Fatalf("failed=%d processed=%d stopped=%v, want 1/0/true", stats.Failed, stats.Processed, stats.Stopped)
+	}
+	if ctx.Err() == nil {
+		t.Error("контекст источника не отменён")
+	}
+	matches, _ := filepath.Glob(filepath.Join(cfg.OutputDir, "*"))
+	if len(matches) != 0 {
+		t.Errorf("после первой ошибки обработаны файлы: %v", matches)
+	}
+}
+
+func TestPool_ContinueOnError(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a_broken.jpg", "b.jpg", "c.jpg")
+	cfg.Workers = 1
+
+	stats := runPool(t, cfg, pool)
+	if stats.Failed != 1 || stats.Processed != 2 || stats.Stopped {
+		t.Errorf("failed=%d processed=%d stopped=%v, want 1/2/false", stats.Failed, stats.Processed, stats.Stopped)
+	}
+}
+
+func TestPool_SingleFileInput(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg", "b.jpg")
+	cfg.InputDir = filepath.Join(cfg.InputDir, "a.jpg")
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate с файлом в --in: %v", err)
+	}
+
+	if stats := runPool(t, cfg, pool); stats.Processed != 1 || stats.Total != 1 {
+		t.Fatalf("processed=%d total=%d, want 1/1", stats.Processed, stats.Total)
+	}
+	outputs, err := filepath.Glob(filepath.Join(cfg.OutputDir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(cfg.OutputDir, "a."+string(cfg.OutputFormat))
+	if len(outputs) != 1 || outputs[0] != want {
+		t.Errorf("выходные файлы: %v, want [%s]", outputs, want)
+	}
+}
+
+func TestPool_SkipExistingOutput(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg", "b.jpg")
+	cfg.SkipExistingOutput = true
+
+	// Результат прошлой утилиты: в БД его нет
+	existing := filepath.Join(cfg.OutputDir, "a."+string(cfg.OutputFormat))
+	if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(existing, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logPath := filepath.Join(t.TempDir(), "run.jsonl")
+	rl, err := runlog.Open(logPath)
+	if err != nil {
+		t.Fatalf("runlog.Open: %v", err)
+	}
+	pool.SetRunLog(rl)
+
+	stats := runPool(t, cfg, pool)
+	if err := rl.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if stats.Processed != 1 || stats.Skipped != 1 {
+		t.Fatalf("processed=%d skipped=%d, want 1/1", stats.Processed, stats.Skipped)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "old" {
+		t.Errorf("существующий выходной файл перезаписан: %q", data)
+	}
+
+	var reasons []string
+	for _, e := range readRunLog(t, logPath) {
+		if e.Status == runlog.StatusSkipped {
+			reasons = append(reasons, e.Reason)
+		}
+	}
+	if len(reasons) != 1 || reasons[0] != SkipReasonOutputExists {
+		t.Errorf("причины пропуска: %v, want [%s]", reasons, SkipReasonOutputExists)
+	}
+}
+
+func TestPool_DebugLogsVipsCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		verbosity int
+		wantCmd   bool
+		wantFile  bool
+	}{
+		{name: "quiet", verbosity: config.VerbosityQuiet},
+		{name: "normal", verbosity: config.VerbosityNormal},
+		{name: "verbose", verbosity: config.VerbosityVerbose, wantFile: true},
+		{name: "debug", verbosity: config.VerbosityDebug, wantCmd: true, wantFile: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, pool := newTestEnv(t, "a.jpg")
+			cfg.Verbosity = tt.verbosity
+			pool = New(cfg, pool.storage, pool.converter)
+
+			var buf bytes.Buffer
+			pool.SetProgressBar(progress.New(progress.Options{Total: 1, Writer: &buf}))
+			if stats := runPool(t, cfg, pool); stats.Processed != 1 {
+				t.Fatalf("Processed = %d, want 1", stats.Processed)
+			}
+
+			// Командная строка: путь к vips, операция и вход
+			src := filepath.Join(cfg.InputDir, "a.jpg")
+			gotCmd := strings.Contains(buf.String(), "🔧 ") &&
+				strings.Contains(buf.String(), "/vips copy "+src+" ")
+			if gotCmd != tt.wantCmd {
+				t.Errorf("командная строка vips в выводе = %v, want %v:\n%s", gotCmd, tt.wantCmd, buf.String())
+			}
+			if gotFile := strings.Contains(buf.String(), "✅ a.jpg"); gotFile != tt.wantFile {
+				t.Errorf("строка о файле в выводе = %v, want %v:\n%s", gotFile, tt.wantFile, buf.String())
+			}
+		})
+	}
+}
+
+func TestPool_DetectByContent(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg", "notes.dat")
+	// JPEG с расширением .dat (камера) и файл без расширения
+	jpegHead := []byte("\xFF\xD8\xFF\xE0\x00\x10JFIF\x00")
+	for _, name := range []string{"photo.dat", "IMG0001"} {
+		if err := os.WriteFile(filepath.Join(cfg.InputDir, name), jpegHead, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Без определения по содержимому - только по расширению
+	if count, err := scanner.New(cfg).CountFiles(); err != nil || count != 1 {
+		t.Fatalf("без --detect-by-content найдено %d (%v), want 1", count, err)
+	}
+
+	cfg.DetectByContent = true
+	stats := runPool(t, cfg, pool)
+	if stats.Processed != 3 || stats.Failed != 0 {
+		t.Fatalf("processed=%d failed=%d, want 3/0 (notes.dat не изображение)", stats.Processed, stats.Failed)
+	}
+	for _, name := range []string{"a", "photo", "IMG0001"} {
+		if _, err := os.Stat(filepath.Join(cfg.OutputDir, name+"."+cfg.OutputExt())); err != nil {
+			t.Errorf("результат %s: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cfg.OutputDir, "notes."+cfg.OutputExt())); err == nil {
+		t.Error("notes.dat без сигнатуры изображения сконвертирован")
+	}
+}
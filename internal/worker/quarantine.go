@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/artemshloyda/photoconverter/internal/converter"
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+)
+
+// quarantineErrorSuffix - суффикс файла с текстом ошибки рядом с копией исходника.
+const quarantineErrorSuffix = ".error.txt"
+
+// quarantine копирует исходник, который не удалось сконвертировать, в cfg.QuarantineDir
+// с сохранением относительного пути и пишет рядом <имя>.error.txt с ошибкой, командной
+// строкой и stderr vips. Исходный файл не удаляется: карантин - копия для ручного разбора.
+func (p *Pool) quarantine(file scanner.File, res *converter.ConvertResult) error {
+	dst := filepath.Join(p.cfg.QuarantineDir, file.RelPath)
+	if file.RelPath == "" {
+		dst = filepath.Join(p.cfg.QuarantineDir, filepath.Base(file.Path))
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("не удалось создать директорию карантина: %w", err)
+	}
+	if err := copyFile(file.Path, dst); err != nil {
+		return fmt.Errorf("не удалось скопировать в карантин: %w", err)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "source: %s\nerror: %v\n", file.Info.Path, res.Error)
+	if res.Command != "" {
+		fmt.Fprintf(&sb, "\ncommand:\n%s\n", res.Command)
+	}
+	if stderr := strings.TrimSpace(res.Stderr); stderr != "" {
+		fmt.Fprintf(&sb, "\nvips stderr:\n%s\n", stderr)
+	}
+	if err := os.WriteFile(dst+quarantineErrorSuffix, []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("не удалось записать %s: %w", dst+quarantineErrorSuffix, err)
+	}
+	return nil
+}
+
+// copyFile копирует файл src в dst, перезаписывая dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+/*
+Возможные расширения:
+- Режим перемещения вместо копирования
+- Повторная обработка файлов из карантина отдельной командой
+*/
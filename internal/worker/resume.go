@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/artemshloyda/photoconverter/internal/converter"
+	"github.com/artemshloyda/photoconverter/internal/storage"
+)
+
+// ResumeStats - итоги разбора прерванных задач (--resume).
+type ResumeStats struct {
+	// Finalized - задачи, результат которых уже записан и прошёл проверку.
+	Finalized int64
+
+	// Retried - задачи без готового результата, они будут выполнены заново.
+	Retried int64
+}
+
+// Resume разбирает задачи, прерванные при предыдущем запуске, вместо того чтобы
+// пометить их failed. Если выходной файл задачи записан (после начала задачи)
+// и проходит проверку, задача завершается как ok; иначе временный файл удаляется,
+// а задача удаляется из БД, чтобы файл был обработан заново как новый.
+func (p *Pool) Resume(ctx context.Context) (ResumeStats, error) {
+	var stats ResumeStats
+
+	jobs, err := p.storage.ListInProgress()
+	if err != nil {
+		return stats, err
+	}
+
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			return stats, ctx.Err()
+		}
+
+		if job.DstPath != nil {
+			_ = os.Remove(converter.TempPath(*job.DstPath))
+			if size, ok := p.resumableOutput(ctx, job); ok {
+				if err := p.storage.FinalizeJobOK(job.ID, *job.DstPath, size); err == nil {
+					stats.Finalized++
+					continue
+				}
+			}
+		}
+
+		if err := p.storage.DeleteJob(job.ID); err != nil {
+			return stats, err
+		}
+		stats.Retried++
+	}
+	return stats, nil
+}
+
+// resumableOutput проверяет, что выходной файл прерванной задачи записан ею самой
+// (не раньше начала задачи) и является корректным изображением. Возвращает размер файла.
+func (p *Pool) resumableOutput(ctx context.Context, job storage.Job) (int64, bool) {
+	info, err := os.Stat(*job.DstPath)
+	if err != nil || info.IsDir() || info.Size() == 0 {
+		return 0, false
+	}
+	// Файл от прошлой конвертации (например, перед --force) - не результат этой задачи
+	if job.StartedAt != nil && info.ModTime().Unix() < job.StartedAt.Unix() {
+		return 0, false
+	}
+	if !p.cfg.NoVerify {
+		if err := p.converter.VerifyOutput(ctx, *job.DstPath); err != nil {
+			if p.verbose {
+				p.logError(job.SrcPath, fmt.Errorf("--resume: %w", err))
+			}
+			return 0, false
+		}
+	}
+	return info.Size(), true
+}
+
+/*
+Возможные расширения:
+- Разбор прерванных задач без полного запуска (отдельная команда resume)
+- Проверка результата по ожидаемым размерам после resize
+*/
@@ -0,0 +1,107 @@
+package worker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/converter"
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+)
+
+// seedInterrupted создаёт задачи in_progress для всех входных файлов,
+// как если бы предыдущий запуск упал посреди конвертации.
+func seedInterrupted(t *testing.T, pool *Pool) map[string]string {
+	t.Helper()
+	dsts := make(map[string]string)
+	files, _ := scanner.New(pool.cfg).Scan(context.Background())
+	for file := range files {
+		v := pool.variants[0]
+		res, err := pool.storage.TryStartJob(file.Info, string(v.cfg.OutputFormat), v.cfg.OutputParams(),
+			v.cfg.OutputParamsHash(), false)
+		if err != nil || !res.Started {
+			t.Fatalf("TryStartJob(%s): %+v, %v", file.RelPath, res, err)
+		}
+		dst := buildDstPath(file, v)
+		if err := pool.storage.SetJobDstPath(res.JobID, dst); err != nil {
+			t.Fatal(err)
+		}
+		dsts[file.RelPath] = dst
+	}
+	return dsts
+}
+
+func TestPool_Resume(t *testing.T) {
+	cfg, pool := newTestEnv(t, "done.jpg", "partial.jpg")
+	dsts := seedInterrupted(t, pool)
+
+	// done.jpg успел переименоваться в итоговый файл, partial.jpg остался во временном
+	if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dsts["done.jpg"], []byte("converted"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tmp := converter.TempPath(dsts["partial.jpg"])
+	if err := os.WriteFile(tmp, []byte("half"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := pool.Resume(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Finalized != 1 || resumed.Retried != 1 {
+		t.Fatalf("finalized=%d retried=%d, want 1/1", resumed.Finalized, resumed.Retried)
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Errorf("временный файл прерванной задачи не удалён: %v", err)
+	}
+
+	_, ok, _, inProgress, err := pool.storage.GetStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok != 1 || inProgress != 0 {
+		t.Errorf("ok=%d in_progress=%d, want 1/0", ok, inProgress)
+	}
+
+	// Готовый результат не конвертируется заново, прерванный файл обрабатывается
+	stats := runPool(t, cfg, pool)
+	if stats.Skipped != 1 || stats.Processed != 1 || stats.Failed != 0 {
+		t.Fatalf("skipped=%d processed=%d failed=%d, want 1/1/0", stats.Skipped, stats.Processed, stats.Failed)
+	}
+	if data, _ := os.ReadFile(dsts["done.jpg"]); string(data) != "converted" {
+		t.Errorf("результат done.jpg перезаписан: %q", data)
+	}
+}
+
+func TestPool_ResumeRejectsEmptyOutput(t *testing.T) {
+	_, pool := newTestEnv(t, "empty.jpg")
+	dsts := seedInterrupted(t, pool)
+
+	if err := os.MkdirAll(filepath.Dir(dsts["empty.jpg"]), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dsts["empty.jpg"], nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := pool.Resume(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Finalized != 0 || resumed.Retried != 1 {
+		t.Errorf("finalized=%d retried=%d, want 0/1", resumed.Finalized, resumed.Retried)
+	}
+	// Задача удалена: при следующем сканировании файл будет новым
+	jobs, err := pool.storage.ListJobsForRun(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 0 {
+		t.Errorf("задачи в БД: %+v, want нет", jobs)
+	}
+}
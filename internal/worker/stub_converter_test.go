@@ -0,0 +1,145 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/converter"
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+)
+
+// stubConverter - конвертер без vips: записывает в результат имя исходника
+// и сообщает заданную длительность. Файлы с "broken" в имени - ошибка.
+type stubConverter struct {
+	cfg      *config.Config
+	duration time.Duration
+	calls    *atomic.Int64
+}
+
+func (s *stubConverter) Convert(_ context.Context, srcPath, dstPath string) *converter.ConvertResult {
+	s.calls.Add(1)
+	if strings.Contains(srcPath, "broken") {
+		return &converter.ConvertResult{Error: errors.New("stub: not a known file format"), Duration: s.duration}
+	}
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return &converter.ConvertResult{Error: err}
+	}
+	if err := os.WriteFile(dstPath, []byte("converted:"+filepath.Base(srcPath)), 0644); err != nil {
+		return &converter.ConvertResult{Error: err}
+	}
+	return &converter.ConvertResult{Success: true, DstPath: dstPath, Duration: s.duration}
+}
+
+func (s *stubConverter) BuildDstPath(srcPath string) string {
+	return s.BuildDstPathRel(filepath.Base(srcPath))
+}
+
+func (s *stubConverter) BuildDstPathRel(relPath string) string {
+	stem := strings.TrimSuffix(relPath, filepath.Ext(relPath))
+	return filepath.Join(s.cfg.OutputDir, stem+"."+string(s.cfg.OutputFormat))
+}
+
+func (s *stubConverter) BuildDstPathDedup(contentSHA256 string) string {
+	return filepath.Join(s.cfg.OutputDir, contentSHA256+"."+string(s.cfg.OutputFormat))
+}
+
+func (s *stubConverter) WithConfig(cfg *config.Config) converter.Converter {
+	return &stubConverter{cfg: cfg, duration: s.duration, calls: s.calls}
+}
+
+func (s *stubConverter) ImageSize(context.Context, string) (int, int, error) { return 64, 48, nil }
+func (s *stubConverter) VerifyOutput(context.Context, string) error          { return nil }
+func (s *stubConverter) CheckHealth() error                                  { return nil }
+
+// CaptureTime сообщает, что даты съёмки нет: --organize-by-date берёт mtime.
+func (s *stubConverter) CaptureTime(context.Context, string) (time.Time, error) {
+	return time.Time{}, errors.New("stub: нет EXIF")
+}
+
+// newStubEnv создаёт окружение как newTestEnv, но пул работает через stubConverter.
+func newStubEnv(t *testing.T, duration time.Duration, names ...string) (*config.Config, *Pool, *stubConverter) {
+	t.Helper()
+	cfg, pool := newTestEnv(t, names...)
+	stub := &stubConverter{cfg: cfg, duration: duration, calls: &atomic.Int64{}}
+	return cfg, New(cfg, pool.storage, stub), stub
+}
+
+func TestPool_StubConverter(t *testing.T) {
+	cfg, pool, stub := newStubEnv(t, 0, "a.jpg", "b.jpg", "broken.jpg")
+
+	stats := runPool(t, cfg, pool)
+	if stats.Processed != 2 || stats.Failed != 1 {
+		t.Fatalf("processed=%d failed=%d, want 2/1", stats.Processed, stats.Failed)
+	}
+	data, err := os.ReadFile(filepath.Join(cfg.OutputDir, "a."+string(cfg.OutputFormat)))
+	if err != nil || string(data) != "converted:a.jpg" {
+		t.Errorf("результат a = %q, %v", data, err)
+	}
+
+	// Повторный прогон: успешные пропускаются по БД, ошибочный повторяется
+	stub.calls.Store(0)
+	stats = runPool(t, cfg, New(cfg, pool.storage, stub))
+	if stats.Skipped != 2 || stats.Failed != 1 || stub.calls.Load() != 1 {
+		t.Errorf("повтор: skipped=%d failed=%d вызовов=%d, want 2/1/1", stats.Skipped, stats.Failed, stub.calls.Load())
+	}
+}
+
+func TestPool_ReleasesTempFiles(t *testing.T) {
+	cfg, pool, stub := newStubEnv(t, 0, "a.jpg", "b.jpg", "broken.jpg")
+
+	// Временные копии, как у файлов из архива: удаляются после обработки,
+	// в том числе с ошибкой конвертации
+	tempDir := t.TempDir()
+	scanned, errChan := scanner.New(cfg).Scan(context.Background())
+	files := make(chan scanner.File, 3)
+	var copies []string
+	for file := range scanned {
+		data, err := os.ReadFile(file.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Path = filepath.Join(tempDir, file.RelPath)
+		if err := os.WriteFile(file.Path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		file.Temp = true
+		files <- file
+		copies = append(copies, file.Path)
+	}
+	close(files)
+
+	stats := pool.Process(context.Background(), files, errChan)
+	if stats.Processed != 2 || stats.Failed != 1 || stub.calls.Load() != 3 {
+		t.Fatalf("processed=%d failed=%d вызовов=%d, want 2/1/3", stats.Processed, stats.Failed, stub.calls.Load())
+	}
+	for _, path := range copies {
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("временная копия %s не удалена: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cfg.InputDir, "a.jpg")); err != nil {
+		t.Errorf("обычный исходник не должен удаляться: %v", err)
+	}
+}
+
+func TestPool_StubConverterTiming(t *testing.T) {
+	cfg, pool, _ := newStubEnv(t, 40*time.Millisecond, "a.jpg", "b.jpg", "c.jpg")
+
+	stats := runPool(t, cfg, pool)
+	if stats.Processed != 3 {
+		t.Fatalf("processed=%d, want 3", stats.Processed)
+	}
+	if stats.ConvertTime != 120*time.Millisecond || stats.AvgDuration() != 40*time.Millisecond {
+		t.Errorf("ConvertTime=%s AvgDuration=%s, want 120ms/40ms", stats.ConvertTime, stats.AvgDuration())
+	}
+	if stats.MedianDuration != 40*time.Millisecond {
+		t.Errorf("MedianDuration=%s, want 40ms", stats.MedianDuration)
+	}
+}
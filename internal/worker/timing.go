@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// durationSampleSize - предельное число длительностей в выборке для медианы:
+// память не растёт с числом файлов.
+const durationSampleSize = 1024
+
+// durationSample - равномерная выборка длительностей конвертации
+// (reservoir sampling): первые durationSampleSize значений сохраняются все,
+// дальше каждое новое заменяет случайное с вероятностью size/seen.
+type durationSample struct {
+	mu     sync.Mutex
+	seen   int64
+	values []time.Duration
+}
+
+// add добавляет длительность в выборку.
+func (s *durationSample) add(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seen++
+	if len(s.values) < durationSampleSize {
+		s.values = append(s.values, d)
+		return
+	}
+	if j := rand.Int64N(s.seen); j < durationSampleSize {
+		s.values[j] = d
+	}
+}
+
+// median возвращает медиану выборки (0 для пустой). Пока файлов не больше
+// durationSampleSize, медиана точная.
+func (s *durationSample) median() time.Duration {
+	s.mu.Lock()
+	sorted := slices.Clone(s.values)
+	s.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return 0
+	}
+	slices.Sort(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// observeDuration учитывает длительность успешной конвертации.
+func (p *Pool) observeDuration(d time.Duration) {
+	p.durations.add(d)
+	atomic.AddInt64((*int64)(&p.stats.ConvertTime), int64(d))
+}
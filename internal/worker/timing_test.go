@@ -0,0 +1,63 @@
+package worker
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool_TimingStats(t *testing.T) {
+	_, pool := newTestEnv(t)
+
+	// Известные длительности: среднее 4s, медиана (2s+3s)/2
+	for _, d := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 10 * time.Second} {
+		pool.observeDuration(d)
+		atomic.AddInt64(&pool.stats.Processed, 1)
+	}
+
+	stats := pool.GetStats()
+	if stats.ConvertTime != 16*time.Second {
+		t.Errorf("ConvertTime = %s, want 16s", stats.ConvertTime)
+	}
+	if got := stats.AvgDuration(); got != 4*time.Second {
+		t.Errorf("AvgDuration() = %s, want 4s", got)
+	}
+	if stats.MedianDuration != 2500*time.Millisecond {
+		t.Errorf("MedianDuration = %s, want 2.5s", stats.MedianDuration)
+	}
+}
+
+func TestPool_TimingStatsProcess(t *testing.T) {
+	cfg, pool := newTestEnv(t, "a.jpg", "b.jpg", "broken.jpg")
+
+	stats := runPool(t, cfg, pool)
+	if stats.Processed != 2 {
+		t.Fatalf("processed=%d, want 2", stats.Processed)
+	}
+	if stats.ConvertTime <= 0 || stats.MedianDuration <= 0 || stats.AvgDuration() <= 0 {
+		t.Errorf("ConvertTime=%s MedianDuration=%s AvgDuration=%s, want > 0",
+			stats.ConvertTime, stats.MedianDuration, stats.AvgDuration())
+	}
+	if n := len(pool.durations.values); n != 2 {
+		t.Errorf("в выборке %d длительностей, want 2 (только успешные)", n)
+	}
+}
+
+func TestDurationSample_Bounded(t *testing.T) {
+	var s durationSample
+	for i := 1; i <= 10000; i++ {
+		s.add(time.Duration(i) * time.Millisecond)
+	}
+	if len(s.values) != durationSampleSize {
+		t.Fatalf("размер выборки = %d, want %d", len(s.values), durationSampleSize)
+	}
+	// Медиана равномерной выборки близка к медиане всех значений (5s)
+	if m := s.median(); m < 4*time.Second || m > 6*time.Second {
+		t.Errorf("median = %s, want около 5s", m)
+	}
+
+	var empty durationSample
+	if m := empty.median(); m != 0 {
+		t.Errorf("median пустой выборки = %s, want 0", m)
+	}
+}
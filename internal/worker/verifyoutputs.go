@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/artemshloyda/photoconverter/internal/config"
+	"github.com/artemshloyda/photoconverter/internal/scanner"
+	"github.com/artemshloyda/photoconverter/internal/storage"
+)
+
+// outputChanged проверяет результат уже успешной задачи исходника
+// (--verify-outputs): файла нет или его размер отличается от записанного
+// в БД. Результат без записанного пути не проверяется.
+func outputChanged(result *storage.StartJobResult) bool {
+	if result.Started || result.Duplicate || result.ExistingDstPath == "" {
+		return false
+	}
+	info, err := os.Stat(result.ExistingDstPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return true
+	}
+	return err == nil && result.ExistingDstSize != nil && *result.ExistingDstSize != info.Size()
+}
+
+// restartChangedOutput начинает задачу заново, если результат уже успешной
+// задачи удалён или изменён после конвертации (--verify-outputs). Иначе
+// возвращает result без изменений; restarted сообщает, что задача начата заново.
+func (p *Pool) restartChangedOutput(file scanner.File, v variant, result *storage.StartJobResult) (_ *storage.StartJobResult, restarted bool, err error) {
+	if !v.cfg.VerifyOutputs || !outputChanged(result) {
+		return result, false, nil
+	}
+	if p.verbose {
+		p.printMessage("🔁 %s: результат %s удалён или изменён, конвертируем заново\n", file.RelPath, result.ExistingDstPath)
+	}
+	if err := p.storage.DeleteJob(result.JobID); err != nil {
+		return nil, false, err
+	}
+	result, err = p.storage.TryStartJob(
+		file.Info,
+		string(v.cfg.OutputFormat),
+		v.cfg.OutputParams(),
+		v.cfg.OutputParamsHash(),
+		v.cfg.Mode == config.ModeDedup,
+	)
+	return result, err == nil && result.Started, err
+}
@@ -0,0 +1,57 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPool_VerifyOutputs(t *testing.T) {
+	cfg, pool, stub := newStubEnv(t, 0, "a.jpg", "b.jpg", "c.jpg")
+	if stats := runPool(t, cfg, pool); stats.Processed != 3 {
+		t.Fatalf("processed=%d, want 3", stats.Processed)
+	}
+
+	// a обрезан, b удалён, c не тронут
+	ext := "." + string(cfg.OutputFormat)
+	dstA, dstB := filepath.Join(cfg.OutputDir, "a"+ext), filepath.Join(cfg.OutputDir, "b"+ext)
+	if err := os.Truncate(dstA, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(dstB); err != nil {
+		t.Fatal(err)
+	}
+
+	// Без --verify-outputs результаты БД не проверяются
+	stub.calls.Store(0)
+	stats := runPool(t, cfg, New(cfg, pool.storage, stub))
+	if stats.Skipped != 3 || stub.calls.Load() != 0 {
+		t.Fatalf("без проверки: skipped=%d вызовов=%d, want 3/0", stats.Skipped, stub.calls.Load())
+	}
+
+	// Dry-run показывает повтор изменённых результатов
+	cfg.VerifyOutputs = true
+	cfg.DryRun = true
+	stats = runPool(t, cfg, New(cfg, pool.storage, stub))
+	if stats.Plan.Retry != 2 || stats.Plan.Skip != 1 || stub.calls.Load() != 0 {
+		t.Errorf("dry-run: retry=%d skip=%d вызовов=%d, want 2/1/0", stats.Plan.Retry, stats.Plan.Skip, stub.calls.Load())
+	}
+
+	cfg.DryRun = false
+	stats = runPool(t, cfg, New(cfg, pool.storage, stub))
+	if stats.Processed != 2 || stats.Skipped != 1 || stub.calls.Load() != 2 {
+		t.Fatalf("--verify-outputs: processed=%d skipped=%d вызовов=%d, want 2/1/2", stats.Processed, stats.Skipped, stub.calls.Load())
+	}
+	for path, want := range map[string]string{dstA: "converted:a.jpg", dstB: "converted:b.jpg"} {
+		if data, err := os.ReadFile(path); err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v, want %q", path, data, err, want)
+		}
+	}
+
+	// Восстановленные результаты записаны в БД с новым размером
+	stub.calls.Store(0)
+	stats = runPool(t, cfg, New(cfg, pool.storage, stub))
+	if stats.Skipped != 3 || stub.calls.Load() != 0 {
+		t.Errorf("повтор: skipped=%d вызовов=%d, want 3/0", stats.Skipped, stub.calls.Load())
+	}
+}